@@ -1,4 +1,4 @@
-// Copyright (c) 2021 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2021-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package v1alpha1
@@ -39,6 +39,14 @@ const (
 	// VirtualMachineImageNotReadyReason (Severity=Error) documents that the VirtualMachineImage specified in the VirtualMachineSpec
 	// is not ready.
 	VirtualMachineImageNotReadyReason = "VirtualMachineImageNotReady"
+
+	// VirtualMachineSourceNotFoundReason (Severity=Error) documents that the source VirtualMachine or
+	// VirtualMachineSnapshot specified in the VirtualMachineSpec is not available.
+	VirtualMachineSourceNotFoundReason = "VirtualMachineSourceNotFound"
+
+	// VirtualMachineSourceNotReadyReason (Severity=Error) documents that the source VirtualMachine or
+	// VirtualMachineSnapshot specified in the VirtualMachineSpec cannot be cloned yet.
+	VirtualMachineSourceNotReadyReason = "VirtualMachineSourceNotReady"
 )
 
 const (
@@ -59,6 +67,13 @@ const (
 
 	// GuestCustomizationFailedReason (Severity=Error) documents that the guest customization failed within the guest OS.
 	GuestCustomizationFailedReason = "GuestCustomizationFailed"
+
+	// GuestCustomizationStaleReason (Severity=Warning) documents that a pending guest customization was not picked up
+	// by the guest OS within the timeout, so it was cleared and the guest customization was applied again.
+	GuestCustomizationStaleReason = "GuestCustomizationStale"
+
+	// GuestCustomizationRerunReason (Severity=Info) documents that a fresh guest customization was applied on request.
+	GuestCustomizationRerunReason = "GuestCustomizationRerun"
 )
 
 const (
@@ -72,6 +87,91 @@ const (
 	VirtualMachineToolsRunningReason = "VirtualMachineToolsRunning"
 )
 
+const (
+	// VirtualMachineResizedCondition documents that the CPU and memory configuration of the VirtualMachineClass
+	// specified in the VirtualMachineSpec has been applied to the VirtualMachine.
+	VirtualMachineResizedCondition ConditionType = "VirtualMachineResized"
+
+	// VirtualMachineResizePendingReason (Severity=Info) documents that the VirtualMachine is being power cycled
+	// because the CPU and memory changes of its VirtualMachineClass cannot be hot-added.
+	VirtualMachineResizePendingReason = "ResizePending"
+
+	// VirtualMachineResizeFailedReason (Severity=Error) documents that reconfiguring the VirtualMachine to the
+	// CPU and memory of its VirtualMachineClass failed.
+	VirtualMachineResizeFailedReason = "ResizeFailed"
+
+	// VirtualMachineResizeDeferredReason (Severity=Info) documents that the CPU and memory changes of the updated
+	// VirtualMachineClass cannot be hot-added, so the VirtualMachine is resized the next time it is powered off.
+	VirtualMachineResizeDeferredReason = "ResizeDeferred"
+)
+
+const (
+	// GuestShutdownCondition documents the shut down of the guest OS when the VirtualMachine is powered off with
+	// the "soft" or "trySoft" PowerOffMode.
+	GuestShutdownCondition ConditionType = "GuestShutdown"
+
+	// GuestShutdownInProgressReason (Severity=Info) documents that the guest OS is shutting down.
+	GuestShutdownInProgressReason = "GuestShutdownInProgress"
+
+	// GuestShutdownFailedReason (Severity=Warning) documents that the shut down of the guest OS could not be
+	// initiated, such as when VMware Tools is not running.
+	GuestShutdownFailedReason = "GuestShutdownFailed"
+
+	// GuestShutdownTimedOutReason (Severity=Warning) documents that the guest OS did not shut down in time, so
+	// the VirtualMachine was powered off.
+	GuestShutdownTimedOutReason = "GuestShutdownTimedOut"
+)
+
+const (
+	// VirtualMachineStorageRelocatedCondition documents that the disks of the VirtualMachine have been relocated to
+	// a datastore compatible with the storage policy of the StorageClass specified in the VirtualMachineSpec.
+	VirtualMachineStorageRelocatedCondition ConditionType = "VirtualMachineStorageRelocated"
+
+	// VirtualMachineStorageRelocationInProgressReason (Severity=Info) documents that the disks of the
+	// VirtualMachine are being relocated.
+	VirtualMachineStorageRelocationInProgressReason = "StorageRelocationInProgress"
+
+	// VirtualMachineStorageRelocationFailedReason (Severity=Error) documents that relocating the disks of the
+	// VirtualMachine failed.
+	VirtualMachineStorageRelocationFailedReason = "StorageRelocationFailed"
+)
+
+const (
+	// VirtualMachineMigratedCondition documents that the VirtualMachine has been migrated to the availability zone
+	// in its topology.kubernetes.io/zone label.
+	VirtualMachineMigratedCondition ConditionType = "VirtualMachineMigrated"
+
+	// VirtualMachineMigratingReason (Severity=Info) documents that the VirtualMachine is being migrated to
+	// another availability zone.
+	VirtualMachineMigratingReason = "Migrating"
+
+	// VirtualMachineMigrationFailedReason (Severity=Error) documents that migrating the VirtualMachine to
+	// another availability zone failed.
+	VirtualMachineMigrationFailedReason = "MigrationFailed"
+)
+
+const (
+	// VirtualMachineSnapshotRevertedCondition documents that the VirtualMachine has been reverted to the
+	// VirtualMachineSnapshot specified in the VirtualMachineSpec.
+	VirtualMachineSnapshotRevertedCondition ConditionType = "VirtualMachineSnapshotReverted"
+
+	// VirtualMachineSnapshotNotFoundReason (Severity=Error) documents that the VirtualMachineSnapshot to revert
+	// the VirtualMachine to does not exist.
+	VirtualMachineSnapshotNotFoundReason = "VirtualMachineSnapshotNotFound"
+
+	// VirtualMachineSnapshotNotReadyReason (Severity=Info) documents that the VirtualMachineSnapshot to revert
+	// the VirtualMachine to is not ready yet.
+	VirtualMachineSnapshotNotReadyReason = "VirtualMachineSnapshotNotReady"
+
+	// VirtualMachineSnapshotOfOtherVMReason (Severity=Error) documents that the VirtualMachineSnapshot to revert
+	// the VirtualMachine to is a snapshot of another VirtualMachine.
+	VirtualMachineSnapshotOfOtherVMReason = "VirtualMachineSnapshotOfOtherVM"
+
+	// VirtualMachineSnapshotRevertFailedReason (Severity=Error) documents that reverting the VirtualMachine to
+	// the VirtualMachineSnapshot failed.
+	VirtualMachineSnapshotRevertFailedReason = "RevertFailed"
+)
+
 // Common Condition.Reason used by VM Operator API objects.
 const (
 	// DeletingReason (Severity=Info) documents a condition not in Status=True because the underlying object it is currently being deleted.
@@ -1,4 +1,4 @@
-// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2020-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package v1alpha1
@@ -14,13 +14,50 @@ import (
 const (
 	VirtualMachinePoweredOff VirtualMachinePowerState = "poweredOff"
 	VirtualMachinePoweredOn  VirtualMachinePowerState = "poweredOn"
+	VirtualMachineSuspended  VirtualMachinePowerState = "suspended"
 )
 
 // VirtualMachinePowerState represents the power state of a VirtualMachine.
-// The value values are "poweredOn", and "poweredOff".
-// +kubebuilder:validation:Enum=poweredOff;poweredOn
+// The value values are "poweredOn", "poweredOff", and "suspended".
+// +kubebuilder:validation:Enum=poweredOff;poweredOn;suspended
 type VirtualMachinePowerState string
 
+const (
+	// VirtualMachinePowerOffModeHard powers off the VirtualMachine without shutting down the guest OS.
+	VirtualMachinePowerOffModeHard VirtualMachinePowerOffMode = "hard"
+
+	// VirtualMachinePowerOffModeSoft shuts down the guest OS, powering off the VirtualMachine when the guest OS
+	// has not shut down within five minutes.
+	VirtualMachinePowerOffModeSoft VirtualMachinePowerOffMode = "soft"
+
+	// VirtualMachinePowerOffModeTrySoft shuts down the guest OS like VirtualMachinePowerOffModeSoft, but powers off
+	// the VirtualMachine right away when the guest OS shut down cannot be initiated, such as when VMware Tools is
+	// not running.
+	VirtualMachinePowerOffModeTrySoft VirtualMachinePowerOffMode = "trySoft"
+)
+
+// VirtualMachinePowerOffMode represents how a VirtualMachine is powered off.
+// The valid values are "hard", "soft", and "trySoft".
+// +kubebuilder:validation:Enum=hard;soft;trySoft
+type VirtualMachinePowerOffMode string
+
+const (
+	// VirtualMachineRestartModeHard resets the VirtualMachine without restarting the guest OS.
+	VirtualMachineRestartModeHard VirtualMachineRestartMode = "hard"
+
+	// VirtualMachineRestartModeSoft restarts the guest OS.
+	VirtualMachineRestartModeSoft VirtualMachineRestartMode = "soft"
+
+	// VirtualMachineRestartModeTrySoft restarts the guest OS, resetting the VirtualMachine when the guest OS
+	// restart cannot be initiated, such as when VMware Tools is not running.
+	VirtualMachineRestartModeTrySoft VirtualMachineRestartMode = "trySoft"
+)
+
+// VirtualMachineRestartMode represents how a VirtualMachine is restarted.
+// The valid values are "hard", "soft", and "trySoft".
+// +kubebuilder:validation:Enum=hard;soft;trySoft
+type VirtualMachineRestartMode string
+
 // VMStatusPhase is used to indicate the phase of a VirtualMachine's lifecycle.
 type VMStatusPhase string
 
@@ -110,11 +147,73 @@ type VirtualMachineNetworkInterface struct {
 	// associated with this network integration.  The default is "vmxnet3".
 	// +optional
 	EthernetCardType string `json:"ethernetCardType,omitempty"`
+
+	// MTU is the maximum transmission unit of the network interface in the guest. If unset, the guest's default
+	// is used. This is only honored by the CloudInit and Ignition transports.
+	// +optional
+	// +kubebuilder:validation:Minimum=68
+	// +kubebuilder:validation:Maximum=9000
+	MTU *int64 `json:"mtu,omitempty"`
+
+	// Routes describes a list of static routes to configure on the network interface in the guest. This is only
+	// honored by the CloudInit and Ignition transports.
+	// +optional
+	Routes []VirtualMachineNetworkRoute `json:"routes,omitempty"`
+
+	// BondName is the name of the VirtualMachineNetworkBond in the VirtualMachine's NetworkBonds that this
+	// network interface is a member of. The IP configuration of a bond member is moved to the bond in the guest.
+	// This is only honored by the CloudInit and Ignition transports.
+	// +optional
+	BondName string `json:"bondName,omitempty"`
+}
+
+// VirtualMachineNetworkRoute describes a static route of a VirtualMachineNetworkInterface.
+type VirtualMachineNetworkRoute struct {
+	// To is the destination of the route in CIDR notation, ex. "192.0.2.0/24" or "2001:db8::/32".
+	// The value "default" may be used for the default route.
+	To string `json:"to"`
+
+	// Via is the IPv4 or IPv6 address of the gateway of the route.
+	Via string `json:"via"`
+
+	// Metric is the metric of the route.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	Metric *int32 `json:"metric,omitempty"`
+}
+
+// VirtualMachineNetworkBondMode is the bonding mode of a VirtualMachineNetworkBond.
+// +kubebuilder:validation:Enum=active-backup;balance-rr;balance-xor;broadcast;"802.3ad";balance-tlb;balance-alb
+type VirtualMachineNetworkBondMode string
+
+const (
+	VirtualMachineNetworkBondModeActiveBackup VirtualMachineNetworkBondMode = "active-backup"
+	VirtualMachineNetworkBondModeBalanceRR    VirtualMachineNetworkBondMode = "balance-rr"
+	VirtualMachineNetworkBondModeBalanceXOR   VirtualMachineNetworkBondMode = "balance-xor"
+	VirtualMachineNetworkBondModeBroadcast    VirtualMachineNetworkBondMode = "broadcast"
+	VirtualMachineNetworkBondMode8023AD       VirtualMachineNetworkBondMode = "802.3ad"
+	VirtualMachineNetworkBondModeBalanceTLB   VirtualMachineNetworkBondMode = "balance-tlb"
+	VirtualMachineNetworkBondModeBalanceALB   VirtualMachineNetworkBondMode = "balance-alb"
+)
+
+// VirtualMachineNetworkBond describes a bond that aggregates two or more VirtualMachineNetworkInterfaces in the
+// guest. The member network interfaces refer to the bond by its name with their BondName field. The bond uses the
+// addresses and routes of all its member network interfaces, and the gateways and MTU of its first member network
+// interface.
+type VirtualMachineNetworkBond struct {
+	// Name is the name of the bond device in the guest, ex. "bond0".
+	// +kubebuilder:validation:MaxLength=15
+	Name string `json:"name"`
+
+	// Mode is the bonding mode. Defaults to "active-backup".
+	// +optional
+	// +kubebuilder:default=active-backup
+	Mode VirtualMachineNetworkBondMode `json:"mode,omitempty"`
 }
 
 // VirtualMachineMetadataTransport is used to indicate the transport used by VirtualMachineMetadata
-// Valid values are "ExtraConfig", "OvfEnv", "vAppConfig" and "CloudInit".
-// +kubebuilder:validation:Enum=ExtraConfig;OvfEnv;vAppConfig;CloudInit
+// Valid values are "ExtraConfig", "OvfEnv", "vAppConfig", "CloudInit", "Sysprep" and "Ignition".
+// +kubebuilder:validation:Enum=ExtraConfig;OvfEnv;vAppConfig;CloudInit;Sysprep;Ignition
 type VirtualMachineMetadataTransport string
 
 const (
@@ -156,6 +255,31 @@ const (
 	//
 	// For more information, please refer to cloud-init's official documentation.
 	VirtualMachineMetadataCloudInitTransport VirtualMachineMetadataTransport = "CloudInit"
+
+	// VirtualMachineMetadataSysprepTransport indicates the data set in
+	// the VirtualMachineMetadata Transport Resource, i.e., a ConfigMap or Secret,
+	// is used to customize a Windows guest with Sysprep.
+	//
+	// The "unattend" key may contain a complete unattend.xml answer file, in
+	// which case all the other keys are ignored. Otherwise, the Sysprep identity
+	// is built from the "full-name", "org-name", "product-id", "admin-password",
+	// "time-zone", "join-workgroup", "join-domain", "domain-admin" and
+	// "domain-admin-password" keys. Since this data usually contains passwords,
+	// it should be provided with a Secret.
+	//
+	// This transport uses Guest OS customization for networking.
+	VirtualMachineMetadataSysprepTransport VirtualMachineMetadataTransport = "Sysprep"
+
+	// VirtualMachineMetadataIgnitionTransport indicates the data set in
+	// the VirtualMachineMetadata Transport Resource, i.e., a ConfigMap or Secret,
+	// is the Ignition config of a CoreOS or Flatcar guest.
+	//
+	// The "ignition" key contains an Ignition config, or the "butane" key
+	// contains a Butane config that is transpiled to an Ignition config.
+	// A systemd-networkd unit is added to the Ignition config for each
+	// network interface, so the guest's network is configured without
+	// cloud-init.
+	VirtualMachineMetadataIgnitionTransport VirtualMachineMetadataTransport = "Ignition"
 )
 
 // VirtualMachineMetadata defines any metadata that should be passed to the VirtualMachine instance.  A typical use
@@ -178,7 +302,7 @@ type VirtualMachineMetadata struct {
 	SecretName string `json:"secretName,omitempty"`
 
 	// Transport describes the name of a supported VirtualMachineMetadata transport protocol.  Currently, the only supported
-	// transport protocols are "ExtraConfig", "OvfEnv" and "CloudInit".
+	// transport protocols are "ExtraConfig", "OvfEnv", "vAppConfig", "CloudInit", "Sysprep" and "Ignition".
 	Transport VirtualMachineMetadataTransport `json:"transport,omitempty"`
 }
 
@@ -214,6 +338,13 @@ type PersistentVolumeClaimVolumeSource struct {
 	// InstanceVolumeClaim is set if the PVC is backed by instance storage.
 	// +optional
 	InstanceVolumeClaim *InstanceVolumeClaimVolumeSource `json:"instanceVolumeClaim,omitempty"`
+
+	// ImageVolumeClaim is set if the PVC is populated from a VirtualMachineImage and is the boot disk of the
+	// VirtualMachine. Unlike a boot disk deployed from the image, the PVC is not deleted with the VirtualMachine.
+	// If the PVC does not exist when the VirtualMachine is created, it is populated with the boot disk of the
+	// image. Otherwise, the existing PVC is used as is. Only one volume can have an ImageVolumeClaim.
+	// +optional
+	ImageVolumeClaim *ImageVolumeClaimVolumeSource `json:"imageVolumeClaim,omitempty"`
 }
 
 // InstanceVolumeClaimVolumeSource contains information about the instance
@@ -227,6 +358,14 @@ type InstanceVolumeClaimVolumeSource struct {
 	Size resource.Quantity `json:"size"`
 }
 
+// ImageVolumeClaimVolumeSource contains information about the VirtualMachineImage that populates a PVC used as
+// the boot disk of a VirtualMachine.
+type ImageVolumeClaimVolumeSource struct {
+	// ImageName is the name of the VirtualMachineImage whose boot disk populates the PVC. It must be the ImageName
+	// of the VirtualMachine.
+	ImageName string `json:"imageName"`
+}
+
 // VsphereVolumeSource describes a volume source that represent static disks that belong to a VirtualMachine.
 type VsphereVolumeSource struct {
 	// A description of the virtual volume's resources and capacity
@@ -249,6 +388,20 @@ type Probe struct {
 	// +optional
 	GuestHeartbeat *GuestHeartbeatAction `json:"guestHeartbeat,omitempty"`
 
+	// HTTPGet specifies an action involving an HTTP GET request.
+	// +optional
+	HTTPGet *HTTPGetAction `json:"httpGet,omitempty"`
+
+	// GuestExec specifies an action involving running a command in the guest through VMware Tools.
+	// +optional
+	GuestExec *GuestExecAction `json:"guestExec,omitempty"`
+
+	// InitialDelaySeconds specifies the number of seconds after the VirtualMachine has been powered on before the
+	// probe is initiated. Defaults to 0 seconds.
+	// +optional
+	// +kubebuilder:validation:Minimum:=0
+	InitialDelaySeconds int32 `json:"initialDelaySeconds,omitempty"`
+
 	// TimeoutSeconds specifies a number of seconds after which the probe times out.
 	// Defaults to 10 seconds. Minimum value is 1.
 	// +optional
@@ -261,6 +414,50 @@ type Probe struct {
 	// +optional
 	// +kubebuilder:validation:Minimum:=1
 	PeriodSeconds int32 `json:"periodSeconds,omitempty"`
+
+	// SuccessThreshold specifies the minimum number of consecutive successes for the probe to be considered
+	// successful after having failed. Defaults to 1. Minimum value is 1.
+	// +optional
+	// +kubebuilder:validation:Minimum:=1
+	SuccessThreshold int32 `json:"successThreshold,omitempty"`
+
+	// FailureThreshold specifies the minimum number of consecutive failures for the probe to be considered
+	// failed after having succeeded. Defaults to 3. Minimum value is 1. A probe that cannot be run, such as when
+	// VMware Tools is not running, counts as a failure.
+	// +optional
+	// +kubebuilder:validation:Minimum:=1
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+}
+
+// LivenessProbeAction describes the action taken when the LivenessProbe of a VirtualMachine fails.
+// +kubebuilder:validation:Enum=None;EventOnly;Reset;PowerCycle
+type LivenessProbeAction string
+
+const (
+	// LivenessProbeActionNone indicates that a failed LivenessProbe is only logged.
+	LivenessProbeActionNone LivenessProbeAction = "None"
+
+	// LivenessProbeActionEventOnly indicates that a failed LivenessProbe is reported with an event.
+	LivenessProbeActionEventOnly LivenessProbeAction = "EventOnly"
+
+	// LivenessProbeActionReset indicates that a failed LivenessProbe causes the VirtualMachine to be hard reset.
+	LivenessProbeActionReset LivenessProbeAction = "Reset"
+
+	// LivenessProbeActionPowerCycle indicates that a failed LivenessProbe causes the VirtualMachine to be powered
+	// off and then powered back on.
+	LivenessProbeActionPowerCycle LivenessProbeAction = "PowerCycle"
+)
+
+// LivenessProbe describes a health check to be performed against a VirtualMachine to determine whether the guest is
+// alive, and the remediation to apply when it is not.
+type LivenessProbe struct {
+	Probe `json:",inline"`
+
+	// Action describes the action taken when the probe has failed FailureThreshold consecutive times. Defaults to
+	// Reset.
+	// +optional
+	// +kubebuilder:default=Reset
+	Action LivenessProbeAction `json:"action,omitempty"`
 }
 
 // TCPSocketAction describes an action based on opening a socket.
@@ -275,6 +472,89 @@ type TCPSocketAction struct {
 	Host string `json:"host,omitempty"`
 }
 
+// URIScheme identifies the scheme used for connection to a host for an HTTPGetAction.
+// +kubebuilder:validation:Enum=HTTP;HTTPS
+type URIScheme string
+
+const (
+	// URISchemeHTTP means that the scheme used will be http://.
+	URISchemeHTTP URIScheme = "HTTP"
+	// URISchemeHTTPS means that the scheme used will be https://.
+	URISchemeHTTPS URIScheme = "HTTPS"
+)
+
+// HTTPHeader describes a custom header to be used in HTTP probes.
+type HTTPHeader struct {
+	// Name is the header field name.
+	Name string `json:"name"`
+
+	// Value is the header field value.
+	Value string `json:"value"`
+}
+
+// HTTPStatusRange describes an inclusive range of HTTP status codes.
+type HTTPStatusRange struct {
+	// Min is the lowest status code of the range.
+	// +kubebuilder:validation:Minimum:=100
+	// +kubebuilder:validation:Maximum:=599
+	Min int32 `json:"min"`
+
+	// Max is the highest status code of the range.
+	// +kubebuilder:validation:Minimum:=100
+	// +kubebuilder:validation:Maximum:=599
+	Max int32 `json:"max"`
+}
+
+// HTTPGetAction describes an action based on HTTP GET requests.
+type HTTPGetAction struct {
+	// Path is the path to access on the HTTP server. Defaults to "/".
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// Port specifies a number or name of the port to access on the VirtualMachine.
+	// If the format of port is a number, it must be in the range 1 to 65535.
+	// If the format of name is a string, it must be an IANA_SVC_NAME.
+	Port intstr.IntOrString `json:"port"`
+
+	// Host is an optional host name to connect to.  Host defaults to the VirtualMachine IP.
+	// +optional
+	Host string `json:"host,omitempty"`
+
+	// Scheme is the scheme to use for connecting to the host. Defaults to HTTP.
+	// +optional
+	// +kubebuilder:default=HTTP
+	Scheme URIScheme `json:"scheme,omitempty"`
+
+	// HTTPHeaders are the custom headers to set in the request. HTTP allows repeated headers.
+	// +optional
+	HTTPHeaders []HTTPHeader `json:"httpHeaders,omitempty"`
+
+	// ExpectedStatus is the range of response status codes that are considered successful.
+	// Defaults to the range 200 to 399.
+	// +optional
+	ExpectedStatus *HTTPStatusRange `json:"expectedStatus,omitempty"`
+
+	// InsecureSkipTLSVerify specifies whether the server certificate is not verified when the
+	// scheme is HTTPS.
+	// +optional
+	InsecureSkipTLSVerify bool `json:"insecureSkipTLSVerify,omitempty"`
+}
+
+// GuestExecAction describes an action based on running a command in the guest through the
+// guest operations of VMware Tools. An exit code of zero is considered successful.
+type GuestExecAction struct {
+	// Command is the command line to execute in the guest. The first element is the absolute
+	// path of the program, and the remaining elements are its arguments. The command is not
+	// run in a shell, so shell instructions like pipes are not supported.
+	// +kubebuilder:validation:MinItems=1
+	Command []string `json:"command"`
+
+	// CredentialsSecretName is the name of the Secret, in the same namespace as the
+	// VirtualMachine, that contains the "username" and "password" keys used to authenticate
+	// with the guest operating system.
+	CredentialsSecretName string `json:"credentialsSecretName"`
+}
+
 // GuestHeartbeatStatus is the status type for a GuestHeartbeat.
 type GuestHeartbeatStatus string
 
@@ -300,21 +580,73 @@ type GuestHeartbeatAction struct {
 	ThresholdStatus GuestHeartbeatStatus `json:"thresholdStatus,omitempty"`
 }
 
+// VirtualMachineSource describes an existing VirtualMachine from which a VirtualMachine is cloned.
+type VirtualMachineSource struct {
+	// VirtualMachineName is the name of the VirtualMachine, in the same namespace, that is cloned. The source
+	// VirtualMachine must have been created on the infrastructure provider. The disks of the PersistentVolumeClaim
+	// volumes of the source VirtualMachine are not cloned, and a source VirtualMachine with such volumes cannot
+	// be instant cloned.
+	VirtualMachineName string `json:"virtualMachineName"`
+
+	// SnapshotName is the name of a VirtualMachineSnapshot of the source VirtualMachine. When specified, the
+	// VirtualMachine is a linked clone of the snapshot: its disks are backed by delta disks on top of the
+	// snapshot's disks, which makes the clone fast and space efficient. Otherwise, a full clone of the source
+	// VirtualMachine's current state is created.
+	// +optional
+	SnapshotName string `json:"snapshotName,omitempty"`
+
+	// Instant specifies whether the VirtualMachine is an instant clone of the source VirtualMachine. An instant
+	// clone is forked from the running source VirtualMachine, sharing its memory and disk state, and is created
+	// in seconds. The source VirtualMachine must be powered on and the VirtualMachine is created powered on.
+	// Since the guest is not rebooted, the VirtualMachine is customized after the fork only through the
+	// "guestinfo." prefixed keys of the ExtraConfig VmMetadata transport. Instant may not be specified together
+	// with SnapshotName.
+	// +optional
+	Instant bool `json:"instant,omitempty"`
+}
+
 // VirtualMachineSpec defines the desired state of a VirtualMachine.
 type VirtualMachineSpec struct {
 	// ImageName describes the name of a VirtualMachineImage that is to be used as the base Operating System image of
 	// the desired VirtualMachine instances.  The VirtualMachineImage resources can be introspected to discover identifying
-	// attributes that may help users to identify the desired image to use.
-	ImageName string `json:"imageName"`
+	// attributes that may help users to identify the desired image to use. Exactly one of ImageName or Source must be
+	// specified. The image must not be of type ISO.
+	// +optional
+	ImageName string `json:"imageName,omitempty"`
+
+	// Source describes an existing VirtualMachine, in the same namespace, from which the VirtualMachine is cloned
+	// instead of being deployed from a VirtualMachineImage. Exactly one of ImageName or Source must be specified.
+	// +optional
+	Source *VirtualMachineSource `json:"source,omitempty"`
 
 	// ClassName describes the name of a VirtualMachineClass that is to be used as the overlaid resource configuration
 	// of VirtualMachine.  A VirtualMachineClass is used to further customize the attributes of the VirtualMachine
 	// instance.  See VirtualMachineClass for more description.
 	ClassName string `json:"className"`
 
-	// PowerState describes the desired power state of a VirtualMachine.  Valid power states are "poweredOff" and "poweredOn".
+	// PowerState describes the desired power state of a VirtualMachine.  Valid power states are "poweredOff",
+	// "poweredOn", and "suspended". A suspended VirtualMachine is resumed when it is powered on.
 	PowerState VirtualMachinePowerState `json:"powerState"`
 
+	// PowerOffMode describes how the VirtualMachine is powered off, both when PowerState is changed to "poweredOff"
+	// and before the VirtualMachine is deleted. Valid modes are "hard", "soft", and "trySoft". Defaults to "hard".
+	// +optional
+	// +kubebuilder:default=hard
+	PowerOffMode VirtualMachinePowerOffMode `json:"powerOffMode,omitempty"`
+
+	// NextRestartTime requests a restart of the VirtualMachine. The VirtualMachine is restarted once each time this
+	// field is set to a time, in RFC3339 format, that is not in the future and is later than status.lastRestartTime.
+	// The value "now" is replaced with the current time when the VirtualMachine is updated. A restart requested while
+	// the VirtualMachine is not powered on is ignored.
+	// +optional
+	NextRestartTime string `json:"nextRestartTime,omitempty"`
+
+	// RestartMode describes how the VirtualMachine is restarted when NextRestartTime is updated. Valid modes are
+	// "hard", "soft", and "trySoft". Defaults to "hard".
+	// +optional
+	// +kubebuilder:default=hard
+	RestartMode VirtualMachineRestartMode `json:"restartMode,omitempty"`
+
 	// Ports is currently unused and can be considered deprecated.
 	// +optional
 	Ports []VirtualMachinePort `json:"ports,omitempty"`
@@ -324,7 +656,8 @@ type VirtualMachineSpec struct {
 	VmMetadata *VirtualMachineMetadata `json:"vmMetadata,omitempty"` //nolint:revive,stylecheck
 
 	// StorageClass describes the name of a StorageClass that should be used to configure storage-related attributes of the VirtualMachine
-	// instance.
+	// instance. Changing the StorageClass of an existing VirtualMachine relocates its disks, other than those of
+	// PersistentVolumeClaims, to a datastore compatible with the storage policy of the new StorageClass.
 	// +optional
 	StorageClass string `json:"storageClass,omitempty"`
 
@@ -334,6 +667,11 @@ type VirtualMachineSpec struct {
 	// +optional
 	NetworkInterfaces []VirtualMachineNetworkInterface `json:"networkInterfaces,omitempty"`
 
+	// NetworkBonds describes a list of bonds that aggregate the VirtualMachine's NetworkInterfaces in the guest.
+	// This is only honored by the CloudInit and Ignition transports.
+	// +optional
+	NetworkBonds []VirtualMachineNetworkBond `json:"networkBonds,omitempty"`
+
 	// ResourcePolicyName describes the name of a VirtualMachineSetResourcePolicy to be used when creating the
 	// VirtualMachine instance.
 	// +optional
@@ -352,8 +690,48 @@ type VirtualMachineSpec struct {
 	// +optional
 	ReadinessProbe *Probe `json:"readinessProbe,omitempty"`
 
+	// LivenessProbe describes a probe that can be used to determine if the guest of the VirtualMachine is alive. A
+	// VirtualMachine whose LivenessProbe fails is remediated according to the probe's action.
+	// +optional
+	LivenessProbe *LivenessProbe `json:"livenessProbe,omitempty"`
+
 	// AdvancedOptions describes a set of optional, advanced options for configuring a VirtualMachine
 	AdvancedOptions *VirtualMachineAdvancedOptions `json:"advancedOptions,omitempty"`
+
+	// BootDiskCapacity describes the desired capacity of the boot disk of the VirtualMachine. When larger than the
+	// boot disk of the image, the boot disk is grown before the VirtualMachine is first powered on, and it may be
+	// grown again later, including while the VirtualMachine is powered on. The boot disk cannot be shrunk, nor be
+	// smaller than the boot disk of the image. The boot disk cannot be grown while the VirtualMachine has snapshots,
+	// and BootDiskCapacity cannot be specified for a linked or instant clone. With the CloudInit transport,
+	// cloud-init is hinted to grow the root partition and filesystem to fill the boot disk.
+	// +optional
+	BootDiskCapacity *resource.Quantity `json:"bootDiskCapacity,omitempty"`
+
+	// BootOptions describes the firmware, boot order, and EFI secure boot of the VirtualMachine. The boot options
+	// are applied before the VirtualMachine is powered on.
+	// +optional
+	BootOptions *VirtualMachineBootOptions `json:"bootOptions,omitempty"`
+
+	// VirtualTPM describes a virtual Trusted Platform Module device added to the VirtualMachine before it is powered
+	// on. A VirtualMachine with a virtual TPM must boot with EFI firmware. Once added, the virtual TPM cannot be
+	// removed.
+	// +optional
+	VirtualTPM *VirtualMachineVirtualTPM `json:"virtualTPM,omitempty"`
+
+	// Cdrom describes the list of virtual CD-ROMs of the VirtualMachine, each backed by the ISO file of a
+	// VirtualMachineImage of type ISO. The CD-ROMs are added before the VirtualMachine is powered on and cannot be
+	// added or removed while it is powered on, but their images may be changed and they may be connected and
+	// disconnected at any time. To boot from an ISO, add "cdrom" to the BootOrder of the BootOptions. A
+	// VirtualMachine is still deployed from an OVF image, so installing an Operating System from an ISO requires a
+	// base OVF image, for example one with an empty boot disk.
+	// +optional
+	Cdrom []VirtualMachineCdrom `json:"cdrom,omitempty"`
+
+	// RevertToSnapshot describes the name of a VirtualMachineSnapshot, in the same Namespace as the VirtualMachine,
+	// that the VirtualMachine should be reverted to. The VirtualMachine controller clears this field once the revert
+	// has completed.
+	// +optional
+	RevertToSnapshot string `json:"revertToSnapshot,omitempty"`
 }
 
 // VirtualMachineAdvancedOptions describes a set of optional, advanced options for configuring a VirtualMachine.
@@ -367,6 +745,63 @@ type VirtualMachineAdvancedOptions struct {
 	ChangeBlockTracking *bool `json:"changeBlockTracking,omitempty"`
 }
 
+// VirtualMachineBootDevice represents a type of device a VirtualMachine boots from.
+// The valid values are "disk", "network", and "cdrom".
+// +kubebuilder:validation:Enum=disk;network;cdrom
+type VirtualMachineBootDevice string
+
+const (
+	// VirtualMachineBootDeviceDisk boots the VirtualMachine from its first disk.
+	VirtualMachineBootDeviceDisk VirtualMachineBootDevice = "disk"
+
+	// VirtualMachineBootDeviceNetwork boots the VirtualMachine from its first network interface.
+	VirtualMachineBootDeviceNetwork VirtualMachineBootDevice = "network"
+
+	// VirtualMachineBootDeviceCDROM boots the VirtualMachine from its first CD-ROM.
+	VirtualMachineBootDeviceCDROM VirtualMachineBootDevice = "cdrom"
+)
+
+// VirtualMachineBootOptions describes how a VirtualMachine boots.
+type VirtualMachineBootOptions struct {
+	// Firmware describes the firmware the VirtualMachine boots with, either "bios" or "efi". When omitted, the
+	// VirtualMachine boots with the firmware of its VirtualMachineImage.
+	// +optional
+	// +kubebuilder:validation:Enum=bios;efi
+	Firmware string `json:"firmware,omitempty"`
+
+	// BootOrder describes the order of the devices the VirtualMachine attempts to boot from. When omitted, the boot
+	// order of the VirtualMachine is left unchanged.
+	// +optional
+	BootOrder []VirtualMachineBootDevice `json:"bootOrder,omitempty"`
+
+	// EFISecureBootEnabled describes whether EFI secure boot is enabled. EFI secure boot requires the VirtualMachine
+	// to boot with EFI firmware.
+	// +optional
+	EFISecureBootEnabled bool `json:"efiSecureBootEnabled,omitempty"`
+}
+
+// VirtualMachineVirtualTPM describes a virtual Trusted Platform Module device.
+type VirtualMachineVirtualTPM struct {
+	// KeyProviderID describes the ID of the vCenter key provider used to encrypt the VirtualMachine, which is required
+	// for a virtual TPM. A VirtualMachine that is already encrypted keeps its existing key.
+	KeyProviderID string `json:"keyProviderID"`
+}
+
+// VirtualMachineCdrom describes a virtual CD-ROM device of a VirtualMachine.
+type VirtualMachineCdrom struct {
+	// Name describes the name of the CD-ROM. The name must be unique among the CD-ROMs of the VirtualMachine.
+	Name string `json:"name"`
+
+	// ImageName describes the name of the VirtualMachineImage, of type ISO, whose ISO file backs the CD-ROM. The
+	// image of a CD-ROM cannot be used by another CD-ROM of the VirtualMachine.
+	ImageName string `json:"imageName"`
+
+	// Connected describes whether the CD-ROM is connected to the VirtualMachine, and whether it is connected when
+	// the VirtualMachine is powered on. Defaults to true.
+	// +optional
+	Connected *bool `json:"connected,omitempty"`
+}
+
 // VirtualMachineVolumeProvisioningOptions specifies the provisioning options for a VirtualMachineVolume.
 type VirtualMachineVolumeProvisioningOptions struct {
 	// ThinProvisioned specifies whether to use thin provisioning for the VirtualMachineVolume.
@@ -463,9 +898,55 @@ type VirtualMachineStatus struct {
 	NetworkInterfaces []NetworkInterfaceStatus `json:"networkInterfaces,omitempty"`
 
 	// Zone describes the availability zone where the VirtualMachine has been scheduled.
-	// Please note this field may be empty when the cluster is not zone-aware.
+	// Please note this field may be empty when the cluster is not zone-aware. Changing the
+	// topology.kubernetes.io/zone label of the VirtualMachine migrates it to that zone, and this
+	// field is updated once the migration completes.
 	// +optional
 	Zone string `json:"zone,omitempty"`
+
+	// CurrentSnapshot describes the name of the VirtualMachineSnapshot the VirtualMachine was most recently
+	// reverted to.
+	// +optional
+	CurrentSnapshot string `json:"currentSnapshot,omitempty"`
+
+	// AppliedClassName describes the name of the VirtualMachineClass whose CPU and memory configuration was most
+	// recently applied to the VirtualMachine.
+	// +optional
+	AppliedClassName string `json:"appliedClassName,omitempty"`
+
+	// AppliedClassGeneration describes the metadata.generation of the VirtualMachineClass whose CPU and memory
+	// configuration was most recently applied to the VirtualMachine. A VirtualMachine is resized when either its
+	// spec.className or the generation of that VirtualMachineClass differs from what was last applied. A change of
+	// the generation alone only power cycles a powered on VirtualMachine that cannot hot-add the changes when the
+	// VirtualMachine has the vmoperator.vmware.com/class-update-power-cycle annotation, and otherwise the
+	// VirtualMachine is resized the next time it is powered off.
+	// +optional
+	AppliedClassGeneration int64 `json:"appliedClassGeneration,omitempty"`
+
+	// BootDiskCapacity describes the current capacity of the boot disk of the VirtualMachine.
+	// +optional
+	BootDiskCapacity *resource.Quantity `json:"bootDiskCapacity,omitempty"`
+
+	// AppliedStorageClass describes the name of the StorageClass whose storage policy was most recently applied to
+	// the disks of the VirtualMachine. The disks are relocated when spec.storageClass differs from what was last
+	// applied.
+	// +optional
+	AppliedStorageClass string `json:"appliedStorageClass,omitempty"`
+
+	// RestartCount describes the number of times the VirtualMachine has been reset or power cycled because its
+	// LivenessProbe failed.
+	// +optional
+	RestartCount int32 `json:"restartCount,omitempty"`
+
+	// LastRestartTime describes the spec.nextRestartTime of the most recent restart requested for the
+	// VirtualMachine.
+	// +optional
+	LastRestartTime *metav1.Time `json:"lastRestartTime,omitempty"`
+
+	// BootTime describes the time the VirtualMachine was most recently powered on. The initial delay of the
+	// probes of the VirtualMachine is measured from this time.
+	// +optional
+	BootTime *metav1.Time `json:"bootTime,omitempty"`
 }
 
 func (vm *VirtualMachine) GetConditions() Conditions {
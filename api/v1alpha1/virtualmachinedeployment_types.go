@@ -0,0 +1,250 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// VirtualMachineDeploymentRevisionAnnotation is the annotation on the
+	// VirtualMachineReplicaSets of a VirtualMachineDeployment with the
+	// revision of the template they were created from.
+	VirtualMachineDeploymentRevisionAnnotation = "vmoperator.vmware.com/revision"
+
+	// VirtualMachineDeploymentTemplateHashLabel is the label added to the
+	// selector and template of the VirtualMachineReplicaSets of a
+	// VirtualMachineDeployment so the replica sets of different revisions do
+	// not select the same VirtualMachines.
+	VirtualMachineDeploymentTemplateHashLabel = "vmoperator.vmware.com/template-hash"
+)
+
+const (
+	// VirtualMachineDeploymentAvailableCondition is the Type for a
+	// VirtualMachineDeployment resource's status condition.
+	//
+	// The condition's status is set to true when at least the minimum number
+	// of replicas allowed by the rolling update strategy are ready.
+	VirtualMachineDeploymentAvailableCondition = "Available"
+
+	// VirtualMachineDeploymentRolledOutCondition is the Type for a
+	// VirtualMachineDeployment resource's status condition.
+	//
+	// The condition's status is set to true when all the replicas have been
+	// updated to the latest template and are ready.
+	VirtualMachineDeploymentRolledOutCondition = "RolledOut"
+)
+
+// Condition.Reason for Conditions related to VirtualMachineDeployment.
+const (
+	// VirtualMachineDeploymentMinimumReplicasUnavailableReason documents that
+	// fewer than the minimum number of replicas are ready.
+	VirtualMachineDeploymentMinimumReplicasUnavailableReason = "MinimumReplicasUnavailable"
+
+	// VirtualMachineDeploymentRolloutInProgressReason documents that replicas
+	// are still being updated to the latest template.
+	VirtualMachineDeploymentRolloutInProgressReason = "RolloutInProgress"
+
+	// VirtualMachineDeploymentRolloutPausedReason documents that the rollout
+	// of the latest template is paused.
+	VirtualMachineDeploymentRolloutPausedReason = "RolloutPaused"
+
+	// VirtualMachineDeploymentSelectorMismatchReason documents that the
+	// labels of the VirtualMachineDeployment's template do not match its
+	// selector.
+	VirtualMachineDeploymentSelectorMismatchReason = "SelectorMismatch"
+)
+
+// VirtualMachineDeploymentRollingUpdate controls the pace of a rolling
+// update.
+type VirtualMachineDeploymentRollingUpdate struct {
+	// MaxSurge is the maximum number of replicas that can be created over the
+	// desired number of replicas during an update. The value can be a number
+	// or a percentage of the desired replicas, rounded up. Defaults to 25%.
+	//
+	// +optional
+	MaxSurge *intstr.IntOrString `json:"maxSurge,omitempty"`
+
+	// MaxUnavailable is the maximum number of replicas that can be unavailable
+	// during an update. The value can be a number or a percentage of the
+	// desired replicas, rounded down. Defaults to 25%. When both MaxSurge and
+	// MaxUnavailable are zero, MaxUnavailable is one.
+	//
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// VirtualMachineDeploymentStrategy describes how the replicas are replaced
+// when the template changes.
+type VirtualMachineDeploymentStrategy struct {
+	// RollingUpdate controls the pace of the rolling update.
+	//
+	// +optional
+	RollingUpdate *VirtualMachineDeploymentRollingUpdate `json:"rollingUpdate,omitempty"`
+}
+
+// VirtualMachineDeploymentRollback describes a rollback of a
+// VirtualMachineDeployment.
+type VirtualMachineDeploymentRollback struct {
+	// Revision is the revision to roll back to. When zero, the deployment is
+	// rolled back to the previous revision.
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	Revision int64 `json:"revision,omitempty"`
+}
+
+// VirtualMachineDeploymentSpec defines the desired state of a
+// VirtualMachineDeployment.
+type VirtualMachineDeploymentSpec struct {
+	// Replicas is the number of desired VirtualMachines.
+	//
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=0
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Selector is a label query over the VirtualMachines that are members of
+	// the deployment. It must match the labels of the template.
+	Selector *metav1.LabelSelector `json:"selector"`
+
+	// Template describes the VirtualMachines of the deployment. Changing the
+	// template creates a new revision that replaces the existing replicas
+	// according to the Strategy.
+	Template VirtualMachineTemplateSpec `json:"template"`
+
+	// ClusterModuleGroupName is the name of the cluster module group, from the
+	// VirtualMachineSetResourcePolicy referenced by the template, that the
+	// replicas are placed in. When omitted, the first cluster module group of
+	// the resource policy is used.
+	//
+	// +optional
+	ClusterModuleGroupName string `json:"clusterModuleGroupName,omitempty"`
+
+	// Strategy describes how the replicas are replaced when the template
+	// changes.
+	//
+	// +optional
+	Strategy VirtualMachineDeploymentStrategy `json:"strategy,omitempty"`
+
+	// RevisionHistoryLimit is the number of old VirtualMachineReplicaSets
+	// retained to allow a rollback.
+	//
+	// +optional
+	// +kubebuilder:default=10
+	// +kubebuilder:validation:Minimum=0
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+
+	// Paused indicates that the rollout of the template is paused. The
+	// replicas are not updated while the deployment is paused.
+	//
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// RollbackTo is the revision the template is rolled back to. The field is
+	// cleared once the template has been rolled back.
+	//
+	// +optional
+	RollbackTo *VirtualMachineDeploymentRollback `json:"rollbackTo,omitempty"`
+}
+
+// VirtualMachineDeploymentStatus defines the observed state of a
+// VirtualMachineDeployment.
+type VirtualMachineDeploymentStatus struct {
+	// Replicas is the number of VirtualMachines of the deployment, across all
+	// revisions.
+	//
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// UpdatedReplicas is the number of VirtualMachines created from the
+	// latest template.
+	//
+	// +optional
+	UpdatedReplicas int32 `json:"updatedReplicas,omitempty"`
+
+	// ReadyReplicas is the number of VirtualMachines of the deployment whose
+	// Ready condition is true.
+	//
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// UnavailableReplicas is the number of desired VirtualMachines that are
+	// not ready.
+	//
+	// +optional
+	UnavailableReplicas int32 `json:"unavailableReplicas,omitempty"`
+
+	// Revision is the revision of the latest template.
+	//
+	// +optional
+	Revision int64 `json:"revision,omitempty"`
+
+	// Selector is the string form of the spec's label selector, used by the
+	// scale subresource.
+	//
+	// +optional
+	Selector string `json:"selector,omitempty"`
+
+	// ObservedGeneration is the most recent generation observed by the
+	// controller.
+	//
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions is a list of the latest, available observations of the
+	// deployment's current state.
+	//
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+func (d *VirtualMachineDeployment) GetConditions() Conditions {
+	return d.Status.Conditions
+}
+
+func (d *VirtualMachineDeployment) SetConditions(conditions Conditions) {
+	d.Status.Conditions = conditions
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Namespaced,shortName=vmdeploy
+// +kubebuilder:storageversion
+// +kubebuilder:subresource:status
+// +kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas,selectorpath=.status.selector
+// +kubebuilder:printcolumn:name="Desired",type="integer",JSONPath=".spec.replicas"
+// +kubebuilder:printcolumn:name="Current",type="integer",JSONPath=".status.replicas"
+// +kubebuilder:printcolumn:name="Updated",type="integer",JSONPath=".status.updatedReplicas"
+// +kubebuilder:printcolumn:name="Ready",type="integer",JSONPath=".status.readyReplicas"
+// +kubebuilder:printcolumn:name="Revision",type="integer",priority=1,JSONPath=".status.revision"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// VirtualMachineDeployment is the Schema for the virtualmachinedeployments
+// API. A VirtualMachineDeployment manages VirtualMachineReplicaSets to roll
+// out changes to the template of its VirtualMachines.
+type VirtualMachineDeployment struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VirtualMachineDeploymentSpec   `json:"spec,omitempty"`
+	Status VirtualMachineDeploymentStatus `json:"status,omitempty"`
+}
+
+func (d *VirtualMachineDeployment) NamespacedName() string {
+	return d.Namespace + "/" + d.Name
+}
+
+// +kubebuilder:object:root=true
+
+// VirtualMachineDeploymentList contains a list of VirtualMachineDeployment.
+type VirtualMachineDeploymentList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VirtualMachineDeployment `json:"items"`
+}
+
+func init() {
+	RegisterTypeWithScheme(&VirtualMachineDeployment{}, &VirtualMachineDeploymentList{})
+}
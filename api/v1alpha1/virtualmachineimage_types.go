@@ -5,6 +5,7 @@ package v1alpha1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -70,7 +71,8 @@ type OvfProperty struct {
 
 // VirtualMachineImageSpec defines the desired state of VirtualMachineImage.
 type VirtualMachineImageSpec struct {
-	// Type describes the type of the VirtualMachineImage. Currently, the only supported image is "OVF"
+	// Type describes the type of the VirtualMachineImage, either an "OVF" image that VirtualMachines are deployed
+	// from, or an "ISO" image that backs the CD-ROMs of VirtualMachines.
 	Type string `json:"type"`
 
 	// ImageSourceType describes the type of content source of the VirtualMachineImage.  The only Content Source
@@ -142,6 +144,11 @@ type VirtualMachineImageStatus struct {
 	// eg: bios, efi.
 	// +optional
 	Firmware string `json:"firmware,omitempty"`
+
+	// BootDiskCapacity describes the capacity of the boot disk of this VirtualMachineImage, that is the first disk
+	// of its OVF descriptor. A VirtualMachine deployed from this image cannot have a smaller boot disk.
+	// +optional
+	BootDiskCapacity *resource.Quantity `json:"bootDiskCapacity,omitempty"`
 }
 
 func (vmImage *VirtualMachineImage) GetConditions() Conditions {
@@ -0,0 +1,178 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// VirtualMachineReplicaSetReplicasReconciledCondition is the Type for a
+	// VirtualMachineReplicaSet resource's status condition.
+	//
+	// The condition's status is set to true when the VirtualMachines of the
+	// replica set have been created or deleted to match the desired number of
+	// replicas.
+	VirtualMachineReplicaSetReplicasReconciledCondition = "ReplicasReconciled"
+)
+
+// Condition.Reason for Conditions related to VirtualMachineReplicaSet.
+const (
+	// VirtualMachineReplicaSetSelectorMismatchReason documents that the
+	// labels of the VirtualMachineReplicaSet's template do not match its
+	// selector.
+	VirtualMachineReplicaSetSelectorMismatchReason = "SelectorMismatch"
+
+	// VirtualMachineReplicaSetClusterModuleNotFoundReason documents that the
+	// cluster module group the replicas are placed in doesn't exist in the
+	// VirtualMachineSetResourcePolicy of the template.
+	VirtualMachineReplicaSetClusterModuleNotFoundReason = "ClusterModuleNotFound"
+
+	// VirtualMachineReplicaSetCreateFailedReason documents that creating a
+	// replica failed.
+	VirtualMachineReplicaSetCreateFailedReason = "ReplicaCreateFailed"
+
+	// VirtualMachineReplicaSetDeleteFailedReason documents that deleting a
+	// replica failed.
+	VirtualMachineReplicaSetDeleteFailedReason = "ReplicaDeleteFailed"
+)
+
+// VirtualMachineTemplateObjectMeta is the metadata applied to the
+// VirtualMachines created from a template.
+type VirtualMachineTemplateObjectMeta struct {
+	// Labels is a map of string keys and values applied to the
+	// VirtualMachines.
+	//
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations is a map of string keys and values applied to the
+	// VirtualMachines.
+	//
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// VirtualMachineTemplateSpec describes the VirtualMachines created from a
+// template.
+type VirtualMachineTemplateSpec struct {
+	// Metadata of the VirtualMachines created from the template.
+	//
+	// +optional
+	VirtualMachineTemplateObjectMeta `json:"metadata,omitempty"`
+
+	// Spec is the specification of the VirtualMachines created from the
+	// template.
+	//
+	// +optional
+	Spec VirtualMachineSpec `json:"spec,omitempty"`
+}
+
+// VirtualMachineReplicaSetSpec defines the desired state of a
+// VirtualMachineReplicaSet.
+type VirtualMachineReplicaSetSpec struct {
+	// Replicas is the number of desired VirtualMachines.
+	//
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=0
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Selector is a label query over the VirtualMachines that are members of
+	// the replica set. It must match the labels of the template.
+	Selector *metav1.LabelSelector `json:"selector"`
+
+	// Template describes the VirtualMachines created when there are not
+	// enough replicas. Changes to the template are not applied to the existing
+	// replicas.
+	Template VirtualMachineTemplateSpec `json:"template"`
+
+	// ClusterModuleGroupName is the name of the cluster module group, from the
+	// VirtualMachineSetResourcePolicy referenced by the template, that the
+	// replicas are placed in so they are kept on separate hosts. When omitted,
+	// the first cluster module group of the resource policy is used.
+	//
+	// +optional
+	ClusterModuleGroupName string `json:"clusterModuleGroupName,omitempty"`
+}
+
+// VirtualMachineReplicaSetStatus defines the observed state of a
+// VirtualMachineReplicaSet.
+type VirtualMachineReplicaSetStatus struct {
+	// Replicas is the number of VirtualMachines of the replica set that are
+	// not being deleted.
+	//
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// ReadyReplicas is the number of VirtualMachines of the replica set whose
+	// Ready condition is true.
+	//
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// Selector is the string form of the spec's label selector, used by the
+	// scale subresource.
+	//
+	// +optional
+	Selector string `json:"selector,omitempty"`
+
+	// ObservedGeneration is the most recent generation observed by the
+	// controller.
+	//
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions is a list of the latest, available observations of the
+	// replica set's current state.
+	//
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+func (rs *VirtualMachineReplicaSet) GetConditions() Conditions {
+	return rs.Status.Conditions
+}
+
+func (rs *VirtualMachineReplicaSet) SetConditions(conditions Conditions) {
+	rs.Status.Conditions = conditions
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Namespaced,shortName=vmrs
+// +kubebuilder:storageversion
+// +kubebuilder:subresource:status
+// +kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas,selectorpath=.status.selector
+// +kubebuilder:printcolumn:name="Desired",type="integer",JSONPath=".spec.replicas"
+// +kubebuilder:printcolumn:name="Current",type="integer",JSONPath=".status.replicas"
+// +kubebuilder:printcolumn:name="Ready",type="integer",JSONPath=".status.readyReplicas"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// VirtualMachineReplicaSet is the Schema for the virtualmachinereplicasets
+// API. A VirtualMachineReplicaSet maintains a stable number of identical
+// VirtualMachines created from a template.
+type VirtualMachineReplicaSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VirtualMachineReplicaSetSpec   `json:"spec,omitempty"`
+	Status VirtualMachineReplicaSetStatus `json:"status,omitempty"`
+}
+
+func (rs *VirtualMachineReplicaSet) NamespacedName() string {
+	return rs.Namespace + "/" + rs.Name
+}
+
+// +kubebuilder:object:root=true
+
+// VirtualMachineReplicaSetList contains a list of VirtualMachineReplicaSet.
+type VirtualMachineReplicaSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VirtualMachineReplicaSet `json:"items"`
+}
+
+func init() {
+	RegisterTypeWithScheme(&VirtualMachineReplicaSet{}, &VirtualMachineReplicaSetList{})
+}
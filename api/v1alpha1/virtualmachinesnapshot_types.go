@@ -0,0 +1,139 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// VirtualMachineSnapshotConditionCreated is the Type for a
+	// VirtualMachineSnapshot resource's status condition.
+	//
+	// The condition's status is set to true only when the snapshot has been
+	// taken on the underlying infrastructure provider.
+	VirtualMachineSnapshotConditionCreated = "SnapshotCreated"
+)
+
+// Condition.Reason for Conditions related to VirtualMachineSnapshot.
+const (
+	// VirtualMachineSnapshotSourceNotFoundReason documents that the
+	// VirtualMachine referenced by the VirtualMachineSnapshot doesn't exist.
+	VirtualMachineSnapshotSourceNotFoundReason = "SourceVirtualMachineNotFound"
+
+	// VirtualMachineSnapshotSourceNotCreatedReason documents that the
+	// VirtualMachine referenced by the VirtualMachineSnapshot hasn't been
+	// created on the infrastructure provider yet.
+	VirtualMachineSnapshotSourceNotCreatedReason = "SourceVirtualMachineNotCreated"
+
+	// VirtualMachineSnapshotCreateFailedReason documents that taking the
+	// snapshot on the infrastructure provider failed.
+	VirtualMachineSnapshotCreateFailedReason = "SnapshotCreateFailed"
+)
+
+// VirtualMachineSnapshotSpec defines the desired state of a
+// VirtualMachineSnapshot.
+type VirtualMachineSnapshotSpec struct {
+	// VirtualMachineName is the name of the VirtualMachine, in the same
+	// namespace as the VirtualMachineSnapshot, of which the snapshot is taken.
+	VirtualMachineName string `json:"virtualMachineName"`
+
+	// Description is a description to assign to the snapshot.
+	//
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// Memory specifies whether the VirtualMachine's memory is included in the
+	// snapshot. This is only applicable when the VirtualMachine is powered on
+	// at the time the snapshot is taken. Reverting to a snapshot that includes
+	// memory restores the VirtualMachine to a powered on state.
+	//
+	// +optional
+	Memory bool `json:"memory,omitempty"`
+
+	// Quiesce specifies whether the guest file system is quiesced prior to
+	// taking the snapshot. Quiescing requires VMware Tools to be running in the
+	// guest and is only applicable when Memory is false.
+	//
+	// +optional
+	Quiesce bool `json:"quiesce,omitempty"`
+}
+
+// VirtualMachineSnapshotStatus defines the observed state of a
+// VirtualMachineSnapshot.
+type VirtualMachineSnapshotStatus struct {
+	// SnapshotID is the managed object ID of the snapshot on vSphere.
+	//
+	// +optional
+	SnapshotID string `json:"snapshotID,omitempty"`
+
+	// CreationTime is the time the snapshot was taken.
+	//
+	// +optional
+	CreationTime metav1.Time `json:"creationTime,omitempty"`
+
+	// Size is the amount of storage consumed by the snapshot, including the
+	// memory image when Memory is true.
+	//
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+
+	// Ready is set to true when the snapshot has been taken and may be used
+	// to revert the VirtualMachine.
+	//
+	// +optional
+	Ready bool `json:"ready,omitempty"`
+
+	// Conditions is a list of the latest, available observations of the
+	// snapshot's current state.
+	//
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+func (vmSnapshot *VirtualMachineSnapshot) GetConditions() Conditions {
+	return vmSnapshot.Status.Conditions
+}
+
+func (vmSnapshot *VirtualMachineSnapshot) SetConditions(conditions Conditions) {
+	vmSnapshot.Status.Conditions = conditions
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Namespaced,shortName=vmsnapshot
+// +kubebuilder:storageversion
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="VirtualMachine",type="string",JSONPath=".spec.virtualMachineName"
+// +kubebuilder:printcolumn:name="Ready",type="boolean",JSONPath=".status.ready"
+// +kubebuilder:printcolumn:name="Size",type="string",priority=1,JSONPath=".status.size"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// VirtualMachineSnapshot is the Schema for the virtualmachinesnapshots API.
+// A VirtualMachineSnapshot represents a point-in-time snapshot of a
+// VirtualMachine that the VirtualMachine may later be reverted to.
+type VirtualMachineSnapshot struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VirtualMachineSnapshotSpec   `json:"spec,omitempty"`
+	Status VirtualMachineSnapshotStatus `json:"status,omitempty"`
+}
+
+func (vmSnapshot *VirtualMachineSnapshot) NamespacedName() string {
+	return vmSnapshot.Namespace + "/" + vmSnapshot.Name
+}
+
+// +kubebuilder:object:root=true
+
+// VirtualMachineSnapshotList contains a list of VirtualMachineSnapshot.
+type VirtualMachineSnapshotList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VirtualMachineSnapshot `json:"items"`
+}
+
+func init() {
+	RegisterTypeWithScheme(&VirtualMachineSnapshot{}, &VirtualMachineSnapshotList{})
+}
@@ -1,4 +1,4 @@
-// Copyright (c) 2022 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2022-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package v1alpha1
@@ -10,10 +10,26 @@ type NetworkDeviceStatus struct {
 	// +optional
 	Gateway4 string
 
-	// IpAddresses represents one or more IP addresses assigned to the network
-	// device in CIDR notation, ex. "192.0.2.1/16".
+	// Gateway6 is the gateway for the IPv6 address family for this device.
+	// +optional
+	Gateway6 string
+
+	// IpAddresses represents one or more IPv4 and IPv6 addresses assigned to
+	// the network device in CIDR notation, ex. "192.0.2.1/16" or "2001:db8::1/64".
 	// +optional
 	IPAddresses []string
+
+	// MacAddress is the MAC address of the network device.
+	// +optional
+	MacAddress string
+
+	// MTU is the maximum transmission unit of the network device.
+	// +optional
+	MTU int64
+
+	// Routes describes the static routes of the network device.
+	// +optional
+	Routes []VirtualMachineNetworkRoute
 }
 
 // NetworkStatus describes the observed state of the VM's network configuration.
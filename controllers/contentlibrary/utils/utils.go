@@ -64,3 +64,9 @@ func GetVMImageSpecStatus(ctx context.Context, ctrlClient client.Client, imageNa
 
 	return spec, status, err
 }
+
+// IsISOImage returns true if the given VirtualMachineImage Spec describes an ISO image. The type of an image is
+// either the type of its ContentLibraryItem, e.g. "Iso", or the type of its content library item, e.g. "iso".
+func IsISOImage(spec *vmopv1a1.VirtualMachineImageSpec) bool {
+	return strings.EqualFold(spec.Type, string(imgregv1a1.ContentLibraryItemTypeIso))
+}
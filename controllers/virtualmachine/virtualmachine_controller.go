@@ -1,4 +1,4 @@
-// Copyright (c) 2019-2022 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2019-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package virtualmachine
@@ -11,12 +11,16 @@ import (
 	"time"
 
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlbuilder "sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
@@ -25,6 +29,7 @@ import (
 
 	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
 
+	"github.com/vmware-tanzu/vm-operator/pkg/conditions"
 	"github.com/vmware-tanzu/vm-operator/pkg/context"
 	"github.com/vmware-tanzu/vm-operator/pkg/lib"
 	"github.com/vmware-tanzu/vm-operator/pkg/metrics"
@@ -58,13 +63,19 @@ func AddToManager(ctx *context.ControllerManagerContext, mgr manager.Manager) er
 		ctx.VMProvider,
 		proberManager,
 		ctx.MaxConcurrentReconciles/(100/lib.MaxConcurrentCreateVMsOnProvider()),
+		maxInstantCloneThreads(ctx.MaxConcurrentReconciles),
 	)
 
 	builder := ctrl.NewControllerManagedBy(mgr).
 		For(controlledType).
 		WithOptions(controller.Options{MaxConcurrentReconciles: ctx.MaxConcurrentReconciles}).
 		Watches(&source.Kind{Type: &vmopv1alpha1.VirtualMachineClassBinding{}},
-			handler.EnqueueRequestsFromMapFunc(classBindingToVMMapperFn(ctx, r.Client)))
+			handler.EnqueueRequestsFromMapFunc(classBindingToVMMapperFn(ctx, r.Client))).
+		Watches(&source.Kind{Type: &vmopv1alpha1.VirtualMachineClass{}},
+			handler.EnqueueRequestsFromMapFunc(classToVMMapperFn(ctx, r.Client)),
+			ctrlbuilder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&source.Kind{Type: &vmopv1alpha1.VirtualMachineSnapshot{}},
+			handler.EnqueueRequestsFromMapFunc(snapshotToVMMapperFn(ctx)))
 
 	if !lib.IsWCPVMImageRegistryEnabled() {
 		builder = builder.Watches(&source.Kind{Type: &vmopv1alpha1.ContentSourceBinding{}},
@@ -165,39 +176,97 @@ func classBindingToVMMapperFn(ctx *context.ControllerManagerContext, c client.Cl
 	}
 }
 
+// snapshotToVMMapperFn returns a mapper function that can be used to queue a reconcile request
+// for the VirtualMachine of a VirtualMachineSnapshot, so that a revert to the snapshot that is
+// waiting for the snapshot to be ready is retried.
+func snapshotToVMMapperFn(ctx *context.ControllerManagerContext) func(o client.Object) []reconcile.Request {
+	return func(o client.Object) []reconcile.Request {
+		vmSnapshot := o.(*vmopv1alpha1.VirtualMachineSnapshot)
+		if vmSnapshot.Spec.VirtualMachineName == "" {
+			return nil
+		}
+
+		key := client.ObjectKey{Namespace: vmSnapshot.Namespace, Name: vmSnapshot.Spec.VirtualMachineName}
+		ctx.Logger.V(4).Info("Returning VM reconcile request due to VirtualMachineSnapshot watch",
+			"name", vmSnapshot.Name, "namespace", vmSnapshot.Namespace, "vm", key)
+		return []reconcile.Request{{NamespacedName: key}}
+	}
+}
+
+// classToVMMapperFn returns a mapper function that can be used to queue reconcile request
+// for the VirtualMachines in response to a change to the spec of a VirtualMachineClass, so
+// that the VirtualMachines using the class are resized.
+func classToVMMapperFn(ctx *context.ControllerManagerContext, c client.Client) func(o client.Object) []reconcile.Request {
+	return func(o client.Object) []reconcile.Request {
+		class := o.(*vmopv1alpha1.VirtualMachineClass)
+		logger := ctx.Logger.WithValues("name", class.Name)
+
+		logger.V(4).Info("Reconciling all VMs referencing a VM class because of a VirtualMachineClass watch")
+
+		vmList := &vmopv1alpha1.VirtualMachineList{}
+		if err := c.List(ctx, vmList); err != nil {
+			logger.Error(err, "Failed to list VirtualMachines for reconciliation due to VirtualMachineClass watch")
+			return nil
+		}
+
+		var reconcileRequests []reconcile.Request
+		for _, vm := range vmList.Items {
+			if vm.Spec.ClassName == class.Name {
+				key := client.ObjectKey{Namespace: vm.Namespace, Name: vm.Name}
+				reconcileRequests = append(reconcileRequests, reconcile.Request{NamespacedName: key})
+			}
+		}
+
+		logger.V(4).Info("Returning VM reconcile requests due to VirtualMachineClass watch", "requests", reconcileRequests)
+		return reconcileRequests
+	}
+}
+
 func NewReconciler(
 	client client.Client,
 	logger logr.Logger,
 	recorder record.Recorder,
 	vmProvider vmprovider.VirtualMachineProviderInterface,
 	prober prober.Manager,
-	maxDeployThreads int) *Reconciler {
+	maxDeployThreads int,
+	maxInstantCloneThreads int) *Reconciler {
 
 	return &Reconciler{
-		Client:           client,
-		Logger:           logger,
-		Recorder:         recorder,
-		VMProvider:       vmProvider,
-		Prober:           prober,
-		vmMetrics:        metrics.NewVMMetrics(),
-		maxDeployThreads: maxDeployThreads,
+		Client:                 client,
+		Logger:                 logger,
+		Recorder:               recorder,
+		VMProvider:             vmProvider,
+		Prober:                 prober,
+		vmMetrics:              metrics.NewVMMetrics(),
+		maxDeployThreads:       maxDeployThreads,
+		maxInstantCloneThreads: maxInstantCloneThreads,
+	}
+}
+
+// maxInstantCloneThreads returns the number of reconciler threads that can be used to instant clone VMs.
+func maxInstantCloneThreads(maxConcurrentReconciles int) int {
+	threads := maxConcurrentReconciles * lib.MaxConcurrentInstantCloneVMsOnProvider() / 100
+	if threads < 1 {
+		threads = 1
 	}
+	return threads
 }
 
 // Reconciler reconciles a VirtualMachine object.
 type Reconciler struct {
 	client.Client
-	Logger           logr.Logger
-	Recorder         record.Recorder
-	VMProvider       vmprovider.VirtualMachineProviderInterface
-	Prober           prober.Manager
-	vmMetrics        *metrics.VMMetrics
-	maxDeployThreads int
+	Logger                 logr.Logger
+	Recorder               record.Recorder
+	VMProvider             vmprovider.VirtualMachineProviderInterface
+	Prober                 prober.Manager
+	vmMetrics              *metrics.VMMetrics
+	maxDeployThreads       int
+	maxInstantCloneThreads int
 }
 
 // +kubebuilder:rbac:groups=vmoperator.vmware.com,resources=virtualmachines,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=vmoperator.vmware.com,resources=virtualmachines/status,verbs=get;update;patch
-// +kubebuilder:rbac:groups=vmoperator.vmware.com,resources=virtualmachineclasses,verbs=get;list
+// +kubebuilder:rbac:groups=vmoperator.vmware.com,resources=virtualmachineclasses,verbs=get;list;watch
 // +kubebuilder:rbac:groups=vmware.com,resources=virtualnetworkinterfaces;virtualnetworkinterfaces/status,verbs=create;get;list;patch;delete;watch;update
 // +kubebuilder:rbac:groups=storage.k8s.io,resources=storageclasses,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=events;configmaps,verbs=get;list;watch;create;update;patch;delete
@@ -206,6 +275,7 @@ type Reconciler struct {
 // +kubebuilder:rbac:groups=vmoperator.vmware.com,resources=contentsources,verbs=get;list;watch
 // +kubebuilder:rbac:groups=vmoperator.vmware.com,resources=contentlibraryproviders,verbs=get;list;watch
 // +kubebuilder:rbac:groups=vmoperator.vmware.com,resources=contentsourcebindings,verbs=get;list;watch
+// +kubebuilder:rbac:groups=vmoperator.vmware.com,resources=virtualmachinesnapshots,verbs=get;list;watch
 
 func (r *Reconciler) Reconcile(ctx goctx.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
 	vm := &vmopv1alpha1.VirtualMachine{}
@@ -214,9 +284,11 @@ func (r *Reconciler) Reconcile(ctx goctx.Context, req ctrl.Request) (_ ctrl.Resu
 	}
 
 	vmCtx := &context.VirtualMachineContext{
-		Context: goctx.WithValue(ctx, context.MaxDeployThreadsContextKey, r.maxDeployThreads),
-		Logger:  ctrl.Log.WithName("VirtualMachine").WithValues("name", vm.NamespacedName()),
-		VM:      vm,
+		Context: goctx.WithValue(
+			goctx.WithValue(ctx, context.MaxDeployThreadsContextKey, r.maxDeployThreads),
+			context.MaxInstantCloneThreadsContextKey, r.maxInstantCloneThreads),
+		Logger: ctrl.Log.WithName("VirtualMachine").WithValues("name", vm.NamespacedName()),
+		VM:     vm,
 	}
 
 	// If the VM has a pause reconcile annotation, it is being restored on vCenter. Return here so our reconcile
@@ -243,8 +315,10 @@ func (r *Reconciler) Reconcile(ctx goctx.Context, req ctrl.Request) (_ ctrl.Resu
 	}()
 
 	if !vm.DeletionTimestamp.IsZero() {
-		err = r.ReconcileDelete(vmCtx)
-		return ctrl.Result{}, err
+		if err := r.ReconcileDelete(vmCtx); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: requeueDelay(vmCtx)}, nil
 	}
 
 	if err := r.ReconcileNormal(vmCtx); err != nil {
@@ -273,6 +347,21 @@ func requeueDelay(ctx *context.VirtualMachineContext) time.Duration {
 		return 10 * time.Second
 	}
 
+	// Check back on the VM until the guest OS has shut down.
+	if conditions.GetReason(ctx.VM, vmopv1alpha1.GuestShutdownCondition) == vmopv1alpha1.GuestShutdownInProgressReason {
+		return 10 * time.Second
+	}
+
+	// Check back on the VM until its storage has been relocated.
+	if conditions.GetReason(ctx.VM, vmopv1alpha1.VirtualMachineStorageRelocatedCondition) == vmopv1alpha1.VirtualMachineStorageRelocationInProgressReason {
+		return 10 * time.Second
+	}
+
+	// Check back on the VM until it has been migrated to another zone.
+	if conditions.GetReason(ctx.VM, vmopv1alpha1.VirtualMachineMigratedCondition) == vmopv1alpha1.VirtualMachineMigratingReason {
+		return 10 * time.Second
+	}
+
 	return 0
 }
 
@@ -282,11 +371,16 @@ func (r *Reconciler) ReconcileDelete(ctx *context.VirtualMachineContext) (reterr
 	if controllerutil.ContainsFinalizer(ctx.VM, finalizerName) {
 		ctx.VM.Status.Phase = vmopv1alpha1.Deleting
 
-		defer func() {
-			r.Recorder.EmitEvent(ctx.VM, "Delete", reterr, false)
-		}()
+		err := r.VMProvider.DeleteVirtualMachine(ctx, ctx.VM)
+		if errors.Is(err, vmprovider.ErrGuestShutdownInProgress) {
+			// The VM is checked back on after the fixed delay of the GuestShutdown condition rather than with
+			// the error backoff, so the VM is powered off once the SoftPowerOffTimeout is reached.
+			ctx.Logger.Info("Waiting for guest OS to shut down prior to deleting VirtualMachine")
+			return nil
+		}
 
-		if err := r.VMProvider.DeleteVirtualMachine(ctx, ctx.VM); err != nil {
+		r.Recorder.EmitEvent(ctx.VM, "Delete", err, false)
+		if err != nil {
 			ctx.Logger.Error(err, "Failed to delete VirtualMachine")
 			return err
 		}
@@ -335,12 +429,28 @@ func (r *Reconciler) ReconcileNormal(ctx *context.VirtualMachineContext) (reterr
 		r.vmMetrics.RegisterVMCreateOrUpdateMetrics(ctx)
 	}()
 
+	if err := r.reconcileRevertToSnapshot(ctx); err != nil {
+		ctx.Logger.Error(err, "Failed to revert VirtualMachine to snapshot")
+		return err
+	}
+
+	powerState := ctx.VM.Status.PowerState
 	if err := r.VMProvider.CreateOrUpdateVirtualMachine(ctx, ctx.VM); err != nil {
 		ctx.Logger.Error(err, "Failed to reconcile VirtualMachine")
 		r.Recorder.EmitEvent(ctx.VM, "CreateOrUpdate", err, false)
 		return err
 	}
 
+	if powerState != "" && powerState != ctx.VM.Status.PowerState {
+		r.Recorder.Eventf(ctx.VM, "PowerStateChanged", "Power state changed from %s to %s",
+			powerState, ctx.VM.Status.PowerState)
+	}
+
+	if err := r.reconcileRestart(ctx); err != nil {
+		ctx.Logger.Error(err, "Failed to restart VirtualMachine")
+		return err
+	}
+
 	ctx.VM.Status.Phase = vmopv1alpha1.Created
 	// Add this VM to prober manager if ReconcileNormal succeeds.
 	r.Prober.AddToProberManager(ctx.VM)
@@ -348,3 +458,121 @@ func (r *Reconciler) ReconcileNormal(ctx *context.VirtualMachineContext) (reterr
 	ctx.Logger.Info("Finished Reconciling VirtualMachine")
 	return nil
 }
+
+// reconcileRevertToSnapshot reverts an existing VM to the snapshot requested in the VM's spec. The
+// revert happens before the VM is updated so the remainder of the spec, like the power state, is
+// applied on top of the reverted VM. A snapshot that cannot be reverted to is reported with the
+// VirtualMachineSnapshotReverted condition, and does not hold up the rest of the reconcile.
+func (r *Reconciler) reconcileRevertToSnapshot(ctx *context.VirtualMachineContext) (reterr error) {
+	snapshotName := ctx.VM.Spec.RevertToSnapshot
+	if snapshotName == "" || ctx.VM.Status.UniqueID == "" {
+		return nil
+	}
+
+	vmSnapshot := &vmopv1alpha1.VirtualMachineSnapshot{}
+	if err := r.Get(ctx, client.ObjectKey{Name: snapshotName, Namespace: ctx.VM.Namespace}, vmSnapshot); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to get VirtualMachineSnapshot %s", snapshotName)
+		}
+		conditions.MarkFalse(ctx.VM,
+			vmopv1alpha1.VirtualMachineSnapshotRevertedCondition,
+			vmopv1alpha1.VirtualMachineSnapshotNotFoundReason,
+			vmopv1alpha1.ConditionSeverityError,
+			"VirtualMachineSnapshot %s does not exist", snapshotName)
+		return nil
+	}
+
+	if vmSnapshot.Spec.VirtualMachineName != ctx.VM.Name {
+		conditions.MarkFalse(ctx.VM,
+			vmopv1alpha1.VirtualMachineSnapshotRevertedCondition,
+			vmopv1alpha1.VirtualMachineSnapshotOfOtherVMReason,
+			vmopv1alpha1.ConditionSeverityError,
+			"VirtualMachineSnapshot %s is not a snapshot of this VM", snapshotName)
+		return nil
+	}
+	if !vmSnapshot.Status.Ready {
+		// The VM is reconciled again once the snapshot is updated.
+		conditions.MarkFalse(ctx.VM,
+			vmopv1alpha1.VirtualMachineSnapshotRevertedCondition,
+			vmopv1alpha1.VirtualMachineSnapshotNotReadyReason,
+			vmopv1alpha1.ConditionSeverityInfo,
+			"VirtualMachineSnapshot %s is not ready", snapshotName)
+		return nil
+	}
+
+	defer func() {
+		r.Recorder.EmitEvent(ctx.VM, "RevertToSnapshot", reterr, false)
+	}()
+
+	if err := r.VMProvider.RevertToSnapshot(ctx, ctx.VM, vmSnapshot); err != nil {
+		conditions.MarkFalse(ctx.VM,
+			vmopv1alpha1.VirtualMachineSnapshotRevertedCondition,
+			vmopv1alpha1.VirtualMachineSnapshotRevertFailedReason,
+			vmopv1alpha1.ConditionSeverityError,
+			err.Error())
+		return err
+	}
+
+	conditions.MarkTrue(ctx.VM, vmopv1alpha1.VirtualMachineSnapshotRevertedCondition)
+	ctx.VM.Status.CurrentSnapshot = snapshotName
+	ctx.VM.Spec.RevertToSnapshot = ""
+	ctx.Logger.Info("Reverted VirtualMachine to snapshot", "snapshot", snapshotName)
+	return nil
+}
+
+// reconcileRestart restarts the VM once for each spec.nextRestartTime later than the last restart. The
+// restart happens after the VM is updated so a restart requested along with a power on is honored.
+func (r *Reconciler) reconcileRestart(ctx *context.VirtualMachineContext) (reterr error) {
+	nextRestartTime := ctx.VM.Spec.NextRestartTime
+	if nextRestartTime == "" {
+		return nil
+	}
+
+	restartTime, err := time.Parse(time.RFC3339, nextRestartTime)
+	if err != nil {
+		return errors.Wrapf(err, "invalid nextRestartTime %q", nextRestartTime)
+	}
+	// The status time is only stored with second precision.
+	restartTime = restartTime.Truncate(time.Second)
+
+	if last := ctx.VM.Status.LastRestartTime; last != nil && !restartTime.After(last.Time) {
+		return nil
+	}
+
+	if ctx.VM.Status.PowerState != vmopv1alpha1.VirtualMachinePoweredOn {
+		ctx.Logger.Info("Ignoring restart of VirtualMachine that is not powered on",
+			"nextRestartTime", nextRestartTime, "powerState", ctx.VM.Status.PowerState)
+		ctx.VM.Status.LastRestartTime = &metav1.Time{Time: restartTime}
+		return nil
+	}
+
+	// Record the restart before it is done so the VM is not restarted again when the status cannot be
+	// patched afterwards. A restart that fails is reported with an event and is not retried.
+	vmPatch := client.MergeFrom(ctx.VM.DeepCopy())
+	ctx.VM.Status.LastRestartTime = &metav1.Time{Time: restartTime}
+	if err := r.Status().Patch(ctx, ctx.VM, vmPatch); err != nil {
+		return errors.Wrapf(err, "failed to record the restart")
+	}
+
+	defer func() {
+		r.Recorder.EmitEvent(ctx.VM, "Restart", reterr, false)
+	}()
+
+	switch ctx.VM.Spec.RestartMode {
+	case vmopv1alpha1.VirtualMachineRestartModeSoft:
+		err = r.VMProvider.RebootGuestVirtualMachine(ctx, ctx.VM)
+	case vmopv1alpha1.VirtualMachineRestartModeTrySoft:
+		if err = r.VMProvider.RebootGuestVirtualMachine(ctx, ctx.VM); err != nil {
+			ctx.Logger.Info("Failed to restart guest OS, resetting VirtualMachine", "error", err.Error())
+			err = r.VMProvider.RestartVirtualMachine(ctx, ctx.VM, false)
+		}
+	default:
+		err = r.VMProvider.RestartVirtualMachine(ctx, ctx.VM, false)
+	}
+	if err != nil {
+		return err
+	}
+
+	ctx.Logger.Info("Restarted VirtualMachine", "nextRestartTime", nextRestartTime, "mode", ctx.VM.Spec.RestartMode)
+	return nil
+}
@@ -1,4 +1,4 @@
-// Copyright (c) 2019-2021 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2019-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package virtualmachine_test
@@ -7,6 +7,7 @@ import (
 	"context"
 	"errors"
 	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -17,8 +18,10 @@ import (
 	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
 
 	"github.com/vmware-tanzu/vm-operator/controllers/virtualmachine"
+	"github.com/vmware-tanzu/vm-operator/pkg/conditions"
 	vmopContext "github.com/vmware-tanzu/vm-operator/pkg/context"
 	proberfake "github.com/vmware-tanzu/vm-operator/pkg/prober/fake"
+	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider"
 	providerfake "github.com/vmware-tanzu/vm-operator/pkg/vmprovider/fake"
 	"github.com/vmware-tanzu/vm-operator/test/builder"
 )
@@ -71,6 +74,7 @@ func unitTestsReconcile() {
 			ctx.VMProvider,
 			fakeProbeManagerIf,
 			16,
+			16,
 		)
 		fakeVMProvider = ctx.VMProvider.(*providerfake.VMProvider)
 		fakeProbeManager = fakeProbeManagerIf.(*proberfake.ProberManager)
@@ -154,6 +158,233 @@ func unitTestsReconcile() {
 			Expect(reconciler.ReconcileNormal(vmCtx)).Should(Succeed())
 			Expect(fakeProbeManager.IsAddToProberManagerCalled).Should(BeTrue())
 		})
+
+		When("VM spec requests to revert to a snapshot", func() {
+			var vmSnapshot *vmopv1alpha1.VirtualMachineSnapshot
+
+			BeforeEach(func() {
+				vmSnapshot = &vmopv1alpha1.VirtualMachineSnapshot{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "dummy-snapshot",
+						Namespace: vm.Namespace,
+					},
+					Spec: vmopv1alpha1.VirtualMachineSnapshotSpec{
+						VirtualMachineName: vm.Name,
+					},
+					Status: vmopv1alpha1.VirtualMachineSnapshotStatus{
+						SnapshotID: "snapshot-1",
+						Ready:      true,
+					},
+				}
+				initObjects = append(initObjects, vmSnapshot)
+
+				vm.Status.UniqueID = "dummy-id"
+				vm.Spec.RevertToSnapshot = vmSnapshot.Name
+			})
+
+			It("reverts the VM and clears the request", func() {
+				var revertedTo string
+				fakeVMProvider.RevertToSnapshotFn = func(_ context.Context, _ *vmopv1alpha1.VirtualMachine, s *vmopv1alpha1.VirtualMachineSnapshot) error {
+					revertedTo = s.Status.SnapshotID
+					return nil
+				}
+
+				Expect(reconciler.ReconcileNormal(vmCtx)).To(Succeed())
+				Expect(revertedTo).To(Equal("snapshot-1"))
+				Expect(vmCtx.VM.Spec.RevertToSnapshot).To(BeEmpty())
+				Expect(vmCtx.VM.Status.CurrentSnapshot).To(Equal(vmSnapshot.Name))
+				Expect(conditions.IsTrue(vmCtx.VM, vmopv1alpha1.VirtualMachineSnapshotRevertedCondition)).To(BeTrue())
+				expectEvent(ctx, "RevertToSnapshotSuccess")
+			})
+
+			When("the snapshot is not ready", func() {
+				BeforeEach(func() {
+					vmSnapshot.Status.Ready = false
+				})
+
+				It("sets the condition, does not revert and updates the VM", func() {
+					fakeVMProvider.RevertToSnapshotFn = func(_ context.Context, _ *vmopv1alpha1.VirtualMachine, _ *vmopv1alpha1.VirtualMachineSnapshot) error {
+						Fail("RevertToSnapshot should not be called")
+						return nil
+					}
+
+					Expect(reconciler.ReconcileNormal(vmCtx)).To(Succeed())
+					Expect(vmCtx.VM.Spec.RevertToSnapshot).To(Equal(vmSnapshot.Name))
+					Expect(conditions.GetReason(vmCtx.VM, vmopv1alpha1.VirtualMachineSnapshotRevertedCondition)).
+						To(Equal(vmopv1alpha1.VirtualMachineSnapshotNotReadyReason))
+					Expect(vmCtx.VM.Status.Phase).To(Equal(vmopv1alpha1.Created))
+				})
+			})
+
+			When("the snapshot does not exist", func() {
+				BeforeEach(func() {
+					vm.Spec.RevertToSnapshot = "missing-snapshot"
+				})
+
+				It("sets the condition and updates the VM", func() {
+					Expect(reconciler.ReconcileNormal(vmCtx)).To(Succeed())
+					Expect(conditions.GetReason(vmCtx.VM, vmopv1alpha1.VirtualMachineSnapshotRevertedCondition)).
+						To(Equal(vmopv1alpha1.VirtualMachineSnapshotNotFoundReason))
+					Expect(vmCtx.VM.Status.Phase).To(Equal(vmopv1alpha1.Created))
+				})
+			})
+
+			When("the snapshot is of another VM", func() {
+				BeforeEach(func() {
+					vmSnapshot.Spec.VirtualMachineName = "other-vm"
+				})
+
+				It("sets the condition and updates the VM", func() {
+					Expect(reconciler.ReconcileNormal(vmCtx)).To(Succeed())
+					Expect(conditions.GetReason(vmCtx.VM, vmopv1alpha1.VirtualMachineSnapshotRevertedCondition)).
+						To(Equal(vmopv1alpha1.VirtualMachineSnapshotOfOtherVMReason))
+					Expect(vmCtx.VM.Status.Phase).To(Equal(vmopv1alpha1.Created))
+				})
+			})
+
+			It("emits an event when the provider fails to revert", func() {
+				fakeVMProvider.RevertToSnapshotFn = func(_ context.Context, _ *vmopv1alpha1.VirtualMachine, _ *vmopv1alpha1.VirtualMachineSnapshot) error {
+					return errors.New(providerError)
+				}
+
+				err := reconciler.ReconcileNormal(vmCtx)
+				Expect(err).To(MatchError(providerError))
+				Expect(vmCtx.VM.Spec.RevertToSnapshot).To(Equal(vmSnapshot.Name))
+				Expect(conditions.GetReason(vmCtx.VM, vmopv1alpha1.VirtualMachineSnapshotRevertedCondition)).
+					To(Equal(vmopv1alpha1.VirtualMachineSnapshotRevertFailedReason))
+				expectEvent(ctx, "RevertToSnapshotFailure")
+			})
+		})
+
+		When("VM spec requests a restart", func() {
+			var (
+				restartTime   time.Time
+				resets        int
+				rebootedGuest bool
+			)
+
+			BeforeEach(func() {
+				restartTime = time.Now().UTC().Truncate(time.Second)
+				vm.Spec.NextRestartTime = restartTime.Format(time.RFC3339)
+				vm.Status.PowerState = vmopv1alpha1.VirtualMachinePoweredOn
+				resets = 0
+				rebootedGuest = false
+			})
+
+			JustBeforeEach(func() {
+				fakeVMProvider.RestartVirtualMachineFn = func(_ context.Context, _ *vmopv1alpha1.VirtualMachine, powerCycle bool) error {
+					Expect(powerCycle).To(BeFalse())
+					resets++
+					return nil
+				}
+				fakeVMProvider.RebootGuestVirtualMachineFn = func(_ context.Context, _ *vmopv1alpha1.VirtualMachine) error {
+					rebootedGuest = true
+					return nil
+				}
+			})
+
+			It("resets the VM once", func() {
+				Expect(reconciler.ReconcileNormal(vmCtx)).To(Succeed())
+				Expect(resets).To(Equal(1))
+				Expect(vmCtx.VM.Status.LastRestartTime).ToNot(BeNil())
+				Expect(vmCtx.VM.Status.LastRestartTime.Time).To(BeTemporally("==", restartTime))
+				expectEvent(ctx, "RestartSuccess")
+
+				Expect(reconciler.ReconcileNormal(vmCtx)).To(Succeed())
+				Expect(resets).To(Equal(1))
+			})
+
+			It("records the restart before resetting the VM", func() {
+				fakeVMProvider.RestartVirtualMachineFn = func(_ context.Context, _ *vmopv1alpha1.VirtualMachine, _ bool) error {
+					vmObj := &vmopv1alpha1.VirtualMachine{}
+					Expect(ctx.Client.Get(ctx, client.ObjectKeyFromObject(vm), vmObj)).To(Succeed())
+					Expect(vmObj.Status.LastRestartTime).ToNot(BeNil())
+					Expect(vmObj.Status.LastRestartTime.Time).To(BeTemporally("==", restartTime))
+					resets++
+					return nil
+				}
+
+				Expect(reconciler.ReconcileNormal(vmCtx)).To(Succeed())
+				Expect(resets).To(Equal(1))
+			})
+
+			When("restart mode is soft", func() {
+				BeforeEach(func() {
+					vm.Spec.RestartMode = vmopv1alpha1.VirtualMachineRestartModeSoft
+				})
+
+				It("restarts the guest OS", func() {
+					Expect(reconciler.ReconcileNormal(vmCtx)).To(Succeed())
+					Expect(rebootedGuest).To(BeTrue())
+					Expect(vmCtx.VM.Status.LastRestartTime).ToNot(BeNil())
+				})
+
+				It("returns error and does not retry when the guest OS restart fails", func() {
+					reboots := 0
+					fakeVMProvider.RebootGuestVirtualMachineFn = func(_ context.Context, _ *vmopv1alpha1.VirtualMachine) error {
+						reboots++
+						return errors.New(providerError)
+					}
+
+					Expect(reconciler.ReconcileNormal(vmCtx)).To(MatchError(providerError))
+					Expect(vmCtx.VM.Status.LastRestartTime).ToNot(BeNil())
+					expectEvent(ctx, "RestartFailure")
+
+					Expect(reconciler.ReconcileNormal(vmCtx)).To(Succeed())
+					Expect(reboots).To(Equal(1))
+				})
+			})
+
+			When("restart mode is trySoft and the guest OS restart fails", func() {
+				BeforeEach(func() {
+					vm.Spec.RestartMode = vmopv1alpha1.VirtualMachineRestartModeTrySoft
+				})
+
+				It("resets the VM", func() {
+					fakeVMProvider.RebootGuestVirtualMachineFn = func(_ context.Context, _ *vmopv1alpha1.VirtualMachine) error {
+						return errors.New(providerError)
+					}
+
+					Expect(reconciler.ReconcileNormal(vmCtx)).To(Succeed())
+					Expect(resets).To(Equal(1))
+					Expect(vmCtx.VM.Status.LastRestartTime).ToNot(BeNil())
+				})
+			})
+
+			When("the restart was already honored", func() {
+				BeforeEach(func() {
+					vm.Status.LastRestartTime = &metav1.Time{Time: restartTime}
+				})
+
+				It("does not restart the VM", func() {
+					Expect(reconciler.ReconcileNormal(vmCtx)).To(Succeed())
+					Expect(resets).To(BeZero())
+				})
+			})
+
+			When("the VM is not powered on", func() {
+				BeforeEach(func() {
+					vm.Status.PowerState = vmopv1alpha1.VirtualMachineSuspended
+				})
+
+				It("records the restart without restarting the VM", func() {
+					Expect(reconciler.ReconcileNormal(vmCtx)).To(Succeed())
+					Expect(resets).To(BeZero())
+					Expect(vmCtx.VM.Status.LastRestartTime).ToNot(BeNil())
+				})
+			})
+		})
+
+		It("emits an event when the power state changes", func() {
+			vm.Status.PowerState = vmopv1alpha1.VirtualMachinePoweredOn
+			fakeVMProvider.CreateOrUpdateVirtualMachineFn = func(_ context.Context, vm *vmopv1alpha1.VirtualMachine) error {
+				vm.Status.PowerState = vmopv1alpha1.VirtualMachineSuspended
+				return nil
+			}
+
+			Expect(reconciler.ReconcileNormal(vmCtx)).To(Succeed())
+			expectEvent(ctx, "PowerStateChanged")
+		})
 	})
 
 	Context("ReconcileDelete", func() {
@@ -187,6 +418,19 @@ func unitTestsReconcile() {
 			Expect(vmCtx.VM.Status.Phase).To(Equal(vmopv1alpha1.Deleting))
 		})
 
+		It("will wait without an error or event while the guest OS shuts down", func() {
+			fakeVMProvider.DeleteVirtualMachineFn = func(ctx context.Context, vm *vmopv1alpha1.VirtualMachine) error {
+				return vmprovider.ErrGuestShutdownInProgress
+			}
+			err := reconciler.ReconcileDelete(vmCtx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(ctx.Events).ToNot(Receive())
+			Expect(vmCtx.VM.Status.Phase).To(Equal(vmopv1alpha1.Deleting))
+			Expect(vmCtx.VM.Finalizers).To(ContainElement(finalizer))
+			Expect(fakeProbeManager.IsRemoveFromProberManagerCalled).Should(BeFalse())
+		})
+
 		It("Should not remove from Prober Manager if ReconcileDelete fails", func() {
 			// Simulate delete failure
 			fakeVMProvider.DeleteVirtualMachineFn = func(ctx context.Context, vm *vmopv1alpha1.VirtualMachine) error {
@@ -0,0 +1,614 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package virtualmachinedeployment
+
+import (
+	goctx "context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
+
+	"github.com/vmware-tanzu/vm-operator/pkg/conditions"
+	"github.com/vmware-tanzu/vm-operator/pkg/context"
+	"github.com/vmware-tanzu/vm-operator/pkg/patch"
+	"github.com/vmware-tanzu/vm-operator/pkg/record"
+)
+
+const (
+	defaultRevisionHistoryLimit = 10
+)
+
+var (
+	defaultMaxSurge       = intstr.FromString("25%")
+	defaultMaxUnavailable = intstr.FromString("25%")
+)
+
+// AddToManager adds this package's controller to the provided manager.
+func AddToManager(ctx *context.ControllerManagerContext, mgr manager.Manager) error {
+	var (
+		controlledType     = &vmopv1alpha1.VirtualMachineDeployment{}
+		controlledTypeName = reflect.TypeOf(controlledType).Elem().Name()
+
+		controllerNameShort = fmt.Sprintf("%s-controller", strings.ToLower(controlledTypeName))
+		controllerNameLong  = fmt.Sprintf("%s/%s/%s", ctx.Namespace, ctx.Name, controllerNameShort)
+	)
+
+	r := NewReconciler(
+		mgr.GetClient(),
+		ctrl.Log.WithName("controllers").WithName(controlledTypeName),
+		record.New(mgr.GetEventRecorderFor(controllerNameLong)),
+	)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(controlledType).
+		Owns(&vmopv1alpha1.VirtualMachineReplicaSet{}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: ctx.MaxConcurrentReconciles}).
+		Complete(r)
+}
+
+func NewReconciler(
+	client client.Client,
+	logger logr.Logger,
+	recorder record.Recorder) *Reconciler {
+	return &Reconciler{
+		Client:   client,
+		Logger:   logger,
+		Recorder: recorder,
+	}
+}
+
+// Reconciler reconciles a VirtualMachineDeployment object.
+type Reconciler struct {
+	client.Client
+	Logger   logr.Logger
+	Recorder record.Recorder
+}
+
+// +kubebuilder:rbac:groups=vmoperator.vmware.com,resources=virtualmachinedeployments,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=vmoperator.vmware.com,resources=virtualmachinedeployments/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=vmoperator.vmware.com,resources=virtualmachinereplicasets,verbs=get;list;watch;create;update;patch;delete
+
+func (r *Reconciler) Reconcile(ctx goctx.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
+	deployment := &vmopv1alpha1.VirtualMachineDeployment{}
+	if err := r.Get(ctx, req.NamespacedName, deployment); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	// The replica sets are garbage collected through their owner reference.
+	if !deployment.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	deploymentCtx := &context.VirtualMachineDeploymentContext{
+		Context:    ctx,
+		Logger:     ctrl.Log.WithName("VirtualMachineDeployment").WithValues("name", req.NamespacedName),
+		Deployment: deployment,
+	}
+
+	patchHelper, err := patch.NewHelper(deployment, r.Client)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "failed to init patch helper for %s", deploymentCtx)
+	}
+	defer func() {
+		if err := patchHelper.Patch(ctx, deployment); err != nil {
+			if reterr == nil {
+				reterr = err
+			}
+			deploymentCtx.Logger.Error(err, "patch failed")
+		}
+	}()
+
+	if err := r.ReconcileNormal(deploymentCtx); err != nil {
+		deploymentCtx.Logger.Error(err, "Failed to reconcile VirtualMachineDeployment")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func (r *Reconciler) ReconcileNormal(ctx *context.VirtualMachineDeploymentContext) error {
+	deployment := ctx.Deployment
+	deployment.Status.ObservedGeneration = deployment.Generation
+
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		markNotRolledOut(deployment, vmopv1alpha1.VirtualMachineDeploymentSelectorMismatchReason,
+			vmopv1alpha1.ConditionSeverityError, err.Error())
+		return errors.Wrapf(err, "invalid selector")
+	}
+	deployment.Status.Selector = selector.String()
+
+	if selector.Empty() || !selector.Matches(labels.Set(deployment.Spec.Template.Labels)) {
+		err := fmt.Errorf("selector %q does not match the template labels", selector.String())
+		markNotRolledOut(deployment, vmopv1alpha1.VirtualMachineDeploymentSelectorMismatchReason,
+			vmopv1alpha1.ConditionSeverityError, err.Error())
+		return err
+	}
+
+	replicaSets, err := r.getReplicaSets(ctx)
+	if err != nil {
+		return err
+	}
+
+	if deployment.Spec.RollbackTo != nil {
+		// The template change is patched and the rollout continues on the next reconcile.
+		r.rollback(ctx, replicaSets)
+		return nil
+	}
+
+	hash := templateHash(&deployment.Spec.Template)
+	newRS, oldRSs := splitReplicaSets(replicaSets, hash)
+
+	defer func() {
+		updateDeploymentStatus(deployment, newRS, replicaSets)
+	}()
+
+	if deployment.Spec.Paused {
+		return nil
+	}
+
+	if newRS == nil {
+		newRS, err = r.createReplicaSet(ctx, hash, maxRevision(replicaSets)+1, oldRSs)
+		if err != nil {
+			return err
+		}
+		replicaSets = append(replicaSets, newRS)
+	} else if revision := maxRevision(replicaSets); getRevision(newRS) < revision {
+		// The template was rolled back to the one of an older replica set, so it becomes the latest revision.
+		if err := r.patchReplicaSet(ctx, newRS, func(rs *vmopv1alpha1.VirtualMachineReplicaSet) {
+			rs.Annotations[vmopv1alpha1.VirtualMachineDeploymentRevisionAnnotation] = strconv.FormatInt(revision+1, 10)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := r.rollout(ctx, newRS, oldRSs); err != nil {
+		return err
+	}
+
+	return r.cleanupReplicaSets(ctx, oldRSs)
+}
+
+// getReplicaSets returns the VirtualMachineReplicaSets controlled by the deployment, sorted by revision.
+func (r *Reconciler) getReplicaSets(ctx *context.VirtualMachineDeploymentContext) ([]*vmopv1alpha1.VirtualMachineReplicaSet, error) {
+	rsList := &vmopv1alpha1.VirtualMachineReplicaSetList{}
+	if err := r.List(ctx, rsList, client.InNamespace(ctx.Deployment.Namespace)); err != nil {
+		return nil, errors.Wrapf(err, "failed to list VirtualMachineReplicaSets")
+	}
+
+	var replicaSets []*vmopv1alpha1.VirtualMachineReplicaSet
+	for i := range rsList.Items {
+		rs := &rsList.Items[i]
+		if metav1.IsControlledBy(rs, ctx.Deployment) && rs.DeletionTimestamp.IsZero() {
+			replicaSets = append(replicaSets, rs)
+		}
+	}
+
+	sort.SliceStable(replicaSets, func(i, j int) bool {
+		return getRevision(replicaSets[i]) < getRevision(replicaSets[j])
+	})
+
+	return replicaSets, nil
+}
+
+// rollback sets the deployment's template to the one of the replica set of the revision to roll back to.
+func (r *Reconciler) rollback(
+	ctx *context.VirtualMachineDeploymentContext,
+	replicaSets []*vmopv1alpha1.VirtualMachineReplicaSet) {
+
+	deployment := ctx.Deployment
+	revision := deployment.Spec.RollbackTo.Revision
+	deployment.Spec.RollbackTo = nil
+
+	if revision == 0 {
+		// Roll back to the revision before the latest one.
+		if len(replicaSets) < 2 {
+			r.Recorder.Warn(deployment, "RollbackRevisionNotFound", "Unable to find the previous revision")
+			return
+		}
+		revision = getRevision(replicaSets[len(replicaSets)-2])
+	}
+
+	for _, rs := range replicaSets {
+		if getRevision(rs) != revision {
+			continue
+		}
+
+		template := rs.Spec.Template.DeepCopy()
+		delete(template.Labels, vmopv1alpha1.VirtualMachineDeploymentTemplateHashLabel)
+		deployment.Spec.Template = *template
+
+		ctx.Logger.Info("Rolled back template", "revision", revision)
+		r.Recorder.Eventf(deployment, "RollbackDone", "Rolled back to revision %d", revision)
+		return
+	}
+
+	r.Recorder.Warnf(deployment, "RollbackRevisionNotFound", "Unable to find revision %d", revision)
+}
+
+// createReplicaSet creates the replica set of the deployment's current template. The replica set is
+// created with as many replicas as the rolling update allows.
+func (r *Reconciler) createReplicaSet(
+	ctx *context.VirtualMachineDeploymentContext,
+	hash string,
+	revision int64,
+	oldRSs []*vmopv1alpha1.VirtualMachineReplicaSet) (*vmopv1alpha1.VirtualMachineReplicaSet, error) {
+
+	deployment := ctx.Deployment
+	template := deployment.Spec.Template.DeepCopy()
+	if template.Labels == nil {
+		template.Labels = map[string]string{}
+	}
+	template.Labels[vmopv1alpha1.VirtualMachineDeploymentTemplateHashLabel] = hash
+
+	selector := deployment.Spec.Selector.DeepCopy()
+	if selector.MatchLabels == nil {
+		selector.MatchLabels = map[string]string{}
+	}
+	selector.MatchLabels[vmopv1alpha1.VirtualMachineDeploymentTemplateHashLabel] = hash
+
+	desired := getDesiredReplicas(deployment)
+	maxSurge, _ := resolveRollingUpdate(deployment)
+	replicas := minInt32(desired, desired+maxSurge-sumSpecReplicas(oldRSs))
+	if replicas < 0 {
+		replicas = 0
+	}
+
+	rs := &vmopv1alpha1.VirtualMachineReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s", deployment.Name, hash),
+			Namespace: deployment.Namespace,
+			Labels:    template.Labels,
+			Annotations: map[string]string{
+				vmopv1alpha1.VirtualMachineDeploymentRevisionAnnotation: strconv.FormatInt(revision, 10),
+			},
+		},
+		Spec: vmopv1alpha1.VirtualMachineReplicaSetSpec{
+			Replicas:               pointer.Int32(replicas),
+			Selector:               selector,
+			Template:               *template,
+			ClusterModuleGroupName: deployment.Spec.ClusterModuleGroupName,
+		},
+	}
+
+	if err := controllerutil.SetControllerReference(deployment, rs, r.Scheme()); err != nil {
+		return nil, errors.Wrapf(err, "failed to set controller reference")
+	}
+
+	err := r.Create(ctx, rs)
+	r.Recorder.EmitEvent(deployment, "CreateReplicaSet", err, false)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create VirtualMachineReplicaSet %s", rs.Name)
+	}
+
+	ctx.Logger.Info("Created replica set", "replicaSet", rs.Name, "revision", revision, "replicas", replicas)
+	return rs, nil
+}
+
+// rollout scales up the new replica set, and scales down the old replica sets, within the bounds of
+// the rolling update. The old replicas are only scaled down once enough of the replicas are ready.
+func (r *Reconciler) rollout(
+	ctx *context.VirtualMachineDeploymentContext,
+	newRS *vmopv1alpha1.VirtualMachineReplicaSet,
+	oldRSs []*vmopv1alpha1.VirtualMachineReplicaSet) error {
+
+	deployment := ctx.Deployment
+	desired := getDesiredReplicas(deployment)
+	maxSurge, maxUnavailable := resolveRollingUpdate(deployment)
+
+	newReplicas := getSpecReplicas(newRS)
+	switch {
+	case newReplicas > desired:
+		newReplicas = desired
+	case newReplicas < desired:
+		allReplicas := newReplicas + sumSpecReplicas(oldRSs)
+		if scaleUp := minInt32(desired+maxSurge-allReplicas, desired-newReplicas); scaleUp > 0 {
+			newReplicas += scaleUp
+		}
+	}
+
+	if newReplicas != getSpecReplicas(newRS) {
+		ctx.Logger.Info("Scaling new replica set", "replicaSet", newRS.Name, "replicas", newReplicas)
+		if err := r.scaleReplicaSet(ctx, newRS, newReplicas); err != nil {
+			return err
+		}
+	}
+
+	// The ready replicas in the status of a replica set that has not yet observed its latest spec may
+	// include replicas that are being deleted, so nothing is scaled down until every replica set status
+	// is current. The deployment is reconciled again once the replica set statuses are updated.
+	if !isObserved(newRS) || !allObserved(oldRSs) {
+		ctx.Logger.V(4).Info("Waiting for the replica set statuses to be observed before scaling down")
+		return nil
+	}
+
+	// The old replicas that are not ready can always be scaled down. The ready ones only as long
+	// as the minimum number of replicas remain ready.
+	minAvailable := desired - maxUnavailable
+	canScaleDown := getReadyReplicas(newRS) + sumReadyReplicas(oldRSs) - minAvailable
+
+	for _, rs := range oldRSs {
+		replicas := getSpecReplicas(rs)
+		if replicas == 0 {
+			continue
+		}
+
+		notReady := replicas - getReadyReplicas(rs)
+
+		scaleDown := minInt32(replicas, notReady+maxInt32(canScaleDown, 0))
+		if scaleDown == 0 {
+			continue
+		}
+		canScaleDown -= maxInt32(scaleDown-notReady, 0)
+
+		ctx.Logger.Info("Scaling old replica set", "replicaSet", rs.Name, "replicas", replicas-scaleDown)
+		if err := r.scaleReplicaSet(ctx, rs, replicas-scaleDown); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cleanupReplicaSets deletes the oldest replica sets without replicas beyond the revision history limit.
+func (r *Reconciler) cleanupReplicaSets(
+	ctx *context.VirtualMachineDeploymentContext,
+	oldRSs []*vmopv1alpha1.VirtualMachineReplicaSet) error {
+
+	limit := int32(defaultRevisionHistoryLimit)
+	if ctx.Deployment.Spec.RevisionHistoryLimit != nil {
+		limit = *ctx.Deployment.Spec.RevisionHistoryLimit
+	}
+
+	excess := len(oldRSs) - int(limit)
+	for _, rs := range oldRSs {
+		if excess <= 0 {
+			break
+		}
+		if getSpecReplicas(rs) != 0 || rs.Status.Replicas != 0 {
+			continue
+		}
+
+		err := r.Delete(ctx, rs)
+		r.Recorder.EmitEvent(ctx.Deployment, "DeleteReplicaSet", err, true)
+		if client.IgnoreNotFound(err) != nil {
+			return errors.Wrapf(err, "failed to delete VirtualMachineReplicaSet %s", rs.Name)
+		}
+		ctx.Logger.Info("Deleted old replica set", "replicaSet", rs.Name, "revision", getRevision(rs))
+		excess--
+	}
+
+	return nil
+}
+
+func (r *Reconciler) scaleReplicaSet(
+	ctx *context.VirtualMachineDeploymentContext,
+	rs *vmopv1alpha1.VirtualMachineReplicaSet,
+	replicas int32) error {
+
+	return r.patchReplicaSet(ctx, rs, func(rs *vmopv1alpha1.VirtualMachineReplicaSet) {
+		rs.Spec.Replicas = pointer.Int32(replicas)
+	})
+}
+
+func (r *Reconciler) patchReplicaSet(
+	ctx *context.VirtualMachineDeploymentContext,
+	rs *vmopv1alpha1.VirtualMachineReplicaSet,
+	mutateFn func(rs *vmopv1alpha1.VirtualMachineReplicaSet)) error {
+
+	rsPatch := client.MergeFrom(rs.DeepCopy())
+	if rs.Annotations == nil {
+		rs.Annotations = map[string]string{}
+	}
+	mutateFn(rs)
+
+	if err := r.Patch(ctx, rs, rsPatch); err != nil {
+		return errors.Wrapf(err, "failed to patch VirtualMachineReplicaSet %s", rs.Name)
+	}
+	return nil
+}
+
+func updateDeploymentStatus(
+	deployment *vmopv1alpha1.VirtualMachineDeployment,
+	newRS *vmopv1alpha1.VirtualMachineReplicaSet,
+	replicaSets []*vmopv1alpha1.VirtualMachineReplicaSet) {
+
+	desired := getDesiredReplicas(deployment)
+	_, maxUnavailable := resolveRollingUpdate(deployment)
+
+	status := &deployment.Status
+	status.Replicas, status.ReadyReplicas, status.UpdatedReplicas = 0, 0, 0
+	for _, rs := range replicaSets {
+		status.Replicas += rs.Status.Replicas
+		status.ReadyReplicas += rs.Status.ReadyReplicas
+	}
+	status.UnavailableReplicas = maxInt32(desired-status.ReadyReplicas, 0)
+
+	if newRS != nil {
+		status.UpdatedReplicas = newRS.Status.Replicas
+		status.Revision = getRevision(newRS)
+	}
+
+	if status.ReadyReplicas >= desired-maxUnavailable {
+		conditions.MarkTrue(deployment, vmopv1alpha1.VirtualMachineDeploymentAvailableCondition)
+	} else {
+		conditions.MarkFalse(deployment,
+			vmopv1alpha1.VirtualMachineDeploymentAvailableCondition,
+			vmopv1alpha1.VirtualMachineDeploymentMinimumReplicasUnavailableReason,
+			vmopv1alpha1.ConditionSeverityWarning,
+			"%d of minimum %d replicas are ready", status.ReadyReplicas, desired-maxUnavailable)
+	}
+
+	switch {
+	case newRS != nil && status.Replicas == desired && status.UpdatedReplicas == desired &&
+		newRS.Status.ReadyReplicas == desired:
+		conditions.MarkTrue(deployment, vmopv1alpha1.VirtualMachineDeploymentRolledOutCondition)
+	case deployment.Spec.Paused:
+		markNotRolledOut(deployment, vmopv1alpha1.VirtualMachineDeploymentRolloutPausedReason,
+			vmopv1alpha1.ConditionSeverityInfo, "Rollout is paused")
+	default:
+		markNotRolledOut(deployment, vmopv1alpha1.VirtualMachineDeploymentRolloutInProgressReason,
+			vmopv1alpha1.ConditionSeverityInfo,
+			fmt.Sprintf("%d of %d replicas are updated", status.UpdatedReplicas, desired))
+	}
+}
+
+func markNotRolledOut(deployment *vmopv1alpha1.VirtualMachineDeployment, reason string,
+	severity vmopv1alpha1.ConditionSeverity, msg string) {
+	conditions.MarkFalse(deployment, vmopv1alpha1.VirtualMachineDeploymentRolledOutCondition, reason, severity, "%s", msg)
+}
+
+// resolveRollingUpdate returns the maximum number of replicas that can be surged and be unavailable
+// during a rolling update.
+func resolveRollingUpdate(deployment *vmopv1alpha1.VirtualMachineDeployment) (int32, int32) {
+	maxSurge, maxUnavailable := &defaultMaxSurge, &defaultMaxUnavailable
+	if rollingUpdate := deployment.Spec.Strategy.RollingUpdate; rollingUpdate != nil {
+		if rollingUpdate.MaxSurge != nil {
+			maxSurge = rollingUpdate.MaxSurge
+		}
+		if rollingUpdate.MaxUnavailable != nil {
+			maxUnavailable = rollingUpdate.MaxUnavailable
+		}
+	}
+
+	desired := int(getDesiredReplicas(deployment))
+	surge, err := intstr.GetScaledValueFromIntOrPercent(maxSurge, desired, true)
+	if err != nil {
+		surge = 0
+	}
+	unavailable, err := intstr.GetScaledValueFromIntOrPercent(maxUnavailable, desired, false)
+	if err != nil {
+		unavailable = 0
+	}
+
+	// The rollout cannot make progress if neither is allowed.
+	if surge == 0 && unavailable == 0 {
+		unavailable = 1
+	}
+
+	return int32(surge), int32(unavailable)
+}
+
+// splitReplicaSets returns the replica set of the template hash and the other replica sets.
+func splitReplicaSets(
+	replicaSets []*vmopv1alpha1.VirtualMachineReplicaSet,
+	hash string) (*vmopv1alpha1.VirtualMachineReplicaSet, []*vmopv1alpha1.VirtualMachineReplicaSet) {
+
+	var newRS *vmopv1alpha1.VirtualMachineReplicaSet
+	var oldRSs []*vmopv1alpha1.VirtualMachineReplicaSet
+	for _, rs := range replicaSets {
+		if newRS == nil && rs.Labels[vmopv1alpha1.VirtualMachineDeploymentTemplateHashLabel] == hash {
+			newRS = rs
+		} else {
+			oldRSs = append(oldRSs, rs)
+		}
+	}
+	return newRS, oldRSs
+}
+
+// templateHash returns the hash of the template used to name the replica set of the template.
+func templateHash(template *vmopv1alpha1.VirtualMachineTemplateSpec) string {
+	hasher := fnv.New32a()
+	data, _ := json.Marshal(template)
+	_, _ = hasher.Write(data)
+	return utilrand.SafeEncodeString(strconv.FormatUint(uint64(hasher.Sum32()), 10))
+}
+
+func getRevision(rs *vmopv1alpha1.VirtualMachineReplicaSet) int64 {
+	revision, _ := strconv.ParseInt(rs.Annotations[vmopv1alpha1.VirtualMachineDeploymentRevisionAnnotation], 10, 64)
+	return revision
+}
+
+func maxRevision(replicaSets []*vmopv1alpha1.VirtualMachineReplicaSet) int64 {
+	var revision int64
+	for _, rs := range replicaSets {
+		if r := getRevision(rs); r > revision {
+			revision = r
+		}
+	}
+	return revision
+}
+
+func getDesiredReplicas(deployment *vmopv1alpha1.VirtualMachineDeployment) int32 {
+	if deployment.Spec.Replicas == nil {
+		return 1
+	}
+	return *deployment.Spec.Replicas
+}
+
+func getSpecReplicas(rs *vmopv1alpha1.VirtualMachineReplicaSet) int32 {
+	if rs.Spec.Replicas == nil {
+		return 1
+	}
+	return *rs.Spec.Replicas
+}
+
+func sumSpecReplicas(replicaSets []*vmopv1alpha1.VirtualMachineReplicaSet) int32 {
+	var sum int32
+	for _, rs := range replicaSets {
+		sum += getSpecReplicas(rs)
+	}
+	return sum
+}
+
+// getReadyReplicas returns the ready replicas of the replica set, capped at its spec replicas since the
+// replicas beyond those are being deleted.
+func getReadyReplicas(rs *vmopv1alpha1.VirtualMachineReplicaSet) int32 {
+	return minInt32(rs.Status.ReadyReplicas, getSpecReplicas(rs))
+}
+
+func sumReadyReplicas(replicaSets []*vmopv1alpha1.VirtualMachineReplicaSet) int32 {
+	var sum int32
+	for _, rs := range replicaSets {
+		sum += getReadyReplicas(rs)
+	}
+	return sum
+}
+
+func isObserved(rs *vmopv1alpha1.VirtualMachineReplicaSet) bool {
+	return rs.Status.ObservedGeneration >= rs.Generation
+}
+
+func allObserved(replicaSets []*vmopv1alpha1.VirtualMachineReplicaSet) bool {
+	for _, rs := range replicaSets {
+		if !isObserved(rs) {
+			return false
+		}
+	}
+	return true
+}
+
+func minInt32(a, b int32) int32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt32(a, b int32) int32 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package virtualmachinedeployment_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
+
+	"github.com/vmware-tanzu/vm-operator/test/builder"
+)
+
+func intgTests() {
+	Describe("Invoking VirtualMachineDeployment controller tests", intgTestsReconcile)
+}
+
+func intgTestsReconcile() {
+	var (
+		ctx *builder.IntegrationTestContext
+
+		deployment *vmopv1alpha1.VirtualMachineDeployment
+	)
+
+	getReplicaSets := func() []vmopv1alpha1.VirtualMachineReplicaSet {
+		rsList := &vmopv1alpha1.VirtualMachineReplicaSetList{}
+		if err := ctx.Client.List(ctx, rsList, client.InNamespace(ctx.Namespace)); err != nil {
+			return nil
+		}
+		return rsList.Items
+	}
+
+	getDeployment := func() *vmopv1alpha1.VirtualMachineDeployment {
+		obj := &vmopv1alpha1.VirtualMachineDeployment{}
+		if err := ctx.Client.Get(ctx, client.ObjectKeyFromObject(deployment), obj); err != nil {
+			return nil
+		}
+		return obj
+	}
+
+	BeforeEach(func() {
+		ctx = suite.NewIntegrationTestContext()
+
+		deployment = &vmopv1alpha1.VirtualMachineDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "dummy-deployment",
+				Namespace: ctx.Namespace,
+			},
+			Spec: vmopv1alpha1.VirtualMachineDeploymentSpec{
+				Replicas: pointer.Int32(2),
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"app": "web"},
+				},
+				Template: vmopv1alpha1.VirtualMachineTemplateSpec{
+					VirtualMachineTemplateObjectMeta: vmopv1alpha1.VirtualMachineTemplateObjectMeta{
+						Labels: map[string]string{"app": "web"},
+					},
+					Spec: vmopv1alpha1.VirtualMachineSpec{
+						ImageName:  "image-v1",
+						ClassName:  "dummy-class",
+						PowerState: vmopv1alpha1.VirtualMachinePoweredOn,
+					},
+				},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		ctx.AfterEach()
+		ctx = nil
+	})
+
+	Context("Reconcile", func() {
+		BeforeEach(func() {
+			Expect(ctx.Client.Create(ctx, deployment)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			err := ctx.Client.Delete(ctx, deployment)
+			Expect(client.IgnoreNotFound(err)).To(Succeed())
+		})
+
+		It("creates a replica set for each revision of the template", func() {
+			By("Replica set of the first revision should be created", func() {
+				Eventually(getReplicaSets).Should(HaveLen(1))
+				rs := getReplicaSets()[0]
+				Expect(rs.Spec.Replicas).To(HaveValue(BeEquivalentTo(2)))
+				Expect(rs.Annotations).To(HaveKeyWithValue(vmopv1alpha1.VirtualMachineDeploymentRevisionAnnotation, "1"))
+			})
+
+			By("Replica set of the second revision should be created when the template changes", func() {
+				obj := getDeployment()
+				Expect(obj).ToNot(BeNil())
+				obj.Spec.Template.Spec.ImageName = "image-v2"
+				Expect(ctx.Client.Update(ctx, obj)).To(Succeed())
+
+				Eventually(getReplicaSets).Should(HaveLen(2))
+				Eventually(func() int64 {
+					if obj := getDeployment(); obj != nil {
+						return obj.Status.Revision
+					}
+					return 0
+				}).Should(BeEquivalentTo(2))
+			})
+		})
+	})
+}
@@ -0,0 +1,345 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package virtualmachinedeployment_test
+
+import (
+	"fmt"
+	"strconv"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
+
+	"github.com/vmware-tanzu/vm-operator/controllers/virtualmachinedeployment"
+	"github.com/vmware-tanzu/vm-operator/pkg/conditions"
+	vmopContext "github.com/vmware-tanzu/vm-operator/pkg/context"
+	"github.com/vmware-tanzu/vm-operator/test/builder"
+)
+
+func unitTests() {
+	Describe("Invoking VirtualMachineDeployment Reconcile", unitTestsReconcile)
+}
+
+func unitTestsReconcile() {
+	var (
+		initObjects   []client.Object
+		ctx           *builder.UnitTestContextForController
+		reconciler    *virtualmachinedeployment.Reconciler
+		deployment    *vmopv1alpha1.VirtualMachineDeployment
+		deploymentCtx *vmopContext.VirtualMachineDeploymentContext
+	)
+
+	// newReplicaSet returns a replica set of the deployment, as created by the controller, for the
+	// given image.
+	newReplicaSet := func(imageName string, revision int64, replicas, readyReplicas int32) *vmopv1alpha1.VirtualMachineReplicaSet {
+		hash := fmt.Sprintf("hash-%d", revision)
+		rs := &vmopv1alpha1.VirtualMachineReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("%s-%s", deployment.Name, hash),
+				Namespace: deployment.Namespace,
+				Labels: map[string]string{
+					"app": "web",
+					vmopv1alpha1.VirtualMachineDeploymentTemplateHashLabel: hash,
+				},
+				Annotations: map[string]string{
+					vmopv1alpha1.VirtualMachineDeploymentRevisionAnnotation: strconv.FormatInt(revision, 10),
+				},
+			},
+			Spec: vmopv1alpha1.VirtualMachineReplicaSetSpec{
+				Replicas: pointer.Int32(replicas),
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app": "web",
+						vmopv1alpha1.VirtualMachineDeploymentTemplateHashLabel: hash,
+					},
+				},
+				Template: *deployment.Spec.Template.DeepCopy(),
+			},
+			Status: vmopv1alpha1.VirtualMachineReplicaSetStatus{
+				Replicas:      replicas,
+				ReadyReplicas: readyReplicas,
+			},
+		}
+		rs.Spec.Template.Labels[vmopv1alpha1.VirtualMachineDeploymentTemplateHashLabel] = hash
+		rs.Spec.Template.Spec.ImageName = imageName
+		Expect(controllerutil.SetControllerReference(deployment, rs, builder.NewScheme())).To(Succeed())
+		return rs
+	}
+
+	getReplicaSets := func() map[string]vmopv1alpha1.VirtualMachineReplicaSet {
+		rsList := &vmopv1alpha1.VirtualMachineReplicaSetList{}
+		Expect(ctx.Client.List(ctx, rsList, client.InNamespace(deployment.Namespace))).To(Succeed())
+		replicaSets := map[string]vmopv1alpha1.VirtualMachineReplicaSet{}
+		for _, rs := range rsList.Items {
+			replicaSets[rs.Spec.Template.Spec.ImageName] = rs
+		}
+		return replicaSets
+	}
+
+	BeforeEach(func() {
+		deployment = &vmopv1alpha1.VirtualMachineDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "dummy-deployment",
+				Namespace: "dummy-ns",
+				UID:       "dummy-uid",
+			},
+			Spec: vmopv1alpha1.VirtualMachineDeploymentSpec{
+				Replicas: pointer.Int32(3),
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"app": "web"},
+				},
+				Template: vmopv1alpha1.VirtualMachineTemplateSpec{
+					VirtualMachineTemplateObjectMeta: vmopv1alpha1.VirtualMachineTemplateObjectMeta{
+						Labels: map[string]string{"app": "web"},
+					},
+					Spec: vmopv1alpha1.VirtualMachineSpec{
+						ImageName:  "image-v1",
+						ClassName:  "dummy-class",
+						PowerState: vmopv1alpha1.VirtualMachinePoweredOn,
+					},
+				},
+				Strategy: vmopv1alpha1.VirtualMachineDeploymentStrategy{
+					RollingUpdate: &vmopv1alpha1.VirtualMachineDeploymentRollingUpdate{
+						MaxSurge:       &intstr.IntOrString{Type: intstr.Int, IntVal: 1},
+						MaxUnavailable: &intstr.IntOrString{Type: intstr.Int, IntVal: 0},
+					},
+				},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		ctx = suite.NewUnitTestContextForController(initObjects...)
+		reconciler = virtualmachinedeployment.NewReconciler(
+			ctx.Client,
+			ctx.Logger,
+			ctx.Recorder,
+		)
+
+		deploymentCtx = &vmopContext.VirtualMachineDeploymentContext{
+			Context:    ctx,
+			Logger:     ctx.Logger.WithName(deployment.Name),
+			Deployment: deployment,
+		}
+	})
+
+	AfterEach(func() {
+		ctx.AfterEach()
+		ctx = nil
+		initObjects = nil
+		deploymentCtx = nil
+		reconciler = nil
+	})
+
+	Context("ReconcileNormal", func() {
+
+		When("there are no replica sets", func() {
+			BeforeEach(func() {
+				initObjects = append(initObjects, deployment)
+			})
+
+			It("creates the replica set of the template with all the replicas", func() {
+				Expect(reconciler.ReconcileNormal(deploymentCtx)).To(Succeed())
+
+				replicaSets := getReplicaSets()
+				Expect(replicaSets).To(HaveLen(1))
+				rs := replicaSets["image-v1"]
+				Expect(rs.Spec.Replicas).To(HaveValue(BeEquivalentTo(3)))
+				Expect(rs.Annotations).To(HaveKeyWithValue(vmopv1alpha1.VirtualMachineDeploymentRevisionAnnotation, "1"))
+				hash := rs.Labels[vmopv1alpha1.VirtualMachineDeploymentTemplateHashLabel]
+				Expect(hash).ToNot(BeEmpty())
+				Expect(rs.Name).To(Equal(deployment.Name + "-" + hash))
+				Expect(rs.Spec.Selector.MatchLabels).To(HaveKeyWithValue(vmopv1alpha1.VirtualMachineDeploymentTemplateHashLabel, hash))
+				Expect(rs.Spec.Template.Labels).To(HaveKeyWithValue(vmopv1alpha1.VirtualMachineDeploymentTemplateHashLabel, hash))
+				Expect(metav1.IsControlledBy(&rs, deployment)).To(BeTrue())
+
+				Expect(deployment.Status.Revision).To(BeEquivalentTo(1))
+				Expect(deployment.Status.Selector).To(Equal("app=web"))
+				Expect(conditions.IsFalse(deployment, vmopv1alpha1.VirtualMachineDeploymentRolledOutCondition)).To(BeTrue())
+			})
+		})
+
+		When("the template is changed", func() {
+			var oldRS *vmopv1alpha1.VirtualMachineReplicaSet
+
+			BeforeEach(func() {
+				oldRS = newReplicaSet("image-v1", 1, 3, 3)
+				deployment.Spec.Template.Spec.ImageName = "image-v2"
+			})
+
+			When("the old replicas are ready", func() {
+				BeforeEach(func() {
+					initObjects = append(initObjects, deployment, oldRS)
+				})
+
+				It("surges the new replica set without scaling down the old one", func() {
+					Expect(reconciler.ReconcileNormal(deploymentCtx)).To(Succeed())
+
+					replicaSets := getReplicaSets()
+					Expect(replicaSets).To(HaveLen(2))
+					Expect(replicaSets["image-v2"].Spec.Replicas).To(HaveValue(BeEquivalentTo(1)))
+					Expect(replicaSets["image-v2"].Annotations).To(
+						HaveKeyWithValue(vmopv1alpha1.VirtualMachineDeploymentRevisionAnnotation, "2"))
+					Expect(replicaSets["image-v1"].Spec.Replicas).To(HaveValue(BeEquivalentTo(3)))
+
+					Expect(deployment.Status.Revision).To(BeEquivalentTo(2))
+					Expect(conditions.IsTrue(deployment, vmopv1alpha1.VirtualMachineDeploymentAvailableCondition)).To(BeTrue())
+					Expect(conditions.GetReason(deployment, vmopv1alpha1.VirtualMachineDeploymentRolledOutCondition)).To(
+						Equal(vmopv1alpha1.VirtualMachineDeploymentRolloutInProgressReason))
+				})
+			})
+
+			When("a new replica is ready", func() {
+				BeforeEach(func() {
+					initObjects = append(initObjects, deployment, oldRS)
+				})
+
+				It("scales down an old replica once the new replica is ready", func() {
+					Expect(reconciler.ReconcileNormal(deploymentCtx)).To(Succeed())
+
+					// Mark the new replica ready as the replica set controller would.
+					rsList := &vmopv1alpha1.VirtualMachineReplicaSetList{}
+					Expect(ctx.Client.List(ctx, rsList)).To(Succeed())
+					for i := range rsList.Items {
+						rs := &rsList.Items[i]
+						if rs.Spec.Template.Spec.ImageName == "image-v2" {
+							rs.Status.Replicas = 1
+							rs.Status.ReadyReplicas = 1
+							Expect(ctx.Client.Status().Update(ctx, rs)).To(Succeed())
+						}
+					}
+
+					Expect(reconciler.ReconcileNormal(deploymentCtx)).To(Succeed())
+
+					replicaSets := getReplicaSets()
+					Expect(replicaSets["image-v2"].Spec.Replicas).To(HaveValue(BeEquivalentTo(1)))
+					Expect(replicaSets["image-v1"].Spec.Replicas).To(HaveValue(BeEquivalentTo(2)))
+				})
+			})
+
+			When("the status of the old replica set is stale", func() {
+				BeforeEach(func() {
+					oldRS.Generation = 2
+					oldRS.Status.ObservedGeneration = 1
+					initObjects = append(initObjects, deployment, oldRS)
+				})
+
+				It("does not scale down the old replica set until its status is observed", func() {
+					Expect(reconciler.ReconcileNormal(deploymentCtx)).To(Succeed())
+
+					// Mark the new replica ready as the replica set controller would.
+					rsList := &vmopv1alpha1.VirtualMachineReplicaSetList{}
+					Expect(ctx.Client.List(ctx, rsList)).To(Succeed())
+					for i := range rsList.Items {
+						rs := &rsList.Items[i]
+						if rs.Spec.Template.Spec.ImageName == "image-v2" {
+							rs.Status.Replicas = 1
+							rs.Status.ReadyReplicas = 1
+							Expect(ctx.Client.Status().Update(ctx, rs)).To(Succeed())
+						}
+					}
+
+					Expect(reconciler.ReconcileNormal(deploymentCtx)).To(Succeed())
+					Expect(getReplicaSets()["image-v1"].Spec.Replicas).To(HaveValue(BeEquivalentTo(3)))
+
+					rs := getReplicaSets()["image-v1"]
+					rs.Status.ObservedGeneration = rs.Generation
+					Expect(ctx.Client.Status().Update(ctx, &rs)).To(Succeed())
+
+					Expect(reconciler.ReconcileNormal(deploymentCtx)).To(Succeed())
+					Expect(getReplicaSets()["image-v1"].Spec.Replicas).To(HaveValue(BeEquivalentTo(2)))
+				})
+			})
+
+			When("the deployment is paused", func() {
+				BeforeEach(func() {
+					deployment.Spec.Paused = true
+					initObjects = append(initObjects, deployment, oldRS)
+				})
+
+				It("does not roll out the template", func() {
+					Expect(reconciler.ReconcileNormal(deploymentCtx)).To(Succeed())
+
+					Expect(getReplicaSets()).To(HaveLen(1))
+					Expect(conditions.GetReason(deployment, vmopv1alpha1.VirtualMachineDeploymentRolledOutCondition)).To(
+						Equal(vmopv1alpha1.VirtualMachineDeploymentRolloutPausedReason))
+				})
+			})
+		})
+
+		When("rolling back to the previous revision", func() {
+			BeforeEach(func() {
+				rsV1 := newReplicaSet("image-v1", 1, 0, 0)
+				rsV2 := newReplicaSet("image-v2", 2, 3, 3)
+				deployment.Spec.Template.Spec.ImageName = "image-v2"
+				deployment.Spec.RollbackTo = &vmopv1alpha1.VirtualMachineDeploymentRollback{}
+				initObjects = append(initObjects, deployment, rsV1, rsV2)
+			})
+
+			It("sets the template to the one of the previous revision", func() {
+				Expect(reconciler.ReconcileNormal(deploymentCtx)).To(Succeed())
+				Expect(deployment.Spec.RollbackTo).To(BeNil())
+				Expect(deployment.Spec.Template.Spec.ImageName).To(Equal("image-v1"))
+				Expect(deployment.Spec.Template.Labels).To(Equal(map[string]string{"app": "web"}))
+			})
+		})
+
+		When("rolling back to a revision that does not exist", func() {
+			BeforeEach(func() {
+				rsV1 := newReplicaSet("image-v1", 1, 3, 3)
+				deployment.Spec.RollbackTo = &vmopv1alpha1.VirtualMachineDeploymentRollback{Revision: 5}
+				initObjects = append(initObjects, deployment, rsV1)
+			})
+
+			It("leaves the template unchanged", func() {
+				Expect(reconciler.ReconcileNormal(deploymentCtx)).To(Succeed())
+				Expect(deployment.Spec.RollbackTo).To(BeNil())
+				Expect(deployment.Spec.Template.Spec.ImageName).To(Equal("image-v1"))
+			})
+		})
+
+		When("there are more old replica sets than the revision history limit", func() {
+			BeforeEach(func() {
+				deployment.Spec.RevisionHistoryLimit = pointer.Int32(1)
+				deployment.Spec.Template.Spec.ImageName = "image-v4"
+				initObjects = append(initObjects, deployment,
+					newReplicaSet("image-v1", 1, 0, 0),
+					newReplicaSet("image-v2", 2, 0, 0),
+					newReplicaSet("image-v3", 3, 3, 3))
+			})
+
+			It("deletes the oldest replica sets without replicas", func() {
+				Expect(reconciler.ReconcileNormal(deploymentCtx)).To(Succeed())
+
+				replicaSets := getReplicaSets()
+				Expect(replicaSets).To(HaveLen(2))
+				Expect(replicaSets).ToNot(HaveKey("image-v1"))
+				Expect(replicaSets).ToNot(HaveKey("image-v2"))
+				Expect(replicaSets).To(HaveKey("image-v3"))
+				Expect(replicaSets).To(HaveKey("image-v4"))
+			})
+		})
+
+		When("the selector does not match the template labels", func() {
+			BeforeEach(func() {
+				deployment.Spec.Selector.MatchLabels = map[string]string{"app": "other"}
+				initObjects = append(initObjects, deployment)
+			})
+
+			It("returns error and does not create a replica set", func() {
+				Expect(reconciler.ReconcileNormal(deploymentCtx)).ToNot(Succeed())
+				Expect(conditions.GetReason(deployment, vmopv1alpha1.VirtualMachineDeploymentRolledOutCondition)).To(
+					Equal(vmopv1alpha1.VirtualMachineDeploymentSelectorMismatchReason))
+				Expect(getReplicaSets()).To(BeEmpty())
+			})
+		})
+	})
+}
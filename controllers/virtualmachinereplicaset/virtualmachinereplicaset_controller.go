@@ -0,0 +1,516 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package virtualmachinereplicaset
+
+import (
+	goctx "context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
+
+	"github.com/vmware-tanzu/vm-operator/pkg"
+	"github.com/vmware-tanzu/vm-operator/pkg/conditions"
+	"github.com/vmware-tanzu/vm-operator/pkg/context"
+	"github.com/vmware-tanzu/vm-operator/pkg/patch"
+	"github.com/vmware-tanzu/vm-operator/pkg/record"
+)
+
+const (
+	// drainRequeueDelay is how long to wait before checking again whether the replicas being drained
+	// have been removed from the VirtualMachineService endpoints.
+	drainRequeueDelay = 5 * time.Second
+
+	// createdReplicaRequeueDelay is how long to wait before checking again whether the created replicas
+	// are in the cache.
+	createdReplicaRequeueDelay = time.Second
+
+	// createdReplicaTimeout is how long a created replica is waited for to be in the cache. After that,
+	// such as when the replica was deleted before it was observed, it is no longer waited for.
+	createdReplicaTimeout = time.Minute
+
+	// ControllerOwnerIndexKey is the field index of the VirtualMachines by the UID of the
+	// VirtualMachineReplicaSet that controls them.
+	ControllerOwnerIndexKey = ".metadata.controller"
+)
+
+// ControllerOwnerIndexFunc returns the UID of the VirtualMachineReplicaSet that controls the
+// VirtualMachine.
+func ControllerOwnerIndexFunc(obj client.Object) []string {
+	owner := metav1.GetControllerOf(obj)
+	if owner == nil || owner.Kind != "VirtualMachineReplicaSet" ||
+		owner.APIVersion != vmopv1alpha1.SchemeGroupVersion.String() {
+		return nil
+	}
+	return []string{string(owner.UID)}
+}
+
+// AddToManager adds this package's controller to the provided manager.
+func AddToManager(ctx *context.ControllerManagerContext, mgr manager.Manager) error {
+	var (
+		controlledType     = &vmopv1alpha1.VirtualMachineReplicaSet{}
+		controlledTypeName = reflect.TypeOf(controlledType).Elem().Name()
+
+		controllerNameShort = fmt.Sprintf("%s-controller", strings.ToLower(controlledTypeName))
+		controllerNameLong  = fmt.Sprintf("%s/%s/%s", ctx.Namespace, ctx.Name, controllerNameShort)
+	)
+
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &vmopv1alpha1.VirtualMachine{},
+		ControllerOwnerIndexKey, ControllerOwnerIndexFunc); err != nil {
+		return errors.Wrapf(err, "failed to index VirtualMachines by their controller")
+	}
+
+	r := NewReconciler(
+		mgr.GetClient(),
+		ctrl.Log.WithName("controllers").WithName(controlledTypeName),
+		record.New(mgr.GetEventRecorderFor(controllerNameLong)),
+	)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(controlledType).
+		Owns(&vmopv1alpha1.VirtualMachine{}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: ctx.MaxConcurrentReconciles}).
+		Complete(r)
+}
+
+func NewReconciler(
+	client client.Client,
+	logger logr.Logger,
+	recorder record.Recorder) *Reconciler {
+	return &Reconciler{
+		Client:   client,
+		Logger:   logger,
+		Recorder: recorder,
+		createdReplicas: &createdReplicas{
+			replicas: map[string]map[string]time.Time{},
+		},
+	}
+}
+
+// Reconciler reconciles a VirtualMachineReplicaSet object.
+type Reconciler struct {
+	client.Client
+	Logger   logr.Logger
+	Recorder record.Recorder
+
+	// createdReplicas is used so a replica created by a previous reconcile, but not yet in the
+	// cache, is not created again.
+	createdReplicas *createdReplicas
+}
+
+// createdReplicas records, by replica set, the replicas created that have not been observed in the
+// cache yet, with the time they were created.
+type createdReplicas struct {
+	sync.Mutex
+	replicas map[string]map[string]time.Time
+}
+
+func (c *createdReplicas) add(rsKey string, name string) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.replicas[rsKey] == nil {
+		c.replicas[rsKey] = map[string]time.Time{}
+	}
+	c.replicas[rsKey][name] = time.Now()
+}
+
+// observe removes the replicas that are in the cache, and the replicas that have been waited for
+// longer than createdReplicaTimeout.
+func (c *createdReplicas) observe(rsKey string, cached map[string]struct{}) {
+	c.Lock()
+	defer c.Unlock()
+
+	for name, created := range c.replicas[rsKey] {
+		if _, ok := cached[name]; ok || time.Since(created) > createdReplicaTimeout {
+			delete(c.replicas[rsKey], name)
+		}
+	}
+	if len(c.replicas[rsKey]) == 0 {
+		delete(c.replicas, rsKey)
+	}
+}
+
+// pending returns true if a replica created for the replica set has not been observed in the cache.
+func (c *createdReplicas) pending(rsKey string) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	return len(c.replicas[rsKey]) > 0
+}
+
+func (c *createdReplicas) forget(rsKey string) {
+	c.Lock()
+	defer c.Unlock()
+
+	delete(c.replicas, rsKey)
+}
+
+// +kubebuilder:rbac:groups=vmoperator.vmware.com,resources=virtualmachinereplicasets,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=vmoperator.vmware.com,resources=virtualmachinereplicasets/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=vmoperator.vmware.com,resources=virtualmachines,verbs=get;list;watch;create;patch;delete
+// +kubebuilder:rbac:groups="",resources=endpoints,verbs=get;list;watch
+// +kubebuilder:rbac:groups=vmoperator.vmware.com,resources=virtualmachinesetresourcepolicies,verbs=get;list;watch
+
+func (r *Reconciler) Reconcile(ctx goctx.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
+	rs := &vmopv1alpha1.VirtualMachineReplicaSet{}
+	if err := r.Get(ctx, req.NamespacedName, rs); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.createdReplicas.forget(req.NamespacedName.String())
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	// The replicas are garbage collected through their owner reference.
+	if !rs.DeletionTimestamp.IsZero() {
+		r.createdReplicas.forget(req.NamespacedName.String())
+		return ctrl.Result{}, nil
+	}
+
+	rsCtx := &context.VirtualMachineReplicaSetContext{
+		Context:    ctx,
+		Logger:     ctrl.Log.WithName("VirtualMachineReplicaSet").WithValues("name", req.NamespacedName),
+		ReplicaSet: rs,
+	}
+
+	patchHelper, err := patch.NewHelper(rs, r.Client)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "failed to init patch helper for %s", rsCtx)
+	}
+	defer func() {
+		if err := patchHelper.Patch(ctx, rs); err != nil {
+			if reterr == nil {
+				reterr = err
+			}
+			rsCtx.Logger.Error(err, "patch failed")
+		}
+	}()
+
+	result, err := r.ReconcileNormal(rsCtx)
+	if err != nil {
+		rsCtx.Logger.Error(err, "Failed to reconcile VirtualMachineReplicaSet")
+		return ctrl.Result{}, err
+	}
+
+	return result, nil
+}
+
+func (r *Reconciler) ReconcileNormal(ctx *context.VirtualMachineReplicaSetContext) (ctrl.Result, error) {
+	rs := ctx.ReplicaSet
+	rs.Status.ObservedGeneration = rs.Generation
+
+	selector, err := metav1.LabelSelectorAsSelector(rs.Spec.Selector)
+	if err != nil {
+		r.markReplicasNotReconciled(rs, vmopv1alpha1.VirtualMachineReplicaSetSelectorMismatchReason, err)
+		return ctrl.Result{}, errors.Wrapf(err, "invalid selector")
+	}
+	rs.Status.Selector = selector.String()
+
+	if selector.Empty() || !selector.Matches(labels.Set(rs.Spec.Template.Labels)) {
+		err := fmt.Errorf("selector %q does not match the template labels", selector.String())
+		r.markReplicasNotReconciled(rs, vmopv1alpha1.VirtualMachineReplicaSetSelectorMismatchReason, err)
+		return ctrl.Result{}, err
+	}
+
+	replicas, draining, err := r.getReplicas(ctx, selector)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	defer func() {
+		updateReplicaStatus(rs, replicas)
+	}()
+
+	if r.createdReplicas.pending(rs.NamespacedName()) {
+		ctx.Logger.V(4).Info("Waiting for the created replicas to be in the cache")
+		return ctrl.Result{RequeueAfter: createdReplicaRequeueDelay}, nil
+	}
+
+	desired := 1
+	if rs.Spec.Replicas != nil {
+		desired = int(*rs.Spec.Replicas)
+	}
+
+	switch {
+	case len(replicas) < desired:
+		ctx.Logger.Info("Creating replicas", "current", len(replicas), "desired", desired)
+
+		groupName, err := r.getClusterModuleGroupName(ctx)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		for i := len(replicas); i < desired; i++ {
+			vm, err := r.createReplica(ctx, groupName)
+			r.Recorder.EmitEvent(rs, "CreateReplica", err, true)
+			if err != nil {
+				r.markReplicasNotReconciled(rs, vmopv1alpha1.VirtualMachineReplicaSetCreateFailedReason, err)
+				return ctrl.Result{}, errors.Wrapf(err, "failed to create replica")
+			}
+			r.createdReplicas.add(rs.NamespacedName(), vm.Name)
+			replicas = append(replicas, *vm)
+		}
+
+	case len(replicas) > desired:
+		ctx.Logger.Info("Deleting replicas", "current", len(replicas), "desired", desired)
+
+		sortReplicasForDeletion(replicas)
+		for len(replicas) > desired {
+			if err := r.drainReplica(ctx, &replicas[0]); err != nil {
+				r.markReplicasNotReconciled(rs, vmopv1alpha1.VirtualMachineReplicaSetDeleteFailedReason, err)
+				return ctrl.Result{}, errors.Wrapf(err, "failed to drain replica %s", replicas[0].Name)
+			}
+			draining = append(draining, replicas[0])
+			replicas = replicas[1:]
+		}
+	}
+
+	if len(draining) > 0 {
+		drained, err := r.deleteDrainedReplicas(ctx, draining)
+		if err != nil {
+			r.markReplicasNotReconciled(rs, vmopv1alpha1.VirtualMachineReplicaSetDeleteFailedReason, err)
+			return ctrl.Result{}, err
+		}
+		if !drained {
+			conditions.MarkTrue(rs, vmopv1alpha1.VirtualMachineReplicaSetReplicasReconciledCondition)
+			return ctrl.Result{RequeueAfter: drainRequeueDelay}, nil
+		}
+	}
+
+	conditions.MarkTrue(rs, vmopv1alpha1.VirtualMachineReplicaSetReplicasReconciledCondition)
+	return ctrl.Result{}, nil
+}
+
+// getReplicas returns the VirtualMachines, that are not being deleted, controlled by the replica set
+// and matching its selector. The replicas being drained are returned separately.
+func (r *Reconciler) getReplicas(
+	ctx *context.VirtualMachineReplicaSetContext,
+	selector labels.Selector) ([]vmopv1alpha1.VirtualMachine, []vmopv1alpha1.VirtualMachine, error) {
+
+	vmList := &vmopv1alpha1.VirtualMachineList{}
+	if err := r.List(ctx, vmList,
+		client.InNamespace(ctx.ReplicaSet.Namespace),
+		client.MatchingFields{ControllerOwnerIndexKey: string(ctx.ReplicaSet.UID)},
+		client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to list VirtualMachines")
+	}
+
+	cached := make(map[string]struct{}, len(vmList.Items))
+	for i := range vmList.Items {
+		cached[vmList.Items[i].Name] = struct{}{}
+	}
+	r.createdReplicas.observe(ctx.ReplicaSet.NamespacedName(), cached)
+
+	replicas := make([]vmopv1alpha1.VirtualMachine, 0, len(vmList.Items))
+	var draining []vmopv1alpha1.VirtualMachine
+	for i := range vmList.Items {
+		vm := &vmList.Items[i]
+		if !metav1.IsControlledBy(vm, ctx.ReplicaSet) || !vm.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if _, ok := vm.Annotations[pkg.DrainingAnnotationKey]; ok {
+			draining = append(draining, *vm)
+		} else {
+			replicas = append(replicas, *vm)
+		}
+	}
+
+	return replicas, draining, nil
+}
+
+// drainReplica annotates the replica so it is removed from the VirtualMachineService endpoints
+// before it is deleted.
+func (r *Reconciler) drainReplica(ctx *context.VirtualMachineReplicaSetContext, vm *vmopv1alpha1.VirtualMachine) error {
+	vmPatch := client.MergeFrom(vm.DeepCopy())
+	if vm.Annotations == nil {
+		vm.Annotations = map[string]string{}
+	}
+	vm.Annotations[pkg.DrainingAnnotationKey] = ""
+
+	ctx.Logger.Info("Draining replica", "vm", vm.NamespacedName())
+	return r.Patch(ctx, vm, vmPatch)
+}
+
+// deleteDrainedReplicas deletes the replicas being drained that are no longer referenced by any
+// Endpoints. Returns true when all the replicas have been deleted.
+func (r *Reconciler) deleteDrainedReplicas(
+	ctx *context.VirtualMachineReplicaSetContext,
+	draining []vmopv1alpha1.VirtualMachine) (bool, error) {
+
+	endpointsList := &corev1.EndpointsList{}
+	if err := r.List(ctx, endpointsList, client.InNamespace(ctx.ReplicaSet.Namespace)); err != nil {
+		return false, errors.Wrapf(err, "failed to list Endpoints")
+	}
+
+	inEndpoints := map[types.UID]struct{}{}
+	for _, endpoints := range endpointsList.Items {
+		for _, subset := range endpoints.Subsets {
+			for _, addresses := range [][]corev1.EndpointAddress{subset.Addresses, subset.NotReadyAddresses} {
+				for _, epa := range addresses {
+					if epa.TargetRef != nil {
+						inEndpoints[epa.TargetRef.UID] = struct{}{}
+					}
+				}
+			}
+		}
+	}
+
+	drained := true
+	for i := range draining {
+		vm := &draining[i]
+		if _, ok := inEndpoints[vm.UID]; ok {
+			ctx.Logger.V(4).Info("Replica is still in the Endpoints", "vm", vm.NamespacedName())
+			drained = false
+			continue
+		}
+
+		err := r.Delete(ctx, vm)
+		r.Recorder.EmitEvent(ctx.ReplicaSet, "DeleteReplica", err, true)
+		if client.IgnoreNotFound(err) != nil {
+			return false, errors.Wrapf(err, "failed to delete replica %s", vm.Name)
+		}
+		ctx.Logger.Info("Deleted replica", "vm", vm.NamespacedName())
+	}
+
+	return drained, nil
+}
+
+// getClusterModuleGroupName returns the cluster module group the replicas are placed in, if the
+// template references a VirtualMachineSetResourcePolicy with cluster modules.
+func (r *Reconciler) getClusterModuleGroupName(ctx *context.VirtualMachineReplicaSetContext) (string, error) {
+	rs := ctx.ReplicaSet
+
+	policyName := rs.Spec.Template.Spec.ResourcePolicyName
+	if policyName == "" {
+		return "", nil
+	}
+
+	resourcePolicy := &vmopv1alpha1.VirtualMachineSetResourcePolicy{}
+	policyKey := client.ObjectKey{Name: policyName, Namespace: rs.Namespace}
+	if err := r.Get(ctx, policyKey, resourcePolicy); err != nil {
+		return "", errors.Wrapf(err, "failed to get VirtualMachineSetResourcePolicy %s", policyKey)
+	}
+
+	groupName := rs.Spec.ClusterModuleGroupName
+	if groupName == "" {
+		if len(resourcePolicy.Spec.ClusterModules) == 0 {
+			return "", nil
+		}
+		return resourcePolicy.Spec.ClusterModules[0].GroupName, nil
+	}
+
+	for _, module := range resourcePolicy.Spec.ClusterModules {
+		if module.GroupName == groupName {
+			return groupName, nil
+		}
+	}
+
+	err := fmt.Errorf("cluster module group %s not found in VirtualMachineSetResourcePolicy %s", groupName, policyKey)
+	r.markReplicasNotReconciled(rs, vmopv1alpha1.VirtualMachineReplicaSetClusterModuleNotFoundReason, err)
+	return "", err
+}
+
+// createReplica creates a VirtualMachine from the replica set's template.
+func (r *Reconciler) createReplica(
+	ctx *context.VirtualMachineReplicaSetContext,
+	clusterModuleGroupName string) (*vmopv1alpha1.VirtualMachine, error) {
+
+	rs := ctx.ReplicaSet
+	template := rs.Spec.Template.DeepCopy()
+
+	vm := &vmopv1alpha1.VirtualMachine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%s-%s", rs.Name, utilrand.String(5)),
+			Namespace:   rs.Namespace,
+			Labels:      template.Labels,
+			Annotations: template.Annotations,
+		},
+		Spec: template.Spec,
+	}
+
+	if clusterModuleGroupName != "" {
+		if vm.Annotations == nil {
+			vm.Annotations = map[string]string{}
+		}
+		vm.Annotations[pkg.ClusterModuleNameKey] = clusterModuleGroupName
+	}
+
+	if err := controllerutil.SetControllerReference(rs, vm, r.Scheme()); err != nil {
+		return nil, errors.Wrapf(err, "failed to set controller reference")
+	}
+
+	if err := r.Create(ctx, vm); err != nil {
+		return nil, err
+	}
+
+	ctx.Logger.Info("Created replica", "vm", vm.NamespacedName())
+	return vm, nil
+}
+
+func (r *Reconciler) markReplicasNotReconciled(rs *vmopv1alpha1.VirtualMachineReplicaSet, reason string, err error) {
+	conditions.MarkFalse(rs,
+		vmopv1alpha1.VirtualMachineReplicaSetReplicasReconciledCondition,
+		reason,
+		vmopv1alpha1.ConditionSeverityError, err.Error())
+}
+
+// sortReplicasForDeletion sorts the replicas so the ones to delete first are at the front: the
+// VMs that have not been created yet, then the VMs that are not ready, then the newest VMs.
+func sortReplicasForDeletion(replicas []vmopv1alpha1.VirtualMachine) {
+	sort.SliceStable(replicas, func(i, j int) bool {
+		a, b := &replicas[i], &replicas[j]
+
+		if aCreated, bCreated := a.Status.UniqueID != "", b.Status.UniqueID != ""; aCreated != bCreated {
+			return !aCreated
+		}
+
+		if aReady, bReady := isReplicaReady(a), isReplicaReady(b); aReady != bReady {
+			return !aReady
+		}
+
+		if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+			return b.CreationTimestamp.Before(&a.CreationTimestamp)
+		}
+
+		return a.Name < b.Name
+	})
+}
+
+// isReplicaReady returns true when the replica's Ready condition, set by the readiness prober, is
+// true. Like for the VirtualMachineService endpoints, a VM that does not have a readiness probe is
+// implicitly ready once it is powered on.
+func isReplicaReady(vm *vmopv1alpha1.VirtualMachine) bool {
+	if condition := conditions.Get(vm, vmopv1alpha1.ReadyCondition); condition != nil || vm.Spec.ReadinessProbe != nil {
+		return conditions.IsTrue(vm, vmopv1alpha1.ReadyCondition)
+	}
+	return vm.Status.PowerState == vmopv1alpha1.VirtualMachinePoweredOn
+}
+
+func updateReplicaStatus(rs *vmopv1alpha1.VirtualMachineReplicaSet, replicas []vmopv1alpha1.VirtualMachine) {
+	rs.Status.Replicas = int32(len(replicas))
+	rs.Status.ReadyReplicas = 0
+	for i := range replicas {
+		if isReplicaReady(&replicas[i]) {
+			rs.Status.ReadyReplicas++
+		}
+	}
+}
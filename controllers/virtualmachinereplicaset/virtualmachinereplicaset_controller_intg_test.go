@@ -0,0 +1,131 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package virtualmachinereplicaset_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
+
+	"github.com/vmware-tanzu/vm-operator/pkg/conditions"
+	"github.com/vmware-tanzu/vm-operator/test/builder"
+)
+
+func intgTests() {
+	Describe("Invoking VirtualMachineReplicaSet controller tests", intgTestsReconcile)
+}
+
+func intgTestsReconcile() {
+	var (
+		ctx *builder.IntegrationTestContext
+
+		rs *vmopv1alpha1.VirtualMachineReplicaSet
+	)
+
+	getReplicas := func() []vmopv1alpha1.VirtualMachine {
+		vmList := &vmopv1alpha1.VirtualMachineList{}
+		if err := ctx.Client.List(ctx, vmList, client.InNamespace(ctx.Namespace)); err != nil {
+			return nil
+		}
+		var replicas []vmopv1alpha1.VirtualMachine
+		for _, vm := range vmList.Items {
+			if vm.DeletionTimestamp.IsZero() {
+				replicas = append(replicas, vm)
+			}
+		}
+		return replicas
+	}
+
+	getReplicaSet := func() *vmopv1alpha1.VirtualMachineReplicaSet {
+		obj := &vmopv1alpha1.VirtualMachineReplicaSet{}
+		if err := ctx.Client.Get(ctx, client.ObjectKeyFromObject(rs), obj); err != nil {
+			return nil
+		}
+		return obj
+	}
+
+	BeforeEach(func() {
+		ctx = suite.NewIntegrationTestContext()
+
+		rs = &vmopv1alpha1.VirtualMachineReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "dummy-rs",
+				Namespace: ctx.Namespace,
+			},
+			Spec: vmopv1alpha1.VirtualMachineReplicaSetSpec{
+				Replicas: pointer.Int32(3),
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"app": "runner"},
+				},
+				Template: vmopv1alpha1.VirtualMachineTemplateSpec{
+					VirtualMachineTemplateObjectMeta: vmopv1alpha1.VirtualMachineTemplateObjectMeta{
+						Labels: map[string]string{"app": "runner"},
+					},
+					Spec: vmopv1alpha1.VirtualMachineSpec{
+						ImageName:  "dummy-image",
+						ClassName:  "dummy-class",
+						PowerState: vmopv1alpha1.VirtualMachinePoweredOn,
+					},
+				},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		ctx.AfterEach()
+		ctx = nil
+	})
+
+	Context("Reconcile", func() {
+		BeforeEach(func() {
+			Expect(ctx.Client.Create(ctx, rs)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			err := ctx.Client.Delete(ctx, rs)
+			Expect(client.IgnoreNotFound(err)).To(Succeed())
+		})
+
+		It("creates the replicas and scales them down", func() {
+			By("Replicas should be created", func() {
+				Eventually(getReplicas).Should(HaveLen(3))
+				Eventually(func() int32 {
+					if obj := getReplicaSet(); obj != nil {
+						return obj.Status.Replicas
+					}
+					return 0
+				}).Should(BeEquivalentTo(3))
+			})
+
+			By("Ready replicas should be counted", func() {
+				vm := getReplicas()[0]
+				conditions.MarkTrue(&vm, vmopv1alpha1.ReadyCondition)
+				Expect(ctx.Client.Status().Update(ctx, &vm)).To(Succeed())
+
+				Eventually(func() int32 {
+					if obj := getReplicaSet(); obj != nil {
+						return obj.Status.ReadyReplicas
+					}
+					return 0
+				}).Should(BeEquivalentTo(1))
+			})
+
+			By("Replicas should be deleted when scaled down", func() {
+				obj := getReplicaSet()
+				Expect(obj).ToNot(BeNil())
+				obj.Spec.Replicas = pointer.Int32(1)
+				Expect(ctx.Client.Update(ctx, obj)).To(Succeed())
+
+				Eventually(getReplicas).Should(HaveLen(1))
+				vm := getReplicas()[0]
+				Expect(conditions.IsTrue(&vm, vmopv1alpha1.ReadyCondition)).To(BeTrue())
+			})
+		})
+	})
+}
@@ -0,0 +1,345 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package virtualmachinereplicaset_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
+
+	"github.com/vmware-tanzu/vm-operator/controllers/virtualmachinereplicaset"
+	"github.com/vmware-tanzu/vm-operator/pkg"
+	"github.com/vmware-tanzu/vm-operator/pkg/conditions"
+	vmopContext "github.com/vmware-tanzu/vm-operator/pkg/context"
+	"github.com/vmware-tanzu/vm-operator/test/builder"
+)
+
+func unitTests() {
+	Describe("Invoking VirtualMachineReplicaSet Reconcile", unitTestsReconcile)
+}
+
+func unitTestsReconcile() {
+	var (
+		initObjects []client.Object
+		ctx         *builder.UnitTestContextForController
+		reconciler  *virtualmachinereplicaset.Reconciler
+
+		rs    *vmopv1alpha1.VirtualMachineReplicaSet
+		rsCtx *vmopContext.VirtualMachineReplicaSetContext
+	)
+
+	newReplica := func(name string, created time.Time) *vmopv1alpha1.VirtualMachine {
+		vm := &vmopv1alpha1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         rs.Namespace,
+				Labels:            map[string]string{"app": "runner"},
+				CreationTimestamp: metav1.NewTime(created),
+			},
+			Status: vmopv1alpha1.VirtualMachineStatus{
+				UniqueID: "vm-" + name,
+			},
+		}
+		Expect(controllerutil.SetControllerReference(rs, vm, builder.NewScheme())).To(Succeed())
+		return vm
+	}
+
+	getReplicas := func() []vmopv1alpha1.VirtualMachine {
+		vmList := &vmopv1alpha1.VirtualMachineList{}
+		Expect(ctx.Client.List(ctx, vmList, client.InNamespace(rs.Namespace))).To(Succeed())
+		return vmList.Items
+	}
+
+	BeforeEach(func() {
+		rs = &vmopv1alpha1.VirtualMachineReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "dummy-rs",
+				Namespace: "dummy-ns",
+				UID:       "dummy-uid",
+			},
+			Spec: vmopv1alpha1.VirtualMachineReplicaSetSpec{
+				Replicas: pointer.Int32(2),
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"app": "runner"},
+				},
+				Template: vmopv1alpha1.VirtualMachineTemplateSpec{
+					VirtualMachineTemplateObjectMeta: vmopv1alpha1.VirtualMachineTemplateObjectMeta{
+						Labels:      map[string]string{"app": "runner"},
+						Annotations: map[string]string{"foo": "bar"},
+					},
+					Spec: vmopv1alpha1.VirtualMachineSpec{
+						ImageName:  "dummy-image",
+						ClassName:  "dummy-class",
+						PowerState: vmopv1alpha1.VirtualMachinePoweredOn,
+					},
+				},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		ctx = suite.NewUnitTestContextForController(initObjects...)
+		ctx.Client = fake.NewClientBuilder().
+			WithScheme(builder.NewScheme()).
+			WithObjects(initObjects...).
+			WithIndex(&vmopv1alpha1.VirtualMachine{},
+				virtualmachinereplicaset.ControllerOwnerIndexKey, virtualmachinereplicaset.ControllerOwnerIndexFunc).
+			Build()
+		reconciler = virtualmachinereplicaset.NewReconciler(
+			ctx.Client,
+			ctx.Logger,
+			ctx.Recorder,
+		)
+
+		rsCtx = &vmopContext.VirtualMachineReplicaSetContext{
+			Context:    ctx,
+			Logger:     ctx.Logger.WithName(rs.Name),
+			ReplicaSet: rs,
+		}
+	})
+
+	AfterEach(func() {
+		ctx.AfterEach()
+		ctx = nil
+		initObjects = nil
+		rsCtx = nil
+		reconciler = nil
+	})
+
+	Context("ReconcileNormal", func() {
+
+		When("there are no replicas", func() {
+			BeforeEach(func() {
+				initObjects = append(initObjects, rs)
+			})
+
+			It("creates the replicas from the template", func() {
+				_, err := reconciler.ReconcileNormal(rsCtx)
+				Expect(err).ToNot(HaveOccurred())
+
+				replicas := getReplicas()
+				Expect(replicas).To(HaveLen(2))
+				for _, vm := range replicas {
+					Expect(vm.Name).To(HavePrefix(rs.Name + "-"))
+					Expect(vm.Labels).To(HaveKeyWithValue("app", "runner"))
+					Expect(vm.Annotations).To(HaveKeyWithValue("foo", "bar"))
+					Expect(vm.Annotations).ToNot(HaveKey(pkg.ClusterModuleNameKey))
+					Expect(vm.Spec).To(Equal(rs.Spec.Template.Spec))
+					Expect(metav1.IsControlledBy(&vm, rs)).To(BeTrue())
+				}
+
+				Expect(rs.Status.Replicas).To(BeEquivalentTo(2))
+				Expect(rs.Status.ReadyReplicas).To(BeZero())
+				Expect(rs.Status.Selector).To(Equal("app=runner"))
+				Expect(conditions.IsTrue(rs, vmopv1alpha1.VirtualMachineReplicaSetReplicasReconciledCondition)).To(BeTrue())
+			})
+
+			It("does not create the replicas again until the created replicas are in the cache", func() {
+				_, err := reconciler.ReconcileNormal(rsCtx)
+				Expect(err).ToNot(HaveOccurred())
+
+				// Remove a created replica from the client, as if it was not in the cache yet.
+				replicas := getReplicas()
+				Expect(replicas).To(HaveLen(2))
+				Expect(ctx.Client.Delete(ctx, &replicas[0])).To(Succeed())
+
+				result, err := reconciler.ReconcileNormal(rsCtx)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.RequeueAfter).ToNot(BeZero())
+				Expect(getReplicas()).To(HaveLen(1))
+			})
+		})
+
+		When("the template references a resource policy with cluster modules", func() {
+			var resourcePolicy *vmopv1alpha1.VirtualMachineSetResourcePolicy
+
+			BeforeEach(func() {
+				resourcePolicy = &vmopv1alpha1.VirtualMachineSetResourcePolicy{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "dummy-policy",
+						Namespace: rs.Namespace,
+					},
+					Spec: vmopv1alpha1.VirtualMachineSetResourcePolicySpec{
+						ClusterModules: []vmopv1alpha1.ClusterModuleSpec{
+							{GroupName: "control-plane"},
+							{GroupName: "workers"},
+						},
+					},
+				}
+				rs.Spec.Template.Spec.ResourcePolicyName = resourcePolicy.Name
+				initObjects = append(initObjects, rs, resourcePolicy)
+			})
+
+			It("places the replicas in the first cluster module group", func() {
+				_, err := reconciler.ReconcileNormal(rsCtx)
+				Expect(err).ToNot(HaveOccurred())
+				for _, vm := range getReplicas() {
+					Expect(vm.Annotations).To(HaveKeyWithValue(pkg.ClusterModuleNameKey, "control-plane"))
+				}
+			})
+
+			When("the cluster module group is specified", func() {
+				BeforeEach(func() {
+					rs.Spec.ClusterModuleGroupName = "workers"
+				})
+
+				It("places the replicas in the cluster module group", func() {
+					_, err := reconciler.ReconcileNormal(rsCtx)
+					Expect(err).ToNot(HaveOccurred())
+					for _, vm := range getReplicas() {
+						Expect(vm.Annotations).To(HaveKeyWithValue(pkg.ClusterModuleNameKey, "workers"))
+					}
+				})
+			})
+
+			When("the cluster module group does not exist", func() {
+				BeforeEach(func() {
+					rs.Spec.ClusterModuleGroupName = "does-not-exist"
+				})
+
+				It("returns error and does not create the replicas", func() {
+					_, err := reconciler.ReconcileNormal(rsCtx)
+					Expect(err).To(HaveOccurred())
+					Expect(conditions.GetReason(rs, vmopv1alpha1.VirtualMachineReplicaSetReplicasReconciledCondition)).
+						To(Equal(vmopv1alpha1.VirtualMachineReplicaSetClusterModuleNotFoundReason))
+					Expect(getReplicas()).To(BeEmpty())
+				})
+			})
+		})
+
+		When("there are more replicas than desired", func() {
+			var (
+				oldReady, newReady, notReady, notCreated *vmopv1alpha1.VirtualMachine
+			)
+
+			BeforeEach(func() {
+				now := time.Now()
+				oldReady = newReplica("old-ready", now.Add(-time.Hour))
+				conditions.MarkTrue(oldReady, vmopv1alpha1.ReadyCondition)
+				newReady = newReplica("new-ready", now)
+				conditions.MarkTrue(newReady, vmopv1alpha1.ReadyCondition)
+				notReady = newReplica("not-ready", now.Add(-2*time.Hour))
+				notCreated = newReplica("not-created", now.Add(-3*time.Hour))
+				notCreated.Status.UniqueID = ""
+
+				rs.Spec.Replicas = pointer.Int32(1)
+				initObjects = append(initObjects, rs, oldReady, newReady, notReady, notCreated)
+			})
+
+			It("deletes the not created, not ready and newest replicas first", func() {
+				_, err := reconciler.ReconcileNormal(rsCtx)
+				Expect(err).ToNot(HaveOccurred())
+
+				replicas := getReplicas()
+				Expect(replicas).To(HaveLen(1))
+				Expect(replicas[0].Name).To(Equal(oldReady.Name))
+				Expect(rs.Status.Replicas).To(BeEquivalentTo(1))
+				Expect(rs.Status.ReadyReplicas).To(BeEquivalentTo(1))
+			})
+		})
+
+		When("a replica to delete is in the Endpoints of a VirtualMachineService", func() {
+			var (
+				oldVM, newVM *vmopv1alpha1.VirtualMachine
+				endpoints    *corev1.Endpoints
+			)
+
+			BeforeEach(func() {
+				now := time.Now()
+				oldVM = newReplica("old", now.Add(-time.Hour))
+				oldVM.UID = "old-uid"
+				conditions.MarkTrue(oldVM, vmopv1alpha1.ReadyCondition)
+				newVM = newReplica("new", now)
+				newVM.UID = "new-uid"
+				conditions.MarkTrue(newVM, vmopv1alpha1.ReadyCondition)
+
+				endpoints = &corev1.Endpoints{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "dummy-service",
+						Namespace: rs.Namespace,
+					},
+					Subsets: []corev1.EndpointSubset{
+						{
+							Addresses: []corev1.EndpointAddress{
+								{IP: "1.1.1.1", TargetRef: &corev1.ObjectReference{UID: oldVM.UID}},
+								{IP: "1.1.1.2", TargetRef: &corev1.ObjectReference{UID: newVM.UID}},
+							},
+						},
+					},
+				}
+
+				rs.Spec.Replicas = pointer.Int32(1)
+				initObjects = append(initObjects, rs, oldVM, newVM, endpoints)
+			})
+
+			It("drains the replica and deletes it once it is removed from the Endpoints", func() {
+				result, err := reconciler.ReconcileNormal(rsCtx)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.RequeueAfter).ToNot(BeZero())
+				Expect(rs.Status.Replicas).To(BeEquivalentTo(1))
+
+				replicas := getReplicas()
+				Expect(replicas).To(HaveLen(2))
+				for _, vm := range replicas {
+					if vm.Name == newVM.Name {
+						Expect(vm.Annotations).To(HaveKey(pkg.DrainingAnnotationKey))
+					} else {
+						Expect(vm.Annotations).ToNot(HaveKey(pkg.DrainingAnnotationKey))
+					}
+				}
+
+				endpoints.Subsets[0].Addresses = endpoints.Subsets[0].Addresses[:1]
+				Expect(ctx.Client.Update(ctx, endpoints)).To(Succeed())
+
+				result, err = reconciler.ReconcileNormal(rsCtx)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.RequeueAfter).To(BeZero())
+
+				replicas = getReplicas()
+				Expect(replicas).To(HaveLen(1))
+				Expect(replicas[0].Name).To(Equal(oldVM.Name))
+			})
+		})
+
+		When("there are VMs matching the selector that are not controlled by the replica set", func() {
+			BeforeEach(func() {
+				orphan := newReplica("orphan", time.Now())
+				orphan.OwnerReferences = nil
+				initObjects = append(initObjects, rs, orphan)
+			})
+
+			It("does not count them as replicas", func() {
+				_, err := reconciler.ReconcileNormal(rsCtx)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(getReplicas()).To(HaveLen(3))
+				Expect(rs.Status.Replicas).To(BeEquivalentTo(2))
+			})
+		})
+
+		When("the selector does not match the template labels", func() {
+			BeforeEach(func() {
+				rs.Spec.Selector.MatchLabels = map[string]string{"app": "other"}
+				initObjects = append(initObjects, rs)
+			})
+
+			It("returns error and does not create the replicas", func() {
+				_, err := reconciler.ReconcileNormal(rsCtx)
+				Expect(err).To(HaveOccurred())
+				Expect(conditions.GetReason(rs, vmopv1alpha1.VirtualMachineReplicaSetReplicasReconciledCondition)).
+					To(Equal(vmopv1alpha1.VirtualMachineReplicaSetSelectorMismatchReason))
+				Expect(getReplicas()).To(BeEmpty())
+			})
+		})
+	})
+}
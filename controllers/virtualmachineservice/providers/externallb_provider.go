@@ -0,0 +1,86 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package providers
+
+import (
+	"context"
+
+	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
+
+	"github.com/vmware-tanzu/vm-operator/controllers/virtualmachineservice/utils"
+	"github.com/vmware-tanzu/vm-operator/pkg/lib"
+)
+
+// ExternalLoadbalancerProvider delegates the load balancer to a third party controller that
+// implements Services of type LoadBalancer. The controller populates the Service's
+// status.loadBalancer, which is then reflected in the VirtualMachineService status.
+type ExternalLoadbalancerProvider struct {
+	// LoadBalancerClass is the loadBalancerClass set on the Service. When empty, the Service
+	// is implemented by the cluster's default load balancer controller.
+	LoadBalancerClass string
+	// IPPoolAnnotationKey is the Service annotation key that selects the IP pool. When empty,
+	// no IP pool annotation is set.
+	IPPoolAnnotationKey string
+	// DefaultIPPool is the IP pool used when the VirtualMachineService does not specify one.
+	DefaultIPPool string
+}
+
+// ExternalLoadBalancerProvider returns an ExternalLoadbalancerProvider instance configured
+// from the environment.
+func ExternalLoadBalancerProvider() *ExternalLoadbalancerProvider {
+	return &ExternalLoadbalancerProvider{
+		LoadBalancerClass:   lib.GetExternalLoadBalancerClass(),
+		IPPoolAnnotationKey: lib.GetExternalLoadBalancerIPPoolAnnotation(),
+		DefaultIPPool:       lib.GetExternalLoadBalancerIPPool(),
+	}
+}
+
+func (el *ExternalLoadbalancerProvider) EnsureLoadBalancer(ctx context.Context, vmService *vmopv1alpha1.VirtualMachineService) error {
+	return nil
+}
+
+func (el *ExternalLoadbalancerProvider) GetServiceLabels(ctx context.Context, vmService *vmopv1alpha1.VirtualMachineService) (map[string]string, error) {
+	return nil, nil
+}
+
+func (el *ExternalLoadbalancerProvider) GetToBeRemovedServiceLabels(ctx context.Context, vmService *vmopv1alpha1.VirtualMachineService) (map[string]string, error) {
+	return nil, nil
+}
+
+// GetServiceAnnotations provides the intended IP pool annotation on Service. The
+// responsibility is left to the caller to actually set them.
+func (el *ExternalLoadbalancerProvider) GetServiceAnnotations(ctx context.Context, vmService *vmopv1alpha1.VirtualMachineService) (map[string]string, error) {
+	res := make(map[string]string)
+
+	if pool := el.ipPool(vmService); el.IPPoolAnnotationKey != "" && pool != "" {
+		res[el.IPPoolAnnotationKey] = pool
+	}
+
+	return res, nil
+}
+
+// GetToBeRemovedServiceAnnotations provides the to be removed IP pool annotation on
+// Service. The responsibility is left to the caller to actually clear them.
+func (el *ExternalLoadbalancerProvider) GetToBeRemovedServiceAnnotations(ctx context.Context, vmService *vmopv1alpha1.VirtualMachineService) (map[string]string, error) {
+	res := make(map[string]string)
+
+	// When there is no IP pool, the corresponding annotation should be cleared as well.
+	if pool := el.ipPool(vmService); el.IPPoolAnnotationKey != "" && pool == "" {
+		res[el.IPPoolAnnotationKey] = ""
+	}
+
+	return res, nil
+}
+
+func (el *ExternalLoadbalancerProvider) GetServiceLoadBalancerClass(ctx context.Context, vmService *vmopv1alpha1.VirtualMachineService) (string, error) {
+	return el.LoadBalancerClass, nil
+}
+
+// ipPool returns the IP pool requested by the VirtualMachineService, or the default IP pool.
+func (el *ExternalLoadbalancerProvider) ipPool(vmService *vmopv1alpha1.VirtualMachineService) string {
+	if pool := vmService.Annotations[utils.AnnotationServiceIPPoolKey]; pool != "" {
+		return pool
+	}
+	return el.DefaultIPPool
+}
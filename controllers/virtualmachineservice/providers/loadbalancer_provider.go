@@ -1,4 +1,4 @@
-// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2019-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package providers
@@ -16,8 +16,9 @@ import (
 )
 
 const (
-	NSXTLoadBalancer   = "nsx-t-lb"
-	SimpleLoadBalancer = "simple-lb"
+	NSXTLoadBalancer     = "nsx-t-lb"
+	SimpleLoadBalancer   = "simple-lb"
+	ExternalLoadBalancer = "external-lb"
 
 	ServiceLoadBalancerHealthCheckNodePortTagKey = "ncp/healthCheckNodePort"
 	NSXTServiceProxy                             = "nsx-t"
@@ -64,6 +65,13 @@ type LoadbalancerProvider interface {
 	// annotations on the Service object without touching the existing ones,
 	// we need to have clearly defined ownership
 	GetToBeRemovedServiceAnnotations(ctx context.Context, vmService *vmopv1alpha1.VirtualMachineService) (map[string]string, error)
+
+	// GetServiceLoadBalancerClass returns the loadBalancerClass, if any, to set on a
+	// Service of type LoadBalancer.
+	// This is applicable when the Service is implemented by a load balancer controller
+	// other than the cluster's default one. The loadBalancerClass cannot be changed
+	// once set so it is only applied when the Service is created.
+	GetServiceLoadBalancerClass(ctx context.Context, vmService *vmopv1alpha1.VirtualMachineService) (string, error)
 }
 
 func GetLoadbalancerProviderByType(mgr manager.Manager, providerType string) (LoadbalancerProvider, error) {
@@ -73,6 +81,9 @@ func GetLoadbalancerProviderByType(mgr manager.Manager, providerType string) (Lo
 	if providerType == SimpleLoadBalancer {
 		return simplelb.New(mgr), nil
 	}
+	if providerType == ExternalLoadBalancer {
+		return ExternalLoadBalancerProvider(), nil
+	}
 	return NoopLoadbalancerProvider{}, nil
 }
 
@@ -98,6 +109,10 @@ func (NoopLoadbalancerProvider) GetToBeRemovedServiceAnnotations(ctx context.Con
 	return nil, nil
 }
 
+func (NoopLoadbalancerProvider) GetServiceLoadBalancerClass(ctx context.Context, vmService *vmopv1alpha1.VirtualMachineService) (string, error) {
+	return "", nil
+}
+
 type NsxtLoadbalancerProvider struct {
 }
 
@@ -163,3 +178,9 @@ func (nl *NsxtLoadbalancerProvider) GetToBeRemovedServiceAnnotations(ctx context
 
 	return res, nil
 }
+
+// GetServiceLoadBalancerClass returns an empty loadBalancerClass since NCP implements
+// Services of type LoadBalancer without one.
+func (nl *NsxtLoadbalancerProvider) GetServiceLoadBalancerClass(ctx context.Context, vmService *vmopv1alpha1.VirtualMachineService) (string, error) {
+	return "", nil
+}
@@ -1,4 +1,4 @@
-// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2019-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package providers
@@ -39,6 +39,12 @@ var _ = Describe("Loadbalancer Provider", func() {
 			Expect(lbProvider).ToNot(BeNil())
 		})
 
+		It("should successfully get external load balancer provider", func() {
+			lbProvider, err := GetLoadbalancerProviderByType(nil, ExternalLoadBalancer)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(lbProvider).To(BeAssignableToTypeOf(&ExternalLoadbalancerProvider{}))
+		})
+
 		It("should successfully get a noop loadbalancer provider", func() {
 			lbProvider, err := GetLoadbalancerProviderByType(nil, "")
 			Expect(err).NotTo(HaveOccurred())
@@ -232,4 +238,80 @@ var _ = Describe("Loadbalancer Provider", func() {
 			})
 		})
 	})
+
+	Context("external loadbalancer provider", func() {
+		const ipPoolAnnotation = "example.com/address-pool"
+
+		var externalProvider *ExternalLoadbalancerProvider
+
+		BeforeEach(func() {
+			vmService = &vmoperatorv1alpha1.VirtualMachineService{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "dummy-vmservice",
+					Namespace:   dummyNamespace,
+					Annotations: make(map[string]string),
+				},
+				Spec: vmoperatorv1alpha1.VirtualMachineServiceSpec{
+					Type: vmoperatorv1alpha1.VirtualMachineServiceTypeLoadBalancer,
+				},
+			}
+			externalProvider = &ExternalLoadbalancerProvider{
+				LoadBalancerClass:   "example.com/external-lb",
+				IPPoolAnnotationKey: ipPoolAnnotation,
+			}
+		})
+
+		Context("GetServiceLoadBalancerClass", func() {
+			It("should return the configured class", func() {
+				lbClass, err := externalProvider.GetServiceLoadBalancerClass(ctx, vmService)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lbClass).To(Equal("example.com/external-lb"))
+			})
+		})
+
+		Context("VMService does not have an IP pool", func() {
+			It("should not create the IP pool annotation", func() {
+				annotations, err := externalProvider.GetServiceAnnotations(ctx, vmService)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(annotations).To(BeEmpty())
+			})
+
+			It("should remove the IP pool annotation", func() {
+				annotations, err := externalProvider.GetToBeRemovedServiceAnnotations(ctx, vmService)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(annotations).To(HaveKey(ipPoolAnnotation))
+			})
+
+			Context("default IP pool is configured", func() {
+				BeforeEach(func() {
+					externalProvider.DefaultIPPool = "default-pool"
+				})
+
+				It("should create the IP pool annotation with the default pool", func() {
+					annotations, err := externalProvider.GetServiceAnnotations(ctx, vmService)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(annotations).To(HaveKeyWithValue(ipPoolAnnotation, "default-pool"))
+				})
+			})
+		})
+
+		Context("VMService has an IP pool", func() {
+			BeforeEach(func() {
+				externalProvider.DefaultIPPool = "default-pool"
+				vmService.Annotations[utils.AnnotationServiceIPPoolKey] = "my-pool"
+			})
+
+			It("should create the IP pool annotation with the VMService pool", func() {
+				annotations, err := externalProvider.GetServiceAnnotations(ctx, vmService)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(annotations).To(HaveKeyWithValue(ipPoolAnnotation, "my-pool"))
+			})
+
+			It("should not remove the IP pool annotation", func() {
+				annotations, err := externalProvider.GetToBeRemovedServiceAnnotations(ctx, vmService)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(annotations).To(BeEmpty())
+			})
+		})
+	})
 })
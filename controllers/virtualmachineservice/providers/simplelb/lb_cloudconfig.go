@@ -1,4 +1,4 @@
-// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2019-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package simplelb
@@ -9,8 +9,6 @@ import (
 	"text/template"
 
 	"sigs.k8s.io/yaml"
-
-	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
 )
 
 const XdsNodePort = 31799
@@ -19,50 +17,40 @@ var envoyBootstrapConfigTemplate, _ = template.New("envoyBootstrapConfig").Parse
 
 type lbConfigParams struct {
 	NodeID      string
-	Ports       []vmopv1alpha1.VirtualMachineServicePort
 	CPNodes     []string
 	XdsNodePort int
 }
 
+// envoyBootstrapConfig only configures how Envoy reaches the xDS server. The listeners,
+// clusters and endpoints are served dynamically by the XdsServer so they can change without
+// recreating the loadbalancer VM.
 const envoyBootstrapConfig = `node:
   id: {{.NodeID}}
   cluster: vmop-simple-lb
+dynamic_resources:
+  ads_config:
+    api_type: GRPC
+    transport_api_version: V3
+    grpc_services:
+    - envoy_grpc:
+        cluster_name: xds_cluster
+  lds_config:
+    resource_api_version: V3
+    ads: {}
+  cds_config:
+    resource_api_version: V3
+    ads: {}
 static_resources:
-  listeners:
-  # {{- range .Ports}}
-  - name: {{.Name}}
-    address:
-      socket_address:
-        address: 0.0.0.0
-        port_value: {{.Port}}
-    filter_chains:
-    - filters:
-      - name: envoy.tcp_proxy
-        config:
-          stat_prefix: ingress_tcp
-          cluster: {{.Name}}
-  # {{- end}}
-
   clusters:
-  # {{- range .Ports}}
-  - name: {{.Name}}
-    connect_timeout: 0.25s
-    type: EDS
-    lb_policy: ROUND_ROBIN
-    eds_cluster_config:
-      eds_config:
-        api_config_source:
-          api_type: GRPC
-          grpc_services:
-            envoy_grpc:
-              cluster_name: xds_cluster
-  # {{- end}}
-
   - name: xds_cluster
     connect_timeout: 0.25s
     type: STATIC
     lb_policy: ROUND_ROBIN
-    http2_protocol_options: {}
+    typed_extension_protocol_options:
+      envoy.extensions.upstreams.http.v3.HttpProtocolOptions:
+        "@type": type.googleapis.com/envoy.extensions.upstreams.http.v3.HttpProtocolOptions
+        explicit_http_config:
+          http2_protocol_options: {}
     upstream_connection_options:
       tcp_keepalive: {}
     load_assignment:
@@ -79,7 +67,6 @@ static_resources:
         # {{- end}}
 
 admin:
-  access_log_path: "/dev/null"
   address:
     socket_address:
       address: 0.0.0.0
@@ -1,4 +1,4 @@
-// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2019-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package simplelb
@@ -39,7 +39,6 @@ var _ = Describe("lbCloudConfig", func() {
 
 			params := lbConfigParams{
 				NodeID:      vmService.NamespacedName(),
-				Ports:       vmService.Spec.Ports,
 				CPNodes:     []string{"10.10.00.3"},
 				XdsNodePort: XdsNodePort,
 			}
@@ -51,6 +50,12 @@ var _ = Describe("lbCloudConfig", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(s).To(ContainSubstring(vmService.NamespacedName()))
 				Expect(s).ToNot(ContainSubstring("\t"))
+				Expect(s).To(ContainSubstring("10.10.00.3"))
+			})
+
+			It("should not render the listeners statically", func() {
+				Expect(s).To(ContainSubstring("lds_config"))
+				Expect(s).ToNot(ContainSubstring("listeners:"))
 			})
 
 			Context("renderAndBase64EncodeLBCloudConfig()", func() {
@@ -1,4 +1,4 @@
-// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2019-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package simplelb
@@ -30,7 +30,7 @@ type Provider struct {
 }
 
 type loadbalancerControlPlane interface {
-	UpdateEndpoints(*corev1.Service, *corev1.Endpoints) error
+	UpdateEndpoints(*corev1.Service, *corev1.Endpoints, *vmopv1alpha1.Probe) error
 }
 
 func New(mgr manager.Manager) *Provider {
@@ -118,6 +118,10 @@ func (s *Provider) GetToBeRemovedServiceAnnotations(ctx context.Context, vmServi
 	return nil, nil
 }
 
+func (s *Provider) GetServiceLoadBalancerClass(ctx context.Context, vmService *vmopv1alpha1.VirtualMachineService) (string, error) {
+	return "", nil
+}
+
 func (s *Provider) ensureLBVM(ctx context.Context, vm *vmopv1alpha1.VirtualMachine, cm *corev1.ConfigMap) error {
 	if err := s.client.Get(ctx, types.NamespacedName{Namespace: cm.Namespace, Name: cm.Name}, cm); err != nil {
 		if !apierrors.IsNotFound(err) {
@@ -234,7 +238,32 @@ func (s *Provider) updateLBConfig(ctx context.Context, vmService *vmopv1alpha1.V
 		}
 		return err
 	}
-	return s.controlPlane.UpdateEndpoints(service, endpoints)
+	probe, err := s.getReadinessProbe(ctx, vmService)
+	if err != nil {
+		return err
+	}
+	return s.controlPlane.UpdateEndpoints(service, endpoints, probe)
+}
+
+// getReadinessProbe returns the readiness probe of the VMs selected by the VMService, which
+// the loadbalancer uses to health check the endpoints. The VMs backing a VMService are
+// expected to share the same probe, so the first one found is returned.
+func (s *Provider) getReadinessProbe(ctx context.Context, vmService *vmopv1alpha1.VirtualMachineService) (*vmopv1alpha1.Probe, error) {
+	if len(vmService.Spec.Selector) == 0 {
+		return nil, nil
+	}
+
+	vmList := &vmopv1alpha1.VirtualMachineList{}
+	if err := s.client.List(ctx, vmList, client.InNamespace(vmService.Namespace), client.MatchingLabels(vmService.Spec.Selector)); err != nil {
+		return nil, err
+	}
+
+	for i := range vmList.Items {
+		if probe := vmList.Items[i].Spec.ReadinessProbe; probe != nil {
+			return probe, nil
+		}
+	}
+	return nil, nil
 }
 
 func (s *Provider) getXDSNodes(ctx context.Context) ([]corev1.Node, error) {
@@ -245,30 +274,13 @@ func (s *Provider) getXDSNodes(ctx context.Context) ([]corev1.Node, error) {
 	return nodeList.Items, nil
 }
 
-func portName(vmSvcPort vmopv1alpha1.VirtualMachineServicePort) string {
-	if vmSvcPort.Name == "" {
-		protocol := vmSvcPort.Protocol
-		if protocol == "" {
-			protocol = string(corev1.ProtocolTCP)
-		}
-		return fmt.Sprintf("%s-%v", protocol, vmSvcPort.Port)
-	}
-	return vmSvcPort.Name
-}
-
 func getLBConfigParams(vmService *vmopv1alpha1.VirtualMachineService, nodes []corev1.Node) lbConfigParams {
 	var cpNodes = make([]string, len(nodes))
 	for i, node := range nodes {
 		cpNodes[i] = node.Status.Addresses[0].Address
 	}
-	ports := make([]vmopv1alpha1.VirtualMachineServicePort, len(vmService.Spec.Ports))
-	for i, port := range vmService.Spec.Ports {
-		port.Name = portName(port)
-		ports[i] = port
-	}
 	return lbConfigParams{
 		NodeID:      vmService.NamespacedName(),
-		Ports:       ports,
 		CPNodes:     cpNodes,
 		XdsNodePort: XdsNodePort,
 	}
@@ -1,4 +1,4 @@
-// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2019-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package simplelb
@@ -23,16 +23,18 @@ import (
 type cpArgs struct {
 	service   *corev1.Service
 	endpoints *corev1.Endpoints
+	probe     *vmopv1alpha1.Probe
 }
 
 type fakeControlPlane struct {
 	calls []cpArgs
 }
 
-func (cp *fakeControlPlane) UpdateEndpoints(service *corev1.Service, endpoints *corev1.Endpoints) error {
+func (cp *fakeControlPlane) UpdateEndpoints(service *corev1.Service, endpoints *corev1.Endpoints, probe *vmopv1alpha1.Probe) error {
 	cp.calls = append(cp.calls, cpArgs{
 		service:   service,
 		endpoints: endpoints,
+		probe:     probe,
 	})
 	return nil
 }
@@ -49,6 +51,7 @@ var _ = Describe("", func() {
 			Name:      testSvc,
 		},
 		Spec: vmopv1alpha1.VirtualMachineServiceSpec{
+			Selector: map[string]string{"app": "backend"},
 			Ports: []vmopv1alpha1.VirtualMachineServicePort{{
 				Name:       "apiserver",
 				Port:       6443,
@@ -192,6 +195,32 @@ var _ = Describe("", func() {
 				Expect(controlPlane.calls).To(HaveLen(1))
 				Expect(controlPlane.calls[0].service.Name).To(Equal(svc.Name))
 				Expect(controlPlane.calls[0].endpoints.Name).To(Equal(eps.Name))
+				Expect(controlPlane.calls[0].probe).To(BeNil())
+			})
+
+			It("should pass the readiness probe of the selected VMs to the LB control plane", func() {
+				backendVM := &vmopv1alpha1.VirtualMachine{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: testNs,
+						Name:      "backend-vm",
+						Labels:    vmService.Spec.Selector,
+					},
+					Spec: vmopv1alpha1.VirtualMachineSpec{
+						ReadinessProbe: &vmopv1alpha1.Probe{
+							TCPSocket: &vmopv1alpha1.TCPSocketAction{
+								Port: intstr.FromInt(port),
+							},
+						},
+					},
+				}
+				Expect(client.Create(context.TODO(), backendVM)).To(Succeed())
+
+				controlPlane.calls = nil
+				err := simpleLbProvider.EnsureLoadBalancer(context.TODO(), vmService)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(controlPlane.calls).To(HaveLen(1))
+				Expect(controlPlane.calls[0].probe).To(Equal(backendVM.Spec.ReadinessProbe))
 			})
 		})
 	})
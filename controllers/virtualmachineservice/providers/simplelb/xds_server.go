@@ -1,4 +1,4 @@
-// Copyright (c) 2019-2021 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2019-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package simplelb
@@ -6,18 +6,54 @@ package simplelb
 import (
 	"context"
 	"fmt"
+	"hash/fnv"
 	"net"
+	"time"
 
-	envoy_api_v2 "github.com/envoyproxy/go-control-plane/envoy/api/v2"
-	envoy_api_v2_core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
-	envoy_api_v2_endpoint "github.com/envoyproxy/go-control-plane/envoy/api/v2/endpoint"
-	"github.com/envoyproxy/go-control-plane/pkg/cache"
-	xds "github.com/envoyproxy/go-control-plane/pkg/server"
+	envoy_config_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	envoy_config_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_config_endpoint_v3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	envoy_config_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	envoy_extensions_tcp_proxy_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
+	envoy_extensions_udp_proxy_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/udp/udp_proxy/v3"
+	envoy_extensions_proxy_protocol_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/proxy_protocol/v3"
+	envoy_extensions_raw_buffer_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/raw_buffer/v3"
+	envoy_service_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/service/cluster/v3"
+	envoy_service_discovery_v3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	envoy_service_endpoint_v3 "github.com/envoyproxy/go-control-plane/envoy/service/endpoint/v3"
+	envoy_service_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/service/listener/v3"
+	envoy_types "github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	xds "github.com/envoyproxy/go-control-plane/pkg/server/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"github.com/go-logr/logr"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
+
+	"github.com/vmware-tanzu/vm-operator/controllers/virtualmachineservice/utils"
+)
+
+const (
+	clusterConnectTimeout = 250 * time.Millisecond
+
+	// Envoy extension names not defined by the wellknown package.
+	udpProxyFilterName                   = "envoy.filters.udp_listener.udp_proxy"
+	upstreamProxyProtocolTransportSocket = "envoy.transport_sockets.upstream_proxy_protocol"
+
+	// Defaults for the cluster health checks, matching the defaults of a VirtualMachine Probe.
+	defaultHealthCheckTimeoutSeconds  = 10
+	defaultHealthCheckIntervalSeconds = 10
+	healthCheckHealthyThreshold       = 1
+	healthCheckUnhealthyThreshold     = 3
 )
 
 type XdsServer struct {
@@ -27,7 +63,7 @@ type XdsServer struct {
 
 func NewXdsServer(mgr manager.Manager, logger logr.Logger) *XdsServer {
 	x := &XdsServer{
-		snapshotCache: cache.NewSnapshotCache(false, cache.IDHash{}, nil),
+		snapshotCache: cache.NewSnapshotCache(true, cache.IDHash{}, nil),
 		log:           logger,
 	}
 	_ = mgr.Add(x) // nothing can go wrong (we don't inject stuff)
@@ -43,7 +79,10 @@ func (x *XdsServer) Start(ctx context.Context) error {
 		return err
 	}
 
-	envoy_api_v2.RegisterEndpointDiscoveryServiceServer(grpcServer, server)
+	envoy_service_discovery_v3.RegisterAggregatedDiscoveryServiceServer(grpcServer, server)
+	envoy_service_listener_v3.RegisterListenerDiscoveryServiceServer(grpcServer, server)
+	envoy_service_cluster_v3.RegisterClusterDiscoveryServiceServer(grpcServer, server)
+	envoy_service_endpoint_v3.RegisterEndpointDiscoveryServiceServer(grpcServer, server)
 
 	go func() {
 		<-ctx.Done()
@@ -53,79 +92,294 @@ func (x *XdsServer) Start(ctx context.Context) error {
 	return grpcServer.Serve(lis)
 }
 
-func (x *XdsServer) UpdateEndpoints(svc *corev1.Service, eps *corev1.Endpoints) error {
-	clusters := make([]cache.Resource, len(svc.Spec.Ports))
-	endpoints := make([]cache.Resource, len(svc.Spec.Ports))
+// UpdateEndpoints sets the listeners, clusters and endpoints of the Service's loadbalancer.
+// When a readiness probe is given, the clusters are configured to health check their
+// endpoints with it.
+func (x *XdsServer) UpdateEndpoints(svc *corev1.Service, eps *corev1.Endpoints, probe *vmopv1alpha1.Probe) error {
+	listeners := make([]envoy_types.Resource, len(svc.Spec.Ports))
+	clusters := make([]envoy_types.Resource, len(svc.Spec.Ports))
+	endpoints := make([]envoy_types.Resource, len(svc.Spec.Ports))
+	proxyProtocol := svc.Annotations[utils.AnnotationServiceProxyProtocolKey] == utils.ServiceProxyProtocolV2
 	for i, svcPort := range svc.Spec.Ports {
-		clusters[i] = cluster(svcPort)
-		endpoints[i] = clusterEndpoints(svcPort, eps.Subsets)
+		l, err := listener(svcPort)
+		if err != nil {
+			return err
+		}
+		c, err := cluster(svcPort, probe, proxyProtocol)
+		if err != nil {
+			return err
+		}
+		listeners[i] = l
+		clusters[i] = c
+		endpoints[i] = clusterEndpoints(svcPort, eps.Subsets, probe)
+	}
+
+	version, err := snapshotVersion(listeners, clusters, endpoints)
+	if err != nil {
+		return err
 	}
 
 	nodeID := nodeID(svc)
-	snapshot := cache.NewSnapshot(eps.ResourceVersion, endpoints, clusters, nil, nil, nil)
+	snapshot, err := cache.NewSnapshot(version, map[resource.Type][]envoy_types.Resource{
+		resource.ListenerType: listeners,
+		resource.ClusterType:  clusters,
+		resource.EndpointType: endpoints,
+	})
+	if err != nil {
+		return err
+	}
 
 	x.log.V(5).Info("setting xds snapshot", "nodeID", nodeID, "snapshot", snapshot)
-	return x.snapshotCache.SetSnapshot(nodeID, snapshot)
+	return x.snapshotCache.SetSnapshot(context.Background(), nodeID, snapshot)
 }
 
 func nodeID(svc *corev1.Service) string {
 	return types.NamespacedName{Namespace: svc.Namespace, Name: svc.Name}.String()
 }
 
+// snapshotVersion returns a version derived from the content of the resources, so that
+// Envoy is only pushed an update when its configuration actually changes.
+func snapshotVersion(resources ...[]envoy_types.Resource) (string, error) {
+	h := fnv.New64a()
+	marshal := proto.MarshalOptions{Deterministic: true}
+	for _, rs := range resources {
+		for _, r := range rs {
+			b, err := marshal.Marshal(r)
+			if err != nil {
+				return "", err
+			}
+			_, _ = h.Write(b)
+		}
+	}
+	return fmt.Sprintf("%x", h.Sum64()), nil
+}
+
 func clusterName(svcPort corev1.ServicePort) string {
 	if svcPort.Name == "" {
-		protocol := string(svcPort.Protocol)
-		if protocol == "" {
-			protocol = string(corev1.ProtocolTCP)
-		}
-		return fmt.Sprintf("%s-%v", protocol, svcPort.Port)
+		return fmt.Sprintf("%s-%v", protocol(svcPort.Protocol), svcPort.Port)
 	}
 	return svcPort.Name
 }
 
-func clusterEndpoints(svcPort corev1.ServicePort, subsets []corev1.EndpointSubset) *envoy_api_v2.ClusterLoadAssignment {
-	var lbEndpoints []*envoy_api_v2_endpoint.LbEndpoint
+// protocol returns the protocol, defaulting to TCP like Kubernetes does.
+func protocol(p corev1.Protocol) corev1.Protocol {
+	if p == "" {
+		return corev1.ProtocolTCP
+	}
+	return p
+}
+
+func isUDP(svcPort corev1.ServicePort) bool {
+	return protocol(svcPort.Protocol) == corev1.ProtocolUDP
+}
+
+func socketAddress(address string, port uint32, proto envoy_config_core_v3.SocketAddress_Protocol) *envoy_config_core_v3.Address {
+	return &envoy_config_core_v3.Address{
+		Address: &envoy_config_core_v3.Address_SocketAddress{
+			SocketAddress: &envoy_config_core_v3.SocketAddress{
+				Protocol: proto,
+				Address:  address,
+				PortSpecifier: &envoy_config_core_v3.SocketAddress_PortValue{
+					PortValue: port,
+				},
+			},
+		},
+	}
+}
+
+func listener(svcPort corev1.ServicePort) (*envoy_config_listener_v3.Listener, error) {
+	if isUDP(svcPort) {
+		return udpListener(svcPort)
+	}
+
+	tcpProxy, err := anypb.New(&envoy_extensions_tcp_proxy_v3.TcpProxy{
+		StatPrefix: "ingress_tcp",
+		ClusterSpecifier: &envoy_extensions_tcp_proxy_v3.TcpProxy_Cluster{
+			Cluster: clusterName(svcPort),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &envoy_config_listener_v3.Listener{
+		Name:    clusterName(svcPort),
+		Address: socketAddress("0.0.0.0", uint32(svcPort.Port), envoy_config_core_v3.SocketAddress_TCP),
+		FilterChains: []*envoy_config_listener_v3.FilterChain{{
+			Filters: []*envoy_config_listener_v3.Filter{{
+				Name: wellknown.TCPProxy,
+				ConfigType: &envoy_config_listener_v3.Filter_TypedConfig{
+					TypedConfig: tcpProxy,
+				},
+			}},
+		}},
+	}, nil
+}
+
+// udpListener returns a listener that proxies the UDP datagrams it receives to the cluster.
+// UDP listeners have no filter chains; the proxying is done by a listener filter.
+func udpListener(svcPort corev1.ServicePort) (*envoy_config_listener_v3.Listener, error) {
+	udpProxy, err := anypb.New(&envoy_extensions_udp_proxy_v3.UdpProxyConfig{
+		StatPrefix: "ingress_udp",
+		RouteSpecifier: &envoy_extensions_udp_proxy_v3.UdpProxyConfig_Cluster{
+			Cluster: clusterName(svcPort),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &envoy_config_listener_v3.Listener{
+		Name:              clusterName(svcPort),
+		Address:           socketAddress("0.0.0.0", uint32(svcPort.Port), envoy_config_core_v3.SocketAddress_UDP),
+		UdpListenerConfig: &envoy_config_listener_v3.UdpListenerConfig{},
+		ListenerFilters: []*envoy_config_listener_v3.ListenerFilter{{
+			Name: udpProxyFilterName,
+			ConfigType: &envoy_config_listener_v3.ListenerFilter_TypedConfig{
+				TypedConfig: udpProxy,
+			},
+		}},
+	}, nil
+}
+
+// healthCheckPort returns the port the probe checks, or zero when the probe checks the
+// endpoint port or is given by name.
+func healthCheckPort(probe *vmopv1alpha1.Probe) uint32 {
+	if probe == nil || probe.TCPSocket == nil || probe.TCPSocket.Port.IntValue() <= 0 {
+		return 0
+	}
+	return uint32(probe.TCPSocket.Port.IntValue())
+}
+
+func clusterEndpoints(svcPort corev1.ServicePort, subsets []corev1.EndpointSubset, probe *vmopv1alpha1.Probe) *envoy_config_endpoint_v3.ClusterLoadAssignment {
+	var lbEndpoints []*envoy_config_endpoint_v3.LbEndpoint
 
 	for _, subset := range subsets {
 		for _, endpointPort := range subset.Ports {
-			if endpointPort.Port != svcPort.TargetPort.IntVal {
+			if endpointPort.Port != svcPort.TargetPort.IntVal || protocol(endpointPort.Protocol) != protocol(svcPort.Protocol) {
 				continue
 			}
 			for _, endpointAddress := range subset.Addresses {
-				lbEndpoints = append(lbEndpoints, &envoy_api_v2_endpoint.LbEndpoint{
-					HostIdentifier: &envoy_api_v2_endpoint.LbEndpoint_Endpoint{
-						Endpoint: &envoy_api_v2_endpoint.Endpoint{
-							Address: &envoy_api_v2_core.Address{
-								Address: &envoy_api_v2_core.Address_SocketAddress{
-									SocketAddress: &envoy_api_v2_core.SocketAddress{
-										Protocol: envoy_api_v2_core.SocketAddress_TCP,
-										Address:  endpointAddress.IP,
-										PortSpecifier: &envoy_api_v2_core.SocketAddress_PortValue{
-											PortValue: uint32(endpointPort.Port),
-										},
-									},
-								},
-							},
-						},
+				// Upstream addresses are always TCP socket addresses; the protocol used to reach
+				// them is determined by the listener.
+				endpoint := &envoy_config_endpoint_v3.Endpoint{
+					Address: socketAddress(endpointAddress.IP, uint32(endpointPort.Port), envoy_config_core_v3.SocketAddress_TCP),
+				}
+				if port := healthCheckPort(probe); port != 0 && port != uint32(endpointPort.Port) {
+					endpoint.HealthCheckConfig = &envoy_config_endpoint_v3.Endpoint_HealthCheckConfig{
+						PortValue: port,
+					}
+				}
+				lbEndpoints = append(lbEndpoints, &envoy_config_endpoint_v3.LbEndpoint{
+					HostIdentifier: &envoy_config_endpoint_v3.LbEndpoint_Endpoint{
+						Endpoint: endpoint,
 					},
 				})
 			}
 		}
 	}
 
-	return &envoy_api_v2.ClusterLoadAssignment{
+	return &envoy_config_endpoint_v3.ClusterLoadAssignment{
 		ClusterName: clusterName(svcPort),
-		Endpoints: []*envoy_api_v2_endpoint.LocalityLbEndpoints{{
+		Endpoints: []*envoy_config_endpoint_v3.LocalityLbEndpoints{{
 			LbEndpoints: lbEndpoints,
 		}},
 	}
 }
 
-func cluster(svcPort corev1.ServicePort) *envoy_api_v2.Cluster {
-	return &envoy_api_v2.Cluster{
-		Name: clusterName(svcPort),
-		ClusterDiscoveryType: &envoy_api_v2.Cluster_Type{
-			Type: envoy_api_v2.Cluster_EDS,
+func cluster(svcPort corev1.ServicePort, probe *vmopv1alpha1.Probe, proxyProtocol bool) (*envoy_config_cluster_v3.Cluster, error) {
+	c := &envoy_config_cluster_v3.Cluster{
+		Name:           clusterName(svcPort),
+		ConnectTimeout: durationpb.New(clusterConnectTimeout),
+		ClusterDiscoveryType: &envoy_config_cluster_v3.Cluster_Type{
+			Type: envoy_config_cluster_v3.Cluster_EDS,
+		},
+		LbPolicy: envoy_config_cluster_v3.Cluster_ROUND_ROBIN,
+		EdsClusterConfig: &envoy_config_cluster_v3.Cluster_EdsClusterConfig{
+			EdsConfig: &envoy_config_core_v3.ConfigSource{
+				ResourceApiVersion: envoy_config_core_v3.ApiVersion_V3,
+				ConfigSourceSpecifier: &envoy_config_core_v3.ConfigSource_Ads{
+					Ads: &envoy_config_core_v3.AggregatedConfigSource{},
+				},
+			},
+		},
+	}
+
+	// A TCP health check against the UDP port would always fail, so UDP clusters are only
+	// health checked when the probe explicitly targets a port.
+	if !isUDP(svcPort) || healthCheckPort(probe) != 0 {
+		if hc := healthCheck(probe); hc != nil {
+			c.HealthChecks = []*envoy_config_core_v3.HealthCheck{hc}
+		}
+	}
+
+	// The PROXY protocol header is only supported on TCP connections.
+	if proxyProtocol && !isUDP(svcPort) {
+		ts, err := proxyProtocolTransportSocket()
+		if err != nil {
+			return nil, err
+		}
+		c.TransportSocket = ts
+	}
+
+	return c, nil
+}
+
+// proxyProtocolTransportSocket returns a transport socket that prepends a PROXY protocol v2
+// header to the upstream connections, so that the backends see the client addresses.
+func proxyProtocolTransportSocket() (*envoy_config_core_v3.TransportSocket, error) {
+	rawBuffer, err := anypb.New(&envoy_extensions_raw_buffer_v3.RawBuffer{})
+	if err != nil {
+		return nil, err
+	}
+
+	proxyProtocol, err := anypb.New(&envoy_extensions_proxy_protocol_v3.ProxyProtocolUpstreamTransport{
+		Config: &envoy_config_core_v3.ProxyProtocolConfig{
+			Version: envoy_config_core_v3.ProxyProtocolConfig_V2,
+		},
+		TransportSocket: &envoy_config_core_v3.TransportSocket{
+			Name: wellknown.TransportSocketRawBuffer,
+			ConfigType: &envoy_config_core_v3.TransportSocket_TypedConfig{
+				TypedConfig: rawBuffer,
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &envoy_config_core_v3.TransportSocket{
+		Name: upstreamProxyProtocolTransportSocket,
+		ConfigType: &envoy_config_core_v3.TransportSocket_TypedConfig{
+			TypedConfig: proxyProtocol,
+		},
+	}, nil
+}
+
+// healthCheck returns the cluster health check equivalent to the VM readiness probe. Only
+// TCP probes can be performed by Envoy; other probes are left to the VirtualMachine
+// controller, which removes not ready VMs from the Endpoints.
+func healthCheck(probe *vmopv1alpha1.Probe) *envoy_config_core_v3.HealthCheck {
+	if probe == nil || probe.TCPSocket == nil {
+		return nil
+	}
+
+	timeoutSeconds := probe.TimeoutSeconds
+	if timeoutSeconds <= 0 {
+		timeoutSeconds = defaultHealthCheckTimeoutSeconds
+	}
+	intervalSeconds := probe.PeriodSeconds
+	if intervalSeconds <= 0 {
+		intervalSeconds = defaultHealthCheckIntervalSeconds
+	}
+
+	return &envoy_config_core_v3.HealthCheck{
+		Timeout:            durationpb.New(time.Duration(timeoutSeconds) * time.Second),
+		Interval:           durationpb.New(time.Duration(intervalSeconds) * time.Second),
+		HealthyThreshold:   wrapperspb.UInt32(healthCheckHealthyThreshold),
+		UnhealthyThreshold: wrapperspb.UInt32(healthCheckUnhealthyThreshold),
+		HealthChecker: &envoy_config_core_v3.HealthCheck_TcpHealthCheck_{
+			TcpHealthCheck: &envoy_config_core_v3.HealthCheck_TcpHealthCheck{},
 		},
 	}
 }
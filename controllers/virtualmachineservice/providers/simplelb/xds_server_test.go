@@ -1,10 +1,16 @@
-// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2019-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package simplelb
 
 import (
-	"github.com/envoyproxy/go-control-plane/pkg/cache"
+	envoy_config_cluster_v3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	envoy_config_core_v3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_config_endpoint_v3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	envoy_config_listener_v3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	envoy_extensions_proxy_protocol_v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/proxy_protocol/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
 	"github.com/go-logr/logr"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -12,6 +18,10 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+
+	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
+
+	"github.com/vmware-tanzu/vm-operator/controllers/virtualmachineservice/utils"
 )
 
 var _ = Describe("xdsServer", func() {
@@ -20,13 +30,14 @@ var _ = Describe("xdsServer", func() {
 		testSvc      = "test-svc"
 		epResVersion = "123"
 		port         = 6443
+		probePort    = 8080
 		portName     = "apiserver"
 		ip1          = "10.11.12.13"
 		ip2          = "21.22.23.24"
 	)
 
 	x := &XdsServer{
-		snapshotCache: cache.NewSnapshotCache(false, cache.IDHash{}, nil),
+		snapshotCache: cache.NewSnapshotCache(true, cache.IDHash{}, nil),
 		log:           logr.Discard(),
 	}
 
@@ -62,25 +73,172 @@ var _ = Describe("xdsServer", func() {
 		}},
 	}
 
-	It("UpdateEndpoints()", func() {
-		err := x.UpdateEndpoints(svc, eps)
-		Expect(err).ToNot(HaveOccurred())
-
+	getSnapshot := func() *cache.Snapshot {
 		snapshot, err := x.snapshotCache.GetSnapshot(nodeID(svc))
 		Expect(err).ToNot(HaveOccurred())
+		Expect(snapshot).To(BeAssignableToTypeOf(&cache.Snapshot{}))
+		return snapshot.(*cache.Snapshot)
+	}
 
-		err = snapshot.Consistent()
+	It("UpdateEndpoints()", func() {
+		err := x.UpdateEndpoints(svc, eps, nil)
 		Expect(err).ToNot(HaveOccurred())
 
-		Expect(snapshot.GetVersion(cache.EndpointType)).To(Equal(epResVersion))
-		Expect(snapshot.GetVersion(cache.ClusterType)).To(Equal(epResVersion))
+		snapshot := getSnapshot()
+		Expect(snapshot.Consistent()).To(Succeed())
 
-		clusters := snapshot.GetResources(cache.ClusterType)
-		endpoints := snapshot.GetResources(cache.EndpointType)
+		listeners := snapshot.GetResources(resource.ListenerType)
+		clusters := snapshot.GetResources(resource.ClusterType)
+		endpoints := snapshot.GetResources(resource.EndpointType)
+		Expect(listeners).To(HaveLen(1))
 		Expect(clusters).To(HaveLen(1))
 		Expect(endpoints).To(HaveLen(1))
+
+		Expect(listeners[portName]).ToNot(BeNil())
+		l := listeners[portName].(*envoy_config_listener_v3.Listener)
+		Expect(l.Address.GetSocketAddress().GetPortValue()).To(BeEquivalentTo(port))
+
+		Expect(clusters[portName]).ToNot(BeNil())
+		Expect(clusters[portName].(*envoy_config_cluster_v3.Cluster).HealthChecks).To(BeEmpty())
+
 		Expect(endpoints[portName]).ToNot(BeNil())
-		Expect(endpoints[portName].String()).To(ContainSubstring(ip1))
-		Expect(endpoints[portName].String()).To(ContainSubstring(ip2))
+		cla := endpoints[portName].(*envoy_config_endpoint_v3.ClusterLoadAssignment)
+		Expect(cla.String()).To(ContainSubstring(ip1))
+		Expect(cla.String()).To(ContainSubstring(ip2))
+	})
+
+	It("UpdateEndpoints() only changes the version when the configuration changes", func() {
+		Expect(x.UpdateEndpoints(svc, eps, nil)).To(Succeed())
+		version := getSnapshot().GetVersion(resource.ListenerType)
+
+		Expect(x.UpdateEndpoints(svc, eps, nil)).To(Succeed())
+		Expect(getSnapshot().GetVersion(resource.ListenerType)).To(Equal(version))
+
+		newSvc := svc.DeepCopy()
+		newSvc.Spec.Ports[0].Port = port + 1
+		Expect(x.UpdateEndpoints(newSvc, eps, nil)).To(Succeed())
+		snapshot := getSnapshot()
+		Expect(snapshot.GetVersion(resource.ListenerType)).ToNot(Equal(version))
+		l := snapshot.GetResources(resource.ListenerType)[portName].(*envoy_config_listener_v3.Listener)
+		Expect(l.Address.GetSocketAddress().GetPortValue()).To(BeEquivalentTo(port + 1))
+	})
+
+	It("UpdateEndpoints() with a TCP readiness probe", func() {
+		probe := &vmopv1alpha1.Probe{
+			TCPSocket: &vmopv1alpha1.TCPSocketAction{
+				Port: intstr.FromInt(probePort),
+			},
+			TimeoutSeconds: 5,
+		}
+		Expect(x.UpdateEndpoints(svc, eps, probe)).To(Succeed())
+
+		snapshot := getSnapshot()
+		Expect(snapshot.Consistent()).To(Succeed())
+
+		c := snapshot.GetResources(resource.ClusterType)[portName].(*envoy_config_cluster_v3.Cluster)
+		Expect(c.HealthChecks).To(HaveLen(1))
+		Expect(c.HealthChecks[0].GetTcpHealthCheck()).ToNot(BeNil())
+		Expect(c.HealthChecks[0].Timeout.AsDuration().Seconds()).To(BeEquivalentTo(5))
+		Expect(c.HealthChecks[0].Interval.AsDuration().Seconds()).To(BeEquivalentTo(defaultHealthCheckIntervalSeconds))
+
+		cla := snapshot.GetResources(resource.EndpointType)[portName].(*envoy_config_endpoint_v3.ClusterLoadAssignment)
+		lbEndpoints := cla.Endpoints[0].LbEndpoints
+		Expect(lbEndpoints).To(HaveLen(2))
+		for _, lbEndpoint := range lbEndpoints {
+			Expect(lbEndpoint.GetEndpoint().HealthCheckConfig.GetPortValue()).To(BeEquivalentTo(probePort))
+		}
+	})
+
+	It("UpdateEndpoints() with a GuestHeartbeat readiness probe", func() {
+		probe := &vmopv1alpha1.Probe{
+			GuestHeartbeat: &vmopv1alpha1.GuestHeartbeatAction{},
+		}
+		Expect(x.UpdateEndpoints(svc, eps, probe)).To(Succeed())
+
+		c := getSnapshot().GetResources(resource.ClusterType)[portName].(*envoy_config_cluster_v3.Cluster)
+		Expect(c.HealthChecks).To(BeEmpty())
+	})
+
+	It("UpdateEndpoints() with a UDP port", func() {
+		const (
+			dnsPort     = 53
+			dnsPortName = "dns"
+		)
+
+		udpSvc := svc.DeepCopy()
+		udpSvc.Spec.Ports = append(udpSvc.Spec.Ports, corev1.ServicePort{
+			Name:       dnsPortName,
+			Protocol:   corev1.ProtocolUDP,
+			Port:       dnsPort,
+			TargetPort: intstr.FromInt(dnsPort),
+		})
+		udpEps := eps.DeepCopy()
+		udpEps.Subsets[0].Ports = append(udpEps.Subsets[0].Ports, corev1.EndpointPort{
+			Name:     dnsPortName,
+			Port:     dnsPort,
+			Protocol: corev1.ProtocolUDP,
+		})
+		probe := &vmopv1alpha1.Probe{
+			TCPSocket: &vmopv1alpha1.TCPSocketAction{
+				Port: intstr.FromString("apiserver"),
+			},
+		}
+		Expect(x.UpdateEndpoints(udpSvc, udpEps, probe)).To(Succeed())
+
+		snapshot := getSnapshot()
+		Expect(snapshot.Consistent()).To(Succeed())
+
+		listeners := snapshot.GetResources(resource.ListenerType)
+		Expect(listeners).To(HaveLen(2))
+		l := listeners[dnsPortName].(*envoy_config_listener_v3.Listener)
+		Expect(l.Address.GetSocketAddress().GetProtocol()).To(Equal(envoy_config_core_v3.SocketAddress_UDP))
+		Expect(l.UdpListenerConfig).ToNot(BeNil())
+		Expect(l.FilterChains).To(BeEmpty())
+		Expect(l.ListenerFilters).To(HaveLen(1))
+		Expect(l.ListenerFilters[0].Name).To(Equal(udpProxyFilterName))
+
+		clusters := snapshot.GetResources(resource.ClusterType)
+		Expect(clusters[portName].(*envoy_config_cluster_v3.Cluster).HealthChecks).To(HaveLen(1))
+		Expect(clusters[dnsPortName].(*envoy_config_cluster_v3.Cluster).HealthChecks).To(BeEmpty())
+
+		endpoints := snapshot.GetResources(resource.EndpointType)
+		cla := endpoints[dnsPortName].(*envoy_config_endpoint_v3.ClusterLoadAssignment)
+		Expect(cla.Endpoints[0].LbEndpoints).To(HaveLen(2))
+		for _, lbEndpoint := range cla.Endpoints[0].LbEndpoints {
+			Expect(lbEndpoint.GetEndpoint().Address.GetSocketAddress().GetPortValue()).To(BeEquivalentTo(dnsPort))
+		}
+	})
+
+	Context("PROXY protocol", func() {
+		It("is not sent to the backends by default", func() {
+			Expect(x.UpdateEndpoints(svc, eps, nil)).To(Succeed())
+
+			c := getSnapshot().GetResources(resource.ClusterType)[portName].(*envoy_config_cluster_v3.Cluster)
+			Expect(c.TransportSocket).To(BeNil())
+		})
+
+		It("v2 is sent to the backends when the annotation is set", func() {
+			proxySvc := svc.DeepCopy()
+			proxySvc.Annotations = map[string]string{utils.AnnotationServiceProxyProtocolKey: "v2"}
+			Expect(x.UpdateEndpoints(proxySvc, eps, nil)).To(Succeed())
+
+			c := getSnapshot().GetResources(resource.ClusterType)[portName].(*envoy_config_cluster_v3.Cluster)
+			Expect(c.TransportSocket).ToNot(BeNil())
+			Expect(c.TransportSocket.Name).To(Equal(upstreamProxyProtocolTransportSocket))
+
+			transport := &envoy_extensions_proxy_protocol_v3.ProxyProtocolUpstreamTransport{}
+			Expect(c.TransportSocket.GetTypedConfig().UnmarshalTo(transport)).To(Succeed())
+			Expect(transport.Config.Version).To(Equal(envoy_config_core_v3.ProxyProtocolConfig_V2))
+		})
+
+		It("is not sent for UDP ports", func() {
+			proxySvc := svc.DeepCopy()
+			proxySvc.Annotations = map[string]string{utils.AnnotationServiceProxyProtocolKey: "v2"}
+			proxySvc.Spec.Ports[0].Protocol = corev1.ProtocolUDP
+			Expect(x.UpdateEndpoints(proxySvc, eps, nil)).To(Succeed())
+
+			c := getSnapshot().GetResources(resource.ClusterType)[portName].(*envoy_config_cluster_v3.Cluster)
+			Expect(c.TransportSocket).To(BeNil())
+		})
 	})
 })
@@ -1,4 +1,4 @@
-// Copyright (c) 2018-2020 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2018-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package utils
@@ -6,4 +6,12 @@ package utils
 const (
 	AnnotationServiceExternalTrafficPolicyKey = "virtualmachineservice.vmoperator.vmware.com/service.externalTrafficPolicy"
 	AnnotationServiceHealthCheckNodePortKey   = "virtualmachineservice.vmoperator.vmware.com/service.healthCheckNodePort"
+	AnnotationServiceIPPoolKey                = "virtualmachineservice.vmoperator.vmware.com/service.ipPool"
+	AnnotationServiceProxyProtocolKey         = "virtualmachineservice.vmoperator.vmware.com/service.proxyProtocol"
+)
+
+const (
+	// ServiceProxyProtocolV2 is the value of the AnnotationServiceProxyProtocolKey annotation
+	// that enables sending PROXY protocol v2 to the backends. It is the only supported value.
+	ServiceProxyProtocolV2 = "v2"
 )
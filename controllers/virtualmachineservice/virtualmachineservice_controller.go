@@ -1,4 +1,4 @@
-// Copyright (c) 2018-2021 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2018-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package virtualmachineservice
@@ -29,6 +29,7 @@ import (
 
 	"github.com/vmware-tanzu/vm-operator/controllers/virtualmachineservice/providers"
 	"github.com/vmware-tanzu/vm-operator/controllers/virtualmachineservice/utils"
+	"github.com/vmware-tanzu/vm-operator/pkg"
 	"github.com/vmware-tanzu/vm-operator/pkg/conditions"
 	"github.com/vmware-tanzu/vm-operator/pkg/context"
 	"github.com/vmware-tanzu/vm-operator/pkg/patch"
@@ -362,7 +363,7 @@ func (r *ReconcileVirtualMachineService) setServiceAnnotationsAndLabels(
 	}
 
 	// Explicitly remove vm service managed annotations if needed
-	for _, k := range []string{utils.AnnotationServiceExternalTrafficPolicyKey, utils.AnnotationServiceHealthCheckNodePortKey} {
+	for _, k := range []string{utils.AnnotationServiceExternalTrafficPolicyKey, utils.AnnotationServiceHealthCheckNodePortKey, utils.AnnotationServiceIPPoolKey, utils.AnnotationServiceProxyProtocolKey} {
 		if _, exist := vmService.Annotations[k]; !exist {
 			if v, exist := service.Annotations[k]; exist {
 				ctx.Logger.V(5).Info("Removing annotation from Service", "key", k, "value", v)
@@ -386,6 +387,16 @@ func (r *ReconcileVirtualMachineService) createOrUpdateService(ctx *context.Virt
 		},
 	}
 
+	var loadBalancerClass string
+	if vmService.Spec.Type == vmopv1alpha1.VirtualMachineServiceTypeLoadBalancer {
+		lbClass, err := r.loadbalancerProvider.GetServiceLoadBalancerClass(ctx, vmService)
+		if err != nil {
+			ctx.Logger.Error(err, "Failed to get loadbalancer class for Service")
+			return nil, err
+		}
+		loadBalancerClass = lbClass
+	}
+
 	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, service, func() error {
 		if err := controllerutil.SetControllerReference(vmService, service, r.scheme); err != nil {
 			return err
@@ -395,6 +406,7 @@ func (r *ReconcileVirtualMachineService) createOrUpdateService(ctx *context.Virt
 			return err
 		}
 
+		wasLoadBalancer := service.Spec.Type == corev1.ServiceTypeLoadBalancer
 		service.Spec.Type = corev1.ServiceType(vmService.Spec.Type)
 		service.Spec.ExternalName = vmService.Spec.ExternalName
 		service.Spec.LoadBalancerIP = vmService.Spec.LoadBalancerIP
@@ -407,6 +419,14 @@ func (r *ReconcileVirtualMachineService) createOrUpdateService(ctx *context.Virt
 			service.Spec.ClusterIP = vmService.Spec.ClusterIP
 		}
 
+		// LoadBalancerClass can only be set when the Service becomes a LoadBalancer, and
+		// cannot be changed through update after that.
+		if service.Spec.Type != corev1.ServiceTypeLoadBalancer {
+			service.Spec.LoadBalancerClass = nil
+		} else if !wasLoadBalancer && loadBalancerClass != "" {
+			service.Spec.LoadBalancerClass = &loadBalancerClass
+		}
+
 		// Maintain the existing mapping of ServicePort -> NodePort as un-setting it will cause
 		// a new NodePort to be allocated.
 		// BMV: Just the Name might not be a sufficient key here.
@@ -616,6 +636,11 @@ func (r *ReconcileVirtualMachineService) generateSubsetsForService(
 			continue
 		}
 
+		if _, ok := vm.Annotations[pkg.DrainingAnnotationKey]; ok {
+			logger.Info("Skipping VM being drained")
+			continue
+		}
+
 		if vm.Status.VmIp == "" {
 			// The EndpointAddress must have a valid IP so we cannot include this VM in the
 			// NotReadyAddresses.
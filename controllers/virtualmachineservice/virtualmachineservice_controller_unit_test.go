@@ -1,4 +1,4 @@
-// Copyright (c) 2019-2021 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2019-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package virtualmachineservice_test
@@ -21,6 +21,7 @@ import (
 	"github.com/vmware-tanzu/vm-operator/controllers/virtualmachineservice"
 	"github.com/vmware-tanzu/vm-operator/controllers/virtualmachineservice/providers"
 	"github.com/vmware-tanzu/vm-operator/controllers/virtualmachineservice/utils"
+	"github.com/vmware-tanzu/vm-operator/pkg"
 	"github.com/vmware-tanzu/vm-operator/pkg/conditions"
 	vmopContext "github.com/vmware-tanzu/vm-operator/pkg/context"
 	"github.com/vmware-tanzu/vm-operator/test/builder"
@@ -29,6 +30,7 @@ import (
 func unitTests() {
 	Describe("Invoking Reconcile", unitTestsReconcile)
 	Describe("Invoking NSXT Reconcile", nsxtLBProviderTestsReconcile)
+	Describe("Invoking External LB Reconcile", externalLBProviderTestsReconcile)
 }
 
 const LabelServiceProxyName = "service.kubernetes.io/service-proxy-name"
@@ -508,6 +510,16 @@ func unitTestsReconcile() {
 						Expect(endpoints.Subsets).To(BeEmpty())
 					})
 				})
+
+				Context("When VM is being drained", func() {
+					BeforeEach(func() {
+						vm1.Annotations = map[string]string{pkg.DrainingAnnotationKey: ""}
+					})
+
+					It("Not included in Subsets", func() {
+						Expect(endpoints.Subsets).To(BeEmpty())
+					})
+				})
 			})
 
 			Context("When multiple VMs match label selector", func() {
@@ -862,6 +874,26 @@ func nsxtLBProviderTestsReconcile() {
 				Expect(newService.Labels).ToNot(HaveKey(LabelServiceProxyName))
 			})
 
+			It("Should update the k8s Service to remove the proxy protocol annotation when it is cleared", func() {
+				if service.Annotations == nil {
+					service.Annotations = make(map[string]string)
+				}
+
+				service.Annotations[utils.AnnotationServiceProxyProtocolKey] = "v2"
+				Expect(ctx.Client.Update(ctx, service)).To(Succeed())
+
+				delete(vmService.Annotations, utils.AnnotationServiceProxyProtocolKey)
+
+				err := reconciler.ReconcileNormal(vmServiceCtx)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				expectEvent(ctx, ContainSubstring(virtualmachineservice.OpUpdate))
+
+				newService := &corev1.Service{}
+				Expect(ctx.Client.Get(ctx, objKey, newService)).To(Succeed())
+				Expect(newService.Annotations).ToNot(HaveKey(utils.AnnotationServiceProxyProtocolKey))
+			})
+
 			It("Should update the k8s Service to remove the provider specific annotations regarding healthCheckNodePort", func() {
 				if service.Annotations == nil {
 					service.Annotations = make(map[string]string)
@@ -894,6 +926,147 @@ func nsxtLBProviderTestsReconcile() {
 	})
 }
 
+// Like the NSX-T tests above, this tests just the bits of the external LB provider that
+// are implemented by the reconciler.
+func externalLBProviderTestsReconcile() {
+	var (
+		initObjects []client.Object
+		ctx         *builder.UnitTestContextForController
+
+		lbProvider   *providers.ExternalLoadbalancerProvider
+		reconciler   *virtualmachineservice.ReconcileVirtualMachineService
+		vmServiceCtx *vmopContext.VirtualMachineServiceContext
+
+		vmService *vmopv1alpha1.VirtualMachineService
+		objKey    client.ObjectKey
+	)
+
+	const (
+		lbClass          = "example.com/external-lb"
+		ipPoolAnnotation = "example.com/address-pool"
+		defaultIPPool    = "default-pool"
+	)
+
+	BeforeEach(func() {
+		vmService = &vmopv1alpha1.VirtualMachineService{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "dummy-vm-service",
+				Namespace:   "dummy-ns",
+				Labels:      map[string]string{},
+				Annotations: map[string]string{},
+			},
+			Spec: vmopv1alpha1.VirtualMachineServiceSpec{
+				Type:     vmopv1alpha1.VirtualMachineServiceTypeLoadBalancer,
+				Selector: map[string]string{},
+				Ports: []vmopv1alpha1.VirtualMachineServicePort{
+					{
+						Name:       "port1",
+						Protocol:   "TCP",
+						Port:       42,
+						TargetPort: 142,
+					},
+				},
+			},
+		}
+
+		objKey = client.ObjectKey{Namespace: vmService.Namespace, Name: vmService.Name}
+	})
+
+	JustBeforeEach(func() {
+		ctx = suite.NewUnitTestContextForController(initObjects...)
+		lbProvider = &providers.ExternalLoadbalancerProvider{
+			LoadBalancerClass:   lbClass,
+			IPPoolAnnotationKey: ipPoolAnnotation,
+			DefaultIPPool:       defaultIPPool,
+		}
+		reconciler = virtualmachineservice.NewReconciler(
+			ctx.Client,
+			ctx.Logger,
+			ctx.Scheme,
+			ctx.Recorder,
+			lbProvider,
+		)
+
+		vmServiceCtx = &vmopContext.VirtualMachineServiceContext{
+			Context:   ctx,
+			Logger:    ctx.Logger.WithName(vmService.Name),
+			VMService: vmService,
+		}
+	})
+
+	AfterEach(func() {
+		ctx.AfterEach()
+		ctx = nil
+		initObjects = nil
+		vmServiceCtx = nil
+		reconciler = nil
+	})
+
+	Describe("ReconcileNormal", func() {
+		var service *corev1.Service
+
+		BeforeEach(func() {
+			service = &corev1.Service{}
+		})
+
+		JustBeforeEach(func() {
+			err := reconciler.ReconcileNormal(vmServiceCtx)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(ctx.Events).Should(Receive(ContainSubstring(virtualmachineservice.OpCreate)))
+			Expect(ctx.Client.Get(ctx, objKey, service)).To(Succeed())
+		})
+
+		It("Should create a LoadBalancer Service with the loadBalancerClass and default IP pool", func() {
+			Expect(service.Spec.Type).To(Equal(corev1.ServiceTypeLoadBalancer))
+			Expect(service.Spec.LoadBalancerClass).ToNot(BeNil())
+			Expect(*service.Spec.LoadBalancerClass).To(Equal(lbClass))
+			Expect(service.Annotations).To(HaveKeyWithValue(ipPoolAnnotation, defaultIPPool))
+		})
+
+		When("VirtualMachineService specifies an IP pool", func() {
+			BeforeEach(func() {
+				vmService.Annotations[utils.AnnotationServiceIPPoolKey] = "my-pool"
+			})
+
+			It("Should set the IP pool annotation on the Service", func() {
+				Expect(service.Annotations).To(HaveKeyWithValue(ipPoolAnnotation, "my-pool"))
+			})
+
+			It("Should remove the IP pool annotation when there is no longer an IP pool", func() {
+				lbProvider.DefaultIPPool = ""
+				delete(vmService.Annotations, utils.AnnotationServiceIPPoolKey)
+
+				err := reconciler.ReconcileNormal(vmServiceCtx)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				expectEvent(ctx, ContainSubstring(virtualmachineservice.OpUpdate))
+
+				newService := &corev1.Service{}
+				Expect(ctx.Client.Get(ctx, objKey, newService)).To(Succeed())
+				Expect(newService.Annotations).ToNot(HaveKey(ipPoolAnnotation))
+				Expect(*newService.Spec.LoadBalancerClass).To(Equal(lbClass))
+			})
+		})
+
+		It("Should set the VirtualMachineService Ingress from the Service Status", func() {
+			service.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{
+				{
+					IP: "ip1",
+				},
+			}
+			Expect(ctx.Client.Update(ctx, service)).To(Succeed())
+
+			err := reconciler.ReconcileNormal(vmServiceCtx)
+			Expect(err).ToNot(HaveOccurred())
+
+			ingress := vmService.Status.LoadBalancer.Ingress
+			Expect(ingress).To(HaveLen(1))
+			Expect(ingress[0].IP).To(Equal("ip1"))
+		})
+	})
+}
+
 func expectEvent(ctx *builder.UnitTestContextForController, matcher types.GomegaMatcher) {
 	var event string
 	EventuallyWithOffset(1, ctx.Events).Should(Receive(&event))
@@ -0,0 +1,216 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package virtualmachinesnapshot
+
+import (
+	goctx "context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
+
+	"github.com/vmware-tanzu/vm-operator/pkg/conditions"
+	"github.com/vmware-tanzu/vm-operator/pkg/context"
+	"github.com/vmware-tanzu/vm-operator/pkg/patch"
+	"github.com/vmware-tanzu/vm-operator/pkg/record"
+	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider"
+)
+
+const finalizerName = "virtualmachinesnapshot.vmoperator.vmware.com"
+
+// AddToManager adds this package's controller to the provided manager.
+func AddToManager(ctx *context.ControllerManagerContext, mgr manager.Manager) error {
+	var (
+		controlledType     = &vmopv1alpha1.VirtualMachineSnapshot{}
+		controlledTypeName = reflect.TypeOf(controlledType).Elem().Name()
+
+		controllerNameShort = fmt.Sprintf("%s-controller", strings.ToLower(controlledTypeName))
+		controllerNameLong  = fmt.Sprintf("%s/%s/%s", ctx.Namespace, ctx.Name, controllerNameShort)
+	)
+
+	r := NewReconciler(
+		mgr.GetClient(),
+		ctrl.Log.WithName("controllers").WithName(controlledTypeName),
+		record.New(mgr.GetEventRecorderFor(controllerNameLong)),
+		ctx.VMProvider,
+	)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(controlledType).
+		WithOptions(controller.Options{MaxConcurrentReconciles: ctx.MaxConcurrentReconciles}).
+		Complete(r)
+}
+
+func NewReconciler(
+	client client.Client,
+	logger logr.Logger,
+	recorder record.Recorder,
+	vmProvider vmprovider.VirtualMachineProviderInterface) *Reconciler {
+	return &Reconciler{
+		Client:     client,
+		Logger:     logger,
+		Recorder:   recorder,
+		VMProvider: vmProvider,
+	}
+}
+
+// Reconciler reconciles a VirtualMachineSnapshot object.
+type Reconciler struct {
+	client.Client
+	Logger     logr.Logger
+	Recorder   record.Recorder
+	VMProvider vmprovider.VirtualMachineProviderInterface
+}
+
+// +kubebuilder:rbac:groups=vmoperator.vmware.com,resources=virtualmachinesnapshots,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=vmoperator.vmware.com,resources=virtualmachinesnapshots/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=vmoperator.vmware.com,resources=virtualmachines,verbs=get;list
+
+func (r *Reconciler) Reconcile(ctx goctx.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
+	vmSnapshot := &vmopv1alpha1.VirtualMachineSnapshot{}
+	if err := r.Get(ctx, req.NamespacedName, vmSnapshot); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	vmSnapshotCtx := &context.VirtualMachineSnapshotContext{
+		Context:    ctx,
+		Logger:     ctrl.Log.WithName("VirtualMachineSnapshot").WithValues("name", req.NamespacedName),
+		VMSnapshot: vmSnapshot,
+	}
+
+	patchHelper, err := patch.NewHelper(vmSnapshot, r.Client)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrapf(err, "failed to init patch helper for %s", vmSnapshotCtx)
+	}
+	defer func() {
+		if err := patchHelper.Patch(ctx, vmSnapshot); err != nil {
+			if reterr == nil {
+				reterr = err
+			}
+			vmSnapshotCtx.Logger.Error(err, "patch failed")
+		}
+	}()
+
+	if !vmSnapshot.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, r.ReconcileDelete(vmSnapshotCtx)
+	}
+
+	if err := r.ReconcileNormal(vmSnapshotCtx); err != nil {
+		vmSnapshotCtx.Logger.Error(err, "Failed to reconcile VirtualMachineSnapshot")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func (r *Reconciler) ReconcileDelete(ctx *context.VirtualMachineSnapshotContext) error {
+	ctx.Logger.Info("Reconciling VirtualMachineSnapshot Deletion")
+
+	if !controllerutil.ContainsFinalizer(ctx.VMSnapshot, finalizerName) {
+		return nil
+	}
+
+	vm := &vmopv1alpha1.VirtualMachine{}
+	vmKey := client.ObjectKey{Name: ctx.VMSnapshot.Spec.VirtualMachineName, Namespace: ctx.VMSnapshot.Namespace}
+	if err := r.Get(ctx, vmKey, vm); err != nil {
+		if !apiErrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to get VirtualMachine %s", vmKey)
+		}
+		// The snapshot is removed along with the VM on the provider.
+		ctx.Logger.Info("VirtualMachine not found, skipping snapshot deletion", "vm", vmKey)
+	} else {
+		ctx.VM = vm
+		err := r.VMProvider.DeleteSnapshot(ctx, ctx.VM, ctx.VMSnapshot)
+		r.Recorder.EmitEvent(ctx.VMSnapshot, "Delete", err, false)
+		if err != nil {
+			return errors.Wrapf(err, "failed to delete snapshot")
+		}
+	}
+
+	controllerutil.RemoveFinalizer(ctx.VMSnapshot, finalizerName)
+	ctx.Logger.Info("Finished Reconciling VirtualMachineSnapshot Deletion")
+	return nil
+}
+
+func (r *Reconciler) ReconcileNormal(ctx *context.VirtualMachineSnapshotContext) error {
+	if !controllerutil.ContainsFinalizer(ctx.VMSnapshot, finalizerName) {
+		// The finalizer must be present before proceeding in order to ensure that the snapshot
+		// will be cleaned up. Return immediately after here to let the patcher helper update the
+		// object, and then we'll proceed on the next reconciliation.
+		controllerutil.AddFinalizer(ctx.VMSnapshot, finalizerName)
+		return nil
+	}
+
+	// A snapshot is a point-in-time copy so once taken, there is nothing left to reconcile.
+	if ctx.VMSnapshot.Status.SnapshotID != "" {
+		return nil
+	}
+
+	ctx.Logger.Info("Reconciling VirtualMachineSnapshot")
+	defer func() {
+		ctx.Logger.Info("Finished Reconciling VirtualMachineSnapshot")
+	}()
+
+	if err := r.getSourceVM(ctx); err != nil {
+		return err
+	}
+
+	if err := controllerutil.SetOwnerReference(ctx.VM, ctx.VMSnapshot, r.Scheme()); err != nil {
+		return errors.Wrapf(err, "failed to set owner reference")
+	}
+
+	err := r.VMProvider.CreateSnapshot(ctx, ctx.VM, ctx.VMSnapshot)
+	r.Recorder.EmitEvent(ctx.VMSnapshot, "Create", err, false)
+	if err != nil {
+		conditions.MarkFalse(ctx.VMSnapshot,
+			vmopv1alpha1.VirtualMachineSnapshotConditionCreated,
+			vmopv1alpha1.VirtualMachineSnapshotCreateFailedReason,
+			vmopv1alpha1.ConditionSeverityError, err.Error())
+		return errors.Wrapf(err, "failed to create snapshot")
+	}
+
+	conditions.MarkTrue(ctx.VMSnapshot, vmopv1alpha1.VirtualMachineSnapshotConditionCreated)
+	ctx.VMSnapshot.Status.Ready = true
+	return nil
+}
+
+// getSourceVM gets the VirtualMachine to snapshot, and marks the Created condition false when the
+// VM either doesn't exist or hasn't been created on the provider yet.
+func (r *Reconciler) getSourceVM(ctx *context.VirtualMachineSnapshotContext) error {
+	vmSnapshot := ctx.VMSnapshot
+	vm := &vmopv1alpha1.VirtualMachine{}
+	vmKey := client.ObjectKey{Name: vmSnapshot.Spec.VirtualMachineName, Namespace: vmSnapshot.Namespace}
+	if err := r.Get(ctx, vmKey, vm); err != nil {
+		if apiErrors.IsNotFound(err) {
+			conditions.MarkFalse(vmSnapshot,
+				vmopv1alpha1.VirtualMachineSnapshotConditionCreated,
+				vmopv1alpha1.VirtualMachineSnapshotSourceNotFoundReason,
+				vmopv1alpha1.ConditionSeverityError, err.Error())
+		}
+		return errors.Wrapf(err, "failed to get VirtualMachine %s", vmKey)
+	}
+
+	if vm.Status.UniqueID == "" {
+		err := fmt.Errorf("VM hasn't been created and has no uniqueID, phase: %s", vm.Status.Phase)
+		conditions.MarkFalse(vmSnapshot,
+			vmopv1alpha1.VirtualMachineSnapshotConditionCreated,
+			vmopv1alpha1.VirtualMachineSnapshotSourceNotCreatedReason,
+			vmopv1alpha1.ConditionSeverityError, err.Error())
+		return err
+	}
+
+	ctx.VM = vm
+	return nil
+}
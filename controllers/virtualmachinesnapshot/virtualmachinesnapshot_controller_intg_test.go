@@ -0,0 +1,133 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package virtualmachinesnapshot_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
+
+	"github.com/vmware-tanzu/vm-operator/test/builder"
+)
+
+func intgTests() {
+	Describe("Invoking VirtualMachineSnapshot controller tests", intgTestsReconcile)
+}
+
+func intgTestsReconcile() {
+	var (
+		ctx *builder.IntegrationTestContext
+
+		vm         *vmopv1alpha1.VirtualMachine
+		vmSnapshot *vmopv1alpha1.VirtualMachineSnapshot
+	)
+
+	getVirtualMachineSnapshot := func(ctx *builder.IntegrationTestContext, objKey client.ObjectKey) *vmopv1alpha1.VirtualMachineSnapshot {
+		vmSnapshot := &vmopv1alpha1.VirtualMachineSnapshot{}
+		if err := ctx.Client.Get(ctx, objKey, vmSnapshot); err != nil {
+			return nil
+		}
+		return vmSnapshot
+	}
+
+	BeforeEach(func() {
+		ctx = suite.NewIntegrationTestContext()
+
+		vm = &vmopv1alpha1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "dummy-vm",
+				Namespace: ctx.Namespace,
+			},
+			Spec: vmopv1alpha1.VirtualMachineSpec{
+				ImageName:  "dummy-image",
+				ClassName:  "dummy-class",
+				PowerState: vmopv1alpha1.VirtualMachinePoweredOn,
+			},
+		}
+
+		vmSnapshot = &vmopv1alpha1.VirtualMachineSnapshot{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "dummy-snapshot",
+				Namespace: ctx.Namespace,
+			},
+			Spec: vmopv1alpha1.VirtualMachineSnapshotSpec{
+				VirtualMachineName: vm.Name,
+			},
+		}
+	})
+
+	AfterEach(func() {
+		ctx.AfterEach()
+		ctx = nil
+		intgFakeVMProvider.Reset()
+	})
+
+	Context("Reconcile", func() {
+		var deleteCalled bool
+
+		BeforeEach(func() {
+			deleteCalled = false
+			intgFakeVMProvider.Lock()
+			intgFakeVMProvider.DeleteSnapshotFn = func(_ context.Context, _ *vmopv1alpha1.VirtualMachine, _ *vmopv1alpha1.VirtualMachineSnapshot) error {
+				deleteCalled = true
+				return nil
+			}
+			intgFakeVMProvider.Unlock()
+
+			Expect(ctx.Client.Create(ctx, vm)).To(Succeed())
+			vm.Status.UniqueID = "dummy-id"
+			Expect(ctx.Client.Status().Update(ctx, vm)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			err := ctx.Client.Delete(ctx, vm)
+			Expect(err == nil || k8serrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("Reconciles after VirtualMachineSnapshot creation and deletion", func() {
+			Expect(ctx.Client.Create(ctx, vmSnapshot)).To(Succeed())
+			objKey := client.ObjectKeyFromObject(vmSnapshot)
+
+			By("Snapshot should have finalizer added", func() {
+				Eventually(func() []string {
+					if s := getVirtualMachineSnapshot(ctx, objKey); s != nil {
+						return s.GetFinalizers()
+					}
+					return nil
+				}).Should(ContainElement(finalizer))
+			})
+
+			By("Snapshot should become ready", func() {
+				Eventually(func() bool {
+					if s := getVirtualMachineSnapshot(ctx, objKey); s != nil {
+						return s.Status.Ready
+					}
+					return false
+				}).Should(BeTrue())
+
+				s := getVirtualMachineSnapshot(ctx, objKey)
+				Expect(s).ToNot(BeNil())
+				Expect(s.Status.SnapshotID).ToNot(BeEmpty())
+			})
+
+			By("Deleting the snapshot", func() {
+				Expect(ctx.Client.Delete(ctx, vmSnapshot)).To(Succeed())
+				Eventually(func() bool {
+					return getVirtualMachineSnapshot(ctx, objKey) == nil
+				}).Should(BeTrue())
+
+				intgFakeVMProvider.Lock()
+				defer intgFakeVMProvider.Unlock()
+				Expect(deleteCalled).To(BeTrue())
+			})
+		})
+	})
+}
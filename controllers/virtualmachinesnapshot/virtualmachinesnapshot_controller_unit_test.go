@@ -0,0 +1,237 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package virtualmachinesnapshot_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
+
+	"github.com/vmware-tanzu/vm-operator/controllers/virtualmachinesnapshot"
+	"github.com/vmware-tanzu/vm-operator/pkg/conditions"
+	vmopContext "github.com/vmware-tanzu/vm-operator/pkg/context"
+	providerfake "github.com/vmware-tanzu/vm-operator/pkg/vmprovider/fake"
+	"github.com/vmware-tanzu/vm-operator/test/builder"
+)
+
+func unitTests() {
+	Describe("Invoking VirtualMachineSnapshot Reconcile", unitTestsReconcile)
+}
+
+const finalizer = "virtualmachinesnapshot.vmoperator.vmware.com"
+
+func unitTestsReconcile() {
+	const (
+		providerError = "provider error"
+	)
+
+	var (
+		initObjects    []client.Object
+		ctx            *builder.UnitTestContextForController
+		reconciler     *virtualmachinesnapshot.Reconciler
+		fakeVMProvider *providerfake.VMProvider
+
+		vm            *vmopv1alpha1.VirtualMachine
+		vmSnapshot    *vmopv1alpha1.VirtualMachineSnapshot
+		vmSnapshotCtx *vmopContext.VirtualMachineSnapshotContext
+	)
+
+	BeforeEach(func() {
+		vm = &vmopv1alpha1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "dummy-vm",
+				Namespace: "dummy-ns",
+			},
+			Status: vmopv1alpha1.VirtualMachineStatus{
+				UniqueID: "dummy-id",
+			},
+		}
+
+		vmSnapshot = &vmopv1alpha1.VirtualMachineSnapshot{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "dummy-snapshot",
+				Namespace:  vm.Namespace,
+				Finalizers: []string{finalizer},
+			},
+			Spec: vmopv1alpha1.VirtualMachineSnapshotSpec{
+				VirtualMachineName: vm.Name,
+				Description:        "dummy description",
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		ctx = suite.NewUnitTestContextForController(initObjects...)
+		reconciler = virtualmachinesnapshot.NewReconciler(
+			ctx.Client,
+			ctx.Logger,
+			ctx.Recorder,
+			ctx.VMProvider,
+		)
+		fakeVMProvider = ctx.VMProvider.(*providerfake.VMProvider)
+
+		vmSnapshotCtx = &vmopContext.VirtualMachineSnapshotContext{
+			Context:    ctx,
+			Logger:     ctx.Logger.WithName(vmSnapshot.Name),
+			VMSnapshot: vmSnapshot,
+		}
+	})
+
+	AfterEach(func() {
+		ctx.AfterEach()
+		ctx = nil
+		initObjects = nil
+		vmSnapshotCtx = nil
+		reconciler = nil
+		fakeVMProvider = nil
+	})
+
+	Context("ReconcileNormal", func() {
+
+		When("object does not have finalizer set", func() {
+			BeforeEach(func() {
+				vmSnapshot.Finalizers = nil
+				initObjects = append(initObjects, vm, vmSnapshot)
+			})
+
+			It("will set finalizer", func() {
+				Expect(reconciler.ReconcileNormal(vmSnapshotCtx)).To(Succeed())
+				Expect(vmSnapshot.GetFinalizers()).To(ContainElement(finalizer))
+				Expect(vmSnapshot.Status.SnapshotID).To(BeEmpty())
+			})
+		})
+
+		When("source VM does not exist", func() {
+			BeforeEach(func() {
+				initObjects = append(initObjects, vmSnapshot)
+			})
+
+			It("returns error and marks the Created condition false", func() {
+				err := reconciler.ReconcileNormal(vmSnapshotCtx)
+				Expect(err).To(HaveOccurred())
+				Expect(conditions.IsFalse(vmSnapshot, vmopv1alpha1.VirtualMachineSnapshotConditionCreated)).To(BeTrue())
+				Expect(conditions.GetReason(vmSnapshot, vmopv1alpha1.VirtualMachineSnapshotConditionCreated)).
+					To(Equal(vmopv1alpha1.VirtualMachineSnapshotSourceNotFoundReason))
+				Expect(vmSnapshot.Status.Ready).To(BeFalse())
+			})
+		})
+
+		When("source VM has not been created", func() {
+			BeforeEach(func() {
+				vm.Status.UniqueID = ""
+				initObjects = append(initObjects, vm, vmSnapshot)
+			})
+
+			It("returns error and marks the Created condition false", func() {
+				err := reconciler.ReconcileNormal(vmSnapshotCtx)
+				Expect(err).To(HaveOccurred())
+				Expect(conditions.GetReason(vmSnapshot, vmopv1alpha1.VirtualMachineSnapshotConditionCreated)).
+					To(Equal(vmopv1alpha1.VirtualMachineSnapshotSourceNotCreatedReason))
+			})
+		})
+
+		When("source VM exists", func() {
+			BeforeEach(func() {
+				initObjects = append(initObjects, vm, vmSnapshot)
+			})
+
+			It("creates the snapshot", func() {
+				Expect(reconciler.ReconcileNormal(vmSnapshotCtx)).To(Succeed())
+				Expect(vmSnapshot.Status.SnapshotID).ToNot(BeEmpty())
+				Expect(vmSnapshot.Status.Ready).To(BeTrue())
+				Expect(conditions.IsTrue(vmSnapshot, vmopv1alpha1.VirtualMachineSnapshotConditionCreated)).To(BeTrue())
+				Expect(vmSnapshot.OwnerReferences).To(HaveLen(1))
+				Expect(vmSnapshot.OwnerReferences[0].Name).To(Equal(vm.Name))
+				expectEvent(ctx, "CreateSuccess")
+			})
+
+			It("does not create the snapshot again", func() {
+				Expect(reconciler.ReconcileNormal(vmSnapshotCtx)).To(Succeed())
+				snapshotID := vmSnapshot.Status.SnapshotID
+
+				fakeVMProvider.CreateSnapshotFn = func(_ context.Context, _ *vmopv1alpha1.VirtualMachine, _ *vmopv1alpha1.VirtualMachineSnapshot) error {
+					Fail("CreateSnapshot should not be called")
+					return nil
+				}
+				Expect(reconciler.ReconcileNormal(vmSnapshotCtx)).To(Succeed())
+				Expect(vmSnapshot.Status.SnapshotID).To(Equal(snapshotID))
+			})
+
+			It("returns error when provider fails to create the snapshot", func() {
+				fakeVMProvider.CreateSnapshotFn = func(_ context.Context, _ *vmopv1alpha1.VirtualMachine, _ *vmopv1alpha1.VirtualMachineSnapshot) error {
+					return errors.New(providerError)
+				}
+
+				err := reconciler.ReconcileNormal(vmSnapshotCtx)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(providerError))
+				Expect(vmSnapshot.Status.Ready).To(BeFalse())
+				Expect(conditions.GetReason(vmSnapshot, vmopv1alpha1.VirtualMachineSnapshotConditionCreated)).
+					To(Equal(vmopv1alpha1.VirtualMachineSnapshotCreateFailedReason))
+				expectEvent(ctx, "CreateFailure")
+			})
+		})
+	})
+
+	Context("ReconcileDelete", func() {
+		BeforeEach(func() {
+			vmSnapshot.Status.SnapshotID = "snapshot-1"
+		})
+
+		When("source VM exists", func() {
+			BeforeEach(func() {
+				initObjects = append(initObjects, vm, vmSnapshot)
+			})
+
+			It("deletes the snapshot and removes the finalizer", func() {
+				var deleted bool
+				fakeVMProvider.DeleteSnapshotFn = func(_ context.Context, _ *vmopv1alpha1.VirtualMachine, _ *vmopv1alpha1.VirtualMachineSnapshot) error {
+					deleted = true
+					return nil
+				}
+
+				Expect(reconciler.ReconcileDelete(vmSnapshotCtx)).To(Succeed())
+				Expect(deleted).To(BeTrue())
+				Expect(vmSnapshot.GetFinalizers()).ToNot(ContainElement(finalizer))
+				expectEvent(ctx, "DeleteSuccess")
+			})
+
+			It("keeps the finalizer when provider fails to delete the snapshot", func() {
+				fakeVMProvider.DeleteSnapshotFn = func(_ context.Context, _ *vmopv1alpha1.VirtualMachine, _ *vmopv1alpha1.VirtualMachineSnapshot) error {
+					return errors.New(providerError)
+				}
+
+				Expect(reconciler.ReconcileDelete(vmSnapshotCtx)).ToNot(Succeed())
+				Expect(vmSnapshot.GetFinalizers()).To(ContainElement(finalizer))
+				expectEvent(ctx, "DeleteFailure")
+			})
+		})
+
+		When("source VM does not exist", func() {
+			BeforeEach(func() {
+				initObjects = append(initObjects, vmSnapshot)
+			})
+
+			It("removes the finalizer", func() {
+				Expect(reconciler.ReconcileDelete(vmSnapshotCtx)).To(Succeed())
+				Expect(vmSnapshot.GetFinalizers()).ToNot(ContainElement(finalizer))
+			})
+		})
+	})
+}
+
+func expectEvent(ctx *builder.UnitTestContextForController, eventStr string) {
+	var event string
+	EventuallyWithOffset(1, ctx.Events).Should(Receive(&event))
+	eventComponents := strings.Split(event, " ")
+	ExpectWithOffset(1, eventComponents[1]).To(Equal(eventStr))
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/object"
 	corev1 "k8s.io/api/core/v1"
 	apiErrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -30,16 +31,22 @@ import (
 	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
 
 	cnsv1alpha1 "github.com/vmware-tanzu/vm-operator/external/vsphere-csi-driver/pkg/syncer/cnsoperator/apis/cnsnodevmattachment/v1alpha1"
+	cnsregv1alpha1 "github.com/vmware-tanzu/vm-operator/external/vsphere-csi-driver/pkg/syncer/cnsoperator/apis/cnsregistervolume/v1alpha1"
 	"github.com/vmware-tanzu/vm-operator/pkg/context"
 	"github.com/vmware-tanzu/vm-operator/pkg/lib"
 	"github.com/vmware-tanzu/vm-operator/pkg/patch"
 	"github.com/vmware-tanzu/vm-operator/pkg/record"
+	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/constants"
+	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/imagevolume"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/instancestorage"
 )
 
 const (
 	AttributeFirstClassDiskUUID = "diskUUID"
+
+	// imageVolumeRequeueDelay is how often a VM is reconciled while its image volume is not yet attached.
+	imageVolumeRequeueDelay = 10 * time.Second
 )
 
 // AddToManager adds this package's controller to the provided manager.
@@ -55,6 +62,7 @@ func AddToManager(ctx *context.ControllerManagerContext, mgr manager.Manager) er
 		ctrl.Log.WithName("controllers").WithName("volume"),
 		record.New(mgr.GetEventRecorderFor(controllerNameLong)),
 		mgr.GetScheme(),
+		ctx.VMProvider,
 	)
 
 	c, err := controller.New(controllerName, mgr, controller.Options{
@@ -89,6 +97,15 @@ func AddToManager(ctx *context.ControllerManagerContext, mgr manager.Manager) er
 		return err
 	}
 
+	// Watch for changes for CnsRegisterVolume, and enqueue VirtualMachine which is the owner of CnsRegisterVolume.
+	err = c.Watch(&source.Kind{Type: &cnsregv1alpha1.CnsRegisterVolume{}}, &handler.EnqueueRequestForOwner{
+		OwnerType:    &vmopv1alpha1.VirtualMachine{},
+		IsController: true,
+	})
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -96,12 +113,14 @@ func NewReconciler(
 	client client.Client,
 	logger logr.Logger,
 	recorder record.Recorder,
-	scheme *runtime.Scheme) *Reconciler {
+	scheme *runtime.Scheme,
+	vmProvider vmprovider.VirtualMachineProviderInterface) *Reconciler {
 	return &Reconciler{
-		Client:   client,
-		logger:   logger,
-		recorder: recorder,
-		scheme:   scheme,
+		Client:     client,
+		logger:     logger,
+		recorder:   recorder,
+		scheme:     scheme,
+		VMProvider: vmProvider,
 	}
 }
 
@@ -109,15 +128,17 @@ var _ reconcile.Reconciler = &Reconciler{}
 
 type Reconciler struct {
 	client.Client
-	logger   logr.Logger
-	recorder record.Recorder
-	scheme   *runtime.Scheme
+	logger     logr.Logger
+	recorder   record.Recorder
+	scheme     *runtime.Scheme
+	VMProvider vmprovider.VirtualMachineProviderInterface
 }
 
 // +kubebuilder:rbac:groups=vmoperator.vmware.com,resources=virtualmachines,verbs=get;list;watch;
 // +kubebuilder:rbac:groups=vmoperator.vmware.com,resources=virtualmachines/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=cns.vmware.com,resources=cnsnodevmattachments,verbs=create;delete;get;list;watch;patch;update
 // +kubebuilder:rbac:groups=cns.vmware.com,resources=cnsnodevmattachments/status,verbs=get;list
+// +kubebuilder:rbac:groups=cns.vmware.com,resources=cnsregistervolumes,verbs=create;delete;get;list;watch;patch;update
 // +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=create;delete;get;list;watch;patch;update
 
 // Reconcile reconciles a VirtualMachine object and processes the volumes for attach/detach.
@@ -181,6 +202,11 @@ func (r *Reconciler) reconcileResult(ctx *context.VolumeContext) ctrl.Result {
 		}
 	}
 
+	// Requeue the request until the image volume is attached since its PVC may not be owned by the VM.
+	if imagevolume.IsConfigured(ctx.VM) && !imagevolume.IsAttached(ctx.VM) {
+		return ctrl.Result{RequeueAfter: imageVolumeRequeueDelay}
+	}
+
 	return ctrl.Result{}
 }
 
@@ -205,6 +231,11 @@ func (r *Reconciler) ReconcileNormal(ctx *context.VolumeContext) error {
 		}
 	}
 
+	ready, err := r.reconcileImageVolumePVC(ctx)
+	if err != nil || !ready {
+		return err
+	}
+
 	if ctx.VM.Status.BiosUUID == "" {
 		// CSI requires the BiosUUID to match up the attachment request with the VM. Defer here
 		// until it is set by the VirtualMachine controller.
@@ -341,6 +372,102 @@ func (r *Reconciler) reconcileInstanceStoragePVCs(ctx *context.VolumeContext) (b
 	return fullyBound, k8serrors.NewAggregate(append(deleteErrs, createErrs...))
 }
 
+// reconcileImageVolumePVC ensures the PVC of the image volume exists. A missing PVC is populated by registering
+// with CNS the boot disk that was detached from the VM after it was deployed from its image. Returns true when
+// the PVC is bound and can be attached.
+func (r *Reconciler) reconcileImageVolumePVC(ctx *context.VolumeContext) (bool, error) {
+	volume := imagevolume.GetVolume(ctx.VM)
+	if volume == nil {
+		return true, nil
+	}
+
+	pvc := &corev1.PersistentVolumeClaim{}
+	pvcKey := client.ObjectKey{Namespace: ctx.VM.Namespace, Name: volume.PersistentVolumeClaim.ClaimName}
+	if err := r.Get(ctx, pvcKey, pvc); err == nil {
+		if pvc.Status.Phase != corev1.ClaimBound {
+			// CSI is still processing this PVC.
+			return false, nil
+		}
+
+		// The PVC has been populated so the boot disk no longer needs to be registered.
+		delete(ctx.VM.Annotations, constants.BootDiskURLPathAnnotation)
+		return true, nil
+	} else if !apiErrors.IsNotFound(err) {
+		return false, err
+	}
+
+	diskURLPath := ctx.VM.Annotations[constants.BootDiskURLPathAnnotation]
+	if diskURLPath == "" {
+		// The VM has not been deployed from its image yet. Defer here until the VM controller has
+		// detached the boot disk.
+		ctx.Logger.Info("VM does not yet have a boot disk to populate the image volume PVC. Deferring registration")
+		return false, nil
+	}
+
+	return false, r.registerImageVolumeDisk(ctx, volume, diskURLPath)
+}
+
+func (r *Reconciler) registerImageVolumeDisk(
+	ctx *context.VolumeContext,
+	volume *vmopv1alpha1.VirtualMachineVolume,
+	diskURLPath string) error {
+
+	claimName := volume.PersistentVolumeClaim.ClaimName
+
+	registerVolume := &cnsregv1alpha1.CnsRegisterVolume{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: ctx.VM.Namespace, Name: claimName}, registerVolume); err == nil {
+		if registerVolume.Status.Error != "" {
+			return errors.Errorf("failed to register boot disk for image volume PVC %s: %s",
+				claimName, sanitizeCNSErrorMessage(registerVolume.Status.Error))
+		}
+
+		// CSI is still registering the boot disk.
+		return nil
+	} else if !apiErrors.IsNotFound(err) {
+		return err
+	}
+
+	// Only a disk in the VM's own directory is registered for the image volume PVC of the VM.
+	dsPath, err := imagevolume.ParseBootDiskURLPath(diskURLPath)
+	if err != nil {
+		return err
+	}
+	vmDir, err := r.VMProvider.GetVirtualMachineDirectory(ctx, ctx.VM)
+	if err != nil {
+		return errors.Wrap(err, "failed to get the directory of the VM")
+	}
+	var vmDirPath object.DatastorePath
+	if !vmDirPath.FromString(vmDir) || !imagevolume.IsInDirectory(dsPath, vmDirPath) {
+		return errors.Errorf("boot disk %s is not in the directory %s of the VM", dsPath.String(), vmDir)
+	}
+
+	registerVolume = &cnsregv1alpha1.CnsRegisterVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      claimName,
+			Namespace: ctx.VM.Namespace,
+		},
+		Spec: cnsregv1alpha1.CnsRegisterVolumeSpec{
+			PvcName:     claimName,
+			DiskURLPath: diskURLPath,
+			AccessMode:  corev1.ReadWriteOnce,
+		},
+	}
+
+	// Only the CnsRegisterVolume is owned by the VM: the PVC that CSI creates for the registered disk is not
+	// deleted with the VM.
+	if err := controllerutil.SetControllerReference(ctx.VM, registerVolume, r.scheme); err != nil {
+		// This is an unexpected error.
+		return errors.Wrap(err, "Cannot set controller reference on CnsRegisterVolume")
+	}
+
+	ctx.Logger.Info("Registering boot disk to populate image volume PVC", "pvcName", claimName)
+	if err := r.Create(ctx, registerVolume); err != nil && !apiErrors.IsAlreadyExists(err) {
+		return errors.Wrap(err, "Error creating CnsRegisterVolume")
+	}
+
+	return nil
+}
+
 func instanceStoragePVCFailed(pvc *corev1.PersistentVolumeClaim) bool {
 	errAnn := pvc.Annotations[constants.InstanceStoragePVPlacementErrorAnnotationKey]
 	if strings.HasPrefix(errAnn, constants.InstanceStoragePVPlacementErrorPrefix) &&
@@ -525,7 +652,7 @@ func (r *Reconciler) processAttachments(
 	// the first place.
 	onlyAllowOnePendingAttachment := ctx.VM.Status.PowerState == "" || ctx.VM.Status.PowerState == vmopv1alpha1.VirtualMachinePoweredOff
 
-	for _, volume := range ctx.VM.Spec.Volumes {
+	for _, volume := range attachmentOrderedVolumes(ctx.VM) {
 		if volume.PersistentVolumeClaim == nil {
 			// Don't process VsphereVolumes here. Note that we don't have Volume status
 			// for Vsphere volumes, so there is nothing to preserve here.
@@ -612,6 +739,25 @@ func (r *Reconciler) createCNSAttachment(
 	return nil
 }
 
+// attachmentOrderedVolumes returns the volumes of the VM in the order they should be attached. This is the
+// Spec.Volumes order except that the image volume is first since the VM boots from it.
+func attachmentOrderedVolumes(vm *vmopv1alpha1.VirtualMachine) []vmopv1alpha1.VirtualMachineVolume {
+	imageVolume := imagevolume.GetVolume(vm)
+	if imageVolume == nil {
+		return vm.Spec.Volumes
+	}
+
+	volumes := make([]vmopv1alpha1.VirtualMachineVolume, 0, len(vm.Spec.Volumes))
+	volumes = append(volumes, *imageVolume)
+	for _, volume := range vm.Spec.Volumes {
+		if volume.Name != imageVolume.Name {
+			volumes = append(volumes, volume)
+		}
+	}
+
+	return volumes
+}
+
 // This is a hack to preserve the prior behavior of including detach(ing) volumes that were
 // removed from the Spec in the Status until they are actually deleted.
 func (r *Reconciler) preserveOrphanedAttachmentStatus(
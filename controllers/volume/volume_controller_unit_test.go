@@ -23,8 +23,10 @@ import (
 
 	"github.com/vmware-tanzu/vm-operator/controllers/volume"
 	cnsv1alpha1 "github.com/vmware-tanzu/vm-operator/external/vsphere-csi-driver/pkg/syncer/cnsoperator/apis/cnsnodevmattachment/v1alpha1"
+	cnsregv1alpha1 "github.com/vmware-tanzu/vm-operator/external/vsphere-csi-driver/pkg/syncer/cnsoperator/apis/cnsregistervolume/v1alpha1"
 	volContext "github.com/vmware-tanzu/vm-operator/pkg/context"
 	"github.com/vmware-tanzu/vm-operator/pkg/lib"
+	providerfake "github.com/vmware-tanzu/vm-operator/pkg/vmprovider/fake"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/constants"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/instancestorage"
 	"github.com/vmware-tanzu/vm-operator/test/builder"
@@ -54,9 +56,10 @@ func unitTestsReconcile() {
 		initObjects []client.Object
 		ctx         *builder.UnitTestContextForController
 
-		reconciler *volume.Reconciler
-		volCtx     *volContext.VolumeContext
-		vm         *vmopv1alpha1.VirtualMachine
+		reconciler     *volume.Reconciler
+		fakeVMProvider *providerfake.VMProvider
+		volCtx         *volContext.VolumeContext
+		vm             *vmopv1alpha1.VirtualMachine
 
 		vmVol               vmopv1alpha1.VirtualMachineVolume
 		vmVolumeWithVsphere *vmopv1alpha1.VirtualMachineVolume
@@ -121,7 +124,9 @@ func unitTestsReconcile() {
 			ctx.Logger,
 			ctx.Recorder,
 			ctx.Scheme,
+			ctx.VMProvider,
 		)
+		fakeVMProvider = ctx.VMProvider.(*providerfake.VMProvider)
 
 		volCtx = &volContext.VolumeContext{
 			Context: ctx,
@@ -136,6 +141,7 @@ func unitTestsReconcile() {
 		initObjects = nil
 		volCtx = nil
 		reconciler = nil
+		fakeVMProvider = nil
 	})
 
 	getCNSAttachmentForVolumeName := func(vm *vmopv1alpha1.VirtualMachine, volumeName string) *cnsv1alpha1.CnsNodeVmAttachment {
@@ -230,6 +236,129 @@ func unitTestsReconcile() {
 			})
 		})
 
+		When("Image volume is configured on VM", func() {
+			const dummyDiskURLPath = "https://vc.domain.com/folder/dummy-vm/dummy-vm.vmdk?dcPath=DC0&dsName=LocalDS_0"
+
+			var imageVol vmopv1alpha1.VirtualMachineVolume
+
+			BeforeEach(func() {
+				imageVol = vmopv1alpha1.VirtualMachineVolume{
+					Name: "image-volume",
+					PersistentVolumeClaim: &vmopv1alpha1.PersistentVolumeClaimVolumeSource{
+						PersistentVolumeClaimVolumeSource: corev1.PersistentVolumeClaimVolumeSource{
+							ClaimName: "image-pvc",
+						},
+						ImageVolumeClaim: &vmopv1alpha1.ImageVolumeClaimVolumeSource{
+							ImageName: "dummy-image",
+						},
+					},
+				}
+				vm.Spec.Volumes = append(vm.Spec.Volumes, *vmVolumeWithPVC1, imageVol)
+				vm.Status.PowerState = vmopv1alpha1.VirtualMachinePoweredOff
+				vm.Annotations = map[string]string{
+					constants.BootDiskURLPathAnnotation: dummyDiskURLPath,
+				}
+			})
+
+			JustBeforeEach(func() {
+				fakeVMProvider.GetVirtualMachineDirectoryFn = func(_ goctx.Context, _ *vmopv1alpha1.VirtualMachine) (string, error) {
+					return "[LocalDS_0] dummy-vm", nil
+				}
+			})
+
+			getCNSRegisterVolume := func() *cnsregv1alpha1.CnsRegisterVolume {
+				registerVolume := &cnsregv1alpha1.CnsRegisterVolume{}
+				objectKey := client.ObjectKey{Name: imageVol.PersistentVolumeClaim.ClaimName, Namespace: vm.Namespace}
+				if err := ctx.Client.Get(ctx, objectKey, registerVolume); err != nil {
+					ExpectWithOffset(1, k8sapierrors.IsNotFound(err)).To(BeTrue())
+					return nil
+				}
+				return registerVolume
+			}
+
+			It("boot disk is not detached yet - no CnsRegisterVolume created", func() {
+				delete(vm.Annotations, constants.BootDiskURLPathAnnotation)
+				Expect(reconciler.ReconcileNormal(volCtx)).To(Succeed())
+
+				Expect(getCNSRegisterVolume()).To(BeNil())
+				Expect(getCNSAttachmentForVolumeName(vm, vmVolumeWithPVC1.Name)).To(BeNil())
+			})
+
+			It("CnsRegisterVolume is created for the boot disk", func() {
+				Expect(reconciler.ReconcileNormal(volCtx)).To(Succeed())
+
+				registerVolume := getCNSRegisterVolume()
+				Expect(registerVolume).ToNot(BeNil())
+				Expect(registerVolume.Spec.PvcName).To(Equal(imageVol.PersistentVolumeClaim.ClaimName))
+				Expect(registerVolume.Spec.DiskURLPath).To(Equal(dummyDiskURLPath))
+				Expect(registerVolume.Spec.AccessMode).To(Equal(corev1.ReadWriteOnce))
+				Expect(metav1.IsControlledBy(registerVolume, vm)).To(BeTrue())
+
+				By("Did not create CnsNodeVmAttachments before the PVC is bound", func() {
+					Expect(getCNSAttachmentForVolumeName(vm, imageVol.Name)).To(BeNil())
+					Expect(getCNSAttachmentForVolumeName(vm, vmVolumeWithPVC1.Name)).To(BeNil())
+				})
+			})
+
+			It("boot disk is not in the directory of the VM - no CnsRegisterVolume created", func() {
+				vm.Annotations[constants.BootDiskURLPathAnnotation] = "https://vc.domain.com/folder/other-vm/other-vm.vmdk?dcPath=DC0&dsName=LocalDS_0"
+				err := reconciler.ReconcileNormal(volCtx)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("is not in the directory [LocalDS_0] dummy-vm of the VM"))
+
+				Expect(getCNSRegisterVolume()).To(BeNil())
+			})
+
+			When("CnsRegisterVolume has an error", func() {
+				BeforeEach(func() {
+					registerVolume := &cnsregv1alpha1.CnsRegisterVolume{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      imageVol.PersistentVolumeClaim.ClaimName,
+							Namespace: vm.Namespace,
+						},
+						Status: cnsregv1alpha1.CnsRegisterVolumeStatus{
+							Error: "disk is attached to a VM",
+						},
+					}
+					initObjects = append(initObjects, registerVolume)
+				})
+
+				It("returns error", func() {
+					err := reconciler.ReconcileNormal(volCtx)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("disk is attached to a VM"))
+				})
+			})
+
+			When("PVC is bound", func() {
+				BeforeEach(func() {
+					pvc := &corev1.PersistentVolumeClaim{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      imageVol.PersistentVolumeClaim.ClaimName,
+							Namespace: vm.Namespace,
+						},
+						Status: corev1.PersistentVolumeClaimStatus{
+							Phase: corev1.ClaimBound,
+						},
+					}
+					initObjects = append(initObjects, pvc)
+				})
+
+				It("attaches the image volume first", func() {
+					Expect(reconciler.ReconcileNormal(volCtx)).To(Succeed())
+					Expect(vm.Annotations).ToNot(HaveKey(constants.BootDiskURLPathAnnotation))
+
+					attachments := &cnsv1alpha1.CnsNodeVmAttachmentList{}
+					Expect(ctx.Client.List(ctx, attachments, client.InNamespace(vm.Namespace))).To(Succeed())
+					Expect(attachments.Items).To(HaveLen(1))
+
+					attachment := getCNSAttachmentForVolumeName(vm, imageVol.Name)
+					Expect(attachment).ToNot(BeNil())
+					assertAttachmentSpecFromVMVol(vm, imageVol, attachment)
+				})
+			})
+		})
+
 		When("VM does not have BiosUUID", func() {
 			BeforeEach(func() {
 				vmVol = *vmVolumeWithPVC1
@@ -0,0 +1,86 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CnsRegisterVolumeSpec defines the desired state of CnsRegisterVolume
+// +k8s:openapi-gen=true
+type CnsRegisterVolumeSpec struct {
+	// PvcName is the name of the PersistentVolumeClaim created for the registered volume.
+	PvcName string `json:"pvcName"`
+
+	// VolumeID is the ID of an existing First Class Disk to register.
+	// Only one of VolumeID or DiskURLPath can be specified.
+	// +optional
+	VolumeID string `json:"volumeID,omitempty"`
+
+	// AccessMode is the access mode of the PersistentVolume and PersistentVolumeClaim.
+	AccessMode v1.PersistentVolumeAccessMode `json:"accessMode,omitempty"`
+
+	// DiskURLPath is the URL path of an existing virtual disk to register.
+	// The virtual disk must not be attached to a VM.
+	// Only one of VolumeID or DiskURLPath can be specified.
+	// +optional
+	DiskURLPath string `json:"diskURLPath,omitempty"`
+}
+
+// CnsRegisterVolumeStatus defines the observed state of CnsRegisterVolume
+// +k8s:openapi-gen=true
+type CnsRegisterVolumeStatus struct {
+	// Indicates the volume is successfully registered.
+	// This field must only be set by the entity completing the register
+	// operation, i.e. the CNS Operator.
+	Registered bool `json:"registered"`
+
+	// The last error encountered during the register operation, if any.
+	// This field must only be set by the entity completing the register
+	// operation, i.e. the CNS Operator.
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// +k8s:openapi-gen=true
+// +kubebuilder:subresource:status
+
+// CnsRegisterVolume is the Schema for the cnsregistervolumes API
+type CnsRegisterVolume struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CnsRegisterVolumeSpec   `json:"spec,omitempty"`
+	Status CnsRegisterVolumeStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CnsRegisterVolumeList contains a list of CnsRegisterVolume
+type CnsRegisterVolumeList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CnsRegisterVolume `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CnsRegisterVolume{}, &CnsRegisterVolumeList{})
+}
@@ -1,4 +1,4 @@
-// Copyright (c) 2022 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2022-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package context
@@ -10,4 +10,8 @@ const (
 	// MaxDeployThreadsContextKey is the context key that stores the maximum
 	// number of threads allowed used to deploy a VM.
 	MaxDeployThreadsContextKey Key = iota
+
+	// MaxInstantCloneThreadsContextKey is the context key that stores the
+	// maximum number of threads allowed used to instant clone a VM.
+	MaxInstantCloneThreadsContextKey
 )
@@ -0,0 +1,25 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package context
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+
+	vmopv1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
+)
+
+// VirtualMachineSnapshotContext is the context used for VirtualMachineSnapshotControllers.
+type VirtualMachineSnapshotContext struct {
+	context.Context
+	Logger     logr.Logger
+	VMSnapshot *vmopv1.VirtualMachineSnapshot
+	VM         *vmopv1.VirtualMachine
+}
+
+func (v *VirtualMachineSnapshotContext) String() string {
+	return fmt.Sprintf("%s %s/%s", v.VMSnapshot.GroupVersionKind(), v.VMSnapshot.Namespace, v.VMSnapshot.Name)
+}
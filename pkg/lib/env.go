@@ -1,4 +1,4 @@
-// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2019-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package lib
@@ -26,6 +26,13 @@ const (
 	MaxCreateVMsOnProviderEnv     = "MAX_CREATE_VMS_ON_PROVIDER"
 	DefaultMaxCreateVMsOnProvider = 80
 
+	// MaxInstantCloneVMsOnProviderEnv is the env variable for setting the percentage of reconciler threads that
+	// can be used to instant clone VMs on the provider concurrently.
+	MaxInstantCloneVMsOnProviderEnv = "MAX_INSTANT_CLONE_VMS_ON_PROVIDER"
+	// DefaultMaxInstantCloneVMsOnProvider is the default percentage of reconciler threads that can be used to
+	// instant clone VMs. Instant clones complete in seconds so they may use all the threads.
+	DefaultMaxInstantCloneVMsOnProvider = 100
+
 	InstanceStoragePVPlacementFailedTTLEnv = "INSTANCE_STORAGE_PV_PLACEMENT_FAILED_TTL"
 	// DefaultInstanceStoragePVPlacementFailedTTL is the default wait time before declaring PV placement failed
 	// after error annotation is set on PVC.
@@ -46,6 +53,23 @@ const (
 	// DefaultInstanceStorageSeedRequeueDuration is the default seed requeue duration for instance storage.
 	DefaultInstanceStorageSeedRequeueDuration = 10 * time.Second
 
+	// GuestCustomizationPendingTimeoutEnv is the env variable for setting how long a guest customization may be
+	// pending before it is considered stale.
+	GuestCustomizationPendingTimeoutEnv = "GUEST_CUSTOMIZATION_PENDING_TIMEOUT"
+	// DefaultGuestCustomizationPendingTimeout is the default time after which a pending guest customization that
+	// was not picked up by the guest is cleared and applied again.
+	DefaultGuestCustomizationPendingTimeout = 30 * time.Minute
+
+	// ExternalLoadBalancerClassEnv is the env variable for setting the loadBalancerClass set on Services so they
+	// are implemented by a specific load balancer controller, such as MetalLB or kube-vip.
+	ExternalLoadBalancerClassEnv = "EXTERNAL_LB_CLASS"
+	// ExternalLoadBalancerIPPoolAnnotationEnv is the env variable for setting the Service annotation key the load
+	// balancer controller reads the IP pool to allocate from, such as "metallb.universe.tf/address-pool".
+	ExternalLoadBalancerIPPoolAnnotationEnv = "EXTERNAL_LB_IP_POOL_ANNOTATION"
+	// ExternalLoadBalancerIPPoolEnv is the env variable for setting the IP pool used when the
+	// VirtualMachineService does not specify one.
+	ExternalLoadBalancerIPPoolEnv = "EXTERNAL_LB_IP_POOL"
+
 	// NetworkProviderType is the cluster network provider type. It can be VSPHERE_NETWORK, NSX-T or NAMED.
 	// NAMED is only used in a local test environment.
 	NetworkProviderType = "NETWORK_PROVIDER"
@@ -115,6 +139,25 @@ var MaxConcurrentCreateVMsOnProvider = func() int {
 	return val
 }
 
+// MaxConcurrentInstantCloneVMsOnProvider returns the percentage of reconciler
+// threads that can be used to instant clone VMs on the provider concurrently.
+// Instant clones are not counted against MaxConcurrentCreateVMsOnProvider. The
+// default is 100.
+var MaxConcurrentInstantCloneVMsOnProvider = func() int {
+	v := os.Getenv(MaxInstantCloneVMsOnProviderEnv)
+	if v == "" {
+		return DefaultMaxInstantCloneVMsOnProvider
+	}
+
+	// Return default in case of an invalid value.
+	val, err := strconv.Atoi(v)
+	if err != nil || val <= 0 || val > 100 {
+		return DefaultMaxInstantCloneVMsOnProvider
+	}
+
+	return val
+}
+
 // GetInstanceStoragePVPlacementFailedTTL returns the configured wait time before declaring PV placement
 // failed after error annotation is set on PVC.
 func GetInstanceStoragePVPlacementFailedTTL() time.Duration {
@@ -126,6 +169,35 @@ func GetInstanceStoragePVPlacementFailedTTL() time.Duration {
 	return DefaultInstanceStoragePVPlacementFailedTTL
 }
 
+// GetGuestCustomizationPendingTimeout returns the configured time after which a pending guest customization
+// is considered stale.
+func GetGuestCustomizationPendingTimeout() time.Duration {
+	if timeout := os.Getenv(GuestCustomizationPendingTimeoutEnv); len(timeout) > 0 {
+		if duration, err := time.ParseDuration(timeout); err == nil {
+			return duration
+		}
+	}
+	return DefaultGuestCustomizationPendingTimeout
+}
+
+// GetExternalLoadBalancerClass returns the configured loadBalancerClass of the Services implemented by an
+// external load balancer controller.
+func GetExternalLoadBalancerClass() string {
+	return os.Getenv(ExternalLoadBalancerClassEnv)
+}
+
+// GetExternalLoadBalancerIPPoolAnnotation returns the configured Service annotation key that selects the IP
+// pool of an external load balancer controller.
+func GetExternalLoadBalancerIPPoolAnnotation() string {
+	return os.Getenv(ExternalLoadBalancerIPPoolAnnotationEnv)
+}
+
+// GetExternalLoadBalancerIPPool returns the configured IP pool used when the VirtualMachineService does not
+// specify one.
+func GetExternalLoadBalancerIPPool() string {
+	return os.Getenv(ExternalLoadBalancerIPPoolEnv)
+}
+
 // GetInstanceStorageRequeueDelay returns requeue delay for instance storage.
 func GetInstanceStorageRequeueDelay() time.Duration {
 	maxFactor := DefaultInstanceStorageJitterMaxFactor
@@ -1,4 +1,4 @@
-// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2020-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package lib
@@ -6,6 +6,7 @@ package lib
 import (
 	"os"
 	"strconv"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -42,3 +43,63 @@ var _ = Describe("MaxAllowedConcurrentVMsOnProvider", func() {
 		})
 	})
 })
+
+var _ = Describe("MaxConcurrentInstantCloneVMsOnProvider", func() {
+	Context("when the MAX_INSTANT_CLONE_VMS_ON_PROVIDER env is set", func() {
+		AfterEach(func() {
+			Expect(os.Unsetenv(MaxInstantCloneVMsOnProviderEnv)).To(Succeed())
+		})
+
+		Context("with a valid env value", func() {
+			It("returns the value from the env", func() {
+				Expect(os.Setenv(MaxInstantCloneVMsOnProviderEnv, "50")).To(Succeed())
+
+				Expect(MaxConcurrentInstantCloneVMsOnProvider()).To(Equal(50))
+			})
+		})
+
+		Context("with an out of range env value", func() {
+			It("returns the default value", func() {
+				Expect(os.Setenv(MaxInstantCloneVMsOnProviderEnv, "200")).To(Succeed())
+
+				Expect(MaxConcurrentInstantCloneVMsOnProvider()).To(Equal(DefaultMaxInstantCloneVMsOnProvider))
+			})
+		})
+	})
+
+	Context("when the MAX_INSTANT_CLONE_VMS_ON_PROVIDER env is not set", func() {
+		It("returns the default value", func() {
+			Expect(MaxConcurrentInstantCloneVMsOnProvider()).To(Equal(DefaultMaxInstantCloneVMsOnProvider))
+		})
+	})
+})
+
+var _ = Describe("GetGuestCustomizationPendingTimeout", func() {
+	AfterEach(func() {
+		Expect(os.Unsetenv(GuestCustomizationPendingTimeoutEnv)).To(Succeed())
+	})
+
+	Context("when the GUEST_CUSTOMIZATION_PENDING_TIMEOUT env is set", func() {
+		Context("with a valid env value", func() {
+			It("returns the value from the env", func() {
+				Expect(os.Setenv(GuestCustomizationPendingTimeoutEnv, "90s")).To(Succeed())
+
+				Expect(GetGuestCustomizationPendingTimeout()).To(Equal(90 * time.Second))
+			})
+		})
+
+		Context("with an invalid env value", func() {
+			It("returns the default value", func() {
+				Expect(os.Setenv(GuestCustomizationPendingTimeoutEnv, "ninety")).To(Succeed())
+
+				Expect(GetGuestCustomizationPendingTimeout()).To(Equal(DefaultGuestCustomizationPendingTimeout))
+			})
+		})
+	})
+
+	Context("when the GUEST_CUSTOMIZATION_PENDING_TIMEOUT env is not set", func() {
+		It("returns the default value", func() {
+			Expect(GetGuestCustomizationPendingTimeout()).To(Equal(DefaultGuestCustomizationPendingTimeout))
+		})
+	})
+})
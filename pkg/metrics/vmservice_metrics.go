@@ -1,4 +1,4 @@
-// Copyright (c) 2022 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2022-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package metrics
@@ -23,6 +23,7 @@ type VMMetrics struct {
 	statusConditionStatus *prometheus.GaugeVec
 	statusPhase           *prometheus.GaugeVec
 	powerState            *prometheus.GaugeVec
+	lastRestartTime       *prometheus.GaugeVec
 	statusIP              *prometheus.GaugeVec
 }
 
@@ -50,6 +51,13 @@ func NewVMMetrics() *VMMetrics {
 					Help:      "Desired and current power state on a VM resource"},
 				[]string{vmNameLabel, vmNamespaceLabel, specLabel, statusLabel},
 			),
+			lastRestartTime: prometheus.NewGaugeVec(
+				prometheus.GaugeOpts{
+					Namespace: metricsNamespace,
+					Name:      "vm_last_restart_time_seconds",
+					Help:      "Time, in seconds since the epoch, of the last restart requested on a VM resource"},
+				[]string{vmNameLabel, vmNamespaceLabel},
+			),
 
 			statusIP: prometheus.NewGaugeVec(
 				prometheus.GaugeOpts{
@@ -64,6 +72,7 @@ func NewVMMetrics() *VMMetrics {
 			vmMetrics.statusConditionStatus,
 			vmMetrics.statusPhase,
 			vmMetrics.powerState,
+			vmMetrics.lastRestartTime,
 			vmMetrics.statusIP,
 		)
 	})
@@ -98,6 +107,9 @@ func (vmm *VMMetrics) DeleteMetrics(vmCtx *context.VirtualMachineContext) {
 	// Delete the 'vm.spec.powerState' metrics.
 	vmm.powerState.DeletePartialMatch(labels)
 
+	// Delete the 'vm.status.lastRestartTime' metrics.
+	vmm.lastRestartTime.DeletePartialMatch(labels)
+
 	// Delete the 'vm.status.ip' metrics.
 	vmm.statusIP.DeletePartialMatch(labels)
 }
@@ -171,6 +183,10 @@ func (vmm *VMMetrics) registerVMPowerState(vmCtx *context.VirtualMachineContext)
 		statusLabel:      string(vm.Status.PowerState),
 	}
 	vmm.powerState.With(newLabels).Set(1)
+
+	if t := vm.Status.LastRestartTime; t != nil {
+		vmm.lastRestartTime.With(labels).Set(float64(t.Unix()))
+	}
 }
 
 func (vmm *VMMetrics) registerVMStatusIP(vmCtx *context.VirtualMachineContext) {
@@ -21,6 +21,7 @@ import (
 type funcs struct {
 	DoProbeFn            func(ctx *context.ProbeContext) error
 	ProcessProbeResultFn func(ctx *context.ProbeContext, res probe.Result, err error) error
+	ResetProbeResultsFn  func(vmName string)
 }
 
 type FakeWorker struct {
@@ -75,3 +76,12 @@ func (w *FakeWorker) ProcessProbeResult(ctx *context.ProbeContext, res probe.Res
 	}
 	return fmt.Errorf("unexpected method call: ProcessProbeResult")
 }
+
+func (w *FakeWorker) ResetProbeResults(vmName string) {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.funcs.ResetProbeResultsFn != nil {
+		w.funcs.ResetProbeResultsFn(vmName)
+	}
+}
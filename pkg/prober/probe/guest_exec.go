@@ -0,0 +1,57 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package probe
+
+import (
+	goctx "context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vmware-tanzu/vm-operator/pkg/prober/context"
+	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider"
+)
+
+type guestExecProber struct {
+	prober vmProviderProber
+}
+
+// NewGuestExecProber creates a new guest exec prober which implements the Probe interface to run
+// commands in the guest.
+func NewGuestExecProber(vmProviderProber vmProviderProber) Probe {
+	return &guestExecProber{
+		prober: vmProviderProber,
+	}
+}
+
+func (gep guestExecProber) Probe(ctx *context.ProbeContext) (Result, error) {
+	p := ctx.ProbeSpec
+	action := p.GuestExec
+
+	var timeout time.Duration
+	if p.TimeoutSeconds <= 0 {
+		timeout = defaultConnectTimeout
+	} else {
+		timeout = time.Duration(p.TimeoutSeconds) * time.Second
+	}
+
+	execCtx, cancel := goctx.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	exitCode, err := gep.prober.RunVirtualMachineGuestCommand(execCtx, ctx.VM, action.Command, action.CredentialsSecretName)
+	if err != nil {
+		// The guest not being able to run the command, such as when the guest rejects the
+		// credentials or VMware Tools is not running, is a failure of the probe.
+		if execCtx.Err() == goctx.DeadlineExceeded || errors.Is(err, vmprovider.ErrGuestCommandNotRun) {
+			return Failure, err
+		}
+		return Unknown, err
+	}
+
+	if exitCode != 0 {
+		return Failure, fmt.Errorf("guest command exited with code %d", exitCode)
+	}
+
+	return Success, nil
+}
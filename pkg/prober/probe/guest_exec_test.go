@@ -0,0 +1,117 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package probe
+
+import (
+	goctx "context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
+
+	"github.com/vmware-tanzu/vm-operator/pkg/prober/context"
+	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider"
+)
+
+var _ = Describe("Guest exec probe", func() {
+	var (
+		vm                 *vmopv1alpha1.VirtualMachine
+		fakeProvider       fakeVMProviderProber
+		testGuestExecProbe Probe
+
+		err error
+		res Result
+	)
+
+	BeforeEach(func() {
+		vm = &vmopv1alpha1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "dummy-vm",
+				Namespace: "dummy-ns",
+			},
+			Spec: vmopv1alpha1.VirtualMachineSpec{
+				ClassName:      "dummy-vmclass",
+				ReadinessProbe: getVirtualMachineReadinessGuestExecProbe(),
+			},
+		}
+
+		fakeProvider = fakeVMProviderProber{}
+	})
+
+	JustBeforeEach(func() {
+		testGuestExecProbe = NewGuestExecProber(&fakeProvider)
+		probeCtx := &context.ProbeContext{
+			Context:   goctx.Background(),
+			Logger:    ctrl.Log.WithName("Probe").WithValues("name", vm.NamespacedName()),
+			ProbeSpec: vm.Spec.ReadinessProbe,
+			VM:        vm,
+		}
+
+		res, err = testGuestExecProbe.Probe(probeCtx)
+	})
+
+	Context("Provider returns an error", func() {
+		BeforeEach(func() { fakeProvider.err = fmt.Errorf("fake error") })
+
+		It("returns unknown", func() {
+			Expect(err).To(MatchError(fmt.Errorf("fake error")))
+			Expect(res).To(Equal(Unknown))
+		})
+	})
+
+	Context("Guest could not run the command", func() {
+		BeforeEach(func() { fakeProvider.err = fmt.Errorf("%w: invalid guest login", vmprovider.ErrGuestCommandNotRun) })
+
+		It("returns failure", func() {
+			Expect(err).To(MatchError(vmprovider.ErrGuestCommandNotRun))
+			Expect(res).To(Equal(Failure))
+		})
+	})
+
+	Context("Command does not exit before the timeout", func() {
+		BeforeEach(func() {
+			fakeProvider.execFn = func(ctx goctx.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+		})
+
+		It("returns failure", func() {
+			Expect(err).To(MatchError(goctx.DeadlineExceeded))
+			Expect(res).To(Equal(Failure))
+		})
+	})
+
+	Context("Command exits with a non-zero code", func() {
+		BeforeEach(func() { fakeProvider.exitCode = 2 })
+
+		It("returns failure", func() {
+			Expect(err).To(MatchError(fmt.Errorf("guest command exited with code 2")))
+			Expect(res).To(Equal(Failure))
+		})
+	})
+
+	Context("Command exits with a zero code", func() {
+		It("returns success", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res).To(Equal(Success))
+		})
+	})
+})
+
+func getVirtualMachineReadinessGuestExecProbe() *vmopv1alpha1.Probe {
+	return &vmopv1alpha1.Probe{
+		GuestExec: &vmopv1alpha1.GuestExecAction{
+			Command:               []string{"/usr/bin/systemctl", "is-active", "app"},
+			CredentialsSecretName: "dummy-secret",
+		},
+		TimeoutSeconds: 1,
+		PeriodSeconds:  1,
+	}
+}
@@ -1,4 +1,4 @@
-// Copyright (c) 2021 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2021-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package probe
@@ -19,14 +19,25 @@ import (
 )
 
 type fakeVMProviderProber struct {
-	status vmopv1alpha1.GuestHeartbeatStatus
-	err    error
+	status   vmopv1alpha1.GuestHeartbeatStatus
+	exitCode int32
+	execFn   func(ctx goctx.Context) error
+	err      error
 }
 
 func (tp fakeVMProviderProber) GetVirtualMachineGuestHeartbeat(_ goctx.Context, _ *vmopv1alpha1.VirtualMachine) (vmopv1alpha1.GuestHeartbeatStatus, error) {
 	return tp.status, tp.err
 }
 
+func (tp fakeVMProviderProber) RunVirtualMachineGuestCommand(ctx goctx.Context, _ *vmopv1alpha1.VirtualMachine, _ []string, _ string) (int32, error) {
+	if tp.execFn != nil {
+		if err := tp.execFn(ctx); err != nil {
+			return -1, err
+		}
+	}
+	return tp.exitCode, tp.err
+}
+
 var _ = Describe("Guest heartbeat probe", func() {
 	var (
 		vm                   *vmopv1alpha1.VirtualMachine
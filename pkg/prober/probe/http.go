@@ -0,0 +1,133 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package probe
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
+
+	"github.com/vmware-tanzu/vm-operator/pkg/prober/context"
+)
+
+const (
+	defaultHTTPSuccessMinStatus = http.StatusOK
+	defaultHTTPSuccessMaxStatus = http.StatusBadRequest - 1
+
+	// maxHTTPResponseBodyBytes is the maximum number of bytes read from the response body
+	// so the connection can be closed cleanly.
+	maxHTTPResponseBodyBytes = 10 * 1024
+)
+
+// httpProber implements the Probe interface.
+type httpProber struct{}
+
+// NewHTTPProber creates a new http prober which implements the Probe interface to execute http probes.
+func NewHTTPProber() Probe {
+	return &httpProber{}
+}
+
+func (pr httpProber) Probe(ctx *context.ProbeContext) (Result, error) {
+	vm := ctx.VM
+	p := ctx.ProbeSpec
+	action := p.HTTPGet
+
+	portNum, err := findPort(vm, action.Port, corev1.ProtocolTCP)
+	if err != nil {
+		return Failure, err
+	}
+
+	var host string
+	if action.Host != "" {
+		host = action.Host
+	} else {
+		ctx.Logger.V(4).Info("HTTPGet Host not specified, using VM IP", "probe", ctx.String())
+		if host = vm.Status.VmIp; host == "" {
+			return Failure, fmt.Errorf("VM %s doesn't have an IP assigned", vm.NamespacedName())
+		}
+	}
+
+	var timeout time.Duration
+	if p.TimeoutSeconds <= 0 {
+		timeout = defaultConnectTimeout
+	} else {
+		timeout = time.Duration(p.TimeoutSeconds) * time.Second
+	}
+
+	req, err := newHTTPGetRequest(action, host, portNum)
+	if err != nil {
+		return Failure, err
+	}
+
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// The user explicitly opts in to skip the verification.
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: action.InsecureSkipTLSVerify}, //nolint:gosec
+			DisableKeepAlives: true,
+		},
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return Failure, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxHTTPResponseBodyBytes))
+
+	minStatus, maxStatus := defaultHTTPSuccessMinStatus, defaultHTTPSuccessMaxStatus
+	if action.ExpectedStatus != nil {
+		minStatus, maxStatus = int(action.ExpectedStatus.Min), int(action.ExpectedStatus.Max)
+	}
+
+	if resp.StatusCode < minStatus || resp.StatusCode > maxStatus {
+		return Failure, fmt.Errorf("HTTP probe failed with status code %d", resp.StatusCode)
+	}
+
+	return Success, nil
+}
+
+func newHTTPGetRequest(action *vmopv1alpha1.HTTPGetAction, host string, port int) (*http.Request, error) {
+	scheme := strings.ToLower(string(vmopv1alpha1.URISchemeHTTP))
+	if action.Scheme != "" {
+		scheme = strings.ToLower(string(action.Scheme))
+	}
+
+	path := action.Path
+	if path != "" && !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+	u.Scheme = scheme
+	u.Host = net.JoinHostPort(host, strconv.Itoa(port))
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, header := range action.HTTPHeaders {
+		if strings.EqualFold(header.Name, "Host") {
+			req.Host = header.Value
+			continue
+		}
+		req.Header.Add(header.Name, header.Value)
+	}
+
+	return req, nil
+}
@@ -0,0 +1,202 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package probe
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
+
+	"github.com/vmware-tanzu/vm-operator/pkg/prober/context"
+)
+
+var _ = Describe("HTTP probe", func() {
+	const (
+		healthPath   = "/healthz"
+		headerName   = "X-Probe"
+		headerValue  = "vm-operator"
+		statusHeader = "X-Status"
+	)
+
+	var (
+		vm            *vmopv1alpha1.VirtualMachine
+		testHTTPProbe Probe
+
+		testServer *httptest.Server
+		testHost   string
+		testPort   int
+		tls        bool
+		probeSpec  *vmopv1alpha1.Probe
+	)
+
+	BeforeEach(func() {
+		vm = &vmopv1alpha1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "dummy-vm",
+				Namespace: "dummy-ns",
+			},
+			Spec: vmopv1alpha1.VirtualMachineSpec{
+				ClassName: "dummy-vmclass",
+			},
+		}
+		probeSpec = &vmopv1alpha1.Probe{
+			HTTPGet: &vmopv1alpha1.HTTPGetAction{
+				Path: healthPath,
+				HTTPHeaders: []vmopv1alpha1.HTTPHeader{
+					{Name: headerName, Value: headerValue},
+				},
+			},
+			PeriodSeconds: 1,
+		}
+		tls = false
+		testHTTPProbe = NewHTTPProber()
+	})
+
+	JustBeforeEach(func() {
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.URL.Path != healthPath:
+				w.WriteHeader(http.StatusNotFound)
+			case r.Header.Get(statusHeader) != "":
+				status, _ := strconv.Atoi(r.Header.Get(statusHeader))
+				w.WriteHeader(status)
+			case r.Header.Get(headerName) != headerValue:
+				w.WriteHeader(http.StatusBadRequest)
+			default:
+				w.WriteHeader(http.StatusOK)
+			}
+		})
+
+		if tls {
+			testServer = httptest.NewTLSServer(handler)
+		} else {
+			testServer = httptest.NewServer(handler)
+		}
+
+		host, port, err := net.SplitHostPort(testServer.Listener.Addr().String())
+		Expect(err).NotTo(HaveOccurred())
+		testHost = host
+		testPort, err = strconv.Atoi(port)
+		Expect(err).NotTo(HaveOccurred())
+
+		probeSpec.HTTPGet.Port = intstr.FromInt(testPort)
+		vm.Spec.ReadinessProbe = probeSpec
+	})
+
+	AfterEach(func() {
+		testServer.Close()
+	})
+
+	doProbe := func() (Result, error) {
+		probeCtx := &context.ProbeContext{
+			VM:        vm,
+			ProbeSpec: vm.Spec.ReadinessProbe,
+			Logger:    ctrl.Log.WithName("Probe").WithValues("name", vm.NamespacedName()),
+		}
+		return testHTTPProbe.Probe(probeCtx)
+	}
+
+	It("HTTP probe succeeds, with empty host", func() {
+		vm.Status.VmIp = testHost
+
+		res, err := doProbe()
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(res).To(Equal(Success))
+	})
+
+	It("HTTP probe succeeds, with host set in VM spec", func() {
+		vm.Spec.ReadinessProbe.HTTPGet.Host = testHost
+
+		res, err := doProbe()
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(res).To(Equal(Success))
+	})
+
+	It("HTTP probe fails when VM has no IP", func() {
+		res, err := doProbe()
+		Expect(err).Should(HaveOccurred())
+		Expect(res).To(Equal(Failure))
+	})
+
+	It("HTTP probe fails when the path is not found", func() {
+		vm.Spec.ReadinessProbe.HTTPGet.Host = testHost
+		vm.Spec.ReadinessProbe.HTTPGet.Path = "/missing"
+
+		res, err := doProbe()
+		Expect(err).Should(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("404"))
+		Expect(res).To(Equal(Failure))
+	})
+
+	It("HTTP probe fails when the headers are not sent", func() {
+		vm.Spec.ReadinessProbe.HTTPGet.Host = testHost
+		vm.Spec.ReadinessProbe.HTTPGet.HTTPHeaders = nil
+
+		res, err := doProbe()
+		Expect(err).Should(HaveOccurred())
+		Expect(res).To(Equal(Failure))
+	})
+
+	It("HTTP probe succeeds when the status code is in the expected range", func() {
+		vm.Spec.ReadinessProbe.HTTPGet.Host = testHost
+		vm.Spec.ReadinessProbe.HTTPGet.HTTPHeaders = append(vm.Spec.ReadinessProbe.HTTPGet.HTTPHeaders,
+			vmopv1alpha1.HTTPHeader{Name: statusHeader, Value: strconv.Itoa(http.StatusServiceUnavailable)})
+
+		res, err := doProbe()
+		Expect(err).Should(HaveOccurred())
+		Expect(res).To(Equal(Failure))
+
+		vm.Spec.ReadinessProbe.HTTPGet.ExpectedStatus = &vmopv1alpha1.HTTPStatusRange{
+			Min: http.StatusOK,
+			Max: http.StatusServiceUnavailable,
+		}
+
+		res, err = doProbe()
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(res).To(Equal(Success))
+	})
+
+	It("HTTP probe fails when nothing is listening", func() {
+		vm.Spec.ReadinessProbe.HTTPGet.Host = testHost
+		vm.Spec.ReadinessProbe.HTTPGet.Port = intstr.FromInt(10001)
+
+		res, err := doProbe()
+		Expect(err).Should(HaveOccurred())
+		Expect(res).To(Equal(Failure))
+	})
+
+	When("the server uses TLS", func() {
+		BeforeEach(func() {
+			tls = true
+			probeSpec.HTTPGet.Scheme = vmopv1alpha1.URISchemeHTTPS
+		})
+
+		It("HTTPS probe fails when the certificate is not trusted", func() {
+			vm.Spec.ReadinessProbe.HTTPGet.Host = testHost
+
+			res, err := doProbe()
+			Expect(err).Should(HaveOccurred())
+			Expect(res).To(Equal(Failure))
+		})
+
+		It("HTTPS probe succeeds when the TLS verification is skipped", func() {
+			vm.Spec.ReadinessProbe.HTTPGet.Host = testHost
+			vm.Spec.ReadinessProbe.HTTPGet.InsecureSkipTLSVerify = true
+
+			res, err := doProbe()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(res).To(Equal(Success))
+		})
+	})
+})
@@ -1,4 +1,4 @@
-// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2020-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package probe
@@ -33,12 +33,15 @@ type Probe interface {
 // Probing related provider methods.
 type vmProviderProber interface {
 	GetVirtualMachineGuestHeartbeat(ctx goctx.Context, vm *vmopv1alpha1.VirtualMachine) (vmopv1alpha1.GuestHeartbeatStatus, error)
+	RunVirtualMachineGuestCommand(ctx goctx.Context, vm *vmopv1alpha1.VirtualMachine, command []string, credentialsSecretName string) (int32, error)
 }
 
 // Prober contains the different type of probes.
 type Prober struct {
 	TCPProbe       Probe
 	GuestHeartbeat Probe
+	HTTPProbe      Probe
+	GuestExec      Probe
 }
 
 // NewProber creates a new Prober.
@@ -46,5 +49,7 @@ func NewProber(vmProviderProber vmProviderProber) *Prober {
 	return &Prober{
 		TCPProbe:       NewTCPProber(),
 		GuestHeartbeat: NewGuestHeartbeatProber(vmProviderProber),
+		HTTPProbe:      NewHTTPProber(),
+		GuestExec:      NewGuestExecProber(vmProviderProber),
 	}
 }
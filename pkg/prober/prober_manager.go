@@ -1,4 +1,4 @@
-// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2020-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package prober
@@ -30,14 +30,18 @@ import (
 const (
 	proberManagerName       = "virtualmachine-prober-manager"
 	readinessProbeQueueName = "readinessProbeQueue"
+	livenessProbeQueueName  = "livenessProbeQueue"
 
 	// defaultPeriodSeconds represents the default value for the frequency (in seconds) to perform the probe.
 	// We use the same default value as the kubernetes container probe.
 	defaultPeriodSeconds = 10
 
-	// the number of readiness workers.
+	// the number of goroutines running the readiness worker.
 	// TODO: find a way to calibrate it.
 	numberOfReadinessWorkers = 5
+
+	// the number of goroutines running the liveness worker.
+	numberOfLivenessWorkers = 5
 )
 
 // Manager represents a prober manager interface.
@@ -51,17 +55,27 @@ type Manager interface {
 type manager struct {
 	client         client.Client
 	readinessQueue workqueue.DelayingInterface
+	livenessQueue  workqueue.DelayingInterface
 	prober         *probe.Prober
+	vmProvider     vmprovider.VirtualMachineProviderInterface
 	log            logr.Logger
 	recorder       vmoprecord.Recorder
 
-	workersWG sync.WaitGroup
+	// The workers track the consecutive probe results of each VM so each is shared by all the
+	// goroutines processing its queue.
+	readinessWorker worker.Worker
+	livenessWorker  worker.Worker
+	workersWG       sync.WaitGroup
 
 	// We will use AddAfter to add an item to the queue, which will insert the item to a heap first
 	// if the time duration set in the AddAfter is not zero. vmReadinessProbeList can be used to avoid
 	// adding VMs to the readiness queue when this VM is already in the heap but not in the queue.
 	readinessMutex       sync.Mutex
 	vmReadinessProbeList map[string]*vmoperatorv1alpha1.Probe
+
+	// vmLivenessProbeList serves the same purpose as vmReadinessProbeList for the liveness queue.
+	livenessMutex       sync.Mutex
+	vmLivenessProbeList map[string]*vmoperatorv1alpha1.LivenessProbe
 }
 
 // NewManger initializes a prober manager.
@@ -69,11 +83,18 @@ func NewManger(client client.Client, record vmoprecord.Recorder, vmProvider vmpr
 	probeManager := &manager{
 		client:               client,
 		readinessQueue:       workqueue.NewNamedDelayingQueue(readinessProbeQueueName),
+		livenessQueue:        workqueue.NewNamedDelayingQueue(livenessProbeQueueName),
 		prober:               probe.NewProber(vmProvider),
+		vmProvider:           vmProvider,
 		log:                  ctrl.Log.WithName(proberManagerName),
 		recorder:             record,
 		vmReadinessProbeList: make(map[string]*vmoperatorv1alpha1.Probe),
+		vmLivenessProbeList:  make(map[string]*vmoperatorv1alpha1.LivenessProbe),
 	}
+	probeManager.readinessWorker = worker.NewReadinessWorker(
+		probeManager.readinessQueue, probeManager.prober, client, record)
+	probeManager.livenessWorker = worker.NewLivenessWorker(
+		probeManager.livenessQueue, probeManager.prober, client, record, vmProvider)
 	return probeManager
 }
 
@@ -92,8 +113,15 @@ func AddToManager(mgr ctrlmgr.Manager, vmProvider vmprovider.VirtualMachineProvi
 
 // AddToProberManager adds a VM to the prober manager.
 func (m *manager) AddToProberManager(vm *vmoperatorv1alpha1.VirtualMachine) {
+	m.log.V(4).Info("Add to prober manager", "vm", vm.NamespacedName())
+
+	m.addToReadinessQueue(vm)
+	m.addToLivenessQueue(vm)
+}
+
+// addToReadinessQueue adds a VM to the readiness queue if it has a readiness probe.
+func (m *manager) addToReadinessQueue(vm *vmoperatorv1alpha1.VirtualMachine) {
 	vmName := vm.NamespacedName()
-	m.log.V(4).Info("Add to prober manager", "vm", vmName)
 
 	m.readinessMutex.Lock()
 	defer m.readinessMutex.Unlock()
@@ -111,6 +139,29 @@ func (m *manager) AddToProberManager(vm *vmoperatorv1alpha1.VirtualMachine) {
 		m.vmReadinessProbeList[vmName] = newProbe
 	} else {
 		delete(m.vmReadinessProbeList, vmName)
+		m.readinessWorker.ResetProbeResults(vmName)
+	}
+}
+
+// addToLivenessQueue adds a VM to the liveness queue if it has a liveness probe.
+func (m *manager) addToLivenessQueue(vm *vmoperatorv1alpha1.VirtualMachine) {
+	vmName := vm.NamespacedName()
+
+	m.livenessMutex.Lock()
+	defer m.livenessMutex.Unlock()
+
+	if vm.Spec.LivenessProbe != nil {
+		newProbe := vm.Spec.LivenessProbe
+		if oldProbe, ok := m.vmLivenessProbeList[vmName]; ok && reflect.DeepEqual(oldProbe, newProbe) {
+			m.log.V(4).Info("VM is already in the liveness probe list and its probe spec is not updated, skip it", "vm", vmName)
+			return
+		}
+
+		m.livenessQueue.Add(client.ObjectKey{Name: vm.Name, Namespace: vm.Namespace})
+		m.vmLivenessProbeList[vmName] = newProbe
+	} else {
+		delete(m.vmLivenessProbeList, vmName)
+		m.livenessWorker.ResetProbeResults(vmName)
 	}
 }
 
@@ -120,8 +171,14 @@ func (m *manager) RemoveFromProberManager(vm *vmoperatorv1alpha1.VirtualMachine)
 	m.log.V(4).Info("Remove from prober manager", "vm", vmName)
 
 	m.readinessMutex.Lock()
-	defer m.readinessMutex.Unlock()
 	delete(m.vmReadinessProbeList, vmName)
+	m.readinessWorker.ResetProbeResults(vmName)
+	m.readinessMutex.Unlock()
+
+	m.livenessMutex.Lock()
+	delete(m.vmLivenessProbeList, vmName)
+	m.livenessWorker.ResetProbeResults(vmName)
+	m.livenessMutex.Unlock()
 }
 
 // Start starts the probe manager.
@@ -132,13 +189,19 @@ func (m *manager) Start(ctx goctx.Context) error {
 	m.log.Info("Starting readiness workers", "count", numberOfReadinessWorkers)
 	m.workersWG.Add(numberOfReadinessWorkers)
 	for i := 0; i < numberOfReadinessWorkers; i++ {
-		readinessWorker := worker.NewReadinessWorker(m.readinessQueue, m.prober, m.client, m.recorder)
-		m.worker(readinessWorker)
+		m.worker(m.readinessWorker)
+	}
+
+	m.log.Info("Starting liveness workers", "count", numberOfLivenessWorkers)
+	m.workersWG.Add(numberOfLivenessWorkers)
+	for i := 0; i < numberOfLivenessWorkers; i++ {
+		m.worker(m.livenessWorker)
 	}
 
 	<-ctx.Done()
 
 	m.readinessQueue.ShutDown()
+	m.livenessQueue.ShutDown()
 	m.workersWG.Wait()
 	return nil
 }
@@ -171,6 +234,8 @@ func (m *manager) processItemFromQueue(w worker.Worker) bool {
 	vm := &vmoperatorv1alpha1.VirtualMachine{}
 	if err := m.client.Get(goctx.Background(), item, vm); err != nil {
 		if apierrors.IsNotFound(err) {
+			// The VM was deleted so its probe results are no longer needed.
+			w.ResetProbeResults(item.String())
 			return false
 		}
 		// Get VM error, immediately re-queue the VM.
@@ -185,6 +250,7 @@ func (m *manager) processItemFromQueue(w worker.Worker) bool {
 
 	if ctx.ProbeSpec == nil {
 		ctx.Logger.V(4).Info("probe is not specified")
+		w.ResetProbeResults(vm.NamespacedName())
 		return false
 	}
 
@@ -1,4 +1,4 @@
-// Copyright (c) 2019-2021 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2019-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package prober
@@ -111,6 +111,20 @@ var _ = Describe("VirtualMachine probes", func() {
 			})
 		})
 
+		When("VM is not found", func() {
+			It("Should reset the probe results of the VM", func() {
+				Expect(fakeClient.Delete(ctx, vm)).To(Succeed())
+				var resetVMName string
+				fakeWorker.ResetProbeResultsFn = func(vmName string) {
+					resetVMName = vmName
+				}
+
+				quit := testManager.processItemFromQueue(fakeWorker)
+				Expect(quit).To(BeFalse())
+				Expect(resetVMName).To(Equal(vm.NamespacedName()))
+			})
+		})
+
 		When("VM specifies a probe", func() {
 			BeforeEach(func() {
 				vm.Spec.ReadinessProbe = vmProbe
@@ -256,6 +270,11 @@ var _ = Describe("VirtualMachine probes", func() {
 			It("Should remove from the manager if the VM's probe spec is changed to nil", func() {
 				newVM.Spec.ReadinessProbe = nil
 				Expect(fakeClient.Update(ctx, newVM)).To(Succeed())
+				var resetVMName string
+				fakeWorker.ResetProbeResultsFn = func(vmName string) {
+					resetVMName = vmName
+				}
+				testManager.readinessWorker = fakeWorker
 
 				testManager.AddToProberManager(newVM)
 
@@ -263,6 +282,43 @@ var _ = Describe("VirtualMachine probes", func() {
 				testManager.readinessMutex.Lock()
 				Expect(testManager.vmReadinessProbeList).ShouldNot(HaveKey(vm.NamespacedName()))
 				testManager.readinessMutex.Unlock()
+				Expect(resetVMName).To(Equal(vm.NamespacedName()))
+			})
+		})
+
+		When("VM specifies a liveness probe", func() {
+			BeforeEach(func() {
+				vm.Spec.ReadinessProbe = nil
+				vm.Spec.LivenessProbe = &vmopv1alpha1.LivenessProbe{
+					Probe:  *vmProbe,
+					Action: vmopv1alpha1.LivenessProbeActionReset,
+				}
+			})
+
+			It("Should add to the liveness queue and list only", func() {
+				testManager.AddToProberManager(vm)
+
+				Expect(testManager.readinessQueue.Len()).To(Equal(0))
+				Expect(testManager.livenessQueue.Len()).To(Equal(1))
+				testManager.livenessMutex.Lock()
+				Expect(testManager.vmLivenessProbeList).Should(HaveKey(vm.NamespacedName()))
+				testManager.livenessMutex.Unlock()
+			})
+
+			It("Should remove from the liveness list when removed from the prober manager", func() {
+				var resetVMName string
+				fakeWorker.ResetProbeResultsFn = func(vmName string) {
+					resetVMName = vmName
+				}
+				testManager.livenessWorker = fakeWorker
+
+				testManager.AddToProberManager(vm)
+				testManager.RemoveFromProberManager(vm)
+
+				testManager.livenessMutex.Lock()
+				Expect(testManager.vmLivenessProbeList).ShouldNot(HaveKey(vm.NamespacedName()))
+				testManager.livenessMutex.Unlock()
+				Expect(resetVMName).To(Equal(vm.NamespacedName()))
 			})
 		})
 	})
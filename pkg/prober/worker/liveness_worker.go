@@ -0,0 +1,164 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package worker
+
+import (
+	goctx "context"
+
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/pkg/errors"
+
+	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
+
+	"github.com/vmware-tanzu/vm-operator/pkg/patch"
+	"github.com/vmware-tanzu/vm-operator/pkg/prober/context"
+	"github.com/vmware-tanzu/vm-operator/pkg/prober/probe"
+	vmoprecord "github.com/vmware-tanzu/vm-operator/pkg/record"
+)
+
+const (
+	// livenessProbeFailedReason represents the reason for the event sent when the liveness probe fails.
+	livenessProbeFailedReason string = "LivenessProbeFailed"
+)
+
+// vmRestarter is the provider method used to remediate a VM whose liveness probe failed.
+type vmRestarter interface {
+	RestartVirtualMachine(ctx goctx.Context, vm *vmopv1alpha1.VirtualMachine, powerCycle bool) error
+}
+
+// livenessWorker implements Worker interface.
+type livenessWorker struct {
+	queue     workqueue.DelayingInterface
+	prober    *probe.Prober
+	client    client.Client
+	recorder  vmoprecord.Recorder
+	restarter vmRestarter
+
+	// results is shared by all the goroutines that run this worker.
+	results *resultTracker
+}
+
+// NewLivenessWorker creates a new liveness worker to run liveness probes. The same worker
+// is expected to be used by all the goroutines processing the liveness queue since it tracks
+// the consecutive probe results of each VM.
+func NewLivenessWorker(
+	queue workqueue.DelayingInterface,
+	prober *probe.Prober,
+	client client.Client,
+	recorder vmoprecord.Recorder,
+	restarter vmRestarter,
+) Worker {
+	return &livenessWorker{
+		queue:     queue,
+		prober:    prober,
+		client:    client,
+		recorder:  recorder,
+		restarter: restarter,
+		// A VM is considered alive until its probe has failed FailureThreshold times.
+		results: newResultTracker(probe.Success),
+	}
+}
+
+func (w *livenessWorker) GetQueue() workqueue.DelayingInterface {
+	return w.queue
+}
+
+// ResetProbeResults forgets the tracked liveness probe results of the VM.
+func (w *livenessWorker) ResetProbeResults(vmName string) {
+	w.results.Reset(vmName)
+}
+
+// CreateProbeContext creates a probe context for liveness probe.
+func (w *livenessWorker) CreateProbeContext(vm *vmopv1alpha1.VirtualMachine) (*context.ProbeContext, error) {
+	patchHelper, err := patch.NewHelper(vm, w.client)
+	if err != nil {
+		return nil, err
+	}
+
+	var probeSpec *vmopv1alpha1.Probe
+	if vm.Spec.LivenessProbe != nil {
+		probeSpec = &vm.Spec.LivenessProbe.Probe
+	}
+
+	return &context.ProbeContext{
+		Context:     goctx.Background(),
+		Logger:      ctrl.Log.WithName("liveness-probe").WithValues("vmName", vm.NamespacedName()),
+		PatchHelper: patchHelper,
+		VM:          vm,
+		ProbeSpec:   probeSpec,
+		ProbeType:   "liveness",
+	}, nil
+}
+
+// ProcessProbeResult processes probe results and, once the probe has failed FailureThreshold
+// consecutive times, remediates the VM according to the liveness probe action.
+func (w *livenessWorker) ProcessProbeResult(ctx *context.ProbeContext, res probe.Result, resErr error) error {
+	vm := ctx.VM
+	vmName := vm.NamespacedName()
+
+	// A VM that is not powered on is not expected to be alive, so there is nothing to remediate.
+	if vm.Status.PowerState != vmopv1alpha1.VirtualMachinePoweredOn {
+		w.results.Reset(vmName)
+		return nil
+	}
+
+	if w.results.Update(vmName, ctx.ProbeSpec, res) != probe.Failure {
+		return nil
+	}
+
+	msg := ""
+	if resErr != nil {
+		msg = resErr.Error()
+	}
+
+	action := vm.Spec.LivenessProbe.Action
+	ctx.Logger.Info("VM resource LIVENESS probe failed", "action", action, "message", msg)
+
+	switch action {
+	case vmopv1alpha1.LivenessProbeActionNone:
+		w.results.Reset(vmName)
+		return nil
+	case vmopv1alpha1.LivenessProbeActionEventOnly:
+		w.recorder.Warn(vm, livenessProbeFailedReason, msg)
+		w.results.Reset(vmName)
+		return nil
+	}
+
+	w.recorder.Warn(vm, livenessProbeFailedReason, msg)
+
+	// An empty action is treated as Reset, the API default.
+	powerCycle := action == vmopv1alpha1.LivenessProbeActionPowerCycle
+	err := w.restarter.RestartVirtualMachine(ctx, vm, powerCycle)
+	w.recorder.EmitEvent(vm, "Restart", err, false)
+	if err != nil {
+		// Keep the failed state so the restart is retried on the next probe period.
+		ctx.Logger.Error(err, "failed to restart VM after liveness probe failure", "powerCycle", powerCycle)
+		return nil
+	}
+
+	w.results.Reset(vmName)
+	vm.Status.RestartCount++
+
+	if err := ctx.PatchHelper.Patch(ctx, vm); err != nil {
+		return errors.Wrapf(err, "patched failed")
+	}
+
+	return nil
+}
+
+func (w *livenessWorker) DoProbe(ctx *context.ProbeContext) error {
+	if !w.results.InitialDelayElapsed(ctx.VM, ctx.ProbeSpec) {
+		ctx.Logger.V(4).Info("the initial delay has not elapsed, skip running the probe")
+		return nil
+	}
+
+	res, err := runProbe(w.prober, ctx)
+	if err != nil {
+		ctx.Logger.Error(err, "liveness probe fails", "result", res)
+	}
+	return w.ProcessProbeResult(ctx, res, err)
+}
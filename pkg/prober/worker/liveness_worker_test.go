@@ -0,0 +1,193 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package worker
+
+import (
+	goctx "context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgorecord "k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
+
+	"github.com/vmware-tanzu/vm-operator/pkg/prober/context"
+	fakeprobe "github.com/vmware-tanzu/vm-operator/pkg/prober/fake/probe"
+	"github.com/vmware-tanzu/vm-operator/pkg/prober/probe"
+	"github.com/vmware-tanzu/vm-operator/pkg/record"
+	"github.com/vmware-tanzu/vm-operator/test/builder"
+)
+
+type fakeRestarter struct {
+	restarts   int
+	powerCycle bool
+	err        error
+}
+
+func (r *fakeRestarter) RestartVirtualMachine(_ goctx.Context, _ *vmopv1alpha1.VirtualMachine, powerCycle bool) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.restarts++
+	r.powerCycle = powerCycle
+	return nil
+}
+
+var _ = Describe("VirtualMachine liveness probes", func() {
+	var (
+		testWorker Worker
+
+		vm    *vmopv1alpha1.VirtualMachine
+		vmKey client.ObjectKey
+
+		fakeClient         client.Client
+		fakeEvents         chan string
+		fakeHeartbeatProbe *fakeprobe.FakeProbe
+		restarter          *fakeRestarter
+	)
+
+	BeforeEach(func() {
+		vm = &vmopv1alpha1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "dummy-vm",
+				Namespace: "dummy-ns",
+			},
+			Spec: vmopv1alpha1.VirtualMachineSpec{
+				ClassName: "dummy-vmclass",
+				LivenessProbe: &vmopv1alpha1.LivenessProbe{
+					Probe:  *getVirtualMachineHeartbeatProbe(),
+					Action: vmopv1alpha1.LivenessProbeActionReset,
+				},
+			},
+			Status: vmopv1alpha1.VirtualMachineStatus{
+				PowerState: vmopv1alpha1.VirtualMachinePoweredOn,
+			},
+		}
+		vm.Spec.LivenessProbe.FailureThreshold = 2
+
+		vmKey = client.ObjectKey{Name: vm.Name, Namespace: vm.Namespace}
+
+		fakeClient = builder.NewFakeClient(vm)
+		eventRecorder := clientgorecord.NewFakeRecorder(1024)
+		fakeEvents = eventRecorder.Events
+
+		fakeHeartbeatProbe = fakeprobe.NewFakeProbe().(*fakeprobe.FakeProbe)
+		fakeHeartbeatProbe.ProbeFn = func(ctx *context.ProbeContext) (probe.Result, error) {
+			return probe.Failure, fmt.Errorf("heartbeat error")
+		}
+		prober := &probe.Prober{
+			GuestHeartbeat: fakeHeartbeatProbe,
+		}
+		restarter = &fakeRestarter{}
+		testWorker = NewLivenessWorker(workqueue.NewNamedDelayingQueue("test"), prober, fakeClient, record.New(eventRecorder), restarter)
+	})
+
+	doProbe := func() {
+		Expect(fakeClient.Get(goctx.Background(), vmKey, vm)).To(Succeed())
+		ctx, err := testWorker.CreateProbeContext(vm)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(testWorker.DoProbe(ctx)).To(Succeed())
+		Expect(fakeClient.Get(goctx.Background(), vmKey, vm)).To(Succeed())
+	}
+
+	It("Should restart the VM once the failure threshold is reached", func() {
+		doProbe()
+		Expect(restarter.restarts).To(Equal(0))
+		Expect(vm.Status.RestartCount).To(BeZero())
+
+		doProbe()
+		Expect(restarter.restarts).To(Equal(1))
+		Expect(restarter.powerCycle).To(BeFalse())
+		Expect(vm.Status.RestartCount).To(BeEquivalentTo(1))
+		Expect(fakeEvents).To(Receive(ContainSubstring(livenessProbeFailedReason)))
+		Expect(fakeEvents).To(Receive(ContainSubstring("RestartSuccess")))
+
+		By("Should count failures again after the restart", func() {
+			doProbe()
+			Expect(restarter.restarts).To(Equal(1))
+		})
+	})
+
+	It("Should not restart the VM when a success resets the failures", func() {
+		doProbe()
+		fakeHeartbeatProbe.ProbeFn = func(ctx *context.ProbeContext) (probe.Result, error) {
+			return probe.Success, nil
+		}
+		doProbe()
+		fakeHeartbeatProbe.ProbeFn = func(ctx *context.ProbeContext) (probe.Result, error) {
+			return probe.Failure, nil
+		}
+		doProbe()
+		Expect(restarter.restarts).To(Equal(0))
+	})
+
+	It("Should count unknown results towards the failure threshold", func() {
+		fakeHeartbeatProbe.ProbeFn = func(ctx *context.ProbeContext) (probe.Result, error) {
+			return probe.Unknown, fmt.Errorf("provider error")
+		}
+		doProbe()
+		Expect(restarter.restarts).To(Equal(0))
+		doProbe()
+		Expect(restarter.restarts).To(Equal(1))
+	})
+
+	It("Should power cycle the VM when the action is PowerCycle", func() {
+		vm.Spec.LivenessProbe.Action = vmopv1alpha1.LivenessProbeActionPowerCycle
+		Expect(fakeClient.Update(goctx.Background(), vm)).To(Succeed())
+
+		doProbe()
+		doProbe()
+		Expect(restarter.restarts).To(Equal(1))
+		Expect(restarter.powerCycle).To(BeTrue())
+	})
+
+	It("Should only send an event when the action is EventOnly", func() {
+		vm.Spec.LivenessProbe.Action = vmopv1alpha1.LivenessProbeActionEventOnly
+		Expect(fakeClient.Update(goctx.Background(), vm)).To(Succeed())
+
+		doProbe()
+		doProbe()
+		Expect(restarter.restarts).To(Equal(0))
+		Expect(vm.Status.RestartCount).To(BeZero())
+		Expect(fakeEvents).To(Receive(ContainSubstring(livenessProbeFailedReason)))
+	})
+
+	It("Should do nothing when the action is None", func() {
+		vm.Spec.LivenessProbe.Action = vmopv1alpha1.LivenessProbeActionNone
+		Expect(fakeClient.Update(goctx.Background(), vm)).To(Succeed())
+
+		doProbe()
+		doProbe()
+		Expect(restarter.restarts).To(Equal(0))
+		Expect(fakeEvents).ToNot(Receive())
+	})
+
+	It("Should retry the restart on the next probe when it fails", func() {
+		restarter.err = fmt.Errorf("restart error")
+
+		doProbe()
+		doProbe()
+		Expect(restarter.restarts).To(Equal(0))
+		Expect(vm.Status.RestartCount).To(BeZero())
+
+		restarter.err = nil
+		doProbe()
+		Expect(restarter.restarts).To(Equal(1))
+		Expect(vm.Status.RestartCount).To(BeEquivalentTo(1))
+	})
+
+	It("Should not restart the VM when it is not powered on", func() {
+		vm.Status.PowerState = vmopv1alpha1.VirtualMachinePoweredOff
+		Expect(fakeClient.Status().Update(goctx.Background(), vm)).To(Succeed())
+
+		doProbe()
+		doProbe()
+		Expect(restarter.restarts).To(Equal(0))
+	})
+})
@@ -1,9 +1,11 @@
-// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2020-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package worker
 
 import (
+	"fmt"
+
 	"k8s.io/client-go/util/workqueue"
 
 	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
@@ -18,4 +20,32 @@ type Worker interface {
 	CreateProbeContext(vm *vmopv1alpha1.VirtualMachine) (*context.ProbeContext, error)
 	DoProbe(ctx *context.ProbeContext) error
 	ProcessProbeResult(ctx *context.ProbeContext, res probe.Result, resErr error) error
+	ResetProbeResults(vmName string)
+}
+
+// getProbe returns a specific type of probe method.
+func getProbe(prober *probe.Prober, probeSpec *vmopv1alpha1.Probe) probe.Probe {
+	if probeSpec.TCPSocket != nil {
+		return prober.TCPProbe
+	}
+	if probeSpec.GuestHeartbeat != nil {
+		return prober.GuestHeartbeat
+	}
+	if probeSpec.HTTPGet != nil {
+		return prober.HTTPProbe
+	}
+	if probeSpec.GuestExec != nil {
+		return prober.GuestExec
+	}
+
+	return nil
+}
+
+// runProbe runs a specific type of probe based on the VM probe spec.
+func runProbe(prober *probe.Prober, ctx *context.ProbeContext) (probe.Result, error) {
+	if p := getProbe(prober, ctx.ProbeSpec); p != nil {
+		return p.Probe(ctx)
+	}
+
+	return probe.Unknown, fmt.Errorf("unknown action specified for VM %s %s probe", ctx.VM.NamespacedName(), ctx)
 }
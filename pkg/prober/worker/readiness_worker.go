@@ -1,11 +1,10 @@
-// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2020-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package worker
 
 import (
 	goctx "context"
-	"fmt"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/util/workqueue"
@@ -36,9 +35,14 @@ type readinessWorker struct {
 	prober   *probe.Prober
 	client   client.Client
 	recorder vmoprecord.Recorder
+
+	// results is shared by all the goroutines that run this worker.
+	results *resultTracker
 }
 
-// NewReadinessWorker creates a new readiness worker to run readiness probes.
+// NewReadinessWorker creates a new readiness worker to run readiness probes. The same worker
+// is expected to be used by all the goroutines processing the readiness queue since it tracks
+// the consecutive probe results of each VM.
 func NewReadinessWorker(
 	queue workqueue.DelayingInterface,
 	prober *probe.Prober,
@@ -50,6 +54,8 @@ func NewReadinessWorker(
 		prober:   prober,
 		client:   client,
 		recorder: recorder,
+		// A VM is not ready until its probe has succeeded SuccessThreshold times.
+		results: newResultTracker(probe.Failure),
 	}
 }
 
@@ -57,6 +63,11 @@ func (w *readinessWorker) GetQueue() workqueue.DelayingInterface {
 	return w.queue
 }
 
+// ResetProbeResults forgets the tracked readiness probe results of the VM.
+func (w *readinessWorker) ResetProbeResults(vmName string) {
+	w.results.Reset(vmName)
+}
+
 // CreateProbeContext creates a probe context for readiness probe.
 func (w *readinessWorker) CreateProbeContext(vm *vmopv1alpha1.VirtualMachine) (*context.ProbeContext, error) {
 	patchHelper, err := patch.NewHelper(vm, w.client)
@@ -76,8 +87,18 @@ func (w *readinessWorker) CreateProbeContext(vm *vmopv1alpha1.VirtualMachine) (*
 
 // ProcessProbeResult processes probe results to get ReadyCondition and
 // sets the ReadyCondition in vm status if the new condition status is a transition.
+// The ReadyCondition only changes once the probe's success or failure threshold is reached.
 func (w *readinessWorker) ProcessProbeResult(ctx *context.ProbeContext, res probe.Result, resErr error) error {
 	vm := ctx.VM
+
+	if vm.Status.PowerState != vmopv1alpha1.VirtualMachinePoweredOn {
+		// A VM that is not powered on is immediately not ready. Its thresholds and initial
+		// delay apply again once it is powered back on.
+		w.results.Reset(vm.NamespacedName())
+	} else {
+		res = w.results.Update(vm.NamespacedName(), ctx.ProbeSpec, res)
+	}
+
 	condition := w.getCondition(res, resErr)
 
 	// We only send event when either the condition type is added or its status changes, not
@@ -107,34 +128,18 @@ func (w *readinessWorker) ProcessProbeResult(ctx *context.ProbeContext, res prob
 }
 
 func (w *readinessWorker) DoProbe(ctx *context.ProbeContext) error {
-	res, err := w.runProbe(ctx)
+	if !w.results.InitialDelayElapsed(ctx.VM, ctx.ProbeSpec) {
+		ctx.Logger.V(4).Info("the initial delay has not elapsed, skip running the probe")
+		return nil
+	}
+
+	res, err := runProbe(w.prober, ctx)
 	if err != nil {
 		ctx.Logger.Error(err, "readiness probe fails", "result", res)
 	}
 	return w.ProcessProbeResult(ctx, res, err)
 }
 
-// getProbe returns a specific type of probe method.
-func (w *readinessWorker) getProbe(probeSpec *vmopv1alpha1.Probe) probe.Probe {
-	if probeSpec.TCPSocket != nil {
-		return w.prober.TCPProbe
-	}
-	if probeSpec.GuestHeartbeat != nil {
-		return w.prober.GuestHeartbeat
-	}
-
-	return nil
-}
-
-// runProbe runs a specific type of probe based on the VM probe spec.
-func (w *readinessWorker) runProbe(ctx *context.ProbeContext) (probe.Result, error) {
-	if p := w.getProbe(ctx.ProbeSpec); p != nil {
-		return p.Probe(ctx)
-	}
-
-	return probe.Unknown, fmt.Errorf("unknown action specified for VM %s readiness probe", ctx.VM.NamespacedName())
-}
-
 // getCondition returns condition based on VM probe results.
 func (w *readinessWorker) getCondition(res probe.Result, err error) *vmopv1alpha1.Condition {
 	msg := ""
@@ -1,4 +1,4 @@
-// Copyright (c) 2020 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2020-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package worker
@@ -7,6 +7,7 @@ import (
 	goctx "context"
 	"fmt"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -41,6 +42,8 @@ var _ = Describe("VirtualMachine readiness probes", func() {
 		fakeEvents         chan string
 		fakeTCPProbe       *fakeprobe.FakeProbe
 		fakeHeartbeatProbe *fakeprobe.FakeProbe
+		fakeHTTPProbe      *fakeprobe.FakeProbe
+		fakeGuestExecProbe *fakeprobe.FakeProbe
 	)
 
 	BeforeEach(func() {
@@ -64,9 +67,13 @@ var _ = Describe("VirtualMachine readiness probes", func() {
 		queue := workqueue.NewNamedDelayingQueue("test")
 		fakeTCPProbe = fakeprobe.NewFakeProbe().(*fakeprobe.FakeProbe)
 		fakeHeartbeatProbe = fakeprobe.NewFakeProbe().(*fakeprobe.FakeProbe)
+		fakeHTTPProbe = fakeprobe.NewFakeProbe().(*fakeprobe.FakeProbe)
+		fakeGuestExecProbe = fakeprobe.NewFakeProbe().(*fakeprobe.FakeProbe)
 		prober := &probe.Prober{
 			TCPProbe:       fakeTCPProbe,
 			GuestHeartbeat: fakeHeartbeatProbe,
+			HTTPProbe:      fakeHTTPProbe,
+			GuestExec:      fakeGuestExecProbe,
 		}
 		testWorker = NewReadinessWorker(queue, prober, fakeClient, fakeRecorder)
 	})
@@ -185,6 +192,152 @@ var _ = Describe("VirtualMachine readiness probes", func() {
 			Expect(condition.Message).To(ContainSubstring("heartbeat error"))
 		})
 	})
+
+	Context("HTTP Probe", func() {
+
+		BeforeEach(func() {
+			vm.Spec.ReadinessProbe = getVirtualMachineReadinessHTTPProbe(10001)
+			Expect(fakeClient.Create(goctx.Background(), vm)).Should(Succeed())
+			Expect(fakeClient.Get(goctx.Background(), vmKey, vm)).Should(Succeed())
+			var err error
+			ctx, err = testWorker.CreateProbeContext(vm)
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		// Just need to test for probe selection.
+		It("Should update ReadyCondition when probe fails", func() {
+			fakeHTTPProbe.ProbeFn = func(ctx *context.ProbeContext) (probe.Result, error) {
+				return probe.Failure, fmt.Errorf("http error")
+			}
+
+			Expect(testWorker.DoProbe(ctx)).Should(Succeed())
+			Expect(fakeClient.Get(ctx, vmKey, vm)).Should(Succeed())
+			condition := conditions.Get(vm, vmopv1alpha1.ReadyCondition)
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.Message).To(ContainSubstring("http error"))
+		})
+	})
+
+	Context("Guest exec Probe", func() {
+
+		BeforeEach(func() {
+			vm.Spec.ReadinessProbe = getVirtualMachineReadinessGuestExecProbe()
+			Expect(fakeClient.Create(goctx.Background(), vm)).Should(Succeed())
+			Expect(fakeClient.Get(goctx.Background(), vmKey, vm)).Should(Succeed())
+			var err error
+			ctx, err = testWorker.CreateProbeContext(vm)
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		// Just need to test for probe selection.
+		It("Should update ReadyCondition when probe succeeds", func() {
+			fakeGuestExecProbe.ProbeFn = func(ctx *context.ProbeContext) (probe.Result, error) {
+				return probe.Success, nil
+			}
+
+			Expect(testWorker.DoProbe(ctx)).Should(Succeed())
+			checkReadyCondition(fakeClient, vmKey, corev1.ConditionTrue)
+		})
+	})
+
+	Context("Probe thresholds and initial delay", func() {
+		var probeResult probe.Result
+
+		BeforeEach(func() {
+			vm.Spec.ReadinessProbe = getVirtualMachineReadinessTCPProbe(10001)
+			vm.Spec.ReadinessProbe.SuccessThreshold = 2
+			vm.Spec.ReadinessProbe.FailureThreshold = 2
+			vm.Status.PowerState = vmopv1alpha1.VirtualMachinePoweredOn
+			Expect(fakeClient.Create(goctx.Background(), vm)).Should(Succeed())
+
+			fakeTCPProbe.ProbeFn = func(ctx *context.ProbeContext) (probe.Result, error) {
+				return probeResult, nil
+			}
+		})
+
+		doProbe := func(res probe.Result) {
+			probeResult = res
+			Expect(fakeClient.Get(goctx.Background(), vmKey, vm)).Should(Succeed())
+			var err error
+			ctx, err = testWorker.CreateProbeContext(vm)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(testWorker.DoProbe(ctx)).Should(Succeed())
+		}
+
+		It("Should only update ReadyCondition once the thresholds are reached", func() {
+			doProbe(probe.Success)
+			checkReadyCondition(fakeClient, vmKey, corev1.ConditionFalse)
+
+			doProbe(probe.Success)
+			checkReadyCondition(fakeClient, vmKey, corev1.ConditionTrue)
+
+			doProbe(probe.Failure)
+			checkReadyCondition(fakeClient, vmKey, corev1.ConditionTrue)
+
+			doProbe(probe.Success)
+			doProbe(probe.Failure)
+			checkReadyCondition(fakeClient, vmKey, corev1.ConditionTrue)
+
+			doProbe(probe.Failure)
+			checkReadyCondition(fakeClient, vmKey, corev1.ConditionFalse)
+		})
+
+		It("Should count unknown results towards the failure threshold", func() {
+			doProbe(probe.Success)
+			doProbe(probe.Success)
+			checkReadyCondition(fakeClient, vmKey, corev1.ConditionTrue)
+
+			doProbe(probe.Unknown)
+			checkReadyCondition(fakeClient, vmKey, corev1.ConditionTrue)
+
+			doProbe(probe.Unknown)
+			checkReadyCondition(fakeClient, vmKey, corev1.ConditionFalse)
+		})
+
+		It("Should immediately set ReadyCondition to false when the VM is powered off", func() {
+			doProbe(probe.Success)
+			doProbe(probe.Success)
+			checkReadyCondition(fakeClient, vmKey, corev1.ConditionTrue)
+
+			Expect(fakeClient.Get(goctx.Background(), vmKey, vm)).Should(Succeed())
+			vm.Status.PowerState = vmopv1alpha1.VirtualMachinePoweredOff
+			var err error
+			ctx, err = testWorker.CreateProbeContext(vm)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(testWorker.ProcessProbeResult(ctx, probe.Failure, fmt.Errorf("not powered on"))).Should(Succeed())
+			checkReadyCondition(fakeClient, vmKey, corev1.ConditionFalse)
+		})
+
+		When("the initial delay has not elapsed", func() {
+			BeforeEach(func() {
+				vm.Spec.ReadinessProbe.InitialDelaySeconds = 3600
+				Expect(fakeClient.Update(goctx.Background(), vm)).Should(Succeed())
+			})
+
+			It("Should not run the probe", func() {
+				fakeTCPProbe.ProbeFn = func(ctx *context.ProbeContext) (probe.Result, error) {
+					return probe.Unknown, fmt.Errorf("probe should not run")
+				}
+				doProbe(probe.Success)
+
+				Expect(fakeClient.Get(goctx.Background(), vmKey, vm)).Should(Succeed())
+				Expect(conditions.Get(vm, vmopv1alpha1.ReadyCondition)).To(BeNil())
+			})
+
+			When("the VM was powered on before the initial delay", func() {
+				BeforeEach(func() {
+					vm.Status.BootTime = &metav1.Time{Time: time.Now().Add(-2 * time.Hour)}
+					Expect(fakeClient.Status().Update(goctx.Background(), vm)).Should(Succeed())
+				})
+
+				It("Should run the probe", func() {
+					doProbe(probe.Success)
+					doProbe(probe.Success)
+					checkReadyCondition(fakeClient, vmKey, corev1.ConditionTrue)
+				})
+			})
+		})
+	})
 })
 
 func TestReadinessProbeWorker(t *testing.T) {
@@ -201,6 +354,25 @@ func getVirtualMachineReadinessTCPProbe(port int) *vmopv1alpha1.Probe {
 	}
 }
 
+func getVirtualMachineReadinessHTTPProbe(port int) *vmopv1alpha1.Probe {
+	return &vmopv1alpha1.Probe{
+		HTTPGet: &vmopv1alpha1.HTTPGetAction{
+			Port: intstr.FromInt(port),
+		},
+		PeriodSeconds: 1,
+	}
+}
+
+func getVirtualMachineReadinessGuestExecProbe() *vmopv1alpha1.Probe {
+	return &vmopv1alpha1.Probe{
+		GuestExec: &vmopv1alpha1.GuestExecAction{
+			Command:               []string{"/bin/true"},
+			CredentialsSecretName: "dummy-secret",
+		},
+		PeriodSeconds: 1,
+	}
+}
+
 func getVirtualMachineHeartbeatProbe() *vmopv1alpha1.Probe {
 	return &vmopv1alpha1.Probe{
 		GuestHeartbeat: &vmopv1alpha1.GuestHeartbeatAction{},
@@ -0,0 +1,120 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package worker
+
+import (
+	"sync"
+	"time"
+
+	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
+
+	"github.com/vmware-tanzu/vm-operator/pkg/prober/probe"
+)
+
+const (
+	defaultSuccessThreshold = 1
+	defaultFailureThreshold = 3
+)
+
+// probeState is the state of a VM's probe across probe runs.
+type probeState struct {
+	startTime time.Time
+	result    probe.Result
+	successes int32
+	failures  int32
+}
+
+// resultTracker tracks the consecutive results of the probe of each VM, so a result is only
+// reported once the probe's success or failure threshold has been reached.
+type resultTracker struct {
+	sync.Mutex
+	initialResult probe.Result
+	states        map[string]*probeState
+}
+
+func newResultTracker(initialResult probe.Result) *resultTracker {
+	return &resultTracker{
+		initialResult: initialResult,
+		states:        make(map[string]*probeState),
+	}
+}
+
+// Update records the result of a probe run for the VM, and returns the result to report. The
+// result is the previously reported one until the respective threshold is reached. An Unknown
+// result, such as when the probe could not be run, counts towards the failure threshold, so a
+// probe that cannot be run is eventually reported as failed.
+func (t *resultTracker) Update(vmName string, probeSpec *vmopv1alpha1.Probe, res probe.Result) probe.Result {
+	t.Lock()
+	defer t.Unlock()
+
+	state := t.getState(vmName)
+
+	switch res {
+	case probe.Success:
+		state.successes++
+		state.failures = 0
+		if state.successes >= successThreshold(probeSpec) {
+			state.result = probe.Success
+		}
+	case probe.Failure, probe.Unknown:
+		state.failures++
+		state.successes = 0
+		if state.failures >= failureThreshold(probeSpec) {
+			state.result = probe.Failure
+		}
+	}
+
+	return state.result
+}
+
+// InitialDelayElapsed returns true if the VM's probe initial delay has elapsed. The delay starts
+// when the VM was powered on. When the VM's boot time is not known, the delay starts when the
+// VM's probe is first tracked, that is the first time the VM is probed after being powered on
+// or its state being reset.
+func (t *resultTracker) InitialDelayElapsed(vm *vmopv1alpha1.VirtualMachine, probeSpec *vmopv1alpha1.Probe) bool {
+	t.Lock()
+	defer t.Unlock()
+
+	startTime := t.getState(vm.NamespacedName()).startTime
+	if vm.Status.BootTime != nil {
+		startTime = vm.Status.BootTime.Time
+	}
+
+	delay := time.Duration(probeSpec.InitialDelaySeconds) * time.Second
+	return time.Since(startTime) >= delay
+}
+
+// Reset forgets the state of the VM's probe.
+func (t *resultTracker) Reset(vmName string) {
+	t.Lock()
+	defer t.Unlock()
+
+	delete(t.states, vmName)
+}
+
+func (t *resultTracker) getState(vmName string) *probeState {
+	state, ok := t.states[vmName]
+	if !ok {
+		state = &probeState{
+			startTime: time.Now(),
+			result:    t.initialResult,
+		}
+		t.states[vmName] = state
+	}
+	return state
+}
+
+func successThreshold(probeSpec *vmopv1alpha1.Probe) int32 {
+	if probeSpec.SuccessThreshold > 0 {
+		return probeSpec.SuccessThreshold
+	}
+	return defaultSuccessThreshold
+}
+
+func failureThreshold(probeSpec *vmopv1alpha1.Probe) int32 {
+	if probeSpec.FailureThreshold > 0 {
+		return probeSpec.FailureThreshold
+	}
+	return defaultFailureThreshold
+}
@@ -1,4 +1,4 @@
-// Copyright (c) 2018-2021 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2018-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package pkg
@@ -9,4 +9,9 @@ const (
 
 	// ClusterModuleNameKey is the annotation key for clusterModule group name information at VM operator.
 	ClusterModuleNameKey string = "vsphere-cluster-module-group"
+
+	// DrainingAnnotationKey is the annotation key set on a VirtualMachine that is about to be deleted by
+	// its replica set. The VirtualMachine is removed from the VirtualMachineService endpoints before it is
+	// deleted.
+	DrainingAnnotationKey string = VMOperatorKey + "/draining"
 )
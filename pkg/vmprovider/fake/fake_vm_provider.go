@@ -33,7 +33,15 @@ type funcs struct {
 	PublishVirtualMachineFn        func(ctx context.Context, vm *v1alpha1.VirtualMachine,
 		vmPub *v1alpha1.VirtualMachinePublishRequest, cl *imgregv1a1.ContentLibrary, actID string) (string, error)
 	GetVirtualMachineGuestHeartbeatFn func(ctx context.Context, vm *v1alpha1.VirtualMachine) (v1alpha1.GuestHeartbeatStatus, error)
+	RunVirtualMachineGuestCommandFn   func(ctx context.Context, vm *v1alpha1.VirtualMachine, command []string, credentialsSecretName string) (int32, error)
+	RestartVirtualMachineFn           func(ctx context.Context, vm *v1alpha1.VirtualMachine, powerCycle bool) error
+	RebootGuestVirtualMachineFn       func(ctx context.Context, vm *v1alpha1.VirtualMachine) error
 	GetVirtualMachineWebMKSTicketFn   func(ctx context.Context, vm *v1alpha1.VirtualMachine, pubKey string) (string, error)
+	GetVirtualMachineDirectoryFn      func(ctx context.Context, vm *v1alpha1.VirtualMachine) (string, error)
+
+	CreateSnapshotFn   func(ctx context.Context, vm *v1alpha1.VirtualMachine, vmSnapshot *v1alpha1.VirtualMachineSnapshot) error
+	RevertToSnapshotFn func(ctx context.Context, vm *v1alpha1.VirtualMachine, vmSnapshot *v1alpha1.VirtualMachineSnapshot) error
+	DeleteSnapshotFn   func(ctx context.Context, vm *v1alpha1.VirtualMachine, vmSnapshot *v1alpha1.VirtualMachineSnapshot) error
 
 	ListItemsFromContentLibraryFn              func(ctx context.Context, contentLibrary *v1alpha1.ContentLibraryProvider) ([]string, error)
 	GetVirtualMachineImageFromContentLibraryFn func(ctx context.Context, contentLibrary *v1alpha1.ContentLibraryProvider, itemID string,
@@ -130,6 +138,70 @@ func (s *VMProvider) GetVirtualMachineWebMKSTicket(ctx context.Context, vm *v1al
 	return "", nil
 }
 
+func (s *VMProvider) RunVirtualMachineGuestCommand(ctx context.Context, vm *v1alpha1.VirtualMachine, command []string, credentialsSecretName string) (int32, error) {
+	s.Lock()
+	defer s.Unlock()
+	if s.RunVirtualMachineGuestCommandFn != nil {
+		return s.RunVirtualMachineGuestCommandFn(ctx, vm, command, credentialsSecretName)
+	}
+	return 0, nil
+}
+
+func (s *VMProvider) RestartVirtualMachine(ctx context.Context, vm *v1alpha1.VirtualMachine, powerCycle bool) error {
+	s.Lock()
+	defer s.Unlock()
+	if s.RestartVirtualMachineFn != nil {
+		return s.RestartVirtualMachineFn(ctx, vm, powerCycle)
+	}
+	return nil
+}
+
+func (s *VMProvider) RebootGuestVirtualMachine(ctx context.Context, vm *v1alpha1.VirtualMachine) error {
+	s.Lock()
+	defer s.Unlock()
+	if s.RebootGuestVirtualMachineFn != nil {
+		return s.RebootGuestVirtualMachineFn(ctx, vm)
+	}
+	return nil
+}
+
+func (s *VMProvider) GetVirtualMachineDirectory(ctx context.Context, vm *v1alpha1.VirtualMachine) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+	if s.GetVirtualMachineDirectoryFn != nil {
+		return s.GetVirtualMachineDirectoryFn(ctx, vm)
+	}
+	return "", nil
+}
+
+func (s *VMProvider) CreateSnapshot(ctx context.Context, vm *v1alpha1.VirtualMachine, vmSnapshot *v1alpha1.VirtualMachineSnapshot) error {
+	s.Lock()
+	defer s.Unlock()
+	if s.CreateSnapshotFn != nil {
+		return s.CreateSnapshotFn(ctx, vm, vmSnapshot)
+	}
+	vmSnapshot.Status.SnapshotID = "snapshot-" + vmSnapshot.Name
+	return nil
+}
+
+func (s *VMProvider) RevertToSnapshot(ctx context.Context, vm *v1alpha1.VirtualMachine, vmSnapshot *v1alpha1.VirtualMachineSnapshot) error {
+	s.Lock()
+	defer s.Unlock()
+	if s.RevertToSnapshotFn != nil {
+		return s.RevertToSnapshotFn(ctx, vm, vmSnapshot)
+	}
+	return nil
+}
+
+func (s *VMProvider) DeleteSnapshot(ctx context.Context, vm *v1alpha1.VirtualMachine, vmSnapshot *v1alpha1.VirtualMachineSnapshot) error {
+	s.Lock()
+	defer s.Unlock()
+	if s.DeleteSnapshotFn != nil {
+		return s.DeleteSnapshotFn(ctx, vm, vmSnapshot)
+	}
+	return nil
+}
+
 func (s *VMProvider) CreateOrUpdateVirtualMachineSetResourcePolicy(ctx context.Context, resourcePolicy *v1alpha1.VirtualMachineSetResourcePolicy) error {
 	s.Lock()
 	defer s.Unlock()
@@ -5,6 +5,7 @@ package vmprovider
 
 import (
 	"context"
+	"errors"
 
 	"github.com/vmware/govmomi/vapi/library"
 	vimTypes "github.com/vmware/govmomi/vim25/types"
@@ -16,6 +17,14 @@ import (
 	imgregv1a1 "github.com/vmware-tanzu/vm-operator/external/image-registry/api/v1alpha1"
 )
 
+// ErrGuestShutdownInProgress is returned by DeleteVirtualMachine while the guest OS of the VM is being shut down
+// prior to the VM being deleted.
+var ErrGuestShutdownInProgress = errors.New("waiting for guest OS to shut down prior to delete")
+
+// ErrGuestCommandNotRun is wrapped by the error returned by RunVirtualMachineGuestCommand when the guest could
+// not run the command, such as when the guest rejects the credentials or VMware Tools is not running.
+var ErrGuestCommandNotRun = errors.New("guest could not run the command")
+
 // VirtualMachineProviderInterface is a plugable interface for VM Providers.
 type VirtualMachineProviderInterface interface {
 	CreateOrUpdateVirtualMachine(ctx context.Context, vm *v1alpha1.VirtualMachine) error
@@ -24,6 +33,14 @@ type VirtualMachineProviderInterface interface {
 		vmPub *v1alpha1.VirtualMachinePublishRequest, cl *imgregv1a1.ContentLibrary, actID string) (string, error)
 	GetVirtualMachineGuestHeartbeat(ctx context.Context, vm *v1alpha1.VirtualMachine) (v1alpha1.GuestHeartbeatStatus, error)
 	GetVirtualMachineWebMKSTicket(ctx context.Context, vm *v1alpha1.VirtualMachine, pubKey string) (string, error)
+	RunVirtualMachineGuestCommand(ctx context.Context, vm *v1alpha1.VirtualMachine, command []string, credentialsSecretName string) (int32, error)
+	RestartVirtualMachine(ctx context.Context, vm *v1alpha1.VirtualMachine, powerCycle bool) error
+	RebootGuestVirtualMachine(ctx context.Context, vm *v1alpha1.VirtualMachine) error
+	GetVirtualMachineDirectory(ctx context.Context, vm *v1alpha1.VirtualMachine) (string, error)
+
+	CreateSnapshot(ctx context.Context, vm *v1alpha1.VirtualMachine, vmSnapshot *v1alpha1.VirtualMachineSnapshot) error
+	RevertToSnapshot(ctx context.Context, vm *v1alpha1.VirtualMachine, vmSnapshot *v1alpha1.VirtualMachineSnapshot) error
+	DeleteSnapshot(ctx context.Context, vm *v1alpha1.VirtualMachine, vmSnapshot *v1alpha1.VirtualMachineSnapshot) error
 
 	CreateOrUpdateVirtualMachineSetResourcePolicy(ctx context.Context, resourcePolicy *v1alpha1.VirtualMachineSetResourcePolicy) error
 	IsVirtualMachineSetResourcePolicyReady(ctx context.Context, availabilityZoneName string, resourcePolicy *v1alpha1.VirtualMachineSetResourcePolicy) (bool, error)
@@ -25,6 +25,18 @@ const (
 	VSphereCustomizationBypassKey     = pkg.VMOperatorKey + "/vsphere-customization"
 	VSphereCustomizationBypassDisable = "disable"
 
+	// RerunCustomizationAnnotation requests a fresh guest customization the next time the VM is powered on. Any
+	// pending customization is cleared, the cloud-init instance ID is regenerated so cloud-init runs again, and
+	// the annotation is removed once the customization has been applied. The VAppConfig and Ignition transports
+	// do not customize the guest, so the annotation is left as is for them.
+	RerunCustomizationAnnotation = pkg.VMOperatorKey + "/rerun-customization"
+	// CustomizationPendingSinceAnnotation records when the VM's guest customization became pending, and is used
+	// to detect pending customizations the guest never picked up.
+	CustomizationPendingSinceAnnotation = pkg.VMOperatorKey + "/customization-pending-since"
+	// CloudInitInstanceIDAnnotation is the cloud-init instance ID of a VM whose customization was rerun. The VM's
+	// UID is used as the instance ID when unset.
+	CloudInitInstanceIDAnnotation = pkg.VMOperatorKey + "/cloud-init-instance-id"
+
 	// VMOperatorV1Alpha1ExtraConfigKey Special ExtraConfig key for v1alpha1 images.
 	VMOperatorV1Alpha1ExtraConfigKey = "guestinfo.vmservice.defer-cloud-init"
 	VMOperatorV1Alpha1ConfigReady    = "ready"
@@ -48,7 +60,7 @@ const (
 	// VMImageCLVersionAnnotation VirtualMachineImage annotation to cache the last fetched version.
 	VMImageCLVersionAnnotation = pkg.VMOperatorKey + "/content-library-version"
 	// VMImageCLVersionAnnotationVersion is the version of the VMImageCLVersionAnnotation for the VirtualMachineImage.
-	VMImageCLVersionAnnotationVersion = 1
+	VMImageCLVersionAnnotationVersion = 2
 
 	PCIPassthruMMIOOverrideAnnotation = pkg.VMOperatorKey + "/pci-passthru-64bit-mmio-size"
 	PCIPassthruMMIOExtraConfigKey     = "pciPassthru.use64bitMMIO"    //nolint:gosec
@@ -61,14 +73,62 @@ const (
 	// FirmwareOverrideAnnotation is the annotation key used for firmware override.
 	FirmwareOverrideAnnotation = pkg.VMOperatorKey + "/firmware"
 
+	// StorageRelocateTaskAnnotation is the annotation key with the ID of the task relocating the disks of a VM
+	// to the datastore of its new StorageClass.
+	StorageRelocateTaskAnnotation = pkg.VMOperatorKey + "/storage-relocate-task"
+
+	// MigrateTaskAnnotation is the annotation key with the ID of the task migrating a VM to the zone in its
+	// zone label.
+	MigrateTaskAnnotation = pkg.VMOperatorKey + "/migrate-task"
+
+	// ClassUpdatePowerCycleAnnotation allows a powered on VM to be power cycled to apply an update of its
+	// VirtualMachineClass that cannot be hot-added. Without it, the update is applied the next time the VM is
+	// powered off. A change of the VM's class is always applied right away.
+	ClassUpdatePowerCycleAnnotation = pkg.VMOperatorKey + "/class-update-power-cycle"
+
+	// BootDiskURLPathAnnotation is the annotation key with the URL path of the boot disk deployed from the image
+	// of a VM. The boot disk is detached from the VM and registered with CNS to populate the PVC of its image
+	// volume.
+	BootDiskURLPathAnnotation = pkg.VMOperatorKey + "/boot-disk-url-path"
+
 	CloudInitTypeAnnotation         = pkg.VMOperatorKey + "/cloudinit-type"
 	CloudInitTypeValueCloudInitPrep = "cloudinitprep"
 	CloudInitTypeValueGuestInfo     = "guestinfo"
 
-	CloudInitGuestInfoMetadata         = "guestinfo.metadata"
-	CloudInitGuestInfoMetadataEncoding = "guestinfo.metadata.encoding"
-	CloudInitGuestInfoUserdata         = "guestinfo.userdata"
-	CloudInitGuestInfoUserdataEncoding = "guestinfo.userdata.encoding"
+	CloudInitGuestInfoMetadata           = "guestinfo.metadata"
+	CloudInitGuestInfoMetadataEncoding   = "guestinfo.metadata.encoding"
+	CloudInitGuestInfoUserdata           = "guestinfo.userdata"
+	CloudInitGuestInfoUserdataEncoding   = "guestinfo.userdata.encoding"
+	CloudInitGuestInfoVendordata         = "guestinfo.vendordata"
+	CloudInitGuestInfoVendordataEncoding = "guestinfo.vendordata.encoding"
+
+	// IgnitionConfigKey and ButaneConfigKey are the VM Metadata keys of the Ignition and Butane configs.
+	IgnitionConfigKey = "ignition"
+	ButaneConfigKey   = "butane"
+
+	IgnitionGuestInfoConfigData         = "guestinfo.ignition.config.data"
+	IgnitionGuestInfoConfigDataEncoding = "guestinfo.ignition.config.data.encoding"
+
+	// SysprepUnattendKey is the VM Metadata key of a complete unattend.xml answer file. When set,
+	// the other Sysprep keys are ignored.
+	SysprepUnattendKey            = "unattend"
+	SysprepFullNameKey            = "full-name"
+	SysprepOrgNameKey             = "org-name"
+	SysprepProductIDKey           = "product-id"
+	SysprepAdminPasswordKey       = "admin-password" //nolint:gosec
+	SysprepTimeZoneKey            = "time-zone"
+	SysprepJoinWorkgroupKey       = "join-workgroup"
+	SysprepJoinDomainKey          = "join-domain"
+	SysprepDomainAdminKey         = "domain-admin"
+	SysprepDomainAdminPasswordKey = "domain-admin-password" //nolint:gosec
+
+	SysprepFullNameDefault      = "Administrator"
+	SysprepOrgNameDefault       = "Organization"
+	SysprepJoinWorkgroupDefault = "WORKGROUP"
+	// SysprepTimeZoneDefault is the Windows time zone index of (GMT) Greenwich Mean Time.
+	SysprepTimeZoneDefault = 85
+	// SysprepComputerNameMaxLength is the maximum length of a Windows (NetBIOS) computer name.
+	SysprepComputerNameMaxLength = 15
 
 	// InstanceStoragePVCNamePrefix prefix of auto-generated PVC names.
 	InstanceStoragePVCNamePrefix = "instance-pvc-"
@@ -95,6 +155,11 @@ const (
 	// InstanceStorageVDiskID vDisk ID for instance storage volume.
 	InstanceStorageVDiskID = "cc737f33-2aa3-4594-aa60-df7d6d4cb984"
 
+	// GuestCredentialsUsernameKey is the key of the guest OS username in a guest credentials Secret.
+	GuestCredentialsUsernameKey = "username"
+	// GuestCredentialsPasswordKey is the key of the guest OS password in a guest credentials Secret.
+	GuestCredentialsPasswordKey = "password"
+
 	// XsiNamespace indicates the XML scheme instance namespace.
 	XsiNamespace = "http://www.w3.org/2001/XMLSchema-instance"
 	// ConfigSpecProviderXML indicates XML as the config spec transport type for virtual machine deployment.
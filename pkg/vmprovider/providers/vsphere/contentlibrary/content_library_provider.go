@@ -5,10 +5,13 @@ package contentlibrary
 
 import (
 	"context"
+	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	k8serrors "k8s.io/apimachinery/pkg/util/errors"
@@ -20,6 +23,7 @@ import (
 	"github.com/vmware/govmomi/vapi/library"
 	"github.com/vmware/govmomi/vapi/rest"
 	"github.com/vmware/govmomi/vim25/soap"
+	vimTypes "github.com/vmware/govmomi/vim25/types"
 
 	"github.com/vmware-tanzu/vm-operator/api/v1alpha1"
 
@@ -35,6 +39,7 @@ type Provider interface {
 	UpdateLibraryItem(ctx context.Context, itemID, newName string, newDescription *string) error
 	RetrieveOvfEnvelopeFromLibraryItem(ctx context.Context, item *library.Item) (*ovf.Envelope, error)
 	RetrieveOvfEnvelopeByLibraryItemID(ctx context.Context, itemID string) (*ovf.Envelope, error)
+	GetLibraryItemIsoFile(ctx context.Context, itemID string) (vimTypes.ManagedObjectReference, string, error)
 
 	// TODO: Testing only. Remove these from this file.
 	CreateLibraryItem(ctx context.Context, libraryItem library.Item, path string) error
@@ -53,6 +58,12 @@ type provider struct {
 const (
 	EnvContentLibAPIWaitSecs     = "CONTENT_API_WAIT_SECS" // BMV: Investigate if setting this to 1 actually reduces the integration test time.
 	DefaultContentLibAPIWaitSecs = 5
+
+	// LibraryDirPrefix is the prefix of the datastore directory that the files of a library's items are stored in.
+	LibraryDirPrefix = "contentlib-"
+
+	libraryItemStoragePath         = "/com/vmware/content/library/item/storage"
+	libraryStorageBackingDatastore = "DATASTORE"
 )
 
 func IsSupportedDeployType(t string) bool {
@@ -162,6 +173,50 @@ func (cs *provider) RetrieveOvfEnvelopeByLibraryItemID(ctx context.Context, item
 	return cs.RetrieveOvfEnvelopeFromLibraryItem(ctx, libItem)
 }
 
+// libraryItemStorage is the storage of a library item file returned by the library item storage API, which
+// govmomi does not have bindings for yet.
+type libraryItemStorage struct {
+	Name           string                  `json:"name"`
+	StorageBacking library.StorageBackings `json:"storage_backing"`
+	StorageURIs    []string                `json:"storage_uris"`
+}
+
+// GetLibraryItemIsoFile returns the datastore, and the URI within the datastore, of the ISO file of the ISO library
+// item with the given ID. The URI is resolved with the library item storage API since the name and location of the
+// file on the datastore are not otherwise exposed.
+func (cs *provider) GetLibraryItemIsoFile(
+	ctx context.Context,
+	itemID string) (vimTypes.ManagedObjectReference, string, error) {
+
+	var dsRef vimTypes.ManagedObjectReference
+
+	var storage []libraryItemStorage
+	resource := cs.libMgr.Resource(libraryItemStoragePath).WithParam("library_item_id", itemID)
+	if err := cs.libMgr.Do(ctx, resource.Request(http.MethodGet), &storage); err != nil {
+		return dsRef, "", errors.Wrapf(err, "failed to get the storage of library item %s", itemID)
+	}
+
+	var isoStorage *libraryItemStorage
+	for i := range storage {
+		if strings.EqualFold(path.Ext(storage[i].Name), "."+library.ItemTypeISO) || len(storage) == 1 {
+			isoStorage = &storage[i]
+			break
+		}
+	}
+
+	if isoStorage == nil {
+		return dsRef, "", errors.Errorf("library item %s does not have an ISO file", itemID)
+	}
+
+	if isoStorage.StorageBacking.Type != libraryStorageBackingDatastore || isoStorage.StorageBacking.DatastoreID == "" ||
+		len(isoStorage.StorageURIs) == 0 {
+		return dsRef, "", errors.Errorf("ISO file %s of library item %s is not stored on a datastore", isoStorage.Name, itemID)
+	}
+
+	dsRef = vimTypes.ManagedObjectReference{Type: "Datastore", Value: isoStorage.StorageBacking.DatastoreID}
+	return dsRef, isoStorage.StorageURIs[0], nil
+}
+
 // RetrieveOvfEnvelopeFromLibraryItem downloads the supported file from content library.
 // parses the downloaded ovf and returns the OVF Envelope descriptor for consumption.
 func (cs *provider) RetrieveOvfEnvelopeFromLibraryItem(ctx context.Context, item *library.Item) (*ovf.Envelope, error) {
@@ -327,6 +382,8 @@ func (cs *provider) VirtualMachineImageResourceForLibrary(ctx context.Context,
 	case library.ItemTypeVMTX:
 		// Do not try to populate VMTX types, but resVm.GetOvfProperties() should return an
 		// OvfEnvelope.
+	case library.ItemTypeISO:
+		// An ISO does not have an OVF envelope. It is not deployed but backs the CD-ROMs of VMs.
 	default:
 		// Not a supported type. Keep this in sync with cloneVMFromContentLibrary().
 		return nil, nil
@@ -5,6 +5,7 @@ package contentlibrary_test
 
 import (
 	"os"
+	"path/filepath"
 	"strings"
 
 	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
@@ -173,5 +174,49 @@ func clTests() {
 				Expect(ovfEnvelope).To(BeNil())
 			})
 		})
+
+		Context("when an ISO item is present in library", func() {
+			var (
+				isoPath string
+				itemID  string
+			)
+
+			JustBeforeEach(func() {
+				iso, err := os.CreateTemp("", "fake-*.iso")
+				Expect(err).NotTo(HaveOccurred())
+				isoPath = iso.Name()
+				Expect(iso.Close()).To(Succeed())
+
+				libItem := library.Item{
+					Name:      strings.TrimSuffix(filepath.Base(isoPath), ".iso"),
+					Type:      library.ItemTypeISO,
+					LibraryID: ctx.ContentLibraryID,
+				}
+				Expect(clProvider.CreateLibraryItem(ctx, libItem, isoPath)).To(Succeed())
+
+				item, err := clProvider.GetLibraryItem(ctx, ctx.ContentLibraryID, libItem.Name, true)
+				Expect(err).ToNot(HaveOccurred())
+				itemID = item.ID
+			})
+
+			AfterEach(func() {
+				Expect(os.Remove(isoPath)).To(Succeed())
+			})
+
+			It("Get VMImage Resource of type ISO from library", func() {
+				image, err := clProvider.VirtualMachineImageResourceForLibrary(ctx, itemID, ctx.ContentLibraryID, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(image.Spec.Type).To(Equal(library.ItemTypeISO))
+				Expect(image.Spec.ImageID).To(Equal(itemID))
+			})
+
+			It("Gets the ISO file of the item", func() {
+				dsRef, isoFile, err := clProvider.GetLibraryItemIsoFile(ctx, itemID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dsRef.Type).To(Equal("Datastore"))
+				Expect(isoFile).To(ContainSubstring("/" + contentlibrary.LibraryDirPrefix + ctx.ContentLibraryID + "/" + itemID + "/"))
+				Expect(isoFile).To(HaveSuffix(".iso"))
+			})
+		})
 	})
 }
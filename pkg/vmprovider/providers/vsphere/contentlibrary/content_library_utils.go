@@ -7,6 +7,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"math"
 	"net/url"
 	"regexp"
 	"strconv"
@@ -19,6 +20,7 @@ import (
 
 	"github.com/vmware-tanzu/vm-operator/api/v1alpha1"
 
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -28,7 +30,11 @@ import (
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/constants"
 )
 
-var vmxRe = regexp.MustCompile(`vmx-(\d+)`)
+var (
+	vmxRe = regexp.MustCompile(`vmx-(\d+)`)
+	// capacityAllocationUnitsRe matches the OVF capacity allocation units of a disk, for eg. "byte * 2^30".
+	capacityAllocationUnitsRe = regexp.MustCompile(`^byte(?:\s*\*\s*2\^(\d+))?$`)
+)
 
 // ParseVirtualHardwareVersion parses the virtual hardware version
 // For eg. "vmx-15" returns 15.
@@ -98,6 +104,8 @@ func LibItemToVirtualMachineImage(
 		if virtualHwSection := ovfEnvelope.VirtualSystem.VirtualHardware; len(virtualHwSection) > 0 {
 			image.Status.Firmware = getFirmwareType(virtualHwSection[0])
 		}
+
+		image.Status.BootDiskCapacity = getBootDiskCapacity(ovfEnvelope.Disk)
 	}
 
 	return image
@@ -135,6 +143,8 @@ func UpdateVmiWithOvfEnvelope(vmi client.Object, ovfEnvelope ovf.Envelope) {
 		if virtualHwSection := ovfEnvelope.VirtualSystem.VirtualHardware; len(virtualHwSection) > 0 {
 			status.Firmware = getFirmwareType(virtualHwSection[0])
 		}
+
+		status.BootDiskCapacity = getBootDiskCapacity(ovfEnvelope.Disk)
 	}
 }
 
@@ -250,6 +260,36 @@ func getFirmwareType(hardware ovf.VirtualHardwareSection) string {
 	return ""
 }
 
+// getBootDiskCapacity returns the capacity of the first disk in the disk section of the OVF, or nil when the OVF
+// has no disk or the capacity cannot be parsed.
+func getBootDiskCapacity(diskSection *ovf.DiskSection) *resource.Quantity {
+	if diskSection == nil || len(diskSection.Disks) == 0 {
+		return nil
+	}
+	disk := diskSection.Disks[0]
+
+	capacity, err := strconv.ParseInt(disk.Capacity, 10, 64)
+	if err != nil || capacity < 0 {
+		return nil
+	}
+
+	if units := disk.CapacityAllocationUnits; units != nil && *units != "" {
+		obj := capacityAllocationUnitsRe.FindStringSubmatch(strings.TrimSpace(*units))
+		if len(obj) != 2 {
+			return nil
+		}
+		if obj[1] != "" {
+			shift, err := strconv.Atoi(obj[1])
+			if err != nil || shift > 62 || capacity > math.MaxInt64>>shift {
+				return nil
+			}
+			capacity <<= shift
+		}
+	}
+
+	return resource.NewQuantity(capacity, resource.BinarySI)
+}
+
 type ImageConditionWrapper interface {
 	conditions.Setter
 	conditions.Getter
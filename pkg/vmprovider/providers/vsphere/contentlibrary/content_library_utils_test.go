@@ -182,6 +182,51 @@ var _ = Describe("LibItemToVirtualMachineImage", func() {
 				Expect(image.Spec.OVFEnv[userConfigurableKey].Label).Should(Equal("label"))
 			})
 		})
+
+		When("There is no disk", func() {
+			It("should not return the boot disk capacity", func() {
+				Expect(image.Status.BootDiskCapacity).To(BeNil())
+			})
+		})
+
+		When("There are disks", func() {
+			BeforeEach(func() {
+				ovfEnvelope.Disk = &ovf.DiskSection{
+					Disks: []ovf.VirtualDiskDesc{
+						{
+							DiskID:                  "vmdisk1",
+							Capacity:                "10",
+							CapacityAllocationUnits: pointer.String("byte * 2^30"),
+						},
+						{
+							DiskID:   "vmdisk2",
+							Capacity: "1073741824",
+						},
+					},
+				}
+			})
+			It("should return the capacity of the first disk as the boot disk capacity", func() {
+				Expect(image.Status.BootDiskCapacity).ToNot(BeNil())
+				Expect(image.Status.BootDiskCapacity.Value()).To(BeEquivalentTo(10 * 1024 * 1024 * 1024))
+			})
+		})
+
+		When("The capacity of the first disk cannot be parsed", func() {
+			BeforeEach(func() {
+				ovfEnvelope.Disk = &ovf.DiskSection{
+					Disks: []ovf.VirtualDiskDesc{
+						{
+							DiskID:                  "vmdisk1",
+							Capacity:                "${disk.size}",
+							CapacityAllocationUnits: pointer.String("byte * 2^30"),
+						},
+					},
+				}
+			})
+			It("should not return the boot disk capacity", func() {
+				Expect(image.Status.BootDiskCapacity).To(BeNil())
+			})
+		})
 	})
 
 	Context("LibItemToVirtualMachineImage, ImageCompatibility and SupportedGuestOS", func() {
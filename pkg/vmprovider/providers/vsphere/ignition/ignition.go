@@ -0,0 +1,329 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package ignition prepares the Ignition config of CoreOS and Flatcar guests.
+package ignition
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/network"
+)
+
+const (
+	// networkdUnitPrefix is the prefix of the systemd-networkd units generated for the VM's
+	// network interfaces. systemd-networkd applies the first matching unit in lexical order, so
+	// the prefix sorts them after the usual units of the Ignition config, which take precedence.
+	networkdUnitPrefix = "90-vmoperator-"
+	networkdUnitDir    = "/etc/systemd/network/"
+
+	// fileMode is the mode of the generated networkd units, 0644.
+	fileMode = 420
+)
+
+// butaneIgnitionVersions maps the supported Butane variants and versions to the version of the
+// Ignition spec they are transpiled to.
+var butaneIgnitionVersions = map[string]map[string]string{
+	"flatcar": {
+		"1.0.0": "3.3.0",
+	},
+	"fcos": {
+		"1.0.0": "3.0.0",
+		"1.1.0": "3.1.0",
+		"1.2.0": "3.2.0",
+		"1.3.0": "3.2.0",
+		"1.4.0": "3.3.0",
+	},
+}
+
+// TranspileButane transpiles a Butane config to an Ignition config.
+//
+// Only the Butane sugar that does not require access to local files is supported: the
+// snake_case fields are converted to the camelCase fields of Ignition, the inline contents
+// of a resource are converted to a data URL source, and the variant and version are
+// converted to the Ignition spec version. The variant specific sugar, such as boot_device,
+// and local file references are rejected.
+func TranspileButane(butane []byte) ([]byte, error) {
+	config := map[string]interface{}{}
+	if err := yaml.Unmarshal(butane, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse Butane config: %v", err)
+	}
+
+	variant, _ := config["variant"].(string)
+	version, _ := config["version"].(string)
+	ignitionVersion, ok := butaneIgnitionVersions[variant][version]
+	if !ok {
+		return nil, fmt.Errorf("unsupported Butane variant %q and version %q", variant, version)
+	}
+	delete(config, "variant")
+	delete(config, "version")
+
+	for _, key := range []string{"boot_device", "grub"} {
+		if _, ok := config[key]; ok {
+			return nil, fmt.Errorf("unsupported Butane field %q", key)
+		}
+	}
+
+	ignition, _ := config["ignition"].(map[string]interface{})
+	if ignition == nil {
+		ignition = map[string]interface{}{}
+		config["ignition"] = ignition
+	}
+	ignition["version"] = ignitionVersion
+
+	ignitionConfig, err := transpileNode(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(ignitionConfig)
+}
+
+// transpileNode walks the config and returns it with the fields converted to camelCase and
+// the inline contents of the resources converted to a data URL source.
+func transpileNode(node interface{}) (interface{}, error) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if _, ok := n["local"]; ok {
+			return nil, fmt.Errorf("unsupported Butane local file reference %v", n["local"])
+		}
+		if _, ok := n["trees"]; ok {
+			return nil, fmt.Errorf("unsupported Butane field %q", "trees")
+		}
+
+		m := make(map[string]interface{}, len(n))
+		for k, v := range n {
+			if inline, ok := v.(string); ok && k == "inline" {
+				m["source"] = dataURL(inline)
+				continue
+			}
+			v, err := transpileNode(v)
+			if err != nil {
+				return nil, err
+			}
+			m[camelCase(k)] = v
+		}
+		return m, nil
+	case []interface{}:
+		l := make([]interface{}, 0, len(n))
+		for _, v := range n {
+			v, err := transpileNode(v)
+			if err != nil {
+				return nil, err
+			}
+			l = append(l, v)
+		}
+		return l, nil
+	}
+	return node, nil
+}
+
+// camelCase converts a snake_case Butane field, like ssh_authorized_keys, to the camelCase
+// Ignition field, like sshAuthorizedKeys.
+func camelCase(field string) string {
+	parts := strings.Split(field, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// MergeNetworkConfig adds a systemd-networkd unit for each of the netplan's ethernets and bonds
+// to the Ignition config, so static IPs are configured without cloud-init. The ethernets without
+// a MAC address cannot be matched and are left to the guest's defaults. Units with the same
+// name in the Ignition config take precedence.
+func MergeNetworkConfig(ignitionConfig []byte, netplan network.Netplan) ([]byte, error) {
+	config := map[string]interface{}{}
+	if err := json.Unmarshal(ignitionConfig, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse Ignition config: %v", err)
+	}
+
+	units := networkdUnits(netplan)
+	if len(units) == 0 {
+		return ignitionConfig, nil
+	}
+
+	names := make([]string, 0, len(units))
+	for name := range units {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ignition, _ := config["ignition"].(map[string]interface{})
+	version, _ := ignition["version"].(string)
+
+	if strings.HasPrefix(version, "2.") {
+		// The Ignition v2 spec has a dedicated section for the networkd units.
+		networkd := getOrCreateMap(config, "networkd")
+		existing := existingNames(networkd["units"], "name")
+		for _, name := range names {
+			if existing[name] {
+				continue
+			}
+			networkd["units"] = appendTo(networkd["units"], map[string]interface{}{
+				"name":     name,
+				"contents": units[name],
+			})
+		}
+	} else {
+		storage := getOrCreateMap(config, "storage")
+		existing := existingNames(storage["files"], "path")
+		for _, name := range names {
+			path := networkdUnitDir + name
+			if existing[path] {
+				continue
+			}
+			storage["files"] = appendTo(storage["files"], map[string]interface{}{
+				"path":      path,
+				"mode":      fileMode,
+				"overwrite": true,
+				"contents": map[string]interface{}{
+					"source": dataURL(units[name]),
+				},
+			})
+		}
+	}
+
+	return json.Marshal(config)
+}
+
+// networkdUnits returns the systemd-networkd units of the netplan's ethernets and bonds, by unit
+// name. A bond is a netdev unit and a network unit with the IP configuration of the bond, and its
+// members are only enslaved to it.
+func networkdUnits(netplan network.Netplan) map[string]string {
+	units := map[string]string{}
+
+	memberOf := map[string]string{}
+	for name, bond := range netplan.Bonds {
+		for _, member := range bond.Interfaces {
+			memberOf[member] = name
+		}
+	}
+
+	for name, ethernet := range netplan.Ethernets {
+		if ethernet.Match.MacAddress == "" {
+			continue
+		}
+
+		var sb strings.Builder
+		sb.WriteString("[Match]\n")
+		sb.WriteString("MACAddress=" + ethernet.Match.MacAddress + "\n")
+		if bondName, ok := memberOf[name]; ok {
+			sb.WriteString("\n[Network]\n")
+			sb.WriteString("Bond=" + bondName + "\n")
+			if ethernet.MTU != 0 {
+				sb.WriteString(fmt.Sprintf("\n[Link]\nMTUBytes=%d\n", ethernet.MTU))
+			}
+		} else {
+			writeNetwork(&sb, ethernet)
+		}
+
+		units[networkdUnitPrefix+name+".network"] = sb.String()
+	}
+
+	for name, bond := range netplan.Bonds {
+		var netdev strings.Builder
+		netdev.WriteString("[NetDev]\n")
+		netdev.WriteString("Name=" + name + "\n")
+		netdev.WriteString("Kind=bond\n")
+		if bond.MTU != 0 {
+			netdev.WriteString(fmt.Sprintf("MTUBytes=%d\n", bond.MTU))
+		}
+		if bond.Parameters.Mode != "" {
+			netdev.WriteString("\n[Bond]\n")
+			netdev.WriteString("Mode=" + bond.Parameters.Mode + "\n")
+		}
+		units[networkdUnitPrefix+name+".netdev"] = netdev.String()
+
+		var sb strings.Builder
+		sb.WriteString("[Match]\n")
+		sb.WriteString("Name=" + name + "\n")
+		writeNetwork(&sb, network.NetplanEthernet{
+			Dhcp4:       bond.Dhcp4,
+			Addresses:   bond.Addresses,
+			Gateway4:    bond.Gateway4,
+			Gateway6:    bond.Gateway6,
+			Nameservers: bond.Nameservers,
+			Routes:      bond.Routes,
+		})
+		units[networkdUnitPrefix+name+".network"] = sb.String()
+	}
+
+	return units
+}
+
+// writeNetwork writes the IP configuration of the ethernet as the sections following the
+// Match section of a networkd network unit.
+func writeNetwork(sb *strings.Builder, ethernet network.NetplanEthernet) {
+	sb.WriteString("\n[Network]\n")
+	if ethernet.Dhcp4 {
+		sb.WriteString("DHCP=ipv4\n")
+	}
+	for _, address := range ethernet.Addresses {
+		sb.WriteString("Address=" + address + "\n")
+	}
+	if ethernet.Gateway4 != "" {
+		sb.WriteString("Gateway=" + ethernet.Gateway4 + "\n")
+	}
+	if ethernet.Gateway6 != "" {
+		sb.WriteString("Gateway=" + ethernet.Gateway6 + "\n")
+	}
+	for _, dns := range ethernet.Nameservers.Addresses {
+		sb.WriteString("DNS=" + dns + "\n")
+	}
+	if len(ethernet.Nameservers.Search) > 0 {
+		sb.WriteString("Domains=" + strings.Join(ethernet.Nameservers.Search, " ") + "\n")
+	}
+	if ethernet.MTU != 0 {
+		sb.WriteString(fmt.Sprintf("\n[Link]\nMTUBytes=%d\n", ethernet.MTU))
+	}
+	for _, route := range ethernet.Routes {
+		sb.WriteString("\n[Route]\n")
+		if route.To != "default" {
+			sb.WriteString("Destination=" + route.To + "\n")
+		}
+		sb.WriteString("Gateway=" + route.Via + "\n")
+		if route.Metric != nil {
+			sb.WriteString(fmt.Sprintf("Metric=%d\n", *route.Metric))
+		}
+	}
+}
+
+func dataURL(data string) string {
+	return "data:;base64," + base64.StdEncoding.EncodeToString([]byte(data))
+}
+
+func getOrCreateMap(parent map[string]interface{}, key string) map[string]interface{} {
+	m, ok := parent[key].(map[string]interface{})
+	if !ok {
+		m = map[string]interface{}{}
+		parent[key] = m
+	}
+	return m
+}
+
+func appendTo(list interface{}, item interface{}) []interface{} {
+	l, _ := list.([]interface{})
+	return append(l, item)
+}
+
+func existingNames(list interface{}, key string) map[string]bool {
+	names := map[string]bool{}
+	l, _ := list.([]interface{})
+	for _, item := range l {
+		if m, ok := item.(map[string]interface{}); ok {
+			if name, ok := m[key].(string); ok {
+				names[name] = true
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,306 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package ignition_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/ignition"
+	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/network"
+)
+
+func decodeDataURL(source string) string {
+	ExpectWithOffset(1, source).To(HavePrefix("data:;base64,"))
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(source, "data:;base64,"))
+	ExpectWithOffset(1, err).ToNot(HaveOccurred())
+	return string(data)
+}
+
+var _ = Describe("TranspileButane", func() {
+	var (
+		butane string
+		config map[string]interface{}
+		err    error
+	)
+
+	JustBeforeEach(func() {
+		var data []byte
+		config = nil
+		data, err = ignition.TranspileButane([]byte(butane))
+		if err == nil {
+			Expect(json.Unmarshal(data, &config)).To(Succeed())
+		}
+	})
+
+	Context("Flatcar config with inline file contents", func() {
+		BeforeEach(func() {
+			butane = `
+variant: flatcar
+version: 1.0.0
+passwd:
+  users:
+  - name: core
+    ssh_authorized_keys:
+    - ssh-rsa AAAA
+storage:
+  files:
+  - path: /etc/hostname
+    mode: 420
+    contents:
+      inline: my-host
+systemd:
+  units:
+  - name: hello.service
+    enabled: true
+    contents: |
+      [Service]
+      ExecStart=/usr/bin/echo hello
+`
+		})
+
+		It("returns the Ignition config", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config).ToNot(HaveKey("variant"))
+			Expect(config).ToNot(HaveKey("version"))
+			Expect(config["ignition"]).To(HaveKeyWithValue("version", "3.3.0"))
+
+			users := config["passwd"].(map[string]interface{})["users"].([]interface{})
+			Expect(users).To(HaveLen(1))
+			Expect(users[0]).ToNot(HaveKey("ssh_authorized_keys"))
+			Expect(users[0]).To(HaveKeyWithValue("sshAuthorizedKeys", ConsistOf("ssh-rsa AAAA")))
+
+			files := config["storage"].(map[string]interface{})["files"].([]interface{})
+			Expect(files).To(HaveLen(1))
+			contents := files[0].(map[string]interface{})["contents"].(map[string]interface{})
+			Expect(contents).ToNot(HaveKey("inline"))
+			Expect(decodeDataURL(contents["source"].(string))).To(Equal("my-host"))
+
+			units := config["systemd"].(map[string]interface{})["units"].([]interface{})
+			Expect(units[0]).To(HaveKeyWithValue("name", "hello.service"))
+		})
+	})
+
+	Context("Unsupported variant", func() {
+		BeforeEach(func() {
+			butane = "variant: rhcos\nversion: 0.1.0\n"
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unsupported Butane variant"))
+		})
+	})
+
+	Context("Local file reference", func() {
+		BeforeEach(func() {
+			butane = `
+variant: fcos
+version: 1.4.0
+storage:
+  files:
+  - path: /etc/motd
+    contents:
+      local: motd
+`
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("local file reference"))
+		})
+	})
+
+	Context("Invalid YAML", func() {
+		BeforeEach(func() {
+			butane = "variant: [flatcar"
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
+
+var _ = Describe("MergeNetworkConfig", func() {
+	var (
+		ignitionConfig string
+		netplan        network.Netplan
+		config         map[string]interface{}
+		err            error
+	)
+
+	BeforeEach(func() {
+		ignitionConfig = `{"ignition":{"version":"3.3.0"}}`
+		netplan = network.Netplan{
+			Version: 2,
+			Ethernets: map[string]network.NetplanEthernet{
+				"eth0": {
+					Match:     network.NetplanEthernetMatch{MacAddress: "00:50:56:aa:bb:cc"},
+					SetName:   "eth0",
+					Addresses: []string{"192.168.1.10/24"},
+					Gateway4:  "192.168.1.1",
+					Nameservers: network.NetplanEthernetNameserver{
+						Addresses: []string{"8.8.8.8"},
+						Search:    []string{"example.com"},
+					},
+				},
+				"eth1": {
+					Match:   network.NetplanEthernetMatch{MacAddress: "00:50:56:aa:bb:dd"},
+					SetName: "eth1",
+					Dhcp4:   true,
+				},
+				"eth2": {
+					SetName: "eth2",
+					Dhcp4:   true,
+				},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		var data []byte
+		config = nil
+		data, err = ignition.MergeNetworkConfig([]byte(ignitionConfig), netplan)
+		if err == nil {
+			Expect(json.Unmarshal(data, &config)).To(Succeed())
+		}
+	})
+
+	It("adds a networkd unit file for each ethernet with a MAC address", func() {
+		Expect(err).ToNot(HaveOccurred())
+
+		files := config["storage"].(map[string]interface{})["files"].([]interface{})
+		Expect(files).To(HaveLen(2))
+
+		eth0 := files[0].(map[string]interface{})
+		Expect(eth0).To(HaveKeyWithValue("path", "/etc/systemd/network/90-vmoperator-eth0.network"))
+		Expect(eth0).To(HaveKeyWithValue("overwrite", true))
+		Expect(decodeDataURL(eth0["contents"].(map[string]interface{})["source"].(string))).To(Equal(
+			"[Match]\nMACAddress=00:50:56:aa:bb:cc\n\n[Network]\nAddress=192.168.1.10/24\nGateway=192.168.1.1\nDNS=8.8.8.8\nDomains=example.com\n"))
+
+		eth1 := files[1].(map[string]interface{})
+		Expect(eth1).To(HaveKeyWithValue("path", "/etc/systemd/network/90-vmoperator-eth1.network"))
+		Expect(decodeDataURL(eth1["contents"].(map[string]interface{})["source"].(string))).To(Equal(
+			"[Match]\nMACAddress=00:50:56:aa:bb:dd\n\n[Network]\nDHCP=ipv4\n"))
+	})
+
+	Context("Ethernet with IPv6, MTU and routes", func() {
+		BeforeEach(func() {
+			metric := int32(50)
+			netplan.Ethernets = map[string]network.NetplanEthernet{
+				"eth0": {
+					Match:     network.NetplanEthernetMatch{MacAddress: "00:50:56:aa:bb:cc"},
+					Addresses: []string{"2001:db8::10/64"},
+					Gateway6:  "2001:db8::1",
+					MTU:       9000,
+					Routes: []network.NetplanRoute{
+						{To: "default", Via: "2001:db8::2"},
+						{To: "10.0.0.0/8", Via: "192.168.1.254", Metric: &metric},
+					},
+				},
+			}
+		})
+
+		It("adds the IPv6 gateway, MTU and routes to the networkd unit", func() {
+			Expect(err).ToNot(HaveOccurred())
+			files := config["storage"].(map[string]interface{})["files"].([]interface{})
+			Expect(files).To(HaveLen(1))
+			Expect(decodeDataURL(files[0].(map[string]interface{})["contents"].(map[string]interface{})["source"].(string))).To(Equal(
+				"[Match]\nMACAddress=00:50:56:aa:bb:cc\n\n[Network]\nAddress=2001:db8::10/64\nGateway=2001:db8::1\n" +
+					"\n[Link]\nMTUBytes=9000\n" +
+					"\n[Route]\nGateway=2001:db8::2\n" +
+					"\n[Route]\nDestination=10.0.0.0/8\nGateway=192.168.1.254\nMetric=50\n"))
+		})
+	})
+
+	Context("Bond", func() {
+		BeforeEach(func() {
+			netplan.Ethernets = map[string]network.NetplanEthernet{
+				"eth0": {
+					Match:   network.NetplanEthernetMatch{MacAddress: "00:50:56:aa:bb:cc"},
+					SetName: "eth0",
+					MTU:     9000,
+				},
+				"eth1": {
+					Match:   network.NetplanEthernetMatch{MacAddress: "00:50:56:aa:bb:dd"},
+					SetName: "eth1",
+					MTU:     9000,
+				},
+			}
+			netplan.Bonds = map[string]network.NetplanBond{
+				"bond0": {
+					Interfaces: []string{"eth0", "eth1"},
+					Parameters: network.NetplanBondParameters{Mode: "active-backup"},
+					Addresses:  []string{"192.168.1.10/24"},
+					Gateway4:   "192.168.1.1",
+					MTU:        9000,
+				},
+			}
+		})
+
+		It("adds the netdev and network units of the bond and enslaves its members", func() {
+			Expect(err).ToNot(HaveOccurred())
+
+			files := config["storage"].(map[string]interface{})["files"].([]interface{})
+			Expect(files).To(HaveLen(4))
+			contents := map[string]string{}
+			for _, f := range files {
+				file := f.(map[string]interface{})
+				contents[file["path"].(string)] = decodeDataURL(file["contents"].(map[string]interface{})["source"].(string))
+			}
+
+			Expect(contents).To(HaveKeyWithValue("/etc/systemd/network/90-vmoperator-bond0.netdev",
+				"[NetDev]\nName=bond0\nKind=bond\nMTUBytes=9000\n\n[Bond]\nMode=active-backup\n"))
+			Expect(contents).To(HaveKeyWithValue("/etc/systemd/network/90-vmoperator-bond0.network",
+				"[Match]\nName=bond0\n\n[Network]\nAddress=192.168.1.10/24\nGateway=192.168.1.1\n"))
+			Expect(contents).To(HaveKeyWithValue("/etc/systemd/network/90-vmoperator-eth0.network",
+				"[Match]\nMACAddress=00:50:56:aa:bb:cc\n\n[Network]\nBond=bond0\n\n[Link]\nMTUBytes=9000\n"))
+			Expect(contents).To(HaveKeyWithValue("/etc/systemd/network/90-vmoperator-eth1.network",
+				"[Match]\nMACAddress=00:50:56:aa:bb:dd\n\n[Network]\nBond=bond0\n\n[Link]\nMTUBytes=9000\n"))
+		})
+	})
+
+	Context("Ignition config with a unit file of the same path", func() {
+		BeforeEach(func() {
+			ignitionConfig = `{"ignition":{"version":"3.3.0"},"storage":{"files":[{"path":"/etc/systemd/network/90-vmoperator-eth0.network"}]}}`
+		})
+
+		It("keeps the unit file of the Ignition config", func() {
+			Expect(err).ToNot(HaveOccurred())
+			files := config["storage"].(map[string]interface{})["files"].([]interface{})
+			Expect(files).To(HaveLen(2))
+			Expect(files[0]).ToNot(HaveKey("contents"))
+			Expect(files[1]).To(HaveKeyWithValue("path", "/etc/systemd/network/90-vmoperator-eth1.network"))
+		})
+	})
+
+	Context("Ignition v2 config", func() {
+		BeforeEach(func() {
+			ignitionConfig = `{"ignition":{"version":"2.3.0"}}`
+		})
+
+		It("adds the networkd units to the networkd section", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config).ToNot(HaveKey("storage"))
+			units := config["networkd"].(map[string]interface{})["units"].([]interface{})
+			Expect(units).To(HaveLen(2))
+			Expect(units[0]).To(HaveKeyWithValue("name", "90-vmoperator-eth0.network"))
+		})
+	})
+
+	Context("Invalid Ignition config", func() {
+		BeforeEach(func() {
+			ignitionConfig = "not-json"
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,76 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagevolume
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/object"
+
+	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
+)
+
+// IsConfigured checks if VM spec has an image volume to identify if the VM boots from a PVC populated from
+// its image and returns true/false accordingly.
+func IsConfigured(vm *vmopv1alpha1.VirtualMachine) bool {
+	return GetVolume(vm) != nil
+}
+
+// GetVolume returns the image volume present in VM spec, or nil if the VM does not have one.
+func GetVolume(vm *vmopv1alpha1.VirtualMachine) *vmopv1alpha1.VirtualMachineVolume {
+	for i := range vm.Spec.Volumes {
+		if pvc := vm.Spec.Volumes[i].PersistentVolumeClaim; pvc != nil && pvc.ImageVolumeClaim != nil {
+			return &vm.Spec.Volumes[i]
+		}
+	}
+
+	return nil
+}
+
+// IsAttached returns true if the image volume of the VM is attached to it.
+func IsAttached(vm *vmopv1alpha1.VirtualMachine) bool {
+	volume := GetVolume(vm)
+	if volume == nil {
+		return false
+	}
+
+	for _, volumeStatus := range vm.Status.Volumes {
+		if volumeStatus.Name == volume.Name {
+			return volumeStatus.Attached
+		}
+	}
+
+	return false
+}
+
+// ParseBootDiskURLPath returns the datastore path of the boot disk with the URL path that was recorded when the
+// boot disk was detached from the VM.
+func ParseBootDiskURLPath(diskURLPath string) (object.DatastorePath, error) {
+	diskURL, err := url.Parse(diskURLPath)
+	if err != nil {
+		return object.DatastorePath{}, errors.Wrapf(err, "invalid boot disk URL path %q", diskURLPath)
+	}
+
+	dsPath := object.DatastorePath{
+		Datastore: diskURL.Query().Get("dsName"),
+		Path:      strings.TrimPrefix(diskURL.Path, "/folder/"),
+	}
+	if dsPath.Datastore == "" || dsPath.Path == "" {
+		return object.DatastorePath{}, errors.Errorf("invalid boot disk URL path %q", diskURLPath)
+	}
+
+	return dsPath, nil
+}
+
+// IsInDirectory returns true if the file with the datastore path is in the directory with the datastore path
+// dir, such as the directory of a VM.
+func IsInDirectory(filePath, dir object.DatastorePath) bool {
+	if filePath.Datastore != dir.Datastore || path.Clean(filePath.Path) != filePath.Path {
+		return false
+	}
+	return path.Dir(filePath.Path) == path.Clean(dir.Path)
+}
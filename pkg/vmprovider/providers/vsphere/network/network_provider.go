@@ -1,4 +1,4 @@
-// Copyright (c) 2018-2022 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2018-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package network
@@ -7,6 +7,8 @@ import (
 	goctx "context"
 	"fmt"
 	"net"
+	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,6 +16,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 
 	"github.com/pkg/errors"
@@ -70,10 +73,13 @@ const (
 )
 
 type InterfaceInfo struct {
-	Device          vimtypes.BaseVirtualDevice
-	Customization   *vimtypes.CustomizationAdapterMapping
-	IPConfiguration IPConfig
-	NetplanEthernet NetplanEthernet
+	Device        vimtypes.BaseVirtualDevice
+	Customization *vimtypes.CustomizationAdapterMapping
+	// IPConfigurations are the IPv4 and IPv6 configurations of the interface. Empty when DHCP is used.
+	IPConfigurations []IPConfig
+	NetplanEthernet  NetplanEthernet
+	// BondName is the name of the bond the interface is a member of, if any.
+	BondName string
 }
 
 type InterfaceInfoList []InterfaceInfo
@@ -90,6 +96,7 @@ func (l InterfaceInfoList) GetVirtualDeviceList() object.VirtualDeviceList {
 type Netplan struct {
 	Version   int                        `yaml:"version,omitempty"`
 	Ethernets map[string]NetplanEthernet `yaml:"ethernets,omitempty"`
+	Bonds     map[string]NetplanBond     `yaml:"bonds,omitempty"`
 }
 type NetplanEthernet struct {
 	Match       NetplanEthernetMatch      `yaml:"match,omitempty"`
@@ -97,7 +104,10 @@ type NetplanEthernet struct {
 	Dhcp4       bool                      `yaml:"dhcp4,omitempty"`
 	Addresses   []string                  `yaml:"addresses,omitempty"`
 	Gateway4    string                    `yaml:"gateway4,omitempty"`
+	Gateway6    string                    `yaml:"gateway6,omitempty"`
+	MTU         int64                     `yaml:"mtu,omitempty"`
 	Nameservers NetplanEthernetNameserver `yaml:"nameservers,omitempty"`
+	Routes      []NetplanRoute            `yaml:"routes,omitempty"`
 }
 type NetplanEthernetMatch struct {
 	MacAddress string `yaml:"macaddress,omitempty"`
@@ -106,12 +116,33 @@ type NetplanEthernetNameserver struct {
 	Addresses []string `yaml:"addresses,omitempty"`
 	Search    []string `yaml:"search,omitempty"`
 }
+type NetplanRoute struct {
+	To     string `yaml:"to"`
+	Via    string `yaml:"via"`
+	Metric *int32 `yaml:"metric,omitempty"`
+}
+type NetplanBond struct {
+	Interfaces  []string                  `yaml:"interfaces,omitempty"`
+	Parameters  NetplanBondParameters     `yaml:"parameters,omitempty"`
+	Dhcp4       bool                      `yaml:"dhcp4,omitempty"`
+	Addresses   []string                  `yaml:"addresses,omitempty"`
+	Gateway4    string                    `yaml:"gateway4,omitempty"`
+	Gateway6    string                    `yaml:"gateway6,omitempty"`
+	MTU         int64                     `yaml:"mtu,omitempty"`
+	Nameservers NetplanEthernetNameserver `yaml:"nameservers,omitempty"`
+	Routes      []NetplanRoute            `yaml:"routes,omitempty"`
+}
+type NetplanBondParameters struct {
+	Mode string `yaml:"mode,omitempty"`
+}
 
 func (l InterfaceInfoList) GetNetplan(
 	currentEthCards object.VirtualDeviceList,
-	dnsServers, searchSuffixes []string) Netplan {
+	dnsServers, searchSuffixes []string,
+	bonds []vmopv1alpha1.VirtualMachineNetworkBond) Netplan {
 
 	ethernets := make(map[string]NetplanEthernet)
+	netplanBonds := make(map[string]NetplanBond)
 
 	for index, info := range l {
 		netplanEthernet := info.NetplanEthernet
@@ -132,13 +163,85 @@ func (l InterfaceInfoList) GetNetplan(
 		netplanEthernet.Nameservers.Search = searchSuffixes
 		name := fmt.Sprintf("eth%d", index)
 		netplanEthernet.SetName = name
+
+		if info.BondName != "" {
+			bond, ok := netplanBonds[info.BondName]
+			if !ok {
+				bond = NetplanBond{
+					Parameters:  NetplanBondParameters{Mode: bondMode(bonds, info.BondName)},
+					Dhcp4:       true,
+					MTU:         netplanEthernet.MTU,
+					Nameservers: netplanEthernet.Nameservers,
+				}
+			}
+			mergeBondIPConfig(&bond, netplanEthernet)
+			bond.Interfaces = append(bond.Interfaces, name)
+			netplanBonds[info.BondName] = bond
+
+			// The members of a bond must not be configured on their own.
+			netplanEthernet = NetplanEthernet{
+				Match:   netplanEthernet.Match,
+				SetName: netplanEthernet.SetName,
+				MTU:     netplanEthernet.MTU,
+			}
+		}
+
 		ethernets[name] = netplanEthernet
 	}
 
-	return Netplan{
+	netplan := Netplan{
 		Version:   constants.NetPlanVersion,
 		Ethernets: ethernets,
 	}
+	if len(netplanBonds) > 0 {
+		netplan.Bonds = netplanBonds
+	}
+
+	return netplan
+}
+
+// mergeBondIPConfig merges the IP configuration of the bond member into the bond, so the bond has
+// the addresses and routes of all its members. The gateways are those of the first member that
+// has one, and the bond only uses DHCP when all its members do.
+func mergeBondIPConfig(bond *NetplanBond, member NetplanEthernet) {
+	bond.Dhcp4 = bond.Dhcp4 && member.Dhcp4
+	addresses := sets.NewString(bond.Addresses...)
+	for _, address := range member.Addresses {
+		if !addresses.Has(address) {
+			addresses.Insert(address)
+			bond.Addresses = append(bond.Addresses, address)
+		}
+	}
+	for _, route := range member.Routes {
+		if !containsRoute(bond.Routes, route) {
+			bond.Routes = append(bond.Routes, route)
+		}
+	}
+	if bond.Gateway4 == "" {
+		bond.Gateway4 = member.Gateway4
+	}
+	if bond.Gateway6 == "" {
+		bond.Gateway6 = member.Gateway6
+	}
+}
+
+func containsRoute(routes []NetplanRoute, route NetplanRoute) bool {
+	for _, r := range routes {
+		if reflect.DeepEqual(r, route) {
+			return true
+		}
+	}
+	return false
+}
+
+// bondMode returns the mode of the named bond, defaulting to active-backup.
+func bondMode(bonds []vmopv1alpha1.VirtualMachineNetworkBond, name string) string {
+	for _, bond := range bonds {
+		if bond.Name == name && bond.Mode != "" {
+			return string(bond.Mode)
+		}
+	}
+	return string(vmopv1alpha1.VirtualMachineNetworkBondModeActiveBackup)
 }
 
 func (l InterfaceInfoList) GetInterfaceCustomizations() []vimtypes.CustomizationAdapterMapping {
@@ -152,7 +255,7 @@ func (l InterfaceInfoList) GetInterfaceCustomizations() []vimtypes.Customization
 func (l InterfaceInfoList) GetIPConfigs() []IPConfig {
 	ipConfigs := make([]IPConfig, 0, len(l))
 	for _, info := range l {
-		ipConfigs = append(ipConfigs, info.IPConfiguration)
+		ipConfigs = append(ipConfigs, info.IPConfigurations...)
 	}
 	return ipConfigs
 }
@@ -186,6 +289,28 @@ func NewProvider(
 }
 
 func (np *networkProvider) EnsureNetworkInterface(vmCtx context.VirtualMachineContext, vif *vmopv1alpha1.VirtualMachineNetworkInterface) (*InterfaceInfo, error) {
+	info, err := np.ensureNetworkInterface(vmCtx, vif)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply the guest network settings that do not depend on the network type.
+	if vif.MTU != nil {
+		info.NetplanEthernet.MTU = *vif.MTU
+	}
+	for _, route := range vif.Routes {
+		info.NetplanEthernet.Routes = append(info.NetplanEthernet.Routes, NetplanRoute{
+			To:     route.To,
+			Via:    route.Via,
+			Metric: route.Metric,
+		})
+	}
+	info.BondName = vif.BondName
+
+	return info, nil
+}
+
+func (np *networkProvider) ensureNetworkInterface(vmCtx context.VirtualMachineContext, vif *vmopv1alpha1.VirtualMachineNetworkInterface) (*InterfaceInfo, error) {
 	if providerRef := vif.ProviderRef; providerRef != nil {
 		// ProviderRef is only supported for NetOP types.
 		gvk, err := apiutil.GVKForObject(&netopv1alpha1.NetworkInterface{}, np.scheme)
@@ -275,7 +400,6 @@ func (np *namedNetworkProvider) EnsureNetworkInterface(
 				Ip: &vimtypes.CustomizationDhcpIpGenerator{},
 			},
 		},
-		IPConfiguration: IPConfig{},
 		NetplanEthernet: NetplanEthernet{},
 	}, nil
 }
@@ -428,40 +552,38 @@ func (np *netOpNetworkProvider) waitForReadyNetworkInterface(
 	return netIf, err
 }
 
-func (np *netOpNetworkProvider) goscCustomization(netIf *netopv1alpha1.NetworkInterface) *vimtypes.CustomizationAdapterMapping {
-	var adapter *vimtypes.CustomizationIPSettings
+func (np *netOpNetworkProvider) goscCustomization(netIf *netopv1alpha1.NetworkInterface) (*vimtypes.CustomizationAdapterMapping, error) {
+	adapter := &vimtypes.CustomizationIPSettings{}
 
 	if len(netIf.Status.IPConfigs) == 0 {
-		adapter = &vimtypes.CustomizationIPSettings{
-			Ip: &vimtypes.CustomizationDhcpIpGenerator{},
-		}
-	} else {
-		switch ipConfig := netIf.Status.IPConfigs[0]; ipConfig.IPFamily {
+		adapter.Ip = &vimtypes.CustomizationDhcpIpGenerator{}
+	}
+
+	for _, ipConfig := range netIf.Status.IPConfigs {
+		switch ipConfig.IPFamily {
 		case netopv1alpha1.IPv4Protocol:
-			adapter = &vimtypes.CustomizationIPSettings{
-				Ip:         &vimtypes.CustomizationFixedIp{IpAddress: ipConfig.IP},
-				SubnetMask: ipConfig.SubnetMask,
-				Gateway:    []string{ipConfig.Gateway},
+			if adapter.Ip != nil {
+				// GOSC only supports a single IPv4 address per adapter.
+				continue
 			}
+			adapter.Ip = &vimtypes.CustomizationFixedIp{IpAddress: ipConfig.IP}
+			adapter.SubnetMask = ipConfig.SubnetMask
+			adapter.Gateway = []string{ipConfig.Gateway}
 		case netopv1alpha1.IPv6Protocol:
-			subnetMask := net.ParseIP(ipConfig.SubnetMask)
-			var ipMask net.IPMask = make([]byte, net.IPv6len)
-			copy(ipMask, subnetMask)
-			ones, _ := ipMask.Size()
-
-			adapter = &vimtypes.CustomizationIPSettings{
-				IpV6Spec: &vimtypes.CustomizationIPSettingsIpV6AddressSpec{
-					Ip: []vimtypes.BaseCustomizationIpV6Generator{
-						&vimtypes.CustomizationFixedIpV6{
-							IpAddress:  ipConfig.IP,
-							SubnetMask: int32(ones),
-						},
-					},
-					Gateway: []string{ipConfig.Gateway},
-				},
+			prefixLength, err := ipv6PrefixLength(ipConfig.SubnetMask)
+			if err != nil {
+				return nil, err
+			}
+			if adapter.IpV6Spec == nil {
+				adapter.IpV6Spec = &vimtypes.CustomizationIPSettingsIpV6AddressSpec{}
+			}
+			adapter.IpV6Spec.Ip = append(adapter.IpV6Spec.Ip, &vimtypes.CustomizationFixedIpV6{
+				IpAddress:  ipConfig.IP,
+				SubnetMask: int32(prefixLength),
+			})
+			if ipConfig.Gateway != "" {
+				adapter.IpV6Spec.Gateway = append(adapter.IpV6Spec.Gateway, ipConfig.Gateway)
 			}
-		default:
-			adapter = &vimtypes.CustomizationIPSettings{}
 		}
 	}
 
@@ -473,7 +595,7 @@ func (np *netOpNetworkProvider) goscCustomization(netIf *netopv1alpha1.NetworkIn
 	return &vimtypes.CustomizationAdapterMapping{
 		MacAddress: netIf.Status.MacAddress,
 		Adapter:    *adapter,
-	}
+	}, nil
 }
 
 func (np *netOpNetworkProvider) EnsureNetworkInterface(
@@ -490,44 +612,49 @@ func (np *netOpNetworkProvider) EnsureNetworkInterface(
 		return nil, err
 	}
 
+	customization, err := np.goscCustomization(netIf)
+	if err != nil {
+		return nil, err
+	}
+
+	netplanEthernet, err := np.getNetplanEthernet(netIf)
+	if err != nil {
+		return nil, err
+	}
+
 	return &InterfaceInfo{
-		Device:          ethDev,
-		Customization:   np.goscCustomization(netIf),
-		IPConfiguration: np.getIPConfig(netIf),
-		NetplanEthernet: np.getNetplanEthernet(netIf),
+		Device:           ethDev,
+		Customization:    customization,
+		IPConfigurations: np.getIPConfigs(netIf),
+		NetplanEthernet:  netplanEthernet,
 	}, nil
 }
 
-func (np *netOpNetworkProvider) getIPConfig(netIf *netopv1alpha1.NetworkInterface) IPConfig {
-	var ipConfig IPConfig
-	if len(netIf.Status.IPConfigs) > 0 {
-		ipConfig = IPConfig{
-			IP:         netIf.Status.IPConfigs[0].IP,
-			Gateway:    netIf.Status.IPConfigs[0].Gateway,
-			SubnetMask: netIf.Status.IPConfigs[0].SubnetMask,
-			IPFamily:   IPFamily(netIf.Status.IPConfigs[0].IPFamily),
-		}
+func (np *netOpNetworkProvider) getIPConfigs(netIf *netopv1alpha1.NetworkInterface) []IPConfig {
+	ipConfigs := make([]IPConfig, 0, len(netIf.Status.IPConfigs))
+	for _, ipConfig := range netIf.Status.IPConfigs {
+		ipConfigs = append(ipConfigs, IPConfig{
+			IP:         ipConfig.IP,
+			Gateway:    ipConfig.Gateway,
+			SubnetMask: ipConfig.SubnetMask,
+			IPFamily:   IPFamily(ipConfig.IPFamily),
+		})
 	}
 
-	return ipConfig
+	return ipConfigs
 }
 
-func (np *netOpNetworkProvider) getNetplanEthernet(netIf *netopv1alpha1.NetworkInterface) NetplanEthernet {
+func (np *netOpNetworkProvider) getNetplanEthernet(netIf *netopv1alpha1.NetworkInterface) (NetplanEthernet, error) {
 	eth := NetplanEthernet{
 		Match: NetplanEthernetMatch{
 			MacAddress: NormalizeNetplanMac(netIf.Status.MacAddress),
 		},
 	}
-
-	if len(netIf.Status.IPConfigs) == 0 {
-		eth.Dhcp4 = true
-	} else {
-		ipAddr := netIf.Status.IPConfigs[0]
-		eth.Addresses = []string{ToCidrNotation(ipAddr.IP, ipAddr.SubnetMask)}
-		eth.Gateway4 = ipAddr.Gateway
+	if err := setNetplanEthernetIPConfigs(&eth, np.getIPConfigs(netIf)); err != nil {
+		return NetplanEthernet{}, err
 	}
 
-	return eth
+	return eth, nil
 }
 
 type nsxtNetworkProvider struct {
@@ -688,44 +815,83 @@ func (np *nsxtNetworkProvider) EnsureNetworkInterface(
 		return nil, err
 	}
 
+	netplanEthernet, err := np.getNetplanEthernet(vnetIf)
+	if err != nil {
+		return nil, err
+	}
+
 	return &InterfaceInfo{
-		Device:          ethDev,
-		Customization:   np.goscCustomization(vnetIf),
-		IPConfiguration: np.getIPConfig(vnetIf),
-		NetplanEthernet: np.getNetplanEthernet(vnetIf),
+		Device:           ethDev,
+		Customization:    np.goscCustomization(vnetIf),
+		IPConfigurations: np.getIPConfigs(vnetIf),
+		NetplanEthernet:  netplanEthernet,
 	}, nil
 }
 
-func (np *nsxtNetworkProvider) getIPConfig(vnetIf *ncpv1alpha1.VirtualNetworkInterface) IPConfig {
-	var ipConfig IPConfig
-	if len(vnetIf.Status.IPAddresses) > 0 {
-		ipAddr := vnetIf.Status.IPAddresses[0]
-		ipConfig.IP = ipAddr.IP
-		ipConfig.Gateway = ipAddr.Gateway
-		ipConfig.SubnetMask = ipAddr.SubnetMask
-		ipConfig.IPFamily = IPv4Protocol
+func (np *nsxtNetworkProvider) getIPConfigs(vnetIf *ncpv1alpha1.VirtualNetworkInterface) []IPConfig {
+	ipConfigs := make([]IPConfig, 0, len(vnetIf.Status.IPAddresses))
+	for _, ipAddr := range vnetIf.Status.IPAddresses {
+		if ipAddr.IP == "" {
+			continue
+		}
+
+		// NCP does not report the IP family so infer it from the address.
+		ipFamily := IPv4Protocol
+		if ip := net.ParseIP(ipAddr.IP); ip != nil && ip.To4() == nil {
+			ipFamily = IPv6Protocol
+		}
+
+		ipConfigs = append(ipConfigs, IPConfig{
+			IP:         ipAddr.IP,
+			Gateway:    ipAddr.Gateway,
+			SubnetMask: ipAddr.SubnetMask,
+			IPFamily:   ipFamily,
+		})
 	}
 
-	return ipConfig
+	return ipConfigs
 }
 
-func (np *nsxtNetworkProvider) getNetplanEthernet(vnetIf *ncpv1alpha1.VirtualNetworkInterface) NetplanEthernet {
+func (np *nsxtNetworkProvider) getNetplanEthernet(vnetIf *ncpv1alpha1.VirtualNetworkInterface) (NetplanEthernet, error) {
 	eth := NetplanEthernet{
 		Match: NetplanEthernetMatch{
 			MacAddress: NormalizeNetplanMac(vnetIf.Status.MacAddress),
 		},
 	}
+	if err := setNetplanEthernetIPConfigs(&eth, np.getIPConfigs(vnetIf)); err != nil {
+		return NetplanEthernet{}, err
+	}
 
-	addrs := vnetIf.Status.IPAddresses
-	if len(addrs) == 0 || (len(addrs) == 1 && addrs[0].IP == "") {
+	return eth, nil
+}
+
+// setNetplanEthernetIPConfigs sets the addresses and gateways of the ethernet from the IP configs,
+// or enables DHCP when there are none.
+func setNetplanEthernetIPConfigs(eth *NetplanEthernet, ipConfigs []IPConfig) error {
+	if len(ipConfigs) == 0 {
 		eth.Dhcp4 = true
-	} else {
-		ipAddr := addrs[0]
-		eth.Addresses = []string{ToCidrNotation(ipAddr.IP, ipAddr.SubnetMask)}
-		eth.Gateway4 = ipAddr.Gateway
+		return nil
+	}
+
+	for _, ipConfig := range ipConfigs {
+		cidr, err := ipConfig.CIDR()
+		if err != nil {
+			return err
+		}
+		eth.Addresses = append(eth.Addresses, cidr)
+		switch ipConfig.IPFamily {
+		case IPv6Protocol:
+			if eth.Gateway6 == "" {
+				eth.Gateway6 = ipConfig.Gateway
+			}
+		default:
+			if eth.Gateway4 == "" {
+				eth.Gateway4 = ipConfig.Gateway
+			}
+		}
 	}
 
-	return eth
+	return nil
 }
 
 // searchNsxtNetworkReference takes in nsx-t logical switch UUID and returns the reference of the network.
@@ -785,6 +951,43 @@ func ToCidrNotation(ip string, mask string) string {
 	return IPNet.String()
 }
 
+// CIDR returns the IP address of the config in CIDR notation. An IPv6 subnet mask may either be
+// expressed as an address, ex. "ffff:ffff:ffff:ffff::", or as a prefix length, ex. "64".
+func (c IPConfig) CIDR() (string, error) {
+	if c.IPFamily != IPv6Protocol {
+		return ToCidrNotation(c.IP, c.SubnetMask), nil
+	}
+	prefixLength, err := ipv6PrefixLength(c.SubnetMask)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%d", c.IP, prefixLength), nil
+}
+
+// ipv6PrefixLength returns the prefix length of the IPv6 subnet mask, or 128 when the mask is unset.
+func ipv6PrefixLength(mask string) (int, error) {
+	if mask == "" {
+		return 128, nil
+	}
+	if ones, err := strconv.Atoi(strings.TrimPrefix(mask, "/")); err == nil {
+		if ones < 0 || ones > 128 {
+			return 0, fmt.Errorf("invalid IPv6 prefix length %q", mask)
+		}
+		return ones, nil
+	}
+
+	ip := net.ParseIP(mask)
+	if ip == nil || ip.To4() != nil {
+		return 0, fmt.Errorf("invalid IPv6 subnet mask %q", mask)
+	}
+	ones, bits := net.IPMask(ip).Size()
+	if bits == 0 {
+		// The mask is not in the canonical form of leading ones followed by zeros.
+		return 0, fmt.Errorf("invalid IPv6 subnet mask %q", mask)
+	}
+	return ones, nil
+}
+
 // NormalizeNetplanMac normalizes the mac address format to one compatible with netplan.
 func NormalizeNetplanMac(mac string) string {
 	if len(mac) == 0 {
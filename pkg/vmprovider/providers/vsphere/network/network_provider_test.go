@@ -1,4 +1,4 @@
-// Copyright (c) 2019-2022 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2019-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package network_test
@@ -126,6 +126,21 @@ var _ = Describe("NetworkProvider", func() {
 				Expect(err).To(HaveOccurred())
 				Expect(err).To(MatchError(fmt.Sprintf("unable to find network \"%s\": network '%s' not found", doesNotExist, doesNotExist)))
 			})
+
+			It("create expected guest network settings", func() {
+				mtu := int64(9000)
+				vmCtx.VM.Spec.NetworkInterfaces[0].MTU = &mtu
+				vmCtx.VM.Spec.NetworkInterfaces[0].Routes = []v1alpha1.VirtualMachineNetworkRoute{
+					{To: "10.0.0.0/8", Via: "192.168.1.1"},
+				}
+				vmCtx.VM.Spec.NetworkInterfaces[0].BondName = "bond0"
+
+				info, err := np.EnsureNetworkInterface(vmCtx, &vmCtx.VM.Spec.NetworkInterfaces[0])
+				Expect(err).ToNot(HaveOccurred())
+				Expect(info.NetplanEthernet.MTU).To(Equal(mtu))
+				Expect(info.NetplanEthernet.Routes).To(Equal([]network.NetplanRoute{{To: "10.0.0.0/8", Via: "192.168.1.1"}}))
+				Expect(info.BondName).To(Equal("bond0"))
+			})
 		})
 	})
 
@@ -472,6 +487,43 @@ var _ = Describe("NetworkProvider", func() {
 						Expect(info.NetplanEthernet.Addresses[0]).To(Equal(expectedCidrNotation))
 					})
 				})
+
+				Context("dual-stack IPConfigs", func() {
+					BeforeEach(func() {
+						netIf.Status.IPConfigs = []netopv1alpha1.IPConfig{
+							{
+								IP:         "192.168.1.37",
+								IPFamily:   netopv1alpha1.IPv4Protocol,
+								Gateway:    "192.168.1.1",
+								SubnetMask: "255.255.255.0",
+							},
+							{
+								IP:         "2001:db8::25",
+								IPFamily:   netopv1alpha1.IPv6Protocol,
+								Gateway:    "2001:db8::1",
+								SubnetMask: "ffff:ffff:ffff:ffff::",
+							},
+						}
+					})
+
+					It("NetplanEthernet with ipv4 and ipv6 customization", func() {
+						info, err := np.EnsureNetworkInterface(vmCtx, &vmCtx.VM.Spec.NetworkInterfaces[0])
+						Expect(err).ToNot(HaveOccurred())
+						Expect(info.NetplanEthernet.Dhcp4).To(BeFalse())
+						Expect(info.NetplanEthernet.Addresses).To(Equal([]string{"192.168.1.37/24", "2001:db8::25/64"}))
+						Expect(info.NetplanEthernet.Gateway4).To(Equal("192.168.1.1"))
+						Expect(info.NetplanEthernet.Gateway6).To(Equal("2001:db8::1"))
+						Expect(info.IPConfigurations).To(HaveLen(2))
+
+						Expect(info.Customization.Adapter.Ip).To(BeAssignableToTypeOf(&types.CustomizationFixedIp{}))
+						Expect(info.Customization.Adapter.IpV6Spec).ToNot(BeNil())
+						Expect(info.Customization.Adapter.IpV6Spec.Ip).To(HaveLen(1))
+						fixedIP := info.Customization.Adapter.IpV6Spec.Ip[0].(*types.CustomizationFixedIpV6)
+						Expect(fixedIP.IpAddress).To(Equal("2001:db8::25"))
+						Expect(fixedIP.SubnetMask).To(BeEquivalentTo(64))
+						Expect(info.Customization.Adapter.IpV6Spec.Gateway).To(Equal([]string{"2001:db8::1"}))
+					})
+				})
 			})
 		})
 	})
@@ -655,6 +707,146 @@ var _ = Describe("NetworkProvider utils", func() {
 			Expect(cidrNotation).To(Equal("1.2.3.4/24"))
 		})
 	})
+	Context("IPConfig CIDR", func() {
+		It("ipv4", func() {
+			ipConfig := network.IPConfig{IP: "1.2.3.4", SubnetMask: "255.255.255.0", IPFamily: network.IPv4Protocol}
+			Expect(ipConfig.CIDR()).To(Equal("1.2.3.4/24"))
+		})
+		It("ipv6 with mask", func() {
+			ipConfig := network.IPConfig{IP: "2001:db8::2", SubnetMask: "ffff:ffff:ffff:ffff::", IPFamily: network.IPv6Protocol}
+			Expect(ipConfig.CIDR()).To(Equal("2001:db8::2/64"))
+		})
+		It("ipv6 with prefix length", func() {
+			ipConfig := network.IPConfig{IP: "2001:db8::2", SubnetMask: "/48", IPFamily: network.IPv6Protocol}
+			Expect(ipConfig.CIDR()).To(Equal("2001:db8::2/48"))
+		})
+		It("ipv6 without mask", func() {
+			ipConfig := network.IPConfig{IP: "2001:db8::2", IPFamily: network.IPv6Protocol}
+			Expect(ipConfig.CIDR()).To(Equal("2001:db8::2/128"))
+		})
+		It("ipv6 with invalid mask", func() {
+			ipConfig := network.IPConfig{IP: "2001:db8::2", SubnetMask: "not-a-mask", IPFamily: network.IPv6Protocol}
+			_, err := ipConfig.CIDR()
+			Expect(err).To(MatchError(`invalid IPv6 subnet mask "not-a-mask"`))
+		})
+		It("ipv6 with non-canonical mask", func() {
+			ipConfig := network.IPConfig{IP: "2001:db8::2", SubnetMask: "ffff::ffff", IPFamily: network.IPv6Protocol}
+			_, err := ipConfig.CIDR()
+			Expect(err).To(HaveOccurred())
+		})
+		It("ipv6 with out of range prefix length", func() {
+			ipConfig := network.IPConfig{IP: "2001:db8::2", SubnetMask: "/129", IPFamily: network.IPv6Protocol}
+			_, err := ipConfig.CIDR()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("GetNetplan", func() {
+		var (
+			infoList network.InterfaceInfoList
+			bonds    []v1alpha1.VirtualMachineNetworkBond
+		)
+
+		BeforeEach(func() {
+			infoList = network.InterfaceInfoList{
+				{
+					NetplanEthernet: network.NetplanEthernet{
+						Match:     network.NetplanEthernetMatch{MacAddress: "00:50:56:00:00:01"},
+						Addresses: []string{"192.168.1.10/24", "2001:db8::10/64"},
+						Gateway4:  "192.168.1.1",
+						Gateway6:  "2001:db8::1",
+						MTU:       9000,
+						Routes:    []network.NetplanRoute{{To: "10.0.0.0/8", Via: "192.168.1.254"}},
+					},
+				},
+				{
+					NetplanEthernet: network.NetplanEthernet{
+						Match: network.NetplanEthernetMatch{MacAddress: "00:50:56:00:00:02"},
+						Dhcp4: true,
+					},
+				},
+			}
+			bonds = nil
+		})
+
+		It("without bonds", func() {
+			netplan := infoList.GetNetplan(nil, []string{"8.8.8.8"}, nil, bonds)
+			Expect(netplan.Bonds).To(BeEmpty())
+			Expect(netplan.Ethernets).To(HaveLen(2))
+			eth0 := netplan.Ethernets["eth0"]
+			Expect(eth0.SetName).To(Equal("eth0"))
+			Expect(eth0.Gateway6).To(Equal("2001:db8::1"))
+			Expect(eth0.MTU).To(BeEquivalentTo(9000))
+			Expect(eth0.Routes).To(HaveLen(1))
+			Expect(eth0.Nameservers.Addresses).To(Equal([]string{"8.8.8.8"}))
+		})
+
+		It("with a bond", func() {
+			infoList[0].BondName = "bond0"
+			infoList[1].BondName = "bond0"
+			bonds = []v1alpha1.VirtualMachineNetworkBond{
+				{Name: "bond0", Mode: v1alpha1.VirtualMachineNetworkBondMode8023AD},
+			}
+
+			netplan := infoList.GetNetplan(nil, []string{"8.8.8.8"}, nil, bonds)
+			Expect(netplan.Bonds).To(HaveKey("bond0"))
+			bond := netplan.Bonds["bond0"]
+			Expect(bond.Interfaces).To(Equal([]string{"eth0", "eth1"}))
+			Expect(bond.Parameters.Mode).To(Equal("802.3ad"))
+			Expect(bond.Addresses).To(Equal([]string{"192.168.1.10/24", "2001:db8::10/64"}))
+			Expect(bond.Gateway4).To(Equal("192.168.1.1"))
+			Expect(bond.Gateway6).To(Equal("2001:db8::1"))
+			Expect(bond.Dhcp4).To(BeFalse())
+			Expect(bond.MTU).To(BeEquivalentTo(9000))
+			Expect(bond.Routes).To(HaveLen(1))
+			Expect(bond.Nameservers.Addresses).To(Equal([]string{"8.8.8.8"}))
+
+			for _, name := range []string{"eth0", "eth1"} {
+				eth := netplan.Ethernets[name]
+				Expect(eth.Match.MacAddress).ToNot(BeEmpty())
+				Expect(eth.Addresses).To(BeEmpty())
+				Expect(eth.Dhcp4).To(BeFalse())
+				Expect(eth.Nameservers.Addresses).To(BeEmpty())
+			}
+		})
+
+		It("with a bond without mode", func() {
+			infoList[0].BondName = "bond0"
+			infoList[1].BondName = "bond0"
+
+			netplan := infoList.GetNetplan(nil, nil, nil, bonds)
+			Expect(netplan.Bonds["bond0"].Parameters.Mode).To(Equal("active-backup"))
+		})
+
+		It("with a bond whose members have different addresses", func() {
+			infoList[0].BondName = "bond0"
+			infoList[1].BondName = "bond0"
+			infoList[1].NetplanEthernet.Dhcp4 = false
+			infoList[1].NetplanEthernet.Addresses = []string{"192.168.1.10/24", "192.168.2.10/24"}
+			infoList[1].NetplanEthernet.Gateway4 = "192.168.2.1"
+			infoList[1].NetplanEthernet.Routes = []network.NetplanRoute{{To: "172.16.0.0/12", Via: "192.168.2.254"}}
+
+			netplan := infoList.GetNetplan(nil, nil, nil, bonds)
+			bond := netplan.Bonds["bond0"]
+			Expect(bond.Addresses).To(Equal([]string{"192.168.1.10/24", "2001:db8::10/64", "192.168.2.10/24"}))
+			Expect(bond.Gateway4).To(Equal("192.168.1.1"))
+			Expect(bond.Routes).To(HaveLen(2))
+			Expect(bond.Dhcp4).To(BeFalse())
+		})
+
+		It("with a bond whose members all use DHCP", func() {
+			infoList[0].BondName = "bond0"
+			infoList[1].BondName = "bond0"
+			infoList[0].NetplanEthernet = network.NetplanEthernet{
+				Match: network.NetplanEthernetMatch{MacAddress: "00:50:56:00:00:01"},
+				Dhcp4: true,
+			}
+
+			netplan := infoList.GetNetplan(nil, nil, nil, bonds)
+			bond := netplan.Bonds["bond0"]
+			Expect(bond.Dhcp4).To(BeTrue())
+			Expect(bond.Addresses).To(BeEmpty())
+		})
+	})
 	Context("NormalizeNetplanMac", func() {
 		It("empty string", func() {
 			Expect(network.NormalizeNetplanMac("")).To(Equal(""))
@@ -331,3 +331,39 @@ func Placement(
 
 	return result, nil
 }
+
+// MigrationPlacement determines where to place an existing VM in the zone it is being migrated to, which
+// is the zone in its zone label.
+func MigrationPlacement(
+	vmCtx context.VirtualMachineContext,
+	client ctrlclient.Client,
+	vcClient *vim25.Client,
+	configSpec *types.VirtualMachineConfigSpec,
+	childRPName string) (*Result, error) {
+
+	candidates, err := getPlacementCandidates(vmCtx, client, vcClient, false, childRPName)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no placement candidates available")
+	}
+
+	recommendations := getZonalPlacementRecommendations(vmCtx, vcClient, candidates, configSpec, false)
+	if len(recommendations) == 0 {
+		return nil, fmt.Errorf("no placement recommendations available")
+	}
+
+	zoneName, rec := MakePlacementDecision(recommendations)
+	vmCtx.Logger.V(5).Info("Migration placement decision result", "zone", zoneName, "recommendation", rec)
+
+	result := &Result{
+		ZonePlacement: true,
+		ZoneName:      zoneName,
+		PoolMoRef:     rec.PoolMoRef,
+		HostMoRef:     rec.HostMoRef,
+	}
+
+	return result, nil
+}
@@ -1,4 +1,4 @@
-// Copyright (c) 2018-2022 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2018-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package resources
@@ -76,6 +76,23 @@ func (vm *VirtualMachine) Clone(ctx context.Context, folder *object.Folder, clon
 	return &ref, nil
 }
 
+func (vm *VirtualMachine) InstantClone(ctx context.Context, instantCloneSpec *types.VirtualMachineInstantCloneSpec) (*types.ManagedObjectReference, error) {
+	vm.logger.V(5).Info("Instant clone VM")
+
+	instantCloneTask, err := vm.vcVirtualMachine.InstantClone(ctx, *instantCloneSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := instantCloneTask.WaitForResult(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "instant clone VM task failed")
+	}
+
+	ref := result.Result.(types.ManagedObjectReference)
+	return &ref, nil
+}
+
 func (vm *VirtualMachine) Reconfigure(ctx context.Context, configSpec *types.VirtualMachineConfigSpec) error {
 	vm.logger.V(5).Info("Reconfiguring VM", "configSpec", configSpec)
 
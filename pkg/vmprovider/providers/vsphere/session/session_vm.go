@@ -8,6 +8,7 @@ import (
 	"github.com/vmware/govmomi/object"
 	vimTypes "github.com/vmware/govmomi/vim25/types"
 
+	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
 	"github.com/vmware-tanzu/vm-operator/pkg/context"
 )
 
@@ -59,5 +60,78 @@ func updateVirtualDiskDeviceChanges(
 		}
 	}
 
+	bootDiskChange, err := bootDiskDeviceChange(vmCtx.VM, virtualDisks)
+	if err != nil {
+		return nil, err
+	}
+
+	if bootDiskChange != nil {
+		// A VsphereVolume with the device key of the boot disk may have already added a change for it. Both
+		// changes share the same device so that change already has the larger capacity.
+		bootDiskKey := bootDiskChange.GetVirtualDeviceConfigSpec().Device.GetVirtualDevice().Key
+		found := false
+		for _, deviceChange := range deviceChanges {
+			if deviceChange.GetVirtualDeviceConfigSpec().Device.GetVirtualDevice().Key == bootDiskKey {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			deviceChanges = append(deviceChanges, bootDiskChange)
+		}
+	}
+
 	return deviceChanges, nil
 }
+
+// getBootDisk returns the boot disk of the VM. This is the disk with the lowest device key since the disks
+// of the image are added before those of any volumes.
+func getBootDisk(virtualDisks object.VirtualDeviceList) *vimTypes.VirtualDisk {
+	var bootDisk *vimTypes.VirtualDisk
+
+	for _, vmDevice := range virtualDisks {
+		vmDisk, ok := vmDevice.(*vimTypes.VirtualDisk)
+		if !ok {
+			continue
+		}
+
+		if bootDisk == nil || vmDisk.Key < bootDisk.Key {
+			bootDisk = vmDisk
+		}
+	}
+
+	return bootDisk
+}
+
+// bootDiskDeviceChange returns the device change that grows the boot disk to the BootDiskCapacity of the VM,
+// or nil when the boot disk already has that capacity.
+func bootDiskDeviceChange(
+	vm *vmopv1alpha1.VirtualMachine,
+	virtualDisks object.VirtualDeviceList) (vimTypes.BaseVirtualDeviceConfigSpec, error) {
+
+	if vm.Spec.BootDiskCapacity == nil {
+		return nil, nil
+	}
+
+	bootDisk := getBootDisk(virtualDisks)
+	if bootDisk == nil {
+		return nil, errors.New("could not find the boot disk")
+	}
+
+	newCapacityInBytes := vm.Spec.BootDiskCapacity.Value()
+	if newCapacityInBytes < bootDisk.CapacityInBytes {
+		return nil, errors.Errorf("cannot shrink boot disk from %d bytes to %d bytes",
+			bootDisk.CapacityInBytes, newCapacityInBytes)
+	}
+
+	if newCapacityInBytes == bootDisk.CapacityInBytes {
+		return nil, nil
+	}
+
+	bootDisk.CapacityInBytes = newCapacityInBytes
+	return &vimTypes.VirtualDeviceConfigSpec{
+		Operation: vimTypes.VirtualDeviceConfigSpecOperationEdit,
+		Device:    bootDisk,
+	}, nil
+}
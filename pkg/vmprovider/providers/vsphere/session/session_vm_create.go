@@ -1,4 +1,4 @@
-// Copyright (c) 2018-2021 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2018-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package session
@@ -6,13 +6,18 @@ package session
 import (
 	"encoding/base64"
 	"fmt"
+	"strings"
 
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/utils/pointer"
+	ctrlruntime "sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/pkg/errors"
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/vapi/library"
 	"github.com/vmware/govmomi/vapi/vcenter"
+	"github.com/vmware/govmomi/vim25/mo"
 	vimTypes "github.com/vmware/govmomi/vim25/types"
 
 	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
@@ -21,8 +26,11 @@ import (
 	"github.com/vmware-tanzu/vm-operator/pkg/lib"
 	"github.com/vmware-tanzu/vm-operator/pkg/util"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/constants"
+	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/imagevolume"
+	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/network"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/placement"
 	res "github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/resources"
+	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/virtualmachine"
 )
 
 // VMCreateArgs contains the arguments needed to create a VM on VC.
@@ -45,6 +53,11 @@ type VMCreateArgs struct {
 	PlacementConfigSpec *vimTypes.VirtualMachineConfigSpec
 	ClassConfigSpec     *vimTypes.VirtualMachineConfigSpec
 
+	// From the VM Spec.Source if specified
+	SourceVMMoID       string
+	SourceSnapshotMoID string
+	InstantClone       bool
+
 	FolderMoID       string
 	ResourcePoolMoID string
 	HostMoID         string
@@ -111,6 +124,151 @@ func (s *Session) cloneVMFromInventory(
 		return nil, errors.Wrapf(err, "failed to find clone source VM: %s", srcVMName)
 	}
 
+	return s.cloneVM(vmCtx, createArgs, srcVM)
+}
+
+func (s *Session) cloneVMFromVM(
+	vmCtx context.VirtualMachineContext,
+	createArgs *VMCreateArgs) (*object.VirtualMachine, error) {
+
+	srcVM := object.NewVirtualMachine(s.Client.VimClient(), vimTypes.ManagedObjectReference{
+		Type:  "VirtualMachine",
+		Value: createArgs.SourceVMMoID,
+	})
+
+	return s.cloneVM(vmCtx, createArgs, srcVM)
+}
+
+func (s *Session) instantCloneVMFromVM(
+	vmCtx context.VirtualMachineContext,
+	createArgs *VMCreateArgs) (*object.VirtualMachine, error) {
+
+	srcVM := object.NewVirtualMachine(s.Client.VimClient(), vimTypes.ManagedObjectReference{
+		Type:  "VirtualMachine",
+		Value: createArgs.SourceVMMoID,
+	})
+
+	instantCloneSpec := CreateInstantCloneSpec(vmCtx.VM.Name, createArgs)
+
+	srcDevices, err := srcVM.Device(vmCtx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source VM devices: %w", err)
+	}
+
+	netIfList, err := s.ensureNetworkInterfaces(vmCtx, createArgs.ConfigSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	srcEthCards := srcDevices.SelectByType((*vimTypes.VirtualEthernetCard)(nil))
+	ethCardDeviceChanges, err := InstantCloneEthCardDeviceChanges(srcEthCards, netIfList)
+	if err != nil {
+		return nil, err
+	}
+	instantCloneSpec.Location.DeviceChange = append(instantCloneSpec.Location.DeviceChange, ethCardDeviceChanges...)
+
+	vmCtx.Logger.Info("Instant cloning VM", "sourceVM", createArgs.SourceVMMoID)
+
+	vmMoRef, err := res.NewVMFromObject(srcVM).InstantClone(vmCtx, instantCloneSpec)
+	if err != nil {
+		return nil, errors.Wrapf(err, "instant clone from source VM %s failed", createArgs.SourceVMMoID)
+	}
+
+	return object.NewVirtualMachine(s.Client.VimClient(), *vmMoRef), nil
+}
+
+// CreateInstantCloneSpec returns the spec to instant clone the VM. Since the guest of an instant clone is not
+// rebooted, it is customized after the fork only through the guestinfo keys of the VM metadata, which are set in
+// the ExtraConfig of the instant clone.
+func CreateInstantCloneSpec(
+	vmName string,
+	createArgs *VMCreateArgs) *vimTypes.VirtualMachineInstantCloneSpec {
+
+	instantCloneSpec := &vimTypes.VirtualMachineInstantCloneSpec{
+		Name: vmName,
+		Location: vimTypes.VirtualMachineRelocateSpec{
+			Pool: &vimTypes.ManagedObjectReference{
+				Type:  "ResourcePool",
+				Value: createArgs.ResourcePoolMoID,
+			},
+			Folder: &vimTypes.ManagedObjectReference{
+				Type:  "Folder",
+				Value: createArgs.FolderMoID,
+			},
+		},
+	}
+
+	if createArgs.HostMoID != "" {
+		instantCloneSpec.Location.Host = &vimTypes.ManagedObjectReference{
+			Type:  "HostSystem",
+			Value: createArgs.HostMoID,
+		}
+	}
+
+	if createArgs.StorageProfileID != "" {
+		instantCloneSpec.Location.Profile = []vimTypes.BaseVirtualMachineProfileSpec{
+			&vimTypes.VirtualMachineDefinedProfileSpec{ProfileId: createArgs.StorageProfileID},
+		}
+	} else if createArgs.DatastoreMoID != "" {
+		instantCloneSpec.Location.Datastore = &vimTypes.ManagedObjectReference{
+			Type:  "Datastore",
+			Value: createArgs.DatastoreMoID,
+		}
+	}
+
+	extraConfig := make(map[string]string)
+	for k, v := range createArgs.VMMetadata.Data {
+		if strings.HasPrefix(k, constants.ExtraConfigGuestInfoPrefix) {
+			extraConfig[k] = v
+		}
+	}
+	instantCloneSpec.Config = MergeExtraConfig(nil, extraConfig)
+
+	return instantCloneSpec
+}
+
+// InstantCloneEthCardDeviceChanges returns the device changes that connect the ethernet cards of the instant
+// clone, which are those of the source VM, to the networks of the VM's network interfaces. An instant clone
+// cannot add devices, so the source VM must have an ethernet card for each network interface.
+func InstantCloneEthCardDeviceChanges(
+	srcEthCards object.VirtualDeviceList,
+	netIfList network.InterfaceInfoList) ([]vimTypes.BaseVirtualDeviceConfigSpec, error) {
+
+	if len(netIfList) > len(srcEthCards) {
+		return nil, fmt.Errorf("instant clone source VM has %d network interfaces but %d are required",
+			len(srcEthCards), len(netIfList))
+	}
+
+	deviceChanges := make([]vimTypes.BaseVirtualDeviceConfigSpec, 0, len(netIfList))
+	for i, info := range netIfList {
+		ethCard := srcEthCards[i].(vimTypes.BaseVirtualEthernetCard).GetVirtualEthernetCard()
+		expectedEthCard := info.Device.(vimTypes.BaseVirtualEthernetCard).GetVirtualEthernetCard()
+
+		ethCard.Backing = expectedEthCard.Backing
+		ethCard.ExternalId = expectedEthCard.ExternalId
+		if mac := expectedEthCard.MacAddress; mac != "" {
+			ethCard.MacAddress = mac
+			ethCard.AddressType = string(vimTypes.VirtualEthernetCardMacTypeManual)
+		} else {
+			// The instant clone must not have the MAC address of its source.
+			ethCard.MacAddress = ""
+			ethCard.AddressType = string(vimTypes.VirtualEthernetCardMacTypeGenerated)
+		}
+
+		deviceChanges = append(deviceChanges, &vimTypes.VirtualDeviceConfigSpec{
+			Operation: vimTypes.VirtualDeviceConfigSpecOperationEdit,
+			Device:    srcEthCards[i],
+		})
+	}
+
+	return deviceChanges, nil
+}
+
+func (s *Session) cloneVM(
+	vmCtx context.VirtualMachineContext,
+	createArgs *VMCreateArgs,
+	srcVM *object.VirtualMachine) (*object.VirtualMachine, error) {
+
 	cloneSpec, err := s.createCloneSpec(vmCtx, createArgs, srcVM)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create CloneSpec")
@@ -121,7 +279,7 @@ func (s *Session) cloneVMFromInventory(
 
 	vmMoRef, err := res.NewVMFromObject(srcVM).Clone(vmCtx, folder, cloneSpec)
 	if err != nil {
-		return nil, errors.Wrapf(err, "clone from source VM %s failed", srcVMName)
+		return nil, errors.Wrapf(err, "clone from source VM %s failed", srcVM.Reference().Value)
 	}
 
 	return object.NewVirtualMachine(s.Client.VimClient(), *vmMoRef), nil
@@ -154,6 +312,33 @@ func (s *Session) CreateVirtualMachine(
 	vmCtx context.VirtualMachineContext,
 	createArgs *VMCreateArgs) (*object.VirtualMachine, error) {
 
+	vcVM, err := s.createVirtualMachine(vmCtx, createArgs)
+	if err != nil || !imagevolume.IsConfigured(vmCtx.VM) {
+		return vcVM, err
+	}
+
+	if err := s.detachImageBootDisk(vmCtx, vcVM); err != nil {
+		// Delete the VM so it is created again instead of later booting from the disk of the image.
+		if deleteErr := virtualmachine.DeleteVirtualMachine(vmCtx, vcVM); deleteErr != nil {
+			vmCtx.Logger.Error(deleteErr, "Failed to delete VM after failing to detach its boot disk")
+		}
+		return nil, err
+	}
+
+	return vcVM, nil
+}
+
+func (s *Session) createVirtualMachine(
+	vmCtx context.VirtualMachineContext,
+	createArgs *VMCreateArgs) (*object.VirtualMachine, error) {
+
+	if createArgs.SourceVMMoID != "" {
+		if createArgs.InstantClone {
+			return s.instantCloneVMFromVM(vmCtx, createArgs)
+		}
+		return s.cloneVMFromVM(vmCtx, createArgs)
+	}
+
 	// The ContentLibraryUUID can be empty when we want to clone from inventory VMs. This is
 	// not a supported workflow but we have tests that use this.
 	if createArgs.ContentLibraryUUID != "" {
@@ -166,6 +351,82 @@ func (s *Session) CreateVirtualMachine(
 	return s.cloneVMFromInventory(vmCtx, createArgs)
 }
 
+// detachImageBootDisk removes the boot disk deployed from the image from a VM that boots from its image volume.
+// When the PVC of the image volume does not exist yet, the disk is kept and its URL path is recorded so that the
+// volume controller registers it with CNS to populate the PVC. Otherwise, the VM boots from the existing PVC so
+// the disk is deleted.
+func (s *Session) detachImageBootDisk(
+	vmCtx context.VirtualMachineContext,
+	vcVM *object.VirtualMachine) error {
+
+	devices, err := vcVM.Device(vmCtx)
+	if err != nil {
+		return fmt.Errorf("failed to get VM devices: %w", err)
+	}
+
+	bootDisk := getBootDisk(devices.SelectByType((*vimTypes.VirtualDisk)(nil)))
+	if bootDisk == nil {
+		return errors.New("could not find the boot disk")
+	}
+
+	volume := imagevolume.GetVolume(vmCtx.VM)
+	pvcKey := ctrlruntime.ObjectKey{Namespace: vmCtx.VM.Namespace, Name: volume.PersistentVolumeClaim.ClaimName}
+
+	err = s.K8sClient.Get(vmCtx, pvcKey, &corev1.PersistentVolumeClaim{})
+	if err == nil {
+		vmCtx.Logger.Info("Deleting boot disk of the image since the image volume PVC already exists",
+			"pvcName", pvcKey.Name)
+		return vcVM.RemoveDevice(vmCtx, false, bootDisk)
+	} else if !apierrs.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to get image volume PVC %s", pvcKey.Name)
+	}
+
+	backing, ok := bootDisk.Backing.(vimTypes.BaseVirtualDeviceFileBackingInfo)
+	if !ok {
+		return errors.Errorf("boot disk has unsupported backing %T", bootDisk.Backing)
+	}
+
+	fileName := backing.GetVirtualDeviceFileBackingInfo().FileName
+	var dsPath object.DatastorePath
+	if !dsPath.FromString(fileName) {
+		return errors.Errorf("invalid boot disk file name %q", fileName)
+	}
+
+	datastore, err := s.Finder.Datastore(vmCtx, dsPath.Datastore)
+	if err != nil {
+		return errors.Wrapf(err, "failed to find datastore %s of the boot disk", dsPath.Datastore)
+	}
+
+	// The detached disk can no longer be found from the VM, so its URL path is persisted before the disk is
+	// detached instead of with the rest of the VM changes at the end of the reconcile.
+	diskURLPath := datastore.NewURL(dsPath.Path).String()
+	vm := vmCtx.VM.DeepCopy()
+	vmPatch := ctrlruntime.MergeFrom(vm.DeepCopy())
+	if vm.Annotations == nil {
+		vm.Annotations = map[string]string{}
+	}
+	vm.Annotations[constants.BootDiskURLPathAnnotation] = diskURLPath
+	if err := s.K8sClient.Patch(vmCtx, vm, vmPatch); err != nil {
+		return errors.Wrap(err, "failed to record the URL path of the boot disk")
+	}
+
+	if vmCtx.VM.Annotations == nil {
+		vmCtx.VM.Annotations = map[string]string{}
+	}
+	vmCtx.VM.Annotations[constants.BootDiskURLPathAnnotation] = diskURLPath
+
+	vmCtx.Logger.Info("Detaching boot disk of the image to populate the image volume PVC",
+		"pvcName", pvcKey.Name, "fileName", fileName)
+	if err := vcVM.RemoveDevice(vmCtx, true, bootDisk); err != nil {
+		// The VM is deleted with its boot disk, so the recorded URL path is cleared with the rest of the
+		// VM changes.
+		delete(vmCtx.VM.Annotations, constants.BootDiskURLPathAnnotation)
+		return err
+	}
+
+	return nil
+}
+
 func (s *Session) createCloneSpec(
 	vmCtx context.VirtualMachineContext,
 	createArgs *VMCreateArgs,
@@ -176,12 +437,20 @@ func (s *Session) createCloneSpec(
 		Memory: pointer.Bool(false), // No full memory clones.
 	}
 
-	virtualDevices, err := srcVM.Device(vmCtx)
+	if createArgs.SourceSnapshotMoID != "" {
+		cloneSpec.Snapshot = &vimTypes.ManagedObjectReference{
+			Type:  "VirtualMachineSnapshot",
+			Value: createArgs.SourceSnapshotMoID,
+		}
+	}
+
+	virtualDevices, err := cloneVMSourceDevices(vmCtx, srcVM, createArgs.SourceSnapshotMoID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get VM devices: %w", err)
+		return nil, err
 	}
 
-	virtualDisks := virtualDevices.SelectByType((*vimTypes.VirtualDisk)(nil))
+	virtualDisks, removeDiskChanges := CloneVMDiskDeviceChanges(virtualDevices)
+	cloneSpec.Config.DeviceChange = append(cloneSpec.Config.DeviceChange, removeDiskChanges...)
 
 	diskDeviceChanges, err := updateVirtualDiskDeviceChanges(vmCtx, virtualDisks)
 	if err != nil {
@@ -239,6 +508,55 @@ func (s *Session) createCloneSpec(
 	return cloneSpec, nil
 }
 
+// cloneVMSourceDevices returns the devices of the source of the clone. A linked clone is created from the
+// snapshot, whose hardware may differ from the current hardware of the source VM.
+func cloneVMSourceDevices(
+	vmCtx context.VirtualMachineContext,
+	srcVM *object.VirtualMachine,
+	snapshotMoID string) (object.VirtualDeviceList, error) {
+
+	if snapshotMoID == "" {
+		virtualDevices, err := srcVM.Device(vmCtx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get VM devices: %w", err)
+		}
+		return virtualDevices, nil
+	}
+
+	snapshotRef := vimTypes.ManagedObjectReference{Type: "VirtualMachineSnapshot", Value: snapshotMoID}
+	snapshot := object.NewCommon(srcVM.Client(), snapshotRef)
+
+	var moSnapshot mo.VirtualMachineSnapshot
+	if err := snapshot.Properties(vmCtx, snapshotRef, []string{"config.hardware.device"}, &moSnapshot); err != nil {
+		return nil, fmt.Errorf("failed to get VM snapshot devices: %w", err)
+	}
+
+	return moSnapshot.Config.Hardware.Device, nil
+}
+
+// CloneVMDiskDeviceChanges returns the disks of the source that are copied to the clone, and the device changes
+// that remove the other disks from the clone. The First Class Disks of the source's PVC volumes are not copied:
+// they are managed by CNS and the user that clones the VM may not have access to the data of the PVCs.
+func CloneVMDiskDeviceChanges(
+	virtualDevices object.VirtualDeviceList) (object.VirtualDeviceList, []vimTypes.BaseVirtualDeviceConfigSpec) {
+
+	var disks object.VirtualDeviceList
+	var deviceChanges []vimTypes.BaseVirtualDeviceConfigSpec
+
+	for _, dev := range virtualDevices.SelectByType((*vimTypes.VirtualDisk)(nil)) {
+		if dev.(*vimTypes.VirtualDisk).VDiskId != nil {
+			deviceChanges = append(deviceChanges, &vimTypes.VirtualDeviceConfigSpec{
+				Operation: vimTypes.VirtualDeviceConfigSpecOperationRemove,
+				Device:    dev,
+			})
+			continue
+		}
+		disks = append(disks, dev)
+	}
+
+	return disks, deviceChanges
+}
+
 func cloneVMDiskLocators(
 	disks object.VirtualDeviceList,
 	createArgs *VMCreateArgs,
@@ -246,13 +564,25 @@ func cloneVMDiskLocators(
 
 	diskLocators := make([]vimTypes.VirtualMachineRelocateSpecDiskLocator, 0, len(disks))
 
+	// TODO: Check if policy is encrypted and use correct DiskMoveType
+	diskMoveType := vimTypes.VirtualMachineRelocateDiskMoveOptionsMoveChildMostDiskBacking
+	if createArgs.SourceSnapshotMoID != "" {
+		// Linked clone: the clone's disks are delta disks on top of the snapshot's disks.
+		diskMoveType = vimTypes.VirtualMachineRelocateDiskMoveOptionsCreateNewChildDiskBacking
+	}
+
 	for _, disk := range disks {
 		locator := vimTypes.VirtualMachineRelocateSpecDiskLocator{
-			DiskId:    disk.GetVirtualDevice().Key,
-			Datastore: *location.Datastore,
-			Profile:   location.Profile,
-			// TODO: Check if policy is encrypted and use correct DiskMoveType
-			DiskMoveType: string(vimTypes.VirtualMachineRelocateDiskMoveOptionsMoveChildMostDiskBacking),
+			DiskId:       disk.GetVirtualDevice().Key,
+			Datastore:    *location.Datastore,
+			Profile:      location.Profile,
+			DiskMoveType: string(diskMoveType),
+		}
+
+		if createArgs.SourceSnapshotMoID != "" {
+			// The backing of a child disk cannot be changed.
+			diskLocators = append(diskLocators, locator)
+			continue
 		}
 
 		if backing, ok := disk.(*vimTypes.VirtualDisk).Backing.(*vimTypes.VirtualDiskFlatVer2BackingInfo); ok {
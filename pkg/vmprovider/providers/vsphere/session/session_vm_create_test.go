@@ -0,0 +1,195 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package session_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/vmware/govmomi/object"
+	vimTypes "github.com/vmware/govmomi/vim25/types"
+
+	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/network"
+	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/session"
+)
+
+var _ = Describe("CreateInstantCloneSpec", func() {
+	var (
+		createArgs *session.VMCreateArgs
+		spec       *vimTypes.VirtualMachineInstantCloneSpec
+	)
+
+	BeforeEach(func() {
+		createArgs = &session.VMCreateArgs{
+			ResourcePoolMoID: "resgroup-1",
+			FolderMoID:       "group-v1",
+			DatastoreMoID:    "datastore-1",
+			VMMetadata: session.VMMetadata{
+				Data: map[string]string{
+					"guestinfo.runner.token": "abc",
+					"runner.name":            "ci-1",
+				},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		spec = session.CreateInstantCloneSpec("my-vm", createArgs)
+	})
+
+	It("returns the instant clone spec", func() {
+		Expect(spec.Name).To(Equal("my-vm"))
+		Expect(spec.Location.Pool.Value).To(Equal("resgroup-1"))
+		Expect(spec.Location.Folder.Value).To(Equal("group-v1"))
+		Expect(spec.Location.Host).To(BeNil())
+		Expect(spec.Location.Datastore.Value).To(Equal("datastore-1"))
+		Expect(spec.Location.Profile).To(BeEmpty())
+	})
+
+	It("only includes the guestinfo metadata keys in the ExtraConfig", func() {
+		Expect(spec.Config).To(HaveLen(1))
+		option := spec.Config[0].GetOptionValue()
+		Expect(option.Key).To(Equal("guestinfo.runner.token"))
+		Expect(option.Value).To(Equal("abc"))
+	})
+
+	Context("with a host and storage profile", func() {
+		BeforeEach(func() {
+			createArgs.HostMoID = "host-1"
+			createArgs.StorageProfileID = "profile-1"
+		})
+
+		It("places the VM on the host with the storage profile", func() {
+			Expect(spec.Location.Host.Value).To(Equal("host-1"))
+			Expect(spec.Location.Datastore).To(BeNil())
+			Expect(spec.Location.Profile).To(HaveLen(1))
+			profile, ok := spec.Location.Profile[0].(*vimTypes.VirtualMachineDefinedProfileSpec)
+			Expect(ok).To(BeTrue())
+			Expect(profile.ProfileId).To(Equal("profile-1"))
+		})
+	})
+})
+
+var _ = Describe("InstantCloneEthCardDeviceChanges", func() {
+	var (
+		srcEthCards   object.VirtualDeviceList
+		netIfList     network.InterfaceInfoList
+		deviceChanges []vimTypes.BaseVirtualDeviceConfigSpec
+		err           error
+	)
+
+	BeforeEach(func() {
+		srcEthCards = object.VirtualDeviceList{
+			&vimTypes.VirtualVmxnet3{
+				VirtualVmxnet: vimTypes.VirtualVmxnet{
+					VirtualEthernetCard: vimTypes.VirtualEthernetCard{
+						VirtualDevice: vimTypes.VirtualDevice{
+							Key: 4000,
+							Backing: &vimTypes.VirtualEthernetCardNetworkBackingInfo{
+								VirtualDeviceDeviceBackingInfo: vimTypes.VirtualDeviceDeviceBackingInfo{DeviceName: "src-network"},
+							},
+						},
+						AddressType: string(vimTypes.VirtualEthernetCardMacTypeAssigned),
+						MacAddress:  "00:50:56:00:00:01",
+					},
+				},
+			},
+		}
+
+		netIfList = network.InterfaceInfoList{
+			{
+				Device: &vimTypes.VirtualVmxnet3{
+					VirtualVmxnet: vimTypes.VirtualVmxnet{
+						VirtualEthernetCard: vimTypes.VirtualEthernetCard{
+							VirtualDevice: vimTypes.VirtualDevice{
+								Backing: &vimTypes.VirtualEthernetCardNetworkBackingInfo{
+									VirtualDeviceDeviceBackingInfo: vimTypes.VirtualDeviceDeviceBackingInfo{DeviceName: "vm-network"},
+								},
+							},
+							ExternalId: "interface-id",
+						},
+					},
+				},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		deviceChanges, err = session.InstantCloneEthCardDeviceChanges(srcEthCards, netIfList)
+	})
+
+	It("edits the ethernet card of the source to connect it to the network of the interface", func() {
+		Expect(err).ToNot(HaveOccurred())
+		Expect(deviceChanges).To(HaveLen(1))
+
+		configSpec := deviceChanges[0].GetVirtualDeviceConfigSpec()
+		Expect(configSpec.Operation).To(Equal(vimTypes.VirtualDeviceConfigSpecOperationEdit))
+		ethCard := configSpec.Device.(vimTypes.BaseVirtualEthernetCard).GetVirtualEthernetCard()
+		Expect(ethCard.Key).To(Equal(int32(4000)))
+		Expect(ethCard.ExternalId).To(Equal("interface-id"))
+		backing := ethCard.Backing.(*vimTypes.VirtualEthernetCardNetworkBackingInfo)
+		Expect(backing.DeviceName).To(Equal("vm-network"))
+		Expect(ethCard.MacAddress).To(BeEmpty())
+		Expect(ethCard.AddressType).To(Equal(string(vimTypes.VirtualEthernetCardMacTypeGenerated)))
+	})
+
+	Context("when the network interface has a MAC address", func() {
+		BeforeEach(func() {
+			ethCard := netIfList[0].Device.(vimTypes.BaseVirtualEthernetCard).GetVirtualEthernetCard()
+			ethCard.MacAddress = "00:50:56:00:00:02"
+		})
+
+		It("sets the MAC address of the ethernet card", func() {
+			Expect(err).ToNot(HaveOccurred())
+			ethCard := deviceChanges[0].GetVirtualDeviceConfigSpec().Device.(vimTypes.BaseVirtualEthernetCard).GetVirtualEthernetCard()
+			Expect(ethCard.MacAddress).To(Equal("00:50:56:00:00:02"))
+			Expect(ethCard.AddressType).To(Equal(string(vimTypes.VirtualEthernetCardMacTypeManual)))
+		})
+	})
+
+	Context("when the source has fewer ethernet cards than network interfaces", func() {
+		BeforeEach(func() {
+			srcEthCards = nil
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(deviceChanges).To(BeEmpty())
+		})
+	})
+})
+
+var _ = Describe("CloneVMDiskDeviceChanges", func() {
+	var (
+		devices       object.VirtualDeviceList
+		disks         object.VirtualDeviceList
+		deviceChanges []vimTypes.BaseVirtualDeviceConfigSpec
+	)
+
+	BeforeEach(func() {
+		devices = object.VirtualDeviceList{
+			&vimTypes.VirtualDisk{VirtualDevice: vimTypes.VirtualDevice{Key: 2000}},
+			&vimTypes.VirtualDisk{
+				VirtualDevice: vimTypes.VirtualDevice{Key: 2001},
+				VDiskId:       &vimTypes.ID{Id: "fcd-1"},
+			},
+			&vimTypes.VirtualCdrom{VirtualDevice: vimTypes.VirtualDevice{Key: 3000}},
+		}
+	})
+
+	JustBeforeEach(func() {
+		disks, deviceChanges = session.CloneVMDiskDeviceChanges(devices)
+	})
+
+	It("copies the disks that are not First Class Disks", func() {
+		Expect(disks).To(HaveLen(1))
+		Expect(disks[0].GetVirtualDevice().Key).To(Equal(int32(2000)))
+	})
+
+	It("removes the First Class Disks from the clone", func() {
+		Expect(deviceChanges).To(HaveLen(1))
+		configSpec := deviceChanges[0].GetVirtualDeviceConfigSpec()
+		Expect(configSpec.Operation).To(Equal(vimTypes.VirtualDeviceConfigSpecOperationRemove))
+		Expect(configSpec.Device.GetVirtualDevice().Key).To(Equal(int32(2001)))
+	})
+})
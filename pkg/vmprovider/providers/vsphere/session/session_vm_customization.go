@@ -1,16 +1,20 @@
-// Copyright (c) 2021-2022 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2021-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package session
 
 import (
 	"bytes"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 	"text/template"
+	"time"
 
+	"github.com/google/uuid"
 	vimTypes "github.com/vmware/govmomi/vim25/types"
 	"gopkg.in/yaml.v2"
 	apiEquality "k8s.io/apimachinery/pkg/api/equality"
@@ -19,9 +23,12 @@ import (
 
 	"github.com/vmware-tanzu/vm-operator/api/v1alpha1"
 
+	"github.com/vmware-tanzu/vm-operator/pkg/conditions"
 	"github.com/vmware-tanzu/vm-operator/pkg/context"
+	"github.com/vmware-tanzu/vm-operator/pkg/lib"
 	"github.com/vmware-tanzu/vm-operator/pkg/util"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/constants"
+	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/ignition"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/internal"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/network"
 	res "github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/resources"
@@ -38,6 +45,45 @@ func IsCustomizationPendingExtraConfig(extraConfig []vimTypes.BaseOptionValue) b
 	return false
 }
 
+// IsCustomizationPendingStale returns true if the VM's guest customization has been pending for longer than the
+// timeout. A VM without a valid CustomizationPendingSinceAnnotation is never considered stale.
+func IsCustomizationPendingStale(vm *v1alpha1.VirtualMachine, now time.Time, timeout time.Duration) bool {
+	since, err := time.Parse(time.RFC3339, vm.Annotations[constants.CustomizationPendingSinceAnnotation])
+	if err != nil {
+		return false
+	}
+	return now.Sub(since) > timeout
+}
+
+// IsCustomizationRerunRequested returns true if a fresh guest customization was requested for the VM.
+func IsCustomizationRerunRequested(vm *v1alpha1.VirtualMachine) bool {
+	_, ok := vm.Annotations[constants.RerunCustomizationAnnotation]
+	return ok
+}
+
+// clearPendingCustomization clears the VM's pending guest customization so it can be customized again.
+func clearPendingCustomization(vmCtx context.VirtualMachineContext, resVM *res.VirtualMachine) error {
+	configSpec := &vimTypes.VirtualMachineConfigSpec{
+		ExtraConfig: []vimTypes.BaseOptionValue{
+			&vimTypes.OptionValue{Key: constants.GOSCPendingExtraConfigKey, Value: ""},
+		},
+	}
+
+	vmCtx.Logger.Info("Clearing pending customization")
+	if err := resVM.Reconfigure(vmCtx, configSpec); err != nil {
+		vmCtx.Logger.Error(err, "clearing pending customization failed")
+		return err
+	}
+	return nil
+}
+
+func setVMAnnotation(vm *v1alpha1.VirtualMachine, key, value string) {
+	if vm.Annotations == nil {
+		vm.Annotations = map[string]string{}
+	}
+	vm.Annotations[key] = value
+}
+
 func isCustomizationPendingError(err error) bool {
 	if te, ok := err.(task.Error); ok {
 		if _, ok := te.Fault().(*vimTypes.CustomizationPending); ok {
@@ -62,6 +108,117 @@ func GetLinuxPrepCustSpec(vmName string, updateArgs VMUpdateArgs) *vimTypes.Cust
 	}
 }
 
+// GetSysprepCustSpec returns the Sysprep customization spec of a Windows guest. The spec uses
+// the unattend.xml answer file in the VM Metadata when present, and otherwise a Sysprep identity
+// built from the individual VM Metadata keys.
+func GetSysprepCustSpec(vmName string, updateArgs VMUpdateArgs) (*vimTypes.CustomizationSpec, error) {
+	data := updateArgs.VMMetadata.Data
+
+	var identity vimTypes.BaseCustomizationIdentitySettings
+	if unattend := data[constants.SysprepUnattendKey]; unattend != "" {
+		identity = &vimTypes.CustomizationSysprepText{
+			Value: unattend,
+		}
+	} else {
+		sysprep, err := getSysprepIdentity(vmName, data)
+		if err != nil {
+			return nil, err
+		}
+		identity = sysprep
+	}
+
+	return &vimTypes.CustomizationSpec{
+		Identity: identity,
+		GlobalIPSettings: vimTypes.CustomizationGlobalIPSettings{
+			DnsSuffixList: updateArgs.SearchSuffixes,
+		},
+		NicSettingMap: getSysprepInterfaceCustomizations(updateArgs),
+	}, nil
+}
+
+func getSysprepIdentity(vmName string, data map[string]string) (*vimTypes.CustomizationSysprep, error) {
+	valueOrDefault := func(key, defaultValue string) string {
+		if v := data[key]; v != "" {
+			return v
+		}
+		return defaultValue
+	}
+
+	timeZone := int32(constants.SysprepTimeZoneDefault)
+	if tz := data[constants.SysprepTimeZoneKey]; tz != "" {
+		v, err := strconv.ParseInt(tz, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Sysprep %s %q: %v", constants.SysprepTimeZoneKey, tz, err)
+		}
+		timeZone = int32(v)
+	}
+
+	// Windows computer names cannot be longer than 15 characters, nor end with a hyphen.
+	computerName := vmName
+	if len(computerName) > constants.SysprepComputerNameMaxLength {
+		computerName = strings.TrimRight(computerName[:constants.SysprepComputerNameMaxLength], "-")
+	}
+
+	sysprep := &vimTypes.CustomizationSysprep{
+		GuiUnattended: vimTypes.CustomizationGuiUnattended{
+			TimeZone: timeZone,
+		},
+		UserData: vimTypes.CustomizationUserData{
+			FullName: valueOrDefault(constants.SysprepFullNameKey, constants.SysprepFullNameDefault),
+			OrgName:  valueOrDefault(constants.SysprepOrgNameKey, constants.SysprepOrgNameDefault),
+			ComputerName: &vimTypes.CustomizationFixedName{
+				Name: computerName,
+			},
+			ProductId: data[constants.SysprepProductIDKey],
+		},
+	}
+
+	if password := data[constants.SysprepAdminPasswordKey]; password != "" {
+		sysprep.GuiUnattended.Password = &vimTypes.CustomizationPassword{
+			Value:     password,
+			PlainText: true,
+		}
+	}
+
+	// A workgroup and a domain are mutually exclusive.
+	if domain := data[constants.SysprepJoinDomainKey]; domain != "" {
+		sysprep.Identification.JoinDomain = domain
+		sysprep.Identification.DomainAdmin = data[constants.SysprepDomainAdminKey]
+		if password := data[constants.SysprepDomainAdminPasswordKey]; password != "" {
+			sysprep.Identification.DomainAdminPassword = &vimTypes.CustomizationPassword{
+				Value:     password,
+				PlainText: true,
+			}
+		}
+	} else {
+		sysprep.Identification.JoinWorkgroup = valueOrDefault(constants.SysprepJoinWorkgroupKey, constants.SysprepJoinWorkgroupDefault)
+	}
+
+	return sysprep, nil
+}
+
+// getSysprepInterfaceCustomizations returns the adapter mappings of a Windows guest. Unlike
+// Linux guests, Windows guests do not use the global DNS servers so they are set on each
+// adapter that is not configured with DHCP.
+func getSysprepInterfaceCustomizations(updateArgs VMUpdateArgs) []vimTypes.CustomizationAdapterMapping {
+	mappings := updateArgs.NetIfList.GetInterfaceCustomizations()
+
+	dnsDomain := ""
+	if len(updateArgs.SearchSuffixes) > 0 {
+		dnsDomain = updateArgs.SearchSuffixes[0]
+	}
+
+	for i := range mappings {
+		if _, ok := mappings[i].Adapter.Ip.(*vimTypes.CustomizationDhcpIpGenerator); ok {
+			continue
+		}
+		mappings[i].Adapter.DnsServerList = updateArgs.DNSServers
+		mappings[i].Adapter.DnsDomain = dnsDomain
+	}
+
+	return mappings
+}
+
 type CloudInitMetadata struct {
 	InstanceID    string          `yaml:"instance-id,omitempty"`
 	LocalHostname string          `yaml:"local-hostname,omitempty"`
@@ -74,8 +231,13 @@ func GetCloudInitMetadata(vm *v1alpha1.VirtualMachine,
 	netplan network.Netplan,
 	data map[string]string) (string, error) {
 
+	instanceID := vm.Annotations[constants.CloudInitInstanceIDAnnotation]
+	if instanceID == "" {
+		instanceID = string(vm.UID)
+	}
+
 	metadataObj := &CloudInitMetadata{
-		InstanceID:    string(vm.UID),
+		InstanceID:    instanceID,
 		LocalHostname: vm.Name,
 		Hostname:      vm.Name,
 		Network:       netplan,
@@ -172,6 +334,78 @@ func GetCloudInitGuestInfoCustSpec(
 	return configSpec, nil
 }
 
+// cloudInitGrowpartVendordata is the cloud-init vendordata that grows the root partition and filesystem to
+// fill the boot disk. Unlike userdata, it is merged with any userdata the VM has.
+const cloudInitGrowpartVendordata = `#cloud-config
+growpart:
+  mode: auto
+  devices: ["/"]
+resize_rootfs: true
+`
+
+// SetCloudInitGuestInfoGrowpartVendordata adds the vendordata that hints cloud-init to grow the root partition
+// and filesystem to the ConfigSpec of the GuestInfo transport.
+func SetCloudInitGuestInfoGrowpartVendordata(
+	config *vimTypes.VirtualMachineConfigInfo,
+	configSpec *vimTypes.VirtualMachineConfigSpec) error {
+
+	encodedVendordata, err := EncodeGzipBase64(cloudInitGrowpartVendordata)
+	if err != nil {
+		return fmt.Errorf("encoding cloud-init vendordata failed %v", err)
+	}
+
+	extraConfig := map[string]string{
+		constants.CloudInitGuestInfoVendordata:         encodedVendordata,
+		constants.CloudInitGuestInfoVendordataEncoding: "gzip+base64",
+	}
+	configSpec.ExtraConfig = append(configSpec.ExtraConfig, MergeExtraConfig(config.ExtraConfig, extraConfig)...)
+
+	return nil
+}
+
+func GetIgnitionGuestInfoCustSpec(
+	netplan network.Netplan,
+	config *vimTypes.VirtualMachineConfigInfo,
+	updateArgs VMUpdateArgs) (*vimTypes.VirtualMachineConfigSpec, error) {
+
+	var ignitionConfig []byte
+
+	if data := updateArgs.VMMetadata.Data[constants.IgnitionConfigKey]; data != "" {
+		// Ensure the data is normalized first to plain-text.
+		plainText, err := util.TryToDecodeBase64Gzip([]byte(data))
+		if err != nil {
+			return nil, fmt.Errorf("decoding Ignition config failed %v", err)
+		}
+		ignitionConfig = []byte(plainText)
+	} else if data := updateArgs.VMMetadata.Data[constants.ButaneConfigKey]; data != "" {
+		plainText, err := util.TryToDecodeBase64Gzip([]byte(data))
+		if err != nil {
+			return nil, fmt.Errorf("decoding Butane config failed %v", err)
+		}
+		ignitionConfig, err = ignition.TranspileButane([]byte(plainText))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("the Ignition transport requires either the %q or %q key",
+			constants.IgnitionConfigKey, constants.ButaneConfigKey)
+	}
+
+	ignitionConfig, err := ignition.MergeNetworkConfig(ignitionConfig, netplan)
+	if err != nil {
+		return nil, err
+	}
+
+	extraConfig := map[string]string{
+		constants.IgnitionGuestInfoConfigData:         base64.StdEncoding.EncodeToString(ignitionConfig),
+		constants.IgnitionGuestInfoConfigDataEncoding: "base64",
+	}
+
+	configSpec := &vimTypes.VirtualMachineConfigSpec{}
+	configSpec.ExtraConfig = MergeExtraConfig(config.ExtraConfig, extraConfig)
+	return configSpec, nil
+}
+
 func GetExtraConfigCustSpec(
 	config *vimTypes.VirtualMachineConfigInfo,
 	updateArgs VMUpdateArgs) *vimTypes.VirtualMachineConfigSpec {
@@ -221,7 +455,12 @@ func customizeCloudInit(
 	}
 
 	netplan := updateArgs.NetIfList.GetNetplan(
-		ethCards, updateArgs.DNSServers, updateArgs.SearchSuffixes)
+		ethCards, updateArgs.DNSServers, updateArgs.SearchSuffixes, vmCtx.VM.Spec.NetworkBonds)
+
+	if IsCustomizationRerunRequested(vmCtx.VM) {
+		// Cloud-init only runs again when the instance ID changes.
+		setVMAnnotation(vmCtx.VM, constants.CloudInitInstanceIDAnnotation, uuid.New().String())
+	}
 
 	cloudInitMetadata, err := GetCloudInitMetadata(vmCtx.VM, netplan, updateArgs.VMMetadata.Data)
 	if err != nil {
@@ -238,6 +477,9 @@ func customizeCloudInit(
 		fallthrough
 	default:
 		configSpec, err = GetCloudInitGuestInfoCustSpec(cloudInitMetadata, config, updateArgs)
+		if err == nil && vmCtx.VM.Spec.BootDiskCapacity != nil {
+			err = SetCloudInitGuestInfoGrowpartVendordata(config, configSpec)
+		}
 	}
 
 	if err != nil {
@@ -247,6 +489,23 @@ func customizeCloudInit(
 	return configSpec, custSpec, nil
 }
 
+func customizeIgnition(
+	vmCtx context.VirtualMachineContext,
+	resVM *res.VirtualMachine,
+	config *vimTypes.VirtualMachineConfigInfo,
+	updateArgs VMUpdateArgs) (*vimTypes.VirtualMachineConfigSpec, error) {
+
+	ethCards, err := resVM.GetNetworkDevices(vmCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	netplan := updateArgs.NetIfList.GetNetplan(
+		ethCards, updateArgs.DNSServers, updateArgs.SearchSuffixes, vmCtx.VM.Spec.NetworkBonds)
+
+	return GetIgnitionGuestInfoCustSpec(netplan, config, updateArgs)
+}
+
 func (s *Session) customize(
 	vmCtx context.VirtualMachineContext,
 	resVM *res.VirtualMachine,
@@ -272,6 +531,10 @@ func (s *Session) customize(
 	case v1alpha1.VirtualMachineMetadataExtraConfigTransport:
 		configSpec = GetExtraConfigCustSpec(config, updateArgs)
 		custSpec = GetLinuxPrepCustSpec(vmCtx.VM.Name, updateArgs)
+	case v1alpha1.VirtualMachineMetadataSysprepTransport:
+		custSpec, err = GetSysprepCustSpec(vmCtx.VM.Name, updateArgs)
+	case v1alpha1.VirtualMachineMetadataIgnitionTransport:
+		configSpec, err = customizeIgnition(vmCtx, resVM, config, updateArgs)
 	default:
 		custSpec = GetLinuxPrepCustSpec(vmCtx.VM.Name, updateArgs)
 	}
@@ -296,44 +559,127 @@ func (s *Session) customize(
 			vmCtx.Logger.Info("Skipping vsphere customization because of vsphere-customization bypass annotation")
 			return nil
 		}
-		if IsCustomizationPendingExtraConfig(config.ExtraConfig) {
-			vmCtx.Logger.Info("Skipping customization because it is already pending")
-			// TODO: We should really determine if the pending customization is stale, clear it
-			// if so, and then re-customize. Otherwise, the Customize call could perpetually fail
-			// preventing power on.
-			return nil
+		if err := ensureCustomizationNotPending(vmCtx, resVM, config); err != nil {
+			if errors.Is(err, errCustomizationPending) {
+				return nil
+			}
+			return err
+		}
+		logSpec := *custSpec
+		if transport == v1alpha1.VirtualMachineMetadataSysprepTransport {
+			// The Sysprep identity may contain passwords.
+			logSpec.Identity = nil
 		}
-		vmCtx.Logger.Info("Customizing VM", "customizationSpec", *custSpec)
+		vmCtx.Logger.Info("Customizing VM", "customizationSpec", logSpec)
 		if err := resVM.Customize(vmCtx, *custSpec); err != nil {
-			// isCustomizationPendingExtraConfig() above is suppose to prevent this error, but
+			// ensureCustomizationNotPending() above is suppose to prevent this error, but
 			// handle it explicitly here just in case so VM reconciliation can proceed.
 			if !isCustomizationPendingError(err) {
 				return err
 			}
 		}
+		setVMAnnotation(vmCtx.VM, constants.CustomizationPendingSinceAnnotation, time.Now().UTC().Format(time.RFC3339))
+	}
+
+	// The VAppConfig and Ignition transports do not customize the guest, so there is nothing to rerun. The
+	// CloudInit transport reruns through the new instance ID even when it does not use a customization spec.
+	if IsCustomizationRerunRequested(vmCtx.VM) && (custSpec != nil || transport == v1alpha1.VirtualMachineMetadataCloudInitTransport) {
+		delete(vmCtx.VM.Annotations, constants.RerunCustomizationAnnotation)
+		if conditions.GetReason(vmCtx.VM, v1alpha1.GuestCustomizationCondition) != v1alpha1.GuestCustomizationStaleReason {
+			conditions.MarkFalse(vmCtx.VM, v1alpha1.GuestCustomizationCondition, v1alpha1.GuestCustomizationRerunReason,
+				v1alpha1.ConditionSeverityInfo, "A fresh guest customization was applied as requested")
+		}
 	}
 
 	return nil
 }
 
-func NicInfoToDevicesStatus(updateArgs VMUpdateArgs) []v1alpha1.NetworkDeviceStatus {
+var errCustomizationPending = errors.New("customization is pending")
+
+// ensureCustomizationNotPending clears the VM's pending customization when a fresh customization was requested
+// or when it is stale, that is the guest did not pick it up within the timeout. Otherwise, errCustomizationPending
+// is returned if the customization is pending.
+func ensureCustomizationNotPending(
+	vmCtx context.VirtualMachineContext,
+	resVM *res.VirtualMachine,
+	config *vimTypes.VirtualMachineConfigInfo) error {
+
+	vm := vmCtx.VM
+
+	if !IsCustomizationPendingExtraConfig(config.ExtraConfig) {
+		delete(vm.Annotations, constants.CustomizationPendingSinceAnnotation)
+		return nil
+	}
+
+	timeout := lib.GetGuestCustomizationPendingTimeout()
+	stale := IsCustomizationPendingStale(vm, time.Now(), timeout)
+
+	if !stale && !IsCustomizationRerunRequested(vm) {
+		if _, ok := vm.Annotations[constants.CustomizationPendingSinceAnnotation]; !ok {
+			// Start tracking a pending customization that was not applied by us.
+			setVMAnnotation(vm, constants.CustomizationPendingSinceAnnotation, time.Now().UTC().Format(time.RFC3339))
+		}
+		vmCtx.Logger.Info("Skipping customization because it is already pending")
+		return errCustomizationPending
+	}
+
+	if err := clearPendingCustomization(vmCtx, resVM); err != nil {
+		return err
+	}
+	delete(vm.Annotations, constants.CustomizationPendingSinceAnnotation)
+
+	if stale {
+		conditions.MarkFalse(vm, v1alpha1.GuestCustomizationCondition, v1alpha1.GuestCustomizationStaleReason,
+			v1alpha1.ConditionSeverityWarning,
+			"The pending guest customization was not picked up by the guest within %s so it was cleared and applied again", timeout)
+	}
+
+	return nil
+}
+
+func NicInfoToDevicesStatus(updateArgs VMUpdateArgs) ([]v1alpha1.NetworkDeviceStatus, error) {
 	networkDevicesStatus := make([]v1alpha1.NetworkDeviceStatus, 0, len(updateArgs.NetIfList))
 
-	// TODO: Add MacAddress field when the it's present in updateArgs.NetIfList
 	for _, info := range updateArgs.NetIfList {
-		ipConfig := info.IPConfiguration
 		networkDevice := v1alpha1.NetworkDeviceStatus{
-			Gateway4:    ipConfig.Gateway,
-			IPAddresses: []string{network.ToCidrNotation(ipConfig.IP, ipConfig.SubnetMask)},
+			MacAddress: info.NetplanEthernet.Match.MacAddress,
+			MTU:        info.NetplanEthernet.MTU,
+		}
+
+		for _, ipConfig := range info.IPConfigurations {
+			cidr, err := ipConfig.CIDR()
+			if err != nil {
+				return nil, err
+			}
+			networkDevice.IPAddresses = append(networkDevice.IPAddresses, cidr)
+			switch {
+			case ipConfig.IPFamily == network.IPv6Protocol && networkDevice.Gateway6 == "":
+				networkDevice.Gateway6 = ipConfig.Gateway
+			case ipConfig.IPFamily != network.IPv6Protocol && networkDevice.Gateway4 == "":
+				networkDevice.Gateway4 = ipConfig.Gateway
+			}
 		}
+
+		for _, route := range info.NetplanEthernet.Routes {
+			networkDevice.Routes = append(networkDevice.Routes, v1alpha1.VirtualMachineNetworkRoute{
+				To:     route.To,
+				Via:    route.Via,
+				Metric: route.Metric,
+			})
+		}
+
 		networkDevicesStatus = append(networkDevicesStatus, networkDevice)
 	}
-	return networkDevicesStatus
+	return networkDevicesStatus, nil
 }
 
 // TemplateVMMetadata can convert templated expressions to dynamic configuration data.
 func TemplateVMMetadata(vmCtx context.VirtualMachineContext, updateArgs VMUpdateArgs) {
-	networkDevicesStatus := NicInfoToDevicesStatus(updateArgs)
+	networkDevicesStatus, err := NicInfoToDevicesStatus(updateArgs)
+	if err != nil {
+		vmCtx.Logger.Error(err, "failed to get the network devices status, templates are not rendered")
+		return
+	}
 
 	networkStatus := v1alpha1.NetworkStatus{
 		Devices:     networkDevicesStatus,
@@ -357,6 +703,9 @@ func TemplateVMMetadata(vmCtx context.VirtualMachineContext, updateArgs VMUpdate
 		if len(networkDevicesStatus) == 0 {
 			return "", errors.New("no available network device, check with VI admin")
 		}
+		if len(networkDevicesStatus[0].IPAddresses) == 0 {
+			return "", errors.New("no IP address assigned to the network device")
+		}
 		return networkDevicesStatus[0].IPAddresses[0], nil
 	}
 
@@ -369,6 +718,9 @@ func TemplateVMMetadata(vmCtx context.VirtualMachineContext, updateArgs VMUpdate
 		if index >= len(networkDevicesStatus) {
 			return "", errors.New("index out of bound")
 		}
+		if len(networkDevicesStatus[index].IPAddresses) == 0 {
+			return "", errors.New("no IP address assigned to the network device")
+		}
 		return networkDevicesStatus[index].IPAddresses[0], nil
 	}
 
@@ -1,4 +1,4 @@
-// Copyright (c) 2021-2022 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2021-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package session_test
@@ -7,6 +7,7 @@ import (
 	goctx "context"
 	"encoding/base64"
 	"fmt"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -23,6 +24,7 @@ import (
 	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
 
 	"github.com/vmware-tanzu/vm-operator/pkg/context"
+	"github.com/vmware-tanzu/vm-operator/pkg/util"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/constants"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/internal"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/network"
@@ -61,6 +63,63 @@ var _ = Describe("Customization utils", func() {
 			})
 		})
 	})
+
+	Context("IsCustomizationPendingStale", func() {
+		var (
+			vm    *vmopv1alpha1.VirtualMachine
+			now   time.Time
+			stale bool
+		)
+
+		BeforeEach(func() {
+			vm = &vmopv1alpha1.VirtualMachine{}
+			now = time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+		})
+
+		JustBeforeEach(func() {
+			stale = session.IsCustomizationPendingStale(vm, now, 30*time.Minute)
+		})
+
+		Context("No pending since annotation", func() {
+			It("is not stale", func() {
+				Expect(stale).To(BeFalse())
+			})
+		})
+
+		Context("Invalid pending since annotation", func() {
+			BeforeEach(func() {
+				vm.Annotations = map[string]string{constants.CustomizationPendingSinceAnnotation: "yesterday"}
+			})
+
+			It("is not stale", func() {
+				Expect(stale).To(BeFalse())
+			})
+		})
+
+		Context("Pending within the timeout", func() {
+			BeforeEach(func() {
+				vm.Annotations = map[string]string{
+					constants.CustomizationPendingSinceAnnotation: now.Add(-10 * time.Minute).Format(time.RFC3339),
+				}
+			})
+
+			It("is not stale", func() {
+				Expect(stale).To(BeFalse())
+			})
+		})
+
+		Context("Pending longer than the timeout", func() {
+			BeforeEach(func() {
+				vm.Annotations = map[string]string{
+					constants.CustomizationPendingSinceAnnotation: now.Add(-time.Hour).Format(time.RFC3339),
+				}
+			})
+
+			It("is stale", func() {
+				Expect(stale).To(BeTrue())
+			})
+		})
+	})
 })
 
 var _ = Describe("Customization via ConfigSpec", func() {
@@ -188,6 +247,146 @@ var _ = Describe("Customization via Cust Spec", func() {
 		})
 	})
 
+	Context("GetSysprepCustSpec", func() {
+		var err error
+
+		BeforeEach(func() {
+			updateArgs.VMMetadata = session.VMMetadata{
+				Data:      map[string]string{},
+				Transport: vmopv1alpha1.VirtualMachineMetadataSysprepTransport,
+			}
+			updateArgs.SearchSuffixes = []string{"example.com"}
+			updateArgs.NetIfList = []network.InterfaceInfo{
+				{
+					Customization: &vimTypes.CustomizationAdapterMapping{
+						MacAddress: macaddress,
+						Adapter: vimTypes.CustomizationIPSettings{
+							Ip: &vimTypes.CustomizationFixedIp{IpAddress: "192.168.1.10"},
+						},
+					},
+				},
+				{
+					Customization: &vimTypes.CustomizationAdapterMapping{
+						Adapter: vimTypes.CustomizationIPSettings{
+							Ip: &vimTypes.CustomizationDhcpIpGenerator{},
+						},
+					},
+				},
+			}
+		})
+
+		JustBeforeEach(func() {
+			custSpec, err = session.GetSysprepCustSpec(vmName, updateArgs)
+		})
+
+		It("should return sysprep customization spec with defaults", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(custSpec).ToNot(BeNil())
+			Expect(custSpec.GlobalIPSettings.DnsSuffixList).To(Equal(updateArgs.SearchSuffixes))
+
+			sysprep := custSpec.Identity.(*vimTypes.CustomizationSysprep)
+			Expect(sysprep.UserData.ComputerName.(*vimTypes.CustomizationFixedName).Name).To(Equal(vmName))
+			Expect(sysprep.UserData.FullName).To(Equal(constants.SysprepFullNameDefault))
+			Expect(sysprep.UserData.OrgName).To(Equal(constants.SysprepOrgNameDefault))
+			Expect(sysprep.GuiUnattended.TimeZone).To(BeEquivalentTo(constants.SysprepTimeZoneDefault))
+			Expect(sysprep.GuiUnattended.Password).To(BeNil())
+			Expect(sysprep.Identification.JoinWorkgroup).To(Equal(constants.SysprepJoinWorkgroupDefault))
+		})
+
+		It("should set the DNS settings on the adapters with a static IP", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(custSpec.NicSettingMap).To(HaveLen(2))
+			Expect(custSpec.NicSettingMap[0].Adapter.DnsServerList).To(Equal([]string{nameserver}))
+			Expect(custSpec.NicSettingMap[0].Adapter.DnsDomain).To(Equal("example.com"))
+			Expect(custSpec.NicSettingMap[1].Adapter.DnsServerList).To(BeEmpty())
+			Expect(custSpec.NicSettingMap[1].Adapter.DnsDomain).To(BeEmpty())
+		})
+
+		Context("With identity keys", func() {
+			BeforeEach(func() {
+				updateArgs.VMMetadata.Data = map[string]string{
+					constants.SysprepFullNameKey:            "Jane Doe",
+					constants.SysprepOrgNameKey:             "Example",
+					constants.SysprepProductIDKey:           "XXXXX-XXXXX-XXXXX-XXXXX-XXXXX",
+					constants.SysprepAdminPasswordKey:       "admin-pass",
+					constants.SysprepTimeZoneKey:            "35",
+					constants.SysprepJoinDomainKey:          "corp.example.com",
+					constants.SysprepDomainAdminKey:         "domain-admin",
+					constants.SysprepDomainAdminPasswordKey: "domain-pass",
+				}
+			})
+
+			It("should return sysprep customization spec with the identity", func() {
+				Expect(err).ToNot(HaveOccurred())
+				sysprep := custSpec.Identity.(*vimTypes.CustomizationSysprep)
+				Expect(sysprep.UserData.FullName).To(Equal("Jane Doe"))
+				Expect(sysprep.UserData.OrgName).To(Equal("Example"))
+				Expect(sysprep.UserData.ProductId).To(Equal("XXXXX-XXXXX-XXXXX-XXXXX-XXXXX"))
+				Expect(sysprep.GuiUnattended.TimeZone).To(BeEquivalentTo(35))
+				Expect(sysprep.GuiUnattended.Password).To(Equal(&vimTypes.CustomizationPassword{Value: "admin-pass", PlainText: true}))
+				Expect(sysprep.Identification.JoinWorkgroup).To(BeEmpty())
+				Expect(sysprep.Identification.JoinDomain).To(Equal("corp.example.com"))
+				Expect(sysprep.Identification.DomainAdmin).To(Equal("domain-admin"))
+				Expect(sysprep.Identification.DomainAdminPassword).To(Equal(&vimTypes.CustomizationPassword{Value: "domain-pass", PlainText: true}))
+			})
+		})
+
+		Context("With invalid time zone", func() {
+			BeforeEach(func() {
+				updateArgs.VMMetadata.Data[constants.SysprepTimeZoneKey] = "pacific"
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("invalid Sysprep time-zone"))
+			})
+		})
+
+		Context("With a long VM name", func() {
+			BeforeEach(func() {
+				vmName = "a-very-long-windows-vm-name"
+			})
+
+			AfterEach(func() {
+				vmName = "dummy-vm"
+			})
+
+			It("should truncate the computer name", func() {
+				Expect(err).ToNot(HaveOccurred())
+				sysprep := custSpec.Identity.(*vimTypes.CustomizationSysprep)
+				Expect(sysprep.UserData.ComputerName.(*vimTypes.CustomizationFixedName).Name).To(Equal("a-very-long-win"))
+			})
+		})
+
+		Context("With a long VM name that is truncated at a hyphen", func() {
+			BeforeEach(func() {
+				vmName = "my-windows-vm--name"
+			})
+
+			AfterEach(func() {
+				vmName = "dummy-vm"
+			})
+
+			It("should trim the trailing hyphens of the truncated computer name", func() {
+				Expect(err).ToNot(HaveOccurred())
+				sysprep := custSpec.Identity.(*vimTypes.CustomizationSysprep)
+				Expect(sysprep.UserData.ComputerName.(*vimTypes.CustomizationFixedName).Name).To(Equal("my-windows-vm"))
+			})
+		})
+
+		Context("With unattend answer file", func() {
+			BeforeEach(func() {
+				updateArgs.VMMetadata.Data[constants.SysprepUnattendKey] = "<unattend/>"
+				updateArgs.VMMetadata.Data[constants.SysprepFullNameKey] = "ignored"
+			})
+
+			It("should return sysprep text customization spec", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(custSpec.Identity).To(Equal(&vimTypes.CustomizationSysprepText{Value: "<unattend/>"}))
+				Expect(custSpec.NicSettingMap).To(HaveLen(2))
+			})
+		})
+	})
 })
 
 var _ = Describe("CloudInitmetadata", func() {
@@ -246,6 +445,19 @@ var _ = Describe("CloudInitmetadata", func() {
 		Expect(metadata.Network).To(Equal(netplan))
 		Expect(metadata.PublicKeys).To(Equal(publicKeys))
 	})
+
+	Context("With cloud-init instance ID annotation", func() {
+		BeforeEach(func() {
+			vm.Annotations = map[string]string{constants.CloudInitInstanceIDAnnotation: "new-instance-id"}
+		})
+
+		It("Uses the annotation as the instance ID", func() {
+			Expect(err).ToNot(HaveOccurred())
+			metadata := session.CloudInitMetadata{}
+			Expect(yaml.Unmarshal([]byte(metadataString), &metadata)).To(Succeed())
+			Expect(metadata.InstanceID).To(Equal("new-instance-id"))
+		})
+	})
 })
 
 var _ = Describe("Cloud-Init Customization", func() {
@@ -377,6 +589,23 @@ var _ = Describe("Cloud-Init Customization", func() {
 
 	})
 
+	Context("SetCloudInitGuestInfoGrowpartVendordata", func() {
+		It("ConfigSpec.ExtraConfig to have growpart vendordata", func() {
+			configSpec := &vimTypes.VirtualMachineConfigSpec{}
+			Expect(session.SetCloudInitGuestInfoGrowpartVendordata(configInfo, configSpec)).To(Succeed())
+
+			extraConfig := session.ExtraConfigToMap(configSpec.ExtraConfig)
+			Expect(extraConfig).To(HaveLen(2))
+			Expect(extraConfig[constants.CloudInitGuestInfoVendordataEncoding]).To(Equal("gzip+base64"))
+
+			vendordata, err := util.TryToDecodeBase64Gzip([]byte(extraConfig[constants.CloudInitGuestInfoVendordata]))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vendordata).To(HavePrefix("#cloud-config"))
+			Expect(vendordata).To(ContainSubstring("growpart:"))
+			Expect(vendordata).To(ContainSubstring("resize_rootfs: true"))
+		})
+	})
+
 	Context("GetCloudInitPrepCustSpec", func() {
 		var (
 			custSpec *vimTypes.CustomizationSpec
@@ -447,6 +676,105 @@ var _ = Describe("Cloud-Init Customization", func() {
 	})
 })
 
+var _ = Describe("Ignition Customization", func() {
+	var (
+		netplan    network.Netplan
+		updateArgs session.VMUpdateArgs
+		configInfo *vimTypes.VirtualMachineConfigInfo
+		configSpec *vimTypes.VirtualMachineConfigSpec
+		err        error
+	)
+
+	BeforeEach(func() {
+		netplan = network.Netplan{
+			Version: 2,
+			Ethernets: map[string]network.NetplanEthernet{
+				"eth0": {
+					Match:   network.NetplanEthernetMatch{MacAddress: "00:50:56:aa:bb:cc"},
+					SetName: "eth0",
+					Dhcp4:   true,
+				},
+			},
+		}
+		configInfo = &vimTypes.VirtualMachineConfigInfo{}
+		updateArgs.VMMetadata.Data = map[string]string{}
+	})
+
+	JustBeforeEach(func() {
+		configSpec, err = session.GetIgnitionGuestInfoCustSpec(netplan, configInfo, updateArgs)
+	})
+
+	getIgnitionConfig := func() map[string]interface{} {
+		extraConfig := session.ExtraConfigToMap(configSpec.ExtraConfig)
+		ExpectWithOffset(1, extraConfig).To(HaveLen(2))
+		ExpectWithOffset(1, extraConfig[constants.IgnitionGuestInfoConfigDataEncoding]).To(Equal("base64"))
+		data, err := base64.StdEncoding.DecodeString(extraConfig[constants.IgnitionGuestInfoConfigData])
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		config := map[string]interface{}{}
+		ExpectWithOffset(1, kyaml.Unmarshal(data, &config)).To(Succeed())
+		return config
+	}
+
+	Context("No Ignition or Butane config", func() {
+		It("returns an error", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("requires either"))
+		})
+	})
+
+	Context("With Ignition config", func() {
+		BeforeEach(func() {
+			updateArgs.VMMetadata.Data[constants.IgnitionConfigKey] = `{"ignition":{"version":"3.3.0"}}`
+		})
+
+		It("ConfigSpec.ExtraConfig to have the Ignition config with the networkd units", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(configSpec).ToNot(BeNil())
+			config := getIgnitionConfig()
+			Expect(config["ignition"]).To(HaveKeyWithValue("version", "3.3.0"))
+			files := config["storage"].(map[string]interface{})["files"].([]interface{})
+			Expect(files).To(HaveLen(1))
+			Expect(files[0]).To(HaveKeyWithValue("path", "/etc/systemd/network/90-vmoperator-eth0.network"))
+		})
+	})
+
+	Context("With base64-encoded Ignition config", func() {
+		BeforeEach(func() {
+			updateArgs.VMMetadata.Data[constants.IgnitionConfigKey] = base64.StdEncoding.EncodeToString(
+				[]byte(`{"ignition":{"version":"3.3.0"}}`))
+		})
+
+		It("ConfigSpec.ExtraConfig to have the decoded Ignition config", func() {
+			Expect(err).ToNot(HaveOccurred())
+			config := getIgnitionConfig()
+			Expect(config["ignition"]).To(HaveKeyWithValue("version", "3.3.0"))
+		})
+	})
+
+	Context("With Butane config", func() {
+		BeforeEach(func() {
+			updateArgs.VMMetadata.Data[constants.ButaneConfigKey] = "variant: flatcar\nversion: 1.0.0\n"
+		})
+
+		It("ConfigSpec.ExtraConfig to have the transpiled Ignition config", func() {
+			Expect(err).ToNot(HaveOccurred())
+			config := getIgnitionConfig()
+			Expect(config).ToNot(HaveKey("variant"))
+			Expect(config["ignition"]).To(HaveKeyWithValue("version", "3.3.0"))
+		})
+	})
+
+	Context("With invalid Butane config", func() {
+		BeforeEach(func() {
+			updateArgs.VMMetadata.Data[constants.ButaneConfigKey] = "variant: flatcar\nversion: 9.9.9\n"
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
+
 var _ = Describe("TemplateVMMetadata", func() {
 	Context("update VmConfigArgs", func() {
 		var (
@@ -480,17 +808,23 @@ var _ = Describe("TemplateVMMetadata", func() {
 			updateArgs.DNSServers = []string{nameserver1, nameserver2}
 			updateArgs.NetIfList = []network.InterfaceInfo{
 				{
-					IPConfiguration: network.IPConfig{
-						Gateway:    gateway1,
-						IP:         ip1,
-						SubnetMask: subnetMask,
+					IPConfigurations: []network.IPConfig{
+						{
+							Gateway:    gateway1,
+							IP:         ip1,
+							SubnetMask: subnetMask,
+							IPFamily:   network.IPv4Protocol,
+						},
 					},
 				},
 				{
-					IPConfiguration: network.IPConfig{
-						Gateway:    gateway2,
-						IP:         ip2,
-						SubnetMask: subnetMask,
+					IPConfigurations: []network.IPConfig{
+						{
+							Gateway:    gateway2,
+							IP:         ip2,
+							SubnetMask: subnetMask,
+							IPFamily:   network.IPv4Protocol,
+						},
 					},
 				},
 			}
@@ -501,7 +835,8 @@ var _ = Describe("TemplateVMMetadata", func() {
 
 		It("should return populated NicInfoToNetworkIfStatusEx correctly", func() {
 			IPs := []string{IP1}
-			networkDevicesStatus := session.NicInfoToDevicesStatus(updateArgs)
+			networkDevicesStatus, err := session.NicInfoToDevicesStatus(updateArgs)
+			Expect(err).ToNot(HaveOccurred())
 			Expect(networkDevicesStatus[0].IPAddresses).To(Equal(IPs))
 			Expect(networkDevicesStatus[0].IPAddresses[0]).To(Equal(IP1))
 			Expect(networkDevicesStatus[0].Gateway4).To(Equal(gateway1))
@@ -509,6 +844,35 @@ var _ = Describe("TemplateVMMetadata", func() {
 			Expect(networkDevicesStatus[1].Gateway4).To(Equal(gateway2))
 		})
 
+		It("should return dual-stack NicInfoToDevicesStatus correctly", func() {
+			metric := int32(100)
+			updateArgs.NetIfList[0].IPConfigurations = append(updateArgs.NetIfList[0].IPConfigurations, network.IPConfig{
+				Gateway:    "2001:db8::1",
+				IP:         "2001:db8::10",
+				SubnetMask: "ffff:ffff:ffff:ffff::",
+				IPFamily:   network.IPv6Protocol,
+			})
+			updateArgs.NetIfList[0].NetplanEthernet = network.NetplanEthernet{
+				Match: network.NetplanEthernetMatch{MacAddress: "00:50:56:aa:bb:cc"},
+				MTU:   9000,
+				Routes: []network.NetplanRoute{
+					{To: "10.0.0.0/8", Via: gateway1, Metric: &metric},
+				},
+			}
+
+			networkDevicesStatus, err := session.NicInfoToDevicesStatus(updateArgs)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(networkDevicesStatus[0].IPAddresses).To(Equal([]string{IP1, "2001:db8::10/64"}))
+			Expect(networkDevicesStatus[0].Gateway4).To(Equal(gateway1))
+			Expect(networkDevicesStatus[0].Gateway6).To(Equal("2001:db8::1"))
+			Expect(networkDevicesStatus[0].MacAddress).To(Equal("00:50:56:aa:bb:cc"))
+			Expect(networkDevicesStatus[0].MTU).To(BeEquivalentTo(9000))
+			Expect(networkDevicesStatus[0].Routes).To(Equal([]vmopv1alpha1.VirtualMachineNetworkRoute{
+				{To: "10.0.0.0/8", Via: gateway1, Metric: &metric},
+			}))
+			Expect(networkDevicesStatus[1].Gateway6).To(BeEmpty())
+		})
+
 		It("should resolve them correctly while specifying valid templates", func() {
 
 			updateArgs.VMMetadata.Data["first_cidrIp"] = "{{ (index (index .V1alpha1.Net.Devices 0).IPAddresses 0) }}"
@@ -1,4 +1,4 @@
-// Copyright (c) 2021 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2021-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package session
@@ -6,6 +6,8 @@ package session
 import (
 	"strconv"
 
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8serrors "k8s.io/apimachinery/pkg/util/errors"
 
 	"github.com/vmware/govmomi/object"
@@ -60,13 +62,19 @@ func MarkVMToolsRunningStatusCondition(vm *v1alpha1.VirtualMachine, guestInfo *v
 
 func MarkCustomizationInfoCondition(vm *v1alpha1.VirtualMachine, guestInfo *vimTypes.GuestInfo) {
 	if guestInfo == nil || guestInfo.CustomizationInfo == nil {
-		conditions.MarkUnknown(vm, v1alpha1.GuestCustomizationCondition, "", "")
+		if !isCustomizationRetriedCondition(vm) {
+			conditions.MarkUnknown(vm, v1alpha1.GuestCustomizationCondition, "", "")
+		}
 		return
 	}
 
 	switch guestInfo.CustomizationInfo.CustomizationStatus {
 	case string(vimTypes.GuestInfoCustomizationStatusTOOLSDEPLOYPKG_IDLE), "":
-		conditions.MarkTrue(vm, v1alpha1.GuestCustomizationCondition)
+		// Keep explaining why the customization was retried until the guest has booted with it. A guest that is
+		// idle once VMware Tools is running did not need to report any progress, like with the CloudInit transport.
+		if !isCustomizationRetriedCondition(vm) || isGuestRunning(vm, guestInfo) {
+			conditions.MarkTrue(vm, v1alpha1.GuestCustomizationCondition)
+		}
 	case string(vimTypes.GuestInfoCustomizationStatusTOOLSDEPLOYPKG_PENDING):
 		conditions.MarkFalse(vm, v1alpha1.GuestCustomizationCondition, v1alpha1.GuestCustomizationPendingReason, v1alpha1.ConditionSeverityInfo, "")
 	case string(vimTypes.GuestInfoCustomizationStatusTOOLSDEPLOYPKG_RUNNING):
@@ -88,13 +96,29 @@ func MarkCustomizationInfoCondition(vm *v1alpha1.VirtualMachine, guestInfo *vimT
 	}
 }
 
+// isCustomizationRetriedCondition returns true if the GuestCustomization condition reports that the
+// customization was cleared because it was stale or was re-run as requested.
+func isCustomizationRetriedCondition(vm *v1alpha1.VirtualMachine) bool {
+	switch conditions.GetReason(vm, v1alpha1.GuestCustomizationCondition) {
+	case v1alpha1.GuestCustomizationStaleReason, v1alpha1.GuestCustomizationRerunReason:
+		return true
+	}
+	return false
+}
+
+// isGuestRunning returns true if the VM is powered on and VMware Tools is running in the guest.
+func isGuestRunning(vm *v1alpha1.VirtualMachine, guestInfo *vimTypes.GuestInfo) bool {
+	return vm.Status.PowerState == v1alpha1.VirtualMachinePoweredOn &&
+		guestInfo.ToolsRunningStatus == string(vimTypes.VirtualMachineToolsRunningStatusGuestToolsRunning)
+}
+
 func (s *Session) updateVMStatus(
 	vmCtx context.VirtualMachineContext,
 	resVM *res.VirtualMachine) error {
 
 	// TODO: We could be smarter about not re-fetching the config: if we didn't do a
 	// reconfigure or power change, the prior config is still entirely valid.
-	moVM, err := resVM.GetProperties(vmCtx, []string{"config.changeTrackingEnabled", "guest", "summary"})
+	moVM, err := resVM.GetProperties(vmCtx, []string{"config.changeTrackingEnabled", "config.hardware.device", "guest", "summary"})
 	if err != nil {
 		// Leave the current Status unchanged.
 		return err
@@ -106,6 +130,11 @@ func (s *Session) updateVMStatus(
 
 	vm.Status.Phase = v1alpha1.Created
 	vm.Status.PowerState = v1alpha1.VirtualMachinePowerState(summary.Runtime.PowerState)
+	if bootTime := summary.Runtime.BootTime; bootTime != nil {
+		vm.Status.BootTime = &metav1.Time{Time: *bootTime}
+	} else {
+		vm.Status.BootTime = nil
+	}
 	vm.Status.UniqueID = resVM.MoRef().Value
 	vm.Status.BiosUUID = summary.Config.Uuid
 	vm.Status.InstanceUUID = summary.Config.InstanceUuid
@@ -141,8 +170,16 @@ func (s *Session) updateVMStatus(
 
 	if config := moVM.Config; config != nil {
 		vm.Status.ChangeBlockTracking = config.ChangeTrackingEnabled
+
+		virtualDisks := object.VirtualDeviceList(config.Hardware.Device).SelectByType((*vimTypes.VirtualDisk)(nil))
+		if bootDisk := getBootDisk(virtualDisks); bootDisk != nil {
+			vm.Status.BootDiskCapacity = resource.NewQuantity(bootDisk.CapacityInBytes, resource.BinarySI)
+		} else {
+			vm.Status.BootDiskCapacity = nil
+		}
 	} else {
 		vm.Status.ChangeBlockTracking = nil
+		vm.Status.BootDiskCapacity = nil
 	}
 
 	if lib.IsWcpFaultDomainsFSSEnabled() {
@@ -161,7 +198,8 @@ func (s *Session) updateVMStatus(
 			}
 		}
 
-		if zoneName != "" {
+		// Once set, the zone is only updated when the VM has been migrated to the zone in its label.
+		if zoneName != "" && vm.Status.Zone == "" {
 			vm.Status.Zone = zoneName
 		}
 	}
@@ -1,4 +1,4 @@
-// Copyright (c) 2021 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2021-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package session_test
@@ -182,6 +182,56 @@ var _ = Describe("VSphere Customization Status to VM Status Condition", func() {
 				Expect(vm.Status.Conditions).To(conditions.MatchConditions(expectedConditions))
 			})
 		})
+		Context("customizationInfo idle after a stale customization was cleared", func() {
+			BeforeEach(func() {
+				conditions.MarkFalse(vm, vmopv1alpha1.GuestCustomizationCondition, vmopv1alpha1.GuestCustomizationStaleReason, vmopv1alpha1.ConditionSeverityWarning, "stale")
+				guestInfo.CustomizationInfo.CustomizationStatus = string(vimTypes.GuestInfoCustomizationStatusTOOLSDEPLOYPKG_IDLE)
+			})
+			It("keeps the stale condition", func() {
+				expectedConditions := vmopv1alpha1.Conditions{
+					*conditions.FalseCondition(vmopv1alpha1.GuestCustomizationCondition, vmopv1alpha1.GuestCustomizationStaleReason, vmopv1alpha1.ConditionSeverityWarning, "stale"),
+				}
+				Expect(vm.Status.Conditions).To(conditions.MatchConditions(expectedConditions))
+			})
+		})
+		Context("customizationInfo idle once the guest is running after a customization was re-run", func() {
+			BeforeEach(func() {
+				conditions.MarkFalse(vm, vmopv1alpha1.GuestCustomizationCondition, vmopv1alpha1.GuestCustomizationRerunReason, vmopv1alpha1.ConditionSeverityInfo, "rerun")
+				vm.Status.PowerState = vmopv1alpha1.VirtualMachinePoweredOn
+				guestInfo.ToolsRunningStatus = string(vimTypes.VirtualMachineToolsRunningStatusGuestToolsRunning)
+				guestInfo.CustomizationInfo.CustomizationStatus = string(vimTypes.GuestInfoCustomizationStatusTOOLSDEPLOYPKG_IDLE)
+			})
+			It("sets condition true", func() {
+				expectedConditions := vmopv1alpha1.Conditions{
+					*conditions.TrueCondition(vmopv1alpha1.GuestCustomizationCondition),
+				}
+				Expect(vm.Status.Conditions).To(conditions.MatchConditions(expectedConditions))
+			})
+		})
+		Context("customizationInfo unset after a customization was re-run", func() {
+			BeforeEach(func() {
+				conditions.MarkFalse(vm, vmopv1alpha1.GuestCustomizationCondition, vmopv1alpha1.GuestCustomizationRerunReason, vmopv1alpha1.ConditionSeverityInfo, "rerun")
+				guestInfo.CustomizationInfo = nil
+			})
+			It("keeps the rerun condition", func() {
+				expectedConditions := vmopv1alpha1.Conditions{
+					*conditions.FalseCondition(vmopv1alpha1.GuestCustomizationCondition, vmopv1alpha1.GuestCustomizationRerunReason, vmopv1alpha1.ConditionSeverityInfo, "rerun"),
+				}
+				Expect(vm.Status.Conditions).To(conditions.MatchConditions(expectedConditions))
+			})
+		})
+		Context("customizationInfo succeeded after a customization was re-run", func() {
+			BeforeEach(func() {
+				conditions.MarkFalse(vm, vmopv1alpha1.GuestCustomizationCondition, vmopv1alpha1.GuestCustomizationRerunReason, vmopv1alpha1.ConditionSeverityInfo, "rerun")
+				guestInfo.CustomizationInfo.CustomizationStatus = string(vimTypes.GuestInfoCustomizationStatusTOOLSDEPLOYPKG_SUCCEEDED)
+			})
+			It("sets condition true", func() {
+				expectedConditions := vmopv1alpha1.Conditions{
+					*conditions.TrueCondition(vmopv1alpha1.GuestCustomizationCondition),
+				}
+				Expect(vm.Status.Conditions).To(conditions.MatchConditions(expectedConditions))
+			})
+		})
 		Context("customizationInfo pending", func() {
 			BeforeEach(func() {
 				guestInfo.CustomizationInfo.CustomizationStatus = string(vimTypes.GuestInfoCustomizationStatusTOOLSDEPLOYPKG_PENDING)
@@ -6,31 +6,43 @@ package session
 import (
 	"fmt"
 	"reflect"
+	"strings"
 
 	apiEquality "k8s.io/apimachinery/pkg/api/equality"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	ctrl "sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
 	vimTypes "github.com/vmware/govmomi/vim25/types"
 
 	"github.com/vmware-tanzu/vm-operator/api/v1alpha1"
 
 	"github.com/vmware-tanzu/vm-operator/pkg"
+	"github.com/vmware-tanzu/vm-operator/pkg/conditions"
 	"github.com/vmware-tanzu/vm-operator/pkg/context"
 	"github.com/vmware-tanzu/vm-operator/pkg/lib"
+	"github.com/vmware-tanzu/vm-operator/pkg/topology"
 	"github.com/vmware-tanzu/vm-operator/pkg/util"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/clustermodules"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/config"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/constants"
+	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/contentlibrary"
+	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/imagevolume"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/instancestorage"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/network"
+	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/placement"
 	res "github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/resources"
+	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/storage"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/virtualmachine"
 )
 
 const (
 	FirstBootDoneAnnotation = "virtualmachine.vmoperator.vmware.com/first-boot-done"
+
+	// maxSATAControllers is the maximum number of SATA controllers of a VM.
+	maxSATAControllers = 4
 )
 
 type VMMetadata struct {
@@ -53,6 +65,9 @@ type VMUpdateArgs struct {
 	DNSServers     []string
 	SearchSuffixes []string
 
+	// CdromIsoFiles are the datastore paths of the ISO files of the VM's CD-ROMs, in the order of the CD-ROMs.
+	CdromIsoFiles []string
+
 	// hack. Remove after VMSVC-1261.
 	// indicating if this VM image used is VM service v1alpha1 compatible.
 	VirtualMachineImageV1Alpha1Compatible bool
@@ -237,6 +252,189 @@ func UpdatePCIDeviceChanges(
 	return append(removeDeviceChanges, deviceChanges...), nil
 }
 
+// UpdateCdromDeviceChanges returns the device changes to reconcile the VM's CD-ROMs that are backed by the ISO
+// files of content library items with the VM Spec CD-ROMs, whose ISO files are given in the same order. The
+// CD-ROMs are matched in order, and other CD-ROMs, like those of the VM's image, are left unchanged. CD-ROMs are
+// only added and removed when the VM is not powered on.
+func UpdateCdromDeviceChanges(
+	vm *v1alpha1.VirtualMachine,
+	isoFiles []string,
+	devices object.VirtualDeviceList,
+	isPoweredOn bool) ([]vimTypes.BaseVirtualDeviceConfigSpec, error) {
+
+	if len(isoFiles) != len(vm.Spec.Cdrom) {
+		return nil, fmt.Errorf("expected %d CD-ROM ISO files but got %d", len(vm.Spec.Cdrom), len(isoFiles))
+	}
+
+	var currentCdroms []*vimTypes.VirtualCdrom
+	for _, dev := range devices.SelectByType((*vimTypes.VirtualCdrom)(nil)) {
+		if cdrom := dev.(*vimTypes.VirtualCdrom); isContentLibraryIsoCdrom(cdrom, isoFiles) {
+			currentCdroms = append(currentCdroms, cdrom)
+		}
+	}
+
+	var removeDeviceChanges []vimTypes.BaseVirtualDeviceConfigSpec
+	if !isPoweredOn {
+		for i := len(vm.Spec.Cdrom); i < len(currentCdroms); i++ {
+			key := currentCdroms[i].Key
+			devices = devices.Select(func(dev vimTypes.BaseVirtualDevice) bool {
+				return dev.GetVirtualDevice().Key != key
+			})
+			removeDeviceChanges = append(removeDeviceChanges, &vimTypes.VirtualDeviceConfigSpec{
+				Operation: vimTypes.VirtualDeviceConfigSpecOperationRemove,
+				Device:    currentCdroms[i],
+			})
+		}
+	}
+
+	var deviceChanges []vimTypes.BaseVirtualDeviceConfigSpec
+	for i, cdrom := range vm.Spec.Cdrom {
+		connected := cdrom.Connected == nil || *cdrom.Connected
+
+		if i >= len(currentCdroms) {
+			if isPoweredOn {
+				break
+			}
+
+			controller := cdromController(devices)
+			if controller == nil {
+				sataController := newSATAController(devices, virtualmachine.SATAControllerStartDeviceKey-int32(i))
+				if sataController == nil {
+					return nil, fmt.Errorf("no IDE or SATA controller has a free unit and no SATA controller can be added for CD-ROM %s", cdrom.Name)
+				}
+				devices = append(devices, sataController)
+				deviceChanges = append(deviceChanges, &vimTypes.VirtualDeviceConfigSpec{
+					Operation: vimTypes.VirtualDeviceConfigSpecOperationAdd,
+					Device:    sataController,
+				})
+				controller = sataController
+			}
+
+			newCdrom := &vimTypes.VirtualCdrom{
+				VirtualDevice: vimTypes.VirtualDevice{
+					Key:         virtualmachine.CdromStartDeviceKey - int32(i),
+					Backing:     cdromIsoBacking(isoFiles[i]),
+					Connectable: cdromConnectInfo(connected),
+				},
+			}
+			devices.AssignController(newCdrom, controller)
+			devices = append(devices, newCdrom)
+
+			deviceChanges = append(deviceChanges, &vimTypes.VirtualDeviceConfigSpec{
+				Operation: vimTypes.VirtualDeviceConfigSpecOperationAdd,
+				Device:    newCdrom,
+			})
+			continue
+		}
+
+		curCdrom := currentCdroms[i]
+		editCdrom := *curCdrom
+		changed := false
+
+		if curCdrom.Backing.(*vimTypes.VirtualCdromIsoBackingInfo).FileName != isoFiles[i] {
+			editCdrom.Backing = cdromIsoBacking(isoFiles[i])
+			changed = true
+		}
+
+		// A CD-ROM of a VM that is not powered on is never connected so only compare whether it is connected
+		// when the VM is powered on.
+		if c := curCdrom.Connectable; c == nil || c.StartConnected != connected || (isPoweredOn && c.Connected != connected) {
+			editCdrom.Connectable = cdromConnectInfo(connected)
+			changed = true
+		}
+
+		if changed {
+			deviceChanges = append(deviceChanges, &vimTypes.VirtualDeviceConfigSpec{
+				Operation: vimTypes.VirtualDeviceConfigSpecOperationEdit,
+				Device:    &editCdrom,
+			})
+		}
+	}
+
+	// Process any removes first.
+	return append(removeDeviceChanges, deviceChanges...), nil
+}
+
+// isContentLibraryIsoCdrom returns true if the CD-ROM is backed by the ISO file of a content library item: either
+// one of the given ISO files or a file in the directory of a content library.
+func isContentLibraryIsoCdrom(cdrom *vimTypes.VirtualCdrom, isoFiles []string) bool {
+	backing, ok := cdrom.Backing.(*vimTypes.VirtualCdromIsoBackingInfo)
+	if !ok {
+		return false
+	}
+
+	for _, isoFile := range isoFiles {
+		if backing.FileName == isoFile {
+			return true
+		}
+	}
+
+	var dsPath object.DatastorePath
+	return dsPath.FromString(backing.FileName) && strings.HasPrefix(dsPath.Path, contentlibrary.LibraryDirPrefix)
+}
+
+// cdromController returns an IDE controller, or otherwise a SATA controller, that has a free unit for a CD-ROM.
+func cdromController(devices object.VirtualDeviceList) vimTypes.BaseVirtualController {
+	controllerDevices := map[int32]int{}
+	for _, dev := range devices {
+		controllerDevices[dev.GetVirtualDevice().ControllerKey]++
+	}
+
+	for _, dev := range devices.SelectByType((*vimTypes.VirtualIDEController)(nil)) {
+		if controllerDevices[dev.GetVirtualDevice().Key] < 2 {
+			return dev.(vimTypes.BaseVirtualController)
+		}
+	}
+
+	for _, dev := range devices.SelectByType((*vimTypes.VirtualSATAController)(nil)) {
+		if controllerDevices[dev.GetVirtualDevice().Key] < 30 {
+			return dev.(vimTypes.BaseVirtualController)
+		}
+	}
+
+	return nil
+}
+
+// newSATAController returns a new SATA controller on a free bus, or nil when the VM already has the maximum number
+// of SATA controllers.
+func newSATAController(devices object.VirtualDeviceList, key int32) *vimTypes.VirtualAHCIController {
+	busNumbers := map[int32]bool{}
+	for _, dev := range devices.SelectByType((*vimTypes.VirtualSATAController)(nil)) {
+		busNumbers[dev.(vimTypes.BaseVirtualSATAController).GetVirtualSATAController().BusNumber] = true
+	}
+
+	for busNumber := int32(0); busNumber < maxSATAControllers; busNumber++ {
+		if !busNumbers[busNumber] {
+			return &vimTypes.VirtualAHCIController{
+				VirtualSATAController: vimTypes.VirtualSATAController{
+					VirtualController: vimTypes.VirtualController{
+						VirtualDevice: vimTypes.VirtualDevice{Key: key},
+						BusNumber:     busNumber,
+					},
+				},
+			}
+		}
+	}
+
+	return nil
+}
+
+func cdromIsoBacking(isoFile string) *vimTypes.VirtualCdromIsoBackingInfo {
+	return &vimTypes.VirtualCdromIsoBackingInfo{
+		VirtualDeviceFileBackingInfo: vimTypes.VirtualDeviceFileBackingInfo{
+			FileName: isoFile,
+		},
+	}
+}
+
+func cdromConnectInfo(connected bool) *vimTypes.VirtualDeviceConnectInfo {
+	return &vimTypes.VirtualDeviceConnectInfo{
+		AllowGuestControl: true,
+		Connected:         connected,
+		StartConnected:    connected,
+	}
+}
+
 func UpdateConfigSpecCPUAllocation(
 	config *vimTypes.VirtualMachineConfigInfo,
 	configSpec *vimTypes.VirtualMachineConfigSpec,
@@ -434,6 +632,14 @@ func UpdateConfigSpecFirmware(
 	configSpec *vimTypes.VirtualMachineConfigSpec,
 	vm *v1alpha1.VirtualMachine) {
 
+	// The boot options firmware takes precedence over the older annotation.
+	if bootOptions := vm.Spec.BootOptions; bootOptions != nil && bootOptions.Firmware != "" {
+		if config.Firmware != bootOptions.Firmware {
+			configSpec.Firmware = bootOptions.Firmware
+		}
+		return
+	}
+
 	if val, ok := vm.Annotations[constants.FirmwareOverrideAnnotation]; ok {
 		if (val == "efi" || val == "bios") && config.Firmware != val {
 			configSpec.Firmware = val
@@ -441,6 +647,148 @@ func UpdateConfigSpecFirmware(
 	}
 }
 
+// UpdateConfigSpecBootOptions sets the desired config spec boot order and EFI secure boot from the VM's
+// boot options. The boot order refers to the devices the VM has once the config spec device changes are
+// applied.
+func UpdateConfigSpecBootOptions(
+	config *vimTypes.VirtualMachineConfigInfo,
+	configSpec *vimTypes.VirtualMachineConfigSpec,
+	vm *v1alpha1.VirtualMachine) {
+
+	bootOptions := vm.Spec.BootOptions
+	if bootOptions == nil {
+		return
+	}
+
+	curBootOptions := &vimTypes.VirtualMachineBootOptions{}
+	if config.BootOptions != nil {
+		curBootOptions = config.BootOptions
+	}
+
+	desiredBootOptions := &vimTypes.VirtualMachineBootOptions{}
+	changed := false
+
+	curSecureBoot := curBootOptions.EfiSecureBootEnabled != nil && *curBootOptions.EfiSecureBootEnabled
+	if bootOptions.EFISecureBootEnabled != curSecureBoot {
+		desiredBootOptions.EfiSecureBootEnabled = &bootOptions.EFISecureBootEnabled
+		changed = true
+	}
+
+	if len(bootOptions.BootOrder) > 0 {
+		devices := applyDeviceChanges(config.Hardware.Device, configSpec.DeviceChange)
+		bootOrder := bootableDevices(bootOptions.BootOrder, devices)
+		if !reflect.DeepEqual(bootOrder, curBootOptions.BootOrder) {
+			desiredBootOptions.BootOrder = bootOrder
+			changed = true
+		}
+	}
+
+	if changed {
+		configSpec.BootOptions = desiredBootOptions
+	}
+}
+
+// UpdateConfigSpecVirtualTPM adds a virtual TPM device to the VM when the VM does not have one. A VM that is
+// not already encrypted is encrypted with the key provider of the virtual TPM, as required by vSphere.
+func UpdateConfigSpecVirtualTPM(
+	config *vimTypes.VirtualMachineConfigInfo,
+	configSpec *vimTypes.VirtualMachineConfigSpec,
+	vm *v1alpha1.VirtualMachine) {
+
+	vTPM := vm.Spec.VirtualTPM
+	if vTPM == nil {
+		return
+	}
+
+	if len(object.VirtualDeviceList(config.Hardware.Device).SelectByType((*vimTypes.VirtualTPM)(nil))) > 0 {
+		return
+	}
+
+	// Use a temporary key that does not collide with the other devices being added.
+	key := int32(-1)
+	for _, dc := range configSpec.DeviceChange {
+		if dev := dc.GetVirtualDeviceConfigSpec().Device; dev != nil && dev.GetVirtualDevice().Key <= key {
+			key = dev.GetVirtualDevice().Key - 1
+		}
+	}
+
+	configSpec.DeviceChange = append(configSpec.DeviceChange, &vimTypes.VirtualDeviceConfigSpec{
+		Operation: vimTypes.VirtualDeviceConfigSpecOperationAdd,
+		Device: &vimTypes.VirtualTPM{
+			VirtualDevice: vimTypes.VirtualDevice{Key: key},
+		},
+	})
+
+	if config.KeyId == nil {
+		configSpec.Crypto = &vimTypes.CryptoSpecEncrypt{
+			CryptoKeyId: vimTypes.CryptoKeyId{
+				ProviderId: &vimTypes.KeyProviderId{Id: vTPM.KeyProviderID},
+			},
+		}
+	}
+}
+
+// applyDeviceChanges returns the devices a VM has once the device changes are applied.
+func applyDeviceChanges(
+	devices []vimTypes.BaseVirtualDevice,
+	deviceChanges []vimTypes.BaseVirtualDeviceConfigSpec) object.VirtualDeviceList {
+
+	removed := map[int32]bool{}
+	var added []vimTypes.BaseVirtualDevice
+	for _, dc := range deviceChanges {
+		spec := dc.GetVirtualDeviceConfigSpec()
+		if spec.Device == nil {
+			continue
+		}
+		switch spec.Operation {
+		case vimTypes.VirtualDeviceConfigSpecOperationRemove:
+			removed[spec.Device.GetVirtualDevice().Key] = true
+		case vimTypes.VirtualDeviceConfigSpecOperationAdd:
+			added = append(added, spec.Device)
+		}
+	}
+
+	var result object.VirtualDeviceList
+	for _, dev := range devices {
+		if !removed[dev.GetVirtualDevice().Key] {
+			result = append(result, dev)
+		}
+	}
+
+	return append(result, added...)
+}
+
+// bootableDevices returns the vSphere boot order for the boot devices. A boot device the VM does not have is
+// skipped.
+func bootableDevices(
+	bootOrder []v1alpha1.VirtualMachineBootDevice,
+	devices object.VirtualDeviceList) []vimTypes.BaseVirtualMachineBootOptionsBootableDevice {
+
+	var bootableDevices []vimTypes.BaseVirtualMachineBootOptionsBootableDevice
+	for _, bootDevice := range bootOrder {
+		switch bootDevice {
+		case v1alpha1.VirtualMachineBootDeviceDisk:
+			if disks := devices.SelectByType((*vimTypes.VirtualDisk)(nil)); len(disks) > 0 {
+				bootableDevices = append(bootableDevices, &vimTypes.VirtualMachineBootOptionsBootableDiskDevice{
+					DeviceKey: disks[0].GetVirtualDevice().Key,
+				})
+			}
+		case v1alpha1.VirtualMachineBootDeviceNetwork:
+			if ethCards := devices.SelectByType((*vimTypes.VirtualEthernetCard)(nil)); len(ethCards) > 0 {
+				bootableDevices = append(bootableDevices, &vimTypes.VirtualMachineBootOptionsBootableEthernetDevice{
+					DeviceKey: ethCards[0].GetVirtualDevice().Key,
+				})
+			}
+		case v1alpha1.VirtualMachineBootDeviceCDROM:
+			if cdroms := devices.SelectByType((*vimTypes.VirtualCdrom)(nil)); len(cdroms) > 0 {
+				bootableDevices = append(bootableDevices, &vimTypes.VirtualMachineBootOptionsBootableCdromDevice{})
+			}
+		}
+	}
+
+	return bootableDevices
+}
+
 // UpdateConfigSpecDeviceGroups sets the desired config spec device groups to reconcile by differencing the
 // current VM config and the class config spec device groups.
 func UpdateConfigSpecDeviceGroups(
@@ -468,8 +816,9 @@ func updateConfigSpec(
 	// reconfigured to match the desired CPU and memory reservation.  Maintain that
 	// behavior.  With the FSS enabled, VMs will be _created_ with desired HW spec, and we
 	// will not modify the hardware of the VM post creation.  So, don't populate the
-	// Hardware config and CPU/Memory reservation.
-	if !lib.IsVMClassAsConfigFSSDaynDateEnabled() {
+	// Hardware config and CPU/Memory reservation, unless the VM is being resized to a
+	// different class.
+	if !lib.IsVMClassAsConfigFSSDaynDateEnabled() || isVMResizeRequired(vmCtx.VM, updateArgs.VMClass) {
 		UpdateHardwareConfigSpec(config, configSpec, &vmClassSpec)
 		UpdateConfigSpecCPUAllocation(config, configSpec, &vmClassSpec, updateArgs.MinCPUFreq)
 		UpdateConfigSpecMemoryAllocation(config, configSpec, &vmClassSpec)
@@ -527,6 +876,15 @@ func (s *Session) prePowerOnVMConfigSpec(
 	}
 	configSpec.DeviceChange = append(configSpec.DeviceChange, pciDeviceChanges...)
 
+	cdromDeviceChanges, err := UpdateCdromDeviceChanges(vmCtx.VM, updateArgs.CdromIsoFiles, virtualDevices, false)
+	if err != nil {
+		return nil, err
+	}
+	configSpec.DeviceChange = append(configSpec.DeviceChange, cdromDeviceChanges...)
+
+	UpdateConfigSpecVirtualTPM(config, configSpec, vmCtx.VM)
+	UpdateConfigSpecBootOptions(config, configSpec, vmCtx.VM)
+
 	return configSpec, nil
 }
 
@@ -550,6 +908,8 @@ func (s *Session) prePowerOnVMReconfigure(
 		}
 	}
 
+	setVMAppliedClass(vmCtx.VM, updateArgs.VMClass)
+
 	return nil
 }
 
@@ -733,11 +1093,29 @@ func MutateUpdateArgsWithDNSInformation(
 func (s *Session) poweredOnVMReconfigure(
 	vmCtx context.VirtualMachineContext,
 	resVM *res.VirtualMachine,
-	config *vimTypes.VirtualMachineConfigInfo) error {
+	config *vimTypes.VirtualMachineConfigInfo,
+	cdromIsoFiles []string) error {
 
 	configSpec := &vimTypes.VirtualMachineConfigSpec{}
 	UpdateConfigSpecChangeBlockTracking(config, configSpec, nil, vmCtx.VM.Spec)
 
+	// The boot disk can be grown while the VM is powered on.
+	bootDiskChange, err := bootDiskDeviceChange(vmCtx.VM, object.VirtualDeviceList(config.Hardware.Device))
+	if err != nil {
+		return err
+	}
+	if bootDiskChange != nil {
+		configSpec.DeviceChange = append(configSpec.DeviceChange, bootDiskChange)
+	}
+
+	// The ISO of a CD-ROM can be changed, and the CD-ROM connected or disconnected, while the VM is powered on.
+	cdromDeviceChanges, err := UpdateCdromDeviceChanges(vmCtx.VM, cdromIsoFiles,
+		object.VirtualDeviceList(config.Hardware.Device), true)
+	if err != nil {
+		return err
+	}
+	configSpec.DeviceChange = append(configSpec.DeviceChange, cdromDeviceChanges...)
+
 	defaultConfigSpec := &vimTypes.VirtualMachineConfigSpec{}
 	if !apiEquality.Semantic.DeepEqual(configSpec, defaultConfigSpec) {
 		vmCtx.Logger.Info("PoweredOn Reconfigure", "configSpec", configSpec)
@@ -760,6 +1138,613 @@ func (s *Session) poweredOnVMReconfigure(
 	return nil
 }
 
+// isVMResizeRequired returns true if the CPU and memory of the VM were last applied from a different
+// VirtualMachineClass, or from an older generation of its VirtualMachineClass.
+func isVMResizeRequired(vm *v1alpha1.VirtualMachine, vmClass *v1alpha1.VirtualMachineClass) bool {
+	if vm.Status.AppliedClassName == "" {
+		// The VM predates resize support so assume it matches its class.
+		return false
+	}
+
+	return vm.Status.AppliedClassName != vmClass.Name || vm.Status.AppliedClassGeneration != vmClass.Generation
+}
+
+func setVMAppliedClass(vm *v1alpha1.VirtualMachine, vmClass *v1alpha1.VirtualMachineClass) {
+	// Only report the condition for a VM that is being, or has been, resized.
+	if isVMResizeRequired(vm, vmClass) || conditions.Has(vm, v1alpha1.VirtualMachineResizedCondition) {
+		conditions.MarkTrue(vm, v1alpha1.VirtualMachineResizedCondition)
+	}
+	vm.Status.AppliedClassName = vmClass.Name
+	vm.Status.AppliedClassGeneration = vmClass.Generation
+}
+
+// CanHotResize returns true if the CPU and memory changes in the ConfigSpec can be applied to the
+// powered on VM without a power cycle.
+func CanHotResize(
+	config *vimTypes.VirtualMachineConfigInfo,
+	configSpec *vimTypes.VirtualMachineConfigSpec) bool {
+
+	isEnabled := func(b *bool) bool {
+		return b != nil && *b
+	}
+
+	if nCPUs := configSpec.NumCPUs; nCPUs != 0 {
+		if nCPUs > config.Hardware.NumCPU && !isEnabled(config.CpuHotAddEnabled) {
+			return false
+		}
+		if nCPUs < config.Hardware.NumCPU && !isEnabled(config.CpuHotRemoveEnabled) {
+			return false
+		}
+	}
+
+	if memMB := configSpec.MemoryMB; memMB != 0 {
+		// Memory can only ever be hot-added.
+		if memMB < int64(config.Hardware.MemoryMB) || !isEnabled(config.MemoryHotAddEnabled) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// resizeVM reconfigures the CPU and memory of the VM to match its VirtualMachineClass when the class,
+// or the generation of the class, has changed since it was last applied. A powered on VM is resized
+// in place when the changes can be hot-added, and is otherwise power cycled around the reconfigure.
+// An update of the class only power cycles a VM with the ClassUpdatePowerCycleAnnotation, and is
+// otherwise deferred until the VM is powered off. The power off honors the PowerOffMode of the VM:
+// true is returned while the guest OS is shutting down, and the VM is resized before it is powered
+// back on.
+func (s *Session) resizeVM(
+	vmCtx context.VirtualMachineContext,
+	resVM *res.VirtualMachine,
+	config *vimTypes.VirtualMachineConfigInfo,
+	isOff bool,
+	getUpdateArgsFn func() (*VMUpdateArgs, error)) (bool, error) {
+
+	// Fetch just the class here so that a VM that is not being resized does not depend on
+	// the rest of its prerequisites.
+	vmClass := &v1alpha1.VirtualMachineClass{}
+	if err := s.K8sClient.Get(vmCtx, ctrl.ObjectKey{Name: vmCtx.VM.Spec.ClassName}, vmClass); err != nil {
+		return false, fmt.Errorf("failed to get VirtualMachineClass %s: %w", vmCtx.VM.Spec.ClassName, err)
+	}
+
+	if !isVMResizeRequired(vmCtx.VM, vmClass) {
+		if vmCtx.VM.Status.AppliedClassName == "" {
+			setVMAppliedClass(vmCtx.VM, vmClass)
+		}
+		return false, nil
+	}
+
+	updateArgs, err := getUpdateArgsFn()
+	if err != nil {
+		return false, err
+	}
+	vmClassSpec := updateArgs.VMClass.Spec
+
+	configSpec := &vimTypes.VirtualMachineConfigSpec{}
+	UpdateHardwareConfigSpec(config, configSpec, &vmClassSpec)
+	UpdateConfigSpecCPUAllocation(config, configSpec, &vmClassSpec, updateArgs.MinCPUFreq)
+	UpdateConfigSpecMemoryAllocation(config, configSpec, &vmClassSpec)
+
+	defaultConfigSpec := &vimTypes.VirtualMachineConfigSpec{}
+	if apiEquality.Semantic.DeepEqual(configSpec, defaultConfigSpec) {
+		setVMAppliedClass(vmCtx.VM, updateArgs.VMClass)
+		return false, nil
+	}
+
+	powerCycle := !isOff && !CanHotResize(config, configSpec)
+	if powerCycle && vmCtx.VM.Status.AppliedClassName == updateArgs.VMClass.Name {
+		if _, ok := vmCtx.VM.Annotations[constants.ClassUpdatePowerCycleAnnotation]; !ok {
+			conditions.MarkFalse(vmCtx.VM,
+				v1alpha1.VirtualMachineResizedCondition,
+				v1alpha1.VirtualMachineResizeDeferredReason,
+				v1alpha1.ConditionSeverityInfo,
+				"Deferring the update of VirtualMachineClass %s until the VM is powered off", updateArgs.VMClass.Name)
+			return false, nil
+		}
+	}
+
+	if powerCycle {
+		conditions.MarkFalse(vmCtx.VM,
+			v1alpha1.VirtualMachineResizedCondition,
+			v1alpha1.VirtualMachineResizePendingReason,
+			v1alpha1.ConditionSeverityInfo,
+			"Power cycling VM to apply VirtualMachineClass %s", updateArgs.VMClass.Name)
+
+		poweredOff, err := virtualmachine.PowerOff(vmCtx, resVM.VcVM())
+		if err != nil || !poweredOff {
+			return !poweredOff, err
+		}
+	}
+
+	vmCtx.Logger.Info("Resize Reconfigure", "class", updateArgs.VMClass.Name, "powerCycle", powerCycle, "configSpec", configSpec)
+	if err := resVM.Reconfigure(vmCtx, configSpec); err != nil {
+		vmCtx.Logger.Error(err, "resize reconfigure failed")
+		conditions.MarkFalse(vmCtx.VM,
+			v1alpha1.VirtualMachineResizedCondition,
+			v1alpha1.VirtualMachineResizeFailedReason,
+			v1alpha1.ConditionSeverityError,
+			err.Error())
+		// If the VM was powered off, the resize will be retried before it is powered back on.
+		return false, err
+	}
+
+	if powerCycle {
+		if err := resVM.SetPowerState(vmCtx, v1alpha1.VirtualMachinePoweredOn); err != nil {
+			return false, err
+		}
+	}
+
+	setVMAppliedClass(vmCtx.VM, updateArgs.VMClass)
+	return false, nil
+}
+
+// relocateVMStorage relocates the disks of the VM to a datastore compatible with the storage policy of its
+// StorageClass when the StorageClass has changed. The relocation is not waited on: true is returned while the
+// VM is being relocated so the VM is not otherwise updated until the relocation completes.
+func (s *Session) relocateVMStorage(
+	vmCtx context.VirtualMachineContext,
+	vcVM *object.VirtualMachine,
+	moVM *mo.VirtualMachine) (bool, error) {
+
+	storageClass := vmCtx.VM.Spec.StorageClass
+	if storageClass == "" {
+		return false, nil
+	}
+
+	if taskID := vmCtx.VM.Annotations[constants.StorageRelocateTaskAnnotation]; taskID != "" {
+		return s.checkRelocateVMStorageTask(vmCtx, taskID)
+	}
+
+	if vmCtx.VM.Status.AppliedStorageClass == "" {
+		// The StorageClass applied to the VM is not known, such as when the VM predates relocation
+		// support, so compare the storage policy of the VM with the policy of its StorageClass.
+		applied, err := s.isStorageClassApplied(vmCtx, vcVM, storageClass)
+		if err != nil {
+			return false, err
+		}
+		if applied {
+			vmCtx.VM.Status.AppliedStorageClass = storageClass
+		}
+	}
+
+	if vmCtx.VM.Status.AppliedStorageClass == storageClass {
+		return false, nil
+	}
+
+	if moVM.Config == nil {
+		return false, fmt.Errorf("VM config is not available, connectionState=%s", moVM.Runtime.ConnectionState)
+	}
+
+	relocateSpec, err := s.relocateVMStorageSpec(vmCtx, moVM, s.Cluster)
+	if err != nil {
+		conditions.MarkFalse(vmCtx.VM,
+			v1alpha1.VirtualMachineStorageRelocatedCondition,
+			v1alpha1.VirtualMachineStorageRelocationFailedReason,
+			v1alpha1.ConditionSeverityError,
+			err.Error())
+		return false, err
+	}
+
+	vmCtx.Logger.Info("Relocating VM storage", "storageClass", storageClass, "relocateSpec", relocateSpec)
+	task, err := vcVM.Relocate(vmCtx, *relocateSpec, vimTypes.VirtualMachineMovePriorityDefaultPriority)
+	if err != nil {
+		conditions.MarkFalse(vmCtx.VM,
+			v1alpha1.VirtualMachineStorageRelocatedCondition,
+			v1alpha1.VirtualMachineStorageRelocationFailedReason,
+			v1alpha1.ConditionSeverityError,
+			err.Error())
+		return false, err
+	}
+
+	if vmCtx.VM.Annotations == nil {
+		vmCtx.VM.Annotations = map[string]string{}
+	}
+	vmCtx.VM.Annotations[constants.StorageRelocateTaskAnnotation] = task.Reference().Value
+
+	conditions.MarkFalse(vmCtx.VM,
+		v1alpha1.VirtualMachineStorageRelocatedCondition,
+		v1alpha1.VirtualMachineStorageRelocationInProgressReason,
+		v1alpha1.ConditionSeverityInfo,
+		"Relocating storage to StorageClass %s", storageClass)
+
+	return true, nil
+}
+
+// isStorageClassApplied returns true if the storage policy of the VM is the policy of the StorageClass.
+func (s *Session) isStorageClassApplied(
+	vmCtx context.VirtualMachineContext,
+	vcVM *object.VirtualMachine,
+	storageClass string) (bool, error) {
+
+	profileID, err := storage.GetStoragePolicyID(vmCtx, s.K8sClient, storageClass)
+	if err != nil {
+		return false, err
+	}
+
+	vmProfileID, err := storage.GetVMStoragePolicyID(vmCtx, s.Client, vcVM.Reference().Value)
+	if err != nil {
+		return false, err
+	}
+
+	return vmProfileID == profileID, nil
+}
+
+// relocateVMStorageSpec returns the RelocateSpec that moves the VM, and its disks other than those of
+// PersistentVolumeClaims, to a datastore of the cluster compatible with the storage policy of its StorageClass.
+func (s *Session) relocateVMStorageSpec(
+	vmCtx context.VirtualMachineContext,
+	moVM *mo.VirtualMachine,
+	cluster *object.ClusterComputeResource) (*vimTypes.VirtualMachineRelocateSpec, error) {
+
+	storageClass := vmCtx.VM.Spec.StorageClass
+
+	profileID, err := storage.GetStoragePolicyID(vmCtx, s.K8sClient, storageClass)
+	if err != nil {
+		return nil, err
+	}
+
+	datastores, err := cluster.Datastores(vmCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]vimTypes.ManagedObjectReference, 0, len(datastores))
+	for _, ds := range datastores {
+		candidates = append(candidates, ds.Reference())
+	}
+
+	compatible, err := storage.GetCompatibleDatastores(vmCtx, s.Client, profileID, candidates)
+	if err != nil {
+		return nil, err
+	}
+	if len(compatible) == 0 {
+		return nil, fmt.Errorf("no datastore is compatible with the storage policy of StorageClass %s", storageClass)
+	}
+
+	// Prefer a datastore the VM is already on so only the storage policy of its disks changes.
+	datastore := compatible[0]
+	for _, ds := range compatible {
+		if len(moVM.Datastore) > 0 && ds.Value == moVM.Datastore[0].Value {
+			datastore = ds
+			break
+		}
+	}
+
+	profile := []vimTypes.BaseVirtualMachineProfileSpec{
+		&vimTypes.VirtualMachineDefinedProfileSpec{ProfileId: profileID},
+	}
+
+	// The disks of PersistentVolumeClaims are managed by CNS so stay on their current datastore.
+	volumeDiskUUIDs := map[string]bool{}
+	for _, volume := range vmCtx.VM.Status.Volumes {
+		if volume.DiskUuid != "" {
+			volumeDiskUUIDs[volume.DiskUuid] = true
+		}
+	}
+
+	var diskLocators []vimTypes.VirtualMachineRelocateSpecDiskLocator
+	for _, dev := range object.VirtualDeviceList(moVM.Config.Hardware.Device).SelectByType((*vimTypes.VirtualDisk)(nil)) {
+		disk := dev.(*vimTypes.VirtualDisk)
+		backing, ok := disk.Backing.(*vimTypes.VirtualDiskFlatVer2BackingInfo)
+		if !ok {
+			continue
+		}
+
+		if volumeDiskUUIDs[backing.Uuid] {
+			if backing.Datastore != nil {
+				diskLocators = append(diskLocators, vimTypes.VirtualMachineRelocateSpecDiskLocator{
+					DiskId:    disk.Key,
+					Datastore: *backing.Datastore,
+				})
+			}
+			continue
+		}
+
+		diskLocators = append(diskLocators, vimTypes.VirtualMachineRelocateSpecDiskLocator{
+			DiskId:    disk.Key,
+			Datastore: datastore,
+			Profile:   profile,
+		})
+	}
+
+	return &vimTypes.VirtualMachineRelocateSpec{
+		Datastore: &datastore,
+		Disk:      diskLocators,
+		Profile:   profile,
+	}, nil
+}
+
+// checkRelocateVMStorageTask updates the VM from the task relocating its storage. True is returned while
+// the task is running.
+func (s *Session) checkRelocateVMStorageTask(
+	vmCtx context.VirtualMachineContext,
+	taskID string) (bool, error) {
+
+	info, err := s.getTaskInfo(vmCtx, taskID)
+	if err != nil {
+		return false, err
+	}
+	if info == nil {
+		// The task has expired so relocate the storage again if needed.
+		vmCtx.Logger.Info("Storage relocate task not found", "taskID", taskID)
+		delete(vmCtx.VM.Annotations, constants.StorageRelocateTaskAnnotation)
+		return false, nil
+	}
+
+	storageClass := vmCtx.VM.Spec.StorageClass
+
+	switch info.State {
+	case vimTypes.TaskInfoStateQueued, vimTypes.TaskInfoStateRunning:
+		conditions.MarkFalse(vmCtx.VM,
+			v1alpha1.VirtualMachineStorageRelocatedCondition,
+			v1alpha1.VirtualMachineStorageRelocationInProgressReason,
+			v1alpha1.ConditionSeverityInfo,
+			"Relocating storage to StorageClass %s: %d%% complete", storageClass, info.Progress)
+		return true, nil
+
+	case vimTypes.TaskInfoStateError:
+		delete(vmCtx.VM.Annotations, constants.StorageRelocateTaskAnnotation)
+
+		msg := "storage relocate task failed"
+		if info.Error != nil {
+			msg = info.Error.LocalizedMessage
+		}
+		conditions.MarkFalse(vmCtx.VM,
+			v1alpha1.VirtualMachineStorageRelocatedCondition,
+			v1alpha1.VirtualMachineStorageRelocationFailedReason,
+			v1alpha1.ConditionSeverityError,
+			msg)
+		// The relocation is retried on the next reconcile.
+		return false, fmt.Errorf("failed to relocate storage to StorageClass %s: %s", storageClass, msg)
+	}
+
+	// The webhook does not allow the StorageClass to change while the VM is being relocated.
+	delete(vmCtx.VM.Annotations, constants.StorageRelocateTaskAnnotation)
+	vmCtx.VM.Status.AppliedStorageClass = storageClass
+	conditions.MarkTrue(vmCtx.VM, v1alpha1.VirtualMachineStorageRelocatedCondition)
+
+	return false, nil
+}
+
+// migrateVM migrates the VM to the zone in its zone label when that differs from the zone the VM is in. The
+// compute and storage of the VM are relocated to the zone, which is a cold migration when the VM is powered
+// off, and its network interfaces are rebound to the networks of the zone. The migration is not waited on:
+// true is returned while the VM is being migrated so the VM is not otherwise updated until it completes.
+func (s *Session) migrateVM(
+	vmCtx context.VirtualMachineContext,
+	vcVM *object.VirtualMachine,
+	moVM *mo.VirtualMachine,
+	getUpdateArgsFn func() (*VMUpdateArgs, error)) (bool, error) {
+
+	if !lib.IsWcpFaultDomainsFSSEnabled() {
+		return false, nil
+	}
+
+	if taskID := vmCtx.VM.Annotations[constants.MigrateTaskAnnotation]; taskID != "" {
+		return s.checkMigrateVMTask(vmCtx, taskID)
+	}
+
+	zoneName := vmCtx.VM.Labels[topology.KubernetesTopologyZoneLabelKey]
+	if zoneName == "" || vmCtx.VM.Status.Zone == "" || zoneName == vmCtx.VM.Status.Zone {
+		return false, nil
+	}
+
+	if moVM.Config == nil {
+		return false, fmt.Errorf("VM config is not available, connectionState=%s", moVM.Runtime.ConnectionState)
+	}
+
+	relocateSpec, err := s.migrateVMSpec(vmCtx, moVM, getUpdateArgsFn)
+	if err != nil {
+		conditions.MarkFalse(vmCtx.VM,
+			v1alpha1.VirtualMachineMigratedCondition,
+			v1alpha1.VirtualMachineMigrationFailedReason,
+			v1alpha1.ConditionSeverityError,
+			err.Error())
+		return false, err
+	}
+
+	vmCtx.Logger.Info("Migrating VM", "fromZone", vmCtx.VM.Status.Zone, "toZone", zoneName, "relocateSpec", relocateSpec)
+	task, err := vcVM.Relocate(vmCtx, *relocateSpec, vimTypes.VirtualMachineMovePriorityDefaultPriority)
+	if err != nil {
+		conditions.MarkFalse(vmCtx.VM,
+			v1alpha1.VirtualMachineMigratedCondition,
+			v1alpha1.VirtualMachineMigrationFailedReason,
+			v1alpha1.ConditionSeverityError,
+			err.Error())
+		return false, err
+	}
+
+	if vmCtx.VM.Annotations == nil {
+		vmCtx.VM.Annotations = map[string]string{}
+	}
+	vmCtx.VM.Annotations[constants.MigrateTaskAnnotation] = task.Reference().Value
+
+	conditions.MarkFalse(vmCtx.VM,
+		v1alpha1.VirtualMachineMigratedCondition,
+		v1alpha1.VirtualMachineMigratingReason,
+		v1alpha1.ConditionSeverityInfo,
+		"Migrating from zone %s to zone %s", vmCtx.VM.Status.Zone, zoneName)
+
+	return true, nil
+}
+
+// migrateVMSpec returns the RelocateSpec that moves the VM to a resource pool, and its storage to a datastore,
+// in the zone in its zone label.
+func (s *Session) migrateVMSpec(
+	vmCtx context.VirtualMachineContext,
+	moVM *mo.VirtualMachine,
+	getUpdateArgsFn func() (*VMUpdateArgs, error)) (*vimTypes.VirtualMachineRelocateSpec, error) {
+
+	if instancestorage.IsConfigured(vmCtx.VM) {
+		return nil, fmt.Errorf("a VM with instance storage cannot be migrated to another zone")
+	}
+	for _, vol := range vmCtx.VM.Spec.Volumes {
+		if vol.PersistentVolumeClaim != nil {
+			return nil, fmt.Errorf("a VM with PersistentVolumeClaim volumes cannot be migrated to another zone")
+		}
+	}
+	if vmCtx.VM.Spec.StorageClass == "" {
+		return nil, fmt.Errorf("a VM without a StorageClass cannot be migrated to another zone")
+	}
+
+	updateArgs, err := getUpdateArgsFn()
+	if err != nil {
+		return nil, err
+	}
+
+	storageClassesToIDs, err := storage.GetVMStoragePoliciesIDs(vmCtx, s.K8sClient)
+	if err != nil {
+		return nil, err
+	}
+
+	var childRPName string
+	if updateArgs.ResourcePolicy != nil {
+		childRPName = updateArgs.ResourcePolicy.Spec.ResourcePool.Name
+	}
+
+	placementConfigSpec := virtualmachine.CreateConfigSpecForPlacement(
+		vmCtx,
+		&updateArgs.VMClass.Spec,
+		updateArgs.MinCPUFreq,
+		storageClassesToIDs,
+		"",
+		updateArgs.ClassConfigSpec)
+
+	result, err := placement.MigrationPlacement(vmCtx, s.K8sClient, s.Client.VimClient(), placementConfigSpec, childRPName)
+	if err != nil {
+		return nil, err
+	}
+
+	clusterRef, err := object.NewResourcePool(s.Client.VimClient(), result.PoolMoRef).Owner(vmCtx)
+	if err != nil {
+		return nil, err
+	}
+	cluster := object.NewClusterComputeResource(s.Client.VimClient(), clusterRef.Reference())
+
+	relocateSpec, err := s.relocateVMStorageSpec(vmCtx, moVM, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	relocateSpec.Pool = &result.PoolMoRef
+	relocateSpec.Host = result.HostMoRef
+
+	// Rebind the network interfaces to the networks of the cluster the VM is migrated to.
+	networkProvider := network.NewProvider(s.K8sClient, s.Client.VimClient(), s.Finder, cluster)
+	currentEthCards := object.VirtualDeviceList(moVM.Config.Hardware.Device).SelectByType((*vimTypes.VirtualEthernetCard)(nil))
+
+	for i := range vmCtx.VM.Spec.NetworkInterfaces {
+		if i >= len(currentEthCards) {
+			break
+		}
+
+		info, err := networkProvider.EnsureNetworkInterface(vmCtx, &vmCtx.VM.Spec.NetworkInterfaces[i])
+		if err != nil {
+			return nil, err
+		}
+
+		ethCard := currentEthCards[i].(vimTypes.BaseVirtualEthernetCard).GetVirtualEthernetCard()
+		expectedEthCard := info.Device.(vimTypes.BaseVirtualEthernetCard).GetVirtualEthernetCard()
+		ethCard.Backing = expectedEthCard.Backing
+		ethCard.ExternalId = expectedEthCard.ExternalId
+
+		relocateSpec.DeviceChange = append(relocateSpec.DeviceChange, &vimTypes.VirtualDeviceConfigSpec{
+			Operation: vimTypes.VirtualDeviceConfigSpecOperationEdit,
+			Device:    currentEthCards[i],
+		})
+	}
+
+	return relocateSpec, nil
+}
+
+// checkMigrateVMTask updates the VM from the task migrating it to another zone. True is returned while the
+// task is running.
+func (s *Session) checkMigrateVMTask(
+	vmCtx context.VirtualMachineContext,
+	taskID string) (bool, error) {
+
+	info, err := s.getTaskInfo(vmCtx, taskID)
+	if err != nil {
+		return false, err
+	}
+	if info == nil {
+		// The task has expired so migrate the VM again if needed.
+		vmCtx.Logger.Info("Migrate task not found", "taskID", taskID)
+		delete(vmCtx.VM.Annotations, constants.MigrateTaskAnnotation)
+		return false, nil
+	}
+
+	zoneName := vmCtx.VM.Labels[topology.KubernetesTopologyZoneLabelKey]
+
+	switch info.State {
+	case vimTypes.TaskInfoStateQueued, vimTypes.TaskInfoStateRunning:
+		conditions.MarkFalse(vmCtx.VM,
+			v1alpha1.VirtualMachineMigratedCondition,
+			v1alpha1.VirtualMachineMigratingReason,
+			v1alpha1.ConditionSeverityInfo,
+			"Migrating from zone %s to zone %s: %d%% complete", vmCtx.VM.Status.Zone, zoneName, info.Progress)
+		return true, nil
+
+	case vimTypes.TaskInfoStateError:
+		delete(vmCtx.VM.Annotations, constants.MigrateTaskAnnotation)
+
+		msg := "migrate task failed"
+		if info.Error != nil {
+			msg = info.Error.LocalizedMessage
+		}
+		conditions.MarkFalse(vmCtx.VM,
+			v1alpha1.VirtualMachineMigratedCondition,
+			v1alpha1.VirtualMachineMigrationFailedReason,
+			v1alpha1.ConditionSeverityError,
+			msg)
+		// The migration is retried on the next reconcile.
+		return false, fmt.Errorf("failed to migrate VM to zone %s: %s", zoneName, msg)
+	}
+
+	// The webhook does not allow the zone to change while the VM is being migrated.
+	delete(vmCtx.VM.Annotations, constants.MigrateTaskAnnotation)
+	vmCtx.VM.Status.Zone = zoneName
+	if vmCtx.VM.Spec.StorageClass != "" {
+		// The storage was relocated to the storage policy of the StorageClass too.
+		vmCtx.VM.Status.AppliedStorageClass = vmCtx.VM.Spec.StorageClass
+	}
+	conditions.MarkTrue(vmCtx.VM, v1alpha1.VirtualMachineMigratedCondition)
+
+	// Requeue so the VM is updated with a Session for the cluster it was migrated to.
+	return true, nil
+}
+
+// getTaskInfo returns the info of the task, or nil if the task no longer exists.
+func (s *Session) getTaskInfo(
+	vmCtx context.VirtualMachineContext,
+	taskID string) (*vimTypes.TaskInfo, error) {
+
+	taskRef := vimTypes.ManagedObjectReference{Type: "Task", Value: taskID}
+	task := object.NewTask(s.Client.VimClient(), taskRef)
+
+	var moTask mo.Task
+	if err := task.Properties(vmCtx, taskRef, []string{"info"}, &moTask); err != nil {
+		if isManagedObjectNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &moTask.Info, nil
+}
+
+func isManagedObjectNotFound(err error) bool {
+	if soap.IsSoapFault(err) {
+		vimFault := soap.ToSoapFault(err).VimFault()
+		if _, ok := vimFault.(vimTypes.ManagedObjectNotFound); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (s *Session) attachClusterModule(
 	vmCtx context.VirtualMachineContext,
 	resVM *res.VirtualMachine,
@@ -787,7 +1772,7 @@ func (s *Session) UpdateVirtualMachine(
 
 	resVM := res.NewVMFromObject(vcVM)
 
-	moVM, err := resVM.GetProperties(vmCtx, []string{"config", "runtime"})
+	moVM, err := resVM.GetProperties(vmCtx, []string{"config", "runtime", "datastore"})
 	if err != nil {
 		return err
 	}
@@ -802,22 +1787,65 @@ func (s *Session) UpdateVirtualMachine(
 		}
 	}()
 
+	// The VM is not otherwise updated until it has been migrated to the zone in its zone label.
+	if migrating, err := s.migrateVM(vmCtx, vcVM, moVM, getUpdateArgsFn); err != nil || migrating {
+		return err
+	}
+
+	// The VM is not otherwise updated until its storage has been relocated to its StorageClass.
+	if relocating, err := s.relocateVMStorage(vmCtx, vcVM, moVM); err != nil || relocating {
+		return err
+	}
+
 	isOff := moVM.Runtime.PowerState == vimTypes.VirtualMachinePowerStatePoweredOff
+	isSuspended := moVM.Runtime.PowerState == vimTypes.VirtualMachinePowerStateSuspended
 
 	switch vmCtx.VM.Spec.PowerState {
 	case v1alpha1.VirtualMachinePoweredOff:
-		if !isOff {
-			err := resVM.SetPowerState(vmCtx, v1alpha1.VirtualMachinePoweredOff)
+		if isSuspended {
+			// The guest OS of a suspended VM is not running so cannot be shut down.
+			err := virtualmachine.ChangePowerState(vmCtx, vcVM, vimTypes.VirtualMachinePowerStatePoweredOff)
 			if err != nil {
 				return err
 			}
+		} else if !isOff {
+			poweredOff, err := virtualmachine.PowerOff(vmCtx, vcVM)
+			if err != nil {
+				return err
+			}
+			if !poweredOff {
+				// The guest OS is shutting down.
+				return nil
+			}
+		} else if conditions.GetReason(vmCtx.VM, v1alpha1.GuestShutdownCondition) == v1alpha1.GuestShutdownInProgressReason {
+			conditions.MarkTrue(vmCtx.VM, v1alpha1.GuestShutdownCondition)
 		}
 
 		// BMV: We'll likely want to reconfigure a powered off VM too, but right now
 		// we'll defer that until the pre power on (and until more people complain
-		// that the UI appears wrong).
+		// that the UI appears wrong). The exception is a resize so the VM reflects
+		// its new class while powered off.
+		if config := moVM.Config; config != nil {
+			if _, err := s.resizeVM(vmCtx, resVM, config, true, getUpdateArgsFn); err != nil {
+				return err
+			}
+		}
+
+	case v1alpha1.VirtualMachineSuspended:
+		// A powered off VM cannot be suspended so it is left powered off.
+		if !isOff && !isSuspended {
+			err := virtualmachine.ChangePowerState(vmCtx, vcVM, vimTypes.VirtualMachinePowerStateSuspended)
+			if err != nil {
+				return err
+			}
+		}
 
 	case v1alpha1.VirtualMachinePoweredOn:
+		if isSuspended {
+			// Resume the VM. It is reconfigured on the next update once it is running again.
+			return resVM.SetPowerState(vmCtx, v1alpha1.VirtualMachinePoweredOn)
+		}
+
 		config := moVM.Config
 
 		// See govmomi VirtualMachine::Device() explanation for this check.
@@ -826,6 +1854,16 @@ func (s *Session) UpdateVirtualMachine(
 		}
 
 		if isOff {
+			// The guest OS shut down of the previous power off is no longer relevant.
+			conditions.Delete(vmCtx.VM, v1alpha1.GuestShutdownCondition)
+
+			if imagevolume.IsConfigured(vmCtx.VM) && !imagevolume.IsAttached(vmCtx.VM) {
+				// The VM boots from its image volume so cannot be powered on until the volume controller has
+				// attached it. The VM is reconciled again when the volume status is updated.
+				vmCtx.Logger.Info("Deferring power on until the image volume is attached")
+				return nil
+			}
+
 			updateArgs, err := getUpdateArgsFn()
 			if err != nil {
 				return err
@@ -850,9 +1888,31 @@ func (s *Session) UpdateVirtualMachine(
 			}
 			vmCtx.VM.Annotations[FirstBootDoneAnnotation] = "true"
 		} else {
+			if conditions.GetReason(vmCtx.VM, v1alpha1.VirtualMachineResizedCondition) != v1alpha1.VirtualMachineResizePendingReason {
+				// The guest OS shut down of the previous power off is no longer relevant, unless the VM is
+				// being power cycled to be resized.
+				conditions.Delete(vmCtx.VM, v1alpha1.GuestShutdownCondition)
+			}
+
+			resizing, err := s.resizeVM(vmCtx, resVM, config, false, getUpdateArgsFn)
+			if err != nil || resizing {
+				return err
+			}
+
+			// The ISO files of the CD-ROMs are resolved with the update args, so only get those when the VM
+			// has CD-ROMs.
+			var cdromIsoFiles []string
+			if len(vmCtx.VM.Spec.Cdrom) > 0 {
+				updateArgs, err := getUpdateArgsFn()
+				if err != nil {
+					return err
+				}
+				cdromIsoFiles = updateArgs.CdromIsoFiles
+			}
+
 			// don't pass classConfigSpec to poweredOnVMReconfigure when VM is already powered on
 			// since we don't have to get VM class at this point.
-			err = s.poweredOnVMReconfigure(vmCtx, resVM, config)
+			err = s.poweredOnVMReconfigure(vmCtx, resVM, config, cdromIsoFiles)
 			if err != nil {
 				return err
 			}
@@ -1,4 +1,4 @@
-// Copyright (c) 2021 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2021-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package session_test
@@ -386,6 +386,95 @@ var _ = Describe("Update ConfigSpec", func() {
 		})
 	})
 
+	Context("Hot Resize", func() {
+		var canHotResize bool
+
+		BeforeEach(func() {
+			config.Hardware.NumCPU = 2
+			config.Hardware.MemoryMB = 2048
+		})
+
+		JustBeforeEach(func() {
+			canHotResize = session.CanHotResize(config, configSpec)
+		})
+
+		It("allows an empty config spec", func() {
+			Expect(canHotResize).To(BeTrue())
+		})
+
+		Context("CPUs are added", func() {
+			BeforeEach(func() {
+				configSpec.NumCPUs = 4
+			})
+
+			It("requires a power cycle when CPU hot-add is disabled", func() {
+				Expect(canHotResize).To(BeFalse())
+			})
+
+			When("CPU hot-add is enabled", func() {
+				BeforeEach(func() {
+					config.CpuHotAddEnabled = pointer.Bool(true)
+				})
+
+				It("allows hot resize", func() {
+					Expect(canHotResize).To(BeTrue())
+				})
+			})
+		})
+
+		Context("CPUs are removed", func() {
+			BeforeEach(func() {
+				configSpec.NumCPUs = 1
+				config.CpuHotAddEnabled = pointer.Bool(true)
+			})
+
+			It("requires a power cycle when CPU hot-remove is disabled", func() {
+				Expect(canHotResize).To(BeFalse())
+			})
+
+			When("CPU hot-remove is enabled", func() {
+				BeforeEach(func() {
+					config.CpuHotRemoveEnabled = pointer.Bool(true)
+				})
+
+				It("allows hot resize", func() {
+					Expect(canHotResize).To(BeTrue())
+				})
+			})
+		})
+
+		Context("Memory is added", func() {
+			BeforeEach(func() {
+				configSpec.MemoryMB = 4096
+			})
+
+			It("requires a power cycle when memory hot-add is disabled", func() {
+				Expect(canHotResize).To(BeFalse())
+			})
+
+			When("memory hot-add is enabled", func() {
+				BeforeEach(func() {
+					config.MemoryHotAddEnabled = pointer.Bool(true)
+				})
+
+				It("allows hot resize", func() {
+					Expect(canHotResize).To(BeTrue())
+				})
+			})
+		})
+
+		Context("Memory is removed", func() {
+			BeforeEach(func() {
+				configSpec.MemoryMB = 1024
+				config.MemoryHotAddEnabled = pointer.Bool(true)
+			})
+
+			It("requires a power cycle", func() {
+				Expect(canHotResize).To(BeFalse())
+			})
+		})
+	})
+
 	Context("ExtraConfig", func() {
 		var vmClassSpec *vmopv1alpha1.VirtualMachineClassSpec
 		var classConfigSpec *vimTypes.VirtualMachineConfigSpec
@@ -805,6 +894,159 @@ var _ = Describe("Update ConfigSpec", func() {
 			Expect(configSpec.Firmware).To(BeEmpty())
 		})
 
+		It("Set boot options firmware differing to current vm firmware", func() {
+			vm.Spec.BootOptions = &vmopv1alpha1.VirtualMachineBootOptions{Firmware: "efi"}
+			session.UpdateConfigSpecFirmware(config, configSpec, vm)
+			Expect(configSpec.Firmware).To(Equal("efi"))
+		})
+
+		It("Set boot options firmware takes precedence over firmware annotation", func() {
+			vm.Annotations[constants.FirmwareOverrideAnnotation] = "efi"
+			vm.Spec.BootOptions = &vmopv1alpha1.VirtualMachineBootOptions{Firmware: "bios"}
+			session.UpdateConfigSpecFirmware(config, configSpec, vm)
+			Expect(configSpec.Firmware).To(BeEmpty())
+		})
+	})
+
+	Context("BootOptions", func() {
+		var vm *vmopv1alpha1.VirtualMachine
+
+		BeforeEach(func() {
+			vm = &vmopv1alpha1.VirtualMachine{}
+			config.Hardware.Device = []vimTypes.BaseVirtualDevice{
+				&vimTypes.VirtualDisk{VirtualDevice: vimTypes.VirtualDevice{Key: 2000}},
+				&vimTypes.VirtualVmxnet3{VirtualVmxnet: vimTypes.VirtualVmxnet{
+					VirtualEthernetCard: vimTypes.VirtualEthernetCard{VirtualDevice: vimTypes.VirtualDevice{Key: 4000}}}},
+			}
+		})
+
+		It("No boot options", func() {
+			session.UpdateConfigSpecBootOptions(config, configSpec, vm)
+			Expect(configSpec.BootOptions).To(BeNil())
+		})
+
+		It("Secure boot enabled", func() {
+			vm.Spec.BootOptions = &vmopv1alpha1.VirtualMachineBootOptions{EFISecureBootEnabled: true}
+			session.UpdateConfigSpecBootOptions(config, configSpec, vm)
+			Expect(configSpec.BootOptions).ToNot(BeNil())
+			Expect(configSpec.BootOptions.EfiSecureBootEnabled).To(HaveValue(BeTrue()))
+			Expect(configSpec.BootOptions.BootOrder).To(BeEmpty())
+		})
+
+		It("Secure boot equal to current vm secure boot", func() {
+			config.BootOptions = &vimTypes.VirtualMachineBootOptions{EfiSecureBootEnabled: pointer.Bool(true)}
+			vm.Spec.BootOptions = &vmopv1alpha1.VirtualMachineBootOptions{EFISecureBootEnabled: true}
+			session.UpdateConfigSpecBootOptions(config, configSpec, vm)
+			Expect(configSpec.BootOptions).To(BeNil())
+		})
+
+		It("Boot order", func() {
+			vm.Spec.BootOptions = &vmopv1alpha1.VirtualMachineBootOptions{
+				BootOrder: []vmopv1alpha1.VirtualMachineBootDevice{
+					vmopv1alpha1.VirtualMachineBootDeviceNetwork,
+					vmopv1alpha1.VirtualMachineBootDeviceCDROM,
+					vmopv1alpha1.VirtualMachineBootDeviceDisk,
+				},
+			}
+			session.UpdateConfigSpecBootOptions(config, configSpec, vm)
+			Expect(configSpec.BootOptions).ToNot(BeNil())
+			Expect(configSpec.BootOptions.EfiSecureBootEnabled).To(BeNil())
+			// The VM does not have a CD-ROM.
+			Expect(configSpec.BootOptions.BootOrder).To(Equal([]vimTypes.BaseVirtualMachineBootOptionsBootableDevice{
+				&vimTypes.VirtualMachineBootOptionsBootableEthernetDevice{DeviceKey: 4000},
+				&vimTypes.VirtualMachineBootOptionsBootableDiskDevice{DeviceKey: 2000},
+			}))
+		})
+
+		It("Boot order refers to the devices in the config spec", func() {
+			configSpec.DeviceChange = []vimTypes.BaseVirtualDeviceConfigSpec{
+				&vimTypes.VirtualDeviceConfigSpec{
+					Operation: vimTypes.VirtualDeviceConfigSpecOperationRemove,
+					Device:    config.Hardware.Device[1],
+				},
+				&vimTypes.VirtualDeviceConfigSpec{
+					Operation: vimTypes.VirtualDeviceConfigSpecOperationAdd,
+					Device: &vimTypes.VirtualE1000{VirtualEthernetCard: vimTypes.VirtualEthernetCard{
+						VirtualDevice: vimTypes.VirtualDevice{Key: -100}}},
+				},
+			}
+			vm.Spec.BootOptions = &vmopv1alpha1.VirtualMachineBootOptions{
+				BootOrder: []vmopv1alpha1.VirtualMachineBootDevice{vmopv1alpha1.VirtualMachineBootDeviceNetwork},
+			}
+			session.UpdateConfigSpecBootOptions(config, configSpec, vm)
+			Expect(configSpec.BootOptions).ToNot(BeNil())
+			Expect(configSpec.BootOptions.BootOrder).To(Equal([]vimTypes.BaseVirtualMachineBootOptionsBootableDevice{
+				&vimTypes.VirtualMachineBootOptionsBootableEthernetDevice{DeviceKey: -100},
+			}))
+		})
+
+		It("Boot order equal to current vm boot order", func() {
+			config.BootOptions = &vimTypes.VirtualMachineBootOptions{
+				BootOrder: []vimTypes.BaseVirtualMachineBootOptionsBootableDevice{
+					&vimTypes.VirtualMachineBootOptionsBootableDiskDevice{DeviceKey: 2000},
+				},
+			}
+			vm.Spec.BootOptions = &vmopv1alpha1.VirtualMachineBootOptions{
+				BootOrder: []vmopv1alpha1.VirtualMachineBootDevice{vmopv1alpha1.VirtualMachineBootDeviceDisk},
+			}
+			session.UpdateConfigSpecBootOptions(config, configSpec, vm)
+			Expect(configSpec.BootOptions).To(BeNil())
+		})
+	})
+
+	Context("VirtualTPM", func() {
+		var vm *vmopv1alpha1.VirtualMachine
+
+		BeforeEach(func() {
+			vm = &vmopv1alpha1.VirtualMachine{}
+		})
+
+		It("No virtual TPM", func() {
+			session.UpdateConfigSpecVirtualTPM(config, configSpec, vm)
+			Expect(configSpec.DeviceChange).To(BeEmpty())
+			Expect(configSpec.Crypto).To(BeNil())
+		})
+
+		Context("Virtual TPM is set", func() {
+			BeforeEach(func() {
+				vm.Spec.VirtualTPM = &vmopv1alpha1.VirtualMachineVirtualTPM{KeyProviderID: "key-provider"}
+			})
+
+			It("Adds the virtual TPM and encrypts the VM", func() {
+				configSpec.DeviceChange = []vimTypes.BaseVirtualDeviceConfigSpec{
+					&vimTypes.VirtualDeviceConfigSpec{
+						Operation: vimTypes.VirtualDeviceConfigSpecOperationAdd,
+						Device:    &vimTypes.VirtualE1000{VirtualEthernetCard: vimTypes.VirtualEthernetCard{VirtualDevice: vimTypes.VirtualDevice{Key: -1}}},
+					},
+				}
+
+				session.UpdateConfigSpecVirtualTPM(config, configSpec, vm)
+				Expect(configSpec.DeviceChange).To(HaveLen(2))
+				dc := configSpec.DeviceChange[1].GetVirtualDeviceConfigSpec()
+				Expect(dc.Operation).To(Equal(vimTypes.VirtualDeviceConfigSpecOperationAdd))
+				Expect(dc.Device).To(BeAssignableToTypeOf(&vimTypes.VirtualTPM{}))
+				Expect(dc.Device.GetVirtualDevice().Key).To(Equal(int32(-2)))
+
+				Expect(configSpec.Crypto).To(BeAssignableToTypeOf(&vimTypes.CryptoSpecEncrypt{}))
+				crypto := configSpec.Crypto.(*vimTypes.CryptoSpecEncrypt)
+				Expect(crypto.CryptoKeyId.ProviderId).ToNot(BeNil())
+				Expect(crypto.CryptoKeyId.ProviderId.Id).To(Equal("key-provider"))
+			})
+
+			It("Adds the virtual TPM to an encrypted VM", func() {
+				config.KeyId = &vimTypes.CryptoKeyId{KeyId: "key"}
+				session.UpdateConfigSpecVirtualTPM(config, configSpec, vm)
+				Expect(configSpec.DeviceChange).To(HaveLen(1))
+				Expect(configSpec.Crypto).To(BeNil())
+			})
+
+			It("VM already has a virtual TPM", func() {
+				config.Hardware.Device = []vimTypes.BaseVirtualDevice{&vimTypes.VirtualTPM{}}
+				session.UpdateConfigSpecVirtualTPM(config, configSpec, vm)
+				Expect(configSpec.DeviceChange).To(BeEmpty())
+				Expect(configSpec.Crypto).To(BeNil())
+			})
+		})
 	})
 
 	Context("DeviceGroups", func() {
@@ -1393,4 +1635,264 @@ var _ = Describe("Update ConfigSpec", func() {
 			})
 		})
 	})
+
+	Context("CD-ROM Changes", func() {
+		const (
+			isoFile1 = "[datastore1] contentlib-lib-id/item-id-1/ubuntu.iso"
+			isoFile2 = "[datastore1] contentlib-lib-id/item-id-2/tools.iso"
+		)
+
+		var (
+			vm            *vmopv1alpha1.VirtualMachine
+			isoFiles      []string
+			devices       object.VirtualDeviceList
+			isPoweredOn   bool
+			deviceChanges []vimTypes.BaseVirtualDeviceConfigSpec
+			err           error
+
+			ideController *vimTypes.VirtualIDEController
+		)
+
+		newCdrom := func(key int32, isoFile string, connected bool) *vimTypes.VirtualCdrom {
+			return &vimTypes.VirtualCdrom{
+				VirtualDevice: vimTypes.VirtualDevice{
+					Key:           key,
+					ControllerKey: ideController.Key,
+					Backing: &vimTypes.VirtualCdromIsoBackingInfo{
+						VirtualDeviceFileBackingInfo: vimTypes.VirtualDeviceFileBackingInfo{
+							FileName: isoFile,
+						},
+					},
+					Connectable: &vimTypes.VirtualDeviceConnectInfo{
+						Connected:      connected,
+						StartConnected: connected,
+					},
+				},
+			}
+		}
+
+		BeforeEach(func() {
+			vm = &vmopv1alpha1.VirtualMachine{}
+			isoFiles = nil
+			isPoweredOn = false
+			ideController = &vimTypes.VirtualIDEController{
+				VirtualController: vimTypes.VirtualController{
+					VirtualDevice: vimTypes.VirtualDevice{Key: 200},
+				},
+			}
+			devices = object.VirtualDeviceList{ideController}
+		})
+
+		JustBeforeEach(func() {
+			deviceChanges, err = session.UpdateCdromDeviceChanges(vm, isoFiles, devices, isPoweredOn)
+		})
+
+		Context("No CD-ROMs", func() {
+			It("returns empty list", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deviceChanges).To(BeEmpty())
+			})
+		})
+
+		Context("Add CD-ROM", func() {
+			BeforeEach(func() {
+				vm.Spec.Cdrom = []vmopv1alpha1.VirtualMachineCdrom{{Name: "cdrom1", ImageName: "ubuntu"}}
+				isoFiles = []string{isoFile1}
+			})
+
+			It("returns add device change", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deviceChanges).To(HaveLen(1))
+
+				configSpec := deviceChanges[0].GetVirtualDeviceConfigSpec()
+				Expect(configSpec.Operation).To(Equal(vimTypes.VirtualDeviceConfigSpecOperationAdd))
+				cdrom, ok := configSpec.Device.(*vimTypes.VirtualCdrom)
+				Expect(ok).To(BeTrue())
+				Expect(cdrom.Key).To(Equal(virtualmachine.CdromStartDeviceKey))
+				Expect(cdrom.ControllerKey).To(Equal(ideController.Key))
+				Expect(cdrom.Backing.(*vimTypes.VirtualCdromIsoBackingInfo).FileName).To(Equal(isoFile1))
+				Expect(cdrom.Connectable.StartConnected).To(BeTrue())
+			})
+
+			Context("VM is powered on", func() {
+				BeforeEach(func() {
+					isPoweredOn = true
+				})
+
+				It("returns empty list", func() {
+					Expect(err).ToNot(HaveOccurred())
+					Expect(deviceChanges).To(BeEmpty())
+				})
+			})
+
+			Context("IDE controller has no free unit", func() {
+				BeforeEach(func() {
+					devices = append(devices,
+						&vimTypes.VirtualDisk{VirtualDevice: vimTypes.VirtualDevice{Key: 2000, ControllerKey: ideController.Key}},
+						&vimTypes.VirtualDisk{VirtualDevice: vimTypes.VirtualDevice{Key: 2001, ControllerKey: ideController.Key}})
+				})
+
+				It("returns add device changes for a SATA controller and the CD-ROM", func() {
+					Expect(err).ToNot(HaveOccurred())
+					Expect(deviceChanges).To(HaveLen(2))
+
+					configSpec := deviceChanges[0].GetVirtualDeviceConfigSpec()
+					Expect(configSpec.Operation).To(Equal(vimTypes.VirtualDeviceConfigSpecOperationAdd))
+					controller, ok := configSpec.Device.(*vimTypes.VirtualAHCIController)
+					Expect(ok).To(BeTrue())
+					Expect(controller.Key).To(Equal(virtualmachine.SATAControllerStartDeviceKey))
+					Expect(controller.BusNumber).To(BeEquivalentTo(0))
+
+					configSpec = deviceChanges[1].GetVirtualDeviceConfigSpec()
+					Expect(configSpec.Operation).To(Equal(vimTypes.VirtualDeviceConfigSpecOperationAdd))
+					cdrom, ok := configSpec.Device.(*vimTypes.VirtualCdrom)
+					Expect(ok).To(BeTrue())
+					Expect(cdrom.ControllerKey).To(Equal(controller.Key))
+					Expect(cdrom.UnitNumber).ToNot(BeNil())
+				})
+
+				Context("VM has the maximum number of SATA controllers and none has a free unit", func() {
+					BeforeEach(func() {
+						for i := int32(0); i < 4; i++ {
+							sataController := &vimTypes.VirtualAHCIController{
+								VirtualSATAController: vimTypes.VirtualSATAController{
+									VirtualController: vimTypes.VirtualController{
+										VirtualDevice: vimTypes.VirtualDevice{Key: 15000 + i},
+										BusNumber:     i,
+									},
+								},
+							}
+							devices = append(devices, sataController)
+							for unit := int32(0); unit < 30; unit++ {
+								devices = append(devices, &vimTypes.VirtualDisk{
+									VirtualDevice: vimTypes.VirtualDevice{Key: 16000 + 100*i + unit, ControllerKey: sataController.Key},
+								})
+							}
+						}
+					})
+
+					It("returns error", func() {
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("no SATA controller can be added for CD-ROM cdrom1"))
+					})
+				})
+			})
+		})
+
+		Context("CD-ROM matches", func() {
+			BeforeEach(func() {
+				vm.Spec.Cdrom = []vmopv1alpha1.VirtualMachineCdrom{{Name: "cdrom1", ImageName: "ubuntu"}}
+				isoFiles = []string{isoFile1}
+				// The CD-ROM of a VM that is not powered on starts connected but is not connected yet.
+				cdrom := newCdrom(3000, isoFile1, true)
+				cdrom.Connectable.Connected = false
+				devices = append(devices, cdrom)
+			})
+
+			It("returns empty list", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deviceChanges).To(BeEmpty())
+			})
+
+			Context("VM is powered on and the CD-ROM is not connected", func() {
+				BeforeEach(func() {
+					isPoweredOn = true
+				})
+
+				It("returns edit device change that connects the CD-ROM", func() {
+					Expect(err).ToNot(HaveOccurred())
+					Expect(deviceChanges).To(HaveLen(1))
+
+					configSpec := deviceChanges[0].GetVirtualDeviceConfigSpec()
+					Expect(configSpec.Operation).To(Equal(vimTypes.VirtualDeviceConfigSpecOperationEdit))
+					Expect(configSpec.Device.GetVirtualDevice().Key).To(Equal(int32(3000)))
+					Expect(configSpec.Device.GetVirtualDevice().Connectable.Connected).To(BeTrue())
+				})
+			})
+		})
+
+		Context("CD-ROM image is changed", func() {
+			BeforeEach(func() {
+				vm.Spec.Cdrom = []vmopv1alpha1.VirtualMachineCdrom{{Name: "cdrom1", ImageName: "tools"}}
+				isoFiles = []string{isoFile2}
+				devices = append(devices, newCdrom(3000, isoFile1, true))
+				isPoweredOn = true
+			})
+
+			It("returns edit device change", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deviceChanges).To(HaveLen(1))
+
+				configSpec := deviceChanges[0].GetVirtualDeviceConfigSpec()
+				Expect(configSpec.Operation).To(Equal(vimTypes.VirtualDeviceConfigSpecOperationEdit))
+				Expect(configSpec.Device.GetVirtualDevice().Backing.(*vimTypes.VirtualCdromIsoBackingInfo).FileName).To(Equal(isoFile2))
+			})
+		})
+
+		Context("CD-ROM is disconnected", func() {
+			BeforeEach(func() {
+				vm.Spec.Cdrom = []vmopv1alpha1.VirtualMachineCdrom{{Name: "cdrom1", ImageName: "ubuntu", Connected: pointer.Bool(false)}}
+				isoFiles = []string{isoFile1}
+				devices = append(devices, newCdrom(3000, isoFile1, true))
+				isPoweredOn = true
+			})
+
+			It("returns edit device change", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deviceChanges).To(HaveLen(1))
+
+				configSpec := deviceChanges[0].GetVirtualDeviceConfigSpec()
+				Expect(configSpec.Operation).To(Equal(vimTypes.VirtualDeviceConfigSpecOperationEdit))
+				Expect(configSpec.Device.GetVirtualDevice().Connectable.Connected).To(BeFalse())
+				Expect(configSpec.Device.GetVirtualDevice().Connectable.StartConnected).To(BeFalse())
+			})
+		})
+
+		Context("CD-ROM is removed", func() {
+			BeforeEach(func() {
+				devices = append(devices, newCdrom(3000, isoFile1, false))
+			})
+
+			It("returns remove device change", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deviceChanges).To(HaveLen(1))
+
+				configSpec := deviceChanges[0].GetVirtualDeviceConfigSpec()
+				Expect(configSpec.Operation).To(Equal(vimTypes.VirtualDeviceConfigSpecOperationRemove))
+				Expect(configSpec.Device.GetVirtualDevice().Key).To(Equal(int32(3000)))
+			})
+
+			Context("VM is powered on", func() {
+				BeforeEach(func() {
+					isPoweredOn = true
+				})
+
+				It("returns empty list", func() {
+					Expect(err).ToNot(HaveOccurred())
+					Expect(deviceChanges).To(BeEmpty())
+				})
+			})
+		})
+
+		Context("CD-ROM that is not backed by a content library ISO", func() {
+			BeforeEach(func() {
+				devices = append(devices, newCdrom(3000, "[datastore1] vm/guest-tools.iso", false))
+			})
+
+			It("returns empty list", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deviceChanges).To(BeEmpty())
+			})
+		})
+
+		Context("Number of ISO files does not match the number of CD-ROMs", func() {
+			BeforeEach(func() {
+				vm.Spec.Cdrom = []vmopv1alpha1.VirtualMachineCdrom{{Name: "cdrom1", ImageName: "ubuntu"}}
+			})
+
+			It("returns error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
 })
@@ -0,0 +1,57 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package storage
+
+import (
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/pbm"
+	pbmTypes "github.com/vmware/govmomi/pbm/types"
+	vimTypes "github.com/vmware/govmomi/vim25/types"
+
+	"github.com/vmware-tanzu/vm-operator/pkg/context"
+	vcclient "github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/client"
+)
+
+// GetCompatibleDatastores returns the datastores, of the candidate datastores, that are compatible with the
+// storage profile.
+func GetCompatibleDatastores(
+	vmCtx context.VirtualMachineContext,
+	vcClient *vcclient.Client,
+	storageProfileID string,
+	candidates []vimTypes.ManagedObjectReference) ([]vimTypes.ManagedObjectReference, error) {
+
+	c, err := pbm.NewClient(vmCtx, vcClient.VimClient())
+	if err != nil {
+		return nil, err
+	}
+
+	hubs := make([]pbmTypes.PbmPlacementHub, 0, len(candidates))
+	for _, ds := range candidates {
+		hubs = append(hubs, pbmTypes.PbmPlacementHub{
+			HubType: ds.Type,
+			HubId:   ds.Value,
+		})
+	}
+
+	requirements := []pbmTypes.BasePbmPlacementRequirement{
+		&pbmTypes.PbmPlacementCapabilityProfileRequirement{
+			ProfileId: pbmTypes.PbmProfileId{UniqueId: storageProfileID},
+		},
+	}
+
+	result, err := c.CheckRequirements(vmCtx, hubs, nil, requirements)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to check datastores compatible with storage profile ID: %s", storageProfileID)
+	}
+
+	var datastores []vimTypes.ManagedObjectReference
+	for _, hub := range result.CompatibleDatastores() {
+		datastores = append(datastores, vimTypes.ManagedObjectReference{
+			Type:  hub.HubType,
+			Value: hub.HubId,
+		})
+	}
+
+	return datastores, nil
+}
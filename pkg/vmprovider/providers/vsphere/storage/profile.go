@@ -0,0 +1,45 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package storage
+
+import (
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/pbm"
+	pbmMethods "github.com/vmware/govmomi/pbm/methods"
+	pbmTypes "github.com/vmware/govmomi/pbm/types"
+
+	"github.com/vmware-tanzu/vm-operator/pkg/context"
+	vcclient "github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/client"
+)
+
+// GetVMStoragePolicyID returns the ID of the storage policy associated with the home of the VM, or an
+// empty string when the VM does not have a storage policy.
+func GetVMStoragePolicyID(
+	vmCtx context.VirtualMachineContext,
+	vcClient *vcclient.Client,
+	vmMoID string) (string, error) {
+
+	c, err := pbm.NewClient(vmCtx, vcClient.VimClient())
+	if err != nil {
+		return "", err
+	}
+
+	req := pbmTypes.PbmQueryAssociatedProfile{
+		This: c.ServiceContent.ProfileManager,
+		Entity: pbmTypes.PbmServerObjectRef{
+			ObjectType: string(pbmTypes.PbmObjectTypeVirtualMachine),
+			Key:        vmMoID,
+		},
+	}
+
+	res, err := pbmMethods.PbmQueryAssociatedProfile(vmCtx, c, &req)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to query the storage policy of VM %s", vmMoID)
+	}
+
+	if len(res.Returnval) == 0 {
+		return "", nil
+	}
+	return res.Returnval[0].UniqueId, nil
+}
@@ -1,4 +1,4 @@
-// Copyright (c) 2022 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2022-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package virtualmachine
@@ -9,6 +9,7 @@ import (
 	"github.com/vmware/govmomi/vim25/types"
 
 	"github.com/vmware-tanzu/vm-operator/pkg/context"
+	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider"
 )
 
 func DeleteVirtualMachine(
@@ -23,8 +24,19 @@ func DeleteVirtualMachine(
 	// Only a powered off VM can be destroyed.
 	if state != types.VirtualMachinePowerStatePoweredOff {
 		vmCtx.Logger.Info("Powering off VM prior to destroy", "currentState", state)
-		if err := ChangePowerState(vmCtx, vcVM, types.VirtualMachinePowerStatePoweredOff); err != nil {
-			return err
+		if state == types.VirtualMachinePowerStateSuspended {
+			// The guest OS of a suspended VM is not running so cannot be shut down.
+			if err := ChangePowerState(vmCtx, vcVM, types.VirtualMachinePowerStatePoweredOff); err != nil {
+				return err
+			}
+		} else {
+			poweredOff, err := PowerOff(vmCtx, vcVM)
+			if err != nil {
+				return err
+			}
+			if !poweredOff {
+				return vmprovider.ErrGuestShutdownInProgress
+			}
 		}
 	}
 
@@ -1,4 +1,4 @@
-// Copyright (c) 2022 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2022-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package virtualmachine_test
@@ -9,6 +9,7 @@ import (
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/vim25/types"
 
+	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
 	"github.com/vmware-tanzu/vm-operator/pkg/context"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/virtualmachine"
 	"github.com/vmware-tanzu/vm-operator/test/builder"
@@ -68,4 +69,17 @@ func deleteTests() {
 
 		Expect(ctx.GetVMFromMoID(moID)).To(BeNil())
 	})
+
+	It("Shuts down guest of VM that is on prior to delete", func() {
+		moID := vcVM.Reference().Value
+		vmCtx.VM.Spec.PowerOffMode = vmopv1alpha1.VirtualMachinePowerOffModeSoft
+
+		err := virtualmachine.DeleteVirtualMachine(vmCtx, vcVM)
+		Expect(err).To(MatchError(ContainSubstring("waiting for guest OS to shut down")))
+		Expect(ctx.GetVMFromMoID(moID)).ToNot(BeNil())
+
+		err = virtualmachine.DeleteVirtualMachine(vmCtx, vcVM)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ctx.GetVMFromMoID(moID)).To(BeNil())
+	})
 }
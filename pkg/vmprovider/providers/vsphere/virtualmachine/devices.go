@@ -16,6 +16,8 @@ const (
 	// A negative device range is traditionally used.
 	pciDevicesStartDeviceKey      = int32(-200)
 	instanceStorageStartDeviceKey = int32(-300)
+	CdromStartDeviceKey           = int32(-400)
+	SATAControllerStartDeviceKey  = int32(-500)
 )
 
 func CreatePCIPassThroughDevice(deviceKey int32, backingInfo vimTypes.BaseVirtualDeviceBackingInfo) vimTypes.BaseVirtualDevice {
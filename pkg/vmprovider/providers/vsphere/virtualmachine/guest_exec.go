@@ -0,0 +1,157 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package virtualmachine
+
+import (
+	goctx "context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/guest"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"github.com/vmware-tanzu/vm-operator/pkg/context"
+	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider"
+)
+
+// guestCommandPollInterval is how often the guest is polled for the exit of the command.
+const guestCommandPollInterval = time.Second
+
+// guestCommandArguments returns the arguments as the single string the guest operations expect.
+// A Linux guest splits the string like a shell would, so each argument is single quoted to keep
+// its boundaries and to not have any of its characters interpreted. A Windows guest passes the
+// string as the command line of the program, so each argument is quoted the way the Windows C
+// runtime parses it back into arguments.
+func guestCommandArguments(args []string, windows bool) string {
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		if windows {
+			quoted = append(quoted, windowsCommandArgument(arg))
+		} else {
+			quoted = append(quoted, "'"+strings.ReplaceAll(arg, "'", `'\''`)+"'")
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+// windowsCommandArgument quotes the argument following the Windows command line parsing rules:
+// the argument is double quoted when it is empty or contains whitespace or a double quote, each
+// double quote is escaped with a backslash, and backslashes are only doubled when they precede
+// a double quote.
+func windowsCommandArgument(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n\v\"") {
+		return arg
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	backslashes := 0
+	for i := 0; i < len(arg); i++ {
+		switch c := arg[i]; c {
+		case '\\':
+			backslashes++
+		case '"':
+			b.WriteString(strings.Repeat(`\`, 2*backslashes+1))
+			b.WriteByte(c)
+			backslashes = 0
+		default:
+			b.WriteString(strings.Repeat(`\`, backslashes))
+			b.WriteByte(c)
+			backslashes = 0
+		}
+	}
+	// Double the trailing backslashes so they do not escape the closing double quote.
+	b.WriteString(strings.Repeat(`\`, 2*backslashes))
+	b.WriteByte('"')
+	return b.String()
+}
+
+// isWindowsGuest returns true if the guest reports it is running Windows.
+func isWindowsGuest(vmCtx context.VirtualMachineContext, vcVM *object.VirtualMachine) (bool, error) {
+	var o mo.VirtualMachine
+	if err := vcVM.Properties(vmCtx, vcVM.Reference(), []string{"guest.guestFamily"}, &o); err != nil {
+		return false, err
+	}
+	return o.Guest != nil && o.Guest.GuestFamily == string(types.VirtualMachineGuestOsFamilyWindowsGuest), nil
+}
+
+// guestCommandError returns the error of the guest operation, wrapping vmprovider.ErrGuestCommandNotRun
+// when the fault is from the guest, such as the guest rejecting the credentials or VMware Tools not
+// running, instead of from vCenter.
+func guestCommandError(err error) error {
+	if !soap.IsSoapFault(err) {
+		return err
+	}
+
+	switch soap.ToSoapFault(err).VimFault().(type) {
+	case types.InvalidGuestLogin, types.GuestPermissionDenied, types.GuestAuthenticationChallenge,
+		types.GuestOperationsUnavailable, types.ToolsUnavailable:
+		return fmt.Errorf("%w: %v", vmprovider.ErrGuestCommandNotRun, err)
+	}
+	return err
+}
+
+// RunGuestCommand runs the command in the guest through the guest operations of VMware Tools,
+// waits for it to exit and returns its exit code. The command is terminated if the context
+// is done before the command exits.
+func RunGuestCommand(
+	vmCtx context.VirtualMachineContext,
+	vcVM *object.VirtualMachine,
+	command []string,
+	username, password string) (int32, error) {
+
+	if len(command) == 0 {
+		return -1, errors.New("no command specified")
+	}
+
+	windows, err := isWindowsGuest(vmCtx, vcVM)
+	if err != nil {
+		return -1, errors.Wrap(err, "failed to get guest family")
+	}
+
+	pm, err := guest.NewOperationsManager(vcVM.Client(), vcVM.Reference()).ProcessManager(vmCtx)
+	if err != nil {
+		return -1, guestCommandError(err)
+	}
+
+	auth := &types.NamePasswordAuthentication{
+		Username: username,
+		Password: password,
+	}
+	spec := &types.GuestProgramSpec{
+		ProgramPath: command[0],
+		Arguments:   guestCommandArguments(command[1:], windows),
+	}
+
+	pid, err := pm.StartProgram(vmCtx, auth, spec)
+	if err != nil {
+		return -1, errors.Wrapf(guestCommandError(err), "failed to start guest command %q", command[0])
+	}
+
+	for {
+		procs, err := pm.ListProcesses(vmCtx, auth, []int64{pid})
+		if err != nil {
+			return -1, errors.Wrapf(guestCommandError(err), "failed to get guest command %q status", command[0])
+		}
+
+		if len(procs) == 1 && procs[0].EndTime != nil {
+			return procs[0].ExitCode, nil
+		}
+
+		select {
+		case <-vmCtx.Done():
+			// Best effort to not leave the command running in the guest.
+			if err := pm.TerminateProcess(goctx.Background(), auth, pid); err != nil {
+				vmCtx.Logger.V(5).Info("failed to terminate guest command", "pid", pid, "error", err)
+			}
+			return -1, errors.Wrapf(vmCtx.Err(), "guest command %q did not exit", command[0])
+		case <-time.After(guestCommandPollInterval):
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package virtualmachine_test
+
+import (
+	goctx "context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"github.com/vmware-tanzu/vm-operator/pkg/context"
+	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider"
+	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/virtualmachine"
+	"github.com/vmware-tanzu/vm-operator/test/builder"
+)
+
+const fakeGuestCommandPid = 42
+
+// fakeGuestProcessManager replaces the vcsim GuestProcessManager, which requires the VM to be
+// backed by a container, with one that runs no command and reports a fixed exit code.
+type fakeGuestProcessManager struct {
+	*simulator.GuestProcessManager
+
+	exitCode int32
+	running  bool
+	fault    types.BaseMethodFault
+	spec     *types.GuestProgramSpec
+	auth     *types.NamePasswordAuthentication
+}
+
+func (m *fakeGuestProcessManager) StartProgramInGuest(_ *simulator.Context, req *types.StartProgramInGuest) soap.HasFault {
+	m.spec = req.Spec.(*types.GuestProgramSpec)
+	m.auth = req.Auth.(*types.NamePasswordAuthentication)
+	if m.fault != nil {
+		return &methods.StartProgramInGuestBody{Fault_: simulator.Fault("", m.fault)}
+	}
+	return &methods.StartProgramInGuestBody{
+		Res: &types.StartProgramInGuestResponse{Returnval: fakeGuestCommandPid},
+	}
+}
+
+func (m *fakeGuestProcessManager) ListProcessesInGuest(_ *simulator.Context, _ *types.ListProcessesInGuest) soap.HasFault {
+	info := types.GuestProcessInfo{
+		Pid:       fakeGuestCommandPid,
+		StartTime: time.Now(),
+	}
+	if !m.running {
+		info.EndTime = types.NewTime(time.Now())
+		info.ExitCode = m.exitCode
+	}
+	return &methods.ListProcessesInGuestBody{
+		Res: &types.ListProcessesInGuestResponse{Returnval: []types.GuestProcessInfo{info}},
+	}
+}
+
+func (m *fakeGuestProcessManager) TerminateProcessInGuest(_ *simulator.Context, _ *types.TerminateProcessInGuest) soap.HasFault {
+	m.running = false
+	return &methods.TerminateProcessInGuestBody{
+		Res: &types.TerminateProcessInGuestResponse{},
+	}
+}
+
+func guestExecTests() {
+
+	var (
+		ctx   *builder.TestContextForVCSim
+		vcVM  *object.VirtualMachine
+		vmCtx context.VirtualMachineContext
+		pm    *fakeGuestProcessManager
+	)
+
+	BeforeEach(func() {
+		ctx = suite.NewTestContextForVCSim(builder.VCSimTestConfig{})
+
+		var err error
+		vcVM, err = ctx.Finder.VirtualMachine(ctx, "DC0_C0_RP0_VM0")
+		Expect(err).ToNot(HaveOccurred())
+
+		vmCtx = context.VirtualMachineContext{
+			Context: ctx,
+			Logger:  suite.GetLogger().WithValues("vmName", vcVM.Name()),
+			VM:      builder.DummyVirtualMachine(),
+		}
+
+		refs := simulator.Map.AllReference("GuestProcessManager")
+		Expect(refs).To(HaveLen(1))
+		pm = &fakeGuestProcessManager{
+			GuestProcessManager: refs[0].(*simulator.GuestProcessManager),
+		}
+		simulator.Map.Put(pm)
+	})
+
+	AfterEach(func() {
+		ctx.AfterEach()
+		ctx = nil
+	})
+
+	It("Runs the command with the credentials and returns its exit code", func() {
+		pm.exitCode = 3
+
+		exitCode, err := virtualmachine.RunGuestCommand(vmCtx, vcVM,
+			[]string{"/usr/bin/systemctl", "is-active", "app"}, "user", "pass")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exitCode).To(BeEquivalentTo(3))
+
+		Expect(pm.spec).ToNot(BeNil())
+		Expect(pm.spec.ProgramPath).To(Equal("/usr/bin/systemctl"))
+		Expect(pm.spec.Arguments).To(Equal("'is-active' 'app'"))
+		Expect(pm.auth.Username).To(Equal("user"))
+		Expect(pm.auth.Password).To(Equal("pass"))
+	})
+
+	It("Quotes each argument of the command", func() {
+		_, err := virtualmachine.RunGuestCommand(vmCtx, vcVM,
+			[]string{"/bin/test", "-f", "/tmp/it's ready", ""}, "user", "pass")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(pm.spec).ToNot(BeNil())
+		Expect(pm.spec.Arguments).To(Equal(`'-f' '/tmp/it'\''s ready' ''`))
+	})
+
+	It("Quotes each argument of the command for a Windows guest", func() {
+		task, err := vcVM.Reconfigure(ctx, types.VirtualMachineConfigSpec{GuestId: "windows9Server64Guest"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(task.Wait(ctx)).To(Succeed())
+
+		_, err = virtualmachine.RunGuestCommand(vmCtx, vcVM,
+			[]string{`C:\Windows\System32\sc.exe`, "query", `C:\Program Files\app\`, `say "hi"`, ""}, "user", "pass")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(pm.spec).ToNot(BeNil())
+		Expect(pm.spec.Arguments).To(Equal(`query "C:\Program Files\app\\" "say \"hi\"" ""`))
+	})
+
+	It("Returns an error wrapping ErrGuestCommandNotRun when the guest rejects the credentials", func() {
+		pm.fault = new(types.InvalidGuestLogin)
+
+		_, err := virtualmachine.RunGuestCommand(vmCtx, vcVM, []string{"/bin/true"}, "user", "pass")
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Is(err, vmprovider.ErrGuestCommandNotRun)).To(BeTrue())
+	})
+
+	It("Returns an error not wrapping ErrGuestCommandNotRun when vCenter fails the operation", func() {
+		pm.fault = new(types.InvalidArgument)
+
+		_, err := virtualmachine.RunGuestCommand(vmCtx, vcVM, []string{"/bin/true"}, "user", "pass")
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Is(err, vmprovider.ErrGuestCommandNotRun)).To(BeFalse())
+	})
+
+	It("Returns an error when no command is specified", func() {
+		_, err := virtualmachine.RunGuestCommand(vmCtx, vcVM, nil, "user", "pass")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Terminates the command when it does not exit in time", func() {
+		pm.running = true
+
+		timeoutCtx, cancel := goctx.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		vmCtx.Context = timeoutCtx
+
+		_, err := virtualmachine.RunGuestCommand(vmCtx, vcVM, []string{"/bin/sleep", "600"}, "user", "pass")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("did not exit"))
+		Expect(pm.running).To(BeFalse())
+	})
+}
@@ -2,15 +2,21 @@ package virtualmachine
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/task"
 	"github.com/vmware/govmomi/vim25/types"
 
+	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
+	"github.com/vmware-tanzu/vm-operator/pkg/conditions"
 	"github.com/vmware-tanzu/vm-operator/pkg/context"
 )
 
+// SoftPowerOffTimeout is how long the guest OS is given to shut down before the VM is powered off.
+const SoftPowerOffTimeout = 5 * time.Minute
+
 func ChangePowerState(
 	vmCtx context.VirtualMachineContext,
 	vcVM *object.VirtualMachine,
@@ -24,6 +30,8 @@ func ChangePowerState(
 		t, err = vcVM.PowerOn(vmCtx)
 	case types.VirtualMachinePowerStatePoweredOff:
 		t, err = vcVM.PowerOff(vmCtx)
+	case types.VirtualMachinePowerStateSuspended:
+		t, err = vcVM.Suspend(vmCtx)
 	default:
 		return fmt.Errorf("invalid power state %s", ps)
 	}
@@ -49,3 +57,86 @@ func ChangePowerState(
 
 	return nil
 }
+
+// PowerOff powers off the VM according to the VM's PowerOffMode. Shutting down the guest OS does not wait
+// for the VM to be powered off, so the returned bool is false while the guest OS is shutting down, as
+// documented by the GuestShutdown condition. The VM is powered off when the guest OS has not shut down
+// within SoftPowerOffTimeout.
+func PowerOff(
+	vmCtx context.VirtualMachineContext,
+	vcVM *object.VirtualMachine) (bool, error) {
+
+	vm := vmCtx.VM
+
+	mode := vm.Spec.PowerOffMode
+	if mode == "" || mode == vmopv1alpha1.VirtualMachinePowerOffModeHard {
+		return true, ChangePowerState(vmCtx, vcVM, types.VirtualMachinePowerStatePoweredOff)
+	}
+
+	// A guest OS shut down is in progress, or failed to be initiated, until the timeout is reached.
+	reason := conditions.GetReason(vm, vmopv1alpha1.GuestShutdownCondition)
+	if reason == vmopv1alpha1.GuestShutdownInProgressReason || reason == vmopv1alpha1.GuestShutdownFailedReason {
+		if time.Since(conditions.GetLastTransitionTime(vm, vmopv1alpha1.GuestShutdownCondition).Time) >= SoftPowerOffTimeout {
+			vmCtx.Logger.Info("Guest OS did not shut down in time, powering off VM", "timeout", SoftPowerOffTimeout)
+			conditions.MarkFalse(vm, vmopv1alpha1.GuestShutdownCondition, vmopv1alpha1.GuestShutdownTimedOutReason,
+				vmopv1alpha1.ConditionSeverityWarning, "guest OS did not shut down within %s", SoftPowerOffTimeout)
+			return true, ChangePowerState(vmCtx, vcVM, types.VirtualMachinePowerStatePoweredOff)
+		}
+
+		if reason == vmopv1alpha1.GuestShutdownInProgressReason {
+			vmCtx.Logger.V(4).Info("Waiting for guest OS to shut down")
+			return false, nil
+		}
+	}
+
+	if err := vcVM.ShutdownGuest(vmCtx); err != nil {
+		conditions.MarkFalse(vm, vmopv1alpha1.GuestShutdownCondition, vmopv1alpha1.GuestShutdownFailedReason,
+			vmopv1alpha1.ConditionSeverityWarning, "%s", err.Error())
+
+		if mode == vmopv1alpha1.VirtualMachinePowerOffModeTrySoft {
+			vmCtx.Logger.Info("Failed to shut down guest OS, powering off VM", "error", err.Error())
+			return true, ChangePowerState(vmCtx, vcVM, types.VirtualMachinePowerStatePoweredOff)
+		}
+
+		return false, errors.Wrap(err, "failed to shut down guest OS")
+	}
+
+	vmCtx.Logger.Info("Shutting down guest OS")
+	conditions.MarkFalse(vm, vmopv1alpha1.GuestShutdownCondition, vmopv1alpha1.GuestShutdownInProgressReason,
+		vmopv1alpha1.ConditionSeverityInfo, "")
+	return false, nil
+}
+
+// RebootGuest restarts the guest OS of the VM. The guest OS restart does not wait for the guest OS to
+// be back up.
+func RebootGuest(
+	vmCtx context.VirtualMachineContext,
+	vcVM *object.VirtualMachine) error {
+
+	if err := vcVM.RebootGuest(vmCtx); err != nil {
+		return errors.Wrap(err, "failed to reboot guest OS")
+	}
+
+	return nil
+}
+
+// Reset hard resets the VM, without first shutting down the guest.
+func Reset(
+	vmCtx context.VirtualMachineContext,
+	vcVM *object.VirtualMachine) error {
+
+	t, err := vcVM.Reset(vmCtx)
+	if err != nil {
+		return errors.Wrap(err, "failed task creation to reset VM")
+	}
+
+	if taskInfo, err := t.WaitForResult(vmCtx); err != nil {
+		if taskInfo != nil {
+			vmCtx.Logger.V(5).Error(err, "Reset task failed", "taskInfo", taskInfo)
+		}
+
+		return errors.Wrap(err, "reset task failed")
+	}
+
+	return nil
+}
@@ -1,14 +1,19 @@
-// Copyright (c) 2022 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2022-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package virtualmachine_test
 
 import (
+	"time"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/vim25/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
+	"github.com/vmware-tanzu/vm-operator/pkg/conditions"
 	"github.com/vmware-tanzu/vm-operator/pkg/context"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/virtualmachine"
 	"github.com/vmware-tanzu/vm-operator/test/builder"
@@ -63,6 +68,21 @@ func powerStateTests() {
 		Expect(state).To(Equal(types.VirtualMachinePowerStatePoweredOn))
 	})
 
+	It("Suspends VM", func() {
+		err := virtualmachine.ChangePowerState(vmCtx, vcVM, types.VirtualMachinePowerStateSuspended)
+		Expect(err).ToNot(HaveOccurred())
+
+		state, err := vcVM.PowerState(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(state).To(Equal(types.VirtualMachinePowerStateSuspended))
+	})
+
+	It("Returns error when rebooting guest without VMware Tools running", func() {
+		// vcsim VMs do not report VMware Tools as running.
+		err := virtualmachine.RebootGuest(vmCtx, vcVM)
+		Expect(err).To(MatchError(ContainSubstring("ToolsUnavailable")))
+	})
+
 	It("Returns success when VM is already in desired state", func() {
 		state, err := vcVM.PowerState(ctx)
 		Expect(err).ToNot(HaveOccurred())
@@ -71,4 +91,119 @@ func powerStateTests() {
 		err = virtualmachine.ChangePowerState(vmCtx, vcVM, types.VirtualMachinePowerStatePoweredOn)
 		Expect(err).ToNot(HaveOccurred())
 	})
+
+	It("Resets VM", func() {
+		Expect(virtualmachine.Reset(vmCtx, vcVM)).To(Succeed())
+
+		state, err := vcVM.PowerState(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(state).To(Equal(types.VirtualMachinePowerStatePoweredOn))
+	})
+
+	It("Returns error when resetting a powered off VM", func() {
+		t, err := vcVM.PowerOff(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(t.Wait(ctx)).To(Succeed())
+
+		Expect(virtualmachine.Reset(vmCtx, vcVM)).ToNot(Succeed())
+	})
+
+	Context("PowerOff", func() {
+
+		It("Powers VM off in hard mode", func() {
+			vmCtx.VM.Spec.PowerOffMode = vmopv1alpha1.VirtualMachinePowerOffModeHard
+
+			poweredOff, err := virtualmachine.PowerOff(vmCtx, vcVM)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(poweredOff).To(BeTrue())
+			Expect(conditions.Has(vmCtx.VM, vmopv1alpha1.GuestShutdownCondition)).To(BeFalse())
+
+			state, err := vcVM.PowerState(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(state).To(Equal(types.VirtualMachinePowerStatePoweredOff))
+		})
+
+		It("Shuts down guest in soft mode", func() {
+			vmCtx.VM.Spec.PowerOffMode = vmopv1alpha1.VirtualMachinePowerOffModeSoft
+
+			poweredOff, err := virtualmachine.PowerOff(vmCtx, vcVM)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(poweredOff).To(BeFalse())
+			Expect(conditions.GetReason(vmCtx.VM, vmopv1alpha1.GuestShutdownCondition)).To(
+				Equal(vmopv1alpha1.GuestShutdownInProgressReason))
+
+			// vcsim powers off the VM as soon as the guest is shut down.
+			state, err := vcVM.PowerState(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(state).To(Equal(types.VirtualMachinePowerStatePoweredOff))
+		})
+
+		It("Waits for guest shut down in progress", func() {
+			vmCtx.VM.Spec.PowerOffMode = vmopv1alpha1.VirtualMachinePowerOffModeSoft
+			conditions.MarkFalse(vmCtx.VM, vmopv1alpha1.GuestShutdownCondition,
+				vmopv1alpha1.GuestShutdownInProgressReason, vmopv1alpha1.ConditionSeverityInfo, "")
+
+			poweredOff, err := virtualmachine.PowerOff(vmCtx, vcVM)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(poweredOff).To(BeFalse())
+
+			state, err := vcVM.PowerState(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(state).To(Equal(types.VirtualMachinePowerStatePoweredOn))
+		})
+
+		It("Powers VM off when guest shut down times out", func() {
+			vmCtx.VM.Spec.PowerOffMode = vmopv1alpha1.VirtualMachinePowerOffModeSoft
+			conditions.Set(vmCtx.VM, &vmopv1alpha1.Condition{
+				Type:               vmopv1alpha1.GuestShutdownCondition,
+				Status:             "False",
+				Reason:             vmopv1alpha1.GuestShutdownInProgressReason,
+				Severity:           vmopv1alpha1.ConditionSeverityInfo,
+				LastTransitionTime: metav1.NewTime(time.Now().Add(-virtualmachine.SoftPowerOffTimeout)),
+			})
+
+			poweredOff, err := virtualmachine.PowerOff(vmCtx, vcVM)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(poweredOff).To(BeTrue())
+			Expect(conditions.GetReason(vmCtx.VM, vmopv1alpha1.GuestShutdownCondition)).To(
+				Equal(vmopv1alpha1.GuestShutdownTimedOutReason))
+
+			state, err := vcVM.PowerState(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(state).To(Equal(types.VirtualMachinePowerStatePoweredOff))
+		})
+
+		It("Powers VM off in trySoft mode when guest shut down fails", func() {
+			vmCtx.VM.Spec.PowerOffMode = vmopv1alpha1.VirtualMachinePowerOffModeTrySoft
+
+			// The guest of a powered off VM cannot be shut down.
+			t, err := vcVM.PowerOff(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(t.Wait(ctx)).To(Succeed())
+
+			poweredOff, err := virtualmachine.PowerOff(vmCtx, vcVM)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(poweredOff).To(BeTrue())
+			Expect(conditions.GetReason(vmCtx.VM, vmopv1alpha1.GuestShutdownCondition)).To(
+				Equal(vmopv1alpha1.GuestShutdownFailedReason))
+
+			state, err := vcVM.PowerState(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(state).To(Equal(types.VirtualMachinePowerStatePoweredOff))
+		})
+
+		It("Returns error in soft mode when guest shut down fails", func() {
+			vmCtx.VM.Spec.PowerOffMode = vmopv1alpha1.VirtualMachinePowerOffModeSoft
+
+			t, err := vcVM.PowerOff(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(t.Wait(ctx)).To(Succeed())
+
+			poweredOff, err := virtualmachine.PowerOff(vmCtx, vcVM)
+			Expect(err).To(HaveOccurred())
+			Expect(poweredOff).To(BeFalse())
+			Expect(conditions.GetReason(vmCtx.VM, vmopv1alpha1.GuestShutdownCondition)).To(
+				Equal(vmopv1alpha1.GuestShutdownFailedReason))
+		})
+	})
 }
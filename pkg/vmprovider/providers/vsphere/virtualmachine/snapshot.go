@@ -0,0 +1,156 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package virtualmachine
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"github.com/vmware-tanzu/vm-operator/pkg/context"
+)
+
+// CreateSnapshot takes a snapshot of the VM and returns the managed object ID of the new snapshot.
+func CreateSnapshot(
+	vmCtx context.VirtualMachineContext,
+	vcVM *object.VirtualMachine,
+	name, description string,
+	memory, quiesce bool) (string, error) {
+
+	t, err := vcVM.CreateSnapshot(vmCtx, name, description, memory, quiesce)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed task creation to create snapshot %s", name)
+	}
+
+	taskInfo, err := t.WaitForResult(vmCtx)
+	if err != nil {
+		if taskInfo != nil {
+			vmCtx.Logger.V(5).Error(err, "create snapshot task failed", "taskInfo", taskInfo)
+		}
+		return "", errors.Wrapf(err, "create snapshot %s task failed", name)
+	}
+
+	snapshotRef, ok := taskInfo.Result.(types.ManagedObjectReference)
+	if !ok {
+		return "", fmt.Errorf("create snapshot %s task returned unexpected result %v", name, taskInfo.Result)
+	}
+
+	return snapshotRef.Value, nil
+}
+
+// RevertToSnapshot reverts the VM to the snapshot with the given managed object ID. The VM is
+// left in the power state captured by the snapshot.
+func RevertToSnapshot(
+	vmCtx context.VirtualMachineContext,
+	vcVM *object.VirtualMachine,
+	snapshotMoID string) error {
+
+	t, err := vcVM.RevertToSnapshot(vmCtx, snapshotMoID, true)
+	if err != nil {
+		return errors.Wrapf(err, "failed task creation to revert to snapshot %s", snapshotMoID)
+	}
+
+	if taskInfo, err := t.WaitForResult(vmCtx); err != nil {
+		if taskInfo != nil {
+			vmCtx.Logger.V(5).Error(err, "revert to snapshot task failed", "taskInfo", taskInfo)
+		}
+		return errors.Wrapf(err, "revert to snapshot %s task failed", snapshotMoID)
+	}
+
+	return nil
+}
+
+// DeleteSnapshot removes the snapshot with the given managed object ID, consolidating its disks
+// into the parent. Returns success if the snapshot does not exist.
+func DeleteSnapshot(
+	vmCtx context.VirtualMachineContext,
+	vcVM *object.VirtualMachine,
+	snapshotMoID string) error {
+
+	var o mo.VirtualMachine
+	if err := vcVM.Properties(vmCtx, vcVM.Reference(), []string{"snapshot"}, &o); err != nil {
+		return err
+	}
+
+	if tree, _ := findSnapshotTree(o.Snapshot, snapshotMoID); tree == nil {
+		return nil
+	}
+
+	consolidate := true
+	t, err := vcVM.RemoveSnapshot(vmCtx, snapshotMoID, false, &consolidate)
+	if err != nil {
+		return errors.Wrapf(err, "failed task creation to delete snapshot %s", snapshotMoID)
+	}
+
+	if taskInfo, err := t.WaitForResult(vmCtx); err != nil {
+		if taskInfo != nil {
+			vmCtx.Logger.V(5).Error(err, "delete snapshot task failed", "taskInfo", taskInfo)
+		}
+		return errors.Wrapf(err, "delete snapshot %s task failed", snapshotMoID)
+	}
+
+	return nil
+}
+
+// GetSnapshotSize returns the storage, in bytes, consumed by the snapshot with the given managed
+// object ID.
+func GetSnapshotSize(
+	vmCtx context.VirtualMachineContext,
+	vcVM *object.VirtualMachine,
+	snapshotMoID string) (int64, error) {
+
+	var o mo.VirtualMachine
+	if err := vcVM.Properties(vmCtx, vcVM.Reference(), []string{"snapshot", "layoutEx"}, &o); err != nil {
+		return 0, err
+	}
+
+	tree, parent := findSnapshotTree(o.Snapshot, snapshotMoID)
+	if tree == nil {
+		return 0, fmt.Errorf("snapshot %s not found", snapshotMoID)
+	}
+
+	if o.LayoutEx == nil {
+		return 0, nil
+	}
+
+	isCurrent := o.Snapshot.CurrentSnapshot != nil && o.Snapshot.CurrentSnapshot.Value == snapshotMoID
+
+	var parentRef *types.ManagedObjectReference
+	if parent != nil {
+		parentRef = &parent.Snapshot
+	}
+
+	return int64(object.SnapshotSize(tree.Snapshot, parentRef, o.LayoutEx, isCurrent)), nil
+}
+
+// findSnapshotTree returns the snapshot tree node, and its parent node if any, of the snapshot
+// with the given managed object ID. A nil node is returned when the snapshot does not exist.
+func findSnapshotTree(
+	info *types.VirtualMachineSnapshotInfo,
+	snapshotMoID string) (*types.VirtualMachineSnapshotTree, *types.VirtualMachineSnapshotTree) {
+
+	if info == nil {
+		return nil, nil
+	}
+
+	var find func(parent *types.VirtualMachineSnapshotTree,
+		trees []types.VirtualMachineSnapshotTree) (*types.VirtualMachineSnapshotTree, *types.VirtualMachineSnapshotTree)
+	find = func(parent *types.VirtualMachineSnapshotTree,
+		trees []types.VirtualMachineSnapshotTree) (*types.VirtualMachineSnapshotTree, *types.VirtualMachineSnapshotTree) {
+		for i := range trees {
+			if trees[i].Snapshot.Value == snapshotMoID {
+				return &trees[i], parent
+			}
+			if t, p := find(&trees[i], trees[i].ChildSnapshotList); t != nil {
+				return t, p
+			}
+		}
+		return nil, nil
+	}
+
+	return find(nil, info.RootSnapshotList)
+}
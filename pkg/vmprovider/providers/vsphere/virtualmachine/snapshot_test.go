@@ -0,0 +1,90 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package virtualmachine_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+
+	"github.com/vmware-tanzu/vm-operator/pkg/context"
+	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/virtualmachine"
+	"github.com/vmware-tanzu/vm-operator/test/builder"
+)
+
+func snapshotTests() {
+
+	var (
+		ctx   *builder.TestContextForVCSim
+		vcVM  *object.VirtualMachine
+		vmCtx context.VirtualMachineContext
+	)
+
+	getSnapshotInfo := func() *mo.VirtualMachine {
+		var o mo.VirtualMachine
+		Expect(vcVM.Properties(ctx, vcVM.Reference(), []string{"snapshot"}, &o)).To(Succeed())
+		return &o
+	}
+
+	BeforeEach(func() {
+		ctx = suite.NewTestContextForVCSim(builder.VCSimTestConfig{})
+
+		var err error
+		vcVM, err = ctx.Finder.VirtualMachine(ctx, "DC0_C0_RP0_VM0")
+		Expect(err).ToNot(HaveOccurred())
+
+		vmCtx = context.VirtualMachineContext{
+			Context: ctx,
+			Logger:  suite.GetLogger().WithValues("vmName", vcVM.Name()),
+			VM:      builder.DummyVirtualMachine(),
+		}
+	})
+
+	AfterEach(func() {
+		ctx.AfterEach()
+		ctx = nil
+	})
+
+	It("Creates, reverts to, and deletes a snapshot", func() {
+		snapshotID, err := virtualmachine.CreateSnapshot(vmCtx, vcVM, "snap-1", "first", false, false)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(snapshotID).ToNot(BeEmpty())
+
+		o := getSnapshotInfo()
+		Expect(o.Snapshot).ToNot(BeNil())
+		Expect(o.Snapshot.CurrentSnapshot).ToNot(BeNil())
+		Expect(o.Snapshot.CurrentSnapshot.Value).To(Equal(snapshotID))
+
+		snapshotID2, err := virtualmachine.CreateSnapshot(vmCtx, vcVM, "snap-2", "second", false, false)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(snapshotID2).ToNot(Equal(snapshotID))
+		Expect(getSnapshotInfo().Snapshot.CurrentSnapshot.Value).To(Equal(snapshotID2))
+
+		By("Reverting to the older snapshot", func() {
+			Expect(virtualmachine.RevertToSnapshot(vmCtx, vcVM, snapshotID)).To(Succeed())
+			Expect(getSnapshotInfo().Snapshot.CurrentSnapshot.Value).To(Equal(snapshotID))
+		})
+
+		By("Getting the snapshot size", func() {
+			_, err := virtualmachine.GetSnapshotSize(vmCtx, vcVM, snapshotID2)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		By("Deleting the newer snapshot", func() {
+			Expect(virtualmachine.DeleteSnapshot(vmCtx, vcVM, snapshotID2)).To(Succeed())
+
+			_, err := virtualmachine.GetSnapshotSize(vmCtx, vcVM, snapshotID2)
+			Expect(err).To(HaveOccurred())
+		})
+
+		By("Deleting the snapshot again is a no-op", func() {
+			Expect(virtualmachine.DeleteSnapshot(vmCtx, vcVM, snapshotID2)).To(Succeed())
+		})
+	})
+
+	It("Returns error when reverting to a snapshot that does not exist", func() {
+		Expect(virtualmachine.RevertToSnapshot(vmCtx, vcVM, "snapshot-does-not-exist")).ToNot(Succeed())
+	})
+}
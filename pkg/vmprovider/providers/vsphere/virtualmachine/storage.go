@@ -4,6 +4,11 @@
 package virtualmachine
 
 import (
+	"path"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/types"
 
 	"github.com/vmware-tanzu/vm-operator/pkg/context"
@@ -44,3 +49,25 @@ func GetDefaultDiskProvisioningType(
 
 	return string(types.OvfCreateImportSpecParamsDiskProvisioningTypeThin), nil
 }
+
+// GetDirectory returns the datastore path of the directory of the VM, which contains its configuration file.
+func GetDirectory(
+	vmCtx context.VirtualMachineContext,
+	vcVM *object.VirtualMachine) (object.DatastorePath, error) {
+
+	var o mo.VirtualMachine
+	if err := vcVM.Properties(vmCtx, vcVM.Reference(), []string{"config.files.vmPathName"}, &o); err != nil {
+		return object.DatastorePath{}, errors.Wrap(err, "failed to get VM configuration file")
+	}
+	if o.Config == nil {
+		return object.DatastorePath{}, errors.New("VM does not have a configuration file")
+	}
+
+	var dsPath object.DatastorePath
+	if !dsPath.FromString(o.Config.Files.VmPathName) {
+		return object.DatastorePath{}, errors.Errorf("invalid VM configuration file name %q", o.Config.Files.VmPathName)
+	}
+	dsPath.Path = path.Dir(dsPath.Path)
+
+	return dsPath, nil
+}
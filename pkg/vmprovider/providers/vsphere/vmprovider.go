@@ -51,6 +51,10 @@ const (
 	ovfCacheMaxItem                 = 100
 	ovfCacheItemExpiration          = 30 * time.Minute
 	ovfCacheExpirationCheckInterval = 5 * time.Minute
+
+	isoFileCacheMaxItem                 = 100
+	isoFileCacheItemExpiration          = 30 * time.Minute
+	isoFileCacheExpirationCheckInterval = 5 * time.Minute
 )
 
 var log = logf.Log.WithName(VsphereVMProviderName)
@@ -60,6 +64,11 @@ type VersionedOVFEnvelope struct {
 	ContentVersion string
 }
 
+type VersionedISOFile struct {
+	DatastorePath  string
+	ContentVersion string
+}
+
 type vSphereVMProvider struct {
 	k8sClient         ctrlruntime.Client
 	eventRecorder     record.Recorder
@@ -67,6 +76,7 @@ type vSphereVMProvider struct {
 	minCPUFreq        uint64
 	ovfCache          *util.Cache[VersionedOVFEnvelope]
 	ovfCacheLockPool  *util.LockPool[string, *sync.RWMutex]
+	isoFileCache      *util.Cache[VersionedISOFile]
 
 	vcClientLock sync.Mutex
 	vcClient     *vcclient.Client
@@ -78,12 +88,21 @@ func NewVSphereVMProviderFromClient(
 	ovfCache, ovfLockPool := InitOvfCacheAndLockPool(
 		ovfCacheItemExpiration, ovfCacheExpirationCheckInterval, ovfCacheMaxItem)
 
+	isoFileCache := util.NewCache[VersionedISOFile](
+		isoFileCacheItemExpiration, isoFileCacheExpirationCheckInterval, isoFileCacheMaxItem)
+	go func() {
+		// Nothing else needs to be cleaned up when an ISO file cache item expires.
+		for range isoFileCache.ExpiredChan() {
+		}
+	}()
+
 	return &vSphereVMProvider{
 		k8sClient:         client,
 		eventRecorder:     recorder,
 		globalExtraConfig: getExtraConfig(),
 		ovfCache:          ovfCache,
 		ovfCacheLockPool:  ovfLockPool,
+		isoFileCache:      isoFileCache,
 	}
 }
 
@@ -224,17 +243,25 @@ func (vs *vSphereVMProvider) GetVirtualMachineImageFromContentLibrary(
 // SyncVirtualMachineImage syncs the vmi object with the OVF Envelope retrieved from the cli object.
 func (vs *vSphereVMProvider) SyncVirtualMachineImage(ctx goctx.Context, cli, vmi ctrlruntime.Object) error {
 	var itemID, contentVersion string
+	var itemType imgregv1a1.ContentLibraryItemType
 	switch cli := cli.(type) {
 	case *imgregv1a1.ContentLibraryItem:
 		itemID = cli.Spec.UUID
 		contentVersion = cli.Status.ContentVersion
+		itemType = cli.Status.Type
 	case *imgregv1a1.ClusterContentLibraryItem:
 		itemID = cli.Spec.UUID
 		contentVersion = cli.Status.ContentVersion
+		itemType = cli.Status.Type
 	default:
 		return errors.Errorf("unexpected content library item type %T", cli)
 	}
 
+	if itemType == imgregv1a1.ContentLibraryItemTypeIso {
+		// An ISO does not have an OVF envelope to sync the VMI with.
+		return nil
+	}
+
 	ovfEnvelope, err := vs.getOvfEnvelope(ctx, itemID, contentVersion)
 	if err != nil {
 		return err
@@ -291,6 +318,55 @@ func (vs *vSphereVMProvider) getOvfEnvelope(
 	return cacheItem.OvfEnvelope, nil
 }
 
+// getIsoFile gets the datastore path of the ISO file of the library item from the cache if it exists and matches
+// version. If not, it resolves the path from vCenter and stores it in the cache.
+func (vs *vSphereVMProvider) getIsoFile(
+	ctx goctx.Context, itemID, contentVersion string) (string, error) {
+	logger := log.V(4).WithValues("itemID", itemID, "contentVersion", contentVersion)
+
+	isHitFunc := func(cacheItem VersionedISOFile) bool {
+		return cacheItem.ContentVersion == contentVersion
+	}
+	if cacheItem, found := vs.isoFileCache.Get(itemID, isHitFunc); found {
+		return cacheItem.DatastorePath, nil
+	}
+
+	logger.Info("Cache item miss, resolving ISO file from vCenter")
+	client, err := vs.getVcClient(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	dsRef, storageURI, err := client.ContentLibClient().GetLibraryItemIsoFile(ctx, itemID)
+	if err != nil {
+		return "", err
+	}
+
+	var moDS mo.Datastore
+	datastore := object.NewDatastore(client.VimClient(), dsRef)
+	if err := datastore.Properties(ctx, dsRef, []string{"name", "summary.url"}, &moDS); err != nil {
+		return "", fmt.Errorf("failed to get datastore %s of library item %s: %w", dsRef.Value, itemID, err)
+	}
+
+	if !strings.HasPrefix(storageURI, moDS.Summary.Url) {
+		return "", fmt.Errorf("ISO file %s of library item %s is not within datastore %s URL %s",
+			storageURI, itemID, moDS.Name, moDS.Summary.Url)
+	}
+
+	dsPath := object.DatastorePath{
+		Datastore: moDS.Name,
+		Path:      strings.TrimPrefix(strings.TrimPrefix(storageURI, moDS.Summary.Url), "/"),
+	}
+	cacheItem := VersionedISOFile{
+		DatastorePath:  dsPath.String(),
+		ContentVersion: contentVersion,
+	}
+	putResult := vs.isoFileCache.Put(itemID, cacheItem)
+	logger.Info("Cache item put", "datastorePath", cacheItem.DatastorePath, "putResult", putResult)
+
+	return cacheItem.DatastorePath, nil
+}
+
 // GetItemFromLibraryByName get the library item from specified content library by its name.
 // Do not return error if the item doesn't exist in the content library.
 func (vs *vSphereVMProvider) GetItemFromLibraryByName(ctx goctx.Context,
@@ -1,4 +1,4 @@
-// Copyright (c) 2022 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2022-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package vsphere
@@ -12,11 +12,18 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/task"
 	"github.com/vmware/govmomi/vim25/types"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	vmopv1alpha1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
 
 	imgregv1a1 "github.com/vmware-tanzu/vm-operator/external/image-registry/api/v1alpha1"
+	cnsregv1alpha1 "github.com/vmware-tanzu/vm-operator/external/vsphere-csi-driver/pkg/syncer/cnsoperator/apis/cnsregistervolume/v1alpha1"
 
 	"github.com/vmware-tanzu/vm-operator/pkg/conditions"
 	"github.com/vmware-tanzu/vm-operator/pkg/context"
@@ -25,6 +32,7 @@ import (
 	"github.com/vmware-tanzu/vm-operator/pkg/util"
 	vcclient "github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/client"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/constants"
+	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/imagevolume"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/instancestorage"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/network"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/placement"
@@ -44,8 +52,9 @@ const (
 )
 
 var (
-	createCountLock       sync.Mutex
-	concurrentCreateCount int
+	createCountLock             sync.Mutex
+	concurrentCreateCount       int
+	concurrentInstantCloneCount int
 )
 
 func (vs *vSphereVMProvider) CreateOrUpdateVirtualMachine(
@@ -102,14 +111,85 @@ func (vs *vSphereVMProvider) DeleteVirtualMachine(
 	vcVM, err := vs.getVM(vmCtx, client, false)
 	if err != nil {
 		return err
-	} else if vcVM == nil {
-		// VM does not exist.
+	}
+
+	// The VM may not exist if it was already deleted by a previous reconcile.
+	if vcVM == nil {
 		return nil
 	}
 
+	// The boot disk is deleted first since it can only be verified to be in the directory of the VM while
+	// the VM exists.
+	if err := vs.deleteImageBootDisk(vmCtx, client, vcVM); err != nil {
+		return err
+	}
+
 	return virtualmachine.DeleteVirtualMachine(vmCtx, vcVM)
 }
 
+// deleteImageBootDisk deletes the boot disk that was detached from the VM to populate its image volume PVC
+// when the disk was not yet registered with CNS. Once the CnsRegisterVolume exists, the disk is owned by CNS.
+func (vs *vSphereVMProvider) deleteImageBootDisk(
+	vmCtx context.VirtualMachineContext,
+	client *vcclient.Client,
+	vcVM *object.VirtualMachine) error {
+
+	volume := imagevolume.GetVolume(vmCtx.VM)
+	diskURLPath := vmCtx.VM.Annotations[constants.BootDiskURLPathAnnotation]
+	if volume == nil || diskURLPath == "" {
+		return nil
+	}
+
+	registerVolumeKey := ctrlclient.ObjectKey{
+		Namespace: vmCtx.VM.Namespace,
+		Name:      volume.PersistentVolumeClaim.ClaimName,
+	}
+	if err := vs.k8sClient.Get(vmCtx, registerVolumeKey, &cnsregv1alpha1.CnsRegisterVolume{}); err == nil {
+		return nil
+	} else if !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to get CnsRegisterVolume %s", registerVolumeKey.Name)
+	}
+
+	dsPath, err := imagevolume.ParseBootDiskURLPath(diskURLPath)
+	if err != nil {
+		return err
+	}
+
+	// Only a disk in the VM's own directory is deleted with the VM.
+	vmDir, err := virtualmachine.GetDirectory(vmCtx, vcVM)
+	if err != nil {
+		return err
+	}
+	if !imagevolume.IsInDirectory(dsPath, vmDir) {
+		vmCtx.Logger.Info("Not deleting boot disk that is not in the directory of the VM",
+			"fileName", dsPath.String(), "vmDirectory", vmDir.String())
+		delete(vmCtx.VM.Annotations, constants.BootDiskURLPathAnnotation)
+		return nil
+	}
+
+	vmCtx.Logger.Info("Deleting boot disk of the image that was not registered for the image volume PVC",
+		"fileName", dsPath.String())
+	diskManager := object.NewVirtualDiskManager(client.VimClient())
+	t, err := diskManager.DeleteVirtualDisk(vmCtx, dsPath.String(), client.Datacenter())
+	if err == nil {
+		err = t.Wait(vmCtx)
+	}
+	if err != nil {
+		// The disk may have been deleted by a previous reconcile.
+		if te, ok := err.(task.Error); !ok || !isFileNotFound(te.Fault()) {
+			return errors.Wrapf(err, "failed to delete boot disk %s", dsPath.String())
+		}
+	}
+
+	delete(vmCtx.VM.Annotations, constants.BootDiskURLPathAnnotation)
+	return nil
+}
+
+func isFileNotFound(fault types.BaseMethodFault) bool {
+	_, ok := fault.(*types.FileNotFound)
+	return ok
+}
+
 func (vs *vSphereVMProvider) PublishVirtualMachine(ctx goctx.Context, vm *vmopv1alpha1.VirtualMachine,
 	vmPub *vmopv1alpha1.VirtualMachinePublishRequest, cl *imgregv1a1.ContentLibrary, actID string) (string, error) {
 	vmCtx := context.VirtualMachineContext{
@@ -190,6 +270,223 @@ func (vs *vSphereVMProvider) GetVirtualMachineWebMKSTicket(
 	return ticket, nil
 }
 
+func (vs *vSphereVMProvider) RunVirtualMachineGuestCommand(
+	ctx goctx.Context,
+	vm *vmopv1alpha1.VirtualMachine,
+	command []string,
+	credentialsSecretName string) (int32, error) {
+
+	vmCtx := context.VirtualMachineContext{
+		Context: goctx.WithValue(ctx, types.ID{}, vs.getOpID(vm, "guestExec")),
+		Logger:  log.WithValues("vmName", vm.NamespacedName()),
+		VM:      vm,
+	}
+
+	secret := &corev1.Secret{}
+	secretKey := ctrlclient.ObjectKey{Name: credentialsSecretName, Namespace: vm.Namespace}
+	if err := vs.k8sClient.Get(vmCtx, secretKey, secret); err != nil {
+		return -1, errors.Wrapf(err, "failed to get guest credentials Secret %s", secretKey)
+	}
+
+	client, err := vs.getVcClient(vmCtx)
+	if err != nil {
+		return -1, err
+	}
+
+	vcVM, err := vs.getVM(vmCtx, client, true)
+	if err != nil {
+		return -1, err
+	}
+
+	return virtualmachine.RunGuestCommand(vmCtx, vcVM, command,
+		string(secret.Data[constants.GuestCredentialsUsernameKey]),
+		string(secret.Data[constants.GuestCredentialsPasswordKey]))
+}
+
+func (vs *vSphereVMProvider) RestartVirtualMachine(
+	ctx goctx.Context,
+	vm *vmopv1alpha1.VirtualMachine,
+	powerCycle bool) error {
+
+	vmCtx := context.VirtualMachineContext{
+		Context: goctx.WithValue(ctx, types.ID{}, vs.getOpID(vm, "restart")),
+		Logger:  log.WithValues("vmName", vm.NamespacedName()),
+		VM:      vm,
+	}
+
+	client, err := vs.getVcClient(vmCtx)
+	if err != nil {
+		return err
+	}
+
+	vcVM, err := vs.getVM(vmCtx, client, true)
+	if err != nil {
+		return err
+	}
+
+	if !powerCycle {
+		return virtualmachine.Reset(vmCtx, vcVM)
+	}
+
+	if err := virtualmachine.ChangePowerState(vmCtx, vcVM, types.VirtualMachinePowerStatePoweredOff); err != nil {
+		return err
+	}
+
+	return virtualmachine.ChangePowerState(vmCtx, vcVM, types.VirtualMachinePowerStatePoweredOn)
+}
+
+func (vs *vSphereVMProvider) RebootGuestVirtualMachine(
+	ctx goctx.Context,
+	vm *vmopv1alpha1.VirtualMachine) error {
+
+	vmCtx := context.VirtualMachineContext{
+		Context: goctx.WithValue(ctx, types.ID{}, vs.getOpID(vm, "rebootGuest")),
+		Logger:  log.WithValues("vmName", vm.NamespacedName()),
+		VM:      vm,
+	}
+
+	client, err := vs.getVcClient(vmCtx)
+	if err != nil {
+		return err
+	}
+
+	vcVM, err := vs.getVM(vmCtx, client, true)
+	if err != nil {
+		return err
+	}
+
+	return virtualmachine.RebootGuest(vmCtx, vcVM)
+}
+
+func (vs *vSphereVMProvider) GetVirtualMachineDirectory(
+	ctx goctx.Context,
+	vm *vmopv1alpha1.VirtualMachine) (string, error) {
+
+	vmCtx := context.VirtualMachineContext{
+		Context: goctx.WithValue(ctx, types.ID{}, vs.getOpID(vm, "getDirectory")),
+		Logger:  log.WithValues("vmName", vm.NamespacedName()),
+		VM:      vm,
+	}
+
+	client, err := vs.getVcClient(vmCtx)
+	if err != nil {
+		return "", err
+	}
+
+	vcVM, err := vs.getVM(vmCtx, client, true)
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := virtualmachine.GetDirectory(vmCtx, vcVM)
+	if err != nil {
+		return "", err
+	}
+
+	return dir.String(), nil
+}
+
+func (vs *vSphereVMProvider) CreateSnapshot(
+	ctx goctx.Context,
+	vm *vmopv1alpha1.VirtualMachine,
+	vmSnapshot *vmopv1alpha1.VirtualMachineSnapshot) error {
+
+	vmCtx := context.VirtualMachineContext{
+		Context: goctx.WithValue(ctx, types.ID{}, vs.getOpID(vm, "createSnapshot")),
+		Logger:  log.WithValues("vmName", vm.NamespacedName(), "snapshotName", vmSnapshot.Name),
+		VM:      vm,
+	}
+
+	client, err := vs.getVcClient(vmCtx)
+	if err != nil {
+		return err
+	}
+
+	vcVM, err := vs.getVM(vmCtx, client, true)
+	if err != nil {
+		return err
+	}
+
+	snapshotMoID, err := virtualmachine.CreateSnapshot(vmCtx, vcVM, vmSnapshot.Name,
+		vmSnapshot.Spec.Description, vmSnapshot.Spec.Memory, vmSnapshot.Spec.Quiesce)
+	if err != nil {
+		return err
+	}
+
+	vmSnapshot.Status.SnapshotID = snapshotMoID
+	vmSnapshot.Status.CreationTime = metav1.Now()
+
+	size, err := virtualmachine.GetSnapshotSize(vmCtx, vcVM, snapshotMoID)
+	if err != nil {
+		// The snapshot has been taken so just log the error: the size is informational.
+		vmCtx.Logger.Error(err, "Failed to get snapshot size", "snapshotID", snapshotMoID)
+	} else {
+		vmSnapshot.Status.Size = resource.NewQuantity(size, resource.BinarySI)
+	}
+
+	return nil
+}
+
+func (vs *vSphereVMProvider) RevertToSnapshot(
+	ctx goctx.Context,
+	vm *vmopv1alpha1.VirtualMachine,
+	vmSnapshot *vmopv1alpha1.VirtualMachineSnapshot) error {
+
+	vmCtx := context.VirtualMachineContext{
+		Context: goctx.WithValue(ctx, types.ID{}, vs.getOpID(vm, "revertToSnapshot")),
+		Logger:  log.WithValues("vmName", vm.NamespacedName(), "snapshotName", vmSnapshot.Name),
+		VM:      vm,
+	}
+
+	if vmSnapshot.Status.SnapshotID == "" {
+		return fmt.Errorf("VirtualMachineSnapshot %s has not been created", vmSnapshot.Name)
+	}
+
+	client, err := vs.getVcClient(vmCtx)
+	if err != nil {
+		return err
+	}
+
+	vcVM, err := vs.getVM(vmCtx, client, true)
+	if err != nil {
+		return err
+	}
+
+	return virtualmachine.RevertToSnapshot(vmCtx, vcVM, vmSnapshot.Status.SnapshotID)
+}
+
+func (vs *vSphereVMProvider) DeleteSnapshot(
+	ctx goctx.Context,
+	vm *vmopv1alpha1.VirtualMachine,
+	vmSnapshot *vmopv1alpha1.VirtualMachineSnapshot) error {
+
+	vmCtx := context.VirtualMachineContext{
+		Context: goctx.WithValue(ctx, types.ID{}, vs.getOpID(vm, "deleteSnapshot")),
+		Logger:  log.WithValues("vmName", vm.NamespacedName(), "snapshotName", vmSnapshot.Name),
+		VM:      vm,
+	}
+
+	if vmSnapshot.Status.SnapshotID == "" {
+		// Snapshot was never created.
+		return nil
+	}
+
+	client, err := vs.getVcClient(vmCtx)
+	if err != nil {
+		return err
+	}
+
+	vcVM, err := vs.getVM(vmCtx, client, false)
+	if err != nil {
+		return err
+	} else if vcVM == nil {
+		// VM does not exist so neither does the snapshot.
+		return nil
+	}
+
+	return virtualmachine.DeleteSnapshot(vmCtx, vcVM, vmSnapshot.Status.SnapshotID)
+}
+
 func (vs *vSphereVMProvider) createVirtualMachine(
 	vmCtx context.VirtualMachineContext,
 	vcClient *vcclient.Client) (*object.VirtualMachine, error) {
@@ -225,7 +522,15 @@ func (vs *vSphereVMProvider) createVirtualMachine(
 		return nil, fmt.Errorf("MaxDeployThreadsContextKey missing from context")
 	}
 
-	allowed, createDeferFn := vs.vmCreateConcurrentAllowed(vmCtx, maxDeployThreads)
+	if createArgs.InstantClone {
+		// Instant clones complete in seconds so they have their own limit.
+		maxDeployThreads, ok = vmCtx.Value(context.MaxInstantCloneThreadsContextKey).(int)
+		if !ok {
+			return nil, fmt.Errorf("MaxInstantCloneThreadsContextKey missing from context")
+		}
+	}
+
+	allowed, createDeferFn := vs.vmCreateConcurrentAllowed(vmCtx, maxDeployThreads, createArgs.InstantClone)
 	if !allowed {
 		return nil, nil
 	}
@@ -252,6 +557,10 @@ func (vs *vSphereVMProvider) createVirtualMachine(
 		// updateVirtualMachine() next which will set it all.
 		vmCtx.VM.Status.Phase = vmopv1alpha1.Created
 		vmCtx.VM.Status.UniqueID = vcVM.Reference().Value
+		vmCtx.VM.Status.AppliedClassName = createArgs.VMClass.Name
+		vmCtx.VM.Status.AppliedClassGeneration = createArgs.VMClass.Generation
+		// The VM is created with the storage policy of its StorageClass.
+		vmCtx.VM.Status.AppliedStorageClass = vmCtx.VM.Spec.StorageClass
 	}
 
 	return vcVM, nil
@@ -433,23 +742,32 @@ func (vs *vSphereVMProvider) vmCreateIsReady(
 	return nil
 }
 
+// vmCreateConcurrentAllowed returns true if the VM can be created without exceeding the maximum number of
+// concurrent creates. Instant clones are counted separately from the other creates.
 func (vs *vSphereVMProvider) vmCreateConcurrentAllowed(
 	vmCtx context.VirtualMachineContext,
-	maxDeployThreads int) (bool, func()) {
+	maxDeployThreads int,
+	instantClone bool) (bool, func()) {
+
+	count := &concurrentCreateCount
+	if instantClone {
+		count = &concurrentInstantCloneCount
+	}
 
 	createCountLock.Lock()
-	if concurrentCreateCount >= maxDeployThreads {
+	if *count >= maxDeployThreads {
 		createCountLock.Unlock()
-		vmCtx.Logger.Info("Too many create VirtualMachine already occurring. Re-queueing request")
+		vmCtx.Logger.Info("Too many create VirtualMachine already occurring. Re-queueing request",
+			"instantClone", instantClone)
 		return false, nil
 	}
 
-	concurrentCreateCount++
+	*count++
 	createCountLock.Unlock()
 
 	decrementFn := func() {
 		createCountLock.Lock()
-		concurrentCreateCount--
+		*count--
 		createCountLock.Unlock()
 	}
 
@@ -521,6 +839,16 @@ func (vs *vSphereVMProvider) vmCreateGetPrereqs(
 	createArgs.ContentLibraryUUID = clUUID
 	createArgs.VMMetadata = vmMD
 
+	if vmCtx.VM.Spec.Source != nil {
+		srcVMMoID, srcSnapshotMoID, err := GetVMCloneSource(vmCtx, vs.k8sClient)
+		if err != nil {
+			return nil, err
+		}
+		createArgs.SourceVMMoID = srcVMMoID
+		createArgs.SourceSnapshotMoID = srcSnapshotMoID
+		createArgs.InstantClone = vmCtx.VM.Spec.Source.Instant
+	}
+
 	// TODO: Perhaps a condition type for each resource is better so all missing one(s)
 	// 	     can be reported at once (and will help for the best-effort update changes).
 	// This is about where historically we set this condition but there are still a lot
@@ -650,6 +978,28 @@ func (vs *vSphereVMProvider) vmCreateValidateArgs(
 	return nil
 }
 
+// vmGetCdromIsoFiles returns the datastore paths of the ISO files of the VM's CD-ROMs, in the order of the CD-ROMs.
+func (vs *vSphereVMProvider) vmGetCdromIsoFiles(
+	vmCtx context.VirtualMachineContext) ([]string, error) {
+
+	images, err := GetVMCdromImages(vmCtx, vs.k8sClient)
+	if err != nil {
+		return nil, err
+	}
+
+	isoFiles := make([]string, 0, len(vmCtx.VM.Spec.Cdrom))
+	for _, cdrom := range vmCtx.VM.Spec.Cdrom {
+		image := images[cdrom.ImageName]
+		isoFile, err := vs.getIsoFile(vmCtx, image.itemID, image.contentVersion)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the ISO file of CD-ROM %s image %s: %w", cdrom.Name, cdrom.ImageName, err)
+		}
+		isoFiles = append(isoFiles, isoFile)
+	}
+
+	return isoFiles, nil
+}
+
 func (vs *vSphereVMProvider) vmUpdateGetArgs(
 	vmCtx context.VirtualMachineContext) (*vmUpdateArgs, error) {
 
@@ -668,10 +1018,19 @@ func (vs *vSphereVMProvider) vmUpdateGetArgs(
 		return nil, err
 	}
 
+	var cdromIsoFiles []string
+	if len(vmCtx.VM.Spec.Cdrom) > 0 {
+		cdromIsoFiles, err = vs.vmGetCdromIsoFiles(vmCtx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	updateArgs := &vmUpdateArgs{}
 	updateArgs.VMClass = vmClass
 	updateArgs.ResourcePolicy = resourcePolicy
 	updateArgs.VMMetadata = vmMD
+	updateArgs.CdromIsoFiles = cdromIsoFiles
 
 	// We're always ready - again - at this point since we've fetched the above objects. We really should
 	// not be touching this condition after creation but that is for another day.
@@ -9,6 +9,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/rand"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -16,10 +21,12 @@ import (
 
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/vapi/cluster"
+	"github.com/vmware/govmomi/vapi/library"
 	gdj "github.com/vmware/govmomi/vim25/json"
 	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/types"
 	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/pointer"
@@ -33,6 +40,7 @@ import (
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/constants"
+	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/contentlibrary"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/instancestorage"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/virtualmachine"
 	"github.com/vmware-tanzu/vm-operator/test/builder"
@@ -59,6 +67,7 @@ func vmTests() {
 	JustBeforeEach(func() {
 		ctx = suite.NewTestContextForVCSim(testConfig, initObjects...)
 		ctx.Context = goctx.WithValue(ctx.Context, context.MaxDeployThreadsContextKey, 16)
+		ctx.Context = goctx.WithValue(ctx.Context, context.MaxInstantCloneThreadsContextKey, 16)
 		vmProvider = vsphere.NewVSphereVMProviderFromClient(ctx.Client, ctx.Recorder)
 		nsInfo = ctx.CreateWorkloadNamespace()
 	})
@@ -1082,6 +1091,489 @@ func vmTests() {
 				Expect(state).To(Equal(types.VirtualMachinePowerStatePoweredOff))
 			})
 
+			It("Suspends and resumes VM", func() {
+				vcVM, err := createOrUpdateAndGetVcVM(ctx, vm)
+				Expect(err).ToNot(HaveOccurred())
+
+				vm.Spec.PowerState = vmopv1alpha1.VirtualMachineSuspended
+				Expect(vmProvider.CreateOrUpdateVirtualMachine(ctx, vm)).To(Succeed())
+				Expect(vm.Status.PowerState).To(Equal(vmopv1alpha1.VirtualMachineSuspended))
+				state, err := vcVM.PowerState(ctx)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(state).To(Equal(types.VirtualMachinePowerStateSuspended))
+
+				vm.Spec.PowerState = vmopv1alpha1.VirtualMachinePoweredOn
+				Expect(vmProvider.CreateOrUpdateVirtualMachine(ctx, vm)).To(Succeed())
+				Expect(vm.Status.PowerState).To(Equal(vmopv1alpha1.VirtualMachinePoweredOn))
+			})
+
+			It("Powers off suspended VM", func() {
+				_, err := createOrUpdateAndGetVcVM(ctx, vm)
+				Expect(err).ToNot(HaveOccurred())
+
+				vm.Spec.PowerState = vmopv1alpha1.VirtualMachineSuspended
+				Expect(vmProvider.CreateOrUpdateVirtualMachine(ctx, vm)).To(Succeed())
+				Expect(vm.Status.PowerState).To(Equal(vmopv1alpha1.VirtualMachineSuspended))
+
+				vm.Spec.PowerState = vmopv1alpha1.VirtualMachinePoweredOff
+				vm.Spec.PowerOffMode = vmopv1alpha1.VirtualMachinePowerOffModeSoft
+				Expect(vmProvider.CreateOrUpdateVirtualMachine(ctx, vm)).To(Succeed())
+				Expect(vm.Status.PowerState).To(Equal(vmopv1alpha1.VirtualMachinePoweredOff))
+			})
+
+			It("Returns error when rebooting guest without VMware Tools running", func() {
+				_, err := createOrUpdateAndGetVcVM(ctx, vm)
+				Expect(err).ToNot(HaveOccurred())
+
+				err = vmProvider.RebootGuestVirtualMachine(ctx, vm)
+				Expect(err).To(MatchError(ContainSubstring("ToolsUnavailable")))
+			})
+
+			It("Shuts down guest when powering VM off in soft mode", func() {
+				vcVM, err := createOrUpdateAndGetVcVM(ctx, vm)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(vm.Status.PowerState).To(Equal(vmopv1alpha1.VirtualMachinePoweredOn))
+				vm.Spec.PowerState = vmopv1alpha1.VirtualMachinePoweredOff
+				vm.Spec.PowerOffMode = vmopv1alpha1.VirtualMachinePowerOffModeSoft
+				Expect(vmProvider.CreateOrUpdateVirtualMachine(ctx, vm)).To(Succeed())
+				Expect(conditions.GetReason(vm, vmopv1alpha1.GuestShutdownCondition)).To(
+					Equal(vmopv1alpha1.GuestShutdownInProgressReason))
+
+				By("guest has shut down", func() {
+					Expect(vmProvider.CreateOrUpdateVirtualMachine(ctx, vm)).To(Succeed())
+					Expect(vm.Status.PowerState).To(Equal(vmopv1alpha1.VirtualMachinePoweredOff))
+					Expect(conditions.IsTrue(vm, vmopv1alpha1.GuestShutdownCondition)).To(BeTrue())
+				})
+
+				state, err := vcVM.PowerState(ctx)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(state).To(Equal(types.VirtualMachinePowerStatePoweredOff))
+			})
+
+			It("Relocates storage when the StorageClass changes", func() {
+				_, err := createOrUpdateAndGetVcVM(ctx, vm)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(vm.Status.AppliedStorageClass).To(Equal(ctx.StorageClassName))
+
+				storageClass := &storagev1.StorageClass{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "vcsim-other-storageclass",
+					},
+					Parameters: map[string]string{
+						// vcsim "vSAN Default Storage Policy" profile ID.
+						"storagePolicyID": "4d5f673c-536f-11e6-beb8-9e71128cae77",
+					},
+				}
+				Expect(ctx.Client.Create(ctx, storageClass)).To(Succeed())
+
+				vm.Spec.StorageClass = storageClass.Name
+				Expect(vmProvider.CreateOrUpdateVirtualMachine(ctx, vm)).To(Succeed())
+				Expect(vm.Annotations).To(HaveKey(constants.StorageRelocateTaskAnnotation))
+				Expect(conditions.GetReason(vm, vmopv1alpha1.VirtualMachineStorageRelocatedCondition)).To(
+					Equal(vmopv1alpha1.VirtualMachineStorageRelocationInProgressReason))
+				Expect(vm.Status.AppliedStorageClass).To(Equal(ctx.StorageClassName))
+
+				By("storage has been relocated", func() {
+					Expect(vmProvider.CreateOrUpdateVirtualMachine(ctx, vm)).To(Succeed())
+					Expect(vm.Annotations).ToNot(HaveKey(constants.StorageRelocateTaskAnnotation))
+					Expect(conditions.IsTrue(vm, vmopv1alpha1.VirtualMachineStorageRelocatedCondition)).To(BeTrue())
+					Expect(vm.Status.AppliedStorageClass).To(Equal(storageClass.Name))
+				})
+			})
+
+			It("Relocates storage when the StorageClass applied to the VM is not known", func() {
+				_, err := createOrUpdateAndGetVcVM(ctx, vm)
+				Expect(err).ToNot(HaveOccurred())
+
+				// vcsim does not associate a storage policy with the VM so it does not match the StorageClass.
+				vm.Status.AppliedStorageClass = ""
+				Expect(vmProvider.CreateOrUpdateVirtualMachine(ctx, vm)).To(Succeed())
+				Expect(vm.Annotations).To(HaveKey(constants.StorageRelocateTaskAnnotation))
+				Expect(vm.Status.AppliedStorageClass).To(BeEmpty())
+
+				By("storage has been relocated", func() {
+					Expect(vmProvider.CreateOrUpdateVirtualMachine(ctx, vm)).To(Succeed())
+					Expect(vm.Annotations).ToNot(HaveKey(constants.StorageRelocateTaskAnnotation))
+					Expect(vm.Status.AppliedStorageClass).To(Equal(ctx.StorageClassName))
+				})
+			})
+
+			It("Sets boot options", func() {
+				vm.Spec.BootOptions = &vmopv1alpha1.VirtualMachineBootOptions{
+					Firmware:             "efi",
+					EFISecureBootEnabled: true,
+					BootOrder: []vmopv1alpha1.VirtualMachineBootDevice{
+						vmopv1alpha1.VirtualMachineBootDeviceNetwork,
+						vmopv1alpha1.VirtualMachineBootDeviceDisk,
+					},
+				}
+
+				vcVM, err := createOrUpdateAndGetVcVM(ctx, vm)
+				Expect(err).ToNot(HaveOccurred())
+
+				var o mo.VirtualMachine
+				Expect(vcVM.Properties(ctx, vcVM.Reference(), []string{"config"}, &o)).To(Succeed())
+				Expect(o.Config.Firmware).To(Equal("efi"))
+				Expect(o.Config.BootOptions).ToNot(BeNil())
+				Expect(o.Config.BootOptions.EfiSecureBootEnabled).To(HaveValue(BeTrue()))
+				// The VM does not have a network interface so it only boots from the disk.
+				Expect(o.Config.BootOptions.BootOrder).To(HaveLen(1))
+				Expect(o.Config.BootOptions.BootOrder[0]).To(BeAssignableToTypeOf(&types.VirtualMachineBootOptionsBootableDiskDevice{}))
+			})
+
+			It("Grows the boot disk", func() {
+				capacity := resource.MustParse("20Gi")
+				vm.Spec.BootDiskCapacity = &capacity
+
+				_, err := createOrUpdateAndGetVcVM(ctx, vm)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(vm.Status.PowerState).To(Equal(vmopv1alpha1.VirtualMachinePoweredOn))
+				Expect(vm.Status.BootDiskCapacity).ToNot(BeNil())
+				Expect(vm.Status.BootDiskCapacity.Value()).To(Equal(capacity.Value()))
+
+				By("while powered on", func() {
+					capacity := resource.MustParse("30Gi")
+					vm.Spec.BootDiskCapacity = &capacity
+
+					Expect(vmProvider.CreateOrUpdateVirtualMachine(ctx, vm)).To(Succeed())
+					Expect(vm.Status.PowerState).To(Equal(vmopv1alpha1.VirtualMachinePoweredOn))
+					Expect(vm.Status.BootDiskCapacity).ToNot(BeNil())
+					Expect(vm.Status.BootDiskCapacity.Value()).To(Equal(capacity.Value()))
+				})
+
+				By("cannot be shrunk", func() {
+					capacity := resource.MustParse("10Gi")
+					vm.Spec.BootDiskCapacity = &capacity
+
+					err := vmProvider.CreateOrUpdateVirtualMachine(ctx, vm)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("cannot shrink boot disk"))
+				})
+			})
+
+			Context("Image volume", func() {
+				BeforeEach(func() {
+					vm.Spec.Volumes = append(vm.Spec.Volumes, vmopv1alpha1.VirtualMachineVolume{
+						Name: "image-volume",
+						PersistentVolumeClaim: &vmopv1alpha1.PersistentVolumeClaimVolumeSource{
+							PersistentVolumeClaimVolumeSource: corev1.PersistentVolumeClaimVolumeSource{
+								ClaimName: "image-pvc",
+							},
+							ImageVolumeClaim: &vmopv1alpha1.ImageVolumeClaimVolumeSource{
+								ImageName: vm.Spec.ImageName,
+							},
+						},
+					})
+				})
+
+				JustBeforeEach(func() {
+					// The URL path of the detached boot disk is patched onto the VM.
+					Expect(ctx.Client.Create(ctx, vm)).To(Succeed())
+				})
+
+				expectNoDisks := func(vcVM *object.VirtualMachine) {
+					devices, err := vcVM.Device(ctx)
+					ExpectWithOffset(1, err).ToNot(HaveOccurred())
+					ExpectWithOffset(1, devices.SelectByType((*types.VirtualDisk)(nil))).To(BeEmpty())
+				}
+
+				It("Detaches the boot disk to populate the PVC", func() {
+					vcVM, err := createOrUpdateAndGetVcVM(ctx, vm)
+					Expect(err).ToNot(HaveOccurred())
+					expectNoDisks(vcVM)
+
+					diskURLPath := vm.Annotations[constants.BootDiskURLPathAnnotation]
+					Expect(diskURLPath).To(ContainSubstring("/folder/"))
+					Expect(diskURLPath).To(ContainSubstring(".vmdk"))
+					Expect(diskURLPath).To(ContainSubstring("dsName="))
+
+					By("is not powered on until the image volume is attached", func() {
+						Expect(vm.Status.PowerState).To(Equal(vmopv1alpha1.VirtualMachinePoweredOff))
+					})
+
+					By("is powered on once the image volume is attached", func() {
+						vm.Status.Volumes = []vmopv1alpha1.VirtualMachineVolumeStatus{
+							{Name: "image-volume", Attached: true},
+						}
+						Expect(vmProvider.CreateOrUpdateVirtualMachine(ctx, vm)).To(Succeed())
+						Expect(vm.Status.PowerState).To(Equal(vmopv1alpha1.VirtualMachinePoweredOn))
+					})
+				})
+
+				It("Deletes the detached boot disk when the VM is deleted before the disk is registered", func() {
+					_, err := createOrUpdateAndGetVcVM(ctx, vm)
+					Expect(err).ToNot(HaveOccurred())
+
+					By("records the URL path of the boot disk on the VM", func() {
+						Expect(ctx.Client.Get(ctx, client.ObjectKeyFromObject(vm), vm)).To(Succeed())
+						Expect(vm.Annotations).To(HaveKey(constants.BootDiskURLPathAnnotation))
+					})
+
+					diskURL, err := url.Parse(vm.Annotations[constants.BootDiskURLPathAnnotation])
+					Expect(err).ToNot(HaveOccurred())
+					datastore, err := ctx.Finder.Datastore(ctx, diskURL.Query().Get("dsName"))
+					Expect(err).ToNot(HaveOccurred())
+					diskPath := strings.TrimPrefix(diskURL.Path, "/folder/")
+					_, err = datastore.Stat(ctx, diskPath)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(vmProvider.DeleteVirtualMachine(ctx, vm)).To(Succeed())
+					Expect(vm.Annotations).ToNot(HaveKey(constants.BootDiskURLPathAnnotation))
+					_, err = datastore.Stat(ctx, diskPath)
+					Expect(err).To(HaveOccurred())
+				})
+
+				It("Does not delete a boot disk that is not in the directory of the VM", func() {
+					_, err := createOrUpdateAndGetVcVM(ctx, vm)
+					Expect(err).ToNot(HaveOccurred())
+
+					diskURL, err := url.Parse(vm.Annotations[constants.BootDiskURLPathAnnotation])
+					Expect(err).ToNot(HaveOccurred())
+					datastore, err := ctx.Finder.Datastore(ctx, diskURL.Query().Get("dsName"))
+					Expect(err).ToNot(HaveOccurred())
+					diskPath := strings.TrimPrefix(diskURL.Path, "/folder/")
+
+					// Copy the boot disk to another directory and point the VM at the copy.
+					otherDiskPath := path.Join("other-vm", path.Base(diskPath))
+					fileManager := object.NewFileManager(ctx.VCClient.Client)
+					Expect(fileManager.MakeDirectory(ctx, datastore.Path("other-vm"), ctx.Datacenter, true)).To(Succeed())
+					diskManager := object.NewVirtualDiskManager(ctx.VCClient.Client)
+					task, err := diskManager.CopyVirtualDisk(ctx, datastore.Path(diskPath), ctx.Datacenter,
+						datastore.Path(otherDiskPath), ctx.Datacenter, nil, false)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(task.Wait(ctx)).To(Succeed())
+					vm.Annotations[constants.BootDiskURLPathAnnotation] = datastore.NewURL(otherDiskPath).String()
+
+					Expect(vmProvider.DeleteVirtualMachine(ctx, vm)).To(Succeed())
+					Expect(vm.Annotations).ToNot(HaveKey(constants.BootDiskURLPathAnnotation))
+					_, err = datastore.Stat(ctx, otherDiskPath)
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("Deletes the boot disk when the PVC already exists", func() {
+					pvc := &corev1.PersistentVolumeClaim{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "image-pvc",
+							Namespace: vm.Namespace,
+						},
+					}
+					Expect(ctx.Client.Create(ctx, pvc)).To(Succeed())
+
+					vcVM, err := createOrUpdateAndGetVcVM(ctx, vm)
+					Expect(err).ToNot(HaveOccurred())
+					expectNoDisks(vcVM)
+					Expect(vm.Annotations).ToNot(HaveKey(constants.BootDiskURLPathAnnotation))
+				})
+			})
+
+			Context("CD-ROM", func() {
+				var isoPath string
+
+				JustBeforeEach(func() {
+					iso, err := os.CreateTemp("", "fake-*.iso")
+					Expect(err).NotTo(HaveOccurred())
+					isoPath = iso.Name()
+					Expect(iso.Close()).To(Succeed())
+
+					clProvider := contentlibrary.NewProvider(ctx.RestClient)
+					libItem := library.Item{
+						Name:      strings.TrimSuffix(filepath.Base(isoPath), ".iso"),
+						Type:      library.ItemTypeISO,
+						LibraryID: ctx.ContentLibraryID,
+					}
+					Expect(clProvider.CreateLibraryItem(ctx, libItem, isoPath)).To(Succeed())
+					item, err := clProvider.GetLibraryItem(ctx, ctx.ContentLibraryID, libItem.Name, true)
+					Expect(err).ToNot(HaveOccurred())
+
+					isoImage := builder.DummyVirtualMachineImage("iso-image")
+					isoImage.Spec.Type = library.ItemTypeISO
+					isoImage.Spec.ImageID = item.ID
+					Expect(ctx.Client.Create(ctx, isoImage)).To(Succeed())
+
+					vm.Spec.Cdrom = []vmopv1alpha1.VirtualMachineCdrom{
+						{Name: "cdrom1", ImageName: isoImage.Name},
+					}
+				})
+
+				AfterEach(func() {
+					Expect(os.Remove(isoPath)).To(Succeed())
+				})
+
+				It("Backs the CD-ROM with the ISO file of the library item", func() {
+					vcVM, err := createOrUpdateAndGetVcVM(ctx, vm)
+					Expect(err).ToNot(HaveOccurred())
+
+					devices, err := vcVM.Device(ctx)
+					Expect(err).ToNot(HaveOccurred())
+					cdroms := devices.SelectByType((*types.VirtualCdrom)(nil))
+					Expect(cdroms).To(HaveLen(1))
+
+					backing, ok := cdroms[0].GetVirtualDevice().Backing.(*types.VirtualCdromIsoBackingInfo)
+					Expect(ok).To(BeTrue())
+					Expect(backing.FileName).To(HavePrefix("["))
+					Expect(backing.FileName).To(ContainSubstring(contentlibrary.LibraryDirPrefix + ctx.ContentLibraryID + "/"))
+					Expect(backing.FileName).To(HaveSuffix(".iso"))
+
+					By("does not change the CD-ROM on the next update", func() {
+						Expect(vmProvider.CreateOrUpdateVirtualMachine(ctx, vm)).To(Succeed())
+						devices, err := vcVM.Device(ctx)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(devices.SelectByType((*types.VirtualCdrom)(nil))).To(HaveLen(1))
+					})
+				})
+			})
+
+			Context("Resize", func() {
+				var newVMClass *vmopv1alpha1.VirtualMachineClass
+
+				JustBeforeEach(func() {
+					newVMClass = builder.DummyVirtualMachineClass()
+					newVMClass.Spec.Hardware.Cpus = 4
+					newVMClass.Spec.Hardware.Memory = resource.MustParse("8Gi")
+					Expect(ctx.Client.Create(ctx, newVMClass)).To(Succeed())
+
+					vmClassBinding := builder.DummyVirtualMachineClassBinding(newVMClass.Name, nsInfo.Namespace)
+					Expect(ctx.Client.Create(ctx, vmClassBinding)).To(Succeed())
+				})
+
+				AfterEach(func() {
+					newVMClass = nil
+				})
+
+				expectResized := func(vcVM *object.VirtualMachine) {
+					var o mo.VirtualMachine
+					ExpectWithOffset(1, vcVM.Properties(ctx, vcVM.Reference(), nil, &o)).To(Succeed())
+					ExpectWithOffset(1, o.Summary.Config.NumCpu).To(BeEquivalentTo(newVMClass.Spec.Hardware.Cpus))
+					ExpectWithOffset(1, o.Summary.Config.MemorySizeMB).To(BeEquivalentTo(newVMClass.Spec.Hardware.Memory.Value() / 1024 / 1024))
+
+					ExpectWithOffset(1, vm.Status.AppliedClassName).To(Equal(newVMClass.Name))
+					ExpectWithOffset(1, conditions.IsTrue(vm, vmopv1alpha1.VirtualMachineResizedCondition)).To(BeTrue())
+				}
+
+				It("Sets the applied class when the VM is created", func() {
+					_, err := createOrUpdateAndGetVcVM(ctx, vm)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(vm.Status.AppliedClassName).To(Equal(vm.Spec.ClassName))
+					Expect(conditions.Has(vm, vmopv1alpha1.VirtualMachineResizedCondition)).To(BeFalse())
+				})
+
+				When("the class of the VM is updated", func() {
+					var vcVM *object.VirtualMachine
+
+					JustBeforeEach(func() {
+						var err error
+						vcVM, err = createOrUpdateAndGetVcVM(ctx, vm)
+						Expect(err).ToNot(HaveOccurred())
+
+						newVMClass = &vmopv1alpha1.VirtualMachineClass{}
+						Expect(ctx.Client.Get(ctx, client.ObjectKey{Name: vm.Spec.ClassName}, newVMClass)).To(Succeed())
+						newVMClass.Spec.Hardware.Cpus = 4
+						newVMClass.Spec.Hardware.Memory = resource.MustParse("8Gi")
+						newVMClass.Generation++
+						Expect(ctx.Client.Update(ctx, newVMClass)).To(Succeed())
+					})
+
+					It("Defers the resize of a powered on VM without hot-add enabled until it is powered off", func() {
+						Expect(vmProvider.CreateOrUpdateVirtualMachine(ctx, vm)).To(Succeed())
+						Expect(conditions.GetReason(vm, vmopv1alpha1.VirtualMachineResizedCondition)).To(
+							Equal(vmopv1alpha1.VirtualMachineResizeDeferredReason))
+						Expect(vm.Status.AppliedClassGeneration).ToNot(Equal(newVMClass.Generation))
+						Expect(vm.Status.PowerState).To(Equal(vmopv1alpha1.VirtualMachinePoweredOn))
+
+						vm.Spec.PowerState = vmopv1alpha1.VirtualMachinePoweredOff
+						Expect(vmProvider.CreateOrUpdateVirtualMachine(ctx, vm)).To(Succeed())
+						expectResized(vcVM)
+						Expect(vm.Status.AppliedClassGeneration).To(Equal(newVMClass.Generation))
+					})
+
+					When("the VM has the class update power cycle annotation", func() {
+						BeforeEach(func() {
+							if vm.Annotations == nil {
+								vm.Annotations = map[string]string{}
+							}
+							vm.Annotations[constants.ClassUpdatePowerCycleAnnotation] = ""
+						})
+
+						It("Power cycles a powered on VM without hot-add enabled", func() {
+							Expect(vmProvider.CreateOrUpdateVirtualMachine(ctx, vm)).To(Succeed())
+							expectResized(vcVM)
+							Expect(vm.Status.PowerState).To(Equal(vmopv1alpha1.VirtualMachinePoweredOn))
+						})
+					})
+				})
+
+				It("Power cycles a powered on VM without hot-add enabled", func() {
+					vcVM, err := createOrUpdateAndGetVcVM(ctx, vm)
+					Expect(err).ToNot(HaveOccurred())
+
+					vm.Spec.ClassName = newVMClass.Name
+					_, err = createOrUpdateAndGetVcVM(ctx, vm)
+					Expect(err).ToNot(HaveOccurred())
+
+					expectResized(vcVM)
+					Expect(vm.Status.PowerState).To(Equal(vmopv1alpha1.VirtualMachinePoweredOn))
+				})
+
+				It("Shuts down the guest to power cycle a VM in soft mode", func() {
+					vcVM, err := createOrUpdateAndGetVcVM(ctx, vm)
+					Expect(err).ToNot(HaveOccurred())
+
+					vm.Spec.ClassName = newVMClass.Name
+					vm.Spec.PowerOffMode = vmopv1alpha1.VirtualMachinePowerOffModeSoft
+					Expect(vmProvider.CreateOrUpdateVirtualMachine(ctx, vm)).To(Succeed())
+					Expect(conditions.GetReason(vm, vmopv1alpha1.VirtualMachineResizedCondition)).To(
+						Equal(vmopv1alpha1.VirtualMachineResizePendingReason))
+					Expect(conditions.GetReason(vm, vmopv1alpha1.GuestShutdownCondition)).To(
+						Equal(vmopv1alpha1.GuestShutdownInProgressReason))
+
+					By("guest has shut down", func() {
+						Expect(vmProvider.CreateOrUpdateVirtualMachine(ctx, vm)).To(Succeed())
+						expectResized(vcVM)
+						Expect(vm.Status.PowerState).To(Equal(vmopv1alpha1.VirtualMachinePoweredOn))
+						Expect(conditions.Has(vm, vmopv1alpha1.GuestShutdownCondition)).To(BeFalse())
+					})
+				})
+
+				It("Resizes a powered on VM with hot-add enabled", func() {
+					vcVM, err := createOrUpdateAndGetVcVM(ctx, vm)
+					Expect(err).ToNot(HaveOccurred())
+
+					// Hot-add can only be enabled when the VM is powered off.
+					vm.Spec.PowerState = vmopv1alpha1.VirtualMachinePoweredOff
+					Expect(vmProvider.CreateOrUpdateVirtualMachine(ctx, vm)).To(Succeed())
+					t, err := vcVM.Reconfigure(ctx, types.VirtualMachineConfigSpec{
+						CpuHotAddEnabled:    pointer.Bool(true),
+						MemoryHotAddEnabled: pointer.Bool(true),
+					})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(t.Wait(ctx)).To(Succeed())
+					vm.Spec.PowerState = vmopv1alpha1.VirtualMachinePoweredOn
+					Expect(vmProvider.CreateOrUpdateVirtualMachine(ctx, vm)).To(Succeed())
+
+					vm.Spec.ClassName = newVMClass.Name
+					_, err = createOrUpdateAndGetVcVM(ctx, vm)
+					Expect(err).ToNot(HaveOccurred())
+
+					expectResized(vcVM)
+					Expect(vm.Status.PowerState).To(Equal(vmopv1alpha1.VirtualMachinePoweredOn))
+				})
+
+				It("Resizes a powered off VM", func() {
+					vm.Spec.PowerState = vmopv1alpha1.VirtualMachinePoweredOff
+					vcVM, err := createOrUpdateAndGetVcVM(ctx, vm)
+					Expect(err).ToNot(HaveOccurred())
+
+					vm.Spec.ClassName = newVMClass.Name
+					_, err = createOrUpdateAndGetVcVM(ctx, vm)
+					Expect(err).ToNot(HaveOccurred())
+
+					expectResized(vcVM)
+					Expect(vm.Status.PowerState).To(Equal(vmopv1alpha1.VirtualMachinePoweredOff))
+				})
+			})
+
 			It("returns error when StorageClass is required but none specified", func() {
 				vm.Spec.StorageClass = ""
 				err := vmProvider.CreateOrUpdateVirtualMachine(ctx, vm)
@@ -1175,6 +1667,50 @@ func vmTests() {
 						})
 					})
 				})
+
+				Context("Rerun customization", func() {
+					var configMap *corev1.ConfigMap
+
+					BeforeEach(func() {
+						vm.Annotations[constants.RerunCustomizationAnnotation] = ""
+					})
+
+					JustBeforeEach(func() {
+						configMap = &corev1.ConfigMap{
+							ObjectMeta: metav1.ObjectMeta{
+								GenerateName: "md-configmap-",
+								Namespace:    vm.Namespace,
+							},
+						}
+						Expect(ctx.Client.Create(ctx, configMap)).To(Succeed())
+					})
+
+					It("Consumes the request when the customization spec is applied", func() {
+						vm.Spec.VmMetadata = &vmopv1alpha1.VirtualMachineMetadata{
+							ConfigMapName: configMap.Name,
+							Transport:     vmopv1alpha1.VirtualMachineMetadataExtraConfigTransport,
+						}
+						_, err := createOrUpdateAndGetVcVM(ctx, vm)
+						Expect(err).ToNot(HaveOccurred())
+
+						Expect(vm.Annotations).ToNot(HaveKey(constants.RerunCustomizationAnnotation))
+						Expect(conditions.GetReason(vm, vmopv1alpha1.GuestCustomizationCondition)).To(
+							Equal(vmopv1alpha1.GuestCustomizationRerunReason))
+					})
+
+					It("Keeps the request when the transport does not customize the guest", func() {
+						vm.Spec.VmMetadata = &vmopv1alpha1.VirtualMachineMetadata{
+							ConfigMapName: configMap.Name,
+							Transport:     vmopv1alpha1.VirtualMachineMetadataVAppConfigTransport,
+						}
+						_, err := createOrUpdateAndGetVcVM(ctx, vm)
+						Expect(err).ToNot(HaveOccurred())
+
+						Expect(vm.Annotations).To(HaveKey(constants.RerunCustomizationAnnotation))
+						Expect(conditions.GetReason(vm, vmopv1alpha1.GuestCustomizationCondition)).ToNot(
+							Equal(vmopv1alpha1.GuestCustomizationRerunReason))
+					})
+				})
 			})
 
 			Context("Network", func() {
@@ -1411,6 +1947,35 @@ func vmTests() {
 					Expect(vm.Labels).To(HaveKeyWithValue(topology.KubernetesTopologyZoneLabelKey, zoneName))
 					Expect(vm.Status.Zone).To(Equal(zoneName))
 				})
+
+				It("Migrates the VM to another zone", func() {
+					const newZoneName = "az-0"
+
+					_, err := createOrUpdateAndGetVcVM(ctx, vm)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(vm.Status.Zone).To(Equal(zoneName))
+
+					vm.Labels[topology.KubernetesTopologyZoneLabelKey] = newZoneName
+					Expect(vmProvider.CreateOrUpdateVirtualMachine(ctx, vm)).To(Succeed())
+					Expect(vm.Annotations).To(HaveKey(constants.MigrateTaskAnnotation))
+					Expect(conditions.GetReason(vm, vmopv1alpha1.VirtualMachineMigratedCondition)).To(
+						Equal(vmopv1alpha1.VirtualMachineMigratingReason))
+					Expect(vm.Status.Zone).To(Equal(zoneName))
+
+					By("VM has been migrated", func() {
+						vcVM, err := createOrUpdateAndGetVcVM(ctx, vm)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(vm.Annotations).ToNot(HaveKey(constants.MigrateTaskAnnotation))
+						Expect(conditions.IsTrue(vm, vmopv1alpha1.VirtualMachineMigratedCondition)).To(BeTrue())
+						Expect(vm.Status.Zone).To(Equal(newZoneName))
+
+						cluster, err := virtualmachine.GetVMClusterComputeResource(ctx, vcVM)
+						Expect(err).ToNot(HaveOccurred())
+						clusterZoneName, err := topology.LookupZoneForClusterMoID(ctx, ctx.Client, cluster.Reference().Value)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(clusterZoneName).To(Equal(newZoneName))
+					})
+				})
 			})
 		})
 
@@ -1549,6 +2114,46 @@ func vmTests() {
 			})
 		})
 
+		Context("Guest exec", func() {
+			JustBeforeEach(func() {
+				Expect(vmProvider.CreateOrUpdateVirtualMachine(ctx, vm)).To(Succeed())
+			})
+
+			It("returns error when the credentials Secret does not exist", func() {
+				_, err := vmProvider.RunVirtualMachineGuestCommand(ctx, vm, []string{"/bin/true"}, "does-not-exist")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("failed to get guest credentials Secret"))
+			})
+		})
+
+		Context("Restart", func() {
+			JustBeforeEach(func() {
+				vm.Spec.PowerState = vmopv1alpha1.VirtualMachinePoweredOn
+				Expect(vmProvider.CreateOrUpdateVirtualMachine(ctx, vm)).To(Succeed())
+				Expect(vm.Status.PowerState).To(Equal(vmopv1alpha1.VirtualMachinePoweredOn))
+			})
+
+			It("resets the VM", func() {
+				Expect(vmProvider.RestartVirtualMachine(ctx, vm, false)).To(Succeed())
+
+				vcVM := ctx.GetVMFromMoID(vm.Status.UniqueID)
+				Expect(vcVM).ToNot(BeNil())
+				state, err := vcVM.PowerState(ctx)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(state).To(Equal(types.VirtualMachinePowerStatePoweredOn))
+			})
+
+			It("power cycles the VM", func() {
+				Expect(vmProvider.RestartVirtualMachine(ctx, vm, true)).To(Succeed())
+
+				vcVM := ctx.GetVMFromMoID(vm.Status.UniqueID)
+				Expect(vcVM).ToNot(BeNil())
+				state, err := vcVM.PowerState(ctx)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(state).To(Equal(types.VirtualMachinePowerStatePoweredOn))
+			})
+		})
+
 		Context("Web console ticket", func() {
 			JustBeforeEach(func() {
 				Expect(vmProvider.CreateOrUpdateVirtualMachine(ctx, vm)).To(Succeed())
@@ -1562,6 +2167,133 @@ func vmTests() {
 			})
 		})
 
+		Context("Snapshot", func() {
+			var vmSnapshot *vmopv1alpha1.VirtualMachineSnapshot
+
+			JustBeforeEach(func() {
+				Expect(vmProvider.CreateOrUpdateVirtualMachine(ctx, vm)).To(Succeed())
+
+				vmSnapshot = &vmopv1alpha1.VirtualMachineSnapshot{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-snapshot",
+						Namespace: vm.Namespace,
+					},
+					Spec: vmopv1alpha1.VirtualMachineSnapshotSpec{
+						VirtualMachineName: vm.Name,
+					},
+				}
+			})
+
+			It("creates, reverts to, and deletes the snapshot", func() {
+				Expect(vmProvider.CreateSnapshot(ctx, vm, vmSnapshot)).To(Succeed())
+				Expect(vmSnapshot.Status.SnapshotID).ToNot(BeEmpty())
+				Expect(vmSnapshot.Status.CreationTime.IsZero()).To(BeFalse())
+				Expect(vmSnapshot.Status.Size).ToNot(BeNil())
+
+				newerSnapshot := vmSnapshot.DeepCopy()
+				newerSnapshot.Name = "test-snapshot-2"
+				newerSnapshot.Status = vmopv1alpha1.VirtualMachineSnapshotStatus{}
+				Expect(vmProvider.CreateSnapshot(ctx, vm, newerSnapshot)).To(Succeed())
+				Expect(newerSnapshot.Status.SnapshotID).ToNot(Equal(vmSnapshot.Status.SnapshotID))
+
+				Expect(vmProvider.RevertToSnapshot(ctx, vm, vmSnapshot)).To(Succeed())
+
+				Expect(vmProvider.DeleteSnapshot(ctx, vm, newerSnapshot)).To(Succeed())
+				Expect(vmProvider.RevertToSnapshot(ctx, vm, newerSnapshot)).ToNot(Succeed())
+			})
+
+			It("returns error when reverting to a snapshot that was not created", func() {
+				err := vmProvider.RevertToSnapshot(ctx, vm, vmSnapshot)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("has not been created"))
+			})
+
+			It("deletes a snapshot that was not created", func() {
+				Expect(vmProvider.DeleteSnapshot(ctx, vm, vmSnapshot)).To(Succeed())
+			})
+		})
+
+		Context("Clone from source VM", func() {
+			var cloneVM *vmopv1alpha1.VirtualMachine
+
+			JustBeforeEach(func() {
+				Expect(vmProvider.CreateOrUpdateVirtualMachine(ctx, vm)).To(Succeed())
+				Expect(ctx.Client.Create(ctx, vm)).To(Succeed())
+
+				cloneVM = builder.DummyBasicVirtualMachine("test-vm-clone", vm.Namespace)
+				cloneVM.Spec.ImageName = ""
+				cloneVM.Spec.ClassName = vm.Spec.ClassName
+				cloneVM.Spec.StorageClass = vm.Spec.StorageClass
+				cloneVM.Spec.Source = &vmopv1alpha1.VirtualMachineSource{VirtualMachineName: vm.Name}
+			})
+
+			It("creates a full clone of the source VM", func() {
+				vcVM, err := createOrUpdateAndGetVcVM(ctx, cloneVM)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cloneVM.Status.UniqueID).ToNot(Equal(vm.Status.UniqueID))
+				Expect(vcVM.InventoryPath).To(HaveSuffix(fmt.Sprintf("/%s/%s", nsInfo.Namespace, cloneVM.Name)))
+				Expect(conditions.IsTrue(cloneVM, vmopv1alpha1.VirtualMachinePrereqReadyCondition)).To(BeTrue())
+			})
+
+			It("creates a linked clone of the source VM snapshot", func() {
+				vmSnapshot := &vmopv1alpha1.VirtualMachineSnapshot{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-snapshot",
+						Namespace: vm.Namespace,
+					},
+					Spec: vmopv1alpha1.VirtualMachineSnapshotSpec{
+						VirtualMachineName: vm.Name,
+					},
+				}
+				Expect(vmProvider.CreateSnapshot(ctx, vm, vmSnapshot)).To(Succeed())
+				vmSnapshot.Status.Ready = true
+				Expect(ctx.Client.Create(ctx, vmSnapshot)).To(Succeed())
+
+				cloneVM.Spec.Source.SnapshotName = vmSnapshot.Name
+				vcVM, err := createOrUpdateAndGetVcVM(ctx, cloneVM)
+				Expect(err).ToNot(HaveOccurred())
+
+				devices, err := vcVM.Device(ctx)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(devices.SelectByType((*types.VirtualDisk)(nil))).ToNot(BeEmpty())
+			})
+
+			It("returns error when the source VM does not exist", func() {
+				cloneVM.Spec.Source.VirtualMachineName = "does-not-exist"
+				err := vmProvider.CreateOrUpdateVirtualMachine(ctx, cloneVM)
+				Expect(err).To(HaveOccurred())
+				Expect(conditions.GetReason(cloneVM, vmopv1alpha1.VirtualMachinePrereqReadyCondition)).To(
+					Equal(vmopv1alpha1.VirtualMachineSourceNotFoundReason))
+			})
+
+			It("returns error when the source VM snapshot is not ready", func() {
+				vmSnapshot := &vmopv1alpha1.VirtualMachineSnapshot{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-snapshot",
+						Namespace: vm.Namespace,
+					},
+					Spec: vmopv1alpha1.VirtualMachineSnapshotSpec{
+						VirtualMachineName: vm.Name,
+					},
+				}
+				Expect(ctx.Client.Create(ctx, vmSnapshot)).To(Succeed())
+
+				cloneVM.Spec.Source.SnapshotName = vmSnapshot.Name
+				err := vmProvider.CreateOrUpdateVirtualMachine(ctx, cloneVM)
+				Expect(err).To(HaveOccurred())
+				Expect(conditions.GetReason(cloneVM, vmopv1alpha1.VirtualMachinePrereqReadyCondition)).To(
+					Equal(vmopv1alpha1.VirtualMachineSourceNotReadyReason))
+			})
+
+			It("instant clones the powered on source VM", func() {
+				cloneVM.Spec.Source.Instant = true
+				err := vmProvider.CreateOrUpdateVirtualMachine(ctx, cloneVM)
+				// vcsim does not support instant clone so this just checks the task was invoked.
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("does not implement: InstantClone_Task"))
+			})
+		})
+
 		Context("ResVMToVirtualMachineImage", func() {
 			JustBeforeEach(func() {
 				Expect(vmProvider.CreateOrUpdateVirtualMachine(ctx, vm)).To(Succeed())
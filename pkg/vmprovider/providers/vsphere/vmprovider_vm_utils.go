@@ -89,6 +89,11 @@ func GetVMImageStatusAndContentLibraryUUID(
 	vmCtx context.VirtualMachineContext,
 	k8sClient ctrlclient.Client) (*vmopv1alpha1.VirtualMachineImageStatus, string, error) {
 
+	if vmCtx.VM.Spec.Source != nil {
+		// A VM cloned from another VM does not have an image: the configuration of the source VM is used instead.
+		return &vmopv1alpha1.VirtualMachineImageStatus{}, "", nil
+	}
+
 	imageName := vmCtx.VM.Spec.ImageName
 	if lib.IsWCPVMImageRegistryEnabled() {
 		vmImageStatus, err := resolveVMImageStatus(vmCtx, k8sClient, imageName)
@@ -202,6 +207,127 @@ func GetVMImageStatusAndContentLibraryUUID(
 	return &vmImage.Status, clUUID, nil
 }
 
+// GetVMCloneSource returns the managed object IDs of the VM, and optionally of the VM's snapshot, specified as
+// the source of the VM.
+func GetVMCloneSource(
+	vmCtx context.VirtualMachineContext,
+	k8sClient ctrlclient.Client) (string, string, error) {
+
+	source := vmCtx.VM.Spec.Source
+	namespace := vmCtx.VM.Namespace
+
+	markFalse := func(reason, msg string) {
+		conditions.MarkFalse(vmCtx.VM,
+			vmopv1alpha1.VirtualMachinePrereqReadyCondition,
+			reason,
+			vmopv1alpha1.ConditionSeverityError,
+			msg)
+	}
+
+	srcVM := &vmopv1alpha1.VirtualMachine{}
+	if err := k8sClient.Get(vmCtx, ctrlclient.ObjectKey{Name: source.VirtualMachineName, Namespace: namespace}, srcVM); err != nil {
+		msg := fmt.Sprintf("Failed to get source VirtualMachine: %s", source.VirtualMachineName)
+		markFalse(vmopv1alpha1.VirtualMachineSourceNotFoundReason, msg)
+		return "", "", errors.Wrap(err, msg)
+	}
+
+	if srcVM.Status.UniqueID == "" {
+		msg := fmt.Sprintf("Source VirtualMachine %s has not been created", srcVM.Name)
+		markFalse(vmopv1alpha1.VirtualMachineSourceNotReadyReason, msg)
+		return "", "", errors.New(msg)
+	}
+
+	if source.Instant && srcVM.Status.PowerState != vmopv1alpha1.VirtualMachinePoweredOn {
+		msg := fmt.Sprintf("Source VirtualMachine %s must be powered on to be instant cloned", srcVM.Name)
+		markFalse(vmopv1alpha1.VirtualMachineSourceNotReadyReason, msg)
+		return "", "", errors.New(msg)
+	}
+
+	if source.SnapshotName == "" {
+		return srcVM.Status.UniqueID, "", nil
+	}
+
+	vmSnapshot := &vmopv1alpha1.VirtualMachineSnapshot{}
+	if err := k8sClient.Get(vmCtx, ctrlclient.ObjectKey{Name: source.SnapshotName, Namespace: namespace}, vmSnapshot); err != nil {
+		msg := fmt.Sprintf("Failed to get source VirtualMachineSnapshot: %s", source.SnapshotName)
+		markFalse(vmopv1alpha1.VirtualMachineSourceNotFoundReason, msg)
+		return "", "", errors.Wrap(err, msg)
+	}
+
+	if vmSnapshot.Spec.VirtualMachineName != srcVM.Name {
+		msg := fmt.Sprintf("VirtualMachineSnapshot %s is not a snapshot of VirtualMachine %s", vmSnapshot.Name, srcVM.Name)
+		markFalse(vmopv1alpha1.VirtualMachineSourceNotFoundReason, msg)
+		return "", "", errors.New(msg)
+	}
+
+	if !vmSnapshot.Status.Ready || vmSnapshot.Status.SnapshotID == "" {
+		msg := fmt.Sprintf("Source VirtualMachineSnapshot %s is not ready", vmSnapshot.Name)
+		markFalse(vmopv1alpha1.VirtualMachineSourceNotReadyReason, msg)
+		return "", "", errors.New(msg)
+	}
+
+	return srcVM.Status.UniqueID, vmSnapshot.Status.SnapshotID, nil
+}
+
+type cdromImage struct {
+	itemID         string
+	contentVersion string
+}
+
+// GetVMCdromImages returns the library item IDs and content versions of the ISO images of the VM's CD-ROMs, keyed
+// by image name.
+func GetVMCdromImages(
+	vmCtx context.VirtualMachineContext,
+	k8sClient ctrlclient.Client) (map[string]cdromImage, error) {
+
+	images := make(map[string]cdromImage, len(vmCtx.VM.Spec.Cdrom))
+	for _, cdrom := range vmCtx.VM.Spec.Cdrom {
+		imageName := cdrom.ImageName
+		if _, ok := images[imageName]; ok {
+			continue
+		}
+
+		var imageSpec *vmopv1alpha1.VirtualMachineImageSpec
+		var imageStatus *vmopv1alpha1.VirtualMachineImageStatus
+		var err error
+		if lib.IsWCPVMImageRegistryEnabled() {
+			imageSpec, imageStatus, err = clutils.GetVMImageSpecStatus(vmCtx, k8sClient, imageName, vmCtx.VM.Namespace)
+		} else {
+			// The VirtualMachineImage resource remains in cluster scope without the WCP-VM-Image-Registry FSS.
+			vmImage := &vmopv1alpha1.VirtualMachineImage{}
+			err = k8sClient.Get(vmCtx, ctrlclient.ObjectKey{Name: imageName}, vmImage)
+			imageSpec, imageStatus = &vmImage.Spec, &vmImage.Status
+		}
+
+		if err != nil {
+			msg := fmt.Sprintf("Failed to get the image of CD-ROM %s: %s", cdrom.Name, imageName)
+			conditions.MarkFalse(vmCtx.VM,
+				vmopv1alpha1.VirtualMachinePrereqReadyCondition,
+				vmopv1alpha1.VirtualMachineImageNotFoundReason,
+				vmopv1alpha1.ConditionSeverityError,
+				msg)
+			return nil, errors.Wrap(err, msg)
+		}
+
+		if !clutils.IsISOImage(imageSpec) {
+			msg := fmt.Sprintf("The image of CD-ROM %s is not an ISO: %s", cdrom.Name, imageName)
+			conditions.MarkFalse(vmCtx.VM,
+				vmopv1alpha1.VirtualMachinePrereqReadyCondition,
+				vmopv1alpha1.VirtualMachineImageNotReadyReason,
+				vmopv1alpha1.ConditionSeverityError,
+				msg)
+			return nil, errors.New(msg)
+		}
+
+		images[imageName] = cdromImage{
+			itemID:         imageSpec.ImageID,
+			contentVersion: imageStatus.ContentVersion,
+		}
+	}
+
+	return images, nil
+}
+
 func GetVMMetadata(
 	vmCtx context.VirtualMachineContext,
 	k8sClient ctrlclient.Client) (vmMetadata, error) {
@@ -13,10 +13,12 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/json"
 	"encoding/pem"
 	"fmt"
 	"math/big"
 	"net"
+	"net/http"
 	"net/url"
 	"os"
 	"path"
@@ -31,6 +33,7 @@ import (
 	"github.com/vmware/govmomi/vapi/library"
 	"github.com/vmware/govmomi/vapi/rest"
 	"github.com/vmware/govmomi/vapi/vcenter"
+	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/soap"
 	"github.com/vmware/govmomi/vim25/types"
 	corev1 "k8s.io/api/core/v1"
@@ -388,6 +391,9 @@ func (c *TestContextForVCSim) setupVCSim(config VCSimTestConfig) {
 	c.RestClient = rest.NewClient(c.VCClient.Client)
 	Expect(c.RestClient.Login(c, simulator.DefaultLogin)).To(Succeed())
 
+	// vcsim does not implement the library item storage API.
+	c.model.Service.Handle(rest.Path+"/com/vmware/content/library/item/storage", http.HandlerFunc(c.libraryItemStorage))
+
 	c.Finder = find.NewFinder(vcClient.Client)
 
 	dc, err := c.Finder.DefaultDatacenter(c)
@@ -506,6 +512,58 @@ func (c *TestContextForVCSim) setupContentLibrary(config VCSimTestConfig) {
 		path.Join(testutil.GetRootDirOrDie(), "images", "ttylinux-pc_i486-16.1.ovf"))
 }
 
+// libraryItemStorage serves the storage of the files of a library item, which vcsim stores in the
+// "contentlib-<library ID>/<item ID>" directory of the library's datastore.
+func (c *TestContextForVCSim) libraryItemStorage(w http.ResponseWriter, r *http.Request) {
+	libMgr := library.NewManager(c.RestClient)
+	itemID := r.URL.Query().Get("library_item_id")
+
+	item, err := libMgr.GetLibraryItem(c, itemID)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	lib, err := libMgr.GetLibraryByID(c, item.LibraryID)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	files, err := libMgr.ListLibraryItemFiles(c, itemID)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	dsRef := types.ManagedObjectReference{Type: "Datastore", Value: lib.Storage[0].DatastoreID}
+	var moDS mo.Datastore
+	if err := object.NewDatastore(c.VCClient.Client, dsRef).Properties(c, dsRef, []string{"summary.url"}, &moDS); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	type storage struct {
+		Name           string                  `json:"name"`
+		StorageBacking library.StorageBackings `json:"storage_backing"`
+		StorageURIs    []string                `json:"storage_uris"`
+	}
+
+	value := make([]storage, 0, len(files))
+	for _, file := range files {
+		value = append(value, storage{
+			Name:           file.Name,
+			StorageBacking: lib.Storage[0],
+			StorageURIs:    []string{path.Join(moDS.Summary.Url, "contentlib-"+lib.ID, item.ID, file.Name)},
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(struct {
+		Value []storage `json:"value"`
+	}{value})
+}
+
 func (c *TestContextForVCSim) ContentLibraryItemTemplate(srcVMName, templateName string) {
 	clID := c.ContentLibraryID
 	Expect(clID).ToNot(BeEmpty())
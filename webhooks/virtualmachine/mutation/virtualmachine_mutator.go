@@ -1,4 +1,4 @@
-// Copyright (c) 2019-2022 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2019-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package mutation
@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"os"
 	"reflect"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	admissionv1 "k8s.io/api/admission/v1"
@@ -99,6 +101,10 @@ func (m mutator) Mutate(ctx *context.WebhookRequestContext) admission.Response {
 				}
 			}
 		}
+
+		if SetNextRestartTime(modified) {
+			wasMutated = true
+		}
 	}
 
 	if !wasMutated {
@@ -129,6 +135,17 @@ func (m mutator) vmFromUnstructured(obj runtime.Unstructured) (*vmopv1.VirtualMa
 	return vm, nil
 }
 
+// SetNextRestartTime replaces the "now" value of the VM's Spec.NextRestartTime with the current time so
+// the restart is requested once, at the time of this update.
+func SetNextRestartTime(vm *vmopv1.VirtualMachine) bool {
+	if !strings.EqualFold(vm.Spec.NextRestartTime, "now") {
+		return false
+	}
+
+	vm.Spec.NextRestartTime = time.Now().UTC().Format(time.RFC3339)
+	return true
+}
+
 // AddDefaultNetworkInterface adds default network interface to a VM if the NoNetwork annotation is not set
 // and no NetworkInterface is specified.
 // Return true if default NetworkInterface is added, otherwise return false.
@@ -1,10 +1,11 @@
-// Copyright (c) 2019-2022 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2019-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package mutation_test
 
 import (
 	"os"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -131,6 +132,22 @@ func unitTestsMutating() {
 		})
 	})
 
+	Describe("SetNextRestartTime", func() {
+		It("Should replace now with the current time", func() {
+			ctx.vm.Spec.NextRestartTime = "Now"
+			Expect(mutation.SetNextRestartTime(ctx.vm)).To(BeTrue())
+			t, err := time.Parse(time.RFC3339, ctx.vm.Spec.NextRestartTime)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(t).To(BeTemporally("~", time.Now(), 5*time.Second))
+		})
+
+		It("Should not change a time", func() {
+			ctx.vm.Spec.NextRestartTime = "2023-01-01T00:00:00Z"
+			Expect(mutation.SetNextRestartTime(ctx.vm)).To(BeFalse())
+			Expect(ctx.vm.Spec.NextRestartTime).To(Equal("2023-01-01T00:00:00Z"))
+		})
+	})
+
 	Describe("AddDefaultNetworkInterface", func() {
 		BeforeEach(func() {
 			Expect(os.Setenv(lib.NetworkProviderType, mutation.VDSTYPE)).Should(Succeed())
@@ -1,22 +1,29 @@
-// Copyright (c) 2019-2022 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2019-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package validation
 
 import (
 	"fmt"
+	"net"
 	"net/http"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
+	authorizationv1 "k8s.io/api/authorization/v1"
 	corev1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/api/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	utilvalidation "k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -37,6 +44,7 @@ import (
 	"github.com/vmware-tanzu/vm-operator/pkg/topology"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/config"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/constants"
+	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/imagevolume"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/instancestorage"
 	"github.com/vmware-tanzu/vm-operator/pkg/vmprovider/providers/vsphere/network"
 	"github.com/vmware-tanzu/vm-operator/webhooks/common"
@@ -48,8 +56,10 @@ const (
 	isRestrictedNetworkKey               = "IsRestrictedNetwork"
 	allowedRestrictedNetworkTCPProbePort = 6443
 
-	readinessProbeNoActions                   = "must specify an action"
-	readinessProbeOnlyOneAction               = "only one action can be specified"
+	probeNoActions                            = "must specify an action"
+	probeOnlyOneAction                        = "only one action can be specified"
+	httpGetPathNotAbsolute                    = "must be an absolute path"
+	httpGetExpectedStatusInvalidRange         = "min must be less than or equal to max"
 	updatesNotAllowedWhenPowerOn              = "updates to this field is not allowed when VM power is on"
 	storageClassNotAssignedFmt                = "Storage policy is not associated with the namespace %s"
 	storageClassNotFoundFmt                   = "Storage policy is not associated with the namespace %s"
@@ -59,11 +69,65 @@ const (
 	eagerZeroedAndThinProvisionedNotSupported = "Volume provisioning cannot have EagerZeroed and ThinProvisioning set. Eager zeroing requires thick provisioning"
 	addingModifyingInstanceVolumesNotAllowed  = "adding or modifying instance storage volume claim(s) is not allowed"
 	metadataTransportResourcesInvalid         = "%s and %s cannot be specified simultaneously"
+	networkRouteToInvalid                     = "must be a CIDR or \"default\""
+	networkRouteViaInvalid                    = "must be an IP address"
+	networkBondTooFewMembers                  = "must have at least two member network interfaces"
+	imageNameAndSourceSpecified               = "only one of imageName or source must be specified"
+	sourceSameAsVM                            = "must not be the VirtualMachine itself"
+	sourceVMNotCreated                        = "source VirtualMachine has not been created"
+	sourceVMBeingDeleted                      = "source VirtualMachine is being deleted"
+	sourceSnapshotOfOtherVMFmt                = "VirtualMachineSnapshot is not a snapshot of VirtualMachine %s"
+	sourceSnapshotNotReady                    = "source VirtualMachineSnapshot is not ready"
+	sourceAccessDeniedFmt                     = "user %s is not allowed to get %s %s"
+	instantCloneWithSnapshot                  = "only one of instant or snapshotName must be specified"
+	instantCloneSourceNotPoweredOn            = "source VirtualMachine must be powered on to be instant cloned"
+	instantCloneSourceWithVolumes             = "source VirtualMachine with PersistentVolumeClaim volumes cannot be instant cloned"
+	nextRestartTimeOnCreate                   = "cannot be set when creating a VirtualMachine"
+	nextRestartTimeInvalid                    = "must be \"now\" or a time in RFC3339 format"
+	nextRestartTimeInFuture                   = "cannot be in the future"
+	revertToSnapshotOnCreate                  = "cannot be set when creating a VirtualMachine"
+	revertToSnapshotOfOtherVM                 = "VirtualMachineSnapshot is not a snapshot of this VirtualMachine"
+	efiFirmwareRequiredFmt                    = "requires efi firmware but the VirtualMachine has %s firmware"
+	virtualTPMRemovalNotAllowed               = "virtual TPM cannot be removed from a VirtualMachine"
+	storageClassChangeWhileRelocating         = "cannot be changed while the VirtualMachine storage is being relocated"
+	zoneRemovalNotAllowed                     = "zone cannot be removed from a VirtualMachine"
+	zoneChangeWhileMigrating                  = "cannot be changed while the VirtualMachine is being migrated"
+	zoneChangeWhileRelocating                 = "cannot be changed while the VirtualMachine storage is being relocated"
+	zoneChangeWithInstanceStorage             = "cannot be changed for a VirtualMachine with instance storage"
+	zoneChangeWithVolumes                     = "cannot be changed for a VirtualMachine with PersistentVolumeClaim volumes"
+	zoneChangeWithoutStorageClass             = "cannot be changed for a VirtualMachine without a StorageClass"
+	zoneNamespaceNotFoundFmt                  = "zone does not have a resource pool for the namespace %s"
+	storageClassChangeWhileMigrating          = "cannot be changed while the VirtualMachine is being migrated"
+	bootDiskCapacityNotPositive               = "must be greater than zero"
+	bootDiskCapacityNotKiBMultiple            = "must be a multiple of 1Ki"
+	bootDiskCapacityDecreaseFmt               = "cannot be decreased from %s"
+	bootDiskCapacityBelowImageFmt             = "cannot be less than the boot disk capacity of the image, %s"
+	bootDiskCapacityWithImageVolume           = "cannot be specified for a VirtualMachine that boots from an imageVolumeClaim"
+	bootDiskCapacityWithLinkedClone           = "cannot be specified for a VirtualMachine that is a linked or instant clone"
+	bootDiskCapacityWithSnapshots             = "cannot be increased for a VirtualMachine that has snapshots"
+	imageAndInstanceVolumeClaimSpecified      = "only one of instanceVolumeClaim or imageVolumeClaim must be specified"
+	imageVolumeClaimImageNameMismatch         = "must be the imageName of the VirtualMachine"
+	multipleImageVolumeClaims                 = "only one volume can have an imageVolumeClaim"
+	imageVolumeUpdateNotAllowed               = "the volume with an imageVolumeClaim cannot be added, removed or modified"
+	cdromImageNotISO                          = "must be the name of a VirtualMachineImage of type ISO"
+	imageNameISO                              = "must not be an ISO image, which can only back the CD-ROMs of a VirtualMachine"
+	cdromAddRemoveNotAllowedWhenPowerOn       = "CD-ROMs cannot be added, removed or reordered when VM power is on"
+	operatorAnnotationChangeNotAllowed        = "adding, modifying or removing this annotation is not allowed"
 )
 
+// operatorAnnotations are the annotations with the state VM Operator keeps about a VM, such as the task of an
+// operation in progress that locks out other changes. Only privileged users can add, modify or remove them.
+var operatorAnnotations = []string{
+	constants.StorageRelocateTaskAnnotation,
+	constants.MigrateTaskAnnotation,
+	constants.BootDiskURLPathAnnotation,
+}
+
 // +kubebuilder:webhook:verbs=create;update,path=/default-validate-vmoperator-vmware-com-v1alpha1-virtualmachine,mutating=false,failurePolicy=fail,groups=vmoperator.vmware.com,resources=virtualmachines,versions=v1alpha1,name=default.validating.virtualmachine.vmoperator.vmware.com,sideEffects=None,admissionReviewVersions=v1;v1beta1
 // +kubebuilder:rbac:groups=vmoperator.vmware.com,resources=virtualmachines,verbs=get;list
 // +kubebuilder:rbac:groups=vmoperator.vmware.com,resources=virtualmachines/status,verbs=get
+// +kubebuilder:rbac:groups=vmoperator.vmware.com,resources=virtualmachinesnapshots,verbs=get;list
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
 
 // AddToManager adds the webhook to the provided manager.
 func AddToManager(ctx *context.ControllerManagerContext, mgr ctrlmgr.Manager) error {
@@ -100,25 +164,70 @@ func (v validator) ValidateCreate(ctx *context.WebhookRequestContext) admission.
 		return webhook.Errored(http.StatusBadRequest, err)
 	}
 
+	fieldErrs := v.validateCreate(ctx, vm)
+
+	validationErrs := make([]string, 0, len(fieldErrs))
+	for _, fieldErr := range fieldErrs {
+		validationErrs = append(validationErrs, fieldErr.Error())
+	}
+
+	return common.BuildValidationResponse(ctx, validationErrs, nil)
+}
+
+// ValidateTemplate validates the VirtualMachines created from the template of a VirtualMachineReplicaSet
+// or VirtualMachineDeployment. The VirtualMachines are created by the controller's privileged account, so
+// the template is validated as if the user of the request created them. The errors are reported under
+// templatePath. The name of the VirtualMachineReplicaSet or VirtualMachineDeployment stands in for the
+// names generated for the VirtualMachines.
+func ValidateTemplate(
+	ctx *context.WebhookRequestContext,
+	client client.Client,
+	name, namespace string,
+	template *vmopv1.VirtualMachineTemplateSpec,
+	templatePath *field.Path) field.ErrorList {
+
+	vm := &vmopv1.VirtualMachine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Labels:      template.Labels,
+			Annotations: template.Annotations,
+		},
+		Spec: template.Spec,
+	}
+
+	v := NewValidator(client).(validator)
+	fieldErrs := v.validateCreate(ctx, vm)
+	for _, fieldErr := range fieldErrs {
+		fieldErr.Field = templatePath.String() + "." + fieldErr.Field
+	}
+
+	return fieldErrs
+}
+
+func (v validator) validateCreate(ctx *context.WebhookRequestContext, vm *vmopv1.VirtualMachine) field.ErrorList {
 	var fieldErrs field.ErrorList
 
 	fieldErrs = append(fieldErrs, v.validateMetadata(ctx, vm)...)
+	fieldErrs = append(fieldErrs, v.validateOperatorAnnotations(ctx, vm, nil)...)
 	fieldErrs = append(fieldErrs, v.validateAvailabilityZone(ctx, vm, nil)...)
 	fieldErrs = append(fieldErrs, v.validateImage(ctx, vm)...)
+	fieldErrs = append(fieldErrs, v.validateSource(ctx, vm)...)
 	fieldErrs = append(fieldErrs, v.validateClass(ctx, vm)...)
 	fieldErrs = append(fieldErrs, v.validateStorageClass(ctx, vm)...)
 	fieldErrs = append(fieldErrs, v.validateNetwork(ctx, vm)...)
 	fieldErrs = append(fieldErrs, v.validateVolumes(ctx, vm)...)
 	fieldErrs = append(fieldErrs, v.validateVMVolumeProvisioningOptions(ctx, vm)...)
 	fieldErrs = append(fieldErrs, v.validateReadinessProbe(ctx, vm)...)
+	fieldErrs = append(fieldErrs, v.validateLivenessProbe(ctx, vm)...)
 	fieldErrs = append(fieldErrs, v.validateInstanceStorageVolumes(ctx, vm, nil)...)
+	fieldErrs = append(fieldErrs, v.validateNextRestartTime(ctx, vm, nil)...)
+	fieldErrs = append(fieldErrs, v.validateRevertToSnapshot(ctx, vm, nil)...)
+	fieldErrs = append(fieldErrs, v.validateBootOptions(ctx, vm, nil)...)
+	fieldErrs = append(fieldErrs, v.validateBootDiskCapacity(ctx, vm, nil)...)
+	fieldErrs = append(fieldErrs, v.validateCdrom(ctx, vm)...)
 
-	validationErrs := make([]string, 0, len(fieldErrs))
-	for _, fieldErr := range fieldErrs {
-		validationErrs = append(validationErrs, fieldErr.Error())
-	}
-
-	return common.BuildValidationResponse(ctx, validationErrs, nil)
+	return fieldErrs
 }
 
 func (v validator) ValidateDelete(*context.WebhookRequestContext) admission.Response {
@@ -128,14 +237,19 @@ func (v validator) ValidateDelete(*context.WebhookRequestContext) admission.Resp
 // ValidateUpdate validates if the given VirtualMachineSpec update is valid.
 // Updates to following fields are not allowed:
 //   - ImageName
+//   - Source
 //   - ClassName
-//   - StorageClass
 //   - ResourcePolicyName
+//   - Volumes with an ImageVolumeClaim
 
 // Following fields can only be updated when the VM is powered off.
 //   - Ports
 //   - VmMetaData
 //   - NetworkInterfaces
+//   - NetworkBonds
+//   - BootOptions
+//   - VirtualTPM
+//   - Cdrom, other than the ImageName and Connected of a CD-ROM
 //   - Volumes referencing a VsphereVolume
 //   - AdvancedOptions
 //     - DefaultVolumeProvisioningOptions
@@ -163,21 +277,31 @@ func (v validator) ValidateUpdate(ctx *context.WebhookRequestContext) admission.
 	// If a VM is powered off, all config changes are allowed.
 	// If a VM is requesting a power off, we can Reconfigure the VM _after_ we power it off - all changes are allowed.
 	// If a VM is requesting a power on, we can Reconfigure the VM _before_ we power it on - all changes are allowed.
-	// So, we only run these validations when the VM is powered on, and is not requesting a power state change.
-	if currentPowerState == vmopv1.VirtualMachinePoweredOn && desiredPowerState == vmopv1.VirtualMachinePoweredOn {
+	// So, we only run these validations when the VM is powered on or suspended, and is not requesting a power off.
+	if currentPowerState != vmopv1.VirtualMachinePoweredOff && desiredPowerState != vmopv1.VirtualMachinePoweredOff {
 		invalidFields := v.validateUpdatesWhenPoweredOn(ctx, vm, oldVM)
 		fieldErrs = append(fieldErrs, invalidFields...)
 	}
 
 	// Validations for allowed updates. Return validation responses here for conditional updates regardless
 	// of whether the update is allowed or not.
+	fieldErrs = append(fieldErrs, v.validateClass(ctx, vm)...)
 	fieldErrs = append(fieldErrs, v.validateMetadata(ctx, vm)...)
+	fieldErrs = append(fieldErrs, v.validateOperatorAnnotations(ctx, vm, oldVM)...)
 	fieldErrs = append(fieldErrs, v.validateAvailabilityZone(ctx, vm, oldVM)...)
+	fieldErrs = append(fieldErrs, v.validateStorageClassUpdate(ctx, vm, oldVM)...)
 	fieldErrs = append(fieldErrs, v.validateNetwork(ctx, vm)...)
 	fieldErrs = append(fieldErrs, v.validateVolumes(ctx, vm)...)
+	fieldErrs = append(fieldErrs, v.validateImageVolumeUpdate(ctx, vm, oldVM)...)
 	fieldErrs = append(fieldErrs, v.validateVMVolumeProvisioningOptions(ctx, vm)...)
 	fieldErrs = append(fieldErrs, v.validateReadinessProbe(ctx, vm)...)
+	fieldErrs = append(fieldErrs, v.validateLivenessProbe(ctx, vm)...)
 	fieldErrs = append(fieldErrs, v.validateInstanceStorageVolumes(ctx, vm, oldVM)...)
+	fieldErrs = append(fieldErrs, v.validateNextRestartTime(ctx, vm, oldVM)...)
+	fieldErrs = append(fieldErrs, v.validateRevertToSnapshot(ctx, vm, oldVM)...)
+	fieldErrs = append(fieldErrs, v.validateBootOptions(ctx, vm, oldVM)...)
+	fieldErrs = append(fieldErrs, v.validateBootDiskCapacity(ctx, vm, oldVM)...)
+	fieldErrs = append(fieldErrs, v.validateCdrom(ctx, vm)...)
 
 	validationErrs := make([]string, 0, len(fieldErrs))
 	for _, fieldErr := range fieldErrs {
@@ -204,14 +328,205 @@ func (v validator) validateMetadata(ctx *context.WebhookRequestContext, vm *vmop
 	return allErrs
 }
 
+// validateOperatorAnnotations validates that a user other than a privileged user does not add, modify or remove
+// the annotations VM Operator keeps its state in.
+func (v validator) validateOperatorAnnotations(ctx *context.WebhookRequestContext, vm, oldVM *vmopv1.VirtualMachine) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if ctx.IsPrivilegedAccount {
+		return allErrs
+	}
+
+	for _, key := range operatorAnnotations {
+		val, ok := vm.Annotations[key]
+		var oldVal string
+		var oldOk bool
+		if oldVM != nil {
+			oldVal, oldOk = oldVM.Annotations[key]
+		}
+
+		if ok != oldOk || val != oldVal {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("metadata", "annotations").Key(key),
+				operatorAnnotationChangeNotAllowed))
+		}
+	}
+
+	return allErrs
+}
+
 func (v validator) validateImage(ctx *context.WebhookRequestContext, vm *vmopv1.VirtualMachine) field.ErrorList {
 	var allErrs field.ErrorList
 
 	imageNamePath := field.NewPath("spec", "imageName")
 	imageName := vm.Spec.ImageName
 
+	if vm.Spec.Source != nil {
+		if imageName != "" {
+			allErrs = append(allErrs, field.Forbidden(imageNamePath, imageNameAndSourceSpecified))
+		}
+		return allErrs
+	}
+
 	if imageName == "" {
-		allErrs = append(allErrs, field.Required(imageNamePath, ""))
+		return append(allErrs, field.Required(imageNamePath, ""))
+	}
+
+	// The other validations of the image report an image that cannot be found.
+	var imageSpec *vmopv1.VirtualMachineImageSpec
+	if lib.IsWCPVMImageRegistryEnabled() {
+		if spec, _, err := clutils.GetVMImageSpecStatus(ctx, v.client, imageName, vm.Namespace); err == nil {
+			imageSpec = spec
+		}
+	} else {
+		image := vmopv1.VirtualMachineImage{}
+		if err := v.client.Get(ctx, client.ObjectKey{Name: imageName}, &image); err == nil {
+			imageSpec = &image.Spec
+		}
+	}
+
+	if imageSpec != nil && clutils.IsISOImage(imageSpec) {
+		allErrs = append(allErrs, field.Invalid(imageNamePath, imageName, imageNameISO))
+	}
+
+	return allErrs
+}
+
+// validateSource validates that the source VirtualMachine, and its snapshot if any, can be cloned by the user.
+func (v validator) validateSource(ctx *context.WebhookRequestContext, vm *vmopv1.VirtualMachine) field.ErrorList {
+	var allErrs field.ErrorList
+
+	source := vm.Spec.Source
+	if source == nil {
+		return allErrs
+	}
+
+	sourcePath := field.NewPath("spec", "source")
+	vmNamePath := sourcePath.Child("virtualMachineName")
+	snapshotNamePath := sourcePath.Child("snapshotName")
+
+	if source.VirtualMachineName == "" {
+		return append(allErrs, field.Required(vmNamePath, ""))
+	}
+	if source.VirtualMachineName == vm.Name {
+		return append(allErrs, field.Invalid(vmNamePath, source.VirtualMachineName, sourceSameAsVM))
+	}
+
+	if source.Instant {
+		if source.SnapshotName != "" {
+			allErrs = append(allErrs, field.Forbidden(sourcePath.Child("instant"), instantCloneWithSnapshot))
+		}
+		// The guest of an instant clone is not rebooted so it can only be customized through guestinfo.
+		if md := vm.Spec.VmMetadata; md != nil && md.Transport != vmopv1.VirtualMachineMetadataExtraConfigTransport {
+			allErrs = append(allErrs, field.NotSupported(field.NewPath("spec", "vmMetadata", "transport"), md.Transport,
+				[]string{string(vmopv1.VirtualMachineMetadataExtraConfigTransport)}))
+		}
+		if len(allErrs) > 0 {
+			return allErrs
+		}
+	}
+
+	// Check the user's access first so the existence of the source is not disclosed to an unauthorized user.
+	if errs := v.validateSourceAccess(ctx, vm.Namespace, "virtualmachines", source.VirtualMachineName, vmNamePath); len(errs) > 0 {
+		return append(allErrs, errs...)
+	}
+	if source.SnapshotName != "" {
+		if errs := v.validateSourceAccess(ctx, vm.Namespace, "virtualmachinesnapshots", source.SnapshotName, snapshotNamePath); len(errs) > 0 {
+			return append(allErrs, errs...)
+		}
+	}
+
+	srcVM := &vmopv1.VirtualMachine{}
+	if err := v.client.Get(ctx, client.ObjectKey{Name: source.VirtualMachineName, Namespace: vm.Namespace}, srcVM); err != nil {
+		if apierrors.IsNotFound(err) {
+			return append(allErrs, field.NotFound(vmNamePath, source.VirtualMachineName))
+		}
+		return append(allErrs, field.InternalError(vmNamePath, err))
+	}
+
+	if !srcVM.DeletionTimestamp.IsZero() {
+		allErrs = append(allErrs, field.Invalid(vmNamePath, source.VirtualMachineName, sourceVMBeingDeleted))
+	} else if srcVM.Status.UniqueID == "" {
+		allErrs = append(allErrs, field.Invalid(vmNamePath, source.VirtualMachineName, sourceVMNotCreated))
+	} else if source.Instant && srcVM.Status.PowerState != vmopv1.VirtualMachinePoweredOn {
+		allErrs = append(allErrs, field.Invalid(vmNamePath, source.VirtualMachineName, instantCloneSourceNotPoweredOn))
+	} else if source.Instant && hasPVCVolumes(srcVM) {
+		// An instant clone shares all the disks of its source, and unlike a full or linked clone the disks of
+		// the PVC volumes cannot be left out.
+		allErrs = append(allErrs, field.Invalid(vmNamePath, source.VirtualMachineName, instantCloneSourceWithVolumes))
+	}
+
+	if source.SnapshotName == "" {
+		return allErrs
+	}
+
+	vmSnapshot := &vmopv1.VirtualMachineSnapshot{}
+	if err := v.client.Get(ctx, client.ObjectKey{Name: source.SnapshotName, Namespace: vm.Namespace}, vmSnapshot); err != nil {
+		if apierrors.IsNotFound(err) {
+			return append(allErrs, field.NotFound(snapshotNamePath, source.SnapshotName))
+		}
+		return append(allErrs, field.InternalError(snapshotNamePath, err))
+	}
+
+	if vmSnapshot.Spec.VirtualMachineName != srcVM.Name {
+		allErrs = append(allErrs, field.Invalid(snapshotNamePath, source.SnapshotName,
+			fmt.Sprintf(sourceSnapshotOfOtherVMFmt, srcVM.Name)))
+	} else if !vmSnapshot.Status.Ready {
+		allErrs = append(allErrs, field.Invalid(snapshotNamePath, source.SnapshotName, sourceSnapshotNotReady))
+	}
+
+	return allErrs
+}
+
+func hasPVCVolumes(vm *vmopv1.VirtualMachine) bool {
+	for _, vol := range vm.Spec.Volumes {
+		if vol.PersistentVolumeClaim != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// validateSourceAccess validates that the user is allowed to get the named resource in the namespace. A clone
+// copies the disks of its source so the user must have access to the source.
+func (v validator) validateSourceAccess(
+	ctx *context.WebhookRequestContext,
+	namespace, resource, name string,
+	fieldPath *field.Path) field.ErrorList {
+
+	var allErrs field.ErrorList
+
+	if ctx.IsPrivilegedAccount {
+		return allErrs
+	}
+
+	extra := make(map[string]authorizationv1.ExtraValue, len(ctx.UserInfo.Extra))
+	for k, val := range ctx.UserInfo.Extra {
+		extra[k] = authorizationv1.ExtraValue(val)
+	}
+
+	sar := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   ctx.UserInfo.Username,
+			UID:    ctx.UserInfo.UID,
+			Groups: ctx.UserInfo.Groups,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      "get",
+				Group:     vmopv1.SchemeGroupVersion.Group,
+				Resource:  resource,
+				Name:      name,
+			},
+		},
+	}
+
+	if err := v.client.Create(ctx, sar); err != nil {
+		return append(allErrs, field.InternalError(fieldPath, err))
+	}
+
+	if !sar.Status.Allowed {
+		allErrs = append(allErrs, field.Forbidden(fieldPath,
+			fmt.Sprintf(sourceAccessDeniedFmt, ctx.UserInfo.Username, resource, name)))
 	}
 
 	return allErrs
@@ -262,6 +577,25 @@ func (v validator) validateStorageClass(ctx *context.WebhookRequestContext, vm *
 		fmt.Sprintf(storageClassNotAssignedFmt, namespace)))
 }
 
+// validateStorageClassUpdate validates a change of the StorageClass, which relocates the storage of the VM. The
+// StorageClass cannot change again until the storage has been relocated.
+func (v validator) validateStorageClassUpdate(ctx *context.WebhookRequestContext, vm, oldVM *vmopv1.VirtualMachine) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if vm.Spec.StorageClass == oldVM.Spec.StorageClass {
+		return allErrs
+	}
+
+	if _, ok := oldVM.Annotations[constants.StorageRelocateTaskAnnotation]; ok {
+		return append(allErrs, field.Forbidden(field.NewPath("spec", "storageClass"), storageClassChangeWhileRelocating))
+	}
+	if _, ok := oldVM.Annotations[constants.MigrateTaskAnnotation]; ok {
+		return append(allErrs, field.Forbidden(field.NewPath("spec", "storageClass"), storageClassChangeWhileMigrating))
+	}
+
+	return append(allErrs, v.validateStorageClass(ctx, vm)...)
+}
+
 func (v validator) validateNetwork(ctx *context.WebhookRequestContext, vm *vmopv1.VirtualMachine) field.ErrorList {
 	var allErrs field.ErrorList
 
@@ -307,6 +641,59 @@ func (v validator) validateNetwork(ctx *context.WebhookRequestContext, vm *vmopv
 				supportedEthernetCardTypes))
 		}
 
+		for j, route := range nif.Routes {
+			routePath := curPath.Child("routes").Index(j)
+			if _, _, err := net.ParseCIDR(route.To); err != nil && route.To != "default" {
+				allErrs = append(allErrs, field.Invalid(routePath.Child("to"), route.To, networkRouteToInvalid))
+			}
+			if net.ParseIP(route.Via) == nil {
+				allErrs = append(allErrs, field.Invalid(routePath.Child("via"), route.Via, networkRouteViaInvalid))
+			}
+		}
+	}
+
+	allErrs = append(allErrs, v.validateNetworkBonds(ctx, vm)...)
+
+	return allErrs
+}
+
+func (v validator) validateNetworkBonds(ctx *context.WebhookRequestContext, vm *vmopv1.VirtualMachine) field.ErrorList {
+	var allErrs field.ErrorList
+
+	bondsPath := field.NewPath("spec", "networkBonds")
+	members := map[string]int{}
+
+	for i, bond := range vm.Spec.NetworkBonds {
+		namePath := bondsPath.Index(i).Child("name")
+		if bond.Name == "" {
+			allErrs = append(allErrs, field.Required(namePath, ""))
+			continue
+		}
+		if _, ok := members[bond.Name]; ok {
+			allErrs = append(allErrs, field.Duplicate(namePath, bond.Name))
+			continue
+		}
+		members[bond.Name] = 0
+	}
+
+	networkInterfacePath := field.NewPath("spec", "networkInterfaces")
+	for i, nif := range vm.Spec.NetworkInterfaces {
+		if nif.BondName == "" {
+			continue
+		}
+		if _, ok := members[nif.BondName]; !ok {
+			allErrs = append(allErrs, field.NotFound(networkInterfacePath.Index(i).Child("bondName"), nif.BondName))
+			continue
+		}
+		members[nif.BondName]++
+	}
+
+	for i, bond := range vm.Spec.NetworkBonds {
+		if count, ok := members[bond.Name]; ok && count < 2 {
+			allErrs = append(allErrs, field.Invalid(bondsPath.Index(i).Child("name"), bond.Name, networkBondTooFewMembers))
+			// Only report each bond once.
+			delete(members, bond.Name)
+		}
 	}
 
 	return allErrs
@@ -340,6 +727,7 @@ func (v validator) validateVolumes(ctx *context.WebhookRequestContext, vm *vmopv
 	volumesPath := field.NewPath("spec", "volumes")
 	volumeNames := map[string]bool{}
 	hasPVC := false
+	hasImageVolume := false
 
 	for i, vol := range vm.Spec.Volumes {
 		curVolPath := volumesPath.Index(i)
@@ -363,12 +751,21 @@ func (v validator) validateVolumes(ctx *context.WebhookRequestContext, vm *vmopv
 		if vol.PersistentVolumeClaim != nil {
 			hasPVC = true
 			allErrs = append(allErrs, v.validateVolumeWithPVC(ctx, vm, vol, curVolPath)...)
+
+			if vol.PersistentVolumeClaim.ImageVolumeClaim != nil {
+				if hasImageVolume {
+					allErrs = append(allErrs, field.Forbidden(curVolPath.Child("persistentVolumeClaim", "imageVolumeClaim"),
+						multipleImageVolumeClaims))
+				}
+				hasImageVolume = true
+			}
 		} else { // vol.VsphereVolume != nil
 			allErrs = append(allErrs, v.validateVsphereVolume(vol.VsphereVolume, curVolPath)...)
 		}
 	}
 
-	if !hasPVC {
+	if !hasPVC || vm.Spec.Source != nil {
+		// A VM cloned from another VM does not have an image to check the hardware version of.
 		return allErrs
 	}
 
@@ -427,6 +824,33 @@ func (v validator) validateVolumeWithPVC(ctx *context.WebhookRequestContext, vm
 			[]string{"false"}))
 	}
 
+	if imageVolumeClaim := pvcSource.ImageVolumeClaim; imageVolumeClaim != nil {
+		imageVolumeClaimPath := pvcPath.Child("imageVolumeClaim")
+
+		if pvcSource.InstanceVolumeClaim != nil {
+			allErrs = append(allErrs, field.Forbidden(imageVolumeClaimPath, imageAndInstanceVolumeClaimSpecified))
+		}
+
+		// The VM is deployed from its image, so only the boot disk of that image can populate the PVC.
+		if imageVolumeClaim.ImageName == "" {
+			allErrs = append(allErrs, field.Required(imageVolumeClaimPath.Child("imageName"), ""))
+		} else if imageVolumeClaim.ImageName != vm.Spec.ImageName {
+			allErrs = append(allErrs, field.Invalid(imageVolumeClaimPath.Child("imageName"),
+				imageVolumeClaim.ImageName, imageVolumeClaimImageNameMismatch))
+		}
+	}
+
+	return allErrs
+}
+
+// validateImageVolumeUpdate validates that the image volume is not changed since the VM boots from it.
+func (v validator) validateImageVolumeUpdate(ctx *context.WebhookRequestContext, vm, oldVM *vmopv1.VirtualMachine) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if !equality.Semantic.DeepEqual(imagevolume.GetVolume(vm), imagevolume.GetVolume(oldVM)) {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "volumes"), imageVolumeUpdateNotAllowed))
+	}
+
 	return allErrs
 }
 
@@ -468,29 +892,424 @@ func (v validator) validateReadinessProbe(ctx *context.WebhookRequestContext, vm
 		return allErrs
 	}
 
-	readinessProbePath := field.NewPath("spec", "readinessProbe")
+	return append(allErrs, v.validateProbe(ctx, probe, field.NewPath("spec", "readinessProbe"))...)
+}
+
+func (v validator) validateLivenessProbe(ctx *context.WebhookRequestContext, vm *vmopv1.VirtualMachine) field.ErrorList {
+	var allErrs field.ErrorList
+
+	probe := vm.Spec.LivenessProbe
+	if probe == nil {
+		return allErrs
+	}
+
+	return append(allErrs, v.validateProbe(ctx, &probe.Probe, field.NewPath("spec", "livenessProbe"))...)
+}
+
+// validateNextRestartTime validates that a restart is only requested on an existing VM, with a time that is not
+// in the future which the controller can compare against the last restart. The mutation webhook replaces "now" with the current time.
+func (v validator) validateNextRestartTime(ctx *context.WebhookRequestContext, vm, oldVM *vmopv1.VirtualMachine) field.ErrorList {
+	var allErrs field.ErrorList
+
+	nextRestartTime := vm.Spec.NextRestartTime
+	if nextRestartTime == "" {
+		return allErrs
+	}
+
+	nextRestartTimePath := field.NewPath("spec", "nextRestartTime")
+
+	if oldVM == nil {
+		return append(allErrs, field.Forbidden(nextRestartTimePath, nextRestartTimeOnCreate))
+	}
+
+	if nextRestartTime == oldVM.Spec.NextRestartTime {
+		return allErrs
+	}
+
+	restartTime, err := time.Parse(time.RFC3339, nextRestartTime)
+	if err != nil {
+		allErrs = append(allErrs, field.Invalid(nextRestartTimePath, nextRestartTime, nextRestartTimeInvalid))
+	} else if restartTime.After(time.Now()) {
+		// The controller restarts the VM as soon as it observes the time, so a future time would only be
+		// recorded as the last restart time.
+		allErrs = append(allErrs, field.Invalid(nextRestartTimePath, nextRestartTime, nextRestartTimeInFuture))
+	}
+
+	return allErrs
+}
+
+// validateRevertToSnapshot validates that a revert is only requested on an existing VM, to a snapshot of the VM.
+func (v validator) validateRevertToSnapshot(ctx *context.WebhookRequestContext, vm, oldVM *vmopv1.VirtualMachine) field.ErrorList {
+	var allErrs field.ErrorList
+
+	snapshotName := vm.Spec.RevertToSnapshot
+	if snapshotName == "" {
+		return allErrs
+	}
+
+	revertToSnapshotPath := field.NewPath("spec", "revertToSnapshot")
+
+	if oldVM == nil {
+		return append(allErrs, field.Forbidden(revertToSnapshotPath, revertToSnapshotOnCreate))
+	}
+
+	if snapshotName == oldVM.Spec.RevertToSnapshot {
+		return allErrs
+	}
+
+	vmSnapshot := &vmopv1.VirtualMachineSnapshot{}
+	if err := v.client.Get(ctx, client.ObjectKey{Name: snapshotName, Namespace: vm.Namespace}, vmSnapshot); err != nil {
+		if apierrors.IsNotFound(err) {
+			return append(allErrs, field.NotFound(revertToSnapshotPath, snapshotName))
+		}
+		return append(allErrs, field.InternalError(revertToSnapshotPath, err))
+	}
+
+	if vmSnapshot.Spec.VirtualMachineName != vm.Name {
+		allErrs = append(allErrs, field.Invalid(revertToSnapshotPath, snapshotName, revertToSnapshotOfOtherVM))
+	}
+
+	return allErrs
+}
+
+// validateBootDiskCapacity validates the capacity of the boot disk. The boot disk can only be grown, and not when
+// it is or has delta disks.
+func (v validator) validateBootDiskCapacity(ctx *context.WebhookRequestContext, vm, oldVM *vmopv1.VirtualMachine) field.ErrorList {
+	var allErrs field.ErrorList
+
+	capacity := vm.Spec.BootDiskCapacity
+	if capacity == nil {
+		return allErrs
+	}
+
+	capacityPath := field.NewPath("spec", "bootDiskCapacity")
+
+	// The boot disk of a VM that boots from its image volume is resized by expanding the PVC.
+	if imagevolume.IsConfigured(vm) {
+		return append(allErrs, field.Forbidden(capacityPath, bootDiskCapacityWithImageVolume))
+	}
+
+	if capacity.Sign() <= 0 {
+		return append(allErrs, field.Invalid(capacityPath, capacity.String(), bootDiskCapacityNotPositive))
+	}
+
+	if capacity.Value()%1024 != 0 {
+		return append(allErrs, field.Invalid(capacityPath, capacity.String(), bootDiskCapacityNotKiBMultiple))
+	}
+
+	if oldVM != nil && oldVM.Spec.BootDiskCapacity != nil {
+		if capacity.Cmp(*oldVM.Spec.BootDiskCapacity) < 0 {
+			allErrs = append(allErrs, field.Invalid(capacityPath, capacity.String(),
+				fmt.Sprintf(bootDiskCapacityDecreaseFmt, oldVM.Spec.BootDiskCapacity.String())))
+		}
+		if capacity.Cmp(*oldVM.Spec.BootDiskCapacity) == 0 {
+			// Do not fail unrelated updates when the image was synced with a larger boot disk since.
+			return allErrs
+		}
+	}
+
+	// A disk cannot be extended when it is a delta disk or has delta disks on top of it.
+	if source := vm.Spec.Source; source != nil && (source.SnapshotName != "" || source.Instant) {
+		return append(allErrs, field.Forbidden(capacityPath, bootDiskCapacityWithLinkedClone))
+	}
+	if oldVM != nil {
+		hasSnapshots, err := v.hasSnapshots(ctx, vm)
+		if err != nil {
+			return append(allErrs, field.InternalError(capacityPath, err))
+		}
+		if hasSnapshots {
+			return append(allErrs, field.Forbidden(capacityPath, bootDiskCapacityWithSnapshots))
+		}
+	}
+
+	// The boot disk of the image cannot be shrunk when the VM is deployed from it.
+	if imageCapacity := v.imageBootDiskCapacity(ctx, vm); imageCapacity != nil && capacity.Cmp(*imageCapacity) < 0 {
+		allErrs = append(allErrs, field.Invalid(capacityPath, capacity.String(),
+			fmt.Sprintf(bootDiskCapacityBelowImageFmt, imageCapacity.String())))
+	}
+
+	return allErrs
+}
+
+// hasSnapshots returns true if there is a VirtualMachineSnapshot of the VM.
+func (v validator) hasSnapshots(ctx *context.WebhookRequestContext, vm *vmopv1.VirtualMachine) (bool, error) {
+	vmSnapshots := &vmopv1.VirtualMachineSnapshotList{}
+	if err := v.client.List(ctx, vmSnapshots, client.InNamespace(vm.Namespace)); err != nil {
+		return false, err
+	}
+
+	for _, vmSnapshot := range vmSnapshots.Items {
+		if vmSnapshot.Spec.VirtualMachineName == vm.Name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// imageBootDiskCapacity returns the boot disk capacity of the VM's image, or nil when it is not known. An image
+// that cannot be found is reported by the other validations of the image.
+func (v validator) imageBootDiskCapacity(ctx *context.WebhookRequestContext, vm *vmopv1.VirtualMachine) *resource.Quantity {
+	if vm.Spec.Source != nil || vm.Spec.ImageName == "" {
+		return nil
+	}
+
+	if lib.IsWCPVMImageRegistryEnabled() {
+		_, imageStatus, err := clutils.GetVMImageSpecStatus(ctx, v.client, vm.Spec.ImageName, vm.Namespace)
+		if err != nil {
+			return nil
+		}
+		return imageStatus.BootDiskCapacity
+	}
+
+	image := vmopv1.VirtualMachineImage{}
+	if err := v.client.Get(ctx, client.ObjectKey{Name: vm.Spec.ImageName}, &image); err != nil {
+		return nil
+	}
+	return image.Status.BootDiskCapacity
+}
+
+// validateCdrom validates that the CD-ROMs have unique names and images, and that each image is an ISO.
+func (v validator) validateCdrom(ctx *context.WebhookRequestContext, vm *vmopv1.VirtualMachine) field.ErrorList {
+	var allErrs field.ErrorList
+
+	cdromPath := field.NewPath("spec", "cdrom")
+	cdromNames := map[string]bool{}
+	imageNames := map[string]bool{}
+
+	for i, cdrom := range vm.Spec.Cdrom {
+		curCdromPath := cdromPath.Index(i)
 
-	if probe.TCPSocket == nil && probe.GuestHeartbeat == nil {
-		allErrs = append(allErrs, field.Forbidden(readinessProbePath, readinessProbeNoActions))
-	} else if probe.TCPSocket != nil && probe.GuestHeartbeat != nil {
-		allErrs = append(allErrs, field.Forbidden(readinessProbePath, readinessProbeOnlyOneAction))
+		if cdrom.Name == "" {
+			allErrs = append(allErrs, field.Required(curCdromPath.Child("name"), ""))
+		} else if cdromNames[cdrom.Name] {
+			allErrs = append(allErrs, field.Duplicate(curCdromPath.Child("name"), cdrom.Name))
+		}
+		cdromNames[cdrom.Name] = true
+
+		imageNamePath := curCdromPath.Child("imageName")
+		if cdrom.ImageName == "" {
+			allErrs = append(allErrs, field.Required(imageNamePath, ""))
+			continue
+		}
+		if imageNames[cdrom.ImageName] {
+			allErrs = append(allErrs, field.Duplicate(imageNamePath, cdrom.ImageName))
+			continue
+		}
+		imageNames[cdrom.ImageName] = true
+
+		var imageSpec *vmopv1.VirtualMachineImageSpec
+		if lib.IsWCPVMImageRegistryEnabled() {
+			spec, _, err := clutils.GetVMImageSpecStatus(ctx, v.client, cdrom.ImageName, vm.Namespace)
+			if err != nil {
+				allErrs = append(allErrs, field.Invalid(imageNamePath, cdrom.ImageName,
+					fmt.Sprintf("error validating CD-ROM image: %s", err.Error())))
+				continue
+			}
+			imageSpec = spec
+		} else {
+			image := vmopv1.VirtualMachineImage{}
+			if err := v.client.Get(ctx, client.ObjectKey{Name: cdrom.ImageName}, &image); err != nil {
+				allErrs = append(allErrs, field.Invalid(imageNamePath, cdrom.ImageName,
+					fmt.Sprintf("error validating CD-ROM image: %s", err.Error())))
+				continue
+			}
+			imageSpec = &image.Spec
+		}
+
+		if !clutils.IsISOImage(imageSpec) {
+			allErrs = append(allErrs, field.Invalid(imageNamePath, cdrom.ImageName, cdromImageNotISO))
+		}
+	}
+
+	return allErrs
+}
+
+// validateBootOptions validates the boot order, and that EFI secure boot and a virtual TPM are only requested for
+// a VM with EFI firmware. A virtual TPM cannot be removed once added since the VM is encrypted with its key provider.
+func (v validator) validateBootOptions(ctx *context.WebhookRequestContext, vm, oldVM *vmopv1.VirtualMachine) field.ErrorList {
+	var allErrs field.ErrorList
+
+	bootOptionsPath := field.NewPath("spec", "bootOptions")
+	vTPMPath := field.NewPath("spec", "virtualTPM")
+	bootOptions := vm.Spec.BootOptions
+
+	if bootOptions != nil {
+		bootDevices := map[vmopv1.VirtualMachineBootDevice]bool{}
+		for i, bootDevice := range bootOptions.BootOrder {
+			if bootDevices[bootDevice] {
+				allErrs = append(allErrs, field.Duplicate(bootOptionsPath.Child("bootOrder").Index(i), bootDevice))
+			}
+			bootDevices[bootDevice] = true
+		}
+	}
+
+	if vm.Spec.VirtualTPM != nil && vm.Spec.VirtualTPM.KeyProviderID == "" {
+		allErrs = append(allErrs, field.Required(vTPMPath.Child("keyProviderID"), ""))
+	}
+
+	if oldVM != nil && oldVM.Spec.VirtualTPM != nil {
+		if vm.Spec.VirtualTPM == nil {
+			allErrs = append(allErrs, field.Forbidden(vTPMPath, virtualTPMRemovalNotAllowed))
+		} else {
+			allErrs = append(allErrs, validation.ValidateImmutableField(vm.Spec.VirtualTPM.KeyProviderID,
+				oldVM.Spec.VirtualTPM.KeyProviderID, vTPMPath.Child("keyProviderID"))...)
+		}
+	}
+
+	secureBoot := bootOptions != nil && bootOptions.EFISecureBootEnabled
+	if !secureBoot && vm.Spec.VirtualTPM == nil {
+		return allErrs
+	}
+
+	firmware, fieldErr := v.vmFirmware(ctx, vm)
+	if fieldErr != nil {
+		return append(allErrs, fieldErr)
+	}
+
+	// The firmware of a VM cloned from another VM is not known here.
+	if firmware == "" || firmware == "efi" {
+		return allErrs
+	}
+
+	if secureBoot {
+		allErrs = append(allErrs, field.Invalid(bootOptionsPath.Child("efiSecureBootEnabled"), true,
+			fmt.Sprintf(efiFirmwareRequiredFmt, firmware)))
+	}
+	if vm.Spec.VirtualTPM != nil {
+		allErrs = append(allErrs, field.Invalid(vTPMPath, vm.Spec.VirtualTPM,
+			fmt.Sprintf(efiFirmwareRequiredFmt, firmware)))
+	}
+
+	return allErrs
+}
+
+// vmFirmware returns the firmware the VM is deployed with: the boot options firmware, the firmware override
+// annotation, or the firmware of the VM's image, in that order.
+func (v validator) vmFirmware(ctx *context.WebhookRequestContext, vm *vmopv1.VirtualMachine) (string, *field.Error) {
+	if vm.Spec.BootOptions != nil && vm.Spec.BootOptions.Firmware != "" {
+		return vm.Spec.BootOptions.Firmware, nil
+	}
+
+	if val := vm.Annotations[constants.FirmwareOverrideAnnotation]; val == "efi" || val == "bios" {
+		return val, nil
+	}
+
+	if vm.Spec.Source != nil || vm.Spec.ImageName == "" {
+		return "", nil
+	}
+
+	imageNamePath := field.NewPath("spec", "imageName")
+	imageName := vm.Spec.ImageName
+
+	if lib.IsWCPVMImageRegistryEnabled() {
+		_, imageStatus, err := clutils.GetVMImageSpecStatus(ctx, v.client, imageName, vm.Namespace)
+		if err != nil {
+			return "", field.Invalid(imageNamePath, imageName,
+				fmt.Sprintf("error validating image firmware: %s", err.Error()))
+		}
+
+		return imageStatus.Firmware, nil
+	}
+
+	image := vmopv1.VirtualMachineImage{}
+	if err := v.client.Get(ctx, client.ObjectKey{Name: imageName}, &image); err != nil {
+		return "", field.Invalid(imageNamePath, imageName,
+			fmt.Sprintf("error validating image firmware: %s", err.Error()))
+	}
+
+	return image.Status.Firmware, nil
+}
+
+// validateProbe validates the action of a probe.
+func (v validator) validateProbe(ctx *context.WebhookRequestContext, probe *vmopv1.Probe, probePath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	actions := 0
+	for _, set := range []bool{probe.TCPSocket != nil, probe.GuestHeartbeat != nil, probe.HTTPGet != nil, probe.GuestExec != nil} {
+		if set {
+			actions++
+		}
+	}
+	if actions == 0 {
+		allErrs = append(allErrs, field.Forbidden(probePath, probeNoActions))
+	} else if actions > 1 {
+		allErrs = append(allErrs, field.Forbidden(probePath, probeOnlyOneAction))
 	}
 
 	// Validate the TCP probe if set and environment is a restricted network environment between CP VMs and Workload VMs e.g. VMC
 	if probe.TCPSocket != nil {
-		tcpSocketPath := readinessProbePath.Child("tcpSocket")
-		isRestrictedEnv, err := v.isNetworkRestrictedForReadinessProbe(ctx)
-		if err != nil {
-			allErrs = append(allErrs, field.Forbidden(tcpSocketPath, err.Error()))
-		} else if isRestrictedEnv && probe.TCPSocket.Port.IntValue() != allowedRestrictedNetworkTCPProbePort {
-			allErrs = append(allErrs, field.NotSupported(tcpSocketPath.Child("port"), probe.TCPSocket.Port.IntValue(),
-				[]string{strconv.Itoa(allowedRestrictedNetworkTCPProbePort)}))
+		tcpSocketPath := probePath.Child("tcpSocket")
+		allErrs = append(allErrs, v.validateRestrictedNetworkProbePort(ctx, probe.TCPSocket.Port, tcpSocketPath)...)
+	}
+
+	if probe.HTTPGet != nil {
+		httpGetPath := probePath.Child("httpGet")
+		allErrs = append(allErrs, validateHTTPGetAction(probe.HTTPGet, httpGetPath)...)
+		allErrs = append(allErrs, v.validateRestrictedNetworkProbePort(ctx, probe.HTTPGet.Port, httpGetPath)...)
+	}
+
+	if probe.GuestExec != nil {
+		guestExecPath := probePath.Child("guestExec")
+		if len(probe.GuestExec.Command) == 0 || probe.GuestExec.Command[0] == "" {
+			allErrs = append(allErrs, field.Required(guestExecPath.Child("command"), ""))
+		}
+		if probe.GuestExec.CredentialsSecretName == "" {
+			allErrs = append(allErrs, field.Required(guestExecPath.Child("credentialsSecretName"), ""))
 		}
 	}
 
 	return allErrs
 }
 
+// validateRestrictedNetworkProbePort validates the port of a network probe when the environment is a
+// restricted network environment between CP VMs and Workload VMs.
+func (v validator) validateRestrictedNetworkProbePort(ctx *context.WebhookRequestContext, port intstr.IntOrString, fieldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	isRestrictedEnv, err := v.isNetworkRestrictedForReadinessProbe(ctx)
+	if err != nil {
+		allErrs = append(allErrs, field.Forbidden(fieldPath, err.Error()))
+	} else if isRestrictedEnv && port.IntValue() != allowedRestrictedNetworkTCPProbePort {
+		allErrs = append(allErrs, field.NotSupported(fieldPath.Child("port"), port.IntValue(),
+			[]string{strconv.Itoa(allowedRestrictedNetworkTCPProbePort)}))
+	}
+
+	return allErrs
+}
+
+func validateHTTPGetAction(action *vmopv1.HTTPGetAction, fieldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if action.Path != "" && !strings.HasPrefix(action.Path, "/") {
+		allErrs = append(allErrs, field.Invalid(fieldPath.Child("path"), action.Path, httpGetPathNotAbsolute))
+	}
+
+	portPath := fieldPath.Child("port")
+	switch action.Port.Type {
+	case intstr.Int:
+		for _, msg := range utilvalidation.IsValidPortNum(action.Port.IntValue()) {
+			allErrs = append(allErrs, field.Invalid(portPath, action.Port.IntValue(), msg))
+		}
+	case intstr.String:
+		for _, msg := range utilvalidation.IsValidPortName(action.Port.StrVal) {
+			allErrs = append(allErrs, field.Invalid(portPath, action.Port.StrVal, msg))
+		}
+	}
+
+	for i, header := range action.HTTPHeaders {
+		for _, msg := range utilvalidation.IsHTTPHeaderName(header.Name) {
+			allErrs = append(allErrs, field.Invalid(fieldPath.Child("httpHeaders").Index(i).Child("name"), header.Name, msg))
+		}
+	}
+
+	if status := action.ExpectedStatus; status != nil && status.Min > status.Max {
+		allErrs = append(allErrs, field.Invalid(fieldPath.Child("expectedStatus"), fmt.Sprintf("%d-%d", status.Min, status.Max),
+			httpGetExpectedStatusInvalidRange))
+	}
+
+	return allErrs
+}
+
 func (v validator) validateUpdatesWhenPoweredOn(ctx *context.WebhookRequestContext, vm, oldVM *vmopv1.VirtualMachine) field.ErrorList {
 	var allErrs field.ErrorList
 
@@ -506,6 +1325,27 @@ func (v validator) validateUpdatesWhenPoweredOn(ctx *context.WebhookRequestConte
 	if !equality.Semantic.DeepEqual(vm.Spec.NetworkInterfaces, oldVM.Spec.NetworkInterfaces) {
 		allErrs = append(allErrs, field.Forbidden(specPath.Child("networkInterfaces"), updatesNotAllowedWhenPowerOn))
 	}
+	if !equality.Semantic.DeepEqual(vm.Spec.NetworkBonds, oldVM.Spec.NetworkBonds) {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("networkBonds"), updatesNotAllowedWhenPowerOn))
+	}
+	if !equality.Semantic.DeepEqual(vm.Spec.BootOptions, oldVM.Spec.BootOptions) {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("bootOptions"), updatesNotAllowedWhenPowerOn))
+	}
+	if !equality.Semantic.DeepEqual(vm.Spec.VirtualTPM, oldVM.Spec.VirtualTPM) {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("virtualTPM"), updatesNotAllowedWhenPowerOn))
+	}
+
+	// The CD-ROMs are matched to the VM's devices in order so only their image and connection can be changed.
+	cdromNames := func(cdroms []vmopv1.VirtualMachineCdrom) []string {
+		names := make([]string, 0, len(cdroms))
+		for _, cdrom := range cdroms {
+			names = append(names, cdrom.Name)
+		}
+		return names
+	}
+	if !reflect.DeepEqual(cdromNames(vm.Spec.Cdrom), cdromNames(oldVM.Spec.Cdrom)) {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("cdrom"), cdromAddRemoveNotAllowedWhenPowerOn))
+	}
 
 	if vm.Spec.AdvancedOptions != nil {
 		allErrs = append(allErrs, v.validateAdvancedOptionsUpdateWhenPoweredOn(ctx, vm, oldVM)...)
@@ -575,8 +1415,7 @@ func (v validator) validateImmutableFields(ctx *context.WebhookRequestContext, v
 	specPath := field.NewPath("spec")
 
 	allErrs = append(allErrs, validation.ValidateImmutableField(vm.Spec.ImageName, oldVM.Spec.ImageName, specPath.Child("imageName"))...)
-	allErrs = append(allErrs, validation.ValidateImmutableField(vm.Spec.ClassName, oldVM.Spec.ClassName, specPath.Child("className"))...)
-	allErrs = append(allErrs, validation.ValidateImmutableField(vm.Spec.StorageClass, oldVM.Spec.StorageClass, specPath.Child("storageClass"))...)
+	allErrs = append(allErrs, validation.ValidateImmutableField(vm.Spec.Source, oldVM.Spec.Source, specPath.Child("source"))...)
 	allErrs = append(allErrs, validation.ValidateImmutableField(vm.Spec.ResourcePolicyName, oldVM.Spec.ResourcePolicyName, specPath.Child("resourcePolicyName"))...)
 
 	return allErrs
@@ -591,19 +1430,48 @@ func (v validator) validateAvailabilityZone(ctx *context.WebhookRequestContext,
 
 	zoneLabelPath := field.NewPath("metadata", "labels").Key(topology.KubernetesTopologyZoneLabelKey)
 
+	migrating := false
 	if oldVM != nil {
-		// Once the zone has been set then make sure the field is immutable.
+		// Once the zone has been set then it can only be changed to another zone, which migrates the VM.
 		if oldVal := oldVM.Labels[topology.KubernetesTopologyZoneLabelKey]; oldVal != "" {
 			newVal := vm.Labels[topology.KubernetesTopologyZoneLabelKey]
-			return append(allErrs, validation.ValidateImmutableField(newVal, oldVal, zoneLabelPath)...)
+			if newVal == oldVal {
+				return allErrs
+			}
+
+			switch {
+			case newVal == "":
+				return append(allErrs, field.Forbidden(zoneLabelPath, zoneRemovalNotAllowed))
+			case oldVM.Annotations[constants.MigrateTaskAnnotation] != "":
+				return append(allErrs, field.Forbidden(zoneLabelPath, zoneChangeWhileMigrating))
+			case oldVM.Annotations[constants.StorageRelocateTaskAnnotation] != "":
+				return append(allErrs, field.Forbidden(zoneLabelPath, zoneChangeWhileRelocating))
+			case instancestorage.IsConfigured(vm):
+				return append(allErrs, field.Forbidden(zoneLabelPath, zoneChangeWithInstanceStorage))
+			case hasPVCVolumes(vm):
+				// The CNS volumes are not relocated with the VM.
+				return append(allErrs, field.Forbidden(zoneLabelPath, zoneChangeWithVolumes))
+			case vm.Spec.StorageClass == "":
+				// The StorageClass determines the datastore the VM is relocated to.
+				return append(allErrs, field.Forbidden(zoneLabelPath, zoneChangeWithoutStorageClass))
+			}
+			migrating = true
 		}
 	}
 
 	// Validate the name of the provided availability zone.
 	if zone := vm.Labels[topology.KubernetesTopologyZoneLabelKey]; zone != "" {
-		if _, err := topology.GetAvailabilityZone(ctx.Context, v.client, zone); err != nil {
+		az, err := topology.GetAvailabilityZone(ctx.Context, v.client, zone)
+		if err != nil {
 			return append(allErrs, field.Invalid(zoneLabelPath, zone, err.Error()))
 		}
+
+		// The VM is migrated to a resource pool of its namespace in the target zone.
+		if migrating {
+			if nsInfo, ok := az.Spec.Namespaces[vm.Namespace]; !ok || (nsInfo.PoolMoId == "" && len(nsInfo.PoolMoIDs) == 0) {
+				return append(allErrs, field.Invalid(zoneLabelPath, zone, fmt.Sprintf(zoneNamespaceNotFoundFmt, vm.Namespace)))
+			}
+		}
 	}
 
 	return allErrs
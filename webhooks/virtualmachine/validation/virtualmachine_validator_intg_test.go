@@ -1,4 +1,4 @@
-// Copyright (c) 2019-2022 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2019-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package validation_test
@@ -151,11 +151,11 @@ func intgTestsValidateUpdate() {
 		BeforeEach(func() {
 			ctx.vm.Spec.StorageClass += "-2"
 		})
-		It("should deny the request", func() {
+		It("should deny the request when the storage class is not associated with the namespace", func() {
 			Expect(err).To(HaveOccurred())
 			expectedPath := field.NewPath("spec", "storageClass")
 			Expect(err.Error()).To(ContainSubstring(expectedPath.String()))
-			Expect(err.Error()).To(ContainSubstring(immutableFieldMsg))
+			Expect(err.Error()).To(ContainSubstring("Storage policy is not associated with the namespace"))
 		})
 	})
 
@@ -208,6 +208,21 @@ func intgTestsValidateUpdate() {
 			})
 		})
 
+		When("BootOptions are updated", func() {
+			BeforeEach(func() {
+				ctx.vm.Spec.BootOptions = &vmopv1.VirtualMachineBootOptions{
+					BootOrder: []vmopv1.VirtualMachineBootDevice{vmopv1.VirtualMachineBootDeviceNetwork},
+				}
+			})
+
+			It("rejects the request", func() {
+				bootOptionsPath := field.NewPath("spec", "bootOptions")
+				expectedReason := field.Forbidden(bootOptionsPath, "updates to this field is not allowed when VM power is on").Error()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(expectedReason))
+			})
+		})
+
 		When("Volumes are updated", func() {
 			When("a vSphere volume is added", func() {
 				BeforeEach(func() {
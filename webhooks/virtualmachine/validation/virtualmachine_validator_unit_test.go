@@ -1,10 +1,12 @@
-// Copyright (c) 2019-2022 VMware, Inc. All Rights Reserved.
+// Copyright (c) 2019-2023 VMware, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package validation_test
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"k8s.io/apimachinery/pkg/api/resource"
 
@@ -22,6 +24,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	vmopv1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
+	topologyv1 "github.com/vmware-tanzu/vm-operator/external/tanzu-topology/api/v1alpha1"
 
 	"github.com/vmware-tanzu/vm-operator/pkg/lib"
 	"github.com/vmware-tanzu/vm-operator/pkg/topology"
@@ -32,9 +35,13 @@ import (
 )
 
 const (
+	sourceVMName            = "dummy-source-vm"
+	sourceSnapshotName      = "dummy-source-snapshot"
+	revertSnapshotName      = "dummy-revert-snapshot"
 	updateSuffix            = "-updated"
 	dummyNamespaceImageName = "dummy-namespace-image"
 	dummyClusterImageName   = "dummy-cluster-image"
+	dummyISOImageName       = "dummy-iso-image"
 )
 
 func unitTests() {
@@ -72,8 +79,10 @@ func newUnitTestContextForValidatingWebhook(isUpdate bool) *unitValidatingWebhoo
 	nsVMImage := builder.DummyVirtualMachineImage(dummyNamespaceImageName)
 	nsVMImage.Namespace = vm.Namespace
 	clusterVMImage := builder.DummyClusterVirtualMachineImage(dummyClusterImageName)
+	isoVMImage := builder.DummyVirtualMachineImage(dummyISOImageName)
+	isoVMImage.Spec.Type = "iso"
 
-	initObjects := []client.Object{vmImage, vmImage1, zone, nsVMImage, clusterVMImage}
+	initObjects := []client.Object{vmImage, vmImage1, zone, nsVMImage, clusterVMImage, isoVMImage}
 
 	return &unitValidatingWebhookContext{
 		UnitTestContextForValidatingWebhook: *suite.NewUnitTestContextForValidatingWebhook(obj, oldObj, initObjects...),
@@ -130,6 +139,10 @@ func unitTestsValidateCreate() {
 		invalidNetworkType                bool
 		invalidNetworkCardType            bool
 		multipleNetIfToSameNetwork        bool
+		invalidNetworkRoute               bool
+		validNetworkBond                  bool
+		networkBondNotFound               bool
+		networkBondTooFewMembers          bool
 		emptyVolumeName                   bool
 		invalidVolumeName                 bool
 		dupVolumeName                     bool
@@ -147,6 +160,12 @@ func unitTestsValidateCreate() {
 		validStorageClass                 bool
 		invalidReadinessNoProbe           bool
 		invalidReadinessProbe             bool
+		invalidLivenessNoProbe            bool
+		validLivenessProbe                bool
+		validReadinessHTTPGetProbe        bool
+		invalidReadinessHTTPGetProbe      bool
+		validReadinessGuestExecProbe      bool
+		invalidReadinessGuestExecProbe    bool
 		isRestrictedNetworkEnv            bool
 		isRestrictedNetworkValidProbePort bool
 		isNonRestrictedNetworkEnv         bool
@@ -156,12 +175,148 @@ func unitTestsValidateCreate() {
 		isEmptyAvailabilityZone           bool
 		isServiceUser                     bool
 		addInstanceStorageVolumes         bool
+		withStorageRelocateTask           bool
+		withMigrateTask                   bool
+		withBootDiskURLPath               bool
 		isWCPVMImageRegistryEnabled       bool
+		sourceVM                          bool
+		sourceVMNotFound                  bool
+		sourceVMNotCreated                bool
+		sourceWithImageName               bool
+		sourceSnapshot                    bool
+		sourceSnapshotNotReady            bool
+		sourceNotAuthorized               bool
+		instantClone                      bool
+		instantCloneSourceWithVolumes     bool
+		instantCloneSourcePoweredOff      bool
+		instantCloneWithSnapshot          bool
+		instantCloneWithCloudInit         bool
+		withNextRestartTime               bool
+		withRevertToSnapshot              bool
+		biosImage                         bool
+		secureBoot                        bool
+		efiFirmwareOverride               bool
+		virtualTPM                        bool
+		virtualTPMWithoutKeyProvider      bool
+		dupBootOrder                      bool
+		bootDiskCapacity                  string
+		imageBootDiskCapacity             string
+		imageVolume                       bool
+		imageVolumeOtherImage             bool
+		imageVolumeWithInstanceVolume     bool
+		multipleImageVolumes              bool
+		isoCdrom                          bool
+		isoImageName                      bool
+		cdromNotISO                       bool
+		duplicateCdromName                bool
 	}
 
 	validateCreate := func(args createArgs, expectedAllowed bool, expectedReason string, expectedErr error) {
 		var err error
 
+		if args.sourceVM || args.sourceVMNotFound || args.sourceVMNotCreated || args.sourceWithImageName ||
+			args.sourceSnapshot || args.sourceSnapshotNotReady || args.sourceNotAuthorized ||
+			args.instantClone || args.instantCloneSourcePoweredOff || args.instantCloneWithSnapshot || args.instantCloneWithCloudInit ||
+			args.instantCloneSourceWithVolumes {
+			srcVM := builder.DummyVirtualMachine()
+			srcVM.Name = sourceVMName
+			srcVM.Namespace = ctx.vm.Namespace
+			srcVM.Status.UniqueID = "vm-42"
+			srcVM.Status.PowerState = vmopv1.VirtualMachinePoweredOn
+			if args.instantClone {
+				srcVM.Spec.Volumes = nil
+			}
+			if args.instantCloneSourcePoweredOff {
+				srcVM.Status.PowerState = vmopv1.VirtualMachinePoweredOff
+			}
+			if args.sourceVMNotCreated {
+				srcVM.Status.UniqueID = ""
+			}
+			if !args.sourceVMNotFound {
+				Expect(ctx.Client.Create(ctx, srcVM)).To(Succeed())
+				Expect(ctx.Client.Status().Update(ctx, srcVM)).To(Succeed())
+			}
+
+			ctx.vm.Spec.Source = &vmopv1.VirtualMachineSource{VirtualMachineName: sourceVMName}
+			if !args.sourceWithImageName {
+				ctx.vm.Spec.ImageName = ""
+			}
+			// The fake client cannot evaluate the SubjectAccessReview of a non-privileged user.
+			ctx.IsPrivilegedAccount = !args.sourceNotAuthorized
+		}
+		if args.sourceSnapshot || args.sourceSnapshotNotReady {
+			vmSnapshot := &vmopv1.VirtualMachineSnapshot{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      sourceSnapshotName,
+					Namespace: ctx.vm.Namespace,
+				},
+				Spec: vmopv1.VirtualMachineSnapshotSpec{
+					VirtualMachineName: sourceVMName,
+				},
+			}
+			Expect(ctx.Client.Create(ctx, vmSnapshot)).To(Succeed())
+			vmSnapshot.Status.SnapshotID = "snapshot-1"
+			vmSnapshot.Status.Ready = !args.sourceSnapshotNotReady
+			Expect(ctx.Client.Status().Update(ctx, vmSnapshot)).To(Succeed())
+
+			ctx.vm.Spec.Source.SnapshotName = sourceSnapshotName
+		}
+		if args.instantClone || args.instantCloneSourcePoweredOff || args.instantCloneWithSnapshot || args.instantCloneWithCloudInit ||
+			args.instantCloneSourceWithVolumes {
+			ctx.vm.Spec.Source.Instant = true
+			ctx.vm.Spec.VmMetadata.Transport = vmopv1.VirtualMachineMetadataExtraConfigTransport
+		}
+		if args.instantCloneWithSnapshot {
+			ctx.vm.Spec.Source.SnapshotName = sourceSnapshotName
+		}
+		if args.instantCloneWithCloudInit {
+			ctx.vm.Spec.VmMetadata.Transport = vmopv1.VirtualMachineMetadataCloudInitTransport
+		}
+		if args.withNextRestartTime {
+			ctx.vm.Spec.NextRestartTime = time.Now().UTC().Format(time.RFC3339)
+		}
+		if args.withRevertToSnapshot {
+			ctx.vm.Spec.RevertToSnapshot = revertSnapshotName
+		}
+		if args.biosImage {
+			ctx.vmImage.Status.Firmware = "bios"
+			Expect(ctx.Client.Status().Update(ctx, ctx.vmImage)).To(Succeed())
+			ctx.nsVMImage.Status.Firmware = "bios"
+			Expect(ctx.Client.Status().Update(ctx, ctx.nsVMImage)).To(Succeed())
+		}
+		if args.secureBoot || args.efiFirmwareOverride || args.dupBootOrder {
+			ctx.vm.Spec.BootOptions = &vmopv1.VirtualMachineBootOptions{
+				EFISecureBootEnabled: args.secureBoot,
+			}
+		}
+		if args.efiFirmwareOverride {
+			ctx.vm.Spec.BootOptions.Firmware = "efi"
+		}
+		if args.dupBootOrder {
+			ctx.vm.Spec.BootOptions.BootOrder = []vmopv1.VirtualMachineBootDevice{
+				vmopv1.VirtualMachineBootDeviceDisk,
+				vmopv1.VirtualMachineBootDeviceNetwork,
+				vmopv1.VirtualMachineBootDeviceDisk,
+			}
+		}
+		if args.bootDiskCapacity != "" {
+			capacity := resource.MustParse(args.bootDiskCapacity)
+			ctx.vm.Spec.BootDiskCapacity = &capacity
+		}
+		if args.imageBootDiskCapacity != "" {
+			imageCapacity := resource.MustParse(args.imageBootDiskCapacity)
+			ctx.vmImage.Status.BootDiskCapacity = &imageCapacity
+			Expect(ctx.Client.Status().Update(ctx, ctx.vmImage)).To(Succeed())
+			ctx.nsVMImage.Status.BootDiskCapacity = &imageCapacity
+			Expect(ctx.Client.Status().Update(ctx, ctx.nsVMImage)).To(Succeed())
+		}
+		if args.virtualTPM {
+			ctx.vm.Spec.VirtualTPM = &vmopv1.VirtualMachineVirtualTPM{KeyProviderID: "key-provider"}
+		}
+		if args.virtualTPMWithoutKeyProvider {
+			ctx.vm.Spec.VirtualTPM = &vmopv1.VirtualMachineVirtualTPM{}
+		}
+
 		if args.invalidClassName {
 			ctx.vm.Spec.ClassName = ""
 		}
@@ -189,6 +344,22 @@ func unitTestsValidateCreate() {
 			ctx.vm.Spec.NetworkInterfaces[0].NetworkName = bogusNetworkName
 			ctx.vm.Spec.NetworkInterfaces[1].NetworkName = bogusNetworkName
 		}
+		if args.invalidNetworkRoute {
+			ctx.vm.Spec.NetworkInterfaces[0].Routes = []vmopv1.VirtualMachineNetworkRoute{
+				{To: "10.0.0.0", Via: "not-an-ip"},
+			}
+		}
+		if args.validNetworkBond || args.networkBondNotFound || args.networkBondTooFewMembers {
+			ctx.vm.Spec.NetworkBonds = []vmopv1.VirtualMachineNetworkBond{{Name: "bond0"}}
+			ctx.vm.Spec.NetworkInterfaces[0].BondName = "bond0"
+			ctx.vm.Spec.NetworkInterfaces[1].BondName = "bond0"
+		}
+		if args.networkBondNotFound {
+			ctx.vm.Spec.NetworkInterfaces[1].BondName = "bond1"
+		}
+		if args.networkBondTooFewMembers {
+			ctx.vm.Spec.NetworkInterfaces[1].BondName = ""
+		}
 		if args.emptyVolumeName {
 			ctx.vm.Spec.Volumes[0].Name = ""
 		}
@@ -213,6 +384,38 @@ func unitTestsValidateCreate() {
 		if args.invalidPVCReadOnly {
 			ctx.vm.Spec.Volumes[0].PersistentVolumeClaim.ReadOnly = true
 		}
+		if args.imageVolume || args.imageVolumeOtherImage || args.imageVolumeWithInstanceVolume || args.multipleImageVolumes {
+			ctx.vm.Spec.Volumes[0].PersistentVolumeClaim.ImageVolumeClaim = &vmopv1.ImageVolumeClaimVolumeSource{
+				ImageName: ctx.vm.Spec.ImageName,
+			}
+		}
+		if args.imageVolumeOtherImage {
+			ctx.vm.Spec.Volumes[0].PersistentVolumeClaim.ImageVolumeClaim.ImageName += updateSuffix
+		}
+		if args.imageVolumeWithInstanceVolume {
+			ctx.vm.Spec.Volumes[0].PersistentVolumeClaim.InstanceVolumeClaim = &vmopv1.InstanceVolumeClaimVolumeSource{
+				StorageClass: builder.DummyStorageClassName,
+				Size:         resource.MustParse("256Gi"),
+			}
+		}
+		if args.multipleImageVolumes {
+			imageVolume := *ctx.vm.Spec.Volumes[0].DeepCopy()
+			imageVolume.Name += updateSuffix
+			imageVolume.PersistentVolumeClaim.ClaimName += updateSuffix
+			ctx.vm.Spec.Volumes = append(ctx.vm.Spec.Volumes, imageVolume)
+		}
+		if args.isoImageName {
+			ctx.vm.Spec.ImageName = dummyISOImageName
+		}
+		if args.isoCdrom || args.duplicateCdromName {
+			ctx.vm.Spec.Cdrom = []vmopv1.VirtualMachineCdrom{{Name: "cdrom1", ImageName: dummyISOImageName}}
+		}
+		if args.cdromNotISO {
+			ctx.vm.Spec.Cdrom = []vmopv1.VirtualMachineCdrom{{Name: "cdrom1", ImageName: ctx.vm.Spec.ImageName}}
+		}
+		if args.duplicateCdromName {
+			ctx.vm.Spec.Cdrom = append(ctx.vm.Spec.Cdrom, vmopv1.VirtualMachineCdrom{Name: "cdrom1", ImageName: ctx.vm.Spec.ImageName})
+		}
 		if args.invalidPVCHwVersion {
 			ctx.vmImage.Spec.HardwareVersion = 12
 			Expect(ctx.Client.Update(ctx, ctx.vmImage)).ToNot(HaveOccurred())
@@ -276,6 +479,59 @@ func unitTestsValidateCreate() {
 				GuestHeartbeat: &vmopv1.GuestHeartbeatAction{},
 			}
 		}
+		if args.invalidLivenessNoProbe {
+			ctx.vm.Spec.LivenessProbe = &vmopv1.LivenessProbe{}
+		}
+		if args.validLivenessProbe {
+			ctx.vm.Spec.LivenessProbe = &vmopv1.LivenessProbe{
+				Probe: vmopv1.Probe{
+					GuestHeartbeat:   &vmopv1.GuestHeartbeatAction{},
+					FailureThreshold: 5,
+				},
+				Action: vmopv1.LivenessProbeActionPowerCycle,
+			}
+		}
+		if args.validReadinessHTTPGetProbe {
+			ctx.vm.Spec.ReadinessProbe = &vmopv1.Probe{
+				HTTPGet: &vmopv1.HTTPGetAction{
+					Path:        "/healthz",
+					Port:        intstr.FromString("http"),
+					HTTPHeaders: []vmopv1.HTTPHeader{{Name: "X-Probe", Value: "vm-operator"}},
+					ExpectedStatus: &vmopv1.HTTPStatusRange{
+						Min: 200,
+						Max: 299,
+					},
+				},
+			}
+		}
+		if args.invalidReadinessHTTPGetProbe {
+			ctx.vm.Spec.ReadinessProbe = &vmopv1.Probe{
+				HTTPGet: &vmopv1.HTTPGetAction{
+					Path: "healthz",
+					Port: intstr.FromInt(0),
+					ExpectedStatus: &vmopv1.HTTPStatusRange{
+						Min: 299,
+						Max: 200,
+					},
+				},
+			}
+		}
+		if args.validReadinessGuestExecProbe {
+			ctx.vm.Spec.ReadinessProbe = &vmopv1.Probe{
+				GuestExec: &vmopv1.GuestExecAction{
+					Command:               []string{"/bin/true"},
+					CredentialsSecretName: "guest-credentials",
+				},
+			}
+		}
+		if args.invalidReadinessGuestExecProbe {
+			ctx.vm.Spec.ReadinessProbe = &vmopv1.Probe{
+				GuestExec: &vmopv1.GuestExecAction{},
+			}
+		}
+		if args.validReadinessHTTPGetProbe || args.invalidReadinessHTTPGetProbe {
+			Expect(ctx.Client.Create(ctx, setConfigMap(ctx.Namespace, false))).To(Succeed())
+		}
 		if args.isRestrictedNetworkEnv || args.isNonRestrictedNetworkEnv {
 			configMapIn := setConfigMap(ctx.Namespace, args.isRestrictedNetworkEnv)
 			ctx.vm.Spec.ReadinessProbe = setReadinessProbe(args.isRestrictedNetworkValidProbePort)
@@ -288,6 +544,24 @@ func unitTestsValidateCreate() {
 			instanceStorageVolume := builder.DummyInstanceStorageVirtualMachineVolumes()
 			ctx.vm.Spec.Volumes = append(ctx.vm.Spec.Volumes, instanceStorageVolume...)
 		}
+		if args.withStorageRelocateTask {
+			if ctx.vm.Annotations == nil {
+				ctx.vm.Annotations = map[string]string{}
+			}
+			ctx.vm.Annotations[constants.StorageRelocateTaskAnnotation] = "task-1"
+		}
+		if args.withMigrateTask {
+			if ctx.vm.Annotations == nil {
+				ctx.vm.Annotations = map[string]string{}
+			}
+			ctx.vm.Annotations[constants.MigrateTaskAnnotation] = "task-1"
+		}
+		if args.withBootDiskURLPath {
+			if ctx.vm.Annotations == nil {
+				ctx.vm.Annotations = map[string]string{}
+			}
+			ctx.vm.Annotations[constants.BootDiskURLPathAnnotation] = "https://vc.domain.com/folder/other-vm/other-vm.vmdk?dcPath=DC0&dsName=LocalDS_0"
+		}
 		// Please note this prevents the unit tests from running safely in parallel.
 		lib.IsWcpFaultDomainsFSSEnabled = func() bool {
 			return args.isWCPFaultDomainsFSSEnabled
@@ -340,6 +614,7 @@ func unitTestsValidateCreate() {
 	specPath := field.NewPath("spec")
 	netIntPath := specPath.Child("networkInterfaces")
 	volPath := specPath.Child("volumes")
+	cdromPath := specPath.Child("cdrom")
 
 	DescribeTable("create table", validateCreate,
 		Entry("should allow valid", createArgs{}, true, nil, nil),
@@ -357,6 +632,22 @@ func unitTestsValidateCreate() {
 			field.Forbidden(specPath.Child("readinessProbe"), "only one action can be specified").Error(), nil),
 		Entry("should fail when Readiness probe has no actions", createArgs{invalidReadinessNoProbe: true}, false,
 			field.Forbidden(specPath.Child("readinessProbe"), "must specify an action").Error(), nil),
+		Entry("should fail when Liveness probe has no actions", createArgs{invalidLivenessNoProbe: true}, false,
+			field.Forbidden(specPath.Child("livenessProbe"), "must specify an action").Error(), nil),
+		Entry("should allow valid liveness probe", createArgs{validLivenessProbe: true}, true, nil, nil),
+		Entry("should allow valid HTTPGet readiness probe", createArgs{validReadinessHTTPGetProbe: true}, true, nil, nil),
+		Entry("should allow valid GuestExec readiness probe", createArgs{validReadinessGuestExecProbe: true}, true, nil, nil),
+		Entry("should deny invalid GuestExec readiness probe", createArgs{invalidReadinessGuestExecProbe: true}, false,
+			strings.Join([]string{
+				field.Required(specPath.Child("readinessProbe", "guestExec", "command"), "").Error(),
+				field.Required(specPath.Child("readinessProbe", "guestExec", "credentialsSecretName"), "").Error(),
+			}, ", "), nil),
+		Entry("should deny invalid HTTPGet readiness probe", createArgs{invalidReadinessHTTPGetProbe: true}, false,
+			strings.Join([]string{
+				field.Invalid(specPath.Child("readinessProbe", "httpGet", "path"), "healthz", "must be an absolute path").Error(),
+				field.Invalid(specPath.Child("readinessProbe", "httpGet", "port"), 0, "must be between 1 and 65535, inclusive").Error(),
+				field.Invalid(specPath.Child("readinessProbe", "httpGet", "expectedStatus"), "299-200", "min must be less than or equal to max").Error(),
+			}, ", "), nil),
 
 		Entry("should deny invalid network type", createArgs{invalidNetworkType: true}, false,
 			field.NotSupported(netIntPath.Index(0).Child("networkType"), "bogusNetworkType", []string{network.NsxtNetworkType, network.VdsNetworkType}).Error(), nil),
@@ -364,6 +655,16 @@ func unitTestsValidateCreate() {
 			field.NotSupported(netIntPath.Index(0).Child("ethernetCardType"), "bogusCardType", []string{"", "pcnet32", "e1000", "e1000e", "vmxnet2", "vmxnet3"}).Error(), nil),
 		Entry("should deny connection of multiple network interfaces of a VM to the same network", createArgs{multipleNetIfToSameNetwork: true}, false,
 			field.Duplicate(netIntPath.Index(1).Child("networkName"), bogusNetworkName).Error(), nil),
+		Entry("should deny invalid network route", createArgs{invalidNetworkRoute: true}, false,
+			strings.Join([]string{
+				field.Invalid(netIntPath.Index(0).Child("routes").Index(0).Child("to"), "10.0.0.0", `must be a CIDR or "default"`).Error(),
+				field.Invalid(netIntPath.Index(0).Child("routes").Index(0).Child("via"), "not-an-ip", "must be an IP address").Error(),
+			}, ", "), nil),
+		Entry("should allow valid network bond", createArgs{validNetworkBond: true}, true, nil, nil),
+		Entry("should deny network interface with bond that does not exist", createArgs{networkBondNotFound: true}, false,
+			field.NotFound(netIntPath.Index(1).Child("bondName"), "bond1").Error(), nil),
+		Entry("should deny network bond with a single member", createArgs{networkBondTooFewMembers: true}, false,
+			field.Invalid(specPath.Child("networkBonds").Index(0).Child("name"), "bond0", "must have at least two member network interfaces").Error(), nil),
 
 		Entry("should deny empty volume name", createArgs{emptyVolumeName: true}, false,
 			field.Required(volPath.Index(0).Child("name"), "").Error(), nil),
@@ -421,6 +722,84 @@ func unitTestsValidateCreate() {
 		Entry("should deny when there are instance storage volumes and user is SSO user", createArgs{addInstanceStorageVolumes: true}, false,
 			field.Forbidden(volPath, "adding or modifying instance storage volume claim(s) is not allowed").Error(), nil),
 		Entry("should allow when there are instance storage volumes and user is service user", createArgs{addInstanceStorageVolumes: true, isServiceUser: true}, true, nil, nil),
+		Entry("should deny the storage relocate task annotation when user is SSO user", createArgs{withStorageRelocateTask: true}, false,
+			field.Forbidden(field.NewPath("metadata", "annotations").Key(constants.StorageRelocateTaskAnnotation), "adding, modifying or removing this annotation is not allowed").Error(), nil),
+		Entry("should deny the migrate task annotation when user is SSO user", createArgs{withMigrateTask: true}, false,
+			field.Forbidden(field.NewPath("metadata", "annotations").Key(constants.MigrateTaskAnnotation), "adding, modifying or removing this annotation is not allowed").Error(), nil),
+		Entry("should allow the migrate task annotation when user is service user", createArgs{withMigrateTask: true, isServiceUser: true}, true, nil, nil),
+		Entry("should deny the boot disk URL path annotation when user is SSO user", createArgs{withBootDiskURLPath: true}, false,
+			field.Forbidden(field.NewPath("metadata", "annotations").Key(constants.BootDiskURLPathAnnotation), "adding, modifying or removing this annotation is not allowed").Error(), nil),
+
+		Entry("should allow source VM", createArgs{sourceVM: true}, true, nil, nil),
+		Entry("should allow source VM with a ready snapshot", createArgs{sourceSnapshot: true}, true, nil, nil),
+		Entry("should deny source VM with image name", createArgs{sourceWithImageName: true}, false,
+			field.Forbidden(specPath.Child("imageName"), "only one of imageName or source must be specified").Error(), nil),
+		Entry("should deny source VM that does not exist", createArgs{sourceVMNotFound: true}, false,
+			field.NotFound(specPath.Child("source", "virtualMachineName"), sourceVMName).Error(), nil),
+		Entry("should deny source VM that has not been created", createArgs{sourceVMNotCreated: true}, false,
+			field.Invalid(specPath.Child("source", "virtualMachineName"), sourceVMName, "source VirtualMachine has not been created").Error(), nil),
+		Entry("should deny source VM with a snapshot that is not ready", createArgs{sourceSnapshotNotReady: true}, false,
+			field.Invalid(specPath.Child("source", "snapshotName"), sourceSnapshotName, "source VirtualMachineSnapshot is not ready").Error(), nil),
+		Entry("should deny source VM when the user access cannot be granted", createArgs{sourceNotAuthorized: true}, false,
+			specPath.Child("source", "virtualMachineName").String(), nil),
+		Entry("should allow instant clone of a powered on source VM", createArgs{instantClone: true}, true, nil, nil),
+		Entry("should deny instant clone of a powered off source VM", createArgs{instantCloneSourcePoweredOff: true}, false,
+			field.Invalid(specPath.Child("source", "virtualMachineName"), sourceVMName, "source VirtualMachine must be powered on to be instant cloned").Error(), nil),
+		Entry("should deny instant clone of a source VM with PVC volumes", createArgs{instantCloneSourceWithVolumes: true}, false,
+			field.Invalid(specPath.Child("source", "virtualMachineName"), sourceVMName, "source VirtualMachine with PersistentVolumeClaim volumes cannot be instant cloned").Error(), nil),
+		Entry("should deny instant clone of a source VM snapshot", createArgs{instantCloneWithSnapshot: true}, false,
+			field.Forbidden(specPath.Child("source", "instant"), "only one of instant or snapshotName must be specified").Error(), nil),
+		Entry("should deny instant clone with CloudInit transport", createArgs{instantCloneWithCloudInit: true}, false,
+			field.NotSupported(specPath.Child("vmMetadata", "transport"), vmopv1.VirtualMachineMetadataCloudInitTransport, []string{"ExtraConfig"}).Error(), nil),
+		Entry("should deny nextRestartTime", createArgs{withNextRestartTime: true}, false,
+			field.Forbidden(specPath.Child("nextRestartTime"), "cannot be set when creating a VirtualMachine").Error(), nil),
+		Entry("should deny revertToSnapshot", createArgs{withRevertToSnapshot: true}, false,
+			field.Forbidden(specPath.Child("revertToSnapshot"), "cannot be set when creating a VirtualMachine").Error(), nil),
+
+		Entry("should allow secure boot with an image of unknown firmware", createArgs{secureBoot: true}, true, nil, nil),
+		Entry("should allow secure boot with a bios image and efi firmware", createArgs{biosImage: true, secureBoot: true, efiFirmwareOverride: true}, true, nil, nil),
+		Entry("should deny secure boot with a bios image", createArgs{biosImage: true, secureBoot: true}, false,
+			field.Invalid(specPath.Child("bootOptions", "efiSecureBootEnabled"), true, "requires efi firmware but the VirtualMachine has bios firmware").Error(), nil),
+		Entry("should deny secure boot with a bios namespace image, when ImageRegistry FSS is enabled", createArgs{biosImage: true, secureBoot: true, namespaceImage: true, isWCPVMImageRegistryEnabled: true}, false,
+			field.Invalid(specPath.Child("bootOptions", "efiSecureBootEnabled"), true, "requires efi firmware but the VirtualMachine has bios firmware").Error(), nil),
+		Entry("should allow virtual TPM", createArgs{virtualTPM: true}, true, nil, nil),
+		Entry("should deny virtual TPM with a bios image", createArgs{biosImage: true, virtualTPM: true}, false,
+			"spec.virtualTPM: Invalid value", nil),
+		Entry("should deny virtual TPM without a key provider", createArgs{virtualTPMWithoutKeyProvider: true}, false,
+			field.Required(specPath.Child("virtualTPM", "keyProviderID"), "").Error(), nil),
+		Entry("should deny duplicate boot order devices", createArgs{dupBootOrder: true}, false,
+			field.Duplicate(specPath.Child("bootOptions", "bootOrder").Index(2), vmopv1.VirtualMachineBootDeviceDisk).Error(), nil),
+		Entry("should allow boot disk capacity", createArgs{bootDiskCapacity: "20Gi"}, true, nil, nil),
+		Entry("should allow boot disk capacity larger than the boot disk of the image", createArgs{bootDiskCapacity: "20Gi", imageBootDiskCapacity: "10Gi"}, true, nil, nil),
+		Entry("should deny boot disk capacity smaller than the boot disk of the image", createArgs{bootDiskCapacity: "5Gi", imageBootDiskCapacity: "10Gi"}, false,
+			field.Invalid(specPath.Child("bootDiskCapacity"), "5Gi", "cannot be less than the boot disk capacity of the image, 10Gi").Error(), nil),
+		Entry("should deny zero boot disk capacity", createArgs{bootDiskCapacity: "0"}, false,
+			field.Invalid(specPath.Child("bootDiskCapacity"), "0", "must be greater than zero").Error(), nil),
+		Entry("should deny boot disk capacity that is not a multiple of 1Ki", createArgs{bootDiskCapacity: "1000"}, false,
+			field.Invalid(specPath.Child("bootDiskCapacity"), "1k", "must be a multiple of 1Ki").Error(), nil),
+		Entry("should deny boot disk capacity with an image volume", createArgs{bootDiskCapacity: "20Gi", imageVolume: true}, false,
+			field.Forbidden(specPath.Child("bootDiskCapacity"), "cannot be specified for a VirtualMachine that boots from an imageVolumeClaim").Error(), nil),
+		Entry("should deny boot disk capacity for a linked clone", createArgs{bootDiskCapacity: "20Gi", sourceSnapshot: true}, false,
+			field.Forbidden(specPath.Child("bootDiskCapacity"), "cannot be specified for a VirtualMachine that is a linked or instant clone").Error(), nil),
+
+		Entry("should allow image volume", createArgs{imageVolume: true}, true, nil, nil),
+		Entry("should deny image volume of another image", createArgs{imageVolumeOtherImage: true}, false,
+			field.Invalid(volPath.Index(0).Child("persistentVolumeClaim", "imageVolumeClaim", "imageName"),
+				builder.DummyImageName+updateSuffix, "must be the imageName of the VirtualMachine").Error(), nil),
+		Entry("should deny image volume with an instance volume claim", createArgs{imageVolumeWithInstanceVolume: true, isServiceUser: true}, false,
+			field.Forbidden(volPath.Index(0).Child("persistentVolumeClaim", "imageVolumeClaim"),
+				"only one of instanceVolumeClaim or imageVolumeClaim must be specified").Error(), nil),
+		Entry("should deny multiple image volumes", createArgs{multipleImageVolumes: true}, false,
+			field.Forbidden(volPath.Index(1).Child("persistentVolumeClaim", "imageVolumeClaim"),
+				"only one volume can have an imageVolumeClaim").Error(), nil),
+
+		Entry("should allow a CD-ROM of an ISO image", createArgs{isoCdrom: true}, true, nil, nil),
+		Entry("should deny an ISO image name", createArgs{isoImageName: true}, false,
+			field.Invalid(specPath.Child("imageName"), dummyISOImageName, "must not be an ISO image, which can only back the CD-ROMs of a VirtualMachine").Error(), nil),
+		Entry("should deny a CD-ROM of an image that is not an ISO", createArgs{cdromNotISO: true}, false,
+			field.Invalid(cdromPath.Index(0).Child("imageName"), builder.DummyImageName, "must be the name of a VirtualMachineImage of type ISO").Error(), nil),
+		Entry("should deny CD-ROMs with the same name", createArgs{duplicateCdromName: true}, false,
+			field.Duplicate(cdromPath.Index(1).Child("name"), "cdrom1").Error(), nil),
 	)
 }
 
@@ -431,14 +810,44 @@ func unitTestsValidateUpdate() {
 
 	type updateArgs struct {
 		changeClassName                 bool
+		removeClassName                 bool
 		changeImageName                 bool
+		changeSource                    bool
 		changeStorageClass              bool
+		changeToValidStorageClass       bool
+		storageRelocating               bool
+		removeStorageRelocateTask       bool
 		changeResourcePolicy            bool
 		assignZoneName                  bool
 		changeZoneName                  bool
+		changeToValidZoneName           bool
+		changeToZoneWithoutNamespace    bool
+		removeZoneName                  bool
+		removeVolumes                   bool
+		withStorageClass                bool
+		migrating                       bool
+		removeMigrateTask               bool
+		changeBootDiskURLPath           bool
+		isWCPFaultDomainsFSSEnabled     bool
 		changeInstanceStorageVolumeName bool
 		isServiceUser                   bool
 		addInstanceStorageVolume        bool
+		setNextRestartTime              bool
+		setInvalidNextRestartTime       bool
+		setFutureNextRestartTime        bool
+		revertToSnapshot                bool
+		revertToMissingSnapshot         bool
+		revertToOtherVMSnapshot         bool
+		addVirtualTPM                   bool
+		removeVirtualTPM                bool
+		changeVirtualTPMKeyProvider     bool
+		growBootDisk                    bool
+		shrinkBootDisk                  bool
+		withSnapshot                    bool
+		withImageVolume                 bool
+		addImageVolume                  bool
+		addCdrom                        bool
+		changeCdrom                     bool
 	}
 
 	validateUpdate := func(args updateArgs, expectedAllowed bool, expectedReason string, expectedErr error) {
@@ -447,12 +856,39 @@ func unitTestsValidateUpdate() {
 		if args.changeClassName {
 			ctx.vm.Spec.ClassName += updateSuffix
 		}
+		if args.removeClassName {
+			ctx.vm.Spec.ClassName = ""
+		}
 		if args.changeImageName {
 			ctx.vm.Spec.ImageName += updateSuffix
 		}
+		if args.changeSource {
+			ctx.vm.Spec.Source = &vmopv1.VirtualMachineSource{VirtualMachineName: sourceVMName}
+		}
 		if args.changeStorageClass {
 			ctx.vm.Spec.StorageClass += updateSuffix
 		}
+		if args.changeToValidStorageClass {
+			// StorageClass specified and is assigned to ResourceQuota.
+			ctx.vm.Spec.StorageClass = builder.DummyStorageClassName
+			storageClass := builder.DummyStorageClass()
+			rlName := storageClass.Name + ".storageclass.storage.k8s.io/persistentvolumeclaims"
+			resourceQuota := builder.DummyResourceQuota(ctx.vm.Namespace, rlName)
+			Expect(ctx.Client.Create(ctx, resourceQuota)).To(Succeed())
+			Expect(ctx.Client.Create(ctx, storageClass)).To(Succeed())
+		}
+		if args.storageRelocating || args.removeStorageRelocateTask {
+			if ctx.oldVM.Annotations == nil {
+				ctx.oldVM.Annotations = map[string]string{}
+			}
+			ctx.oldVM.Annotations[constants.StorageRelocateTaskAnnotation] = "task-1"
+			if !args.removeStorageRelocateTask {
+				if ctx.vm.Annotations == nil {
+					ctx.vm.Annotations = map[string]string{}
+				}
+				ctx.vm.Annotations[constants.StorageRelocateTaskAnnotation] = "task-1"
+			}
+		}
 		if args.changeResourcePolicy {
 			ctx.vm.Spec.ResourcePolicyName = updateSuffix
 		}
@@ -463,6 +899,53 @@ func unitTestsValidateUpdate() {
 			ctx.oldVM.Labels[topology.KubernetesTopologyZoneLabelKey] = builder.DummyAvailabilityZoneName
 			ctx.vm.Labels[topology.KubernetesTopologyZoneLabelKey] = builder.DummyAvailabilityZoneName + updateSuffix
 		}
+		if args.changeToValidZoneName {
+			zone := builder.DummyAvailabilityZone()
+			zone.Name += updateSuffix
+			zone.Spec.Namespaces[ctx.vm.Namespace] = topologyv1.NamespaceInfo{PoolMoIDs: []string{"resgroup-1"}}
+			Expect(ctx.Client.Create(ctx, zone)).To(Succeed())
+		}
+		if args.changeToZoneWithoutNamespace {
+			zone := builder.DummyAvailabilityZone()
+			zone.Name += updateSuffix
+			Expect(ctx.Client.Create(ctx, zone)).To(Succeed())
+		}
+		if args.removeZoneName {
+			ctx.oldVM.Labels[topology.KubernetesTopologyZoneLabelKey] = builder.DummyAvailabilityZoneName
+			delete(ctx.vm.Labels, topology.KubernetesTopologyZoneLabelKey)
+		}
+		if args.removeVolumes {
+			ctx.oldVM.Spec.Volumes = nil
+			ctx.vm.Spec.Volumes = nil
+		}
+		if args.withStorageClass {
+			ctx.oldVM.Spec.StorageClass = builder.DummyStorageClassName
+			ctx.vm.Spec.StorageClass = builder.DummyStorageClassName
+		}
+		if args.migrating || args.removeMigrateTask {
+			if ctx.oldVM.Annotations == nil {
+				ctx.oldVM.Annotations = map[string]string{}
+			}
+			ctx.oldVM.Annotations[constants.MigrateTaskAnnotation] = "task-1"
+			if !args.removeMigrateTask {
+				if ctx.vm.Annotations == nil {
+					ctx.vm.Annotations = map[string]string{}
+				}
+				ctx.vm.Annotations[constants.MigrateTaskAnnotation] = "task-1"
+			}
+		}
+		if args.changeBootDiskURLPath {
+			ctx.oldVM.Annotations = map[string]string{
+				constants.BootDiskURLPathAnnotation: "https://vc.domain.com/folder/dummy-vm/dummy-vm.vmdk?dcPath=DC0&dsName=LocalDS_0",
+			}
+			ctx.vm.Annotations = map[string]string{
+				constants.BootDiskURLPathAnnotation: "https://vc.domain.com/folder/other-vm/other-vm.vmdk?dcPath=DC0&dsName=LocalDS_0",
+			}
+		}
+		// Please note this prevents the unit tests from running safely in parallel.
+		lib.IsWcpFaultDomainsFSSEnabled = func() bool {
+			return args.isWCPFaultDomainsFSSEnabled
+		}
 
 		if args.isServiceUser {
 			ctx.IsPrivilegedAccount = true
@@ -471,6 +954,82 @@ func unitTestsValidateUpdate() {
 			instanceStorageVolumes := builder.DummyInstanceStorageVirtualMachineVolumes()
 			ctx.vm.Spec.Volumes = append(ctx.vm.Spec.Volumes, instanceStorageVolumes...)
 		}
+		if args.setNextRestartTime {
+			ctx.vm.Spec.NextRestartTime = time.Now().UTC().Format(time.RFC3339)
+		}
+		if args.setInvalidNextRestartTime {
+			ctx.vm.Spec.NextRestartTime = "tomorrow"
+		}
+		if args.setFutureNextRestartTime {
+			ctx.vm.Spec.NextRestartTime = time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+		}
+		if args.revertToSnapshot || args.revertToOtherVMSnapshot {
+			vmSnapshot := &vmopv1.VirtualMachineSnapshot{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      revertSnapshotName,
+					Namespace: ctx.vm.Namespace,
+				},
+				Spec: vmopv1.VirtualMachineSnapshotSpec{
+					VirtualMachineName: ctx.vm.Name,
+				},
+			}
+			if args.revertToOtherVMSnapshot {
+				vmSnapshot.Spec.VirtualMachineName = sourceVMName
+			}
+			Expect(ctx.Client.Create(ctx, vmSnapshot)).To(Succeed())
+		}
+		if args.revertToSnapshot || args.revertToMissingSnapshot || args.revertToOtherVMSnapshot {
+			ctx.vm.Spec.RevertToSnapshot = revertSnapshotName
+		}
+		if args.addVirtualTPM {
+			ctx.oldVM.Spec.PowerState = vmopv1.VirtualMachinePoweredOff
+			ctx.vm.Spec.PowerState = vmopv1.VirtualMachinePoweredOff
+			ctx.vm.Spec.VirtualTPM = &vmopv1.VirtualMachineVirtualTPM{KeyProviderID: "key-provider"}
+		}
+		if args.removeVirtualTPM || args.changeVirtualTPMKeyProvider {
+			ctx.oldVM.Spec.VirtualTPM = &vmopv1.VirtualMachineVirtualTPM{KeyProviderID: "key-provider"}
+		}
+		if args.changeVirtualTPMKeyProvider {
+			ctx.vm.Spec.VirtualTPM = &vmopv1.VirtualMachineVirtualTPM{KeyProviderID: "key-provider" + updateSuffix}
+		}
+		if args.growBootDisk || args.shrinkBootDisk {
+			oldCapacity := resource.MustParse("20Gi")
+			ctx.oldVM.Spec.BootDiskCapacity = &oldCapacity
+			capacity := resource.MustParse("30Gi")
+			if args.shrinkBootDisk {
+				capacity = resource.MustParse("10Gi")
+			}
+			ctx.vm.Spec.BootDiskCapacity = &capacity
+		}
+		if args.withSnapshot {
+			vmSnapshot := &vmopv1.VirtualMachineSnapshot{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "dummy-snapshot",
+					Namespace: ctx.vm.Namespace,
+				},
+				Spec: vmopv1.VirtualMachineSnapshotSpec{
+					VirtualMachineName: ctx.vm.Name,
+				},
+			}
+			Expect(ctx.Client.Create(ctx, vmSnapshot)).To(Succeed())
+		}
+		if args.withImageVolume || args.addImageVolume {
+			ctx.vm.Spec.Volumes[0].PersistentVolumeClaim.ImageVolumeClaim = &vmopv1.ImageVolumeClaimVolumeSource{
+				ImageName: ctx.vm.Spec.ImageName,
+			}
+		}
+		if args.withImageVolume {
+			ctx.oldVM.Spec.Volumes[0].PersistentVolumeClaim.ImageVolumeClaim = &vmopv1.ImageVolumeClaimVolumeSource{
+				ImageName: ctx.oldVM.Spec.ImageName,
+			}
+		}
+		if args.addCdrom || args.changeCdrom {
+			ctx.vm.Spec.Cdrom = []vmopv1.VirtualMachineCdrom{{Name: "cdrom1", ImageName: dummyISOImageName}}
+		}
+		if args.changeCdrom {
+			connected := false
+			ctx.oldVM.Spec.Cdrom = []vmopv1.VirtualMachineCdrom{{Name: "cdrom1", ImageName: dummyISOImageName, Connected: &connected}}
+		}
 		if args.changeInstanceStorageVolumeName {
 			instanceStorageVolumes := builder.DummyInstanceStorageVirtualMachineVolumes()
 			ctx.oldVM.Spec.Volumes = append(ctx.oldVM.Spec.Volumes, instanceStorageVolumes...)
@@ -507,18 +1066,73 @@ func unitTestsValidateUpdate() {
 	DescribeTable("update table", validateUpdate,
 		// Immutable Fields
 		Entry("should allow", updateArgs{}, true, nil, nil),
-		Entry("should deny class name change", updateArgs{changeClassName: true}, false, msg, nil),
+		Entry("should allow class name change", updateArgs{changeClassName: true}, true, nil, nil),
+		Entry("should deny class name removal", updateArgs{removeClassName: true}, false,
+			field.Required(field.NewPath("spec", "className"), "").Error(), nil),
 		Entry("should deny image name change", updateArgs{changeImageName: true}, false, msg, nil),
-		Entry("should deny storageClass change", updateArgs{changeStorageClass: true}, false, msg, nil),
+		Entry("should deny source change", updateArgs{changeSource: true}, false, msg, nil),
+		Entry("should deny storageClass change to a storage class that does not exist", updateArgs{changeStorageClass: true}, false,
+			field.Invalid(field.NewPath("spec", "storageClass"), updateSuffix, fmt.Sprintf("Storage policy is not associated with the namespace %s", "dummy-vm-namespace-for-webhook-validation")).Error(), nil),
+		Entry("should allow storageClass change to a storage class associated with the namespace", updateArgs{changeToValidStorageClass: true}, true, nil, nil),
+		Entry("should deny storageClass change while the storage is being relocated", updateArgs{changeToValidStorageClass: true, storageRelocating: true}, false,
+			field.Forbidden(field.NewPath("spec", "storageClass"), "cannot be changed while the VirtualMachine storage is being relocated").Error(), nil),
+		Entry("should deny removing the storage relocate task annotation, when user is SSO user", updateArgs{removeStorageRelocateTask: true}, false,
+			field.Forbidden(field.NewPath("metadata", "annotations").Key(constants.StorageRelocateTaskAnnotation), "adding, modifying or removing this annotation is not allowed").Error(), nil),
+		Entry("should allow removing the storage relocate task annotation, when user type is service user", updateArgs{removeStorageRelocateTask: true, isServiceUser: true}, true, nil, nil),
 		Entry("should deny resourcePolicy change", updateArgs{changeResourcePolicy: true}, false, msg, nil),
 		Entry("should allow initial zone assignment", updateArgs{assignZoneName: true}, true, nil, nil),
 		Entry("should allow zone name change when WCP FaultDomains FSS is disabled", updateArgs{changeZoneName: true}, true, nil, nil),
+		Entry("should allow zone name change to a valid zone when WCP FaultDomains FSS is enabled", updateArgs{changeZoneName: true, changeToValidZoneName: true, removeVolumes: true, withStorageClass: true, isWCPFaultDomainsFSSEnabled: true}, true, nil, nil),
+		Entry("should deny zone name change of a VM with PersistentVolumeClaim volumes", updateArgs{changeZoneName: true, changeToValidZoneName: true, withStorageClass: true, isWCPFaultDomainsFSSEnabled: true}, false,
+			field.Forbidden(field.NewPath("metadata", "labels").Key(topology.KubernetesTopologyZoneLabelKey), "cannot be changed for a VirtualMachine with PersistentVolumeClaim volumes").Error(), nil),
+		Entry("should deny zone name change of a VM without a StorageClass", updateArgs{changeZoneName: true, changeToValidZoneName: true, removeVolumes: true, isWCPFaultDomainsFSSEnabled: true}, false,
+			field.Forbidden(field.NewPath("metadata", "labels").Key(topology.KubernetesTopologyZoneLabelKey), "cannot be changed for a VirtualMachine without a StorageClass").Error(), nil),
+		Entry("should deny zone name change to an invalid zone when WCP FaultDomains FSS is enabled", updateArgs{changeZoneName: true, isWCPFaultDomainsFSSEnabled: true}, false, nil, nil),
+		Entry("should deny zone name change to a zone without a resource pool for the namespace", updateArgs{changeZoneName: true, changeToZoneWithoutNamespace: true, removeVolumes: true, withStorageClass: true, isWCPFaultDomainsFSSEnabled: true}, false,
+			field.Invalid(field.NewPath("metadata", "labels").Key(topology.KubernetesTopologyZoneLabelKey), builder.DummyAvailabilityZoneName+updateSuffix, "zone does not have a resource pool for the namespace dummy-vm-namespace-for-webhook-validation").Error(), nil),
+		Entry("should deny zone name removal when WCP FaultDomains FSS is enabled", updateArgs{removeZoneName: true, isWCPFaultDomainsFSSEnabled: true}, false,
+			field.Forbidden(field.NewPath("metadata", "labels").Key(topology.KubernetesTopologyZoneLabelKey), "zone cannot be removed from a VirtualMachine").Error(), nil),
+		Entry("should deny zone name change while the VM is being migrated", updateArgs{changeZoneName: true, changeToValidZoneName: true, migrating: true, isWCPFaultDomainsFSSEnabled: true}, false,
+			field.Forbidden(field.NewPath("metadata", "labels").Key(topology.KubernetesTopologyZoneLabelKey), "cannot be changed while the VirtualMachine is being migrated").Error(), nil),
+		Entry("should deny zone name change while the storage is being relocated", updateArgs{changeZoneName: true, changeToValidZoneName: true, storageRelocating: true, isWCPFaultDomainsFSSEnabled: true}, false,
+			field.Forbidden(field.NewPath("metadata", "labels").Key(topology.KubernetesTopologyZoneLabelKey), "cannot be changed while the VirtualMachine storage is being relocated").Error(), nil),
+		Entry("should deny storageClass change while the VM is being migrated", updateArgs{changeToValidStorageClass: true, migrating: true}, false,
+			field.Forbidden(field.NewPath("spec", "storageClass"), "cannot be changed while the VirtualMachine is being migrated").Error(), nil),
+		Entry("should deny removing the migrate task annotation, when user is SSO user", updateArgs{removeMigrateTask: true}, false,
+			field.Forbidden(field.NewPath("metadata", "annotations").Key(constants.MigrateTaskAnnotation), "adding, modifying or removing this annotation is not allowed").Error(), nil),
+		Entry("should allow removing the migrate task annotation, when user type is service user", updateArgs{removeMigrateTask: true, isServiceUser: true}, true, nil, nil),
+		Entry("should deny changing the boot disk URL path annotation, when user is SSO user", updateArgs{changeBootDiskURLPath: true}, false,
+			field.Forbidden(field.NewPath("metadata", "annotations").Key(constants.BootDiskURLPathAnnotation), "adding, modifying or removing this annotation is not allowed").Error(), nil),
 		Entry("should deny instance storage volume name change, when user is SSO user", updateArgs{changeInstanceStorageVolumeName: true}, false,
 			field.Forbidden(volumesPath, "adding or modifying instance storage volume claim(s) is not allowed").Error(), nil),
 		Entry("should deny adding new instance storage volume, when user is SSO user", updateArgs{addInstanceStorageVolume: true}, false,
 			field.Forbidden(volumesPath, "adding or modifying instance storage volume claim(s) is not allowed").Error(), nil),
 		Entry("should allow adding new instance storage volume, when user type is service user", updateArgs{addInstanceStorageVolume: true, isServiceUser: true}, true, nil, nil),
 		Entry("should allow instance storage volume name change, when user type is service user", updateArgs{changeInstanceStorageVolumeName: true, isServiceUser: true}, true, nil, nil),
+		Entry("should allow nextRestartTime in RFC3339 format", updateArgs{setNextRestartTime: true}, true, nil, nil),
+		Entry("should deny nextRestartTime not in RFC3339 format", updateArgs{setInvalidNextRestartTime: true}, false,
+			field.Invalid(field.NewPath("spec", "nextRestartTime"), "tomorrow", `must be "now" or a time in RFC3339 format`).Error(), nil),
+		Entry("should deny nextRestartTime in the future", updateArgs{setFutureNextRestartTime: true}, false, nil, nil),
+		Entry("should allow revertToSnapshot to a snapshot of the VM", updateArgs{revertToSnapshot: true}, true, nil, nil),
+		Entry("should deny revertToSnapshot to a snapshot that does not exist", updateArgs{revertToMissingSnapshot: true}, false,
+			field.NotFound(field.NewPath("spec", "revertToSnapshot"), revertSnapshotName).Error(), nil),
+		Entry("should deny revertToSnapshot to a snapshot of another VM", updateArgs{revertToOtherVMSnapshot: true}, false,
+			field.Invalid(field.NewPath("spec", "revertToSnapshot"), revertSnapshotName, "VirtualMachineSnapshot is not a snapshot of this VirtualMachine").Error(), nil),
+		Entry("should allow adding a virtual TPM when the VM is powered off", updateArgs{addVirtualTPM: true}, true, nil, nil),
+		Entry("should deny removing the virtual TPM", updateArgs{removeVirtualTPM: true}, false,
+			field.Forbidden(field.NewPath("spec", "virtualTPM"), "virtual TPM cannot be removed from a VirtualMachine").Error(), nil),
+		Entry("should deny virtual TPM key provider change", updateArgs{changeVirtualTPMKeyProvider: true}, false, msg, nil),
+		Entry("should allow growing the boot disk while powered on", updateArgs{growBootDisk: true}, true, nil, nil),
+		Entry("should deny shrinking the boot disk", updateArgs{shrinkBootDisk: true}, false,
+			field.Invalid(field.NewPath("spec", "bootDiskCapacity"), "10Gi", "cannot be decreased from 20Gi").Error(), nil),
+		Entry("should deny growing the boot disk of a VM with snapshots", updateArgs{growBootDisk: true, withSnapshot: true}, false,
+			field.Forbidden(field.NewPath("spec", "bootDiskCapacity"), "cannot be increased for a VirtualMachine that has snapshots").Error(), nil),
+		Entry("should allow unchanged image volume", updateArgs{withImageVolume: true}, true, nil, nil),
+		Entry("should deny adding an image volume", updateArgs{addImageVolume: true}, false,
+			field.Forbidden(volumesPath, "the volume with an imageVolumeClaim cannot be added, removed or modified").Error(), nil),
+		Entry("should deny adding a CD-ROM while powered on", updateArgs{addCdrom: true}, false,
+			field.Forbidden(field.NewPath("spec", "cdrom"), "CD-ROMs cannot be added, removed or reordered when VM power is on").Error(), nil),
+		Entry("should allow connecting a CD-ROM while powered on", updateArgs{changeCdrom: true}, true, nil, nil),
 	)
 
 	When("the update is performed while object deletion", func() {
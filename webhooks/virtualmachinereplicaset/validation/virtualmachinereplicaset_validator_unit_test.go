@@ -0,0 +1,214 @@
+// Copyright (c) 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package validation_test
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	vmopv1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
+
+	"github.com/vmware-tanzu/vm-operator/test/builder"
+)
+
+const (
+	sourceVMName = "dummy-source-vm"
+)
+
+func unitTests() {
+	Describe("Invoking ValidateCreate", unitTestsValidateCreate)
+	Describe("Invoking ValidateUpdate", unitTestsValidateUpdate)
+	Describe("Invoking ValidateDelete", unitTestsValidateDelete)
+}
+
+type unitValidatingWebhookContext struct {
+	builder.UnitTestContextForValidatingWebhook
+	rs, oldRS *vmopv1.VirtualMachineReplicaSet
+}
+
+func dummyVirtualMachineReplicaSet() *vmopv1.VirtualMachineReplicaSet {
+	vm := builder.DummyVirtualMachine()
+
+	return &vmopv1.VirtualMachineReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "dummy-rs",
+			Namespace: vm.Namespace,
+		},
+		Spec: vmopv1.VirtualMachineReplicaSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "dummy"},
+			},
+			Template: vmopv1.VirtualMachineTemplateSpec{
+				VirtualMachineTemplateObjectMeta: vmopv1.VirtualMachineTemplateObjectMeta{
+					Labels: map[string]string{"app": "dummy"},
+				},
+				Spec: vm.Spec,
+			},
+		},
+	}
+}
+
+func newUnitTestContextForValidatingWebhook(isUpdate bool) *unitValidatingWebhookContext {
+	rs := dummyVirtualMachineReplicaSet()
+	obj, err := builder.ToUnstructured(rs)
+	Expect(err).ToNot(HaveOccurred())
+
+	var oldRS *vmopv1.VirtualMachineReplicaSet
+	var oldObj *unstructured.Unstructured
+
+	if isUpdate {
+		oldRS = rs.DeepCopy()
+		oldObj, err = builder.ToUnstructured(oldRS)
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	vmImage := builder.DummyVirtualMachineImage(rs.Spec.Template.Spec.ImageName)
+
+	return &unitValidatingWebhookContext{
+		UnitTestContextForValidatingWebhook: *suite.NewUnitTestContextForValidatingWebhook(obj, oldObj, vmImage),
+		rs:                                  rs,
+		oldRS:                               oldRS,
+	}
+}
+
+func unitTestsValidateCreate() {
+	var (
+		ctx *unitValidatingWebhookContext
+	)
+
+	type createArgs struct {
+		instanceStorageVolumes bool
+		sourceVM               bool
+		isPrivilegedAccount    bool
+	}
+
+	validateCreate := func(args createArgs, expectedAllowed bool, expectedReason string, expectedErr error) {
+		var err error
+
+		if args.instanceStorageVolumes {
+			ctx.rs.Spec.Template.Spec.Volumes = append(ctx.rs.Spec.Template.Spec.Volumes,
+				builder.DummyInstanceStorageVirtualMachineVolumes()...)
+		}
+		if args.sourceVM {
+			ctx.rs.Spec.Template.Spec.ImageName = ""
+			ctx.rs.Spec.Template.Spec.Source = &vmopv1.VirtualMachineSource{VirtualMachineName: sourceVMName}
+		}
+		// The fake client cannot evaluate the SubjectAccessReview of a non-privileged user.
+		ctx.IsPrivilegedAccount = args.isPrivilegedAccount
+
+		ctx.WebhookRequestContext.Obj, err = builder.ToUnstructured(ctx.rs)
+		Expect(err).ToNot(HaveOccurred())
+
+		response := ctx.ValidateCreate(&ctx.WebhookRequestContext)
+		Expect(response.Allowed).To(Equal(expectedAllowed))
+		if expectedReason != "" {
+			Expect(string(response.Result.Reason)).To(ContainSubstring(expectedReason))
+		}
+		if expectedErr != nil {
+			Expect(response.Result.Message).To(Equal(expectedErr.Error()))
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = newUnitTestContextForValidatingWebhook(false)
+	})
+	AfterEach(func() {
+		ctx = nil
+	})
+
+	templateSpecPath := field.NewPath("spec", "template", "spec")
+	DescribeTable("create table", validateCreate,
+		Entry("should allow valid", createArgs{}, true, nil, nil),
+		Entry("should deny instance storage volumes in the template when user is SSO user", createArgs{instanceStorageVolumes: true}, false,
+			field.Forbidden(templateSpecPath.Child("volumes"), "adding or modifying instance storage volume claim(s) is not allowed").Error(), nil),
+		Entry("should allow instance storage volumes in the template when user is privileged", createArgs{instanceStorageVolumes: true, isPrivilegedAccount: true}, true, nil, nil),
+		Entry("should deny source VM in the template when the user access cannot be granted", createArgs{sourceVM: true}, false,
+			templateSpecPath.Child("source", "virtualMachineName").String(), nil),
+	)
+}
+
+func unitTestsValidateUpdate() {
+	var (
+		ctx *unitValidatingWebhookContext
+	)
+
+	type updateArgs struct {
+		instanceStorageVolumesInOldTemplate bool
+		changeTemplate                      bool
+	}
+
+	validateUpdate := func(args updateArgs, expectedAllowed bool, expectedReason string, expectedErr error) {
+		var err error
+
+		if args.instanceStorageVolumesInOldTemplate {
+			ctx.oldRS.Spec.Template.Spec.Volumes = append(ctx.oldRS.Spec.Template.Spec.Volumes,
+				builder.DummyInstanceStorageVirtualMachineVolumes()...)
+			ctx.rs.Spec.Template.Spec.Volumes = ctx.oldRS.Spec.Template.Spec.Volumes
+		}
+		if args.changeTemplate {
+			ctx.rs.Spec.Template.Labels["tier"] = "web"
+		}
+
+		ctx.WebhookRequestContext.Obj, err = builder.ToUnstructured(ctx.rs)
+		Expect(err).ToNot(HaveOccurred())
+		ctx.WebhookRequestContext.OldObj, err = builder.ToUnstructured(ctx.oldRS)
+		Expect(err).ToNot(HaveOccurred())
+
+		response := ctx.ValidateUpdate(&ctx.WebhookRequestContext)
+		Expect(response.Allowed).To(Equal(expectedAllowed))
+		if expectedReason != "" {
+			Expect(string(response.Result.Reason)).To(Equal(expectedReason))
+		}
+		if expectedErr != nil {
+			Expect(response.Result.Message).To(Equal(expectedErr.Error()))
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = newUnitTestContextForValidatingWebhook(true)
+	})
+	AfterEach(func() {
+		ctx = nil
+	})
+
+	volumesPath := field.NewPath("spec", "template", "spec", "volumes")
+	DescribeTable("update table", validateUpdate,
+		Entry("should allow", updateArgs{}, true, nil, nil),
+		Entry("should allow unchanged template with instance storage volumes", updateArgs{instanceStorageVolumesInOldTemplate: true}, true, nil, nil),
+		Entry("should deny changed template with instance storage volumes", updateArgs{instanceStorageVolumesInOldTemplate: true, changeTemplate: true}, false,
+			field.Forbidden(volumesPath, "adding or modifying instance storage volume claim(s) is not allowed").Error(), nil),
+	)
+}
+
+func unitTestsValidateDelete() {
+	var (
+		ctx      *unitValidatingWebhookContext
+		response admission.Response
+	)
+
+	BeforeEach(func() {
+		ctx = newUnitTestContextForValidatingWebhook(false)
+	})
+	AfterEach(func() {
+		ctx = nil
+	})
+
+	When("the delete is performed", func() {
+		JustBeforeEach(func() {
+			response = ctx.ValidateDelete(&ctx.WebhookRequestContext)
+		})
+
+		It("should allow the request", func() {
+			Expect(response.Allowed).To(BeTrue())
+			Expect(response.Result).ToNot(BeNil())
+		})
+	})
+}
@@ -26,6 +26,7 @@ import (
 
 	vmopv1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
 
+	"github.com/vmware-tanzu/vm-operator/controllers/virtualmachineservice/utils"
 	"github.com/vmware-tanzu/vm-operator/pkg/builder"
 	"github.com/vmware-tanzu/vm-operator/pkg/context"
 	"github.com/vmware-tanzu/vm-operator/webhooks/common"
@@ -91,6 +92,7 @@ func (v validator) ValidateCreate(ctx *context.WebhookRequestContext) admission.
 
 	var fieldErrs field.ErrorList
 	fieldErrs = append(fieldErrs, v.validateMetadata(ctx, vmService)...)
+	fieldErrs = append(fieldErrs, v.validateAnnotations(ctx, vmService)...)
 	fieldErrs = append(fieldErrs, v.validateSpec(ctx, vmService)...)
 
 	validationErrs := make([]string, 0, len(fieldErrs))
@@ -118,6 +120,7 @@ func (v validator) ValidateUpdate(ctx *context.WebhookRequestContext) admission.
 
 	var fieldErrs field.ErrorList
 	fieldErrs = append(fieldErrs, v.validateAllowedChanges(ctx, vmService, oldVMService)...)
+	fieldErrs = append(fieldErrs, v.validateAnnotations(ctx, vmService)...)
 	fieldErrs = append(fieldErrs, v.validateSpec(ctx, vmService)...)
 
 	validationErrs := make([]string, 0, len(fieldErrs))
@@ -137,6 +140,20 @@ func (v validator) validateMetadata(ctx *context.WebhookRequestContext, vmServic
 	return allErrs
 }
 
+// validateAnnotations validates the annotations that configure the Service created for the
+// VirtualMachineService.
+func (v validator) validateAnnotations(ctx *context.WebhookRequestContext, vmService *vmopv1.VirtualMachineService) field.ErrorList {
+	var allErrs field.ErrorList
+	annotationPath := field.NewPath("metadata", "annotations")
+
+	if val, ok := vmService.Annotations[utils.AnnotationServiceProxyProtocolKey]; ok && val != utils.ServiceProxyProtocolV2 {
+		allErrs = append(allErrs, field.NotSupported(annotationPath.Key(utils.AnnotationServiceProxyProtocolKey),
+			val, []string{utils.ServiceProxyProtocolV2}))
+	}
+
+	return allErrs
+}
+
 func (v validator) validateSpec(ctx *context.WebhookRequestContext, vmService *vmopv1.VirtualMachineService) field.ErrorList {
 	var allErrs field.ErrorList
 	specPath := field.NewPath("spec")
@@ -14,6 +14,7 @@ import (
 
 	vmopv1 "github.com/vmware-tanzu/vm-operator/api/v1alpha1"
 
+	"github.com/vmware-tanzu/vm-operator/controllers/virtualmachineservice/utils"
 	"github.com/vmware-tanzu/vm-operator/test/builder"
 )
 
@@ -64,6 +65,8 @@ func unitTestsValidateCreate() {
 		invalidClusterIP      bool
 		invalidLBSourceRanges bool
 		invalidExternalName   bool
+		proxyProtocolV2       bool
+		invalidProxyProtocol  bool
 	}
 
 	validateCreate := func(args createArgs, expectedAllowed bool, expectedReason string, expectedErr error) {
@@ -96,6 +99,12 @@ func unitTestsValidateCreate() {
 			ctx.vmService.Spec.Type = vmopv1.VirtualMachineServiceTypeExternalName
 			ctx.vmService.Spec.ExternalName = "InValid!"
 		}
+		if args.proxyProtocolV2 {
+			ctx.vmService.Annotations = map[string]string{utils.AnnotationServiceProxyProtocolKey: utils.ServiceProxyProtocolV2}
+		}
+		if args.invalidProxyProtocol {
+			ctx.vmService.Annotations = map[string]string{utils.AnnotationServiceProxyProtocolKey: "v1"}
+		}
 
 		ctx.WebhookRequestContext.Obj, err = builder.ToUnstructured(ctx.vmService)
 		Expect(err).ToNot(HaveOccurred())
@@ -127,6 +136,8 @@ func unitTestsValidateCreate() {
 		Entry("should deny invalid ClusterIP", createArgs{invalidClusterIP: true}, false, "spec.clusterIP: Invalid value: \"100.1000.1.1\": must be a valid IP address", nil),
 		Entry("should deny invalid LoadBalancerSourceRanges", createArgs{invalidLBSourceRanges: true}, false, "spec.loadBalancerSourceRanges: Invalid value: \"[10.1.1.1/42]", nil),
 		Entry("should deny invalid ExternalName", createArgs{invalidExternalName: true}, false, "spec.externalName: Invalid value: \"InValid!\": a lowercase RFC 1123 subdomain must consist of lower case alphanumeric characters", nil),
+		Entry("should allow v2 proxy protocol", createArgs{proxyProtocolV2: true}, true, nil, nil),
+		Entry("should deny unsupported proxy protocol", createArgs{invalidProxyProtocol: true}, false, "metadata.annotations[virtualmachineservice.vmoperator.vmware.com/service.proxyProtocol]: Unsupported value: \"v1\"", nil),
 	)
 
 	validatePortCreate := func(expectedReason string, ports []vmopv1.VirtualMachineServicePort) {
@@ -227,8 +238,9 @@ func unitTestsValidateUpdate() {
 	)
 
 	type updateArgs struct {
-		updateType      bool
-		updateClusterIP bool
+		updateType           bool
+		updateClusterIP      bool
+		invalidProxyProtocol bool
 	}
 
 	validateUpdate := func(args updateArgs, expectedAllowed bool, expectedReason string, expectedErr error) {
@@ -240,6 +252,9 @@ func unitTestsValidateUpdate() {
 		if args.updateClusterIP {
 			ctx.vmService.Spec.ClusterIP = "9.9.9.9"
 		}
+		if args.invalidProxyProtocol {
+			ctx.vmService.Annotations = map[string]string{utils.AnnotationServiceProxyProtocolKey: "v1"}
+		}
 
 		ctx.WebhookRequestContext.Obj, err = builder.ToUnstructured(ctx.vmService)
 		Expect(err).ToNot(HaveOccurred())
@@ -265,6 +280,7 @@ func unitTestsValidateUpdate() {
 		Entry("should allow", updateArgs{}, true, nil, nil),
 		Entry("should deny Type change", updateArgs{updateType: true}, false, "spec.type: Forbidden: field is immutable", nil),
 		Entry("should deny ClusterIP change", updateArgs{updateClusterIP: true}, false, "spec.clusterIP: Forbidden: field is immutable", nil),
+		Entry("should deny unsupported proxy protocol", updateArgs{invalidProxyProtocol: true}, false, "metadata.annotations[virtualmachineservice.vmoperator.vmware.com/service.proxyProtocol]: Unsupported value: \"v1\": supported values: \"v2\"", nil),
 	)
 
 	When("the update is performed while object deletion", func() {